@@ -0,0 +1,99 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// install-cni installs the Istio CNI plugin on the node it runs on, and removes it on termination.
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/pkg/env"
+	"istio.io/pkg/log"
+
+	"istio.io/istio/cni/pkg/install"
+	"istio.io/istio/pkg/cmd"
+)
+
+var (
+	flags = struct {
+		sourceBinDir      string
+		cniBinDir         string
+		cniConfDir        string
+		serviceAccountDir string
+		excludeNamespaces []string
+		logLevel          string
+	}{}
+
+	kubeServiceHost = env.RegisterStringVar("KUBERNETES_SERVICE_HOST", "", "").Get()
+	kubeServicePort = env.RegisterStringVar("KUBERNETES_SERVICE_PORT", "", "").Get()
+
+	loggingOptions = log.DefaultOptions()
+
+	rootCmd = &cobra.Command{
+		Use:          "install-cni",
+		Short:        "Installs the Istio CNI plugin on the node.",
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := log.Configure(loggingOptions); err != nil {
+				return err
+			}
+			kubeconfig, err := install.ServiceAccountKubeconfig(flags.serviceAccountDir, kubeServiceHost, kubeServicePort)
+			if err != nil {
+				return err
+			}
+			cfg := &install.Config{
+				SourceBinDir:      flags.sourceBinDir,
+				CNIBinDir:         flags.cniBinDir,
+				CNIConfDir:        flags.cniConfDir,
+				Kubeconfig:        kubeconfig,
+				ExcludeNamespaces: flags.excludeNamespaces,
+				LogLevel:          flags.logLevel,
+			}
+			confFile, err := install.Install(cfg)
+			if err != nil {
+				return err
+			}
+
+			stop := make(chan struct{})
+			cmd.WaitSignal(stop)
+			return install.Uninstall(cfg, confFile)
+		},
+	}
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&flags.sourceBinDir, "sourceBinDir", "/opt/cni/bin",
+		"Directory holding the plugin binaries to install")
+	rootCmd.PersistentFlags().StringVar(&flags.cniBinDir, "cniBinDir", "/host/opt/cni/bin",
+		"Host CNI binary directory")
+	rootCmd.PersistentFlags().StringVar(&flags.cniConfDir, "cniConfDir", "/host/etc/cni/net.d",
+		"Host CNI network configuration directory")
+	rootCmd.PersistentFlags().StringVar(&flags.serviceAccountDir, "serviceAccountDir",
+		"/var/run/secrets/kubernetes.io/serviceaccount", "Directory holding the service account credentials")
+	rootCmd.PersistentFlags().StringSliceVar(&flags.excludeNamespaces, "excludeNamespaces", []string{"kube-system"},
+		"Namespaces whose pods are ignored by the plugin")
+	rootCmd.PersistentFlags().StringVar(&flags.logLevel, "pluginLogLevel", "info",
+		"Log level of the plugin")
+
+	loggingOptions.AttachCobraFlags(rootCmd)
+	cmd.AddFlags(rootCmd)
+}
+
+func main() {
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(-1)
+	}
+}
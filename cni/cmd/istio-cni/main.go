@@ -0,0 +1,112 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// istio-cni is a chained CNI plugin programming the traffic redirection of pods with an injected sidecar.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"istio.io/pkg/log"
+
+	"istio.io/istio/cni/pkg/plugin"
+)
+
+// cniError is the error format defined by the CNI spec.
+type cniError struct {
+	CNIVersion string `json:"cniVersion"`
+	Code       uint   `json:"code"`
+	Msg        string `json:"msg"`
+}
+
+func run() error {
+	args := &plugin.Args{
+		Command:     os.Getenv("CNI_COMMAND"),
+		ContainerID: os.Getenv("CNI_CONTAINERID"),
+		Netns:       os.Getenv("CNI_NETNS"),
+		IfName:      os.Getenv("CNI_IFNAME"),
+		Path:        os.Getenv("CNI_PATH"),
+	}
+
+	if args.Command == "VERSION" {
+		return json.NewEncoder(os.Stdout).Encode(map[string]interface{}{
+			"cniVersion":        plugin.CNIVersion,
+			"supportedVersions": plugin.SupportedVersions,
+		})
+	}
+
+	stdin, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read network configuration: %v", err)
+	}
+	conf, err := plugin.ParseConfig(stdin)
+	if err != nil {
+		return err
+	}
+	// stdout is reserved for the result reported to the runtime.
+	o := log.DefaultOptions()
+	o.OutputPaths = []string{"stderr"}
+	o.ErrorOutputPaths = []string{"stderr"}
+	o.SetOutputLevel(log.DefaultScopeName, logLevel(conf.LogLevel))
+	if err := log.Configure(o); err != nil {
+		return err
+	}
+
+	switch args.Command {
+	case "ADD":
+		if err := args.ParseCNIArgs(os.Getenv("CNI_ARGS")); err != nil {
+			return err
+		}
+		pods, err := plugin.NewKubePodGetter(conf.Kubernetes.Kubeconfig)
+		if err != nil {
+			return err
+		}
+		if err := plugin.CmdAdd(args, conf, pods, plugin.NewNsenterRedirector(conf.Kubernetes.IptablesPath)); err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(conf.Result())
+		return err
+	case "DEL", "CHECK":
+		// The rules live in the pod network namespace and are removed with it.
+		return nil
+	default:
+		return fmt.Errorf("unknown CNI_COMMAND %q", args.Command)
+	}
+}
+
+func logLevel(level string) log.Level {
+	switch level {
+	case "debug":
+		return log.DebugLevel
+	case "warn", "warning":
+		return log.WarnLevel
+	case "error":
+		return log.ErrorLevel
+	}
+	return log.InfoLevel
+}
+
+func main() {
+	if err := run(); err != nil {
+		_ = json.NewEncoder(os.Stdout).Encode(&cniError{
+			CNIVersion: plugin.CNIVersion,
+			Code:       100,
+			Msg:        err.Error(),
+		})
+		os.Exit(1)
+	}
+}
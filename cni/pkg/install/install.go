@@ -0,0 +1,248 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package install installs the Istio CNI plugin on a node. It is run by the node daemonset.
+package install
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"istio.io/pkg/log"
+)
+
+const (
+	pluginType     = "istio-cni"
+	kubeconfigName = "ZZZ-istio-cni-kubeconfig"
+)
+
+// Binaries lists the binaries copied to the host CNI bin directory.
+var Binaries = []string{"istio-cni", "istio-iptables"}
+
+// Config configures the installation.
+type Config struct {
+	// SourceBinDir is the directory holding the binaries in the installer image.
+	SourceBinDir string
+	// CNIBinDir is the host CNI binary directory, mounted in the installer.
+	CNIBinDir string
+	// CNIConfDir is the host CNI configuration directory, mounted in the installer.
+	CNIConfDir string
+	// Kubeconfig is the content of the kubeconfig the plugin uses to look up pods.
+	Kubeconfig []byte
+	// ExcludeNamespaces lists the namespaces whose pods are ignored by the plugin.
+	ExcludeNamespaces []string
+	// LogLevel is the plugin log level.
+	LogLevel string
+}
+
+// Install copies the plugin binaries and inserts the plugin in the primary CNI network configuration.
+// It returns the path of the modified network configuration.
+func Install(cfg *Config) (string, error) {
+	for _, b := range Binaries {
+		if err := copyFile(filepath.Join(cfg.SourceBinDir, b), filepath.Join(cfg.CNIBinDir, b), 0755); err != nil {
+			return "", err
+		}
+	}
+
+	kubeconfigPath := filepath.Join(cfg.CNIConfDir, kubeconfigName)
+	if err := ioutil.WriteFile(kubeconfigPath, cfg.Kubeconfig, 0600); err != nil {
+		return "", err
+	}
+
+	confFile, err := primaryConfFile(cfg.CNIConfDir)
+	if err != nil {
+		return "", err
+	}
+	existing, err := ioutil.ReadFile(confFile)
+	if err != nil {
+		return "", err
+	}
+	hostKubeconfig := filepath.Join(hostPath(cfg.CNIConfDir), kubeconfigName)
+	updated, err := InsertPlugin(existing, pluginConfig(cfg, hostKubeconfig))
+	if err != nil {
+		return "", fmt.Errorf("failed to insert plugin in %s: %v", confFile, err)
+	}
+
+	// Plain network configurations are converted to configuration lists.
+	target := confFile
+	if strings.HasSuffix(confFile, ".conf") {
+		target = confFile + "list"
+	}
+	if err := ioutil.WriteFile(target, updated, 0644); err != nil {
+		return "", err
+	}
+	if target != confFile {
+		if err := os.Remove(confFile); err != nil {
+			return "", err
+		}
+	}
+	log.Infof("Installed Istio CNI plugin in %s", target)
+	return target, nil
+}
+
+// Uninstall removes the plugin from the given network configuration and removes the plugin files.
+func Uninstall(cfg *Config, confFile string) error {
+	existing, err := ioutil.ReadFile(confFile)
+	if err != nil {
+		return err
+	}
+	updated, err := RemovePlugin(existing)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(confFile, updated, 0644); err != nil {
+		return err
+	}
+	_ = os.Remove(filepath.Join(cfg.CNIConfDir, kubeconfigName))
+	for _, b := range Binaries {
+		_ = os.Remove(filepath.Join(cfg.CNIBinDir, b))
+	}
+	log.Infof("Removed Istio CNI plugin from %s", confFile)
+	return nil
+}
+
+func pluginConfig(cfg *Config, kubeconfig string) map[string]interface{} {
+	exclude := cfg.ExcludeNamespaces
+	if exclude == nil {
+		exclude = []string{}
+	}
+	return map[string]interface{}{
+		"type":      pluginType,
+		"log_level": cfg.LogLevel,
+		"kubernetes": map[string]interface{}{
+			"kubeconfig":         kubeconfig,
+			"exclude_namespaces": exclude,
+			"iptables_path":      filepath.Join(hostPath(cfg.CNIBinDir), "istio-iptables"),
+		},
+	}
+}
+
+// hostPath returns the host path of a directory mounted under /host in the installer.
+func hostPath(dir string) string {
+	if strings.HasPrefix(dir, "/host/") {
+		return strings.TrimPrefix(dir, "/host")
+	}
+	return dir
+}
+
+// primaryConfFile returns the network configuration used by the container runtime,
+// which is the first one in lexicographic order.
+func primaryConfFile(dir string) (string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		if ext := filepath.Ext(f.Name()); ext == ".conf" || ext == ".conflist" {
+			names = append(names, f.Name())
+		}
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("no CNI network configuration found in %s", dir)
+	}
+	sort.Strings(names)
+	return filepath.Join(dir, names[0]), nil
+}
+
+// InsertPlugin appends the plugin to the plugins of the network configuration, converting a plain
+// network configuration into a configuration list. An existing Istio plugin entry is replaced.
+func InsertPlugin(existing []byte, plugin map[string]interface{}) ([]byte, error) {
+	conf := map[string]interface{}{}
+	if err := json.Unmarshal(existing, &conf); err != nil {
+		return nil, err
+	}
+
+	var plugins []interface{}
+	if p, ok := conf["plugins"]; ok {
+		if plugins, ok = p.([]interface{}); !ok {
+			return nil, fmt.Errorf("invalid plugins list")
+		}
+	} else {
+		// A plain network configuration: its settings become the first plugin of the list.
+		first := map[string]interface{}{}
+		list := map[string]interface{}{}
+		for k, v := range conf {
+			switch k {
+			case "cniVersion", "name":
+				list[k] = v
+			default:
+				first[k] = v
+			}
+		}
+		conf = list
+		plugins = []interface{}{first}
+	}
+
+	plugins = removeIstioPlugin(plugins)
+	conf["plugins"] = append(plugins, plugin)
+	return json.MarshalIndent(conf, "", "  ")
+}
+
+// RemovePlugin removes the Istio plugin from the network configuration list.
+func RemovePlugin(existing []byte) ([]byte, error) {
+	conf := map[string]interface{}{}
+	if err := json.Unmarshal(existing, &conf); err != nil {
+		return nil, err
+	}
+	plugins, ok := conf["plugins"].([]interface{})
+	if !ok {
+		return existing, nil
+	}
+	conf["plugins"] = removeIstioPlugin(plugins)
+	return json.MarshalIndent(conf, "", "  ")
+}
+
+func removeIstioPlugin(plugins []interface{}) []interface{} {
+	out := make([]interface{}, 0, len(plugins))
+	for _, p := range plugins {
+		if m, ok := p.(map[string]interface{}); ok && m["type"] == pluginType {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// Write to a temporary file and rename, so the runtime never executes a partial binary.
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
@@ -0,0 +1,136 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func pluginTypes(t *testing.T, conf []byte) []string {
+	t.Helper()
+	var list struct {
+		Name    string                   `json:"name"`
+		Plugins []map[string]interface{} `json:"plugins"`
+	}
+	if err := json.Unmarshal(conf, &list); err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, p := range list.Plugins {
+		types = append(types, p["type"].(string))
+	}
+	return types
+}
+
+func TestInsertPlugin(t *testing.T) {
+	plugin := map[string]interface{}{"type": pluginType}
+	cases := []struct {
+		name     string
+		existing string
+		want     []string
+	}{
+		{
+			name:     "plain configuration",
+			existing: `{"cniVersion": "0.3.1", "name": "k8s", "type": "calico", "ipam": {"type": "host-local"}}`,
+			want:     []string{"calico", pluginType},
+		},
+		{
+			name:     "configuration list",
+			existing: `{"cniVersion": "0.3.1", "name": "k8s", "plugins": [{"type": "calico"}, {"type": "portmap"}]}`,
+			want:     []string{"calico", "portmap", pluginType},
+		},
+		{
+			name:     "already installed",
+			existing: `{"cniVersion": "0.3.1", "name": "k8s", "plugins": [{"type": "calico"}, {"type": "istio-cni"}]}`,
+			want:     []string{"calico", pluginType},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out, err := InsertPlugin([]byte(c.existing), plugin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := pluginTypes(t, out); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("InsertPlugin() got plugins %v, want %v", got, c.want)
+			}
+			removed, err := RemovePlugin(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := pluginTypes(t, removed); !reflect.DeepEqual(got, c.want[:len(c.want)-1]) {
+				t.Errorf("RemovePlugin() got plugins %v, want %v", got, c.want[:len(c.want)-1])
+			}
+		})
+	}
+}
+
+func TestInstallUninstall(t *testing.T) {
+	root, err := ioutil.TempDir("", "install-cni")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	cfg := &Config{
+		SourceBinDir: filepath.Join(root, "src"),
+		CNIBinDir:    filepath.Join(root, "bin"),
+		CNIConfDir:   filepath.Join(root, "net.d"),
+		Kubeconfig:   []byte("kubeconfig"),
+	}
+	for _, d := range []string{cfg.SourceBinDir, cfg.CNIBinDir, cfg.CNIConfDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, b := range Binaries {
+		if err := ioutil.WriteFile(filepath.Join(cfg.SourceBinDir, b), []byte(b), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(cfg.CNIConfDir, "10-calico.conf"),
+		[]byte(`{"cniVersion": "0.3.1", "name": "k8s", "type": "calico"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	confFile, err := Install(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(cfg.CNIConfDir, "10-calico.conflist"); confFile != want {
+		t.Errorf("Install() wrote %s, want %s", confFile, want)
+	}
+	for _, b := range Binaries {
+		if _, err := os.Stat(filepath.Join(cfg.CNIBinDir, b)); err != nil {
+			t.Errorf("binary %s not installed: %v", b, err)
+		}
+	}
+
+	if err := Uninstall(cfg, confFile); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := ioutil.ReadFile(confFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pluginTypes(t, conf); !reflect.DeepEqual(got, []string{"calico"}) {
+		t.Errorf("Uninstall() left plugins %v", got)
+	}
+}
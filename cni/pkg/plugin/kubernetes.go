@@ -0,0 +1,96 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/pkg/log"
+
+	"istio.io/istio/pkg/kube"
+)
+
+const (
+	podLookupAttempts = 10
+	podLookupInterval = 500 * time.Millisecond
+)
+
+// KubePodGetter looks up pods in the Kubernetes API server.
+type KubePodGetter struct {
+	client kubernetes.Interface
+}
+
+// NewKubePodGetter creates a pod getter using the given kubeconfig.
+func NewKubePodGetter(kubeconfig string) (*KubePodGetter, error) {
+	client, err := kube.CreateClientset(kubeconfig, "")
+	if err != nil {
+		return nil, err
+	}
+	return &KubePodGetter{client: client}, nil
+}
+
+// GetPod implements PodGetter.
+func (k *KubePodGetter) GetPod(namespace, name string) (*Pod, error) {
+	var err error
+	for attempt := 0; attempt < podLookupAttempts; attempt++ {
+		p, e := k.client.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{})
+		if e == nil {
+			pod := &Pod{Annotations: p.Annotations}
+			for _, c := range p.Spec.Containers {
+				pod.Containers = append(pod.Containers, c.Name)
+			}
+			for _, c := range p.Spec.InitContainers {
+				pod.InitContainers = append(pod.InitContainers, c.Name)
+			}
+			return pod, nil
+		}
+		err = e
+		log.Debugf("Failed to get pod %s/%s (attempt %d): %v", namespace, name, attempt+1, err)
+		time.Sleep(podLookupInterval)
+	}
+	return nil, err
+}
+
+// NsenterRedirector programs the redirect by running istio-iptables in the pod network namespace.
+type NsenterRedirector struct {
+	IptablesPath string
+}
+
+// NewNsenterRedirector creates a redirector using the istio-iptables binary at the given path,
+// defaulting to the one next to the plugin binary.
+func NewNsenterRedirector(iptablesPath string) *NsenterRedirector {
+	if iptablesPath == "" {
+		iptablesPath = filepath.Join(filepath.Dir(os.Args[0]), "istio-iptables")
+	}
+	return &NsenterRedirector{IptablesPath: iptablesPath}
+}
+
+// Program implements Redirector.
+func (n *NsenterRedirector) Program(netns string, redirect *Redirect) error {
+	args := append([]string{"--net=" + netns, "--", n.IptablesPath}, redirect.Args()...)
+	out, err := exec.Command("nsenter", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to program redirect: %v: %s", err, string(out))
+	}
+	log.Debugf("istio-iptables output: %s", string(out))
+	return nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package plugin implements a chained CNI plugin programming the traffic redirection
+// of pods with an injected sidecar, replacing the privileged istio-init container.
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"istio.io/api/annotation"
+	"istio.io/pkg/log"
+
+	"istio.io/istio/pkg/kube/inject"
+)
+
+const (
+	// CNIVersion is the CNI spec version implemented by the plugin.
+	CNIVersion = "0.3.1"
+)
+
+// SupportedVersions lists the CNI spec versions supported by the plugin.
+var SupportedVersions = []string{"0.1.0", "0.2.0", "0.3.0", "0.3.1"}
+
+// Kubernetes holds the Kubernetes specific plugin configuration.
+type Kubernetes struct {
+	Kubeconfig        string   `json:"kubeconfig"`
+	ExcludeNamespaces []string `json:"exclude_namespaces"`
+	// IptablesPath is the path of the istio-iptables binary. Defaults to the directory of the plugin.
+	IptablesPath string `json:"iptables_path"`
+}
+
+// Config is the plugin configuration passed by the container runtime on stdin.
+type Config struct {
+	CNIVersion string          `json:"cniVersion"`
+	Name       string          `json:"name"`
+	Type       string          `json:"type"`
+	PrevResult json.RawMessage `json:"prevResult,omitempty"`
+	LogLevel   string          `json:"log_level"`
+	Kubernetes Kubernetes      `json:"kubernetes"`
+}
+
+// Args holds the invocation arguments passed by the container runtime in the environment.
+type Args struct {
+	Command     string
+	ContainerID string
+	Netns       string
+	IfName      string
+	Path        string
+	// PodName and PodNamespace are extracted from CNI_ARGS.
+	PodName      string
+	PodNamespace string
+}
+
+// Pod is the subset of the pod information used by the plugin.
+type Pod struct {
+	Annotations    map[string]string
+	Containers     []string
+	InitContainers []string
+}
+
+// PodGetter looks up pods.
+type PodGetter interface {
+	GetPod(namespace, name string) (*Pod, error)
+}
+
+// Redirector programs the redirect in the pod network namespace.
+type Redirector interface {
+	Program(netns string, redirect *Redirect) error
+}
+
+// ParseConfig parses the plugin configuration.
+func ParseConfig(stdin []byte) (*Config, error) {
+	conf := &Config{}
+	if err := json.Unmarshal(stdin, conf); err != nil {
+		return nil, fmt.Errorf("failed to parse network configuration: %v", err)
+	}
+	return conf, nil
+}
+
+// ParseCNIArgs extracts the pod name and namespace from the CNI_ARGS value,
+// e.g. "IgnoreUnknown=1;K8S_POD_NAMESPACE=default;K8S_POD_NAME=foo".
+func (a *Args) ParseCNIArgs(cniArgs string) error {
+	for _, pair := range strings.Split(cniArgs, ";") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid CNI_ARGS pair %q", pair)
+		}
+		switch kv[0] {
+		case "K8S_POD_NAME":
+			a.PodName = kv[1]
+		case "K8S_POD_NAMESPACE":
+			a.PodNamespace = kv[1]
+		}
+	}
+	return nil
+}
+
+// Result returns the result the plugin reports to the runtime: the result of the previous
+// plugin in the chain, unchanged, or an empty result if the plugin is not chained.
+func (c *Config) Result() []byte {
+	if len(c.PrevResult) > 0 {
+		return c.PrevResult
+	}
+	out, _ := json.Marshal(map[string]string{"cniVersion": c.CNIVersion})
+	return out
+}
+
+// CmdAdd programs the traffic redirection for the pod being added, if it has an injected sidecar.
+func CmdAdd(args *Args, conf *Config, pods PodGetter, redirector Redirector) error {
+	if args.PodNamespace == "" || args.PodName == "" {
+		// Not a Kubernetes pod.
+		return nil
+	}
+	for _, ns := range conf.Kubernetes.ExcludeNamespaces {
+		if args.PodNamespace == ns {
+			log.Infof("Pod %s/%s excluded", args.PodNamespace, args.PodName)
+			return nil
+		}
+	}
+
+	pod, err := pods.GetPod(args.PodNamespace, args.PodName)
+	if err != nil {
+		return fmt.Errorf("failed to get pod %s/%s: %v", args.PodNamespace, args.PodName, err)
+	}
+	if !requiresRedirect(pod) {
+		log.Infof("Pod %s/%s has no injected sidecar, skipping", args.PodNamespace, args.PodName)
+		return nil
+	}
+
+	redirect, err := NewRedirect(pod.Annotations)
+	if err != nil {
+		return fmt.Errorf("invalid redirect settings for pod %s/%s: %v", args.PodNamespace, args.PodName, err)
+	}
+	log.Infof("Programming redirect for pod %s/%s: %v", args.PodNamespace, args.PodName, redirect.Args())
+	return redirector.Program(args.Netns, redirect)
+}
+
+// requiresRedirect returns whether the pod has an injected sidecar whose traffic should be captured.
+func requiresRedirect(pod *Pod) bool {
+	if _, ok := pod.Annotations[annotation.SidecarStatus.Name]; !ok {
+		return false
+	}
+	if pod.Annotations[annotation.SidecarInterceptionMode.Name] == "NONE" {
+		return false
+	}
+	if pod.Annotations[inject.GatewayInjectionAnnotation] == "true" {
+		// Gateways receive traffic directly and are never redirected.
+		return false
+	}
+	for _, c := range pod.InitContainers {
+		// The init container already takes care of the redirect.
+		if c == "istio-init" {
+			return false
+		}
+	}
+	for _, c := range pod.Containers {
+		if c == inject.ProxyContainerName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type fakePods map[string]*Pod
+
+func (f fakePods) GetPod(namespace, name string) (*Pod, error) {
+	if p, ok := f[namespace+"/"+name]; ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("pod %s/%s not found", namespace, name)
+}
+
+type fakeRedirector struct {
+	netns    string
+	redirect *Redirect
+}
+
+func (f *fakeRedirector) Program(netns string, redirect *Redirect) error {
+	f.netns = netns
+	f.redirect = redirect
+	return nil
+}
+
+func injectedPod(annotations map[string]string) *Pod {
+	a := map[string]string{"sidecar.istio.io/status": "{}"}
+	for k, v := range annotations {
+		a[k] = v
+	}
+	return &Pod{
+		Annotations: a,
+		Containers:  []string{"app", "istio-proxy"},
+	}
+}
+
+func TestCmdAdd(t *testing.T) {
+	pods := fakePods{
+		"default/injected": injectedPod(map[string]string{
+			"traffic.sidecar.istio.io/excludeInboundPorts":  "15020",
+			"traffic.sidecar.istio.io/excludeOutboundPorts": "53",
+		}),
+		"default/not-injected": {Containers: []string{"app"}},
+		"default/init-container": {
+			Annotations:    map[string]string{"sidecar.istio.io/status": "{}"},
+			Containers:     []string{"app", "istio-proxy"},
+			InitContainers: []string{"istio-init"},
+		},
+		"default/none":         injectedPod(map[string]string{"sidecar.istio.io/interceptionMode": "NONE"}),
+		"default/bad":          injectedPod(map[string]string{"traffic.sidecar.istio.io/excludeInboundPorts": "abc"}),
+		"kube-system/injected": injectedPod(nil),
+	}
+	conf := &Config{Kubernetes: Kubernetes{ExcludeNamespaces: []string{"kube-system"}}}
+
+	cases := []struct {
+		name     string
+		pod      string
+		ns       string
+		wantArgs []string
+		wantErr  bool
+	}{
+		{
+			name: "injected",
+			pod:  "injected",
+			ns:   "default",
+			wantArgs: []string{"-p", "15001", "-z", "15006", "-u", "1337", "-m", "REDIRECT", "-i", "*", "-x", "",
+				"-b", "*", "-d", "15020", "-o", "53"},
+		},
+		{name: "not injected", pod: "not-injected", ns: "default"},
+		{name: "init container", pod: "init-container", ns: "default"},
+		{name: "interception disabled", pod: "none", ns: "default"},
+		{name: "gateway", pod: "gateway", ns: "default"},
+		{name: "excluded namespace", pod: "injected", ns: "kube-system"},
+		{name: "invalid annotation", pod: "bad", ns: "default", wantErr: true},
+		{name: "missing pod", pod: "missing", ns: "default", wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := &fakeRedirector{}
+			args := &Args{Netns: "/var/run/netns/test", PodName: c.pod, PodNamespace: c.ns}
+			err := CmdAdd(args, conf, pods, r)
+			if gotErr := err != nil; gotErr != c.wantErr {
+				t.Fatalf("CmdAdd() got error %v, want error %v", err, c.wantErr)
+			}
+			if c.wantArgs == nil {
+				if r.redirect != nil {
+					t.Fatalf("CmdAdd() programmed unexpected redirect %v", r.redirect.Args())
+				}
+				return
+			}
+			if r.redirect == nil {
+				t.Fatalf("CmdAdd() did not program the redirect")
+			}
+			if r.netns != args.Netns {
+				t.Errorf("CmdAdd() programmed netns %s, want %s", r.netns, args.Netns)
+			}
+			if got := r.redirect.Args(); !reflect.DeepEqual(got, c.wantArgs) {
+				t.Errorf("CmdAdd() got args %v, want %v", got, c.wantArgs)
+			}
+		})
+	}
+}
+
+func TestParseCNIArgs(t *testing.T) {
+	args := &Args{}
+	if err := args.ParseCNIArgs("IgnoreUnknown=1;K8S_POD_NAMESPACE=default;K8S_POD_NAME=foo;K8S_POD_INFRA_CONTAINER_ID=abc"); err != nil {
+		t.Fatal(err)
+	}
+	if args.PodName != "foo" || args.PodNamespace != "default" {
+		t.Errorf("ParseCNIArgs() got pod %s/%s, want default/foo", args.PodNamespace, args.PodName)
+	}
+	if err := args.ParseCNIArgs("invalid"); err == nil {
+		t.Errorf("ParseCNIArgs() expected error for invalid CNI_ARGS")
+	}
+}
+
+func TestResult(t *testing.T) {
+	prev := `{"cniVersion":"0.3.1","ips":[{"version":"4","address":"10.1.0.5/24"}]}`
+	conf, err := ParseConfig([]byte(`{"cniVersion":"0.3.1","name":"k8s","type":"istio-cni","prevResult":` + prev + `}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(conf.Result()); got != prev {
+		t.Errorf("Result() got %s, want %s", got, prev)
+	}
+
+	conf, err = ParseConfig([]byte(`{"cniVersion":"0.3.1","name":"k8s","type":"istio-cni"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(conf.Result()), `{"cniVersion":"0.3.1"}`; got != want {
+		t.Errorf("Result() got %s, want %s", got, want)
+	}
+}
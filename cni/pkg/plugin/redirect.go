@@ -0,0 +1,148 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"istio.io/api/annotation"
+
+	"istio.io/istio/pkg/kube/inject"
+)
+
+const (
+	defaultProxyPort          = "15001"
+	defaultInboundCapturePort = "15006"
+	defaultInterceptionMode   = "REDIRECT"
+	defaultIncludeIPRanges    = "*"
+	defaultIncludeInboundPort = "*"
+)
+
+// Redirect holds the traffic redirection settings of a pod, as written by the sidecar injector
+// in the pod annotations.
+type Redirect struct {
+	ProxyPort            string
+	InboundCapturePort   string
+	ProxyUID             string
+	InterceptionMode     string
+	IncludeIPRanges      string
+	ExcludeIPRanges      string
+	IncludeInboundPorts  string
+	ExcludeInboundPorts  string
+	ExcludeOutboundPorts string
+	KubevirtInterfaces   string
+}
+
+// redirectAnnotation describes how a pod annotation maps to a redirect setting.
+type redirectAnnotation struct {
+	name         string
+	defaultValue string
+	validate     func(string) error
+	field        func(*Redirect) *string
+}
+
+var redirectAnnotations = []redirectAnnotation{
+	{
+		name:         annotation.SidecarInterceptionMode.Name,
+		defaultValue: defaultInterceptionMode,
+		validate:     validateInterceptionMode,
+		field:        func(r *Redirect) *string { return &r.InterceptionMode },
+	},
+	{
+		name:         annotation.SidecarTrafficIncludeOutboundIPRanges.Name,
+		defaultValue: defaultIncludeIPRanges,
+		validate:     inject.ValidateIncludeIPRanges,
+		field:        func(r *Redirect) *string { return &r.IncludeIPRanges },
+	},
+	{
+		name:     annotation.SidecarTrafficExcludeOutboundIPRanges.Name,
+		validate: inject.ValidateExcludeIPRanges,
+		field:    func(r *Redirect) *string { return &r.ExcludeIPRanges },
+	},
+	{
+		name:         annotation.SidecarTrafficIncludeInboundPorts.Name,
+		defaultValue: defaultIncludeInboundPort,
+		validate:     inject.ValidateIncludeInboundPorts,
+		field:        func(r *Redirect) *string { return &r.IncludeInboundPorts },
+	},
+	{
+		name:     annotation.SidecarTrafficExcludeInboundPorts.Name,
+		validate: inject.ValidateExcludeInboundPorts,
+		field:    func(r *Redirect) *string { return &r.ExcludeInboundPorts },
+	},
+	{
+		name:     annotation.SidecarTrafficExcludeOutboundPorts.Name,
+		validate: inject.ValidateExcludeOutboundPorts,
+		field:    func(r *Redirect) *string { return &r.ExcludeOutboundPorts },
+	},
+	{
+		name:  annotation.SidecarTrafficKubevirtInterfaces.Name,
+		field: func(r *Redirect) *string { return &r.KubevirtInterfaces },
+	},
+}
+
+func validateInterceptionMode(mode string) error {
+	switch mode {
+	case "REDIRECT", "TPROXY":
+		return nil
+	}
+	return fmt.Errorf("interceptionMode invalid, use REDIRECT or TPROXY: %v", mode)
+}
+
+// NewRedirect returns the redirect settings for a pod with the given annotations.
+func NewRedirect(annotations map[string]string) (*Redirect, error) {
+	r := &Redirect{
+		ProxyPort:          defaultProxyPort,
+		InboundCapturePort: defaultInboundCapturePort,
+		ProxyUID:           strconv.FormatUint(inject.DefaultSidecarProxyUID, 10),
+	}
+	for _, a := range redirectAnnotations {
+		value, ok := annotations[a.name]
+		if !ok {
+			value = a.defaultValue
+		}
+		value = strings.TrimSpace(value)
+		if a.validate != nil {
+			if err := a.validate(value); err != nil {
+				return nil, fmt.Errorf("annotation %s: %v", a.name, err)
+			}
+		}
+		*a.field(r) = value
+	}
+	return r, nil
+}
+
+// Args returns the istio-iptables arguments programming the redirect.
+func (r *Redirect) Args() []string {
+	args := []string{
+		"-p", r.ProxyPort,
+		"-z", r.InboundCapturePort,
+		"-u", r.ProxyUID,
+		"-m", r.InterceptionMode,
+		"-i", r.IncludeIPRanges,
+		"-x", r.ExcludeIPRanges,
+		"-b", r.IncludeInboundPorts,
+		"-d", r.ExcludeInboundPorts,
+	}
+	if r.ExcludeOutboundPorts != "" {
+		args = append(args, "-o", r.ExcludeOutboundPorts)
+	}
+	if r.KubevirtInterfaces != "" {
+		args = append(args, "-k", r.KubevirtInterfaces)
+	}
+	return args
+}
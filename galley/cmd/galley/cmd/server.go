@@ -76,6 +76,7 @@ func serverCmd() *cobra.Command {
 			if len(serverArgs.ValidationArgs.KeyFile) < 1 {
 				serverArgs.ValidationArgs.KeyFile = serverArgs.CredentialOptions.KeyFile
 			}
+			serverArgs.ValidationArgs.DomainSuffix = serverArgs.DomainSuffix
 
 			if !serverArgs.EnableServer && !serverArgs.ValidationArgs.EnableValidation {
 				log.Fatala("Galley must be running under at least one mode: server or validation")
@@ -135,6 +136,18 @@ func serverCmd() *cobra.Command {
 		"The access list yaml file that contains the allowd mTLS peer ids.")
 	serverCmd.PersistentFlags().StringVar(&serverArgs.ConfigPath, "configPath", serverArgs.ConfigPath,
 		"Istio config file path")
+	serverCmd.PersistentFlags().StringVar(&serverArgs.GitSource.Repository, "gitRepository", serverArgs.GitSource.Repository,
+		"URL of a git repository to read the Istio config from, instead of Kubernetes")
+	serverCmd.PersistentFlags().StringVar(&serverArgs.GitSource.Branch, "gitBranch", serverArgs.GitSource.Branch,
+		"Branch of the git repository to read the Istio config from")
+	serverCmd.PersistentFlags().StringVar(&serverArgs.GitSource.Path, "gitPath", serverArgs.GitSource.Path,
+		"Directory within the git repository holding the Istio config")
+	serverCmd.PersistentFlags().DurationVar(&serverArgs.GitSource.PollInterval, "gitPollInterval", serverArgs.GitSource.PollInterval,
+		"Interval between two fetches of the git repository, 0 to only fetch when the webhook is called")
+	serverCmd.PersistentFlags().StringVar(&serverArgs.GitSource.WebhookAddress, "gitWebhookAddress", serverArgs.GitSource.WebhookAddress,
+		"Address of the HTTP server triggering a fetch of the git repository on POST, e.g. ':15018'")
+	serverCmd.PersistentFlags().StringVar(&serverArgs.GitSource.Dir, "gitDir", serverArgs.GitSource.Dir,
+		"Local directory to clone the git repository into")
 	serverCmd.PersistentFlags().StringVar(&serverArgs.MeshConfigFile, "meshConfigFile", serverArgs.MeshConfigFile,
 		"Path to the mesh config file")
 	serverCmd.PersistentFlags().StringVar(&serverArgs.DomainSuffix, "domain", serverArgs.DomainSuffix,
@@ -151,6 +164,10 @@ func serverCmd() *cobra.Command {
 		serverArgs.SinkMeta, "Comma-separated list of key=values to attach as metadata to outgoing sink connections. Ex: 'key=value,key2=value2'")
 	serverCmd.PersistentFlags().BoolVar(&serverArgs.EnableServiceDiscovery, "enableServiceDiscovery", false,
 		"Enable service discovery processing in Galley")
+	serverCmd.PersistentFlags().BoolVar(&serverArgs.EnableIncrementalMCP, "enableIncrementalMCP", serverArgs.EnableIncrementalMCP,
+		"Send incremental updates, with only the changed and removed resources, to MCP sinks requesting them")
+	serverCmd.PersistentFlags().BoolVar(&serverArgs.EnableConfigAnalysis, "enableAnalysis", serverArgs.EnableConfigAnalysis,
+		"Analyze the configuration and write the analysis messages into the status of the offending resources")
 	serverCmd.PersistentFlags().BoolVar(&serverArgs.UseOldProcessor, "useOldProcessor", serverArgs.UseOldProcessor,
 		"Use the old processing pipeline for config processing")
 
@@ -162,6 +179,16 @@ func serverCmd() *cobra.Command {
 		"HTTPS port of the validation service. Must be 443 if service has more than one port ")
 	serverCmd.PersistentFlags().BoolVar(&serverArgs.ValidationArgs.EnableValidation, "enable-validation", serverArgs.ValidationArgs.EnableValidation,
 		"Run galley validation mode")
+	serverCmd.PersistentFlags().StringVar(&serverArgs.ValidationArgs.CrossResourceValidation, "validation-cross-resource",
+		serverArgs.ValidationArgs.CrossResourceValidation,
+		"Check Pilot resources against the configuration in the cluster, e.g. that referenced gateways exist. "+
+			"One of off, warn (log and admit) or enforce (reject)")
+	serverCmd.PersistentFlags().BoolVar(&serverArgs.ValidationArgs.EnableDefaulting, "enable-defaulting",
+		serverArgs.ValidationArgs.EnableDefaulting,
+		"Register a mutating webhook setting the implicit defaults of Pilot resources, e.g. retry policies")
+	serverCmd.PersistentFlags().BoolVar(&serverArgs.ValidationArgs.EnableConversion, "enable-conversion",
+		serverArgs.ValidationArgs.EnableConversion,
+		"Serve the Istio CRDs at all the API versions of their group")
 	serverCmd.PersistentFlags().BoolVar(&serverArgs.ValidationArgs.EnableReconcileWebhookConfiguration,
 		"enable-reconcileWebhookConfiguration", serverArgs.ValidationArgs.EnableReconcileWebhookConfiguration,
 		"Enable reconciliation for webhook configuration.")
@@ -221,6 +248,12 @@ func setupAliases() {
 	viper.RegisterAlias("processing.server.auth.insecure", "insecure")
 	viper.RegisterAlias("processing.source.kubernetes.resyncPeriod", "resyncPeriod")
 	viper.RegisterAlias("processing.source.filesystem.path", "configPath")
+	viper.RegisterAlias("processing.source.git.repository", "gitRepository")
+	viper.RegisterAlias("processing.source.git.branch", "gitBranch")
+	viper.RegisterAlias("processing.source.git.path", "gitPath")
+	viper.RegisterAlias("processing.source.git.pollInterval", "gitPollInterval")
+	viper.RegisterAlias("processing.source.git.webhookAddress", "gitWebhookAddress")
+	viper.RegisterAlias("processing.source.git.dir", "gitDir")
 	viper.RegisterAlias("validation.enable", "enable-validation")
 	viper.RegisterAlias("validation.webhookConfigFile", "validation-webhook-config-file")
 	viper.RegisterAlias("validation.webhookPort", "validation-port")
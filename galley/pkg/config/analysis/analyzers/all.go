@@ -17,7 +17,9 @@ package analyzers
 import (
 	"istio.io/istio/galley/pkg/config/analysis"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/auth"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/destinationrule"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/injection"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/ownership"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/virtualservice"
 )
 
@@ -26,8 +28,11 @@ func All() []analysis.Analyzer {
 	return []analysis.Analyzer{
 		&virtualservice.GatewayAnalyzer{},
 		&virtualservice.DestinationAnalyzer{},
+		&virtualservice.ConflictAnalyzer{},
 		&auth.ServiceRoleBindingAnalyzer{},
+		&destinationrule.ConflictAnalyzer{},
 		&injection.Analyzer{},
+		&ownership.HostAnalyzer{},
 	}
 }
 
@@ -22,7 +22,9 @@ import (
 
 	"istio.io/istio/galley/pkg/config/analysis"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/auth"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/destinationrule"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/injection"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/ownership"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/virtualservice"
 	"istio.io/istio/galley/pkg/config/analysis/diag"
 	"istio.io/istio/galley/pkg/config/analysis/local"
@@ -77,6 +79,31 @@ var testGrid = []testCase{
 			{msg.ReferencedResourceNotFound, "VirtualService/default/reviews-bogussubset"},
 		},
 	},
+	{
+		name: "virtualServiceConflicts",
+		inputFiles: []string{
+			"testdata/virtualservice_conflicts.yaml",
+		},
+		analyzer: &virtualservice.ConflictAnalyzer{},
+		expected: []message{
+			{msg.VirtualServiceShadowed, "VirtualService/default/reviews-shadowed"},
+			{msg.VirtualServiceShadowed, "VirtualService/default/ratings-a"},
+			{msg.VirtualServiceShadowed, "VirtualService/default/bookinfo-api"},
+			{msg.VirtualServiceShadowed, "VirtualService/team-c/details-public"},
+		},
+	},
+	{
+		name: "destinationRuleConflicts",
+		inputFiles: []string{
+			"testdata/destinationrule_conflicts.yaml",
+		},
+		analyzer: &destinationrule.ConflictAnalyzer{},
+		expected: []message{
+			{msg.DestinationRuleConflict, "DestinationRule/default/reviews-lb"},
+			{msg.DestinationRuleConflict, "DestinationRule/default/reviews-subsets"},
+			{msg.DestinationRuleConflict, "DestinationRule/default/default-2"},
+		},
+	},
 	{
 		name: "istioInjection",
 		inputFiles: []string{
@@ -88,6 +115,18 @@ var testGrid = []testCase{
 			{msg.PodMissingProxy, "Pod/default/noninjectedpod"},
 		},
 	},
+	{
+		name: "hostOwnership",
+		inputFiles: []string{
+			"testdata/host_ownership.yaml",
+		},
+		analyzer: &ownership.HostAnalyzer{},
+		expected: []message{
+			{msg.HostNotOwned, "VirtualService/frontend/reviews"},
+			{msg.HostNotOwned, "VirtualService/frontend/bookinfo-wildcard"},
+			{msg.HostNotOwned, "DestinationRule/frontend/reviews"},
+		},
+	},
 }
 
 // TestAnalyzers allows for table-based testing of Analyzers.
@@ -0,0 +1,126 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destinationrule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/galley/pkg/config/collection"
+	"istio.io/istio/galley/pkg/config/processor/metadata"
+	"istio.io/istio/galley/pkg/config/resource"
+)
+
+// ConflictAnalyzer checks for destination rules whose settings are ignored because another
+// destination rule of the same host and namespace already sets them.
+//
+// The destination rules of the same host and namespace are merged field by field, the oldest taking
+// precedence for each top level setting of the traffic policy and for each subset. Only the oldest
+// default destination rule, of host "*", of a namespace applies. The rules of different namespaces
+// are inherited from one another by design and are not reported.
+type ConflictAnalyzer struct{}
+
+var _ analysis.Analyzer = &ConflictAnalyzer{}
+
+// Metadata implements Analyzer
+func (c *ConflictAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name: "destinationrule.ConflictAnalyzer",
+		Inputs: collection.Names{
+			metadata.IstioNetworkingV1Alpha3Destinationrules,
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (c *ConflictAnalyzer) Analyze(ctx analysis.Context) {
+	var entries []*resource.Entry
+	ctx.ForEach(metadata.IstioNetworkingV1Alpha3Destinationrules, func(r *resource.Entry) bool {
+		entries = append(entries, r)
+		return true
+	})
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Metadata.CreateTime.Equal(entries[j].Metadata.CreateTime) {
+			return entries[i].Metadata.CreateTime.Before(entries[j].Metadata.CreateTime)
+		}
+		return entries[i].Metadata.Name.String() < entries[j].Metadata.Name.String()
+	})
+
+	// The first destination rule, and the destination rule setting each setting, of each host of
+	// each namespace.
+	firstBy := map[string]*resource.Entry{}
+	setBy := map[string]map[string]*resource.Entry{}
+	for _, r := range entries {
+		dr := r.Item.(*v1alpha3.DestinationRule)
+		ns, _ := r.Metadata.Name.InterpretAsNamespaceAndName()
+		h := resolveHost(ns, dr.Host)
+		key := ns + "/" + h
+		first, f := firstBy[key]
+		if !f {
+			firstBy[key] = r
+			setBy[key] = map[string]*resource.Entry{}
+		}
+		for _, setting := range settings(dr) {
+			prev, f := setBy[key][setting]
+			if !f && h == "*" && first != nil {
+				// Only the oldest default destination rule of a namespace applies.
+				prev, f = first, true
+			}
+			if f {
+				ctx.Report(metadata.IstioNetworkingV1Alpha3Destinationrules,
+					msg.NewDestinationRuleConflict(r, setting, h, prev.Metadata.Name.String()))
+				continue
+			}
+			setBy[key][setting] = r
+		}
+	}
+}
+
+// settings returns the names of the top level traffic policy settings and of the subsets the
+// destination rule sets.
+func settings(dr *v1alpha3.DestinationRule) []string {
+	var out []string
+	if p := dr.TrafficPolicy; p != nil {
+		if p.LoadBalancer != nil {
+			out = append(out, "loadBalancer")
+		}
+		if p.ConnectionPool != nil {
+			out = append(out, "connectionPool")
+		}
+		if p.OutlierDetection != nil {
+			out = append(out, "outlierDetection")
+		}
+		if p.Tls != nil {
+			out = append(out, "tls")
+		}
+	}
+	for _, subset := range dr.Subsets {
+		out = append(out, fmt.Sprintf("subset %s", subset.Name))
+	}
+	return out
+}
+
+// resolveHost returns the FQDN of the short names of services of the namespace.
+func resolveHost(namespace, h string) string {
+	if strings.Contains(h, ".") || h == "*" {
+		return h
+	}
+	return h + "." + namespace + ".svc.cluster.local"
+}
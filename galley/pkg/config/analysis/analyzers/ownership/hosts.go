@@ -0,0 +1,154 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ownership
+
+import (
+	"sort"
+	"strings"
+
+	"istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/galley/pkg/config/collection"
+	"istio.io/istio/galley/pkg/config/processor/metadata"
+	"istio.io/istio/galley/pkg/config/resource"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+)
+
+// HostAnalyzer checks for virtual services and destination rules configuring the hosts of services
+// of other namespaces, which Pilot ignores when host ownership is enforced.
+//
+// A host is owned by the namespaces of its services and service entries, which can allow other
+// namespaces to configure it with their networking.istio.io/configWriters annotation. The services
+// are read from their synthetic service entries, whose hosts carry the domain configured in Galley.
+type HostAnalyzer struct{}
+
+var _ analysis.Analyzer = &HostAnalyzer{}
+
+// defaultDomain is the domain of the services when none is known, such as when there are no services.
+const defaultDomain = "cluster.local"
+
+// owner is a namespace owning a host, with the namespaces it allows to configure it.
+type owner struct {
+	namespace string
+	writers   map[string]bool
+}
+
+// Metadata implements Analyzer
+func (a *HostAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name: "ownership.HostAnalyzer",
+		Inputs: collection.Names{
+			metadata.IstioNetworkingV1Alpha3SyntheticServiceentries,
+			metadata.IstioNetworkingV1Alpha3Serviceentries,
+			metadata.IstioNetworkingV1Alpha3Virtualservices,
+			metadata.IstioNetworkingV1Alpha3Destinationrules,
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *HostAnalyzer) Analyze(ctx analysis.Context) {
+	owners := map[host.Name][]owner{}
+	domain := defaultDomain
+	ctx.ForEach(metadata.IstioNetworkingV1Alpha3SyntheticServiceentries, func(r *resource.Entry) bool {
+		ns, name := r.Metadata.Name.InterpretAsNamespaceAndName()
+		for _, h := range r.Item.(*v1alpha3.ServiceEntry).Hosts {
+			if prefix := name + "." + ns + ".svc."; strings.HasPrefix(h, prefix) {
+				domain = strings.TrimPrefix(h, prefix)
+			}
+			owners[host.Name(h)] = append(owners[host.Name(h)], newOwner(ns, r))
+		}
+		return true
+	})
+	ctx.ForEach(metadata.IstioNetworkingV1Alpha3Serviceentries, func(r *resource.Entry) bool {
+		ns, _ := r.Metadata.Name.InterpretAsNamespaceAndName()
+		for _, h := range r.Item.(*v1alpha3.ServiceEntry).Hosts {
+			h := resolveHost(ns, domain, h)
+			owners[h] = append(owners[h], newOwner(ns, r))
+		}
+		return true
+	})
+
+	ctx.ForEach(metadata.IstioNetworkingV1Alpha3Virtualservices, func(r *resource.Entry) bool {
+		analyzeHosts(ctx, metadata.IstioNetworkingV1Alpha3Virtualservices, r, owners, domain,
+			r.Item.(*v1alpha3.VirtualService).Hosts)
+		return true
+	})
+	ctx.ForEach(metadata.IstioNetworkingV1Alpha3Destinationrules, func(r *resource.Entry) bool {
+		analyzeHosts(ctx, metadata.IstioNetworkingV1Alpha3Destinationrules, r, owners, domain,
+			[]string{r.Item.(*v1alpha3.DestinationRule).Host})
+		return true
+	})
+}
+
+func newOwner(namespace string, r *resource.Entry) owner {
+	o := owner{namespace: namespace, writers: map[string]bool{}}
+	for _, ns := range strings.Split(r.Metadata.Annotations[constants.ConfigWritersAnnotation], ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			o.writers[ns] = true
+		}
+	}
+	return o
+}
+
+// analyzeHosts reports the hosts of the resource which its namespace may not configure.
+func analyzeHosts(ctx analysis.Context, col collection.Name, r *resource.Entry, owners map[host.Name][]owner,
+	domain string, hosts []string) {
+	ns, _ := r.Metadata.Name.InterpretAsNamespaceAndName()
+	for _, h := range hosts {
+		h := resolveHost(ns, domain, h)
+		hostnames := []host.Name{h}
+		if len(h) > 0 && h[0] == '*' {
+			hostnames = hostnames[:0]
+			for hostname := range owners {
+				if hostname.SubsetOf(h) {
+					hostnames = append(hostnames, hostname)
+				}
+			}
+			sort.Slice(hostnames, func(i, j int) bool { return hostnames[i] < hostnames[j] })
+		}
+		for _, hostname := range hostnames {
+			if o, f := unauthorizedOwner(ns, owners[hostname]); f {
+				ctx.Report(col, msg.NewHostNotOwned(r, string(hostname), o.namespace))
+				break
+			}
+		}
+	}
+}
+
+// unauthorizedOwner returns an owner of a host if none of its owners allows the namespace to
+// configure it.
+func unauthorizedOwner(namespace string, owners []owner) (owner, bool) {
+	for _, o := range owners {
+		if o.namespace == namespace || o.writers[namespace] || o.writers["*"] {
+			return owner{}, false
+		}
+	}
+	if len(owners) == 0 {
+		return owner{}, false
+	}
+	return owners[0], true
+}
+
+// resolveHost returns the FQDN of the short names of services of the namespace.
+func resolveHost(namespace, domain, h string) host.Name {
+	if strings.Contains(h, ".") || h == "*" {
+		return host.Name(h)
+	}
+	return host.Name(h + "." + namespace + ".svc." + domain)
+}
@@ -0,0 +1,204 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package virtualservice
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/galley/pkg/config/collection"
+	"istio.io/istio/galley/pkg/config/processor/metadata"
+	"istio.io/istio/galley/pkg/config/resource"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/visibility"
+)
+
+// ConflictAnalyzer checks for virtual services whose routes are shadowed by the virtual services
+// of the same hosts which take precedence.
+//
+// Virtual services take precedence by descending route priority, then by creation time. On a
+// sidecar only the first virtual service of a host applies. On a gateway the routes of the virtual
+// services are merged in order, so the routes following a catch-all route never match. Virtual
+// services only conflict when they are exported to a common namespace.
+type ConflictAnalyzer struct{}
+
+var _ analysis.Analyzer = &ConflictAnalyzer{}
+
+// Metadata implements Analyzer
+func (c *ConflictAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name: "virtualservice.ConflictAnalyzer",
+		Inputs: collection.Names{
+			metadata.IstioNetworkingV1Alpha3Virtualservices,
+		},
+	}
+}
+
+// gatewayHost is a host of the virtual services bound to a gateway.
+type gatewayHost struct {
+	gateway string
+	host    string
+}
+
+// Analyze implements Analyzer
+func (c *ConflictAnalyzer) Analyze(ctx analysis.Context) {
+	var entries []*resource.Entry
+	ctx.ForEach(metadata.IstioNetworkingV1Alpha3Virtualservices, func(r *resource.Entry) bool {
+		entries = append(entries, r)
+		return true
+	})
+	sort.SliceStable(entries, func(i, j int) bool {
+		pi, pj := routePriority(entries[i]), routePriority(entries[j])
+		if pi != pj {
+			return pi > pj
+		}
+		if !entries[i].Metadata.CreateTime.Equal(entries[j].Metadata.CreateTime) {
+			return entries[i].Metadata.CreateTime.Before(entries[j].Metadata.CreateTime)
+		}
+		return entries[i].Metadata.Name.String() < entries[j].Metadata.Name.String()
+	})
+
+	// The virtual services with a catch-all route, or any virtual service on sidecars, of each host,
+	// in order of precedence.
+	shadowing := map[gatewayHost][]*resource.Entry{}
+	for _, r := range entries {
+		vs := r.Item.(*v1alpha3.VirtualService)
+		ns, _ := r.Metadata.Name.InterpretAsNamespaceAndName()
+		gateways := vs.Gateways
+		if len(gateways) == 0 {
+			gateways = []string{constants.IstioMeshGateway}
+		}
+		for _, gw := range gateways {
+			if gw != constants.IstioMeshGateway && !strings.Contains(gw, "/") {
+				gw = ns + "/" + gw
+			}
+			for _, h := range vs.Hosts {
+				key := gatewayHost{gateway: gw, host: hostKey(ns, h)}
+				if first := firstVisible(shadowing[key], r); first != nil {
+					ctx.Report(metadata.IstioNetworkingV1Alpha3Virtualservices,
+						msg.NewVirtualServiceShadowed(r, h, gw, first.Metadata.Name.String()))
+					continue
+				}
+				if gw == constants.IstioMeshGateway || hasCatchAllRoute(vs) {
+					shadowing[key] = append(shadowing[key], r)
+				}
+			}
+		}
+	}
+}
+
+// firstVisible returns the first of the virtual services which applies to the proxies of a namespace
+// along with the given one, or nil.
+func firstVisible(entries []*resource.Entry, r *resource.Entry) *resource.Entry {
+	public, namespaces := exportedTo(r)
+	for _, e := range entries {
+		ePublic, eNamespaces := exportedTo(e)
+		switch {
+		case public && ePublic:
+			return e
+		case public:
+			if len(eNamespaces) > 0 {
+				return e
+			}
+		case ePublic:
+			if len(namespaces) > 0 {
+				return e
+			}
+		default:
+			for ns := range namespaces {
+				if eNamespaces[ns] {
+					return e
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// exportedTo returns whether the virtual service is exported to all the namespaces and, if it is not,
+// the namespaces it is exported to. Virtual services without exportTo are assumed to be exported to
+// all the namespaces, which is the default of the mesh config.
+func exportedTo(r *resource.Entry) (bool, map[string]bool) {
+	ns, _ := r.Metadata.Name.InterpretAsNamespaceAndName()
+	exportTo := r.Item.(*v1alpha3.VirtualService).ExportTo
+	if len(exportTo) == 0 {
+		return true, nil
+	}
+	namespaces := map[string]bool{}
+	for _, e := range exportTo {
+		switch visibility.Instance(e) {
+		case visibility.Public:
+			return true, nil
+		case visibility.None:
+		case visibility.Private:
+			namespaces[ns] = true
+		default:
+			namespaces[e] = true
+		}
+	}
+	return false, namespaces
+}
+
+// routePriority returns the priority of the virtual service set by its annotation, or 0.
+func routePriority(r *resource.Entry) int {
+	priority, err := strconv.Atoi(r.Metadata.Annotations[constants.RoutePriorityAnnotation])
+	if err != nil {
+		return 0
+	}
+	return priority
+}
+
+// hostKey returns the host qualified with the namespace of the virtual service for short names.
+func hostKey(namespace, host string) string {
+	if ns, name := getNamespaceAndNameFromFQDN(host); ns != "" {
+		return ns + "/" + name
+	}
+	if strings.Contains(host, ".") || host == "*" {
+		return host
+	}
+	return namespace + "/" + host
+}
+
+// hasCatchAllRoute returns whether an HTTP route of the virtual service matches all the requests.
+func hasCatchAllRoute(vs *v1alpha3.VirtualService) bool {
+	for _, route := range vs.Http {
+		if len(route.Match) == 0 {
+			return true
+		}
+		for _, m := range route.Match {
+			if isCatchAllMatch(m) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isCatchAllMatch(m *v1alpha3.HTTPMatchRequest) bool {
+	if m.Scheme != nil || m.Method != nil || m.Authority != nil || len(m.Headers) > 0 ||
+		m.Port != 0 || len(m.SourceLabels) > 0 || len(m.Gateways) > 0 || len(m.QueryParams) > 0 {
+		return false
+	}
+	if m.Uri == nil {
+		return true
+	}
+	prefix, ok := m.Uri.MatchType.(*v1alpha3.StringMatch_Prefix)
+	return ok && (prefix.Prefix == "/" || prefix.Prefix == "")
+}
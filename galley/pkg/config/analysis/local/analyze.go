@@ -35,7 +35,8 @@ import (
 	"istio.io/istio/galley/pkg/source/kube/client"
 )
 
-const domainSuffix = "svc.local"
+// domainSuffix is the domain of the services, which is the default domain of Galley.
+const domainSuffix = "cluster.local"
 
 // Patch table
 var (
@@ -36,6 +36,18 @@ var (
 	// PodMissingProxy defines a diag.MessageType for message "PodMissingProxy".
 	// Description: A pod is missing the Istio proxy.
 	PodMissingProxy = diag.NewMessageType(diag.Warning, "IST0103", "The pod is missing its Istio proxy. Run 'kubectl delete pod %s -n %s' to restart it")
+
+	// VirtualServiceShadowed defines a diag.MessageType for message "VirtualServiceShadowed".
+	// Description: The routes of a virtual service are shadowed by another virtual service of the same host.
+	VirtualServiceShadowed = diag.NewMessageType(diag.Warning, "IST0104", "The routes of host %q on gateway %q are shadowed by virtual service %q, which takes precedence")
+
+	// HostNotOwned defines a diag.MessageType for message "HostNotOwned".
+	// Description: A virtual service or destination rule configures a host owned by another namespace.
+	HostNotOwned = diag.NewMessageType(diag.Warning, "IST0105", "Host %q is owned by namespace %q, which does not allow this namespace to configure it. The resource is ignored when host ownership is enforced")
+
+	// DestinationRuleConflict defines a diag.MessageType for message "DestinationRuleConflict".
+	// Description: A destination rule sets a traffic policy setting or a subset already set by another destination rule of the same host, which takes precedence.
+	DestinationRuleConflict = diag.NewMessageType(diag.Warning, "IST0106", "The %s of host %q is already set by destination rule %q, which takes precedence. This setting is ignored")
 )
 
 // NewInternalError returns a new diag.Message based on InternalError.
@@ -104,6 +116,38 @@ func NewPodMissingProxy(entry *resource.Entry, pod string, namespace string) dia
 	)
 }
 
+// NewVirtualServiceShadowed returns a new diag.Message based on VirtualServiceShadowed.
+func NewVirtualServiceShadowed(entry *resource.Entry, host string, gateway string, virtualservice string) diag.Message {
+	return diag.NewMessage(
+		VirtualServiceShadowed,
+		originOrNil(entry),
+		host,
+		gateway,
+		virtualservice,
+	)
+}
+
+// NewHostNotOwned returns a new diag.Message based on HostNotOwned.
+func NewHostNotOwned(entry *resource.Entry, host string, owner string) diag.Message {
+	return diag.NewMessage(
+		HostNotOwned,
+		originOrNil(entry),
+		host,
+		owner,
+	)
+}
+
+// NewDestinationRuleConflict returns a new diag.Message based on DestinationRuleConflict.
+func NewDestinationRuleConflict(entry *resource.Entry, setting string, host string, destinationrule string) diag.Message {
+	return diag.NewMessage(
+		DestinationRuleConflict,
+		originOrNil(entry),
+		setting,
+		host,
+		destinationrule,
+	)
+}
+
 func originOrNil(e *resource.Entry) resource.Origin {
 	var o resource.Origin
 	if e != nil {
@@ -0,0 +1,339 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"istio.io/istio/galley/pkg/config/event"
+	"istio.io/istio/galley/pkg/config/schema"
+	"istio.io/istio/galley/pkg/config/scope"
+	"istio.io/istio/galley/pkg/config/source/kube/inmemory"
+)
+
+var (
+	supportedExtensions = map[string]bool{
+		".yaml": true,
+		".yml":  true,
+	}
+)
+
+const (
+	// DefaultBranch is the branch synced when none is specified.
+	DefaultBranch = "master"
+
+	// DefaultPollInterval is the interval between two fetches of the repository.
+	DefaultPollInterval = time.Minute
+)
+
+// Options for the git source.
+type Options struct {
+	// Repository is the URL of the git repository, in any form accepted by git clone.
+	Repository string
+
+	// Branch to sync.
+	Branch string
+
+	// Path of the directory within the repository holding the configuration. The whole repository is
+	// read when empty.
+	Path string
+
+	// PollInterval is the interval between two fetches of the repository. Polling is disabled when zero,
+	// in which case the repository is only fetched when the webhook is called.
+	PollInterval time.Duration
+
+	// WebhookAddress is the address of an HTTP server triggering an immediate fetch on POST, e.g. from
+	// the push webhook of the git hosting service. The webhook is disabled when empty.
+	WebhookAddress string
+
+	// Dir is the local directory the repository is cloned into. An existing clone in this directory is
+	// reused. A temporary directory is used when empty.
+	Dir string
+}
+
+// DefaultOptions returns the default options for the git source.
+func DefaultOptions() *Options {
+	return &Options{
+		Branch:       DefaultBranch,
+		PollInterval: DefaultPollInterval,
+	}
+}
+
+// runGit runs a git command in the given directory and returns its standard output.
+var runGit = func(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+type source struct {
+	mu      sync.Mutex
+	options Options
+	s       *inmemory.KubeSource
+
+	// dir is the local clone of the repository, empty until the first successful clone.
+	dir string
+	// revision is the commit the current content was read from.
+	revision string
+	started  bool
+
+	trigger chan struct{}
+	done    chan struct{}
+	webhook *http.Server
+}
+
+var _ event.Source = &source{}
+
+// New returns a new event.Source that reads the configuration from a git repository.
+func New(o Options, resources schema.KubeResources) (event.Source, error) {
+	if o.Repository == "" {
+		return nil, errors.New("git repository not specified")
+	}
+	if o.Branch == "" {
+		o.Branch = DefaultBranch
+	}
+	if strings.Contains(filepath.Clean(o.Path), "..") {
+		return nil, fmt.Errorf("invalid path within the git repository: %q", o.Path)
+	}
+
+	return &source{
+		options: o,
+		s:       inmemory.NewKubeSource(resources),
+	}, nil
+}
+
+// Start implements event.Source
+func (s *source) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done != nil {
+		return
+	}
+	done := make(chan struct{})
+	s.done = done
+	trigger := make(chan struct{}, 1)
+	s.trigger = trigger
+
+	if s.options.WebhookAddress != "" {
+		if err := s.startWebhook(); err != nil {
+			scope.Source.Errorf("[git] Unable to start the webhook: %v", err)
+		}
+	}
+
+	go func() {
+		var tick <-chan time.Time
+		if s.options.PollInterval > 0 {
+			ticker := time.NewTicker(s.options.PollInterval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		s.sync()
+		for {
+			select {
+			case <-tick:
+				s.sync()
+			case <-trigger:
+				scope.Source.Infof("[git] Fetching %s in response to the webhook", s.options.Repository)
+				s.sync()
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop implements event.Source.
+func (s *source) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done == nil {
+		return
+	}
+	close(s.done)
+	s.done = nil
+	if s.webhook != nil {
+		if err := s.webhook.Close(); err != nil {
+			scope.Source.Errorf("[git] Webhook server terminated with error: %v", err)
+		}
+		s.webhook = nil
+	}
+	if s.started {
+		s.s.Stop()
+		s.s.Clear()
+		s.started = false
+	}
+	s.revision = ""
+}
+
+// Dispatch implements event.Source
+func (s *source) Dispatch(h event.Handler) {
+	s.s.Dispatch(h)
+}
+
+func (s *source) startWebhook() error {
+	l, err := net.Listen("tcp", s.options.WebhookAddress)
+	if err != nil {
+		return err
+	}
+	trigger := s.trigger
+	s.webhook = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		// A fetch is already pending if the channel is full.
+		select {
+		case trigger <- struct{}{}:
+		default:
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})}
+	go func(server *http.Server) {
+		if err := server.Serve(l); err != nil {
+			scope.Source.Debugf("[git] Webhook server done: %v", err)
+		}
+	}(s.webhook)
+	return nil
+}
+
+// sync fetches the branch and reloads the content when the revision changed. The in-memory source is
+// only started after the first successful sync, so that downstream consumers never see an empty
+// configuration because the repository is unreachable.
+func (s *source) sync() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done == nil {
+		return
+	}
+
+	revision, err := s.fetch()
+	if err != nil {
+		scope.Source.Errorf("[git] Unable to fetch %s (branch %s): %v", s.options.Repository, s.options.Branch, err)
+		return
+	}
+	if revision == s.revision {
+		return
+	}
+
+	scope.Source.Infof("[git] Loading %s at revision %s", s.options.Repository, revision)
+	if err := s.reload(); err != nil {
+		scope.Source.Errorf("[git] Error reloading revision %s: %v", revision, err)
+		return
+	}
+	s.revision = revision
+	if !s.started {
+		s.s.Start()
+		s.started = true
+	}
+}
+
+// fetch updates the local clone to the tip of the branch and returns its revision.
+func (s *source) fetch() (string, error) {
+	if s.dir == "" {
+		dir, tmp := s.options.Dir, ""
+		if dir == "" {
+			var err error
+			if tmp, err = ioutil.TempDir("", "galley-git"); err != nil {
+				return "", err
+			}
+			dir = filepath.Join(tmp, "repository")
+		} else if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			// Reuse an existing clone, e.g. on a persistent volume.
+			s.dir = dir
+			return s.fetch()
+		}
+		// The repository is passed after "--", so that it is never parsed as an option of git.
+		if _, err := runGit("", "clone", "--quiet", "--depth", "1", "--single-branch", "--branch", s.options.Branch,
+			"--", s.options.Repository, dir); err != nil {
+			if tmp != "" {
+				// The next poll clones into a new temporary directory.
+				_ = os.RemoveAll(tmp)
+			}
+			return "", err
+		}
+		s.dir = dir
+	} else {
+		if _, err := runGit(s.dir, "fetch", "--quiet", "--depth", "1", "origin", s.options.Branch); err != nil {
+			return "", err
+		}
+		if _, err := runGit(s.dir, "reset", "--quiet", "--hard", "FETCH_HEAD"); err != nil {
+			return "", err
+		}
+	}
+	return runGit(s.dir, "rev-parse", "HEAD")
+}
+
+// reload applies the content of the configuration directory of the local clone.
+func (s *source) reload() error {
+	names := s.s.ContentNames()
+	root := filepath.Join(s.dir, s.options.Path)
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		// Symbolic links are skipped, since they could point to any file of the filesystem of Galley.
+		if !supportedExtensions[filepath.Ext(path)] || info.Mode()&os.ModeType != 0 {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		// Name the content after its path in the repository, which is stable across clones.
+		name, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		if err := s.s.ApplyContent(name, string(data)); err != nil {
+			scope.Source.Errorf("[git] Error applying file contents(%q): %v", name, err)
+		}
+		delete(names, name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for n := range names {
+		scope.Source.Infof("[git] Removing the contents of the file %q", n)
+		s.s.RemoveContent(n)
+	}
+	return nil
+}
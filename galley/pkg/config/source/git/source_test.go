@@ -0,0 +1,180 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/galley/pkg/config/event"
+	"istio.io/istio/galley/pkg/config/resource"
+	"istio.io/istio/galley/pkg/config/testing/basicmeta"
+	"istio.io/istio/galley/pkg/config/testing/data"
+	"istio.io/istio/galley/pkg/config/testing/fixtures"
+)
+
+func TestNewInvalidOptions(t *testing.T) {
+	r := basicmeta.MustGet().KubeSource().Resources()
+	if _, err := New(Options{}, r); err == nil {
+		t.Fatal("expected error without repository")
+	}
+	if _, err := New(Options{Repository: "repo", Path: "../config"}, r); err == nil {
+		t.Fatal("expected error for path outside of the repository")
+	}
+}
+
+func TestSync(t *testing.T) {
+	g := NewGomegaWithT(t)
+	origin := createOriginOrFail(t)
+	defer func() { _ = os.RemoveAll(origin) }()
+
+	commitFile(t, origin, "config/foo.yaml", data.YamlN1I1V1)
+	commitFile(t, origin, "README.md", "not a config file")
+	// Files outside of the configured path are ignored.
+	commitFile(t, origin, "other/foo.yaml", data.YamlN2I2V1)
+
+	src, err := New(Options{Repository: origin, Path: "config"}, basicmeta.MustGet().KubeSource().Resources())
+	if err != nil {
+		t.Fatalf("Unexpected error found: %v", err)
+	}
+	s := src.(*source)
+	acc := &fixtures.Accumulator{}
+	s.Dispatch(acc)
+	s.Start()
+	defer s.Stop()
+
+	g.Eventually(acc.EventsWithoutOrigins).Should(ConsistOf(
+		event.FullSyncFor(basicmeta.Collection1),
+		event.AddFor(data.Collection1, data.EntryN1I1V1)))
+
+	acc.Clear()
+	commitFile(t, origin, "config/foo.yaml", data.YamlN1I1V2)
+	s.sync()
+
+	g.Eventually(acc.EventsWithoutOrigins).Should(ConsistOf(
+		event.UpdateFor(data.Collection1, withVersion(data.EntryN1I1V2, "v2"))))
+
+	acc.Clear()
+	removeFile(t, origin, "config/foo.yaml")
+	s.sync()
+
+	g.Eventually(acc.EventsWithoutOrigins).Should(ConsistOf(
+		event.DeleteForResource(data.Collection1, withVersion(data.EntryN1I1V2, "v2"))))
+}
+
+func TestSymlinksAreIgnored(t *testing.T) {
+	g := NewGomegaWithT(t)
+	origin := createOriginOrFail(t)
+	defer func() { _ = os.RemoveAll(origin) }()
+
+	outside, err := ioutil.TempFile("", t.Name())
+	if err != nil {
+		t.Fatalf("Error creating temp file: %v", err)
+	}
+	defer func() { _ = os.Remove(outside.Name()) }()
+	if _, err := outside.WriteString(data.YamlN2I2V1); err != nil {
+		t.Fatalf("Error writing file: %v", err)
+	}
+	_ = outside.Close()
+
+	commitFile(t, origin, "config/foo.yaml", data.YamlN1I1V1)
+	if err := os.Symlink(outside.Name(), filepath.Join(origin, "config/link.yaml")); err != nil {
+		t.Fatalf("Error creating symlink: %v", err)
+	}
+	gitOrFail(t, origin, "add", "config/link.yaml")
+	gitOrFail(t, origin, "-c", "user.name=test", "-c", "user.email=test@istio.io", "commit", "--quiet", "-m", "add link")
+
+	src, err := New(Options{Repository: origin, Path: "config"}, basicmeta.MustGet().KubeSource().Resources())
+	if err != nil {
+		t.Fatalf("Unexpected error found: %v", err)
+	}
+	s := src.(*source)
+	acc := &fixtures.Accumulator{}
+	s.Dispatch(acc)
+	s.Start()
+	defer s.Stop()
+
+	g.Eventually(acc.EventsWithoutOrigins).Should(ConsistOf(
+		event.FullSyncFor(basicmeta.Collection1),
+		event.AddFor(data.Collection1, data.EntryN1I1V1)))
+}
+
+func TestUnreachableRepositoryDoesNotSync(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	src, err := New(Options{Repository: "/does/not/exist"}, basicmeta.MustGet().KubeSource().Resources())
+	if err != nil {
+		t.Fatalf("Unexpected error found: %v", err)
+	}
+	s := src.(*source)
+	acc := &fixtures.Accumulator{}
+	s.Dispatch(acc)
+	s.Start()
+	defer s.Stop()
+
+	s.sync()
+	g.Consistently(acc.EventsWithoutOrigins).Should(BeEmpty())
+}
+
+func createOriginOrFail(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", t.Name())
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %v", err)
+	}
+	gitOrFail(t, dir, "init", "--quiet")
+	gitOrFail(t, dir, "symbolic-ref", "HEAD", "refs/heads/"+DefaultBranch)
+	return dir
+}
+
+func commitFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatalf("Error creating dir: %v", err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), os.ModePerm); err != nil {
+		t.Fatalf("Error writing file: %v", err)
+	}
+	gitOrFail(t, dir, "add", name)
+	gitOrFail(t, dir, "-c", "user.name=test", "-c", "user.email=test@istio.io", "commit", "--quiet", "-m", "update "+name)
+}
+
+func removeFile(t *testing.T, dir, name string) {
+	t.Helper()
+	gitOrFail(t, dir, "rm", "--quiet", name)
+	gitOrFail(t, dir, "-c", "user.name=test", "-c", "user.email=test@istio.io", "commit", "--quiet", "-m", "remove "+name)
+}
+
+func gitOrFail(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if _, err := runGit(dir, args...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func withVersion(r *resource.Entry, v string) *resource.Entry {
+	r = r.Clone()
+	r.Metadata.Version = resource.Version(v)
+	return r
+}
@@ -0,0 +1,298 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kubeSchema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/collection"
+	"istio.io/istio/galley/pkg/config/processing/snapshotter"
+	"istio.io/istio/galley/pkg/config/resource"
+	"istio.io/istio/galley/pkg/config/schema"
+	"istio.io/istio/galley/pkg/config/scope"
+	"istio.io/istio/galley/pkg/config/source/kube"
+	"istio.io/istio/galley/pkg/config/source/kube/rt"
+)
+
+const (
+	// Field is the field of the resource status holding the analysis messages.
+	Field = "validationMessages"
+
+	// istioGroupSuffix is the suffix of the API groups of the resources whose status is updated.
+	// Other resources, e.g. pods, are owned by other controllers.
+	istioGroupSuffix = "istio.io"
+)
+
+// Message is an analysis message as written in the status of a resource.
+type Message struct {
+	Code        string `json:"code"`
+	Level       string `json:"level"`
+	Description string `json:"description"`
+}
+
+type key struct {
+	collection collection.Name
+	name       resource.Name
+}
+
+// Controller writes the messages of the analysis into the status of the offending Istio resources.
+// Status updates are applied asynchronously, so that the analysis never waits for the API server.
+type Controller struct {
+	k         kube.Interfaces
+	resources map[collection.Name]schema.KubeResource
+
+	mu      sync.Mutex
+	pending diag.Messages
+	dirty   bool
+	notify  chan struct{}
+	done    chan struct{}
+
+	// Only accessed by the worker.
+	client  dynamic.Interface
+	written map[key][]Message
+	loaded  map[collection.Name]bool
+}
+
+var _ snapshotter.StatusUpdater = &Controller{}
+
+// NewController returns a new status controller for the given resources.
+func NewController(k kube.Interfaces, resources schema.KubeResources) *Controller {
+	c := &Controller{
+		k:         k,
+		resources: make(map[collection.Name]schema.KubeResource),
+		written:   make(map[key][]Message),
+		loaded:    make(map[collection.Name]bool),
+	}
+	for _, r := range resources {
+		if strings.HasSuffix(r.Group, istioGroupSuffix) && !r.Disabled {
+			c.resources[r.Collection.Name] = r
+		}
+	}
+	return c
+}
+
+// Start the controller.
+func (c *Controller) Start() error {
+	client, err := c.k.DynamicInterface()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done != nil {
+		return nil
+	}
+	c.client = client
+	c.notify = make(chan struct{}, 1)
+	c.done = make(chan struct{})
+	go c.run(c.notify, c.done)
+	return nil
+}
+
+// Stop the controller.
+func (c *Controller) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done != nil {
+		close(c.done)
+		c.done = nil
+	}
+}
+
+// Update implements snapshotter.StatusUpdater
+func (c *Controller) Update(messages diag.Messages) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = messages
+	c.dirty = true
+	if c.notify != nil {
+		select {
+		case c.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (c *Controller) run(notify, done chan struct{}) {
+	for {
+		select {
+		case <-notify:
+			c.mu.Lock()
+			messages, dirty := c.pending, c.dirty
+			c.dirty = false
+			c.mu.Unlock()
+			if dirty {
+				c.apply(messages)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// apply writes the messages into the status of the resources, and clears the status of resources
+// without messages anymore. Resources whose messages did not change are left untouched, so that the
+// status updates do not trigger another analysis cycle.
+func (c *Controller) apply(messages diag.Messages) {
+	c.load()
+	desired := c.group(messages)
+
+	for k, msgs := range desired {
+		if reflect.DeepEqual(c.written[k], msgs) {
+			continue
+		}
+		if err := c.patch(k, msgs); err != nil {
+			scope.Source.Errorf("[status] Unable to update the status of %s %s: %v", k.collection, k.name, err)
+			continue
+		}
+		c.written[k] = msgs
+	}
+
+	for k := range c.written {
+		if _, ok := desired[k]; ok {
+			continue
+		}
+		if err := c.patch(k, nil); err != nil {
+			scope.Source.Errorf("[status] Unable to clear the status of %s %s: %v", k.collection, k.name, err)
+			continue
+		}
+		delete(c.written, k)
+	}
+}
+
+// load records the messages already in the status of the resources, e.g. written before a restart of
+// Galley, so that the first analysis clears the stale ones. The resources that cannot be listed are
+// retried at the next analysis.
+func (c *Controller) load() {
+	for col, r := range c.resources {
+		if c.loaded[col] {
+			continue
+		}
+		gvr := kubeSchema.GroupVersionResource{Group: r.Group, Version: r.Version, Resource: r.Plural}
+		list, err := c.client.Resource(gvr).List(metav1.ListOptions{})
+		if errors.IsNotFound(err) {
+			// The CRD is not installed.
+			c.loaded[col] = true
+			continue
+		}
+		if err != nil {
+			scope.Source.Errorf("[status] Unable to list %s: %v", col, err)
+			continue
+		}
+		for i := range list.Items {
+			item := &list.Items[i]
+			msgs, err := currentMessages(item)
+			if err != nil {
+				scope.Source.Warnf("[status] Ignoring the status of %s %s/%s: %v",
+					col, item.GetNamespace(), item.GetName(), err)
+				continue
+			}
+			if len(msgs) > 0 {
+				c.written[key{collection: col, name: resource.NewName(item.GetNamespace(), item.GetName())}] = msgs
+			}
+		}
+		c.loaded[col] = true
+	}
+}
+
+// currentMessages returns the messages in the status of the resource.
+func currentMessages(obj *unstructured.Unstructured) ([]Message, error) {
+	value, found, err := unstructured.NestedFieldNoCopy(obj.Object, "status", Field)
+	if err != nil || !found || value == nil {
+		return nil, err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var msgs []Message
+	if err = json.Unmarshal(data, &msgs); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// group returns the messages of each Istio resource, in a stable order.
+func (c *Controller) group(messages diag.Messages) map[key][]Message {
+	result := make(map[key][]Message)
+	for _, m := range messages {
+		origin, ok := m.Origin.(*rt.Origin)
+		if !ok {
+			continue
+		}
+		if _, ok := c.resources[origin.Collection]; !ok {
+			continue
+		}
+		k := key{collection: origin.Collection, name: origin.Name}
+		result[k] = append(result[k], Message{
+			Code:        m.Type.Code(),
+			Level:       string(m.Type.Level()),
+			Description: fmt.Sprintf(m.Type.Template(), m.Parameters...),
+		})
+	}
+	for _, msgs := range result {
+		sort.Slice(msgs, func(i, j int) bool {
+			if msgs[i].Code != msgs[j].Code {
+				return msgs[i].Code < msgs[j].Code
+			}
+			return msgs[i].Description < msgs[j].Description
+		})
+	}
+	return result
+}
+
+// patch replaces the messages in the status of the resource. The messages are removed when empty.
+func (c *Controller) patch(k key, messages []Message) error {
+	r := c.resources[k.collection]
+	var value interface{}
+	if len(messages) > 0 {
+		value = messages
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			Field: value,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	namespace, name := k.name.InterpretAsNamespaceAndName()
+	gvr := kubeSchema.GroupVersionResource{Group: r.Group, Version: r.Version, Resource: r.Plural}
+	var client dynamic.ResourceInterface = c.client.Resource(gvr)
+	if !r.ClusterScoped {
+		client = c.client.Resource(gvr).Namespace(namespace)
+	}
+	_, err = client.Patch(name, types.MergePatchType, data, metav1.UpdateOptions{})
+	if errors.IsNotFound(err) {
+		// The resource was deleted since the analysis.
+		return nil
+	}
+	return err
+}
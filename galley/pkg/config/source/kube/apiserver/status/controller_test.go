@@ -0,0 +1,168 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sRuntime "k8s.io/apimachinery/pkg/runtime"
+	kubeSchema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/galley/pkg/config/resource"
+	"istio.io/istio/galley/pkg/config/schema"
+	"istio.io/istio/galley/pkg/config/source/kube/rt"
+	"istio.io/istio/galley/pkg/config/testing/basicmeta"
+	"istio.io/istio/galley/pkg/testing/mock"
+)
+
+var gvr = kubeSchema.GroupVersionResource{Group: "networking.istio.io", Version: "v1alpha3", Resource: "virtualservices"}
+
+func virtualServiceResource() schema.KubeResource {
+	r := basicmeta.MustGet().KubeSource().Resources()[0]
+	r.Group = gvr.Group
+	r.Version = gvr.Version
+	r.Plural = gvr.Resource
+	r.Kind = "VirtualService"
+	return r
+}
+
+func newVirtualService(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "networking.istio.io/v1alpha3",
+			"kind":       "VirtualService",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": "ns",
+			},
+			"spec": map[string]interface{}{},
+		},
+	}
+}
+
+func statusMessages(cl *fake.FakeDynamicClient, name string) func() interface{} {
+	return func() interface{} {
+		obj, err := cl.Resource(gvr).Namespace("ns").Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		messages, _, _ := unstructured.NestedSlice(obj.Object, "status", Field)
+		return messages
+	}
+}
+
+func TestController(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	r := virtualServiceResource()
+	cl := fake.NewSimpleDynamicClient(k8sRuntime.NewScheme(), newVirtualService("vs"))
+	k := mock.NewKube()
+	k.AddResponse(cl, nil)
+
+	c := NewController(k, schema.KubeResources{r})
+	g.Expect(c.Start()).To(BeNil())
+	defer c.Stop()
+
+	origin := &rt.Origin{Collection: r.Collection.Name, Kind: "VirtualService", Name: resource.NewName("ns", "vs")}
+	other := &rt.Origin{Collection: basicmeta.Collection2, Kind: "Pod", Name: resource.NewName("ns", "pod")}
+	c.Update(diag.Messages{
+		diag.NewMessage(msg.ReferencedResourceNotFound, origin, "gateway", "ns/missing"),
+		diag.NewMessage(msg.PodMissingProxy, other, "pod", "ns"),
+	})
+
+	g.Eventually(statusMessages(cl, "vs")).Should(Equal([]interface{}{
+		map[string]interface{}{
+			"code":        "IST0101",
+			"level":       "Error",
+			"description": `Referenced gateway not found: "ns/missing"`,
+		},
+	}))
+
+	// The status is cleared once the resource is fixed.
+	c.Update(diag.Messages{})
+	g.Eventually(statusMessages(cl, "vs")).Should(BeNil())
+}
+
+func TestControllerClearsStaleStatus(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	r := virtualServiceResource()
+	written := []interface{}{
+		map[string]interface{}{
+			"code":        "IST0101",
+			"level":       "Error",
+			"description": `Referenced gateway not found: "ns/missing"`,
+		},
+	}
+	// Both resources have messages written before a restart, and only one of them still has them.
+	current, stale := newVirtualService("current"), newVirtualService("stale")
+	for _, vs := range []*unstructured.Unstructured{current, stale} {
+		g.Expect(unstructured.SetNestedSlice(vs.Object, written, "status", Field)).To(Succeed())
+	}
+	cl := fake.NewSimpleDynamicClient(k8sRuntime.NewScheme(), current.DeepCopy(), stale.DeepCopy())
+	cl.PrependReactor("list", gvr.Resource, func(k8stesting.Action) (bool, k8sRuntime.Object, error) {
+		return true, &unstructured.UnstructuredList{Items: []unstructured.Unstructured{*current, *stale}}, nil
+	})
+
+	c := NewController(mock.NewKube(), schema.KubeResources{r})
+	c.client = cl
+	origin := &rt.Origin{Collection: r.Collection.Name, Kind: "VirtualService", Name: resource.NewName("ns", "current")}
+	c.apply(diag.Messages{diag.NewMessage(msg.ReferencedResourceNotFound, origin, "gateway", "ns/missing")})
+
+	g.Expect(statusMessages(cl, "stale")()).To(BeNil())
+	g.Expect(statusMessages(cl, "current")()).To(Equal(written))
+	for _, a := range cl.Actions() {
+		if p, ok := a.(k8stesting.PatchAction); ok && p.GetName() == "current" {
+			t.Errorf("unexpected update of the unchanged status: %s", p.GetPatch())
+		}
+	}
+}
+
+func TestControllerDeletedResource(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	r := virtualServiceResource()
+	c := NewController(mock.NewKube(), schema.KubeResources{r})
+	c.client = fake.NewSimpleDynamicClient(k8sRuntime.NewScheme())
+	origin := &rt.Origin{Collection: r.Collection.Name, Kind: "VirtualService", Name: resource.NewName("ns", "deleted")}
+
+	// A resource deleted since the analysis is not an error, and is not tracked anymore.
+	c.apply(diag.Messages{diag.NewMessage(msg.ReferencedResourceNotFound, origin, "gateway", "ns/missing")})
+	g.Expect(c.written).To(HaveLen(1))
+	c.apply(diag.Messages{})
+	g.Expect(c.written).To(BeEmpty())
+}
+
+func TestNewControllerIgnoresNonIstioResources(t *testing.T) {
+	r := virtualServiceResource()
+	pods := basicmeta.MustGet().KubeSource().Resources()[0]
+	pods.Group = ""
+	pods.Collection.Name = basicmeta.Collection2
+
+	c := NewController(mock.NewKube(), schema.KubeResources{r, pods})
+	if _, ok := c.resources[basicmeta.Collection2]; ok {
+		t.Fatal("non Istio resources must not be updated")
+	}
+	if _, ok := c.resources[r.Collection.Name]; !ok {
+		t.Fatal("Istio resources must be updated")
+	}
+}
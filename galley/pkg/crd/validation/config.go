@@ -32,6 +32,7 @@ import (
 	"github.com/howeyc/fsnotify"
 	"k8s.io/api/admissionregistration/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
@@ -281,6 +282,51 @@ func rebuildWebhookConfigHelper(
 	return &webhookConfig, nil
 }
 
+// Register the defaulting webhook with the current CA bundle, or remove its registration when
+// defaulting is disabled.
+func (whc *WebhookConfigController) reconcileDefaulting() {
+	p := whc.webhookParameters
+	client := p.Clientset.AdmissionregistrationV1beta1().MutatingWebhookConfigurations()
+	if !p.EnableDefaulting || !p.EnableValidation {
+		if deleted, err := deleteMutatingWebhookConfigHelper(client, p.WebhookName); err != nil {
+			scope.Errorf("%v mutatingwebhookconfiguration delete failed: %v", p.WebhookName, err)
+		} else if deleted {
+			scope.Infof("%v mutatingwebhookconfiguration deleted", p.WebhookName)
+		}
+		return
+	}
+
+	in, err := os.Open(p.CACertFile)
+	if err != nil {
+		scope.Errorf("mutatingwebhookconfiguration update failed: could not read ca bundle from %v: %v", p.CACertFile, err)
+		return
+	}
+	defer in.Close() // nolint: errcheck
+
+	caPem, err := loadCaCertPem(in)
+	if err != nil {
+		scope.Errorf("mutatingwebhookconfiguration update failed: %v", err)
+		return
+	}
+	config := buildDefaultingWebhookConfig(p, caPem, whc.ownerRefs)
+	if updated, err := createOrUpdateMutatingWebhookConfigHelper(client, config); err != nil {
+		scope.Errorf("%v mutatingwebhookconfiguration update failed: %v", config.Name, err)
+	} else if updated {
+		scope.Infof("%v mutatingwebhookconfiguration updated", config.Name)
+	}
+}
+
+// Serve the Istio CRDs at all the API versions of their group.
+func (whc *WebhookConfigController) reconcileConversion() {
+	p := whc.webhookParameters
+	if !p.EnableConversion || p.APIExtensionsClientset == nil {
+		return
+	}
+	if err := reconcileCRDConversion(p.APIExtensionsClientset); err != nil {
+		scope.Errorf("CRD conversion update failed: %v", err)
+	}
+}
+
 // Reload the server's cert/key for TLS from file.
 func (whc *WebhookConfigController) reloadKeyCert() {
 	pair, err := tls.LoadX509KeyPair(whc.webhookParameters.CertFile, whc.webhookParameters.KeyFile)
@@ -372,6 +418,8 @@ func (whc *WebhookConfigController) reconcile(stopCh <-chan struct{}) {
 	// the desired configuration.
 	if err := whc.rebuildWebhookConfig(); err == nil {
 		whc.createOrUpdateWebhookConfig()
+		whc.reconcileDefaulting()
+		whc.reconcileConversion()
 	}
 	webhookChangedCh := whc.monitorWebhookChanges(stopCh)
 
@@ -391,6 +439,8 @@ func (whc *WebhookConfigController) reconcile(stopCh <-chan struct{}) {
 			// existing configuration.
 			if err := whc.rebuildWebhookConfig(); err == nil {
 				whc.createOrUpdateWebhookConfig()
+				whc.reconcileDefaulting()
+				whc.reconcileConversion()
 			}
 		case <-webhookChangedCh:
 			if whc.webhookParameters.EnableValidation {
@@ -426,6 +476,15 @@ func ReconcileWebhookConfiguration(webhookServerReady, stopCh <-chan struct{},
 		log.Fatalf("could not create k8s clientset: %v", err)
 	}
 	vc.Clientset = clientset
+	if vc.EnableConversion {
+		restConfig, err := kube.BuildClientConfig(kubeConfig, "")
+		if err != nil {
+			log.Fatalf("could not create k8s client config: %v", err)
+		}
+		if vc.APIExtensionsClientset, err = apiextensionsclient.NewForConfig(restConfig); err != nil {
+			log.Fatalf("could not create apiextensions clientset: %v", err)
+		}
+	}
 
 	whc, err := NewWebhookConfigController(*vc)
 	if err != nil {
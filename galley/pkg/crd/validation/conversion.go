@@ -0,0 +1,98 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/schemas"
+)
+
+// conversionVersions lists the versions served for each API group. All the versions of a group
+// share the same schema, so converting a resource only rewrites its apiVersion and round-trips
+// between versions are lossless.
+var conversionVersions = map[string][]string{
+	"networking.istio.io": {"v1alpha3", "v1beta1"},
+}
+
+// conversionCRDNames returns the names of the Istio CRDs served at multiple versions.
+func conversionCRDNames() []string {
+	var names []string
+	for _, s := range schemas.Istio {
+		group := s.Group + constants.IstioAPIGroupDomain
+		if len(conversionVersions[group]) > 1 {
+			names = append(names, s.Plural+"."+group)
+		}
+	}
+	return names
+}
+
+// reconcileCRDConversion serves the Istio CRDs at all the versions of their group. As converting
+// only rewrites the apiVersion, the CRDs use the None strategy, where the API server does the
+// same. A conversion webhook would need a structural schema, which the Istio CRDs do not have.
+// The version storing the resources is left unchanged, so that existing resources remain readable.
+func reconcileCRDConversion(client apiextensionsclient.Interface) error {
+	conversion := &apiextensionsv1beta1.CustomResourceConversion{Strategy: apiextensionsv1beta1.NoneConverter}
+	for _, name := range conversionCRDNames() {
+		crd, err := client.ApiextensionsV1beta1().CustomResourceDefinitions().Get(name, v1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("could not get CRD %s: %v", name, err)
+		}
+
+		versions := append([]apiextensionsv1beta1.CustomResourceDefinitionVersion(nil), crd.Spec.Versions...)
+		for _, version := range conversionVersions[crd.Spec.Group] {
+			found := false
+			for i := range versions {
+				if versions[i].Name == version {
+					versions[i].Served = true
+					found = true
+				}
+			}
+			if !found {
+				versions = append(versions, apiextensionsv1beta1.CustomResourceDefinitionVersion{Name: version, Served: true})
+			}
+		}
+
+		if reflect.DeepEqual(crd.Spec.Versions, versions) && reflect.DeepEqual(crd.Spec.Conversion, conversion) {
+			continue
+		}
+		// The CRD is patched rather than updated, so that the fields of the CRD unknown to this client
+		// are kept.
+		patch, err := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{
+			"versions":   versions,
+			"conversion": map[string]interface{}{"strategy": conversion.Strategy, "webhookClientConfig": nil},
+		}})
+		if err != nil {
+			return fmt.Errorf("could not encode the patch of CRD %s: %v", name, err)
+		}
+		if _, err := client.ApiextensionsV1beta1().CustomResourceDefinitions().Patch(name, types.MergePatchType, patch); err != nil {
+			return fmt.Errorf("could not update CRD %s: %v", name, err)
+		}
+		scope.Infof("%s served at versions %v", name, conversionVersions[crd.Spec.Group])
+	}
+	return nil
+}
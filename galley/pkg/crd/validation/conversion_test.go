@@ -0,0 +1,85 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReconcileCRDConversion(t *testing.T) {
+	crd := &apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "virtualservices.networking.istio.io"},
+		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
+			Group: "networking.istio.io",
+			Versions: []apiextensionsv1beta1.CustomResourceDefinitionVersion{
+				{Name: "v1alpha3", Served: true, Storage: true},
+			},
+		},
+	}
+	// security.istio.io is only served at v1beta1, so its CRDs are left unchanged.
+	security := &apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "authorizationpolicies.security.istio.io"},
+		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
+			Group: "security.istio.io",
+			Versions: []apiextensionsv1beta1.CustomResourceDefinitionVersion{
+				{Name: "v1beta1", Served: true, Storage: true},
+			},
+		},
+	}
+	client := fake.NewSimpleClientset(crd, security)
+
+	if err := reconcileCRDConversion(client); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := client.ApiextensionsV1beta1().CustomResourceDefinitions().Get(crd.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantVersions := []apiextensionsv1beta1.CustomResourceDefinitionVersion{
+		{Name: "v1alpha3", Served: true, Storage: true},
+		{Name: "v1beta1", Served: true},
+	}
+	if !reflect.DeepEqual(got.Spec.Versions, wantVersions) {
+		t.Errorf("got versions %+v, want %+v", got.Spec.Versions, wantVersions)
+	}
+	if conversion := got.Spec.Conversion; conversion == nil || conversion.Strategy != apiextensionsv1beta1.NoneConverter ||
+		conversion.WebhookClientConfig != nil {
+		t.Errorf("got conversion %+v, want the None strategy", conversion)
+	}
+
+	got, err = client.ApiextensionsV1beta1().CustomResourceDefinitions().Get(security.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Spec, security.Spec) {
+		t.Errorf("got spec %+v, want it unchanged", got.Spec)
+	}
+
+	// A second reconciliation is a no-op.
+	client.ClearActions()
+	if err := reconcileCRDConversion(client); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "patch" {
+			t.Errorf("unexpected patch of an up-to-date CRD: %v", action)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	networking "istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/schemas"
+)
+
+// Modes of the cross-resource validation, which checks resources against the configuration
+// already present in the cluster.
+const (
+	// CrossResourceValidationOff disables cross-resource validation.
+	CrossResourceValidationOff = "off"
+	// CrossResourceValidationWarn logs violations and admits the resource.
+	CrossResourceValidationWarn = "warn"
+	// CrossResourceValidationEnforce rejects resources with violations.
+	CrossResourceValidationEnforce = "enforce"
+)
+
+// crossResourceValidator checks that Istio resources are consistent with the configuration
+// already present in the cluster.
+type crossResourceValidator struct {
+	store     model.ConfigStore
+	clientset clientset.Interface
+}
+
+// validate returns the violations of the given resource. Nothing is checked until the store
+// has synced, as resources referring to configuration not synced yet would be rejected.
+func (v *crossResourceValidator) validate(cfg *model.Config) error {
+	if cache, ok := v.store.(model.ConfigStoreCache); ok && !cache.HasSynced() {
+		scope.Debugf("skipped cross-resource validation of %s %s/%s: config store not synced",
+			cfg.Type, cfg.Namespace, cfg.Name)
+		return nil
+	}
+	switch cfg.Type {
+	case schemas.VirtualService.Type:
+		return v.validateVirtualService(cfg)
+	case schemas.DestinationRule.Type:
+		return v.validateDestinationRule(cfg)
+	case schemas.Gateway.Type:
+		return v.validateGateway(cfg)
+	}
+	return nil
+}
+
+// validateVirtualService checks that the gateways the virtual service binds to exist.
+func (v *crossResourceValidator) validateVirtualService(cfg *model.Config) error {
+	vs, ok := cfg.Spec.(*networking.VirtualService)
+	if !ok {
+		return nil
+	}
+
+	gateways := append([]string{}, vs.Gateways...)
+	for _, route := range vs.Http {
+		for _, m := range route.Match {
+			gateways = append(gateways, m.Gateways...)
+		}
+	}
+	for _, route := range vs.Tls {
+		for _, m := range route.Match {
+			gateways = append(gateways, m.Gateways...)
+		}
+	}
+	for _, route := range vs.Tcp {
+		for _, m := range route.Match {
+			gateways = append(gateways, m.Gateways...)
+		}
+	}
+
+	var errs error
+	seen := map[string]bool{}
+	for _, gw := range gateways {
+		if gw == constants.IstioMeshGateway {
+			continue
+		}
+		key := model.ResolveGatewayName(gw, cfg.ConfigMeta)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		parts := strings.SplitN(key, "/", 2)
+		if len(parts) != 2 || v.store.Get(schemas.Gateway.Type, parts[1], parts[0]) == nil {
+			errs = multierror.Append(errs, fmt.Errorf("referenced gateway %q not found", gw))
+		}
+	}
+	return errs
+}
+
+// validateDestinationRule checks that the host of the destination rule is a Kubernetes service
+// or is declared by a service entry.
+func (v *crossResourceValidator) validateDestinationRule(cfg *model.Config) error {
+	rule, ok := cfg.Spec.(*networking.DestinationRule)
+	if !ok {
+		return nil
+	}
+
+	h := model.ResolveShortnameToFQDN(rule.Host, cfg.ConfigMeta)
+	if strings.HasPrefix(string(h), "*") {
+		return nil
+	}
+
+	suffix := ".svc." + cfg.Domain
+	if cfg.Domain != "" && v.clientset != nil && strings.HasSuffix(string(h), suffix) {
+		// A Kubernetes service, name.namespace.svc.domain.
+		if parts := strings.Split(strings.TrimSuffix(string(h), suffix), "."); len(parts) == 2 {
+			_, err := v.clientset.CoreV1().Services(parts[1]).Get(parts[0], v1.GetOptions{})
+			if err == nil {
+				return nil
+			}
+			if !errors.IsNotFound(err) {
+				return fmt.Errorf("failed to look up service %s: %v", h, err)
+			}
+		}
+	}
+
+	entries, err := v.store.List(schemas.ServiceEntry.Type, "")
+	if err != nil {
+		return fmt.Errorf("failed to list service entries: %v", err)
+	}
+	for _, entry := range entries {
+		se, ok := entry.Spec.(*networking.ServiceEntry)
+		if !ok {
+			continue
+		}
+		for _, seHost := range se.Hosts {
+			if h.SubsetOf(host.Name(seHost)) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("host %q does not match any service or service entry", rule.Host)
+}
+
+// validateGateway checks that no other gateway selecting the same workloads defines a server for
+// the same host and port.
+func (v *crossResourceValidator) validateGateway(cfg *model.Config) error {
+	gw, ok := cfg.Spec.(*networking.Gateway)
+	if !ok {
+		return nil
+	}
+
+	others, err := v.store.List(schemas.Gateway.Type, "")
+	if err != nil {
+		return fmt.Errorf("failed to list gateways: %v", err)
+	}
+
+	var errs error
+	for _, other := range others {
+		if other.Name == cfg.Name && other.Namespace == cfg.Namespace {
+			continue
+		}
+		otherGw, ok := other.Spec.(*networking.Gateway)
+		if !ok || !sameSelector(gw.Selector, otherGw.Selector) {
+			continue
+		}
+		for _, server := range gw.Servers {
+			for _, otherServer := range otherGw.Servers {
+				if server.Port == nil || otherServer.Port == nil || server.Port.Number != otherServer.Port.Number {
+					continue
+				}
+				for _, h := range server.Hosts {
+					for _, otherHost := range otherServer.Hosts {
+						if gatewayHost(h) == gatewayHost(otherHost) {
+							errs = multierror.Append(errs, fmt.Errorf("host %q on port %d is already defined by gateway %s/%s",
+								gatewayHost(h), server.Port.Number, other.Namespace, other.Name))
+						}
+					}
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// gatewayHost strips the namespace from a gateway server host in the form of namespace/host.
+func gatewayHost(h string) string {
+	if i := strings.Index(h, "/"); i >= 0 {
+		return h[i+1:]
+	}
+	return h
+}
+
+func sameSelector(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
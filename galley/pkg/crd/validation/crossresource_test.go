@@ -0,0 +1,189 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	networking "istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/schemas"
+)
+
+func crossResourceConfig(typ, name, namespace string, spec proto.Message) model.Config {
+	return model.Config{
+		ConfigMeta: model.ConfigMeta{
+			Type:      typ,
+			Name:      name,
+			Namespace: namespace,
+			Domain:    testDomainSuffix,
+		},
+		Spec: spec,
+	}
+}
+
+func newTestCrossResourceValidator(t *testing.T) *crossResourceValidator {
+	t.Helper()
+	store := memory.Make(schemas.Istio)
+	existing := []model.Config{
+		crossResourceConfig(schemas.Gateway.Type, "ingress", "istio-system", &networking.Gateway{
+			Selector: map[string]string{"istio": "ingressgateway"},
+			Servers: []*networking.Server{{
+				Port:  &networking.Port{Number: 80, Name: "http", Protocol: "HTTP"},
+				Hosts: []string{"bookinfo.example.com"},
+			}},
+		}),
+		crossResourceConfig(schemas.ServiceEntry.Type, "external", "default", &networking.ServiceEntry{
+			Hosts:      []string{"*.googleapis.com"},
+			Ports:      []*networking.Port{{Number: 443, Name: "https", Protocol: "TLS"}},
+			Location:   networking.ServiceEntry_MESH_EXTERNAL,
+			Resolution: networking.ServiceEntry_NONE,
+		}),
+	}
+	for _, c := range existing {
+		if _, err := store.Create(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := fake.NewSimpleClientset(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "reviews", Namespace: "default"},
+	})
+	return &crossResourceValidator{store: store, clientset: client}
+}
+
+// unsyncedStore is a config store whose informers have not synced yet.
+type unsyncedStore struct {
+	model.ConfigStoreCache
+}
+
+func (unsyncedStore) HasSynced() bool { return false }
+
+func TestCrossResourceValidationBeforeSync(t *testing.T) {
+	v := newTestCrossResourceValidator(t)
+	v.store = unsyncedStore{memory.NewController(v.store)}
+
+	cfg := crossResourceConfig(schemas.VirtualService.Type, "bookinfo", "default", &networking.VirtualService{
+		Hosts:    []string{"bookinfo.example.com"},
+		Gateways: []string{"ingress"},
+	})
+	if err := v.validate(&cfg); err != nil {
+		t.Errorf("unexpected error before the store synced: %v", err)
+	}
+}
+
+func TestCrossResourceValidation(t *testing.T) {
+	v := newTestCrossResourceValidator(t)
+
+	cases := []struct {
+		name  string
+		cfg   model.Config
+		valid bool
+	}{
+		{
+			name: "virtual service bound to existing gateway",
+			cfg: crossResourceConfig(schemas.VirtualService.Type, "bookinfo", "default", &networking.VirtualService{
+				Hosts:    []string{"bookinfo.example.com"},
+				Gateways: []string{"istio-system/ingress", "mesh"},
+			}),
+			valid: true,
+		},
+		{
+			name: "virtual service bound to missing gateway",
+			cfg: crossResourceConfig(schemas.VirtualService.Type, "bookinfo", "default", &networking.VirtualService{
+				Hosts:    []string{"bookinfo.example.com"},
+				Gateways: []string{"ingress"},
+			}),
+		},
+		{
+			name: "virtual service route matching missing gateway",
+			cfg: crossResourceConfig(schemas.VirtualService.Type, "bookinfo", "default", &networking.VirtualService{
+				Hosts: []string{"bookinfo.example.com"},
+				Http: []*networking.HTTPRoute{{
+					Match: []*networking.HTTPMatchRequest{{Gateways: []string{"istio-system/egress"}}},
+				}},
+			}),
+		},
+		{
+			name: "destination rule for kubernetes service",
+			cfg: crossResourceConfig(schemas.DestinationRule.Type, "reviews", "default", &networking.DestinationRule{
+				Host: "reviews",
+			}),
+			valid: true,
+		},
+		{
+			name: "destination rule for service entry host",
+			cfg: crossResourceConfig(schemas.DestinationRule.Type, "storage", "default", &networking.DestinationRule{
+				Host: "storage.googleapis.com",
+			}),
+			valid: true,
+		},
+		{
+			name: "destination rule for unknown host",
+			cfg: crossResourceConfig(schemas.DestinationRule.Type, "ratings", "default", &networking.DestinationRule{
+				Host: "ratings",
+			}),
+		},
+		{
+			name: "gateway with duplicate host on the same port",
+			cfg: crossResourceConfig(schemas.Gateway.Type, "other", "default", &networking.Gateway{
+				Selector: map[string]string{"istio": "ingressgateway"},
+				Servers: []*networking.Server{{
+					Port:  &networking.Port{Number: 80, Name: "http", Protocol: "HTTP"},
+					Hosts: []string{"default/bookinfo.example.com"},
+				}},
+			}),
+		},
+		{
+			name: "gateway with same host on another port",
+			cfg: crossResourceConfig(schemas.Gateway.Type, "other", "default", &networking.Gateway{
+				Selector: map[string]string{"istio": "ingressgateway"},
+				Servers: []*networking.Server{{
+					Port:  &networking.Port{Number: 8080, Name: "http", Protocol: "HTTP"},
+					Hosts: []string{"bookinfo.example.com"},
+				}},
+			}),
+			valid: true,
+		},
+		{
+			name: "update of the existing gateway",
+			cfg: crossResourceConfig(schemas.Gateway.Type, "ingress", "istio-system", &networking.Gateway{
+				Selector: map[string]string{"istio": "ingressgateway"},
+				Servers: []*networking.Server{{
+					Port:  &networking.Port{Number: 80, Name: "http", Protocol: "HTTP"},
+					Hosts: []string{"bookinfo.example.com"},
+				}},
+			}),
+			valid: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := v.validate(&tc.cfg)
+			if tc.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
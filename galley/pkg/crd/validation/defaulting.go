@@ -0,0 +1,213 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/api/admissionregistration/v1beta1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	admissionregistration "k8s.io/client-go/kubernetes/typed/admissionregistration/v1beta1"
+
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schemas"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
+)
+
+const (
+	// defaultingPath is the path of the defaulting webhook of the Pilot configuration.
+	defaultingPath = "/defaultpilot"
+
+	// defaultingWebhookName is the name of the webhook in the mutatingwebhookconfiguration.
+	defaultingWebhookName = "pilot.defaulting.istio.io"
+)
+
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+func (wh *Webhook) serveDefaultPilot(w http.ResponseWriter, r *http.Request) {
+	serve(w, r, wh.defaultPilot)
+}
+
+// defaultPilot materializes the implicit defaults of Pilot resources by patching their spec. Resources
+// which cannot be decoded are admitted unchanged and left to the validating webhook.
+func (wh *Webhook) defaultPilot(request *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	allowed := &admissionv1beta1.AdmissionResponse{Allowed: true}
+	switch request.Operation {
+	case admissionv1beta1.Create, admissionv1beta1.Update:
+	default:
+		return allowed
+	}
+
+	var obj crd.IstioKind
+	if err := yaml.Unmarshal(request.Object.Raw, &obj); err != nil {
+		scope.Debugf("cannot decode configuration for defaulting: %v", err)
+		return allowed
+	}
+	s, exists := wh.descriptor.GetByType(crd.CamelCaseToKebabCase(obj.Kind))
+	if !exists || s.Default == nil {
+		return allowed
+	}
+	out, err := crd.ConvertObject(s, &obj, wh.domainSuffix)
+	if err != nil {
+		scope.Debugf("cannot decode configuration for defaulting: %v", err)
+		return allowed
+	}
+
+	if !s.Default(out.Spec) {
+		return allowed
+	}
+	spec, err := gogoprotomarshal.ToJSONMap(out.Spec)
+	if err != nil {
+		scope.Errorf("cannot encode the defaulted configuration: %v", err)
+		return allowed
+	}
+	patch, err := json.Marshal([]jsonPatchOperation{{Op: "add", Path: "/spec", Value: spec}})
+	if err != nil {
+		scope.Errorf("cannot encode the defaulting patch: %v", err)
+		return allowed
+	}
+
+	reportDefaultingApplied(request)
+	patchType := admissionv1beta1.PatchTypeJSONPatch
+	return &admissionv1beta1.AdmissionResponse{
+		Allowed:   true,
+		Patch:     patch,
+		PatchType: &patchType,
+	}
+}
+
+// defaultingResources returns, per API group, the resources of the schemas with implicit defaults.
+func defaultingResources(descriptor schema.Set) map[string][]string {
+	resources := map[string][]string{}
+	for _, s := range descriptor {
+		if s.Default == nil {
+			continue
+		}
+		group := s.Group + constants.IstioAPIGroupDomain
+		resources[group] = append(resources[group], crd.ResourceName(s.Plural))
+	}
+	for _, r := range resources {
+		sort.Strings(r)
+	}
+	return resources
+}
+
+// buildDefaultingWebhookConfig builds the mutatingwebhookconfiguration sending the resources with
+// implicit defaults to the defaulting webhook. Failures are ignored, so that resources can still be
+// created when Galley is unavailable.
+func buildDefaultingWebhookConfig(p *WebhookParameters, caPem []byte,
+	ownerRefs []metav1.OwnerReference) *v1beta1.MutatingWebhookConfiguration {
+	path := defaultingPath
+	failurePolicy := v1beta1.Ignore
+	sideEffects := v1beta1.SideEffectClassNone
+
+	resources := defaultingResources(schemas.Istio)
+	groups := make([]string, 0, len(resources))
+	for g := range resources {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+
+	rules := make([]v1beta1.RuleWithOperations, 0, len(groups))
+	for _, g := range groups {
+		rules = append(rules, v1beta1.RuleWithOperations{
+			Operations: []v1beta1.OperationType{v1beta1.Create, v1beta1.Update},
+			Rule: v1beta1.Rule{
+				APIGroups:   []string{g},
+				APIVersions: []string{"*"},
+				Resources:   resources[g],
+			},
+		})
+	}
+
+	return &v1beta1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            p.WebhookName,
+			OwnerReferences: ownerRefs,
+		},
+		Webhooks: []v1beta1.Webhook{{
+			Name: defaultingWebhookName,
+			ClientConfig: v1beta1.WebhookClientConfig{
+				Service: &v1beta1.ServiceReference{
+					Namespace: p.DeploymentAndServiceNamespace,
+					Name:      p.ServiceName,
+					Path:      &path,
+				},
+				CABundle: caPem,
+			},
+			Rules:             rules,
+			FailurePolicy:     &failurePolicy,
+			NamespaceSelector: &metav1.LabelSelector{},
+			SideEffects:       &sideEffects,
+		}},
+	}
+}
+
+// Create the specified mutatingwebhookconfiguration resource or, if the resource already exists,
+// update it's contents with the desired state.
+func createOrUpdateMutatingWebhookConfigHelper(
+	client admissionregistration.MutatingWebhookConfigurationInterface,
+	webhookConfiguration *v1beta1.MutatingWebhookConfiguration,
+) (bool, error) {
+	current, err := client.Get(webhookConfiguration.Name, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			if _, createErr := client.Create(webhookConfiguration); createErr != nil {
+				return false, createErr
+			}
+			return true, nil
+		}
+		return false, err
+	}
+
+	updated := current.DeepCopyObject().(*v1beta1.MutatingWebhookConfiguration)
+	updated.Webhooks = webhookConfiguration.Webhooks
+	updated.OwnerReferences = webhookConfiguration.OwnerReferences
+
+	if !reflect.DeepEqual(updated, current) {
+		_, err := client.Update(updated)
+		return true, err
+	}
+	return false, nil
+}
+
+// Delete the mutatingwebhookconfiguration if it exists. Otherwise, do nothing.
+func deleteMutatingWebhookConfigHelper(
+	client admissionregistration.MutatingWebhookConfigurationInterface,
+	webhookName string,
+) (bool, error) {
+	if _, err := client.Get(webhookName, metav1.GetOptions{}); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := client.Delete(webhookName, &metav1.DeleteOptions{}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,197 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	networking "istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/defaults"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schemas"
+)
+
+func makeDefaultingConfig(t *testing.T, s schema.Instance, spec proto.Message) []byte {
+	t.Helper()
+	obj, err := crd.ConvertConfig(s, model.Config{
+		ConfigMeta: model.ConfigMeta{Type: s.Type, Name: "config", Namespace: "default"},
+		Spec:       spec,
+	})
+	if err != nil {
+		t.Fatalf("ConvertConfig failed: %v", err)
+	}
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	return raw
+}
+
+func TestDefaultPilot(t *testing.T) {
+	wh := &Webhook{descriptor: schemas.Istio, domainSuffix: testDomainSuffix}
+
+	withoutRetries := makeDefaultingConfig(t, schemas.VirtualService, &networking.VirtualService{
+		Hosts: []string{"reviews"},
+		Http: []*networking.HTTPRoute{{
+			Route: []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: "reviews"}}},
+		}},
+	})
+	withRetries := makeDefaultingConfig(t, schemas.VirtualService, &networking.VirtualService{
+		Hosts: []string{"reviews"},
+		Http: []*networking.HTTPRoute{{
+			Route:   []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: "reviews"}, Weight: 100}},
+			Retries: &networking.HTTPRetry{Attempts: 3},
+		}},
+	})
+	withoutDefaults := makeDefaultingConfig(t, schemas.DestinationRule, &networking.DestinationRule{Host: "reviews"})
+
+	cases := []struct {
+		name      string
+		in        *admissionv1beta1.AdmissionRequest
+		wantPatch bool
+	}{
+		{
+			name: "create with implicit defaults",
+			in: &admissionv1beta1.AdmissionRequest{
+				Object:    runtime.RawExtension{Raw: withoutRetries},
+				Operation: admissionv1beta1.Create,
+			},
+			wantPatch: true,
+		},
+		{
+			name: "update with explicit values",
+			in: &admissionv1beta1.AdmissionRequest{
+				Object:    runtime.RawExtension{Raw: withRetries},
+				Operation: admissionv1beta1.Update,
+			},
+		},
+		{
+			name: "type without defaults",
+			in: &admissionv1beta1.AdmissionRequest{
+				Object:    runtime.RawExtension{Raw: withoutDefaults},
+				Operation: admissionv1beta1.Create,
+			},
+		},
+		{
+			name: "delete",
+			in: &admissionv1beta1.AdmissionRequest{
+				Object:    runtime.RawExtension{Raw: withoutRetries},
+				Operation: admissionv1beta1.Delete,
+			},
+		},
+		{
+			name: "invalid object",
+			in: &admissionv1beta1.AdmissionRequest{
+				Object:    runtime.RawExtension{Raw: []byte("{")},
+				Operation: admissionv1beta1.Create,
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := wh.defaultPilot(tc.in)
+			if !got.Allowed {
+				t.Fatalf("defaulting must not reject resources: %+v", got.Result)
+			}
+			if (len(got.Patch) > 0) != tc.wantPatch {
+				t.Fatalf("got patch %q, want patch %v", got.Patch, tc.wantPatch)
+			}
+			if !tc.wantPatch {
+				return
+			}
+			if got.PatchType == nil || *got.PatchType != admissionv1beta1.PatchTypeJSONPatch {
+				t.Errorf("got patch type %v, want JSONPatch", got.PatchType)
+			}
+
+			var patch []jsonPatchOperation
+			if err := json.Unmarshal(got.Patch, &patch); err != nil {
+				t.Fatal(err)
+			}
+			if len(patch) != 1 || patch[0].Path != "/spec" {
+				t.Fatalf("unexpected patch %s", got.Patch)
+			}
+			spec, err := json.Marshal(patch[0].Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			vs, err := schemas.VirtualService.FromJSON(string(spec))
+			if err != nil {
+				t.Fatal(err)
+			}
+			retries := vs.(*networking.VirtualService).Http[0].Retries
+			if retries == nil || retries.Attempts != defaults.DefaultRetryAttempts || retries.RetryOn != defaults.DefaultRetryOn {
+				t.Errorf("got retries %v, want the default retry policy", retries)
+			}
+		})
+	}
+}
+
+func TestReconcileDefaultingWebhookConfig(t *testing.T) {
+	p := &WebhookParameters{
+		WebhookName:                   "istio-galley",
+		ServiceName:                   "istio-galley",
+		DeploymentAndServiceNamespace: "istio-system",
+	}
+	config := buildDefaultingWebhookConfig(p, []byte("ca"), nil)
+
+	if len(config.Webhooks) != 1 || len(config.Webhooks[0].Rules) != 1 {
+		t.Fatalf("unexpected webhooks %+v", config.Webhooks)
+	}
+	rule := config.Webhooks[0].Rules[0]
+	if !reflect.DeepEqual(rule.APIGroups, []string{"networking.istio.io"}) {
+		t.Errorf("got API groups %v", rule.APIGroups)
+	}
+	if want := []string{"gateways", "serviceentries", "virtualservices"}; !reflect.DeepEqual(rule.Resources, want) {
+		t.Errorf("got resources %v, want %v", rule.Resources, want)
+	}
+
+	client := fake.NewSimpleClientset().AdmissionregistrationV1beta1().MutatingWebhookConfigurations()
+	if updated, err := createOrUpdateMutatingWebhookConfigHelper(client, config); err != nil || !updated {
+		t.Fatalf("create: got updated %v, err %v", updated, err)
+	}
+	if updated, err := createOrUpdateMutatingWebhookConfigHelper(client, config); err != nil || updated {
+		t.Fatalf("unchanged: got updated %v, err %v", updated, err)
+	}
+
+	rotated := buildDefaultingWebhookConfig(p, []byte("rotated"), nil)
+	if updated, err := createOrUpdateMutatingWebhookConfigHelper(client, rotated); err != nil || !updated {
+		t.Fatalf("ca rotation: got updated %v, err %v", updated, err)
+	}
+	current, err := client.Get(p.WebhookName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(current.Webhooks[0].ClientConfig.CABundle) != "rotated" {
+		t.Errorf("caBundle not updated: %q", current.Webhooks[0].ClientConfig.CABundle)
+	}
+
+	if deleted, err := deleteMutatingWebhookConfigHelper(client, p.WebhookName); err != nil || !deleted {
+		t.Fatalf("delete: got deleted %v, err %v", deleted, err)
+	}
+	if deleted, err := deleteMutatingWebhookConfigHelper(client, p.WebhookName); err != nil || deleted {
+		t.Fatalf("delete missing: got deleted %v, err %v", deleted, err)
+	}
+}
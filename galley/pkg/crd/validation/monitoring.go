@@ -72,6 +72,14 @@ var (
 		"galley/validation/failed",
 		"Resource validation failed",
 		stats.UnitDimensionless)
+	metricValidationWarning = stats.Int64(
+		"galley/validation/warning",
+		"Resource is admitted despite cross-resource validation failures",
+		stats.UnitDimensionless)
+	metricDefaultingApplied = stats.Int64(
+		"galley/validation/defaulted",
+		"Implicit defaults were set on the resource",
+		stats.UnitDimensionless)
 	metricValidationHTTPError = stats.Int64(
 		"galley/validation/http_error",
 		"Resource validation http serve errors",
@@ -140,6 +148,8 @@ func init() {
 		newView(metricCertKeyUpdateError, errorKey, view.Count()),
 		newView(metricValidationPassed, resourceKeys, view.Count()),
 		newView(metricValidationFailed, resourceErrorKeys, view.Count()),
+		newView(metricValidationWarning, resourceErrorKeys, view.Count()),
+		newView(metricDefaultingApplied, resourceKeys, view.Count()),
 		newView(metricValidationHTTPError, statusKey, view.Count()),
 		newView(metricWebhookConfigurationUpdateError, errorKey, view.Count()),
 		newView(metricWebhookConfigurationUpdates, noKeys, view.Count()),
@@ -165,6 +175,19 @@ func reportValidationFailed(request *admissionv1beta1.AdmissionRequest, reason s
 	}
 }
 
+func reportValidationWarning(request *admissionv1beta1.AdmissionRequest, reason string) {
+	ctx, err := tag.New(context.Background(),
+		tag.Insert(GroupTag, request.Resource.Group),
+		tag.Insert(VersionTag, request.Resource.Version),
+		tag.Insert(ResourceTag, request.Resource.Resource),
+		tag.Insert(ReasonTag, reason))
+	if err != nil {
+		scope.Errorf("Error creating monitoring context for reportValidationWarning: %v", err)
+	} else {
+		stats.Record(ctx, metricValidationWarning.M(1))
+	}
+}
+
 func reportValidationPass(request *admissionv1beta1.AdmissionRequest) {
 	ctx, err := tag.New(context.Background(),
 		tag.Insert(GroupTag, request.Resource.Group),
@@ -177,6 +200,18 @@ func reportValidationPass(request *admissionv1beta1.AdmissionRequest) {
 	}
 }
 
+func reportDefaultingApplied(request *admissionv1beta1.AdmissionRequest) {
+	ctx, err := tag.New(context.Background(),
+		tag.Insert(GroupTag, request.Resource.Group),
+		tag.Insert(VersionTag, request.Resource.Version),
+		tag.Insert(ResourceTag, request.Resource.Resource))
+	if err != nil {
+		scope.Errorf("Error creating monitoring context for reportDefaultingApplied: %v", err)
+	} else {
+		stats.Record(ctx, metricDefaultingApplied.M(1))
+	}
+}
+
 func reportValidationHTTPError(status int) {
 	ctx, err := tag.New(context.Background(), tag.Insert(StatusTag, strconv.Itoa(status)))
 	if err != nil {
@@ -240,4 +275,5 @@ const (
 	reasonUnknownType          = "unknown_type"
 	reasonCRDConversionError   = "crd_conversion_error"
 	reasonInvalidConfig        = "invalid_resource"
+	reasonCrossResource        = "cross_resource_violation"
 )
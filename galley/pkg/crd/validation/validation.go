@@ -29,6 +29,8 @@ import (
 	"istio.io/pkg/probe"
 
 	mixervalidate "istio.io/istio/mixer/pkg/validate"
+	crdcontroller "istio.io/istio/pilot/pkg/config/kube/crd/controller"
+	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pkg/cmd"
 	"istio.io/istio/pkg/config/schemas"
 	"istio.io/istio/pkg/kube"
@@ -84,6 +86,16 @@ func RunValidation(ready, stopCh chan struct{}, vc *WebhookParameters, kubeConfi
 	vc.MixerValidator = mixerValidator
 	vc.PilotDescriptor = schemas.Istio
 	vc.Clientset = clientset
+	kube.WatchHostDelegations(clientset, vc.DeploymentAndServiceNamespace, stopCh)
+	if vc.CrossResourceValidation != CrossResourceValidationOff {
+		client, err := crdcontroller.NewClient(kubeConfig, "", schemas.Istio, vc.DomainSuffix)
+		if err != nil {
+			log.Fatalf("could not create Istio config client: %v", err)
+		}
+		configStore := crdcontroller.NewController(client, kubecontroller.Options{DomainSuffix: vc.DomainSuffix})
+		go configStore.Run(stopCh)
+		vc.ConfigStore = configStore
+	}
 	wh, err := NewWebhook(*vc)
 	if err != nil {
 		log.Fatalf("cannot create validation webhook service: %v", err)
@@ -191,6 +203,11 @@ func (args *WebhookParameters) Validate() error {
 		if err := validatePort(int(args.Port)); err != nil {
 			errs = multierror.Append(errs, err)
 		}
+		switch args.CrossResourceValidation {
+		case CrossResourceValidationOff, CrossResourceValidationWarn, CrossResourceValidationEnforce:
+		default:
+			errs = multierror.Append(errs, fmt.Errorf("invalid cross-resource validation mode: %q", args.CrossResourceValidation))
+		}
 	}
 
 	return errs.ErrorOrNil()
@@ -35,6 +35,10 @@ func TestValidate(t *testing.T) {
 			wrapFunc:      func(args *WebhookParameters) { args.DeploymentAndServiceNamespace = "_/invalid" },
 			expectedError: `invalid deployment namespace: "_/invalid"`,
 		},
+		"invalid cross-resource validation mode": {
+			wrapFunc:      func(args *WebhookParameters) { args.CrossResourceValidation = "strict" },
+			expectedError: `invalid cross-resource validation mode: "strict"`,
+		},
 		"invalid deployment name": {
 			wrapFunc:      func(args *WebhookParameters) { args.DeploymentName = "_/invalid" },
 			expectedError: `invalid deployment name: "_/invalid"`,
@@ -27,6 +27,7 @@ import (
 	"github.com/ghodss/yaml"
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
 	"k8s.io/api/admissionregistration/v1beta1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
@@ -38,8 +39,10 @@ import (
 	mixerCrd "istio.io/istio/mixer/pkg/config/crd"
 	"istio.io/istio/mixer/pkg/config/store"
 	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/security"
 )
 
 var (
@@ -121,6 +124,22 @@ type WebhookParameters struct {
 
 	// Enable reconcile validatingwebhookconfiguration
 	EnableReconcileWebhookConfiguration bool
+
+	// CrossResourceValidation is the mode of the checks of Pilot resources against the configuration
+	// already present in the cluster: off, warn or enforce.
+	CrossResourceValidation string
+
+	// ConfigStore holds the existing Pilot configuration used by cross-resource validation.
+	ConfigStore model.ConfigStore
+
+	// EnableDefaulting registers the webhook materializing the implicit defaults of Pilot resources.
+	EnableDefaulting bool
+
+	// EnableConversion serves the Istio CRDs at all the API versions of their group.
+	EnableConversion bool
+
+	// APIExtensionsClientset is used to configure the served versions of the Istio CRDs.
+	APIExtensionsClientset apiextensionsclient.Interface
 }
 
 type createInformerEndpointSource func(cl clientset.Interface, namespace, name string) cache.ListerWatcher
@@ -151,6 +170,9 @@ func (p *WebhookParameters) String() string {
 	fmt.Fprintf(buf, "ServiceName: %s\n", p.ServiceName)
 	fmt.Fprintf(buf, "EnableValidation: %v\n", p.EnableValidation)
 	fmt.Fprintf(buf, "EnableReconcileWebhookConfiguration: %v\n", p.EnableReconcileWebhookConfiguration)
+	fmt.Fprintf(buf, "CrossResourceValidation: %s\n", p.CrossResourceValidation)
+	fmt.Fprintf(buf, "EnableDefaulting: %v\n", p.EnableDefaulting)
+	fmt.Fprintf(buf, "EnableConversion: %v\n", p.EnableConversion)
 
 	return buf.String()
 }
@@ -168,6 +190,7 @@ func DefaultArgs() *WebhookParameters {
 		WebhookName:                         "istio-galley",
 		EnableValidation:                    true,
 		EnableReconcileWebhookConfiguration: true,
+		CrossResourceValidation:             CrossResourceValidationOff,
 	}
 }
 
@@ -180,6 +203,10 @@ type Webhook struct {
 	descriptor   schema.Set
 	domainSuffix string
 
+	// cross-resource validation of pilot configuration, nil when disabled
+	crossResource        *crossResourceValidator
+	crossResourceEnforce bool
+
 	// mixer
 	validator store.BackendValidator
 
@@ -207,6 +234,7 @@ func NewWebhook(p WebhookParameters) (*Webhook, error) {
 		},
 		cert:                          &pair,
 		descriptor:                    p.PilotDescriptor,
+		domainSuffix:                  p.DomainSuffix,
 		validator:                     p.MixerValidator,
 		clientset:                     p.Clientset,
 		deploymentName:                p.DeploymentName,
@@ -216,11 +244,17 @@ func NewWebhook(p WebhookParameters) (*Webhook, error) {
 		createInformerEndpointSource:  defaultCreateInformerEndpointSource,
 	}
 
+	if p.CrossResourceValidation != CrossResourceValidationOff && p.CrossResourceValidation != "" && p.ConfigStore != nil {
+		wh.crossResource = &crossResourceValidator{store: p.ConfigStore, clientset: p.Clientset}
+		wh.crossResourceEnforce = p.CrossResourceValidation == CrossResourceValidationEnforce
+	}
+
 	// mtls disabled because apiserver webhook cert usage is still TBD.
-	wh.server.TLSConfig = &tls.Config{GetCertificate: wh.getCert}
+	wh.server.TLSConfig = security.RestrictTLSConfig(&tls.Config{GetCertificate: wh.getCert})
 	h := http.NewServeMux()
 	h.HandleFunc("/admitpilot", wh.serveAdmitPilot)
 	h.HandleFunc("/admitmixer", wh.serveAdmitMixer)
+	h.HandleFunc(defaultingPath, wh.serveDefaultPilot)
 	h.HandleFunc(httpsHandlerReadyPath, wh.serveReady)
 	wh.server.Handler = h
 
@@ -370,6 +404,18 @@ func (wh *Webhook) admitPilot(request *admissionv1beta1.AdmissionRequest) *admis
 		return toAdmissionResponse(err)
 	}
 
+	if wh.crossResource != nil {
+		if err := wh.crossResource.validate(out); err != nil {
+			if wh.crossResourceEnforce {
+				scope.Infof("configuration is inconsistent with the cluster: %v", err)
+				reportValidationFailed(request, reasonCrossResource)
+				return toAdmissionResponse(fmt.Errorf("configuration is inconsistent with the cluster: %v", err))
+			}
+			scope.Warnf("%s %s/%s is inconsistent with the cluster: %v", obj.Kind, out.Namespace, out.Name, err)
+			reportValidationWarning(request, reasonCrossResource)
+		}
+	}
+
 	reportValidationPass(request)
 	return &admissionv1beta1.AdmissionResponse{Allowed: true}
 }
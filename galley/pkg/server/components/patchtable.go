@@ -23,6 +23,8 @@ import (
 	"istio.io/istio/galley/pkg/config/event"
 	"istio.io/istio/galley/pkg/config/meshcfg"
 	"istio.io/istio/galley/pkg/config/processor"
+	"istio.io/istio/galley/pkg/config/source/git"
+	"istio.io/istio/galley/pkg/config/source/kube/apiserver/status"
 	check2 "istio.io/istio/galley/pkg/config/source/kube/check"
 	fs2 "istio.io/istio/galley/pkg/config/source/kube/fs"
 	"istio.io/istio/galley/pkg/meshconfig"
@@ -50,6 +52,8 @@ var (
 	processorInitialize        = processor.Initialize
 	checkResourceTypesPresence = check2.ResourceTypesPresence
 	fsNew2                     = fs2.New
+	gitNew                     = git.New
+	statusNew                  = status.NewController
 )
 
 func resetPatchTable() {
@@ -68,4 +72,6 @@ func resetPatchTable() {
 	processorInitialize = processor.Initialize
 	checkResourceTypesPresence = check2.ResourceTypesPresence
 	fsNew2 = fs2.New
+	gitNew = git.New
+	statusNew = status.NewController
 }
@@ -15,6 +15,7 @@
 package components
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"strings"
@@ -135,7 +136,7 @@ func (p *Processing) Start() (err error) {
 	options := &source.Options{
 		Watcher:            p.distributor,
 		Reporter:           p.reporter,
-		CollectionsOptions: source.CollectionOptionsFromSlice(types.Collections()),
+		CollectionsOptions: collectionOptions(types.Collections(), p.args.EnableIncrementalMCP),
 		ConnRateLimiter:    mcprate.NewRateLimiter(time.Second, 100), // TODO(Nino-K): https://github.com/istio/istio/issues/12074
 	}
 
@@ -246,6 +247,15 @@ func (p *Processing) getServerGrpcOptions() []grpc.ServerOption {
 	return grpcOptions
 }
 
+// collectionOptions returns the options of the collections served over MCP.
+func collectionOptions(collections []string, incremental bool) []source.CollectionOptions {
+	options := source.CollectionOptionsFromSlice(collections)
+	for i := range options {
+		options[i].Incremental = incremental
+	}
+	return options
+}
+
 func (p *Processing) createSource(mesh meshconfig.Cache) (src runtime.Source, err error) {
 	converterCfg := &converter.Config{
 		Mesh:         mesh,
@@ -254,6 +264,11 @@ func (p *Processing) createSource(mesh meshconfig.Cache) (src runtime.Source, er
 
 	sourceSchema := p.getSourceSchema()
 
+	if p.args.GitSource != nil && p.args.GitSource.Repository != "" {
+		err = errors.New("the git source is not supported by the old processing pipeline")
+		return
+	}
+
 	if p.args.ConfigPath != "" {
 		if src, err = fsNew(p.args.ConfigPath, sourceSchema, converterCfg); err != nil {
 			return
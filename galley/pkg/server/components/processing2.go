@@ -32,6 +32,7 @@ import (
 	"istio.io/pkg/log"
 	"istio.io/pkg/version"
 
+	"istio.io/istio/galley/pkg/config/analysis/analyzers"
 	"istio.io/istio/galley/pkg/config/event"
 	"istio.io/istio/galley/pkg/config/processing"
 	"istio.io/istio/galley/pkg/config/processing/snapshotter"
@@ -40,6 +41,7 @@ import (
 	"istio.io/istio/galley/pkg/config/schema"
 	"istio.io/istio/galley/pkg/config/source/kube"
 	"istio.io/istio/galley/pkg/config/source/kube/apiserver"
+	"istio.io/istio/galley/pkg/config/source/kube/apiserver/status"
 	"istio.io/istio/galley/pkg/config/source/kube/rt"
 	"istio.io/istio/galley/pkg/runtime/groups"
 	"istio.io/istio/galley/pkg/server/process"
@@ -66,6 +68,7 @@ type Processing2 struct {
 	serveWG       sync.WaitGroup
 	grpcServer    *grpc.Server
 	runtime       *processing.Runtime
+	statusCtl     *status.Controller
 	mcpSource     *source.Server
 	reporter      monitoring.Reporter
 	callOut       *callout
@@ -104,6 +107,11 @@ func (p *Processing2) Start() (err error) {
 	}
 
 	var distributor snapshotter.Distributor = snapshotter.NewMCPDistributor(p.mcpCache)
+	if p.args.EnableConfigAnalysis {
+		if distributor, err = p.createAnalyzingDistributor(kubeResources, distributor); err != nil {
+			return
+		}
+	}
 	transformProviders := transforms.Providers(m)
 
 	if p.runtime, err = processorInitialize(m, p.args.DomainSuffix, event.CombineSources(mesh, src), transformProviders, distributor); err != nil {
@@ -135,7 +143,7 @@ func (p *Processing2) Start() (err error) {
 	options := &source.Options{
 		Watcher:            p.mcpCache,
 		Reporter:           p.reporter,
-		CollectionsOptions: source.CollectionOptionsFromSlice(m.AllCollectionsInSnapshots()),
+		CollectionsOptions: collectionOptions(m.AllCollectionsInSnapshots(), p.args.EnableIncrementalMCP),
 		ConnRateLimiter:    mcprate.NewRateLimiter(time.Second, 100), // TODO(Nino-K): https://github.com/istio/istio/issues/12074
 	}
 
@@ -285,6 +293,10 @@ func (p *Processing2) createSource(resources schema.KubeResources) (src event.So
 		if src, err = fsNew2(p.args.ConfigPath, resources); err != nil {
 			return
 		}
+	} else if p.args.GitSource != nil && p.args.GitSource.Repository != "" {
+		if src, err = gitNew(*p.args.GitSource, resources); err != nil {
+			return
+		}
 	} else {
 		var k kube.Interfaces
 		if k, err = p.getKubeInterfaces(); err != nil {
@@ -307,6 +319,26 @@ func (p *Processing2) createSource(resources schema.KubeResources) (src event.So
 	return
 }
 
+// createAnalyzingDistributor wraps the distributor to analyze the snapshots, and write the analysis
+// messages into the status of the resources.
+func (p *Processing2) createAnalyzingDistributor(resources schema.KubeResources,
+	d snapshotter.Distributor) (snapshotter.Distributor, error) {
+	if p.args.ConfigPath != "" || (p.args.GitSource != nil && p.args.GitSource.Repository != "") {
+		log.Warn("Configuration analysis is only supported with the Kubernetes API server as source")
+		return d, nil
+	}
+
+	k, err := p.getKubeInterfaces()
+	if err != nil {
+		return nil, err
+	}
+	p.statusCtl = statusNew(k, resources)
+	if err = p.statusCtl.Start(); err != nil {
+		return nil, err
+	}
+	return snapshotter.NewAnalyzingDistributor(p.statusCtl, analyzers.AllCombined(), d, nil), nil
+}
+
 func (p *Processing2) isKindExcluded(kind string) bool {
 	for _, excludedKind := range p.args.ExcludedResourceKinds {
 		if kind == excludedKind {
@@ -334,6 +366,11 @@ func (p *Processing2) Stop() {
 		p.runtime = nil
 	}
 
+	if p.statusCtl != nil {
+		p.statusCtl.Stop()
+		p.statusCtl = nil
+	}
+
 	p.listenerMutex.Lock()
 	if p.listener != nil {
 		_ = p.listener.Close()
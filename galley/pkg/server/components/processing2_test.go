@@ -32,6 +32,7 @@ import (
 	"istio.io/istio/galley/pkg/config/processing/snapshotter"
 	"istio.io/istio/galley/pkg/config/processing/transformer"
 	"istio.io/istio/galley/pkg/config/schema"
+	"istio.io/istio/galley/pkg/config/source/git"
 	"istio.io/istio/galley/pkg/config/source/kube"
 	"istio.io/istio/galley/pkg/server/settings"
 	"istio.io/istio/galley/pkg/source/kube/client"
@@ -94,6 +95,9 @@ loop:
 		case 8:
 			args.ConfigPath = "aaa"
 			fsNew2 = func(_ string, _ schema.KubeResources) (event.Source, error) { return nil, e }
+		case 9:
+			args.GitSource.Repository = "https://example.com/config.git"
+			gitNew = func(_ git.Options, _ schema.KubeResources) (event.Source, error) { return nil, e }
 		default:
 			break loop
 
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"time"
 
+	"istio.io/istio/galley/pkg/config/source/git"
 	"istio.io/istio/galley/pkg/crd/validation"
 	"istio.io/istio/galley/pkg/source/kube/builtin"
 	"istio.io/istio/pkg/keepalive"
@@ -74,6 +75,10 @@ type Args struct { // nolint:maligned
 	// ConfigPath is the path for Galley specific config files
 	ConfigPath string
 
+	// GitSource configures a git repository as the source of the configuration. Used instead of
+	// Kubernetes when GitSource.Repository is set.
+	GitSource *git.Options
+
 	// ExcludedResourceKinds is a list of resource kinds for which no source events will be triggered.
 	ExcludedResourceKinds []string
 
@@ -108,6 +113,13 @@ type Args struct { // nolint:maligned
 	// Enable service discovery / endpoint processing.
 	EnableServiceDiscovery bool
 
+	// EnableIncrementalMCP allows sending incremental updates to MCP sinks requesting them.
+	EnableIncrementalMCP bool
+
+	// EnableConfigAnalysis runs the analyzers on the configuration and writes their messages into the
+	// status of the offending resources. Only supported with the Kubernetes API server as source.
+	EnableConfigAnalysis bool
+
 	// DisableResourceReadyCheck disables the CRD readiness check. This
 	// allows Galley to start when not all supported CRD are
 	// registered with the kube-apiserver.
@@ -144,6 +156,7 @@ func DefaultArgs() *Args {
 		EnableServer:                true,
 		CredentialOptions:           creds.DefaultOptions(),
 		ConfigPath:                  "",
+		GitSource:                   git.DefaultOptions(),
 		DomainSuffix:                defaultDomainSuffix,
 		DisableResourceReadyCheck:   false,
 		ExcludedResourceKinds:       defaultExcludedResourceKinds(),
@@ -193,9 +206,13 @@ func (a *Args) String() string {
 	_, _ = fmt.Fprintf(buf, "CertificateFile: %s\n", a.CredentialOptions.CertificateFile)
 	_, _ = fmt.Fprintf(buf, "CACertificateFile: %s\n", a.CredentialOptions.CACertificateFile)
 	_, _ = fmt.Fprintf(buf, "ConfigFilePath: %s\n", a.ConfigPath)
+	if a.GitSource != nil {
+		_, _ = fmt.Fprintf(buf, "GitSource: %+v\n", *a.GitSource)
+	}
 	_, _ = fmt.Fprintf(buf, "MeshConfigFile: %s\n", a.MeshConfigFile)
 	_, _ = fmt.Fprintf(buf, "DomainSuffix: %s\n", a.DomainSuffix)
 	_, _ = fmt.Fprintf(buf, "DisableResourceReadyCheck: %v\n", a.DisableResourceReadyCheck)
+	_, _ = fmt.Fprintf(buf, "EnableConfigAnalysis: %v\n", a.EnableConfigAnalysis)
 	_, _ = fmt.Fprintf(buf, "ExcludedResourceKinds: %v\n", a.ExcludedResourceKinds)
 	_, _ = fmt.Fprintf(buf, "SinkAddress: %v\n", a.SinkAddress)
 	_, _ = fmt.Fprintf(buf, "SinkAuthMode: %v\n", a.SinkAuthMode)
@@ -57,4 +57,7 @@ func TestArgs_String(t *testing.T) {
 	a := DefaultArgs()
 	// Should not crash
 	_ = a.String()
+
+	a.GitSource = nil
+	_ = a.String()
 }
@@ -37,3 +37,12 @@ Create a fully qualified configmap name.
 {{- define "istio.configmap.fullname" -}}
 {{- printf "%s-%s" .Release.Name "istio-mesh-config" | trunc 63 | trimSuffix "-" -}}
 {{- end -}}
+
+{{/*
+Suffix appended to the names of the resources of a control plane revision.
+*/}}
+{{- define "istio.revision.suffix" -}}
+{{- if .Values.global.revision -}}
+{{- printf "-%s" .Values.global.revision -}}
+{{- end -}}
+{{- end -}}
@@ -0,0 +1,145 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	v2 "istio.io/istio/pilot/pkg/proxy/envoy/v2"
+)
+
+var dryRunFilename string
+
+func dryRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dry-run -f <file>",
+		Short: "Reports the impact of Istio configuration on the proxies, without applying it [kube only]",
+		Long: `
+Reports the impact of the Istio configuration in the file on the proxies of the mesh, before it is
+applied. Each Pilot instance validates the configuration, and generates the configuration of its
+connected proxies with and without it. The command lists the proxies whose clusters, listeners or
+routes change, and would be pushed, and the push errors, e.g. conflicts, that the configuration adds.
+ServiceEntries are not supported. Pilot must run with PILOT_ENABLE_DRY_RUN set, and generates the
+configuration of at most PILOT_DRY_RUN_MAX_PROXIES proxies.
+`,
+		Example: `
+# Report the impact of a new VirtualService
+istioctl experimental dry-run -f reviews-v2.yaml
+
+# Report the impact of the configuration read from the standard input
+kubectl get virtualservice reviews -o yaml | istioctl experimental dry-run -f -
+`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			if dryRunFilename == "" {
+				c.Println(c.UsageString())
+				return errors.New("dry-run requires a file")
+			}
+			var body []byte
+			var err error
+			if dryRunFilename == "-" {
+				body, err = ioutil.ReadAll(os.Stdin)
+			} else {
+				body, err = ioutil.ReadFile(dryRunFilename)
+			}
+			if err != nil {
+				return err
+			}
+
+			kubeClient, err := clientExecFactory(kubeconfig, configContext)
+			if err != nil {
+				return fmt.Errorf("failed to create k8s client: %v", err)
+			}
+			results, err := kubeClient.AllPilotsDiscoveryDo(istioNamespace, "POST", "/debug/dryrunz", body)
+			if err != nil {
+				return err
+			}
+			report, err := mergeDryRunReports(results)
+			if err != nil {
+				return err
+			}
+			printDryRunReport(c.OutOrStdout(), report)
+			return nil
+		},
+	}
+	cmd.PersistentFlags().StringVarP(&dryRunFilename, "filename", "f", "",
+		"Input Istio configuration filename, or \"-\" for the standard input")
+	return cmd
+}
+
+// mergeDryRunReports merges the reports of the Pilot instances, each reporting the impact on its
+// connected proxies.
+func mergeDryRunReports(results map[string][]byte) (*v2.DryRunReport, error) {
+	merged := &v2.DryRunReport{}
+	warnings := map[string]struct{}{}
+	for pilot, result := range results {
+		report := v2.DryRunReport{}
+		if err := json.Unmarshal(result, &report); err != nil {
+			return nil, fmt.Errorf("%s: %s", pilot, string(result))
+		}
+		merged.Configs = report.Configs
+		merged.Proxies = append(merged.Proxies, report.Proxies...)
+		merged.Skipped += report.Skipped
+		for _, w := range report.Warnings {
+			warnings[w] = struct{}{}
+		}
+	}
+	for w := range warnings {
+		merged.Warnings = append(merged.Warnings, w)
+	}
+	sort.Strings(merged.Warnings)
+	sort.Slice(merged.Proxies, func(i, j int) bool {
+		return merged.Proxies[i].ProxyID < merged.Proxies[j].ProxyID
+	})
+	return merged, nil
+}
+
+func printDryRunReport(writer io.Writer, report *v2.DryRunReport) {
+	for _, c := range report.Configs {
+		fmt.Fprintf(writer, "Config: %s\n", c)
+	}
+	for _, w := range report.Warnings {
+		fmt.Fprintf(writer, "Warning: %s\n", w)
+	}
+	if report.Skipped > 0 {
+		fmt.Fprintf(writer, "Warning: the impact on %d proxies was not computed\n", report.Skipped)
+	}
+	if len(report.Proxies) == 0 {
+		fmt.Fprintln(writer, "No proxy configuration changes")
+		return
+	}
+	fmt.Fprintf(writer, "%d proxies would be pushed\n", len(report.Proxies))
+	w := tabwriter.NewWriter(writer, 0, 8, 5, ' ', 0)
+	fmt.Fprintln(w, "PROXY\tCLUSTERS\tLISTENERS\tROUTES")
+	for _, p := range report.Proxies {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.ProxyID, resourceChangesSummary(p.Clusters),
+			resourceChangesSummary(p.Listeners), resourceChangesSummary(p.Routes))
+	}
+	_ = w.Flush()
+}
+
+// resourceChangesSummary returns the numbers of added, removed and modified resources.
+func resourceChangesSummary(c v2.ResourceChanges) string {
+	return fmt.Sprintf("+%d -%d ~%d", len(c.Added), len(c.Removed), len(c.Modified))
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	f, err := ioutil.TempFile("", "dryrun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_ = f.Close()
+
+	reports := map[string][]byte{
+		"istio-pilot-1": []byte(`{"configs": ["virtual-service/default/reviews"],
+			"warnings": ["pilot_conflict_outbound_listener_http_over_current_tcp: 0.0.0.0:9080 conflict"],
+			"proxies": [{"proxy": "reviews-v1.default", "routes": {"modified": ["9080"]}}]}`),
+		"istio-pilot-2": []byte(`{"configs": ["virtual-service/default/reviews"],
+			"warnings": ["pilot_conflict_outbound_listener_http_over_current_tcp: 0.0.0.0:9080 conflict"],
+			"proxies": [{"proxy": "productpage-v1.default", "clusters": {"added": ["a", "b"]}, "routes": {"modified": ["9080"]}}]}`),
+	}
+	cases := []execTestCase{
+		{
+			args:          strings.Split("experimental dry-run", " "),
+			wantException: true,
+		},
+		{
+			execClientConfig: reports,
+			args:             strings.Split("experimental dry-run -f "+f.Name(), " "),
+			expectedOutput: `Config: virtual-service/default/reviews
+Warning: pilot_conflict_outbound_listener_http_over_current_tcp: 0.0.0.0:9080 conflict
+2 proxies would be pushed
+PROXY                      CLUSTERS     LISTENERS     ROUTES
+productpage-v1.default     +2 -0 ~0     +0 -0 ~0      +0 -0 ~1
+reviews-v1.default         +0 -0 ~0     +0 -0 ~0      +0 -0 ~1
+`,
+		},
+		{
+			execClientConfig: map[string][]byte{"istio-pilot-1": []byte("invalid configs: missing hosts")},
+			args:             strings.Split("experimental dry-run -f "+f.Name(), " "),
+			wantException:    true,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			dryRunFilename = ""
+			verifyExecTestOutput(t, c)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	prommodel "github.com/prometheus/common/model"
+	"github.com/spf13/cobra"
+
+	networking "istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/istioctl/pkg/kubernetes"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schemas"
+	"istio.io/pkg/log"
+)
+
+const (
+	tcpConnOpened = "istio_tcp_connections_opened_total"
+	svcLabel      = "destination_service"
+	svcNsLabel    = "destination_service_namespace"
+	unknownLabel  = "unknown"
+)
+
+var trafficWindow time.Duration
+
+func generateSidecarCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "generate-sidecar <namespace>...",
+		Short: "Generates least-privilege Sidecar resources from the traffic observed in Kubernetes.",
+		Long: `
+Generates a default Sidecar resource for each of the specified namespaces, restricting the
+egress of their workloads to the services they called during the observation window.
+
+This command finds a Prometheus pod running in the specified istio system namespace. It then
+queries, per namespace, the services which received HTTP requests or TCP connections reported
+by the workloads of the namespace. The egress hosts of the generated Sidecar are these services,
+and the services of the istio system namespace. Review the generated resources before applying
+them: services not called during the window, e.g. by rare batch jobs, are not included.
+`,
+		Example: `
+# Generate the Sidecar of the bookinfo namespace from the traffic of the last day
+istioctl experimental generate-sidecar bookinfo
+
+# Generate the Sidecars of several namespaces from the traffic of the last week
+istioctl experimental generate-sidecar foo bar --window 168h | kubectl apply -f -
+`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("generate-sidecar requires a namespace")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			client, err := clientExecFactory(kubeconfig, configContext)
+			if err != nil {
+				return fmt.Errorf("failed to create k8s client: %v", err)
+			}
+
+			pl, err := client.PodsForSelector(istioNamespace, "app=prometheus")
+			if err != nil {
+				return fmt.Errorf("not able to locate Prometheus pod: %v", err)
+			}
+			if len(pl.Items) < 1 {
+				return errors.New("no Prometheus pods found")
+			}
+
+			fw, err := client.BuildPortForwarder(pl.Items[0].Name, istioNamespace, 0, 9090)
+			if err != nil {
+				return fmt.Errorf("could not build port forwarder for prometheus: %v", err)
+			}
+			if err = kubernetes.RunPortForwarder(fw, func(fw *kubernetes.PortForward) error {
+				defer close(fw.StopChannel)
+				promAPI, err := prometheusAPI(fw.LocalPort)
+				if err != nil {
+					return err
+				}
+
+				configs := make([]model.Config, 0, len(args))
+				for _, ns := range args {
+					sidecar, err := sidecarFromTraffic(promAPI, ns, trafficWindow)
+					if err != nil {
+						return fmt.Errorf("could not generate the sidecar of namespace %q: %v", ns, err)
+					}
+					configs = append(configs, sidecar)
+				}
+				writeYAMLOutput(schema.Set{schemas.Sidecar}, configs, c.OutOrStdout())
+				return nil
+			}); err != nil {
+				return fmt.Errorf("failure running port forward process: %v", err)
+			}
+			return nil
+		},
+	}
+	cmd.PersistentFlags().DurationVar(&trafficWindow, "window", 24*time.Hour,
+		"Window of the traffic observed to generate the Sidecar resources")
+	return cmd
+}
+
+// sidecarFromTraffic returns the default Sidecar of the namespace, with the services its workloads
+// called during the window as egress hosts.
+func sidecarFromTraffic(promAPI promv1.API, namespace string, window time.Duration) (model.Config, error) {
+	hosts := map[string]struct{}{istioNamespace + "/*": {}}
+	for _, metric := range []string{reqTot, tcpConnOpened} {
+		query := fmt.Sprintf(`sum(increase(%s{reporter="source", source_workload_namespace=%q}[%s])) by (%s, %s) > 0`,
+			metric, namespace, prommodel.Duration(window), svcNsLabel, svcLabel)
+		log.Debugf("executing query: %s", query)
+		val, err := promAPI.Query(context.Background(), query, time.Now())
+		if err != nil {
+			return model.Config{}, fmt.Errorf("query() failure for '%s': %v", query, err)
+		}
+		samples, ok := val.(prommodel.Vector)
+		if !ok {
+			return model.Config{}, errors.New("bad metric value type returned for query")
+		}
+		for _, sample := range samples {
+			svc := string(sample.Metric[svcLabel])
+			if svc == "" || svc == unknownLabel {
+				continue
+			}
+			// Services of ServiceEntries have no namespace in the telemetry.
+			svcNs := string(sample.Metric[svcNsLabel])
+			if svcNs == "" || svcNs == unknownLabel {
+				svcNs = "*"
+			}
+			hosts[svcNs+"/"+svc] = struct{}{}
+		}
+	}
+
+	egressHosts := make([]string, 0, len(hosts))
+	for h := range hosts {
+		egressHosts = append(egressHosts, h)
+	}
+	sort.Strings(egressHosts)
+
+	return model.Config{
+		ConfigMeta: model.ConfigMeta{
+			Type:      schemas.Sidecar.Type,
+			Group:     schemas.Sidecar.Group,
+			Version:   schemas.Sidecar.Version,
+			Name:      "default",
+			Namespace: namespace,
+		},
+		Spec: &networking.Sidecar{
+			Egress: []*networking.IstioEgressListener{{Hosts: egressHosts}},
+		},
+	}, nil
+}
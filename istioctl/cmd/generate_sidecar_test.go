@@ -0,0 +1,64 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	prometheus_model "github.com/prometheus/common/model"
+
+	networking "istio.io/api/networking/v1alpha3"
+)
+
+func TestSidecarFromTraffic(t *testing.T) {
+	defer func(ns string) { istioNamespace = ns }(istioNamespace)
+	istioNamespace = "istio-system"
+
+	mockProm := mockPromAPI{
+		cannedResponse: map[string]prometheus_model.Value{
+			`sum(increase(istio_requests_total{reporter="source", source_workload_namespace="bookinfo"}[1d])) by (destination_service_namespace, destination_service) > 0`: prometheus_model.Vector{ // nolint: lll
+				&prometheus_model.Sample{Metric: prometheus_model.Metric{
+					"destination_service_namespace": "bookinfo", "destination_service": "reviews.bookinfo.svc.cluster.local"}},
+				&prometheus_model.Sample{Metric: prometheus_model.Metric{
+					"destination_service_namespace": "unknown", "destination_service": "api.example.com"}},
+				&prometheus_model.Sample{Metric: prometheus_model.Metric{
+					"destination_service_namespace": "unknown", "destination_service": "unknown"}},
+			},
+			`sum(increase(istio_tcp_connections_opened_total{reporter="source", source_workload_namespace="bookinfo"}[1d])) by (destination_service_namespace, destination_service) > 0`: prometheus_model.Vector{ // nolint: lll
+				&prometheus_model.Sample{Metric: prometheus_model.Metric{
+					"destination_service_namespace": "db", "destination_service": "mysql.db.svc.cluster.local"}},
+			},
+		},
+	}
+
+	cfg, err := sidecarFromTraffic(mockProm, "bookinfo", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Unwanted exception %v", err)
+	}
+	if cfg.Name != "default" || cfg.Namespace != "bookinfo" {
+		t.Errorf("unexpected sidecar %s/%s", cfg.Namespace, cfg.Name)
+	}
+	want := []string{
+		"*/api.example.com",
+		"bookinfo/reviews.bookinfo.svc.cluster.local",
+		"db/mysql.db.svc.cluster.local",
+		"istio-system/*",
+	}
+	if got := cfg.Spec.(*networking.Sidecar).Egress[0].Hosts; !reflect.DeepEqual(got, want) {
+		t.Errorf("got egress hosts %v, want %v", got, want)
+	}
+}
@@ -157,6 +157,7 @@ var (
 	valuesFile          string
 	injectConfigFile    string
 	injectConfigMapName string
+	revision            string
 )
 
 const (
@@ -205,11 +206,22 @@ istioctl kube-inject -f samples/bookinfo/platform/kube/bookinfo.yaml \
 	--injectConfigFile /tmp/inj-template.tmpl \
 	--meshConfigFile /tmp/mesh.yaml \
 	--valuesFile /tmp/values.json
+
+# Inject using the configuration of the "canary" control plane revision
+istioctl kube-inject -f deployment.yaml --revision canary
 `,
 		RunE: func(c *cobra.Command, _ []string) (err error) {
 			if err = validateFlags(); err != nil {
 				return err
 			}
+			if revision != "" {
+				if !c.Flags().Changed("meshConfigMapName") {
+					meshConfigMapName = defaultMeshConfigMapName + "-" + revision
+				}
+				if !c.Flags().Changed("injectConfigMapName") {
+					injectConfigMapName = defaultInjectConfigMapName + "-" + revision
+				}
+			}
 
 			var reader io.Reader
 			if !emitTemplate {
@@ -336,6 +348,8 @@ istioctl kube-inject -f samples/bookinfo/platform/kube/bookinfo.yaml \
 		fmt.Sprintf("ConfigMap name for Istio mesh configuration, key should be %q", configMapKey))
 	injectCmd.PersistentFlags().StringVar(&injectConfigMapName, "injectConfigMapName", defaultInjectConfigMapName,
 		fmt.Sprintf("ConfigMap name for Istio sidecar injection, key should be %q.", injectConfigMapKey))
+	injectCmd.PersistentFlags().StringVar(&revision, "revision", "",
+		"Control plane revision. The configuration of the revision is used unless ConfigMap names are set explicitly")
 
 	return injectCmd
 }
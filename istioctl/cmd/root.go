@@ -121,14 +121,19 @@ debug and diagnose their Istio mesh.
 	experimentalCmd.AddCommand(graduatedCmd("dashboard"))
 	experimentalCmd.AddCommand(uninjectCommand())
 	experimentalCmd.AddCommand(metricsCmd)
+	experimentalCmd.AddCommand(generateSidecarCmd())
+	experimentalCmd.AddCommand(dryRunCmd())
+	experimentalCmd.AddCommand(shiftTrafficCmd())
 	experimentalCmd.AddCommand(describe())
 	experimentalCmd.AddCommand(addToMeshCmd())
 	experimentalCmd.AddCommand(removeFromMeshCmd())
+	experimentalCmd.AddCommand(uninstallCmd())
 	experimentalCmd.AddCommand(Analyze())
 
 	manifestCmd := mesh.ManifestCmd()
 	hideInheritedFlags(manifestCmd, "namespace", "istioNamespace")
-	experimentalCmd.AddCommand(manifestCmd)
+	rootCmd.AddCommand(manifestCmd)
+	experimentalCmd.AddCommand(graduatedCmd("manifest"))
 
 	profileCmd := mesh.ProfileCmd()
 	hideInheritedFlags(profileCmd, "namespace", "istioNamespace")
@@ -0,0 +1,323 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	prommodel "github.com/prometheus/common/model"
+	"github.com/spf13/cobra"
+
+	networking "istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/istioctl/pkg/kubernetes"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/schemas"
+)
+
+// shiftOptions are the options of a progressive traffic shift.
+type shiftOptions struct {
+	subset       string
+	step         int32
+	interval     time.Duration
+	maxErrorRate float64
+	maxLatency   time.Duration
+}
+
+var (
+	shiftOpts shiftOptions
+
+	// shiftSleep waits for the interval between two steps, replaced in tests.
+	shiftSleep = time.Sleep
+)
+
+func shiftTrafficCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "shift-traffic <virtual-service>[.<namespace>] --subset <subset>",
+		Short: "Progressively shifts the traffic of a VirtualService to a subset [kube only]",
+		Long: `
+Progressively shifts the traffic of the HTTP routes of a VirtualService to a subset of their destination.
+
+The weight of the subset is increased by the step every interval, the other destinations of the routes
+sharing the rest of the traffic in proportion to their weights. Before each step, the error rate and the
+p99 latency of the requests received by the subset during the interval are queried from the Prometheus
+pod of the istio system namespace. If they breach the thresholds, the VirtualService is rolled back to
+its original routes, as it is if the subset received no requests during the interval. The command runs
+until all the traffic is shifted or rolled back.
+`,
+		Example: `
+# Shift the traffic of reviews to the v2 subset by 10% every 5 minutes
+istioctl experimental shift-traffic reviews.bookinfo --subset v2
+
+# Shift by 25% every minute, rolling back above 1% errors or 500ms p99 latency
+istioctl experimental shift-traffic reviews --subset v2 --step 25 --interval 1m --max-error-rate 0.01 --max-latency 500ms
+`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return errors.New("shift-traffic requires a virtual service")
+			}
+			if shiftOpts.subset == "" {
+				return errors.New("shift-traffic requires a subset")
+			}
+			if shiftOpts.step <= 0 || shiftOpts.step > 100 {
+				return fmt.Errorf("invalid step %d, must be between 1 and 100", shiftOpts.step)
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			name, ns := handlers.InferPodInfo(args[0], handlers.HandleNamespace(namespace, defaultNamespace))
+			configClient, err := clientFactory()
+			if err != nil {
+				return err
+			}
+			client, err := clientExecFactory(kubeconfig, configContext)
+			if err != nil {
+				return fmt.Errorf("failed to create k8s client: %v", err)
+			}
+
+			pl, err := client.PodsForSelector(istioNamespace, "app=prometheus")
+			if err != nil {
+				return fmt.Errorf("not able to locate Prometheus pod: %v", err)
+			}
+			if len(pl.Items) < 1 {
+				return errors.New("no Prometheus pods found")
+			}
+			fw, err := client.BuildPortForwarder(pl.Items[0].Name, istioNamespace, 0, 9090)
+			if err != nil {
+				return fmt.Errorf("could not build port forwarder for prometheus: %v", err)
+			}
+			return kubernetes.RunPortForwarder(fw, func(fw *kubernetes.PortForward) error {
+				defer close(fw.StopChannel)
+				promAPI, err := prometheusAPI(fw.LocalPort)
+				if err != nil {
+					return err
+				}
+				return shiftTraffic(c.OutOrStdout(), configClient, promAPI, name, ns, shiftOpts)
+			})
+		},
+	}
+	cmd.PersistentFlags().StringVar(&shiftOpts.subset, "subset", "", "Subset the traffic is shifted to")
+	cmd.PersistentFlags().Int32Var(&shiftOpts.step, "step", 10, "Percentage of the traffic shifted at each step")
+	cmd.PersistentFlags().DurationVar(&shiftOpts.interval, "interval", 5*time.Minute, "Interval between two steps")
+	cmd.PersistentFlags().Float64Var(&shiftOpts.maxErrorRate, "max-error-rate", 0.05,
+		"Maximum ratio of 5xx responses of the subset before rolling back")
+	cmd.PersistentFlags().DurationVar(&shiftOpts.maxLatency, "max-latency", 0,
+		"Maximum p99 latency of the subset before rolling back, 0 to not check the latency")
+	return cmd
+}
+
+// shiftTraffic shifts the traffic of the virtual service to the subset step by step, and rolls it
+// back if the subset breaches the thresholds.
+func shiftTraffic(w io.Writer, configClient model.ConfigStore, promAPI promv1.API, name, namespace string,
+	opts shiftOptions) error {
+	vs := configClient.Get(schemas.VirtualService.Type, name, namespace)
+	if vs == nil {
+		return fmt.Errorf("virtual service %s.%s not found", name, namespace)
+	}
+	original := proto.Clone(vs.Spec.(*networking.VirtualService)).(*networking.VirtualService)
+	host := shiftHost(original)
+	if host == "" {
+		return fmt.Errorf("virtual service %s.%s has no HTTP route", name, namespace)
+	}
+	version := subsetVersion(configClient, namespace, host, opts.subset)
+	service := shiftServiceFQDN(host, namespace)
+
+	for weight := opts.step; ; weight += opts.step {
+		if weight > 100 {
+			weight = 100
+		}
+		if err := updateVirtualService(configClient, name, namespace, func(spec *networking.VirtualService) error {
+			return shiftRoutes(spec, host, opts.subset, weight)
+		}); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Shifted %d%% of the traffic of %s to subset %s\n", weight, host, opts.subset)
+		if weight == 100 {
+			return nil
+		}
+
+		shiftSleep(opts.interval)
+		errorRate, latency, err := subsetHealth(promAPI, service, version, opts.interval)
+		if err == nil {
+			fmt.Fprintf(w, "Subset %s: %.2f%% errors, %v p99 latency\n", opts.subset, errorRate*100, latency)
+			if errorRate > opts.maxErrorRate {
+				err = fmt.Errorf("error rate %.2f%% above %.2f%%", errorRate*100, opts.maxErrorRate*100)
+			} else if opts.maxLatency > 0 && latency > opts.maxLatency {
+				err = fmt.Errorf("p99 latency %v above %v", latency, opts.maxLatency)
+			}
+		}
+		if err != nil {
+			if rbErr := updateVirtualService(configClient, name, namespace, func(spec *networking.VirtualService) error {
+				spec.Reset()
+				proto.Merge(spec, original)
+				return nil
+			}); rbErr != nil {
+				return fmt.Errorf("%v, and the rollback failed: %v", err, rbErr)
+			}
+			fmt.Fprintf(w, "Rolled back the traffic of %s\n", host)
+			return fmt.Errorf("traffic shift to subset %s rolled back: %v", opts.subset, err)
+		}
+	}
+}
+
+// updateVirtualService applies the update to the current virtual service.
+func updateVirtualService(configClient model.ConfigStore, name, namespace string,
+	update func(spec *networking.VirtualService) error) error {
+	vs := configClient.Get(schemas.VirtualService.Type, name, namespace)
+	if vs == nil {
+		return fmt.Errorf("virtual service %s.%s not found", name, namespace)
+	}
+	spec := proto.Clone(vs.Spec.(*networking.VirtualService)).(*networking.VirtualService)
+	if err := update(spec); err != nil {
+		return err
+	}
+	vs.Spec = spec
+	_, err := configClient.Update(*vs)
+	return err
+}
+
+// shiftHost returns the destination host of the first HTTP route, whose traffic is shifted.
+func shiftHost(vs *networking.VirtualService) string {
+	for _, route := range vs.Http {
+		if len(route.Route) > 0 {
+			return route.Route[0].Destination.GetHost()
+		}
+	}
+	return ""
+}
+
+// shiftServiceFQDN returns the FQDN of the destination host, resolving the short names of the
+// namespace of the virtual service as the destination_service label of the metrics does.
+func shiftServiceFQDN(host, namespace string) string {
+	switch strings.Count(host, ".") {
+	case 0:
+		return host + "." + namespace + k8sSuffix
+	case 1:
+		return host + k8sSuffix
+	default:
+		return host
+	}
+}
+
+// shiftRoutes sets the weight of the subset in the HTTP routes to the host. The other destinations
+// of the routes share the rest of the traffic in proportion to their weights.
+func shiftRoutes(vs *networking.VirtualService, host, subset string, weight int32) error {
+	shifted := false
+	for _, route := range vs.Http {
+		var target *networking.HTTPRouteDestination
+		var others []*networking.HTTPRouteDestination
+		sameHost := len(route.Route) > 0
+		for _, d := range route.Route {
+			if d.Destination.GetHost() != host {
+				sameHost = false
+			} else if d.Destination.Subset == subset {
+				target = d
+			} else {
+				others = append(others, d)
+			}
+		}
+		if !sameHost || len(others) == 0 {
+			continue
+		}
+		if target == nil {
+			target = &networking.HTTPRouteDestination{
+				Destination: &networking.Destination{Host: host, Subset: subset, Port: others[0].Destination.Port},
+			}
+			route.Route = append(route.Route, target)
+		}
+
+		var total int32
+		for _, d := range others {
+			total += d.Weight
+		}
+		remaining := 100 - weight
+		assigned := int32(0)
+		for _, d := range others {
+			if total > 0 {
+				d.Weight = remaining * d.Weight / total
+			} else {
+				d.Weight = remaining / int32(len(others))
+			}
+			assigned += d.Weight
+		}
+		// Hand out the traffic left over by the rounding down.
+		others[0].Weight += remaining - assigned
+		target.Weight = weight
+		shifted = true
+	}
+	if !shifted {
+		return fmt.Errorf("no HTTP route to %s has a destination other than subset %s", host, subset)
+	}
+	return nil
+}
+
+// subsetVersion returns the version label of the subset in the destination rules of the
+// namespace, which identifies the subset in the metrics, or the subset name if there is none.
+func subsetVersion(configClient model.ConfigStore, namespace, host, subset string) string {
+	drs, err := configClient.List(schemas.DestinationRule.Type, namespace)
+	if err != nil {
+		return subset
+	}
+	for _, dr := range drs {
+		rule := dr.Spec.(*networking.DestinationRule)
+		if rule.Host != host && !strings.HasPrefix(host, rule.Host+".") {
+			continue
+		}
+		for _, s := range rule.Subsets {
+			if v, f := s.Labels["version"]; f && s.Name == subset {
+				return v
+			}
+		}
+	}
+	return subset
+}
+
+// subsetHealth returns the error rate and the p99 latency of the requests received by the subset
+// during the window. It fails if the subset received no requests, as there is then no data to
+// check the thresholds against.
+func subsetHealth(promAPI promv1.API, host, version string, window time.Duration) (float64, time.Duration, error) {
+	selector := fmt.Sprintf(`reporter="destination", destination_service=%q, destination_version=%q`, host, version)
+	total, err := vectorValue(promAPI, fmt.Sprintf(`sum(rate(%s{%s}[%s]))`, reqTot, selector, prommodel.Duration(window)))
+	if err != nil {
+		return 0, 0, err
+	}
+	if total == 0 {
+		return 0, 0, fmt.Errorf("no requests received by %s version %s during the last %v", host, version, window)
+	}
+	errorRPS, err := vectorValue(promAPI, fmt.Sprintf(`sum(rate(%s{%s, response_code=~"5.."}[%s]))`,
+		reqTot, selector, prommodel.Duration(window)))
+	if err != nil {
+		return 0, 0, err
+	}
+	p99, err := vectorValue(promAPI, fmt.Sprintf(`histogram_quantile(0.99, sum(rate(%s_bucket{%s}[%s])) by (le))`,
+		reqDur, selector, prommodel.Duration(window)))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if math.IsNaN(p99) {
+		p99 = 0
+	}
+	return errorRPS / total, time.Duration(p99*1000) * time.Millisecond, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	prometheus_model "github.com/prometheus/common/model"
+
+	networking "istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/schemas"
+)
+
+func routeWeights(vs *networking.VirtualService) map[string]int32 {
+	out := map[string]int32{}
+	for _, d := range vs.Http[0].Route {
+		out[d.Destination.Subset] = d.Weight
+	}
+	return out
+}
+
+func TestShiftRoutes(t *testing.T) {
+	vs := &networking.VirtualService{
+		Hosts: []string{"reviews"},
+		Http: []*networking.HTTPRoute{{
+			Route: []*networking.HTTPRouteDestination{
+				{Destination: &networking.Destination{Host: "reviews", Subset: "v1"}, Weight: 75},
+				{Destination: &networking.Destination{Host: "reviews", Subset: "v3"}, Weight: 25},
+			},
+		}},
+	}
+	if err := shiftRoutes(vs, "reviews", "v2", 10); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := routeWeights(vs), map[string]int32{"v1": 68, "v2": 10, "v3": 22}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got weights %v, want %v", got, want)
+	}
+	if err := shiftRoutes(vs, "reviews", "v2", 100); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := routeWeights(vs), map[string]int32{"v1": 0, "v2": 100, "v3": 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got weights %v, want %v", got, want)
+	}
+
+	only := &networking.VirtualService{
+		Http: []*networking.HTTPRoute{{
+			Route: []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: "reviews", Subset: "v2"}}},
+		}},
+	}
+	if err := shiftRoutes(only, "reviews", "v2", 10); err == nil {
+		t.Error("expected an error shifting a route without other destinations")
+	}
+}
+
+func TestShiftTraffic(t *testing.T) {
+	defer func(sleep func(time.Duration)) { shiftSleep = sleep }(shiftSleep)
+	shiftSleep = func(time.Duration) {}
+
+	newStore := func() model.ConfigStore {
+		store := memory.Make(schemas.Istio)
+		_, err := store.Create(model.Config{
+			ConfigMeta: model.ConfigMeta{
+				Type:      schemas.VirtualService.Type,
+				Group:     schemas.VirtualService.Group,
+				Version:   schemas.VirtualService.Version,
+				Name:      "reviews",
+				Namespace: "bookinfo",
+			},
+			Spec: &networking.VirtualService{
+				Hosts: []string{"reviews.bookinfo.svc.cluster.local"},
+				Http: []*networking.HTTPRoute{{
+					Route: []*networking.HTTPRouteDestination{
+						{Destination: &networking.Destination{Host: "reviews", Subset: "v1"}},
+					},
+				}},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}
+	opts := shiftOptions{subset: "v2", step: 40, interval: time.Minute, maxErrorRate: 0.05}
+	selector := `reporter="destination", destination_service="reviews.bookinfo.svc.cluster.local", destination_version="v2"`
+
+	healthy := mockPromAPI{cannedResponse: map[string]prometheus_model.Value{
+		`sum(rate(istio_requests_total{` + selector + `}[1m]))`: prometheus_model.Vector{
+			&prometheus_model.Sample{Value: 10}},
+	}}
+	store := newStore()
+	var out bytes.Buffer
+	if err := shiftTraffic(&out, store, healthy, "reviews", "bookinfo", opts); err != nil {
+		t.Fatalf("unexpected error %v, output %s", err, out.String())
+	}
+	vs := store.Get(schemas.VirtualService.Type, "reviews", "bookinfo").Spec.(*networking.VirtualService)
+	if got, want := routeWeights(vs), map[string]int32{"v1": 0, "v2": 100}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got weights %v, want %v", got, want)
+	}
+
+	failing := mockPromAPI{cannedResponse: map[string]prometheus_model.Value{
+		`sum(rate(istio_requests_total{` + selector + `}[1m]))`: prometheus_model.Vector{
+			&prometheus_model.Sample{Value: 10}},
+		`sum(rate(istio_requests_total{` + selector + `, response_code=~"5.."}[1m]))`: prometheus_model.Vector{
+			&prometheus_model.Sample{Value: 2}},
+	}}
+	store = newStore()
+	out.Reset()
+	if err := shiftTraffic(&out, store, failing, "reviews", "bookinfo", opts); err == nil {
+		t.Fatalf("expected the traffic shift to be rolled back, output %s", out.String())
+	}
+	vs = store.Get(schemas.VirtualService.Type, "reviews", "bookinfo").Spec.(*networking.VirtualService)
+	if got, want := routeWeights(vs), map[string]int32{"v1": 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got weights %v after the rollback, want %v", got, want)
+	}
+
+	// Without any request to the subset there is nothing to check, and the shift stops.
+	store = newStore()
+	out.Reset()
+	if err := shiftTraffic(&out, store, mockPromAPI{}, "reviews", "bookinfo", opts); err == nil {
+		t.Fatalf("expected the traffic shift without requests to be rolled back, output %s", out.String())
+	}
+	vs = store.Get(schemas.VirtualService.Type, "reviews", "bookinfo").Spec.(*networking.VirtualService)
+	if got, want := routeWeights(vs), map[string]int32{"v1": 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got weights %v without requests, want %v", got, want)
+	}
+}
+
+func TestShiftServiceFQDN(t *testing.T) {
+	cases := map[string]string{
+		"reviews":                            "reviews.bookinfo.svc.cluster.local",
+		"reviews.other":                      "reviews.other.svc.cluster.local",
+		"reviews.bookinfo.svc.cluster.local": "reviews.bookinfo.svc.cluster.local",
+		"api.example.com":                    "api.example.com",
+	}
+	for host, want := range cases {
+		if got := shiftServiceFQDN(host, "bookinfo"); got != want {
+			t.Errorf("shiftServiceFQDN(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
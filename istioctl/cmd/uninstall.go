@@ -0,0 +1,425 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/multierr"
+	admissionv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/api/annotation"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/kube"
+)
+
+const (
+	// istioSecretType is the type of the key and certificate secrets Citadel generates for the
+	// service accounts of every namespace.
+	istioSecretType = "istio.io/key-and-cert"
+)
+
+var apiExtensionsFactory = createAPIExtensionsInterface
+
+type uninstallOptions struct {
+	revision  string
+	purge     bool
+	pruneCRDs bool
+	dryRun    bool
+}
+
+// uninstallResource is a resource removed by uninstall.
+type uninstallResource struct {
+	kind      string
+	namespace string
+	name      string
+	remove    func() error
+}
+
+func (r uninstallResource) String() string {
+	if r.namespace == "" {
+		return r.kind + " " + r.name
+	}
+	return fmt.Sprintf("%s %s/%s", r.kind, r.namespace, r.name)
+}
+
+// resourceKind gets and deletes the resources of a kind by name.
+type resourceKind struct {
+	kind      string
+	namespace string
+	get       func(name string) error
+	delete    func(name string, options *metav1.DeleteOptions) error
+}
+
+func uninstallCmd() *cobra.Command {
+	opts := uninstallOptions{}
+	cmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: "Uninstall a control plane revision, or the whole Istio control plane",
+		Long: `istioctl experimental uninstall removes the deployments, services, config maps and
+webhooks of a control plane revision installed with --set global.revision=<revision>.
+
+With --purge, the whole control plane is removed instead: the Istio namespace, the webhooks
+and cluster roles of the control plane and the key and certificate secrets generated by
+Citadel in every namespace. The Istio CRDs, and with them all of the Istio configuration,
+are only removed with --prune-crds.
+
+Pods keep running the sidecars injected from the removed control plane until they are
+restarted, they are listed once the removal is done.
+THIS COMMAND IS STILL UNDER ACTIVE DEVELOPMENT AND NOT READY FOR PRODUCTION USE.
+`,
+		Example: `# Remove the "canary" control plane revision
+istioctl experimental uninstall --revision canary
+
+# List what removing the whole control plane, including the Istio CRDs, would delete
+istioctl experimental uninstall --purge --prune-crds --dry-run`,
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("uninstall takes no arguments")
+			}
+			if (opts.revision == "") == !opts.purge {
+				return errors.New("exactly one of --revision and --purge must be set")
+			}
+			if opts.pruneCRDs && !opts.purge {
+				return errors.New("--prune-crds requires --purge")
+			}
+
+			client, err := interfaceFactory(kubeconfig)
+			if err != nil {
+				return err
+			}
+			var crdClient apiextensionsclient.Interface
+			if opts.pruneCRDs {
+				if crdClient, err = apiExtensionsFactory(kubeconfig); err != nil {
+					return err
+				}
+			}
+			return uninstall(client, crdClient, istioNamespace, opts, c.OutOrStdout())
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&opts.revision, "revision", "",
+		"Control plane revision to remove")
+	cmd.PersistentFlags().BoolVar(&opts.purge, "purge", false,
+		"Remove the whole control plane, including all of its revisions")
+	cmd.PersistentFlags().BoolVar(&opts.pruneCRDs, "prune-crds", false,
+		"Also remove the Istio CRDs, deleting all Istio configuration. Requires --purge")
+	cmd.PersistentFlags().BoolVar(&opts.dryRun, "dry-run", false,
+		"List the resources that would be removed without removing them")
+	return cmd
+}
+
+func createAPIExtensionsInterface(kubeconfig string) (apiextensionsclient.Interface, error) {
+	restConfig, err := kube.BuildClientConfig(kubeconfig, configContext)
+	if err != nil {
+		return nil, err
+	}
+	return apiextensionsclient.NewForConfig(restConfig)
+}
+
+func uninstall(client kubernetes.Interface, crdClient apiextensionsclient.Interface, ns string,
+	opts uninstallOptions, writer io.Writer) error {
+	var resources []uninstallResource
+	var err error
+	if opts.purge {
+		resources, err = controlPlaneResources(client, crdClient, ns)
+	} else {
+		resources, err = revisionResources(client, ns, opts.revision)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(resources) == 0 {
+		fmt.Fprintf(writer, "No control plane resources found in %s\n", ns)
+	} else if opts.dryRun {
+		fmt.Fprintln(writer, "The following resources would be removed:")
+		for _, r := range resources {
+			fmt.Fprintf(writer, "  %v\n", r)
+		}
+	} else {
+		var errs error
+		for _, r := range resources {
+			if err := r.remove(); err != nil && !apierrors.IsNotFound(err) {
+				errs = multierr.Append(errs, fmt.Errorf("failed to remove %v: %v", r, err))
+				continue
+			}
+			fmt.Fprintf(writer, "Removed %v\n", r)
+		}
+		if errs != nil {
+			return errs
+		}
+	}
+
+	pods, err := injectedPods(client, opts.revision)
+	if err != nil {
+		return err
+	}
+	if len(pods) > 0 {
+		if opts.revision != "" {
+			fmt.Fprintf(writer, "\nThe following pods still run sidecars injected by revision %s, "+
+				"restart them to move them to another revision:\n", opts.revision)
+		} else {
+			fmt.Fprintln(writer, "\nThe following pods still run injected sidecars, restart them to remove the sidecars:")
+		}
+		for _, pod := range pods {
+			fmt.Fprintf(writer, "  %s\n", pod)
+		}
+	}
+	return nil
+}
+
+// revisionResources returns the existing resources of a control plane revision. They are
+// named after the resources of the default control plane, suffixed with the revision, as done
+// by the istio.revision.suffix template of the Helm charts.
+func revisionResources(client kubernetes.Interface, ns, revision string) ([]uninstallResource, error) {
+	pilot := "istio-pilot-" + revision
+	injector := "istio-sidecar-injector-" + revision
+	workloads := []string{pilot, injector}
+
+	// The webhook goes first, so that no pod is injected while the revision is being removed.
+	candidates := []struct {
+		kind  resourceKind
+		names []string
+	}{
+		{mutatingWebhookKind(client), []string{injector}},
+		{resourceKind{
+			kind:      "Deployment",
+			namespace: ns,
+			get: func(name string) error {
+				_, err := client.AppsV1().Deployments(ns).Get(name, metav1.GetOptions{})
+				return err
+			},
+			delete: client.AppsV1().Deployments(ns).Delete,
+		}, workloads},
+		{resourceKind{
+			kind:      "HorizontalPodAutoscaler",
+			namespace: ns,
+			get: func(name string) error {
+				_, err := client.AutoscalingV1().HorizontalPodAutoscalers(ns).Get(name, metav1.GetOptions{})
+				return err
+			},
+			delete: client.AutoscalingV1().HorizontalPodAutoscalers(ns).Delete,
+		}, []string{pilot}},
+		{resourceKind{
+			kind:      "PodDisruptionBudget",
+			namespace: ns,
+			get: func(name string) error {
+				_, err := client.PolicyV1beta1().PodDisruptionBudgets(ns).Get(name, metav1.GetOptions{})
+				return err
+			},
+			delete: client.PolicyV1beta1().PodDisruptionBudgets(ns).Delete,
+		}, workloads},
+		{resourceKind{
+			kind:      "Service",
+			namespace: ns,
+			get: func(name string) error {
+				_, err := client.CoreV1().Services(ns).Get(name, metav1.GetOptions{})
+				return err
+			},
+			delete: client.CoreV1().Services(ns).Delete,
+		}, workloads},
+		{resourceKind{
+			kind:      "ConfigMap",
+			namespace: ns,
+			get: func(name string) error {
+				_, err := client.CoreV1().ConfigMaps(ns).Get(name, metav1.GetOptions{})
+				return err
+			},
+			delete: client.CoreV1().ConfigMaps(ns).Delete,
+		}, []string{defaultMeshConfigMapName + "-" + revision, defaultInjectConfigMapName + "-" + revision}},
+	}
+
+	var out []uninstallResource
+	for _, c := range candidates {
+		for _, name := range c.names {
+			err := c.kind.get(name)
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, newUninstallResource(c.kind, name))
+		}
+	}
+	return out, nil
+}
+
+// controlPlaneResources returns the resources of the whole control plane: its namespace, the
+// webhooks calling services in it, its cluster roles and bindings, the secrets Citadel generated
+// in other namespaces and, if crdClient is set, the Istio CRDs.
+func controlPlaneResources(client kubernetes.Interface, crdClient apiextensionsclient.Interface,
+	ns string) ([]uninstallResource, error) {
+	var out []uninstallResource
+
+	mutatingWebhooks, err := client.AdmissionregistrationV1beta1().MutatingWebhookConfigurations().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, wh := range mutatingWebhooks.Items {
+		if callsNamespace(wh.Webhooks, ns) {
+			out = append(out, newUninstallResource(mutatingWebhookKind(client), wh.Name))
+		}
+	}
+	validatingWebhooks, err := client.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, wh := range validatingWebhooks.Items {
+		if callsNamespace(wh.Webhooks, ns) {
+			out = append(out, newUninstallResource(resourceKind{
+				kind:   "ValidatingWebhookConfiguration",
+				delete: client.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Delete,
+			}, wh.Name))
+		}
+	}
+
+	if _, err := client.CoreV1().Namespaces().Get(ns, metav1.GetOptions{}); err == nil {
+		out = append(out, newUninstallResource(resourceKind{
+			kind:   "Namespace",
+			delete: client.CoreV1().Namespaces().Delete,
+		}, ns))
+	} else if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	// The cluster wide resources of the charts are named istio-<component>-<namespace>.
+	clusterRoles, err := client.RbacV1().ClusterRoles().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, cr := range clusterRoles.Items {
+		if isControlPlaneClusterResource(cr.Name, ns) {
+			out = append(out, newUninstallResource(resourceKind{
+				kind:   "ClusterRole",
+				delete: client.RbacV1().ClusterRoles().Delete,
+			}, cr.Name))
+		}
+	}
+	clusterRoleBindings, err := client.RbacV1().ClusterRoleBindings().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, crb := range clusterRoleBindings.Items {
+		if isControlPlaneClusterResource(crb.Name, ns) {
+			out = append(out, newUninstallResource(resourceKind{
+				kind:   "ClusterRoleBinding",
+				delete: client.RbacV1().ClusterRoleBindings().Delete,
+			}, crb.Name))
+		}
+	}
+
+	secrets, err := client.CoreV1().Secrets(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: "type=" + istioSecretType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range secrets.Items {
+		// The secrets of the control plane namespace go away with it.
+		if s.Type == istioSecretType && s.Namespace != ns {
+			out = append(out, newUninstallResource(resourceKind{
+				kind:      "Secret",
+				namespace: s.Namespace,
+				delete:    client.CoreV1().Secrets(s.Namespace).Delete,
+			}, s.Name))
+		}
+	}
+
+	if crdClient != nil {
+		crds, err := crdClient.ApiextensionsV1beta1().CustomResourceDefinitions().List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, crd := range crds.Items {
+			if crd.Spec.Group == "istio.io" || strings.HasSuffix(crd.Spec.Group, ".istio.io") {
+				out = append(out, newUninstallResource(resourceKind{
+					kind:   "CustomResourceDefinition",
+					delete: crdClient.ApiextensionsV1beta1().CustomResourceDefinitions().Delete,
+				}, crd.Name))
+			}
+		}
+	}
+	return out, nil
+}
+
+func newUninstallResource(kind resourceKind, name string) uninstallResource {
+	return uninstallResource{
+		kind:      kind.kind,
+		namespace: kind.namespace,
+		name:      name,
+		remove: func() error {
+			return kind.delete(name, &metav1.DeleteOptions{})
+		},
+	}
+}
+
+func mutatingWebhookKind(client kubernetes.Interface) resourceKind {
+	return resourceKind{
+		kind: "MutatingWebhookConfiguration",
+		get: func(name string) error {
+			_, err := client.AdmissionregistrationV1beta1().MutatingWebhookConfigurations().Get(name, metav1.GetOptions{})
+			return err
+		},
+		delete: client.AdmissionregistrationV1beta1().MutatingWebhookConfigurations().Delete,
+	}
+}
+
+// callsNamespace returns true if one of the webhooks calls a service in namespace ns.
+func callsNamespace(webhooks []admissionv1beta1.Webhook, ns string) bool {
+	for _, wh := range webhooks {
+		if wh.ClientConfig.Service != nil && wh.ClientConfig.Service.Namespace == ns {
+			return true
+		}
+	}
+	return false
+}
+
+func isControlPlaneClusterResource(name, ns string) bool {
+	return strings.HasPrefix(name, "istio-") && strings.HasSuffix(name, "-"+ns)
+}
+
+// injectedPods returns the pods running an injected sidecar, as name.namespace, limited to the
+// ones injected by the given revision if set.
+func injectedPods(client kubernetes.Interface, revision string) ([]string, error) {
+	opts := metav1.ListOptions{}
+	if revision != "" {
+		opts.LabelSelector = model.RevisionLabel + "=" + revision
+	}
+	pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(opts)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, pod := range pods.Items {
+		// The control plane pods carry the revision label too, but no sidecar.
+		if _, f := pod.Annotations[annotation.SidecarStatus.Name]; f {
+			out = append(out, fmt.Sprintf("%s.%s", pod.Name, pod.Namespace))
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	admissionv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func uninstallTestObjects() []runtime.Object {
+	webhook := func(name, service string) *admissionv1beta1.MutatingWebhookConfiguration {
+		return &admissionv1beta1.MutatingWebhookConfiguration{
+			ObjectMeta: metaV1.ObjectMeta{Name: name},
+			Webhooks: []admissionv1beta1.Webhook{{
+				Name: "sidecar-injector.istio.io",
+				ClientConfig: admissionv1beta1.WebhookClientConfig{
+					Service: &admissionv1beta1.ServiceReference{Namespace: "istio-system", Name: service},
+				},
+			}},
+		}
+	}
+	deployment := func(name string) *appsv1.Deployment {
+		return &appsv1.Deployment{ObjectMeta: metaV1.ObjectMeta{Name: name, Namespace: "istio-system"}}
+	}
+	pod := func(name, namespace string, labels map[string]string, injected bool) *coreV1.Pod {
+		p := &coreV1.Pod{ObjectMeta: metaV1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels}}
+		if injected {
+			p.Annotations = map[string]string{"sidecar.istio.io/status": "{}"}
+		}
+		return p
+	}
+	canary := map[string]string{"istio.io/rev": "canary"}
+
+	return []runtime.Object{
+		&coreV1.Namespace{ObjectMeta: metaV1.ObjectMeta{Name: "istio-system"}},
+		webhook("istio-sidecar-injector", "istio-sidecar-injector"),
+		webhook("istio-sidecar-injector-canary", "istio-sidecar-injector-canary"),
+		deployment("istio-pilot"),
+		deployment("istio-pilot-canary"),
+		deployment("istio-sidecar-injector-canary"),
+		&coreV1.ConfigMap{ObjectMeta: metaV1.ObjectMeta{Name: "istio-canary", Namespace: "istio-system"}},
+		&rbacv1.ClusterRole{ObjectMeta: metaV1.ObjectMeta{Name: "istio-pilot-istio-system"}},
+		&rbacv1.ClusterRole{ObjectMeta: metaV1.ObjectMeta{Name: "cluster-admin"}},
+		&coreV1.Secret{ObjectMeta: metaV1.ObjectMeta{Name: "istio.default", Namespace: "default"}, Type: istioSecretType},
+		&coreV1.Secret{ObjectMeta: metaV1.ObjectMeta{Name: "app-secret", Namespace: "default"}},
+		pod("istio-pilot-canary-1234", "istio-system", canary, false),
+		pod("productpage-1234", "default", canary, true),
+		pod("reviews-1234", "default", nil, true),
+		pod("ratings-1234", "default", nil, false),
+	}
+}
+
+func TestUninstallRevision(t *testing.T) {
+	client := fake.NewSimpleClientset(uninstallTestObjects()...)
+	var out bytes.Buffer
+	if err := uninstall(client, nil, "istio-system", uninstallOptions{revision: "canary"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `Removed MutatingWebhookConfiguration istio-sidecar-injector-canary
+Removed Deployment istio-system/istio-pilot-canary
+Removed Deployment istio-system/istio-sidecar-injector-canary
+Removed ConfigMap istio-system/istio-canary
+
+The following pods still run sidecars injected by revision canary, restart them to move them to another revision:
+  productpage-1234.default
+`
+	if got := out.String(); got != want {
+		t.Fatalf("unexpected output\n got: %q\nwant: %q", got, want)
+	}
+	if _, err := client.AppsV1().Deployments("istio-system").Get("istio-pilot-canary", metaV1.GetOptions{}); err == nil {
+		t.Error("expected the revision deployment to be removed")
+	}
+	if _, err := client.AppsV1().Deployments("istio-system").Get("istio-pilot", metaV1.GetOptions{}); err != nil {
+		t.Errorf("expected the default revision to be kept: %v", err)
+	}
+}
+
+func TestUninstallPurgeDryRun(t *testing.T) {
+	client := fake.NewSimpleClientset(uninstallTestObjects()...)
+	crdClient := apiextensionsfake.NewSimpleClientset(
+		&apiextensionsv1beta1.CustomResourceDefinition{
+			ObjectMeta: metaV1.ObjectMeta{Name: "virtualservices.networking.istio.io"},
+			Spec:       apiextensionsv1beta1.CustomResourceDefinitionSpec{Group: "networking.istio.io"},
+		},
+		&apiextensionsv1beta1.CustomResourceDefinition{
+			ObjectMeta: metaV1.ObjectMeta{Name: "certificates.certmanager.k8s.io"},
+			Spec:       apiextensionsv1beta1.CustomResourceDefinitionSpec{Group: "certmanager.k8s.io"},
+		},
+	)
+	var out bytes.Buffer
+	opts := uninstallOptions{purge: true, pruneCRDs: true, dryRun: true}
+	if err := uninstall(client, crdClient, "istio-system", opts, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `The following resources would be removed:
+  MutatingWebhookConfiguration istio-sidecar-injector
+  MutatingWebhookConfiguration istio-sidecar-injector-canary
+  Namespace istio-system
+  ClusterRole istio-pilot-istio-system
+  Secret default/istio.default
+  CustomResourceDefinition virtualservices.networking.istio.io
+
+The following pods still run injected sidecars, restart them to remove the sidecars:
+  productpage-1234.default
+  reviews-1234.default
+`
+	if got := out.String(); got != want {
+		t.Fatalf("unexpected output\n got: %q\nwant: %q", got, want)
+	}
+	if _, err := client.CoreV1().Namespaces().Get("istio-system", metaV1.GetOptions{}); err != nil {
+		t.Errorf("expected nothing to be removed in dry run mode: %v", err)
+	}
+}
+
+func TestUninstallFlags(t *testing.T) {
+	cases := []testcase{
+		{
+			description:       "revision or purge required",
+			args:              strings.Split("experimental uninstall", " "),
+			expectedException: true,
+			expectedOutput:    "Error: exactly one of --revision and --purge must be set\n",
+		},
+		{
+			description:       "revision and purge exclusive",
+			args:              strings.Split("experimental uninstall --revision canary --purge", " "),
+			expectedException: true,
+			expectedOutput:    "Error: exactly one of --revision and --purge must be set\n",
+		},
+		{
+			description:       "prune CRDs requires purge",
+			args:              strings.Split("experimental uninstall --revision canary --prune-crds", " "),
+			expectedException: true,
+			expectedOutput:    "Error: --prune-crds requires --purge\n",
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, c.description), func(t *testing.T) {
+			verifyAddToMeshOutput(t, c)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"istio.io/pkg/env"
+	"istio.io/pkg/log"
+)
+
+var (
+	exitOnApplicationExitVar = env.RegisterBoolVar("EXIT_ON_APPLICATION_EXIT", false,
+		"If enabled, pilot-agent terminates gracefully once all application processes have exited. "+
+			"Requires the pod containers to share a single process namespace.")
+
+	applicationExitPollInterval = 2 * time.Second
+)
+
+// waitForApplicationExit watches the processes of the pod process namespace and sends SIGTERM to
+// pilot-agent once the application processes, i.e. processes that are neither the pod sandbox nor
+// pilot-agent and its children, have all exited. Nothing happens until an application process
+// has been seen.
+func waitForApplicationExit(ctx context.Context, procDir string, self int) {
+	seen := false
+	ticker := time.NewTicker(applicationExitPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		apps, err := applicationProcesses(procDir, self)
+		if err != nil {
+			log.Warnf("Failed to list application processes: %v", err)
+			continue
+		}
+		if len(apps) > 0 {
+			seen = true
+			continue
+		}
+		if seen {
+			log.Info("All application processes have exited, terminating")
+			_ = syscall.Kill(self, syscall.SIGTERM)
+			return
+		}
+	}
+}
+
+// applicationProcesses returns the pids found in procDir that are neither the pod sandbox (pid 1)
+// nor self or one of its descendants.
+func applicationProcesses(procDir string, self int) ([]int, error) {
+	entries, err := ioutil.ReadDir(procDir)
+	if err != nil {
+		return nil, err
+	}
+	parents := map[int]int{}
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || !e.IsDir() {
+			continue
+		}
+		ppid, err := parentPid(filepath.Join(procDir, e.Name(), "stat"))
+		if err != nil {
+			// The process exited while listing.
+			continue
+		}
+		parents[pid] = ppid
+	}
+
+	var apps []int
+	for pid := range parents {
+		if pid == 1 || descendsFrom(pid, self, parents) {
+			continue
+		}
+		apps = append(apps, pid)
+	}
+	return apps, nil
+}
+
+// parentPid reads the parent pid from a /proc/<pid>/stat file. The command name may contain spaces,
+// so fields are counted from the closing parenthesis.
+func parentPid(statFile string) (int, error) {
+	b, err := ioutil.ReadFile(statFile)
+	if err != nil {
+		return 0, err
+	}
+	stat := string(b)
+	fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+	if len(fields) < 2 {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.Atoi(fields[1])
+}
+
+func descendsFrom(pid, ancestor int, parents map[int]int) bool {
+	for i := 0; i < len(parents) && pid > 1; i++ {
+		if pid == ancestor {
+			return true
+		}
+		pid = parents[pid]
+	}
+	return pid == ancestor
+}
@@ -0,0 +1,64 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func writeProc(t *testing.T, dir string, pid, ppid int, comm string) {
+	t.Helper()
+	p := filepath.Join(dir, fmt.Sprint(pid))
+	if err := os.MkdirAll(p, 0755); err != nil {
+		t.Fatal(err)
+	}
+	stat := fmt.Sprintf("%d (%s) S %d 1 1 0 -1", pid, comm, ppid)
+	if err := ioutil.WriteFile(filepath.Join(p, "stat"), []byte(stat), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApplicationProcesses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeProc(t, dir, 1, 0, "pause")
+	writeProc(t, dir, 7, 0, "pilot-agent")
+	writeProc(t, dir, 20, 7, "envoy")
+	writeProc(t, dir, 21, 20, "envoy worker")
+	writeProc(t, dir, 30, 0, "my app")
+	writeProc(t, dir, 31, 30, "sh")
+	if err := os.MkdirAll(filepath.Join(dir, "self"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	apps, err := applicationProcesses(dir, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Ints(apps)
+	if want := []int{30, 31}; !reflect.DeepEqual(apps, want) {
+		t.Errorf("got application processes %v, want %v", apps, want)
+	}
+}
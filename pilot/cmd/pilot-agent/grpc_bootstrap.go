@@ -0,0 +1,81 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/bootstrap"
+	"istio.io/pkg/log"
+)
+
+var (
+	grpcBootstrapOut              string
+	grpcBootstrapDiscoveryAddress string
+	grpcBootstrapDomain           string
+
+	// grpcBootstrapCmd writes the xDS bootstrap of a proxyless gRPC application, which connects to
+	// pilot directly instead of going through a sidecar. The injector runs it in an init container
+	// of the pods with the sidecar.istio.io/proxylessGRPC annotation, sharing a volume with the
+	// applications, whose GRPC_XDS_BOOTSTRAP variable points at the file.
+	grpcBootstrapCmd = &cobra.Command{
+		Use:   "grpc-bootstrap",
+		Short: "Writes the xDS bootstrap of a proxyless gRPC application",
+		RunE: func(c *cobra.Command, args []string) error {
+			ip := instanceIPVar.Get()
+			if ip == "" {
+				return fmt.Errorf("INSTANCE_IP must be set")
+			}
+			node := &model.Proxy{
+				Type:        model.SidecarProxy,
+				IPAddresses: []string{ip},
+				ID:          podNameVar.Get() + "." + podNamespaceVar.Get(),
+				DNSDomain:   getDNSDomain(grpcBootstrapDomain),
+			}
+
+			meta := map[string]string{
+				model.NodeMetadataInstanceName: podNameVar.Get(),
+				model.NodeMetadataNamespace:    podNamespaceVar.Get(),
+				model.NodeMetadataInstanceIPs:  ip,
+			}
+			out, err := bootstrap.GenerateGRPCBootstrap(grpcBootstrapDiscoveryAddress, node.ServiceNode(), meta)
+			if err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(grpcBootstrapOut, out, 0644); err != nil {
+				return err
+			}
+			log.Infof("Wrote the gRPC xDS bootstrap to %s, set %s=%s in the application",
+				grpcBootstrapOut, bootstrap.GRPCBootstrapEnv, grpcBootstrapOut)
+			return nil
+		},
+	}
+)
+
+func init() {
+	grpcBootstrapCmd.PersistentFlags().StringVar(&grpcBootstrapOut, "out", os.TempDir()+"/grpc-bootstrap.json",
+		"Path of the bootstrap file to write")
+	grpcBootstrapCmd.PersistentFlags().StringVar(&grpcBootstrapDiscoveryAddress, "discoveryAddress",
+		"istio-pilot.istio-system:15010", "Address of the plain text xDS port of pilot")
+	grpcBootstrapCmd.PersistentFlags().StringVar(&grpcBootstrapDomain, "domain", "",
+		"DNS domain suffix. If not provided uses ${POD_NAMESPACE}.svc.cluster.local")
+
+	rootCmd.AddCommand(grpcBootstrapCmd)
+}
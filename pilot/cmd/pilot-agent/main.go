@@ -397,6 +397,11 @@ var (
 					localHostAddr = "[::1]"
 				}
 				prober := kubeAppProberNameVar.Get()
+				// Report the validity of the file mounted certificates, the ones Envoy waited for.
+				var certChains []string
+				if (controlPlaneAuthEnabled || rsTLSEnabled) && !sdsEnabled {
+					certChains = dedupeStrings([]string{tlsServerCertChain, tlsClientCertChain})
+				}
 				statusServer, err := status.NewServer(status.Config{
 					LocalHostAddr:      localHostAddr,
 					AdminPort:          proxyAdminPort,
@@ -404,6 +409,7 @@ var (
 					ApplicationPorts:   parsedPorts,
 					KubeAppHTTPProbers: prober,
 					NodeType:           role.Type,
+					CertChains:         certChains,
 				})
 				if err != nil {
 					return err
@@ -419,6 +425,9 @@ var (
 
 			go waitForCompletion(ctx, agent.Run)
 			go waitForCompletion(ctx, watcher.Run)
+			if exitOnApplicationExitVar.Get() {
+				go waitForApplicationExit(ctx, "/proc", os.Getpid())
+			}
 
 			cmd.WaitSignal(make(chan struct{}))
 			return nil
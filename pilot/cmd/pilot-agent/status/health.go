@@ -0,0 +1,167 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"istio.io/istio/pilot/cmd/pilot-agent/status/util"
+	"istio.io/pkg/log"
+)
+
+const (
+	// HealthPath reports the aggregated health of the proxy and the application. The injector
+	// points the readiness probe of the sidecar to it when it rewrites the application probers.
+	HealthPath = "/healthz/status"
+
+	healthCheckEnvoy        = "envoy"
+	healthCheckXDS          = "xds"
+	healthCheckApplication  = "application"
+	healthCheckCertificates = "certificates"
+)
+
+// HealthCheck is the outcome of one of the checks aggregated by the health endpoint.
+type HealthCheck struct {
+	Healthy bool   `json:"healthy"`
+	Message string `json:"message,omitempty"`
+}
+
+// Health is the aggregated health reported by the health endpoint. Checks that do not apply, e.g.
+// the application check when the application has no readiness prober, are omitted.
+type Health struct {
+	Healthy bool                   `json:"healthy"`
+	Checks  map[string]HealthCheck `json:"checks"`
+}
+
+func newHealthCheck(err error) HealthCheck {
+	if err != nil {
+		return HealthCheck{Message: err.Error()}
+	}
+	return HealthCheck{Healthy: true}
+}
+
+// health runs all checks. The header is forwarded to the application probers.
+func (s *Server) health(header http.Header) Health {
+	h := Health{Checks: map[string]HealthCheck{
+		healthCheckEnvoy: newHealthCheck(s.ready.Check()),
+		healthCheckXDS:   newHealthCheck(checkXDSConnected(s.ready.LocalHostAddr, s.ready.AdminPort)),
+	}}
+	if probers := s.readinessProbers(); len(probers) > 0 {
+		h.Checks[healthCheckApplication] = newHealthCheck(checkApplication(probers, header))
+	}
+	if len(s.certChains) > 0 {
+		h.Checks[healthCheckCertificates] = newHealthCheck(checkCertificates(s.certChains, s.nowFn()))
+	}
+	h.Healthy = true
+	for _, c := range h.Checks {
+		h.Healthy = h.Healthy && c.Healthy
+	}
+	return h
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	h := s.health(r.Header)
+	b, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		log.Errorf("Failed to encode health: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if h.Healthy {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_, _ = w.Write(b)
+}
+
+// readinessProbers returns the application readiness probers, keyed by their prober URL path.
+func (s *Server) readinessProbers() map[string]*Prober {
+	probers := map[string]*Prober{}
+	for path, prober := range s.appKubeProbers {
+		if strings.HasSuffix(path, "/readyz") {
+			probers[path] = prober
+		}
+	}
+	return probers
+}
+
+func checkXDSConnected(localHostAddr string, adminPort uint16) error {
+	stats, err := util.GetStats(localHostAddr, adminPort)
+	if err != nil {
+		return err
+	}
+	if stats.ControlPlaneConnected != 1 {
+		return fmt.Errorf("envoy is not connected to the xDS server")
+	}
+	return nil
+}
+
+func checkApplication(probers map[string]*Prober, header http.Header) error {
+	paths := make([]string, 0, len(probers))
+	for path := range probers {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var failures []string
+	for _, path := range paths {
+		code, err := probeApp(probers[path], header)
+		if err == nil && (code < http.StatusOK || code >= http.StatusBadRequest) {
+			err = fmt.Errorf("status code %d", code)
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", path, err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("application is not ready: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// checkCertificates verifies that the leaf certificate of each of the given chains is valid at the given time.
+func checkCertificates(chains []string, now time.Time) error {
+	for _, chain := range chains {
+		b, err := ioutil.ReadFile(chain)
+		if err != nil {
+			return err
+		}
+		block, _ := pem.Decode(b)
+		if block == nil {
+			return fmt.Errorf("no certificate found in %s", chain)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %v", chain, err)
+		}
+		if now.Before(cert.NotBefore) {
+			return fmt.Errorf("certificate %s is not valid before %v", chain, cert.NotBefore)
+		}
+		if now.After(cert.NotAfter) {
+			return fmt.Errorf("certificate %s expired at %v", chain, cert.NotAfter)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/cmd/pilot-agent/status/ready"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func httpGet(path string, port int) corev1.HTTPGetAction {
+	return corev1.HTTPGetAction{Path: path, Port: intstr.FromInt(port)}
+}
+
+func fakeEnvoyAdmin(t *testing.T, connected int) (*httptest.Server, uint16) {
+	t.Helper()
+	admin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stats":
+			fmt.Fprintf(w, "cluster_manager.cds.update_success: 1\nlistener_manager.lds.update_success: 1\n"+
+				"control_plane.connected_state: %d\n", connected)
+		case "/server_info":
+			fmt.Fprint(w, `{"state": "LIVE"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return admin, uint16(admin.Listener.Addr().(*net.TCPAddr).Port)
+}
+
+func TestHealth(t *testing.T) {
+	app := httptest.NewServer(&handler{})
+	defer app.Close()
+	appPort := app.Listener.Addr().(*net.TCPAddr).Port
+
+	cases := []struct {
+		name      string
+		connected int
+		probers   KubeAppProbers
+		certs     []string
+		healthy   bool
+		unhealthy string
+	}{
+		{
+			name:      "healthy",
+			connected: 1,
+			probers: KubeAppProbers{
+				"/app-health/hello-world/readyz": {HTTPGetAction: httpGet("/hello/sunnyvale", appPort)},
+				// Liveness probers are not part of the health.
+				"/app-health/hello-world/livez": {HTTPGetAction: httpGet("/unknown", appPort)},
+			},
+			certs:   []string{"test-cert/cert.crt"},
+			healthy: true,
+		},
+		{
+			name:      "xds disconnected",
+			connected: 0,
+			unhealthy: healthCheckXDS,
+		},
+		{
+			name:      "application not ready",
+			connected: 1,
+			probers: KubeAppProbers{
+				"/app-health/hello-world/readyz": {HTTPGetAction: httpGet("/unknown", appPort)},
+			},
+			unhealthy: healthCheckApplication,
+		},
+		{
+			name:      "missing certificate",
+			connected: 1,
+			certs:     []string{"test-cert/missing.crt"},
+			unhealthy: healthCheckCertificates,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			admin, adminPort := fakeEnvoyAdmin(t, tc.connected)
+			defer admin.Close()
+			s := &Server{
+				ready:          &ready.Probe{LocalHostAddr: "127.0.0.1", AdminPort: adminPort},
+				appKubeProbers: tc.probers,
+				certChains:     tc.certs,
+				// test-cert/cert.crt is valid from 2019-03 to 2029-03.
+				nowFn: func() time.Time { return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) },
+			}
+
+			h := s.health(http.Header{})
+			if h.Healthy != tc.healthy {
+				t.Errorf("got healthy %v, want %v: %+v", h.Healthy, tc.healthy, h.Checks)
+			}
+			if tc.unhealthy != "" && h.Checks[tc.unhealthy].Healthy {
+				t.Errorf("expected check %s to fail: %+v", tc.unhealthy, h.Checks)
+			}
+			if _, ok := h.Checks[healthCheckApplication]; ok != (len(tc.probers) > 0) {
+				t.Errorf("application check reported without readiness probers: %+v", h.Checks)
+			}
+		})
+	}
+}
+
+func TestCheckCertificates(t *testing.T) {
+	chains := []string{"test-cert/cert.crt"}
+	if err := checkCertificates(chains, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := checkCertificates(chains, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)); err == nil ||
+		!strings.Contains(err.Error(), "expired") {
+		t.Errorf("expected expiry error, got %v", err)
+	}
+	if err := checkCertificates(chains, time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)); err == nil ||
+		!strings.Contains(err.Error(), "not valid before") {
+		t.Errorf("expected not yet valid error, got %v", err)
+	}
+}
@@ -39,8 +39,8 @@ import (
 )
 
 const (
-	// readyPath is for the pilot agent readiness itself.
-	readyPath = "/healthz/ready"
+	// ReadyPath is for the pilot agent readiness itself.
+	ReadyPath = "/healthz/ready"
 	// quitPath is to notify the pilot agent to quit.
 	quitPath = "/quitquitquit"
 	// KubeAppProberEnvName is the name of the command line flag for pilot agent to pass app prober config.
@@ -59,7 +59,23 @@ var (
 // It's a map from the prober URL path to the Kubernetes Prober config.
 // For example, "/app-health/hello-world/livez" entry contains livenss prober config for
 // container "hello-world".
-type KubeAppProbers map[string]*corev1.HTTPGetAction
+type KubeAppProbers map[string]*Prober
+
+// Prober is an application prober taken over by the agent. The HTTP prober config is inlined,
+// so that the encoding of HTTP probers matches the one used by older injectors.
+type Prober struct {
+	corev1.HTTPGetAction
+	// TCPSocket is set if the original prober was a TCP socket prober. The agent then only checks
+	// that a connection can be established to the prober port.
+	TCPSocket bool `json:"tcpSocket,omitempty"`
+	// TimeoutSeconds is the timeout of the original prober. Defaults to 10 seconds when unset.
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+}
+
+const (
+	// defaultAppProbeTimeout is used for application probers without an explicit timeout.
+	defaultAppProbeTimeout = 10 * time.Second
+)
 
 // Config for the status server.
 type Config struct {
@@ -70,6 +86,8 @@ type Config struct {
 	NodeType           model.NodeType
 	StatusPort         uint16
 	AdminPort          uint16
+	// CertChains are the certificate chain files whose validity is reported by the health endpoint.
+	CertChains []string
 }
 
 // Server provides an endpoint for handling status probes.
@@ -79,12 +97,17 @@ type Server struct {
 	appKubeProbers      KubeAppProbers
 	statusPort          uint16
 	lastProbeSuccessful bool
+	certChains          []string
+	// nowFn is the clock the certificate chains are checked against.
+	nowFn func() time.Time
 }
 
 // NewServer creates a new status server.
 func NewServer(config Config) (*Server, error) {
 	s := &Server{
 		statusPort: config.StatusPort,
+		certChains: config.CertChains,
+		nowFn:      time.Now,
 		ready: &ready.Probe{
 			LocalHostAddr:    config.LocalHostAddr,
 			AdminPort:        config.AdminPort,
@@ -124,8 +147,9 @@ func (s *Server) Run(ctx context.Context) {
 	mux := http.NewServeMux()
 
 	// Add the handler for ready probes.
-	mux.HandleFunc(readyPath, s.handleReadyProbe)
+	mux.HandleFunc(ReadyPath, s.handleReadyProbe)
 	mux.HandleFunc(quitPath, s.handleQuit)
+	mux.HandleFunc(HealthPath, s.handleHealth)
 	mux.HandleFunc("/app-health/", s.handleAppProbe)
 
 	l, err := net.Listen("tcp", fmt.Sprintf(":%d", s.statusPort))
@@ -216,10 +240,36 @@ func (s *Server) handleAppProbe(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	code, err := probeApp(prober, req.Header)
+	if err != nil {
+		log.Errorf("Request to probe app failed: %v, original URL path = %v", err, path)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	// We only write the status code to the response.
+	w.WriteHeader(code)
+}
+
+// probeApp runs the given application prober and returns the resulting HTTP status code. TCP socket
+// probers report http.StatusOK once a connection could be established.
+func probeApp(prober *Prober, header http.Header) (int, error) {
+	timeout := defaultAppProbeTimeout
+	if prober.TimeoutSeconds > 0 {
+		timeout = time.Duration(prober.TimeoutSeconds) * time.Second
+	}
+
+	if prober.TCPSocket {
+		conn, err := net.DialTimeout("tcp", fmt.Sprintf("localhost:%v", prober.Port.IntValue()), timeout)
+		if err != nil {
+			return 0, err
+		}
+		_ = conn.Close()
+		return http.StatusOK, nil
+	}
+
 	// Construct a request sent to the application.
 	httpClient := &http.Client{
-		// TODO: figure out the appropriate timeout?
-		Timeout: 10 * time.Second,
+		Timeout: timeout,
 		// We skip the verification since kubelet skips the verification for HTTPS prober as well
 		// https://kubernetes.io/docs/tasks/configure-pod-container/configure-liveness-readiness-probes/#configure-probes
 		Transport: &http.Transport{
@@ -234,13 +284,11 @@ func (s *Server) handleAppProbe(w http.ResponseWriter, req *http.Request) {
 	}
 	appReq, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		log.Errorf("Failed to create request to probe app %v, original url %v", err, path)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		return 0, err
 	}
 
 	// Forward incoming headers to the application.
-	for name, values := range req.Header {
+	for name, values := range header {
 		newValues := make([]string, len(values))
 		copy(newValues, values)
 		appReq.Header[name] = newValues
@@ -249,14 +297,10 @@ func (s *Server) handleAppProbe(w http.ResponseWriter, req *http.Request) {
 	// Send the request.
 	response, err := httpClient.Do(appReq)
 	if err != nil {
-		log.Errorf("Request to probe app failed: %v, original URL path = %v\napp URL path = %v", err, path, prober.Path)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		return 0, fmt.Errorf("%v, app URL path = %v", err, prober.Path)
 	}
-	defer response.Body.Close()
-
-	// We only write the status code to the response.
-	w.WriteHeader(response.StatusCode)
+	_ = response.Body.Close()
+	return response.StatusCode, nil
 }
 
 // notifyExit sends SIGTERM to itself
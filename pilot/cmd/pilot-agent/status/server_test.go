@@ -109,7 +109,9 @@ func TestAppProbe(t *testing.T) {
 	server, err := NewServer(Config{
 		StatusPort: 0,
 		KubeAppHTTPProbers: fmt.Sprintf(`{"/app-health/hello-world/readyz": {"path": "/hello/sunnyvale", "port": %v},
-"/app-health/hello-world/livez": {"port": %v}}`, appPort, appPort),
+"/app-health/hello-world/livez": {"port": %v, "timeoutSeconds": 1},
+"/app-health/tcp-world/readyz": {"port": %v, "tcpSocket": true},
+"/app-health/tcp-world/livez": {"port": %v, "tcpSocket": true}}`, appPort, appPort, appPort, unusedPort(t)),
 	})
 	if err != nil {
 		t.Errorf("failed to create status server %v", err)
@@ -141,6 +143,14 @@ func TestAppProbe(t *testing.T) {
 			probePath:  fmt.Sprintf(":%v/app-health/hello-world/livez", statusPort),
 			statusCode: http.StatusOK,
 		},
+		{
+			probePath:  fmt.Sprintf(":%v/app-health/tcp-world/readyz", statusPort),
+			statusCode: http.StatusOK,
+		},
+		{
+			probePath:  fmt.Sprintf(":%v/app-health/tcp-world/livez", statusPort),
+			statusCode: http.StatusInternalServerError,
+		},
 	}
 	for _, tc := range testCases {
 		client := http.Client{}
@@ -159,6 +169,17 @@ func TestAppProbe(t *testing.T) {
 	}
 }
 
+// unusedPort returns a port nothing is listening on.
+func unusedPort(t *testing.T) int {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("failed to allocate unused port %v", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	_ = l.Close()
+	return port
+}
+
 func TestHttpsAppProbe(t *testing.T) {
 	// Starts the application first.
 	listener, err := net.Listen("tcp", ":0")
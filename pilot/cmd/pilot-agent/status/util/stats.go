@@ -24,10 +24,11 @@ import (
 )
 
 const (
-	statCdsUpdatesSuccess   = "cluster_manager.cds.update_success"
-	statCdsUpdatesRejection = "cluster_manager.cds.update_rejected"
-	statLdsUpdatesSuccess   = "listener_manager.lds.update_success"
-	statLdsUpdatesRejection = "listener_manager.lds.update_rejected"
+	statCdsUpdatesSuccess     = "cluster_manager.cds.update_success"
+	statCdsUpdatesRejection   = "cluster_manager.cds.update_rejected"
+	statLdsUpdatesSuccess     = "listener_manager.lds.update_success"
+	statLdsUpdatesRejection   = "listener_manager.lds.update_rejected"
+	statControlPlaneConnected = "control_plane.connected_state"
 )
 
 // Stats contains values of interest from a poll of Envoy stats.
@@ -36,6 +37,8 @@ type Stats struct {
 	CDSUpdatesRejection uint64
 	LDSUpdatesSuccess   uint64
 	LDSUpdatesRejection uint64
+	// ControlPlaneConnected is 1 while Envoy is connected to its xDS server.
+	ControlPlaneConnected uint64
 }
 
 // String representation of the Stats.
@@ -61,6 +64,7 @@ func GetStats(localHostAddr string, adminPort uint16) (*Stats, error) {
 		{name: statCdsUpdatesRejection, value: &s.CDSUpdatesRejection},
 		{name: statLdsUpdatesSuccess, value: &s.LDSUpdatesSuccess},
 		{name: statLdsUpdatesRejection, value: &s.LDSUpdatesRejection},
+		{name: statControlPlaneConnected, value: &s.ControlPlaneConnected},
 	}
 	if err := parseStats(input, allStats); err != nil {
 		return nil, err
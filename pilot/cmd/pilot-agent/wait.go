@@ -0,0 +1,84 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/pkg/log"
+)
+
+var (
+	waitTimeout     time.Duration
+	waitPeriod      time.Duration
+	waitRequestTime time.Duration
+	waitURL         string
+
+	// waitCmd blocks until the local proxy reports ready. It is used by the injected postStart hook
+	// of the proxy container, which holds the start of the application containers until it returns.
+	waitCmd = &cobra.Command{
+		Use:   "wait",
+		Short: "Waits until the Envoy proxy is ready",
+		RunE: func(c *cobra.Command, args []string) error {
+			client := &http.Client{
+				Timeout: waitRequestTime,
+			}
+			log.Infof("Waiting for Envoy proxy to be ready (timeout: %v)", waitTimeout)
+
+			var err error
+			deadline := time.Now().Add(waitTimeout)
+			for time.Now().Before(deadline) {
+				if err = checkIfReady(client, waitURL); err == nil {
+					log.Infof("Envoy is ready!")
+					return nil
+				}
+				log.Debugf("Not ready yet: %v", err)
+				time.Sleep(waitPeriod)
+			}
+			return fmt.Errorf("timeout waiting for Envoy proxy to become ready. Last error: %v", err)
+		},
+	}
+)
+
+func checkIfReady(client *http.Client, url string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP status code %v: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+func init() {
+	waitCmd.PersistentFlags().DurationVar(&waitTimeout, "timeout", 60*time.Second,
+		"Maximum time to wait for the Envoy proxy to become ready")
+	waitCmd.PersistentFlags().DurationVar(&waitPeriod, "period", 500*time.Millisecond,
+		"Interval between consecutive readiness checks")
+	waitCmd.PersistentFlags().DurationVar(&waitRequestTime, "requestTimeout", 500*time.Millisecond,
+		"Timeout of each readiness check request")
+	waitCmd.PersistentFlags().StringVar(&waitURL, "url", "http://localhost:15020/healthz/ready",
+		"URL of the proxy readiness endpoint")
+
+	rootCmd.AddCommand(waitCmd)
+}
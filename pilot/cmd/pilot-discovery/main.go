@@ -29,6 +29,7 @@ import (
 	"istio.io/istio/pilot/pkg/serviceregistry"
 	"istio.io/istio/pkg/cmd"
 	"istio.io/istio/pkg/keepalive"
+	"istio.io/istio/pkg/tracing"
 	"istio.io/pkg/collateral"
 	"istio.io/pkg/ctrlz"
 	"istio.io/pkg/log"
@@ -43,6 +44,8 @@ var (
 
 	loggingOptions = log.DefaultOptions()
 
+	tracingOptions = tracing.DefaultOptions()
+
 	rootCmd = &cobra.Command{
 		Use:          "pilot-discovery",
 		Short:        "Istio Pilot.",
@@ -60,6 +63,14 @@ var (
 				return err
 			}
 
+			if tracingOptions.TracingEnabled() {
+				tracer, err := tracing.Configure("istio-pilot", tracingOptions)
+				if err != nil {
+					return fmt.Errorf("failed to configure tracing: %v", err)
+				}
+				defer tracer.Close() // nolint: errcheck
+			}
+
 			spiffe.SetTrustDomain(spiffe.DetermineTrustDomain(serverArgs.Config.ControllerOptions.TrustDomain, hasKubeRegistry()))
 
 			// Create the stop channel for all of the servers.
@@ -117,6 +128,9 @@ func init() {
 		"Initial window size for MCP's gRPC connection")
 	discoveryCmd.PersistentFlags().IntVar(&serverArgs.MCPInitialConnWindowSize, "mcpInitialConnWindowSize", bootstrap.DefaultMCPInitialConnWindowSize,
 		"Initial connection window size for MCP's gRPC connection")
+	discoveryCmd.PersistentFlags().StringArrayVar(&serverArgs.MCPSourceCollections, "mcpSourceCollections", nil,
+		"Restrict an MCP config source to some collections, as <address>=<collection>[,<collection>...]. "+
+			"Can be repeated for several config sources. Config sources serve all collections by default")
 
 	// Config Controller options
 	discoveryCmd.PersistentFlags().BoolVar(&serverArgs.Config.DisableInstallCRDs, "disable-install-crds", false,
@@ -143,6 +157,8 @@ func init() {
 		"Discovery service HTTP address")
 	discoveryCmd.PersistentFlags().StringVar(&serverArgs.DiscoveryOptions.GrpcAddr, "grpcAddr", ":15010",
 		"Discovery service grpc address")
+	discoveryCmd.PersistentFlags().StringVar(&serverArgs.DiscoveryOptions.GrpcUDSPath, "grpcUdsPath", "",
+		"Unix domain socket path on which the discovery service grpc is also served, for the agents on the same node")
 	discoveryCmd.PersistentFlags().StringVar(&serverArgs.DiscoveryOptions.SecureGrpcAddr, "secureGrpcAddr", ":15012",
 		"Discovery service grpc address, with https")
 	discoveryCmd.PersistentFlags().StringVar(&serverArgs.DiscoveryOptions.MonitoringAddr, "monitoringAddr", ":15014",
@@ -155,6 +171,9 @@ func init() {
 	// Attach the Istio logging options to the command.
 	loggingOptions.AttachCobraFlags(rootCmd)
 
+	// Attach the Istio tracing options to the command, tracing the push pipeline.
+	tracingOptions.AttachCobraFlags(rootCmd)
+
 	// Attach the Istio Ctrlz options to the command.
 	serverArgs.CtrlZOptions.AttachCobraFlags(rootCmd)
 
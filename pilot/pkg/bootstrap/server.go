@@ -59,6 +59,7 @@ import (
 	"istio.io/istio/pilot/pkg/config/memory"
 	configmonitor "istio.io/istio/pilot/pkg/config/monitor"
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/leaderelection"
 	"istio.io/istio/pilot/pkg/model"
 	istio_networking "istio.io/istio/pilot/pkg/networking/core"
 	"istio.io/istio/pilot/pkg/networking/plugin"
@@ -76,6 +77,7 @@ import (
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/schemas"
+	"istio.io/istio/pkg/config/security"
 	istiokeepalive "istio.io/istio/pkg/keepalive"
 	kubelib "istio.io/istio/pkg/kube"
 	configz "istio.io/istio/pkg/mcp/configz/client"
@@ -191,7 +193,10 @@ type PilotArgs struct {
 	MCPMaxMessageSize        int
 	MCPInitialWindowSize     int
 	MCPInitialConnWindowSize int
-	KeepaliveOptions         *istiokeepalive.Options
+	// MCPSourceCollections restricts MCP config sources to some collections, as
+	// <address>=<collection>[,<collection>...].
+	MCPSourceCollections []string
+	KeepaliveOptions     *istiokeepalive.Options
 	// ForceStop is set as true when used for testing to make the server stop quickly
 	ForceStop bool
 }
@@ -214,6 +219,7 @@ type Server struct {
 	kubeClient       kubernetes.Interface
 	startFuncs       []startFunc
 	multicluster     *clusterregistry.Multicluster
+	globalServices   *clusterregistry.GlobalServices
 	httpServer       *http.Server
 	grpcServer       *grpc.Server
 	secureHTTPServer *http.Server
@@ -224,7 +230,10 @@ type Server struct {
 	fileWatcher      filewatcher.FileWatcher
 }
 
-var podNamespaceVar = env.RegisterStringVar("POD_NAMESPACE", "", "")
+var (
+	podNameVar      = env.RegisterStringVar("POD_NAME", "", "")
+	podNamespaceVar = env.RegisterStringVar("POD_NAMESPACE", "", "")
+)
 
 // NewServer creates a new Server instance based on the provided arguments.
 func NewServer(args PilotArgs) (*Server, error) {
@@ -328,9 +337,11 @@ func (s *Server) initClusterRegistries(args *PilotArgs) (err error) {
 			args.Config.ControllerOptions.WatchedNamespace,
 			args.Config.ControllerOptions.DomainSuffix,
 			args.Config.ControllerOptions.ResyncPeriod,
+			args.Config.ControllerOptions.ClusterID,
 			s.ServiceController,
 			s.EnvoyXdsServer,
-			s.meshNetworks)
+			s.meshNetworks,
+			s.globalServices)
 
 		if err != nil {
 			log.Info("Unable to create new Multicluster object")
@@ -399,14 +410,22 @@ func (s *Server) initMesh(args *PilotArgs) error {
 			}
 			if !reflect.DeepEqual(meshConfig, s.mesh) {
 				log.Infof("mesh configuration updated to: %s", spew.Sdump(meshConfig))
-				if !reflect.DeepEqual(meshConfig.ConfigSources, s.mesh.ConfigSources) {
-					log.Infof("mesh configuration sources have changed")
+				changes := mesh.DiffMeshConfig(s.mesh, meshConfig)
+				if len(changes.PilotRestart) > 0 {
 					//TODO Need to re-create or reload initConfigController()
+					log.Warnf("mesh configuration fields %v have changed, pilot must be restarted to apply them",
+						changes.PilotRestart)
+				}
+				if len(changes.ProxyRestart) > 0 {
+					log.Warnf("mesh configuration fields %v have changed, workloads must be restarted to apply them",
+						changes.ProxyRestart)
 				}
 				s.mesh = meshConfig
 				if s.EnvoyXdsServer != nil {
 					s.EnvoyXdsServer.Env.Mesh = meshConfig
-					s.EnvoyXdsServer.ConfigUpdate(&model.PushRequest{Full: true})
+					if changes.Push {
+						s.EnvoyXdsServer.ConfigUpdate(&model.PushRequest{Full: true})
+					}
 				}
 			}
 		})
@@ -498,6 +517,11 @@ func (s *Server) initKubeClient(args *PilotArgs) error {
 		}
 		s.kubeClient = client
 
+		// Pilot validates the configs it ingests against the host delegations of its namespace.
+		s.addStartFunc(func(stop <-chan struct{}) error {
+			kubelib.WatchHostDelegations(client, args.Namespace, stop)
+			return nil
+		})
 	}
 
 	return nil
@@ -515,11 +539,28 @@ func (c *mockController) AppendInstanceHandler(f func(*model.ServiceInstance, mo
 
 func (c *mockController) Run(<-chan struct{}) {}
 
+// parseMCPSourceCollections parses the collections MCP config sources are restricted to, keyed by source address.
+func parseMCPSourceCollections(values []string) (map[string][]string, error) {
+	out := make(map[string][]string, len(values))
+	for _, v := range values {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid MCP source collections %q, expected <address>=<collection>[,<collection>...]", v)
+		}
+		for _, collection := range strings.Split(parts[1], ",") {
+			if collection = strings.TrimSpace(collection); collection != "" {
+				out[parts[0]] = append(out[parts[0]], collection)
+			}
+		}
+	}
+	return out, nil
+}
+
 func (s *Server) initMCPConfigController(args *PilotArgs) error {
 	clientNodeID := ""
-	collections := make([]sink.CollectionOptions, len(schemas.Istio))
-	for i, t := range schemas.Istio {
-		collections[i] = sink.CollectionOptions{Name: t.Collection, Incremental: false}
+	sourceCollections, err := parseMCPSourceCollections(args.MCPSourceCollections)
+	if err != nil {
+		return err
 	}
 
 	options := coredatamodel.Options{
@@ -533,6 +574,8 @@ func (s *Server) initMCPConfigController(args *PilotArgs) error {
 	var clients []*sink.Client
 	var conns []*grpc.ClientConn
 	var configStores []model.ConfigStoreCache
+	// availability of the MCP sources, keyed by address, used to trigger a push on failover.
+	availability := make(map[string]func() bool)
 
 	reporter := monitoring.NewStatsContext("pilot")
 
@@ -635,7 +678,13 @@ func (s *Server) initMCPConfigController(args *PilotArgs) error {
 			return err
 		}
 
-		mcpController := coredatamodel.NewController(options)
+		sourceOptions := options
+		sourceOptions.Collections = sourceCollections[configSource.Address]
+		mcpController := coredatamodel.NewController(sourceOptions)
+		collections := make([]sink.CollectionOptions, 0, len(mcpController.ConfigDescriptor()))
+		for _, t := range mcpController.ConfigDescriptor() {
+			collections = append(collections, sink.CollectionOptions{Name: t.Collection, Incremental: features.EnableIncrementalMCP})
+		}
 		sinkOptions := &sink.Options{
 			CollectionOptions: collections,
 			Updater:           mcpController,
@@ -649,7 +698,9 @@ func (s *Server) initMCPConfigController(args *PilotArgs) error {
 		clients = append(clients, mcpClient)
 
 		conns = append(conns, conn)
-		configStores = append(configStores, mcpController)
+		available := func() bool { return mcpClient.DisconnectedFor() < features.MCPFailoverDelay }
+		availability[configSource.Address] = available
+		configStores = append(configStores, configaggregate.WithAvailability(mcpController, available))
 	}
 
 	s.addStartFunc(func(stop <-chan struct{}) error {
@@ -664,6 +715,10 @@ func (s *Server) initMCPConfigController(args *PilotArgs) error {
 			}()
 		}
 
+		if len(availability) > 1 {
+			go s.watchMCPAvailability(ctx, availability)
+		}
+
 		go func() {
 			<-stop
 
@@ -692,6 +747,39 @@ func (s *Server) initMCPConfigController(args *PilotArgs) error {
 	return nil
 }
 
+// watchMCPAvailability triggers a full push when an MCP source becomes unavailable or available again,
+// as the configuration is then served from a different set of sources.
+func (s *Server) watchMCPAvailability(ctx context.Context, availability map[string]func() bool) {
+	previous := make(map[string]bool, len(availability))
+	for address, available := range availability {
+		previous[address] = available()
+	}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed := false
+			for address, available := range availability {
+				if a := available(); a != previous[address] {
+					if a {
+						log.Infof("MCP config source %s is available again", address)
+					} else {
+						log.Warnf("MCP config source %s is unavailable, failing over to the other config sources", address)
+					}
+					previous[address] = a
+					changed = true
+				}
+			}
+			if changed {
+				s.EnvoyXdsServer.ConfigUpdate(&model.PushRequest{Full: true})
+			}
+		}
+	}
+}
+
 // initConfigController creates the config controller in the pilotConfig.
 func (s *Server) initConfigController(args *PilotArgs) error {
 	if len(s.mesh.ConfigSources) > 0 {
@@ -740,16 +828,34 @@ func (s *Server) initConfigController(args *PilotArgs) error {
 		s.configController = configController
 
 		if ingressSyncer, errSyncer := ingress.NewStatusSyncer(s.mesh, s.kubeClient,
-			args.Namespace, args.Config.ControllerOptions); errSyncer != nil {
+			args.Config.ControllerOptions); errSyncer != nil {
 			log.Warnf("Disabled ingress status syncer due to %v", errSyncer)
 		} else {
+			// Only one replica writes the ingress status, all of them keep serving xDS.
+			election := leaderelection.NewLeaderElection(args.Namespace, podNameVar.Get(),
+				ingress.ElectionID(s.mesh), s.kubeClient).AddRunFunction(ingressSyncer.Run)
 			s.addStartFunc(func(stop <-chan struct{}) error {
-				go ingressSyncer.Run(stop)
+				ingressSyncer.Start(stop)
+				go election.Run(stop)
 				return nil
 			})
 		}
 	}
 
+	// Generate the ServiceEntries of the services exported by the remote clusters.
+	if hasKubeRegistry(args) && features.EnableGlobalServiceEntries {
+		s.globalServices = clusterregistry.NewGlobalServices(args.Config.ClusterRegistriesNamespace,
+			args.Config.ControllerOptions.ResyncPeriod)
+		configController, err := configaggregate.MakeCache([]model.ConfigStoreCache{
+			s.configController,
+			s.globalServices.ConfigStore(),
+		})
+		if err != nil {
+			return err
+		}
+		s.configController = configController
+	}
+
 	// Create the config store.
 	s.istioConfigStore = model.MakeIstioStore(s.configController)
 
@@ -787,7 +893,7 @@ func (s *Server) makeFileMonitor(fileDir string, configController model.ConfigSt
 
 // createK8sServiceControllers creates all the k8s service controllers under this pilot
 func (s *Server) createK8sServiceControllers(serviceControllers *aggregate.Controller, args *PilotArgs) (err error) {
-	clusterID := string(serviceregistry.KubernetesRegistry)
+	clusterID := features.ClusterName
 	log.Infof("Primary Cluster name: %s", clusterID)
 	args.Config.ControllerOptions.ClusterID = clusterID
 	kubectl := controller2.NewController(s.kubeClient, args.Config.ControllerOptions)
@@ -948,12 +1054,24 @@ func (s *Server) initDiscoveryService(args *PilotArgs) error {
 	}
 	s.GRPCListeningAddr = grpcListener.Addr()
 
+	// create the grpc listener for the agents on the same node
+	var grpcUDSListener net.Listener
+	if args.DiscoveryOptions.GrpcUDSPath != "" {
+		if grpcUDSListener, err = listenUDS(args.DiscoveryOptions.GrpcUDSPath); err != nil {
+			return err
+		}
+	}
+
 	s.addStartFunc(func(stop <-chan struct{}) error {
 		go func() {
 			if !s.waitForCacheSync(stop) {
 				return
 			}
 
+			// Build the initial push context now that the caches are synced; the
+			// readiness probe reports ready only after it has been initialized.
+			s.EnvoyXdsServer.ClearCache()
+
 			log.Infof("starting discovery service at http=%s grpc=%s", listener.Addr(), grpcListener.Addr())
 			go func() {
 				if err := s.httpServer.Serve(listener); err != nil {
@@ -965,6 +1083,14 @@ func (s *Server) initDiscoveryService(args *PilotArgs) error {
 					log.Warna(err)
 				}
 			}()
+			if grpcUDSListener != nil {
+				log.Infof("starting discovery service at grpc=unix://%s", args.DiscoveryOptions.GrpcUDSPath)
+				go func() {
+					if err := s.grpcServer.Serve(grpcUDSListener); err != nil {
+						log.Warna(err)
+					}
+				}()
+			}
 
 			go func() {
 				<-stop
@@ -989,10 +1115,6 @@ func (s *Server) initDiscoveryService(args *PilotArgs) error {
 
 	// run secure grpc server
 	if args.DiscoveryOptions.SecureGrpcAddr != "" {
-		// create secure grpc server
-		if err := s.initSecureGrpcServer(args.KeepaliveOptions); err != nil {
-			return fmt.Errorf("secure grpc server: %s", err)
-		}
 		// create secure grpc listener
 		secureGrpcListener, err := net.Listen("tcp", args.DiscoveryOptions.SecureGrpcAddr)
 		if err != nil {
@@ -1001,6 +1123,10 @@ func (s *Server) initDiscoveryService(args *PilotArgs) error {
 		s.SecureGRPCListeningAddr = secureGrpcListener.Addr()
 
 		s.addStartFunc(func(stop <-chan struct{}) error {
+			// create secure grpc server, its certificates are reloaded until stopped
+			if err := s.initSecureGrpcServer(args.KeepaliveOptions, stop); err != nil {
+				return fmt.Errorf("secure grpc server: %s", err)
+			}
 			go func() {
 				if !s.waitForCacheSync(stop) {
 					return
@@ -1062,28 +1188,45 @@ func (s *Server) initGrpcServer(options *istiokeepalive.Options) {
 	s.EnvoyXdsServer.Register(s.grpcServer)
 }
 
+// listenUDS listens on the Unix domain socket, removing the socket left by a previous run.
+func listenUDS(path string) (net.Listener, error) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove unix domain socket %s: %v", path, err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// Only the agents running as the same user, or in its group, can connect.
+	if err := os.Chmod(path, 0660); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
 // initialize secureGRPCServer
-func (s *Server) initSecureGrpcServer(options *istiokeepalive.Options) error {
+func (s *Server) initSecureGrpcServer(options *istiokeepalive.Options, stop <-chan struct{}) error {
 	certDir := features.CertDir
 	if certDir == "" {
 		certDir = PilotCertDir
 	}
 
 	ca := path.Join(certDir, constants.RootCertFilename)
-	key := path.Join(certDir, constants.KeyFilename)
-	cert := path.Join(certDir, constants.CertChainFilename)
 
-	tlsCreds, err := credentials.NewServerTLSFromFile(cert, key)
+	// The certificates are rotated before they expire, serve the latest ones.
+	watcher, err := creds.WatchFolder(stop, certDir)
 	// certs not ready yet.
 	if err != nil {
 		return err
 	}
-
-	// TODO: parse the file to determine expiration date. Restart listener before expiration
-	certificate, err := tls.LoadX509KeyPair(cert, key)
-	if err != nil {
-		return err
+	getCertificate := func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		certificate := watcher.Get()
+		return &certificate, nil
 	}
+	tlsCreds := credentials.NewTLS(security.RestrictTLSConfig(&tls.Config{
+		GetCertificate: getCertificate,
+	}))
 
 	caCert, err := ioutil.ReadFile(ca)
 	if err != nil {
@@ -1097,8 +1240,8 @@ func (s *Server) initSecureGrpcServer(options *istiokeepalive.Options) error {
 	s.secureGRPCServer = grpc.NewServer(opts...)
 	s.EnvoyXdsServer.Register(s.secureGRPCServer)
 	s.secureHTTPServer = &http.Server{
-		TLSConfig: &tls.Config{
-			Certificates: []tls.Certificate{certificate},
+		TLSConfig: security.RestrictTLSConfig(&tls.Config{
+			GetCertificate: getCertificate,
 			VerifyPeerCertificate: func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
 				// For now accept any certs - pilot is not authenticating the caller, TLS used for
 				// privacy
@@ -1107,7 +1250,7 @@ func (s *Server) initSecureGrpcServer(options *istiokeepalive.Options) error {
 			NextProtos: []string{"h2", "http/1.1"},
 			ClientAuth: tls.RequireAndVerifyClientCert,
 			ClientCAs:  caCertPool,
-		},
+		}),
 		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if r.ProtoMajor == 2 && strings.HasPrefix(
 				r.Header.Get("Content-Type"), "application/grpc") {
@@ -1142,6 +1285,14 @@ func (s *Server) grpcServerOptions(options *istiokeepalive.Options) []grpc.Serve
 		}),
 	}
 
+	// gRPC ignores windows smaller than 64KiB.
+	if features.InitialWindowSize > 0 {
+		grpcOptions = append(grpcOptions, grpc.InitialWindowSize(int32(features.InitialWindowSize)))
+	}
+	if features.InitialConnWindowSize > 0 {
+		grpcOptions = append(grpcOptions, grpc.InitialConnWindowSize(int32(features.InitialConnWindowSize)))
+	}
+
 	return grpcOptions
 }
 
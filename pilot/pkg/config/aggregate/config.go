@@ -27,8 +27,37 @@ import (
 
 var errorUnsupported = errors.New("unsupported operation: the config aggregator is read-only")
 
+// AvailabilityReporter is implemented by stores whose backend can become unavailable, e.g.
+// when the connection to an MCP server is lost. The aggregate fails over from an unavailable
+// store to the other stores holding the same type, as long as one of them is available.
+type AvailabilityReporter interface {
+	Available() bool
+}
+
+// WithAvailability returns a config store cache reporting its availability with the given function.
+func WithAvailability(cache model.ConfigStoreCache, available func() bool) model.ConfigStoreCache {
+	return &availabilityCache{ConfigStoreCache: cache, available: available}
+}
+
+type availabilityCache struct {
+	model.ConfigStoreCache
+	available func() bool
+}
+
+func (c *availabilityCache) Available() bool {
+	return c.available()
+}
+
+func isAvailable(store model.ConfigStore) bool {
+	if r, ok := store.(AvailabilityReporter); ok {
+		return r.Available()
+	}
+	return true
+}
+
 // Make creates an aggregate config store from several config stores and
-// unifies their descriptors
+// unifies their descriptors. The stores are in decreasing order of precedence:
+// a config present in several stores is read from the first one.
 func Make(stores []model.ConfigStore) (model.ConfigStore, error) {
 	union := schema.Set{}
 	storeTypes := make(map[string][]model.ConfigStore)
@@ -78,9 +107,25 @@ func (cr *store) ConfigDescriptor() schema.Set {
 	return cr.descriptor
 }
 
+// storesFor returns the available stores holding the given type, or all of them if
+// none is available so that the last known configuration keeps being served.
+func (cr *store) storesFor(typ string) []model.ConfigStore {
+	stores := cr.stores[typ]
+	available := make([]model.ConfigStore, 0, len(stores))
+	for _, store := range stores {
+		if isAvailable(store) {
+			available = append(available, store)
+		}
+	}
+	if len(available) == 0 {
+		return stores
+	}
+	return available
+}
+
 // Get the first config found in the stores.
 func (cr *store) Get(typ, name, namespace string) *model.Config {
-	for _, store := range cr.stores[typ] {
+	for _, store := range cr.storesFor(typ) {
 		config := store.Get(typ, name, namespace)
 		if config != nil {
 			return config
@@ -99,7 +144,7 @@ func (cr *store) List(typ, namespace string) ([]model.Config, error) {
 	// Used to remove duplicated config
 	configMap := make(map[string]struct{})
 
-	for _, store := range cr.stores[typ] {
+	for _, store := range cr.storesFor(typ) {
 		storeConfigs, err := store.List(typ, namespace)
 		if err != nil {
 			errs = multierror.Append(errs, err)
@@ -133,11 +178,29 @@ type storeCache struct {
 	caches []model.ConfigStoreCache
 }
 
+// HasSynced returns true once all the caches have synced. Unavailable caches are
+// ignored as long as another cache holding the same types has synced.
 func (cr *storeCache) HasSynced() bool {
+	synced := make(map[string]bool)
+	for _, cache := range cr.caches {
+		if cache.HasSynced() {
+			for _, typ := range cache.ConfigDescriptor().Types() {
+				synced[typ] = true
+			}
+		}
+	}
 	for _, cache := range cr.caches {
-		if !cache.HasSynced() {
+		if cache.HasSynced() {
+			continue
+		}
+		if isAvailable(cache) {
 			return false
 		}
+		for _, typ := range cache.ConfigDescriptor().Types() {
+			if !synced[typ] {
+				return false
+			}
+		}
 	}
 	return true
 }
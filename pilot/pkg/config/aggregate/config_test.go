@@ -249,3 +249,52 @@ func TestAggregateStoreCache(t *testing.T) {
 		g.Expect(h).ToNot(gomega.BeNil())
 	})
 }
+
+func TestAggregateStoreCacheFailover(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	primary := &fakes.ConfigStoreCache{}
+	secondary := &fakes.ConfigStoreCache{}
+	descriptor := []schema.Instance{{
+		Type:        "some-config",
+		Plural:      "some-configs",
+		MessageName: "istio.networking.v1alpha3.Gateway",
+	}}
+	primary.ConfigDescriptorReturns(descriptor)
+	secondary.ConfigDescriptorReturns(descriptor)
+
+	primaryConfig := &model.Config{ConfigMeta: model.ConfigMeta{Type: "some-config", Name: "primary"}}
+	secondaryConfig := &model.Config{ConfigMeta: model.ConfigMeta{Type: "some-config", Name: "secondary"}}
+	primary.GetReturns(primaryConfig)
+	primary.ListReturns([]model.Config{*primaryConfig}, nil)
+	secondary.GetReturns(secondaryConfig)
+	secondary.ListReturns([]model.Config{*secondaryConfig}, nil)
+
+	available := true
+	cacheStore, err := aggregate.MakeCache([]model.ConfigStoreCache{
+		aggregate.WithAvailability(primary, func() bool { return available }),
+		secondary,
+	})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	// The first store takes precedence while available.
+	g.Expect(cacheStore.Get("some-config", "", "")).To(gomega.Equal(primaryConfig))
+	l, err := cacheStore.List("some-config", "")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(l).To(gomega.ConsistOf(*primaryConfig, *secondaryConfig))
+
+	primary.HasSyncedReturns(false)
+	secondary.HasSyncedReturns(true)
+	g.Expect(cacheStore.HasSynced()).To(gomega.BeFalse())
+
+	available = false
+	g.Expect(cacheStore.Get("some-config", "", "")).To(gomega.Equal(secondaryConfig))
+	l, err = cacheStore.List("some-config", "")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(l).To(gomega.ConsistOf(*secondaryConfig))
+	g.Expect(cacheStore.HasSynced()).To(gomega.BeTrue())
+
+	// An available store that has not synced blocks the sync.
+	secondary.HasSyncedReturns(false)
+	g.Expect(cacheStore.HasSynced()).To(gomega.BeFalse())
+}
@@ -0,0 +1,361 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterregistry
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/pkg/log"
+
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/kube"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/config/schemas"
+)
+
+const (
+	// GlobalServiceLabel marks the services of a remote cluster which are exported to the other
+	// clusters of the mesh as "<name>.<namespace>.global".
+	GlobalServiceLabel = "networking.istio.io/exportGlobal"
+
+	// globalServiceSuffix is the domain of the hosts of the exported services.
+	globalServiceSuffix = "global"
+
+	// globalGatewayPort is the AUTO_PASSTHROUGH port of the gateways routing the traffic of the
+	// exported services to the remote clusters.
+	globalGatewayPort = 15443
+)
+
+var (
+	// globalGatewayServices are the names of the gateway services, in order of preference, which
+	// expose the exported services of a remote cluster.
+	globalGatewayServices = []string{"istio-eastwestgateway", "istio-ingressgateway"}
+
+	// globalServiceVIPs is the range the addresses of the generated ServiceEntries are allocated from.
+	// The addresses are only used to capture the traffic of the exported services.
+	globalServiceVIPs = net.IPv4(240, 0, 0, 0)
+)
+
+// GlobalServices generates the ServiceEntries of the services exported by the remote clusters,
+// which otherwise have to be created by hand, along with a fake VIP, for every remote service of
+// a multicluster mesh with gateways. The ServiceEntries route the traffic to the gateways of all
+// the remote clusters exporting the service.
+type GlobalServices struct {
+	store        model.ConfigStoreCache
+	namespace    string
+	resyncPeriod time.Duration
+
+	mu       sync.Mutex // protects clusters and the generated ServiceEntries
+	clusters map[string]*globalServicesCluster
+}
+
+type globalServicesCluster struct {
+	informer cache.SharedIndexInformer
+	stopCh   chan struct{}
+}
+
+// NewGlobalServices creates the generator of the ServiceEntries of the exported services. The
+// ServiceEntries are created in the Istio namespace, which is also the namespace of the gateways
+// of the remote clusters.
+func NewGlobalServices(namespace string, resyncPeriod time.Duration) *GlobalServices {
+	return &GlobalServices{
+		store:        memory.NewController(memory.Make(schema.Set{schemas.ServiceEntry})),
+		namespace:    namespace,
+		resyncPeriod: resyncPeriod,
+		clusters:     make(map[string]*globalServicesCluster),
+	}
+}
+
+// ConfigStore returns the in-memory store of the generated ServiceEntries. The store is writable,
+// but the generator owns its content: every sync overwrites or deletes the ServiceEntries of its
+// namespace written by others.
+func (g *GlobalServices) ConfigStore() model.ConfigStoreCache {
+	return g.store
+}
+
+// AddCluster starts watching the services exported by a remote cluster.
+func (g *GlobalServices) AddCluster(client kubernetes.Interface, clusterID string) {
+	informer := coreinformers.NewServiceInformer(client, metav1.NamespaceAll, g.resyncPeriod, cache.Indexers{})
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if g.isGlobalService(obj) {
+				g.sync()
+			}
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			if (g.isGlobalService(old) || g.isGlobalService(cur)) && !reflect.DeepEqual(old, cur) {
+				g.sync()
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if g.isGlobalService(obj) {
+				g.sync()
+			}
+		},
+	})
+	cluster := &globalServicesCluster{informer: informer, stopCh: make(chan struct{})}
+
+	g.mu.Lock()
+	if previous, exists := g.clusters[clusterID]; exists {
+		close(previous.stopCh)
+	}
+	g.clusters[clusterID] = cluster
+	g.mu.Unlock()
+
+	go informer.Run(cluster.stopCh)
+}
+
+// DeleteCluster stops watching a remote cluster and removes its services from the ServiceEntries.
+func (g *GlobalServices) DeleteCluster(clusterID string) {
+	g.mu.Lock()
+	cluster, exists := g.clusters[clusterID]
+	if exists {
+		close(cluster.stopCh)
+		delete(g.clusters, clusterID)
+	}
+	g.mu.Unlock()
+
+	if exists {
+		g.sync()
+	}
+}
+
+// isGlobalService returns true for the exported services and the gateways exposing them.
+func (g *GlobalServices) isGlobalService(obj interface{}) bool {
+	svc, ok := obj.(*v1.Service)
+	if !ok {
+		return false
+	}
+	if svc.Labels[GlobalServiceLabel] == "true" {
+		return true
+	}
+	if svc.Namespace != g.namespace {
+		return false
+	}
+	for _, name := range globalGatewayServices {
+		if svc.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sync reconciles the generated ServiceEntries with the services exported by the remote clusters.
+func (g *GlobalServices) sync() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	existing := make(map[string]model.Config)
+	configs, err := g.store.List(schemas.ServiceEntry.Type, g.namespace)
+	if err != nil {
+		log.Warnf("cannot list the generated global ServiceEntries: %v", err)
+		return
+	}
+	for _, c := range configs {
+		existing[c.Name] = c
+	}
+
+	desired := g.buildServiceEntries(existing)
+	for name, se := range desired {
+		config := model.Config{
+			ConfigMeta: model.ConfigMeta{
+				Type:      schemas.ServiceEntry.Type,
+				Group:     schemas.ServiceEntry.Group,
+				Version:   schemas.ServiceEntry.Version,
+				Name:      name,
+				Namespace: g.namespace,
+			},
+			Spec: se,
+		}
+		if old, exists := existing[name]; exists {
+			if reflect.DeepEqual(old.Spec, se) {
+				continue
+			}
+			config.ResourceVersion = old.ResourceVersion
+			_, err = g.store.Update(config)
+		} else {
+			_, err = g.store.Create(config)
+		}
+		if err != nil {
+			log.Warnf("cannot generate the global ServiceEntry %s: %v", name, err)
+		}
+	}
+	for name := range existing {
+		if _, exists := desired[name]; !exists {
+			if err := g.store.Delete(schemas.ServiceEntry.Type, name, g.namespace); err != nil {
+				log.Warnf("cannot delete the global ServiceEntry %s: %v", name, err)
+			}
+		}
+	}
+}
+
+// buildServiceEntries builds the ServiceEntries of the exported services, keyed by name. The
+// addresses of the existing ServiceEntries are kept, so that the VIPs of the services are stable.
+func (g *GlobalServices) buildServiceEntries(existing map[string]model.Config) map[string]*networking.ServiceEntry {
+	clusterIDs := make([]string, 0, len(g.clusters))
+	for clusterID := range g.clusters {
+		clusterIDs = append(clusterIDs, clusterID)
+	}
+	sort.Strings(clusterIDs)
+
+	entries := make(map[string]*networking.ServiceEntry)
+	for _, clusterID := range clusterIDs {
+		services := g.clusters[clusterID].informer.GetStore().List()
+		gateway := globalGatewayAddress(services, g.namespace)
+		if gateway == "" {
+			log.Debugf("no gateway address for the exported services of cluster %s", clusterID)
+			continue
+		}
+
+		sort.Slice(services, func(i, j int) bool {
+			return cacheKey(services[i]) < cacheKey(services[j])
+		})
+		for _, obj := range services {
+			svc, ok := obj.(*v1.Service)
+			if !ok || svc.Labels[GlobalServiceLabel] != "true" {
+				continue
+			}
+			hostname := fmt.Sprintf("%s.%s.%s", svc.Name, svc.Namespace, globalServiceSuffix)
+			name := globalServiceEntryName(hostname)
+			se, exists := entries[name]
+			if !exists {
+				se = &networking.ServiceEntry{
+					Hosts:      []string{hostname},
+					Location:   networking.ServiceEntry_MESH_INTERNAL,
+					Resolution: networking.ServiceEntry_DNS,
+				}
+				entries[name] = se
+			}
+
+			ports := make(map[string]uint32)
+			for _, port := range svc.Spec.Ports {
+				p := &networking.Port{
+					Number:   uint32(port.Port),
+					Protocol: string(kube.ConvertProtocol(port.Port, port.Name, port.Protocol)),
+					Name:     port.Name,
+				}
+				if p.Name == "" {
+					p.Name = fmt.Sprintf("%s-%d", strings.ToLower(p.Protocol), p.Number)
+				}
+				ports[p.Name] = globalGatewayPort
+				if !hasPortName(se.Ports, p.Name) {
+					se.Ports = append(se.Ports, p)
+				}
+			}
+			se.Endpoints = append(se.Endpoints, &networking.ServiceEntry_Endpoint{
+				Address: gateway,
+				Ports:   ports,
+			})
+		}
+	}
+
+	allocated := make(map[string]bool)
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+		if old, exists := existing[name]; exists {
+			if se, ok := old.Spec.(*networking.ServiceEntry); ok && len(se.Addresses) > 0 {
+				entries[name].Addresses = se.Addresses
+				allocated[se.Addresses[0]] = true
+			}
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if se := entries[name]; len(se.Addresses) == 0 {
+			vip := allocateGlobalServiceVIP(se.Hosts[0], allocated)
+			allocated[vip] = true
+			se.Addresses = []string{vip}
+		}
+	}
+	return entries
+}
+
+// globalGatewayAddress returns the external address of the gateway exposing the exported services.
+func globalGatewayAddress(services []interface{}, namespace string) string {
+	for _, name := range globalGatewayServices {
+		for _, obj := range services {
+			svc, ok := obj.(*v1.Service)
+			if !ok || svc.Name != name || svc.Namespace != namespace {
+				continue
+			}
+			for _, ingress := range svc.Status.LoadBalancer.Ingress {
+				if ingress.IP != "" {
+					return ingress.IP
+				}
+				if ingress.Hostname != "" {
+					return ingress.Hostname
+				}
+			}
+			if len(svc.Spec.ExternalIPs) > 0 {
+				return svc.Spec.ExternalIPs[0]
+			}
+		}
+	}
+	return ""
+}
+
+// allocateGlobalServiceVIP allocates an address derived from the hostname of the service, so that
+// the addresses of the services do not depend on the order in which they are exported.
+func allocateGlobalServiceVIP(hostname string, allocated map[string]bool) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(hostname))
+	// Skip the network and the broadcast addresses of the range.
+	index := h.Sum32() % 65534
+	for i := uint32(0); i < 65534; i++ {
+		n := (index+i)%65534 + 1
+		vip := net.IPv4(globalServiceVIPs[12], globalServiceVIPs[13], byte(n>>8), byte(n)).String()
+		if !allocated[vip] {
+			return vip
+		}
+	}
+	return ""
+}
+
+func globalServiceEntryName(hostname string) string {
+	return strings.Replace(hostname, ".", "-", -1)
+}
+
+func hasPortName(ports []*networking.Port, name string) bool {
+	for _, p := range ports {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func cacheKey(obj interface{}) string {
+	key, _ := cache.MetaNamespaceKeyFunc(obj)
+	return key
+}
@@ -0,0 +1,145 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterregistry
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	networking "istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/pkg/config/schemas"
+)
+
+func exportedService(name, namespace string, exported bool) *v1.Service {
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{Name: "http", Port: 9080, Protocol: v1.ProtocolTCP}},
+		},
+	}
+	if exported {
+		svc.Labels = map[string]string{GlobalServiceLabel: "true"}
+	}
+	return svc
+}
+
+func gatewayService(name string, ingress v1.LoadBalancerIngress) *v1.Service {
+	svc := exportedService(name, "istio-system", false)
+	svc.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{ingress}
+	return svc
+}
+
+func addTestCluster(t *testing.T, g *GlobalServices, clusterID string, services ...*v1.Service) {
+	t.Helper()
+	informer := coreinformers.NewServiceInformer(fake.NewSimpleClientset(), metav1.NamespaceAll, 0, cache.Indexers{})
+	for _, svc := range services {
+		if err := informer.GetStore().Add(svc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g.clusters[clusterID] = &globalServicesCluster{informer: informer, stopCh: make(chan struct{})}
+}
+
+func globalServiceEntry(t *testing.T, g *GlobalServices, name string) *networking.ServiceEntry {
+	t.Helper()
+	config := g.ConfigStore().Get(schemas.ServiceEntry.Type, name, "istio-system")
+	if config == nil {
+		return nil
+	}
+	return config.Spec.(*networking.ServiceEntry)
+}
+
+func TestGlobalServices(t *testing.T) {
+	g := NewGlobalServices("istio-system", 0)
+	addTestCluster(t, g, "cluster1",
+		gatewayService("istio-ingressgateway", v1.LoadBalancerIngress{IP: "1.1.1.1"}),
+		exportedService("reviews", "default", true),
+		exportedService("ratings", "default", false))
+	addTestCluster(t, g, "cluster2",
+		gatewayService("istio-ingressgateway", v1.LoadBalancerIngress{IP: "2.2.2.1"}),
+		gatewayService("istio-eastwestgateway", v1.LoadBalancerIngress{Hostname: "gateway.example.com"}),
+		exportedService("reviews", "default", true))
+	// Services of clusters without a gateway cannot be reached.
+	addTestCluster(t, g, "cluster3", exportedService("details", "default", true))
+	g.sync()
+
+	configs, err := g.ConfigStore().List(schemas.ServiceEntry.Type, "istio-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 1 {
+		t.Fatalf("expected a single ServiceEntry, got %v", configs)
+	}
+	se := globalServiceEntry(t, g, "reviews-default-global")
+	if se == nil {
+		t.Fatal("missing the ServiceEntry of reviews.default.global")
+	}
+	if len(se.Hosts) != 1 || se.Hosts[0] != "reviews.default.global" {
+		t.Errorf("got hosts %v, want reviews.default.global", se.Hosts)
+	}
+	if se.Location != networking.ServiceEntry_MESH_INTERNAL || se.Resolution != networking.ServiceEntry_DNS {
+		t.Errorf("got location %v and resolution %v", se.Location, se.Resolution)
+	}
+	if len(se.Ports) != 1 || se.Ports[0].Name != "http" || se.Ports[0].Number != 9080 || se.Ports[0].Protocol != "HTTP" {
+		t.Errorf("unexpected ports %v", se.Ports)
+	}
+	if len(se.Endpoints) != 2 {
+		t.Fatalf("expected an endpoint per remote cluster, got %v", se.Endpoints)
+	}
+	for i, address := range []string{"1.1.1.1", "gateway.example.com"} {
+		ep := se.Endpoints[i]
+		if ep.Address != address || ep.Ports["http"] != globalGatewayPort {
+			t.Errorf("got endpoint %v, want %s:%d", ep, address, globalGatewayPort)
+		}
+	}
+	if len(se.Addresses) != 1 || !strings.HasPrefix(se.Addresses[0], "240.0.") {
+		t.Fatalf("got addresses %v, want a VIP in 240.0.0.0/16", se.Addresses)
+	}
+	vip := se.Addresses[0]
+
+	// The VIP is kept when the service is no longer exported by one of the clusters.
+	g.DeleteCluster("cluster2")
+	se = globalServiceEntry(t, g, "reviews-default-global")
+	if se == nil || len(se.Endpoints) != 1 || se.Endpoints[0].Address != "1.1.1.1" {
+		t.Fatalf("unexpected ServiceEntry after removing cluster2: %v", se)
+	}
+	if se.Addresses[0] != vip {
+		t.Errorf("got VIP %s, want the stable VIP %s", se.Addresses[0], vip)
+	}
+
+	g.DeleteCluster("cluster1")
+	if se := globalServiceEntry(t, g, "reviews-default-global"); se != nil {
+		t.Errorf("expected the ServiceEntry to be removed, got %v", se)
+	}
+}
+
+func TestAllocateGlobalServiceVIP(t *testing.T) {
+	allocated := map[string]bool{}
+	vip := allocateGlobalServiceVIP("reviews.default.global", allocated)
+	if vip != allocateGlobalServiceVIP("reviews.default.global", allocated) {
+		t.Fatal("the VIP of a service must not depend on the allocation order")
+	}
+	allocated[vip] = true
+	if other := allocateGlobalServiceVIP("reviews.default.global", allocated); other == vip {
+		t.Errorf("allocated VIP %s twice", vip)
+	}
+}
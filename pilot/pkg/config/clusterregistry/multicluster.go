@@ -42,6 +42,14 @@ type Multicluster struct {
 	serviceController *aggregate.Controller
 	XDSUpdater        model.XDSUpdater
 
+	// localClusterID is the ID of the cluster Pilot runs in. Secrets for the local cluster are
+	// ignored, so that the same set of remote secrets can be applied to every cluster of the mesh.
+	localClusterID string
+
+	// globalServices generates the ServiceEntries of the services exported by the remote clusters,
+	// if enabled.
+	globalServices *GlobalServices
+
 	m                     sync.Mutex // protects remoteKubeControllers
 	remoteKubeControllers map[string]*kubeController
 	meshNetworks          *meshconfig.MeshNetworks
@@ -50,8 +58,9 @@ type Multicluster struct {
 // NewMulticluster initializes data structure to store multicluster information
 // It also starts the secret controller
 func NewMulticluster(kc kubernetes.Interface, secretNamespace string,
-	watchedNamespace string, domainSuffix string, resyncPeriod time.Duration,
-	serviceController *aggregate.Controller, xds model.XDSUpdater, meshNetworks *meshconfig.MeshNetworks) (*Multicluster, error) {
+	watchedNamespace string, domainSuffix string, resyncPeriod time.Duration, localClusterID string,
+	serviceController *aggregate.Controller, xds model.XDSUpdater, meshNetworks *meshconfig.MeshNetworks,
+	globalServices *GlobalServices) (*Multicluster, error) {
 
 	remoteKubeController := make(map[string]*kubeController)
 	if resyncPeriod == 0 {
@@ -64,6 +73,8 @@ func NewMulticluster(kc kubernetes.Interface, secretNamespace string,
 		DomainSuffix:          domainSuffix,
 		ResyncPeriod:          resyncPeriod,
 		serviceController:     serviceController,
+		localClusterID:        localClusterID,
+		globalServices:        globalServices,
 		XDSUpdater:            xds,
 		remoteKubeControllers: remoteKubeController,
 		meshNetworks:          meshNetworks,
@@ -80,6 +91,11 @@ func NewMulticluster(kc kubernetes.Interface, secretNamespace string,
 // when a remote cluster is added.  This function needs to set up all the handlers
 // to watch for resources being added, deleted or changed on remote clusters.
 func (m *Multicluster) AddMemberCluster(clientset kubernetes.Interface, clusterID string) error {
+	if clusterID == m.localClusterID {
+		log.Infof("ignoring remote secret for the local cluster %s", clusterID)
+		return nil
+	}
+
 	// stopCh to stop controller created here when cluster removed.
 	stopCh := make(chan struct{})
 	var remoteKubeController kubeController
@@ -109,6 +125,10 @@ func (m *Multicluster) AddMemberCluster(clientset kubernetes.Interface, clusterI
 	_ = kubectl.AppendServiceHandler(func(*model.Service, model.Event) { m.updateHandler() })
 	_ = kubectl.AppendInstanceHandler(func(*model.ServiceInstance, model.Event) { m.updateHandler() })
 	go kubectl.Run(stopCh)
+
+	if m.globalServices != nil {
+		m.globalServices.AddCluster(clientset, clusterID)
+	}
 	return nil
 }
 
@@ -116,6 +136,13 @@ func (m *Multicluster) AddMemberCluster(clientset kubernetes.Interface, clusterI
 // when a remote cluster is deleted.  Also must clear the cache so remote resources
 // are removed.
 func (m *Multicluster) DeleteMemberCluster(clusterID string) error {
+	if clusterID == m.localClusterID {
+		return nil
+	}
+
+	if m.globalServices != nil {
+		m.globalServices.DeleteCluster(clusterID)
+	}
 
 	m.m.Lock()
 	defer m.m.Unlock()
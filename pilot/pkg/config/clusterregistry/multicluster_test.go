@@ -25,6 +25,7 @@ import (
 	"k8s.io/client-go/kubernetes/fake"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 
+	"istio.io/istio/pilot/pkg/serviceregistry"
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
 	"istio.io/istio/pkg/kube/secretcontroller"
 	pkgtest "istio.io/istio/pkg/test"
@@ -97,7 +98,8 @@ func Test_KubeSecretController(t *testing.T) {
 
 	clientset := fake.NewSimpleClientset()
 
-	mc, err := NewMulticluster(clientset, testSecretNameSpace, WatchedNamespace, DomainSuffix, ResyncPeriod, mockserviceController, nil, nil)
+	mc, err := NewMulticluster(clientset, testSecretNameSpace, WatchedNamespace, DomainSuffix, ResyncPeriod, "Kubernetes",
+		mockserviceController, nil, nil, nil)
 
 	if err != nil {
 		t.Fatalf("error creating Multicluster object and startign secret controller: %v", err)
@@ -124,3 +126,27 @@ func Test_KubeSecretController(t *testing.T) {
 	verifyControllers(t, mc, 0, "delete remote controller")
 
 }
+
+func TestLocalClusterSecretIgnored(t *testing.T) {
+	serviceController := aggregate.NewController()
+	serviceController.AddRegistry(aggregate.Registry{Name: serviceregistry.KubernetesRegistry, ClusterID: "cluster1"})
+	mc := &Multicluster{
+		serviceController:     serviceController,
+		localClusterID:        "cluster1",
+		remoteKubeControllers: map[string]*kubeController{},
+	}
+
+	if err := mc.AddMemberCluster(fake.NewSimpleClientset(), "cluster1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mc.remoteKubeControllers) != 0 {
+		t.Fatalf("expected no remote controller for the local cluster, got %d", len(mc.remoteKubeControllers))
+	}
+
+	if err := mc.DeleteMemberCluster("cluster1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, found := serviceController.GetRegistryIndex("cluster1"); !found {
+		t.Fatal("the registry of the local cluster must not be removed")
+	}
+}
@@ -44,6 +44,9 @@ type CoreDataModel interface {
 type Options struct {
 	DomainSuffix              string
 	ClearDiscoveryServerCache func(configType string)
+	// Collections restricts the controller to the given MCP collections. All
+	// Istio collections are handled when empty.
+	Collections []string
 }
 
 // Controller is a temporary storage for the changes received
@@ -52,6 +55,7 @@ type Controller struct {
 	configStoreMu sync.RWMutex
 	// keys [type][namespace][name]
 	configStore             map[string]map[string]map[string]*model.Config
+	descriptor              schema.Set
 	descriptorsByCollection map[string]schema.Instance
 	options                 Options
 	eventHandlers           map[string][]func(model.Config, model.Event)
@@ -64,7 +68,11 @@ type Controller struct {
 func NewController(options Options) CoreDataModel {
 	descriptorsByMessageName := make(map[string]schema.Instance, len(schemas.Istio))
 	synced := make(map[string]bool)
-	for _, descriptor := range schemas.Istio {
+	descriptors := schemas.Istio
+	if len(options.Collections) > 0 {
+		descriptors = collectionDescriptors(options.Collections)
+	}
+	for _, descriptor := range descriptors {
 		// don't register duplicate descriptors for the same collection
 		if _, ok := descriptorsByMessageName[descriptor.Collection]; !ok {
 			descriptorsByMessageName[descriptor.Collection] = descriptor
@@ -74,6 +82,7 @@ func NewController(options Options) CoreDataModel {
 
 	return &Controller{
 		configStore:             make(map[string]map[string]map[string]*model.Config),
+		descriptor:              descriptors,
 		options:                 options,
 		descriptorsByCollection: descriptorsByMessageName,
 		eventHandlers:           make(map[string][]func(model.Config, model.Event)),
@@ -81,10 +90,29 @@ func NewController(options Options) CoreDataModel {
 	}
 }
 
+// collectionDescriptors returns the descriptors of the given collections.
+func collectionDescriptors(collections []string) schema.Set {
+	out := schema.Set{}
+	for _, collection := range collections {
+		found := false
+		for _, descriptor := range schemas.Istio {
+			if descriptor.Collection == collection {
+				out = append(out, descriptor)
+				found = true
+				break
+			}
+		}
+		if !found {
+			log.Warnf("Ignoring unknown MCP collection %q", collection)
+		}
+	}
+	return out
+}
+
 // ConfigDescriptor returns all the ConfigDescriptors that this
 // controller is responsible for
 func (c *Controller) ConfigDescriptor() schema.Set {
-	return schemas.Istio
+	return c.descriptor
 }
 
 // List returns all the config that is stored by type and namespace
@@ -137,6 +165,25 @@ func (c *Controller) Apply(change *sink.Change) error {
 
 	// innerStore is [namespace][name]
 	innerStore := make(map[string]map[string]*model.Config)
+	if change.Incremental {
+		// Incremental changes only carry the updated and removed resources, start from a copy of
+		// the current resources, as the store is replaced below.
+		c.configStoreMu.RLock()
+		for namespace, byName := range c.configStore[descriptor.Type] {
+			innerStore[namespace] = make(map[string]*model.Config, len(byName))
+			for name, conf := range byName {
+				innerStore[namespace][name] = conf
+			}
+		}
+		c.configStoreMu.RUnlock()
+		for _, removed := range change.Removed {
+			namespace, name := extractNameNamespace(removed)
+			delete(innerStore[namespace], name)
+			if len(innerStore[namespace]) == 0 {
+				delete(innerStore, namespace)
+			}
+		}
+	}
 	for _, obj := range change.Objects {
 		namespace, name := extractNameNamespace(obj.Metadata.Name)
 
@@ -30,6 +30,7 @@ import (
 
 	"istio.io/istio/pilot/pkg/config/coredatamodel"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/schema"
 	"istio.io/istio/pkg/config/schemas"
 	"istio.io/istio/pkg/mcp/sink"
 )
@@ -173,6 +174,25 @@ func TestConfigDescriptor(t *testing.T) {
 	g.Expect(descriptors).To(gomega.Equal(schemas.Istio))
 }
 
+func TestConfigDescriptorCollections(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	options := testControllerOptions
+	options.Collections = []string{schemas.Gateway.Collection, schemas.VirtualService.Collection, "unknown"}
+	controller := coredatamodel.NewController(options)
+
+	g.Expect(controller.ConfigDescriptor()).To(gomega.Equal(schema.Set{schemas.Gateway, schemas.VirtualService}))
+	g.Expect(controller.HasSynced()).To(gomega.BeFalse())
+
+	for _, collection := range []string{schemas.Gateway.Collection, schemas.VirtualService.Collection} {
+		err := controller.Apply(&sink.Change{Collection: collection})
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+	}
+	g.Expect(controller.HasSynced()).To(gomega.BeTrue())
+
+	err := controller.Apply(&sink.Change{Collection: schemas.DestinationRule.Collection})
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
 func TestListInvalidType(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 	controller := coredatamodel.NewController(testControllerOptions)
@@ -257,6 +277,53 @@ func TestListSpecificNameSpace(t *testing.T) {
 	}
 }
 
+func TestApplyIncremental(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	controller := coredatamodel.NewController(testControllerOptions)
+
+	messages := convertToResource(g, schemas.Gateway.MessageName, []proto.Message{gateway, gateway2, gateway3})
+	message, message2, message3 := messages[0], messages[1], messages[2]
+
+	change := convert(
+		[]proto.Message{message, message2},
+		[]string{"namespace1/some-gateway1", "default/some-other-gateway"},
+		schemas.Gateway.Collection, schemas.Gateway.MessageName)
+	err := controller.Apply(change)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	// Update some-gateway1 and remove some-other-gateway, the other gateways are kept.
+	change = convert(
+		[]proto.Message{message3, message2},
+		[]string{"namespace1/some-gateway1", "namespace2/some-other-gateway3"},
+		schemas.Gateway.Collection, schemas.Gateway.MessageName)
+	change.Incremental = true
+	change.Removed = []string{"default/some-other-gateway"}
+	err = controller.Apply(change)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	c, err := controller.List(schemas.Gateway.Type, "")
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(c).To(gomega.HaveLen(2))
+	specs := make(map[string]proto.Message)
+	for _, conf := range c {
+		specs[conf.Namespace+"/"+conf.Name] = conf.Spec
+	}
+	g.Expect(specs).To(gomega.Equal(map[string]proto.Message{
+		"namespace1/some-gateway1":       message3,
+		"namespace2/some-other-gateway3": message2,
+	}))
+
+	// A full update replaces all the gateways.
+	change = convert([]proto.Message{message}, []string{"namespace1/some-gateway1"},
+		schemas.Gateway.Collection, schemas.Gateway.MessageName)
+	err = controller.Apply(change)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	c, err = controller.List(schemas.Gateway.Type, "")
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(c).To(gomega.HaveLen(1))
+}
+
 func TestApplyInvalidType(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 	controller := coredatamodel.NewController(testControllerOptions)
@@ -54,6 +54,7 @@ func ConvertObject(schema schema.Instance, object IstioObject, domain string) (*
 			Labels:            meta.Labels,
 			Annotations:       meta.Annotations,
 			ResourceVersion:   meta.ResourceVersion,
+			UID:               string(meta.UID),
 			CreationTimestamp: meta.CreationTimestamp.Time,
 		},
 		Spec: data,
@@ -79,6 +80,7 @@ func ConvertObjectFromUnstructured(schema schema.Instance, un *unstructured.Unst
 			Labels:            un.GetLabels(),
 			Annotations:       un.GetAnnotations(),
 			ResourceVersion:   un.GetResourceVersion(),
+			UID:               string(un.GetUID()),
 			CreationTimestamp: un.GetCreationTimestamp().Time,
 		},
 		Spec: data,
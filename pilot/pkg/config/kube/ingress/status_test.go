@@ -128,7 +128,7 @@ func makeStatusSyncer(t *testing.T, client kubernetes.Interface) (*StatusSyncer,
 	// Restore env settings
 	defer setAndRestoreEnv(t, oldEnvs)
 
-	return NewStatusSyncer(&m, client, testNamespace, kubecontroller.Options{
+	return NewStatusSyncer(&m, client, kubecontroller.Options{
 		WatchedNamespace: testNamespace,
 		ResyncPeriod:     resync,
 	})
@@ -258,3 +258,69 @@ func TestRunningAddressesWithPod(t *testing.T) {
 		t.Errorf("Address is not correctly set to node ip %v %v", address, nodeIP)
 	}
 }
+
+func TestStatusSyncerLeadershipTerms(t *testing.T) {
+	defer func(ns string) { ingressNamespace = ns }(ingressNamespace)
+	ingressNamespace = testNamespace
+
+	client := makeFakeClient()
+	ingClient := client.ExtensionsV1beta1().Ingresses(testNamespace)
+	if _, err := ingClient.Create(&extensions.Ingress{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   testNamespace,
+			Annotations: map[string]string{kube.IngressClassAnnotation: "istio"},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	syncer, err := makeStatusSyncer(t, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	syncer.Start(stop)
+
+	waitForStatus := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			ing, err := ingClient.Get("foo", metaV1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lb := ing.Status.LoadBalancer.Ingress; len(lb) == 1 && lb[0].IP == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("ingress status is %v, want %s", ing.Status.LoadBalancer.Ingress, want)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	// First term.
+	term := make(chan struct{})
+	go syncer.Run(term)
+	waitForStatus(serviceIP)
+	close(term)
+
+	// The lease is lost, and the status changes meanwhile.
+	ing, err := ingClient.Get("foo", metaV1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ing.Status.LoadBalancer.Ingress = []coreV1.LoadBalancerIngress{{IP: "5.6.7.8"}}
+	if _, err := ingClient.UpdateStatus(ing); err != nil {
+		t.Fatal(err)
+	}
+	waitForStatus("5.6.7.8")
+
+	// The lease is won back: the new term updates the status again.
+	term = make(chan struct{})
+	defer close(term)
+	go syncer.Run(term)
+	waitForStatus(serviceIP)
+}
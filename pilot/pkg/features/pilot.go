@@ -34,6 +34,20 @@ var (
 		"Sets the maximum number of concurrent grpc streams.",
 	).Get()
 
+	// InitialWindowSize and InitialConnWindowSize set the HTTP/2 flow control windows of the xDS
+	// connections. Zero keeps the gRPC defaults.
+	InitialWindowSize = env.RegisterIntVar(
+		"PILOT_GRPC_INITIAL_WINDOW_SIZE",
+		0,
+		"Sets the initial HTTP/2 flow control window of the grpc streams, in bytes.",
+	).Get()
+
+	InitialConnWindowSize = env.RegisterIntVar(
+		"PILOT_GRPC_INITIAL_CONN_WINDOW_SIZE",
+		0,
+		"Sets the initial HTTP/2 flow control window of the grpc connections, in bytes.",
+	).Get()
+
 	TraceSampling = env.RegisterFloatVar(
 		"PILOT_TRACE_SAMPLING",
 		100.0,
@@ -125,6 +139,14 @@ var (
 		"",
 	).Get()
 
+	// EnableMongoFilter enables injection of `envoy.filters.network.mongo_proxy` in the filter chain.
+	// Pilot injects this filter if the service port name is `mongo`.
+	EnableMongoFilter = env.RegisterBoolVar(
+		"PILOT_ENABLE_MONGO_FILTER",
+		true,
+		"EnableMongoFilter enables injection of `envoy.filters.network.mongo_proxy` in the filter chain.",
+	)
+
 	// EnableMysqlFilter enables injection of `envoy.filters.network.mysql_proxy` in the filter chain.
 	// Pilot injects this outbound filter if the service port name is `mysql`.
 	EnableMysqlFilter = env.RegisterBoolVar(
@@ -141,6 +163,23 @@ var (
 		"EnableRedisFilter enables injection of `envoy.filters.network.redis_proxy` in the filter chain.",
 	)
 
+	// EnableThriftFilter enables injection of `envoy.filters.network.thrift_proxy` in the filter chain.
+	// Pilot injects this filter if the service port name is `thrift`.
+	EnableThriftFilter = env.RegisterBoolVar(
+		"PILOT_ENABLE_THRIFT_FILTER",
+		false,
+		"EnableThriftFilter enables injection of `envoy.filters.network.thrift_proxy` in the filter chain.",
+	)
+
+	// EnableKafkaFilter enables injection of `envoy.filters.network.kafka_broker` in the filter chain.
+	// Pilot injects this filter if the service port name is `kafka`. The filter requires Envoy 1.13 or later,
+	// so only the proxies of Istio 1.6 or later get it.
+	EnableKafkaFilter = env.RegisterBoolVar(
+		"PILOT_ENABLE_KAFKA_FILTER",
+		false,
+		"EnableKafkaFilter enables injection of `envoy.filters.network.kafka_broker` in the filter chain.",
+	)
+
 	// UseRemoteAddress sets useRemoteAddress to true for side car outbound listeners so that it picks up the localhost
 	// address of the sender, which is an internal address, so that trusted headers are not sanitized.
 	UseRemoteAddress = env.RegisterBoolVar(
@@ -218,6 +257,190 @@ var (
 			"outbound listener for each pod in a headless service. This feature should be disabled "+
 			"if headless services have a large number of pods. ",
 	)
+
+	EnableIncrementalMCP = env.RegisterBoolVar(
+		"PILOT_ENABLE_INCREMENTAL_MCP",
+		false,
+		"If enabled, Pilot requests incremental updates from MCP config sources, which then only send the "+
+			"resources that changed instead of full snapshots of the collections.",
+	).Get()
+
+	MCPFailoverDelay = env.RegisterDurationVar(
+		"PILOT_MCP_FAILOVER_DELAY",
+		10*time.Second,
+		"How long an MCP config source must be disconnected before Pilot fails over to the next config source "+
+			"serving the same collections. Config sources earlier in MeshConfig.configSources take precedence.",
+	).Get()
+
+	ClusterName = env.RegisterStringVar(
+		"CLUSTER_ID",
+		"Kubernetes",
+		"Defines the name of the cluster this Pilot runs in. It must match the cluster name of the proxies in "+
+			"this cluster, and differ from the cluster names of the remote clusters Pilot watches.",
+	).Get()
+
+	EnableGlobalServiceEntries = env.RegisterBoolVar(
+		"PILOT_ENABLE_GLOBAL_SERVICE_ENTRIES",
+		false,
+		"If enabled, Pilot generates a ServiceEntry for the <name>.<namespace>.global host of every service "+
+			"exported by a remote cluster, routing the traffic to the gateways of the remote clusters.",
+	).Get()
+
+	OutboundTrafficPolicyAccessLogFile = env.RegisterStringVar(
+		"PILOT_OUTBOUND_TRAFFIC_POLICY_ACCESS_LOG_FILE",
+		"",
+		"If set, the connections forwarded to the PassthroughCluster or the BlackHoleCluster by the outbound "+
+			"traffic policy are logged to this file, with their original destination and SNI, independently "+
+			"of the mesh access log settings.",
+	).Get()
+
+	RejectAmbiguousPaths = env.RegisterBoolVar(
+		"PILOT_REJECT_AMBIGUOUS_PATHS",
+		false,
+		"If enabled, the gateways and the inbound HTTP listeners of the sidecars reject with a 400 the requests "+
+			"whose path holds repeated slashes or percent-encoded slashes, backslashes or dots, which Envoy does "+
+			"not normalize before routing and authorization.",
+	).Get()
+
+	// RegexMaxProgramSize bounds the size of the compiled RE2 programs of the regexes of the generated
+	// configuration, which Envoy rejects above it. The Envoy default of 100 rejects regexes accepted
+	// by the deprecated ECMAScript matchers, such as long alternations, so the default is larger.
+	RegexMaxProgramSize = env.RegisterIntVar(
+		"PILOT_REGEX_MAX_PROGRAM_SIZE",
+		1024,
+		"Sets the max_program_size of the RE2 regexes of the routes, headers, query parameters and CORS origins. "+
+			"Envoy rejects the regexes whose compiled program is larger. Zero keeps the Envoy default of 100.",
+	).Get()
+
+	ServerHeaderTransformation = env.RegisterStringVar(
+		"PILOT_SERVER_HEADER_TRANSFORMATION",
+		"OVERWRITE",
+		"How the HTTP listeners handle the server header of the responses: OVERWRITE it with istio-envoy, "+
+			"APPEND_IF_ABSENT or PASS_THROUGH the one of the application. Gateways can override it with the "+
+			"networking.istio.io/serverHeader annotation.",
+	).Get()
+
+	ViaHeader = env.RegisterStringVar(
+		"PILOT_VIA_HEADER",
+		"",
+		"If set, the HTTP listeners add a via header with this value to the requests and responses. Gateways "+
+			"can override it with the networking.istio.io/via annotation.",
+	).Get()
+
+	TCPAuthzAccessLogFile = env.RegisterStringVar(
+		"PILOT_TCP_AUTHZ_ACCESS_LOG_FILE",
+		"",
+		"If set, the authorization decisions on the inbound TCP connections are logged to this file as JSON, with "+
+			"the source principal, the destination and the matched policy, independently of the mesh access log settings.",
+	).Get()
+
+	LocalityRegionLabels = env.RegisterStringVar(
+		"PILOT_LOCALITY_REGION_LABELS",
+		"",
+		"Comma separated node label keys holding the region of the workloads, for topologies that do not use "+
+			"the well-known Kubernetes labels. They are consulted in order, before failure-domain.beta.kubernetes.io/region. "+
+			"The istio-locality label of a pod overrides the locality of its node, even when the node has no "+
+			"locality labels.",
+	).Get()
+
+	LocalityZoneLabels = env.RegisterStringVar(
+		"PILOT_LOCALITY_ZONE_LABELS",
+		"",
+		"Comma separated node label keys holding the zone of the workloads. They are consulted in order, "+
+			"before failure-domain.beta.kubernetes.io/zone.",
+	).Get()
+
+	LocalitySubzoneLabels = env.RegisterStringVar(
+		"PILOT_LOCALITY_SUBZONE_LABELS",
+		"",
+		"Comma separated node label keys holding the sub zone of the workloads, e.g. a rack. They are "+
+			"consulted in order. Kubernetes has no well-known sub zone label. A node with a sub zone but "+
+			"without a region or zone gets a locality with an empty region or zone.",
+	).Get()
+
+	EndpointPodFields = env.RegisterBoolVar(
+		"PILOT_ENDPOINT_POD_FIELDS",
+		false,
+		"If enabled, the node name and runtime class of Kubernetes pods are added to the labels of their endpoints, "+
+			"as pod.istio.io/nodeName and pod.istio.io/runtimeClassName, so DestinationRule subsets can select on them.",
+	).Get()
+
+	EndpointPodAnnotations = env.RegisterStringVar(
+		"PILOT_ENDPOINT_POD_ANNOTATIONS",
+		"",
+		"Comma separated pod annotation keys added to the labels of the pod endpoints, so DestinationRule subsets "+
+			"can select on them. Pod labels take precedence.",
+	).Get()
+
+	EndpointNodeLabels = env.RegisterStringVar(
+		"PILOT_ENDPOINT_NODE_LABELS",
+		"",
+		"Comma separated node label keys added to the labels of the endpoints of the pods running on the node, "+
+			"e.g. to select GPU nodes in DestinationRule subsets. Pod labels take precedence.",
+	).Get()
+
+	EnableEgressSNIRouting = env.RegisterBoolVar(
+		"PILOT_ENABLE_EGRESS_SNI_ROUTING",
+		true,
+		"If enabled, sidecars route TLS traffic originated by applications to port 443 of external services "+
+			"declared with a TCP or unspecified protocol and no address by its SNI, so each service gets its own "+
+			"cluster and DestinationRule rather than the one of the first service on the port.",
+	).Get()
+
+	EndpointMetadataLabels = env.RegisterStringVar(
+		"PILOT_ENDPOINT_METADATA_LABELS",
+		"",
+		"Comma separated workload label keys sent in the istio metadata of the endpoints, under labels, so the "+
+			"proxies know the app and version of the peers they call, e.g. for telemetry, without Mixer or request "+
+			"headers. For example, app,version. Empty, the default, sends no labels.",
+	).Get()
+
+	EnableHostOwnership = env.RegisterBoolVar(
+		"PILOT_ENABLE_HOST_OWNERSHIP",
+		false,
+		"If enabled, the VirtualServices and DestinationRules of the hosts of services are ignored unless they "+
+			"are in the namespace of a service of the host, or in a namespace listed by the "+
+			"networking.istio.io/configWriters annotation of the service.",
+	).Get()
+
+	EnableDestinationRuleInheritance = env.RegisterBoolVar(
+		"PILOT_ENABLE_DESTINATION_RULE_INHERITANCE",
+		false,
+		"If enabled, the DestinationRules of a host in the namespace of the proxy, in the namespace of the service "+
+			"and in the root namespace are merged, each traffic policy setting and subset coming from the first rule "+
+			"setting it. Otherwise only the first of these rules applies. Rules without TLS settings then inherit "+
+			"the TLS mode of a mesh wide rule such as *.local, so check them before enabling.",
+	).Get()
+
+	EnableStableEndpointHashing = env.RegisterBoolVar(
+		"PILOT_ENABLE_STABLE_ENDPOINT_HASHING",
+		false,
+		"If enabled, the endpoints are hashed by the ring hash and maglev load balancers by their workload UID, "+
+			"e.g. the pod name, rather than by their address, so the consistent hash assignments only change "+
+			"when the workloads do. Requires a proxy honoring the hash_key of the envoy.lb endpoint metadata.",
+	).Get()
+
+	EnableConfigRevisionMetadata = env.RegisterBoolVar(
+		"PILOT_ENABLE_CONFIG_REVISION_METADATA",
+		false,
+		"If enabled, the istio metadata of the generated clusters, listeners and routes also records the "+
+			"resourceVersion and UID of their config. Every update of a config then changes the objects built "+
+			"from it, and pushes them to the proxies, even when the generated configuration is otherwise the same.",
+	).Get()
+
+	EnableDryRun = env.RegisterBoolVar(
+		"PILOT_ENABLE_DRY_RUN",
+		false,
+		"If enabled, /debug/dryrunz reports the impact of the posted configs on the connected proxies. "+
+			"Each dry run generates the configuration of the proxies twice, so only one runs at a time.",
+	).Get()
+
+	DryRunMaxProxies = env.RegisterIntVar(
+		"PILOT_DRY_RUN_MAX_PROXIES",
+		100,
+		"Sets the maximum number of connected proxies whose configuration a dry run generates. The other "+
+			"proxies are counted as skipped in the report.",
+	).Get()
 )
 
 var (
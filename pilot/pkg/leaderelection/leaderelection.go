@@ -0,0 +1,164 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leaderelection
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
+
+	"istio.io/pkg/log"
+)
+
+const (
+	// leaseDuration is how long non-leaders wait before trying to acquire an expired lease.
+	leaseDuration = 30 * time.Second
+)
+
+// LeaderElection runs a set of functions in exactly one of the replicas sharing the same
+// election ID. All replicas keep serving, only the registered functions are gated.
+type LeaderElection struct {
+	namespace  string
+	name       string
+	electionID string
+	client     kubernetes.Interface
+	// leaseDuration is how long non-leaders wait before trying to acquire an expired lease.
+	leaseDuration time.Duration
+
+	mu     sync.Mutex
+	runFns []func(stop <-chan struct{})
+	leader bool
+}
+
+// NewLeaderElection creates a leader election for the given election ID, identifying this
+// replica by name. The lock is a config map named after the election ID in namespace.
+func NewLeaderElection(namespace, name, electionID string, client kubernetes.Interface) *LeaderElection {
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+	return &LeaderElection{
+		namespace:     namespace,
+		name:          name,
+		electionID:    electionID,
+		client:        client,
+		leaseDuration: leaseDuration,
+	}
+}
+
+// AddRunFunction registers a function to run while this replica is the leader. The stop
+// channel is closed when leadership is lost or the election is stopped. The function is
+// called again, with a new stop channel, each time the replica becomes the leader again,
+// so it must not reuse anything stopped by a previous stop channel.
+func (l *LeaderElection) AddRunFunction(f func(stop <-chan struct{})) *LeaderElection {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.runFns = append(l.runFns, f)
+	return l
+}
+
+// IsLeader returns true while this replica holds the lease.
+func (l *LeaderElection) IsLeader() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.leader
+}
+
+// Run takes part in the election until stop is closed. A replica that loses the lease
+// rejoins the election instead of giving up on its duties.
+func (l *LeaderElection) Run(stop <-chan struct{}) {
+	for {
+		le, err := l.create()
+		if err != nil {
+			log.Errorf("unexpected error starting leader election %s: %v", l.electionID, err)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		le.Run(ctx)
+		cancel()
+
+		select {
+		case <-stop:
+			return
+		default:
+			log.Infof("leader election %s lost, rejoining", l.electionID)
+		}
+	}
+}
+
+func (l *LeaderElection) create() (*leaderelection.LeaderElector, error) {
+	callbacks := leaderelection.LeaderCallbacks{
+		OnStartedLeading: func(ctx context.Context) {
+			log.Infof("leader election %s: %s is the new leader", l.electionID, l.name)
+			l.mu.Lock()
+			l.leader = true
+			runFns := l.runFns
+			l.mu.Unlock()
+
+			for _, f := range runFns {
+				go f(ctx.Done())
+			}
+		},
+		OnStoppedLeading: func() {
+			log.Infof("leader election %s: %s is not the leader anymore", l.electionID, l.name)
+			l.mu.Lock()
+			l.leader = false
+			l.mu.Unlock()
+		},
+		OnNewLeader: func(identity string) {
+			log.Infof("leader election %s: new leader elected: %v", l.electionID, identity)
+		},
+	}
+
+	broadcaster := record.NewBroadcaster()
+	hostname, _ := os.Hostname()
+	recorder := broadcaster.NewRecorder(scheme.Scheme, coreV1.EventSource{
+		Component: l.electionID,
+		Host:      hostname,
+	})
+
+	lock := resourcelock.ConfigMapLock{
+		ConfigMapMeta: metaV1.ObjectMeta{Namespace: l.namespace, Name: l.electionID},
+		Client:        l.client.CoreV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity:      l.name,
+			EventRecorder: recorder,
+		},
+	}
+
+	return leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          &lock,
+		LeaseDuration: l.leaseDuration,
+		RenewDeadline: l.leaseDuration / 2,
+		RetryPeriod:   l.leaseDuration / 4,
+		Callbacks:     callbacks,
+	})
+}
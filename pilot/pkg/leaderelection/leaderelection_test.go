@@ -0,0 +1,116 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leaderelection
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const testElectionID = "test-election"
+
+func TestLeaderElection(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	stop := make(chan struct{})
+	defer close(stop)
+
+	runs := atomic.NewInt32(0)
+	first := NewLeaderElection("istio-system", "pilot-1", testElectionID, client).
+		AddRunFunction(func(<-chan struct{}) { runs.Inc() })
+	second := NewLeaderElection("istio-system", "pilot-2", testElectionID, client).
+		AddRunFunction(func(<-chan struct{}) { runs.Inc() })
+
+	go first.Run(stop)
+	waitForLeader(t, first)
+	go second.Run(stop)
+
+	// The lease is held by the first replica, so the second one must keep waiting.
+	time.Sleep(time.Second)
+	if second.IsLeader() {
+		t.Fatal("expected only the first replica to be the leader")
+	}
+	if got := runs.Load(); got != 1 {
+		t.Fatalf("expected the run functions to be called once, got %d", got)
+	}
+}
+
+func TestLeaderElectionRejoin(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	stop := make(chan struct{})
+	defer close(stop)
+
+	terms := make(chan (<-chan struct{}), 2)
+	l := NewLeaderElection("istio-system", "pilot-1", testElectionID, client).
+		AddRunFunction(func(termStop <-chan struct{}) { terms <- termStop })
+	l.leaseDuration = 2 * time.Second
+
+	go l.Run(stop)
+	waitForLeader(t, l)
+	first := <-terms
+
+	// Another replica takes the lease over.
+	cm, err := client.CoreV1().ConfigMaps("istio-system").Get(testElectionID, metaV1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := metaV1.Now()
+	record, _ := json.Marshal(resourcelock.LeaderElectionRecord{
+		HolderIdentity:       "pilot-2",
+		LeaseDurationSeconds: 2,
+		AcquireTime:          now,
+		RenewTime:            now,
+	})
+	cm.Annotations[resourcelock.LeaderElectionRecordAnnotationKey] = string(record)
+	if _, err := client.CoreV1().ConfigMaps("istio-system").Update(cm); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-first:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the first term was not stopped when the lease was lost")
+	}
+
+	// The lease of the other replica expires without being renewed, so the replica wins it back
+	// and runs its functions again with a new stop channel.
+	select {
+	case second := <-terms:
+		if second == first {
+			t.Fatal("the second term reused the stop channel of the first one")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the replica did not rejoin the election")
+	}
+	if !l.IsLeader() {
+		t.Fatal("expected the replica to be the leader again")
+	}
+}
+
+func waitForLeader(t *testing.T, l *LeaderElection) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !l.IsLeader() {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s did not become the leader", l.name)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
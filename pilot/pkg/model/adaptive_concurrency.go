@@ -0,0 +1,111 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// AdaptiveConcurrencyAnnotation is the Sidecar annotation holding, in JSON, the adaptive concurrency
+// limit of the inbound requests of the selected workloads, e.g. {"maxConcurrencyLimit": 500}, or "{}"
+// for the defaults. The sidecars measure the latency of the requests, and reject with a 503 the
+// requests above a concurrency limit which they lower when the latency increases, so that overloaded
+// workloads shed load instead of queueing requests until they time out. It requires Envoy 1.12 or later.
+const AdaptiveConcurrencyAnnotation = "networking.istio.io/adaptiveConcurrency"
+
+const (
+	defaultConcurrencyUpdateInterval = 100 * time.Millisecond
+	defaultMinRTTInterval            = time.Minute
+	defaultLatencyPercentile         = 50
+)
+
+// AdaptiveConcurrency is the adaptive concurrency limit set by the AdaptiveConcurrencyAnnotation, with
+// the gradient controller of Envoy. The unset settings keep their defaults.
+type AdaptiveConcurrency struct {
+	// LatencyPercentile is the percentile of the latency of the sampled requests compared with the
+	// minimum latency, 50 by default.
+	LatencyPercentile float64 `json:"latencyPercentile,omitempty"`
+
+	// MaxConcurrencyLimit is the maximum concurrency limit, 1000 by default.
+	MaxConcurrencyLimit uint32 `json:"maxConcurrencyLimit,omitempty"`
+
+	// ConcurrencyUpdateInterval is the period of the updates of the concurrency limit, 100ms by default.
+	ConcurrencyUpdateInterval string `json:"concurrencyUpdateInterval,omitempty"`
+
+	// MinRTTInterval is the period of the measurements of the minimum latency, during which the
+	// concurrency is lowered, 1m by default.
+	MinRTTInterval string `json:"minRttInterval,omitempty"`
+
+	// MinRTTRequestCount is the number of requests sampled to measure the minimum latency, 50 by default.
+	MinRTTRequestCount uint32 `json:"minRttRequestCount,omitempty"`
+}
+
+// ParseAdaptiveConcurrency parses the value of the AdaptiveConcurrencyAnnotation, returning nil if it
+// is not set or invalid.
+func ParseAdaptiveConcurrency(value string) *AdaptiveConcurrency {
+	if value == "" {
+		return nil
+	}
+	ac := &AdaptiveConcurrency{}
+	if err := json.Unmarshal([]byte(value), ac); err != nil {
+		log.Warnf("ignoring invalid %s annotation %q: %v", AdaptiveConcurrencyAnnotation, value, err)
+		return nil
+	}
+	if err := ac.validate(); err != nil {
+		log.Warnf("ignoring invalid %s annotation %q: %v", AdaptiveConcurrencyAnnotation, value, err)
+		return nil
+	}
+	return ac
+}
+
+// validate checks the settings and sets the defaults of the unset ones.
+func (ac *AdaptiveConcurrency) validate() error {
+	if ac.LatencyPercentile < 0 || ac.LatencyPercentile > 100 {
+		return errors.New("latencyPercentile must be between 0 and 100")
+	}
+	if ac.LatencyPercentile == 0 {
+		ac.LatencyPercentile = defaultLatencyPercentile
+	}
+	for _, interval := range []struct {
+		value        *string
+		defaultValue time.Duration
+	}{
+		{&ac.ConcurrencyUpdateInterval, defaultConcurrencyUpdateInterval},
+		{&ac.MinRTTInterval, defaultMinRTTInterval},
+	} {
+		if *interval.value == "" {
+			*interval.value = interval.defaultValue.String()
+			continue
+		}
+		if d, err := time.ParseDuration(*interval.value); err != nil || d <= 0 {
+			return errors.New("the intervals must be positive durations")
+		}
+	}
+	return nil
+}
+
+// ConcurrencyUpdateIntervalDuration returns the period of the updates of the concurrency limit.
+func (ac *AdaptiveConcurrency) ConcurrencyUpdateIntervalDuration() time.Duration {
+	d, _ := time.ParseDuration(ac.ConcurrencyUpdateInterval)
+	return d
+}
+
+// MinRTTIntervalDuration returns the period of the measurements of the minimum latency.
+func (ac *AdaptiveConcurrency) MinRTTIntervalDuration() time.Duration {
+	d, _ := time.ParseDuration(ac.MinRTTInterval)
+	return d
+}
@@ -0,0 +1,95 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// CompressionAnnotation is the Gateway and Sidecar annotation holding, in JSON, the compression policy
+// of the responses sent by the servers of the gateway, or by the inbound listeners of the sidecars
+// selected by the Sidecar, e.g. {"contentTypes": ["application/json"], "minLength": 1024, "level": "BEST"}.
+const CompressionAnnotation = "networking.istio.io/compression"
+
+const (
+	// CompressionGzip is the gzip compression algorithm, the default one.
+	CompressionGzip = "gzip"
+
+	// CompressionBrotli is the brotli compression algorithm, which the proxies of this release do not
+	// support yet.
+	CompressionBrotli = "brotli"
+
+	// minCompressionLength is the smallest minimum length of the compressed responses accepted by Envoy.
+	minCompressionLength = 30
+)
+
+// CompressionPolicy is the response compression set by the CompressionAnnotation. The unset settings
+// keep the Envoy defaults.
+type CompressionPolicy struct {
+	// Algorithm is the compression algorithm, gzip.
+	Algorithm string `json:"algorithm,omitempty"`
+
+	// ContentTypes are the content types of the compressed responses. Envoy compresses the common
+	// text, JSON, JavaScript and XML content types if unset.
+	ContentTypes []string `json:"contentTypes,omitempty"`
+
+	// MinLength is the minimum length, in bytes, of the compressed responses, 30 by default.
+	MinLength uint32 `json:"minLength,omitempty"`
+
+	// Level is the compression level: BEST, SPEED or DEFAULT.
+	Level string `json:"level,omitempty"`
+}
+
+// ParseCompressionPolicy parses the value of the CompressionAnnotation, returning nil if it is not set
+// or invalid.
+func ParseCompressionPolicy(value string) *CompressionPolicy {
+	if value == "" {
+		return nil
+	}
+	policy := &CompressionPolicy{}
+	if err := json.Unmarshal([]byte(value), policy); err != nil {
+		log.Warnf("ignoring invalid %s annotation %q: %v", CompressionAnnotation, value, err)
+		return nil
+	}
+	if err := policy.validate(); err != nil {
+		log.Warnf("ignoring invalid %s annotation %q: %v", CompressionAnnotation, value, err)
+		return nil
+	}
+	return policy
+}
+
+// validate checks the policy can be generated into the compression filter of the proxies.
+func (p *CompressionPolicy) validate() error {
+	p.Algorithm = strings.ToLower(p.Algorithm)
+	p.Level = strings.ToUpper(p.Level)
+	switch p.Algorithm {
+	case "", CompressionGzip:
+	case CompressionBrotli:
+		return fmt.Errorf("the %s algorithm is not supported by the proxies, use %s", CompressionBrotli, CompressionGzip)
+	default:
+		return fmt.Errorf("unknown algorithm %q", p.Algorithm)
+	}
+	switch p.Level {
+	case "", "BEST", "SPEED", "DEFAULT":
+	default:
+		return fmt.Errorf("unknown level %q, must be BEST, SPEED or DEFAULT", p.Level)
+	}
+	if p.MinLength != 0 && p.MinLength < minCompressionLength {
+		return fmt.Errorf("minLength must be at least %d", minCompressionLength)
+	}
+	return nil
+}
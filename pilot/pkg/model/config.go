@@ -17,6 +17,7 @@ package model
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -82,6 +83,10 @@ type ConfigMeta struct {
 	// not been stored and assigned a revision.
 	ResourceVersion string `json:"resourceVersion,omitempty"`
 
+	// UID uniquely identifies the object in the config registry across deletions and re-creations
+	// under the same name. Optional, only set by registries that assign one.
+	UID string `json:"uid,omitempty"`
+
 	// CreationTimestamp records the creation time
 	CreationTimestamp time.Time `json:"creationTimestamp,omitempty"`
 }
@@ -302,9 +307,9 @@ func ResolveShortnameToFQDN(hostname string, meta ConfigMeta) host.Name {
 	return host.Name(out)
 }
 
-// resolveGatewayName uses metadata information to resolve a reference
+// ResolveGatewayName uses metadata information to resolve a reference
 // to shortname of the gateway to FQDN
-func resolveGatewayName(gwname string, meta ConfigMeta) string {
+func ResolveGatewayName(gwname string, meta ConfigMeta) string {
 	out := gwname
 
 	// New way of binding to a gateway in remote namespace
@@ -376,6 +381,24 @@ func sortConfigByCreationTime(configs []Config) []Config {
 	return configs
 }
 
+// RoutePriority returns the priority of the virtual service set by its annotation, or 0 if it is
+// not set or invalid.
+func RoutePriority(config Config) int {
+	priority, err := strconv.Atoi(config.Annotations[constants.RoutePriorityAnnotation])
+	if err != nil {
+		return 0
+	}
+	return priority
+}
+
+// sortVirtualServicesByPriority sorts the virtual services by descending priority, keeping the
+// order of the virtual services with the same priority.
+func sortVirtualServicesByPriority(configs []Config) {
+	sort.SliceStable(configs, func(i, j int) bool {
+		return RoutePriority(configs[i]) > RoutePriority(configs[j])
+	})
+}
+
 func (store *istioConfigStore) Gateways(workloadLabels labels.Collection) []Config {
 	configs, err := store.List(schemas.Gateway.Type, NamespaceAll)
 	if err != nil {
@@ -510,6 +510,15 @@ const (
 	// Alpha in 1.1, based on feedback may be turned into an API or change. Set to "1" to enable.
 	NodeMetadataHTTP10 = "HTTP10"
 
+	// NodeMetadataHTTP10DefaultHost is the host used for the HTTP/1.0 requests that have no Host header,
+	// which are rejected otherwise. Setting it enables the "AcceptHttp_10" option on all HTTP listeners.
+	NodeMetadataHTTP10DefaultHost = "HTTP10_DEFAULT_HOST"
+
+	// NodeMetadataAllowAbsoluteURL sets whether the HTTP listeners accept proxy-style requests with an
+	// absolute URL, e.g. "GET http://foo/bar". Set to "true" or "false" to override the Envoy default,
+	// which only accepts them on the HTTP proxy listener.
+	NodeMetadataAllowAbsoluteURL = "ALLOW_ABSOLUTE_URL"
+
 	// NodeMetadataConfigNamespace is the name of the metadata variable that carries info about
 	// the config namespace associated with the proxy
 	NodeMetadataConfigNamespace = "CONFIG_NAMESPACE"
@@ -546,6 +555,14 @@ const (
 	// NodeMetadataTLSServerRootCert is the absolute path to server root cert file
 	NodeMetadataTLSServerRootCert = "TLS_SERVER_ROOT_CERT"
 
+	// NodeMetadataTLSSessionTicketKeys is a comma separated list of absolute paths to the TLS session
+	// ticket keys of a gateway, the first one encrypting new tickets. Replicas sharing the keys resume
+	// the sessions established by each other.
+	NodeMetadataTLSSessionTicketKeys = "TLS_SESSION_TICKET_KEYS"
+
+	// NodeMetadataTLSSessionTimeout is the lifetime of the TLS sessions of a gateway, e.g. "2h".
+	NodeMetadataTLSSessionTimeout = "TLS_SESSION_TIMEOUT"
+
 	// NodeMetadataTLSClientCertChain is the absolute path to client cert-chain file
 	NodeMetadataTLSClientCertChain = "TLS_CLIENT_CERT_CHAIN"
 
@@ -559,6 +576,30 @@ const (
 	// If not set, no timeout is set.
 	NodeMetadataIdleTimeout = "IDLE_TIMEOUT"
 
+	// NodeMetadataMaxConcurrentStreams specifies the maximum number of concurrent HTTP/2 streams of a
+	// downstream connection to a gateway. If not set, the Envoy default is used.
+	NodeMetadataMaxConcurrentStreams = "MAX_CONCURRENT_STREAMS"
+
+	// NodeMetadataMaxRequestHeadersKb specifies the maximum size, in KiB, of the request headers the HTTP
+	// listeners of the proxy accept, up to 96. If not set, the Envoy default of 60 KiB is used.
+	NodeMetadataMaxRequestHeadersKb = "MAX_REQUEST_HEADERS_KB"
+
+	// NodeMetadataNumTrustedProxies specifies the number of trusted proxies, e.g. cloud load balancers,
+	// in front of a gateway. The gateway takes the client address from the X-Forwarded-For header,
+	// skipping the addresses appended by these proxies. If not set, the address of the downstream
+	// connection is used.
+	NodeMetadataNumTrustedProxies = "NUM_TRUSTED_PROXIES"
+
+	// NodeMetadataForwardClientCertDetails specifies how a gateway handles the x-forwarded-client-cert
+	// header of the requests, one of SANITIZE, FORWARD_ONLY, APPEND_FORWARD, SANITIZE_SET or
+	// ALWAYS_FORWARD_ONLY. If not set, SANITIZE_SET is used.
+	NodeMetadataForwardClientCertDetails = "FORWARD_CLIENT_CERT_DETAILS"
+
+	// NodeMetadataPreserveSourceAddress, if "true", makes the sidecar open the inbound connections to the
+	// application from the address of the downstream client instead of the loopback address. It only
+	// applies to the TPROXY interception mode, and the application must listen on the pod IP.
+	NodeMetadataPreserveSourceAddress = "PRESERVE_SOURCE_ADDRESS"
+
 	// NodeMetadataPodPorts the ports on a pod. This is used to lookup named ports.
 	NodeMetadataPodPorts = "POD_PORTS"
 
@@ -590,8 +631,21 @@ const (
 	// NodeMetadataExchangeKeys specifies a list of metadata keys that should be used for Node Metadata Exchange.
 	// The list is comma-separated.
 	NodeMetadataExchangeKeys = "EXCHANGE_KEYS"
+
+	// NodeMetadataGenerator selects the config generator for the node. Proxyless gRPC clients set it to
+	// GeneratorGRPC.
+	NodeMetadataGenerator = "GENERATOR"
 )
 
+// GeneratorGRPC is the NodeMetadataGenerator value of proxyless gRPC clients, which use the xDS
+// resolver and balancer of gRPC instead of a sidecar.
+const GeneratorGRPC = "grpc"
+
+// IsProxylessGRPC returns true if the node is a gRPC client talking to pilot directly.
+func (node *Proxy) IsProxylessGRPC() bool {
+	return node != nil && node.Metadata[NodeMetadataGenerator] == GeneratorGRPC
+}
+
 // TrafficInterceptionMode indicates how traffic to/from the workload is captured and
 // sent to Envoy. This should not be confused with the CaptureMode in the API that indicates
 // how the user wants traffic to be intercepted for the listener. TrafficInterceptionMode is
@@ -628,3 +682,9 @@ func (node *Proxy) GetInterceptionMode() TrafficInterceptionMode {
 
 	return InterceptionRedirect
 }
+
+// PreservesSourceAddress returns whether the inbound connections of the proxy to the application keep
+// the address of the downstream client.
+func (node *Proxy) PreservesSourceAddress() bool {
+	return node.GetInterceptionMode() == InterceptionTproxy && node.Metadata[NodeMetadataPreserveSourceAddress] == "true"
+}
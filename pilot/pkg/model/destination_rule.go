@@ -15,17 +15,102 @@
 package model
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 
 	networking "istio.io/api/networking/v1alpha3"
 
 	"istio.io/istio/pkg/config/host"
 )
 
+// CredentialNameAnnotation is the DestinationRule annotation naming the secret holding the client
+// certificate, key and CA certificate of its MUTUAL TLS settings. The secret is delivered to gateways
+// through SDS, in the same way as the credentialName of Gateway servers, and takes precedence over
+// the file paths of the TLS settings.
+const CredentialNameAnnotation = "networking.istio.io/credentialName"
+
+// WarmupDurationAnnotation is the DestinationRule annotation setting the time, e.g. "2m", over which
+// the load balancing weight of endpoints added to the destination ramps up from a tenth of their
+// weight to their full weight. This keeps the cold endpoints of a rolling deploy from instantly
+// receiving their full share of traffic.
+const WarmupDurationAnnotation = "networking.istio.io/warmupDuration"
+
+// EndpointSubsetSizeAnnotation is the DestinationRule annotation limiting the number of endpoints of the
+// destination each proxy gets, e.g. "50". Each proxy gets a stable subset of the endpoints, spread across
+// the localities, which bounds the memory and the health checks of proxies calling very large services.
+const EndpointSubsetSizeAnnotation = "networking.istio.io/endpointSubsetSize"
+
+// HTTP2OptionsAnnotation is the DestinationRule and Gateway annotation holding, in JSON, the HTTP/2
+// settings of the connections to the destination, or of the connections accepted by the servers of
+// the gateway, e.g. {"maxConcurrentStreams": 100, "initialStreamWindowSize": 1048576}.
+const HTTP2OptionsAnnotation = "networking.istio.io/http2Options"
+
+// HTTP1ProperCaseHeadersAnnotation is the DestinationRule and Gateway annotation which, set to "true",
+// capitalizes the names of the HTTP/1 headers sent to the destination, or to the clients of the servers
+// of the gateway, e.g. "Content-Type", for legacy applications that do not accept lowercase names. The
+// names are capitalized rather than preserved: Envoy has no header formatter preserving their case.
+const HTTP1ProperCaseHeadersAnnotation = "networking.istio.io/http1ProperCaseHeaders"
+
+// RetainEndpointsOnDNSFailureAnnotation is the DestinationRule annotation which, set to "true", keeps
+// the last resolved address of a DNS destination when its DNS resolution fails, instead of emptying
+// the cluster. It only applies to destinations with a single DNS endpoint, e.g. most external hosts.
+const RetainEndpointsOnDNSFailureAnnotation = "networking.istio.io/retainEndpointsOnDnsFailure"
+
+// HTTP2Options are the HTTP/2 settings set by the HTTP2OptionsAnnotation. The unset settings keep
+// their defaults.
+type HTTP2Options struct {
+	// MaxConcurrentStreams is the maximum number of concurrent streams of a connection.
+	MaxConcurrentStreams uint32 `json:"maxConcurrentStreams,omitempty"`
+
+	// InitialStreamWindowSize is the initial flow control window of the streams, in bytes.
+	InitialStreamWindowSize uint32 `json:"initialStreamWindowSize,omitempty"`
+
+	// InitialConnectionWindowSize is the initial flow control window of the connections, in bytes.
+	InitialConnectionWindowSize uint32 `json:"initialConnectionWindowSize,omitempty"`
+
+	// AllowConnect allows the CONNECT method over HTTP/2, used by WebSockets over HTTP/2.
+	AllowConnect bool `json:"allowConnect,omitempty"`
+}
+
+// ParseHTTP2Options parses the value of the HTTP2OptionsAnnotation, returning nil if it is not set or invalid.
+func ParseHTTP2Options(value string) *HTTP2Options {
+	if value == "" {
+		return nil
+	}
+	opts := &HTTP2Options{}
+	if err := json.Unmarshal([]byte(value), opts); err != nil {
+		log.Warnf("ignoring invalid %s annotation %q: %v", HTTP2OptionsAnnotation, value, err)
+		return nil
+	}
+	if err := opts.validate(); err != nil {
+		log.Warnf("ignoring invalid %s annotation %q: %v", HTTP2OptionsAnnotation, value, err)
+		return nil
+	}
+	return opts
+}
+
+// validate checks the settings are within the ranges accepted by Envoy.
+func (o *HTTP2Options) validate() error {
+	if o.MaxConcurrentStreams > math.MaxInt32 {
+		return fmt.Errorf("maxConcurrentStreams must be at most %d", math.MaxInt32)
+	}
+	for name, size := range map[string]uint32{
+		"initialStreamWindowSize":     o.InitialStreamWindowSize,
+		"initialConnectionWindowSize": o.InitialConnectionWindowSize,
+	} {
+		if size != 0 && (size < math.MaxUint16 || size > math.MaxInt32) {
+			return fmt.Errorf("%s must be between %d and %d", name, math.MaxUint16, math.MaxInt32)
+		}
+	}
+	return nil
+}
+
 // This function merges one or more destination rules for a given host string
-// into a single destination rule. Note that it does not perform inheritance style merging.
+// into a single destination rule. Note that it does not merge rules of different hosts.
 // IOW, given three dest rules (*.foo.com, *.foo.com, *.com), calling this function for
 // each config will result in a final dest rule set (*.foo.com, and *.com).
+// The rules of the same host are merged field by field, the oldest rule taking precedence.
 func (ps *PushContext) combineSingleDestinationRule(
 	combinedDestRuleHosts []host.Name,
 	combinedDestRuleMap map[host.Name]*combinedDestinationRule,
@@ -47,11 +132,8 @@ func (ps *PushContext) combineSingleDestinationRule(
 						subset.Name, string(resolvedHost)))
 			}
 		}
-		// If there is no top level policy and the incoming rule has top level
-		// traffic policy, use the one from the incoming rule.
-		if combinedRule.TrafficPolicy == nil && rule.TrafficPolicy != nil {
-			combinedRule.TrafficPolicy = rule.TrafficPolicy
-		}
+		// The incoming rule sets the top level traffic policy settings left unset so far.
+		combinedRule.TrafficPolicy = mergeTrafficPolicy(combinedRule.TrafficPolicy, rule.TrafficPolicy)
 		return combinedDestRuleHosts
 	}
 
@@ -66,3 +148,88 @@ func (ps *PushContext) combineSingleDestinationRule(
 
 	return combinedDestRuleHosts
 }
+
+// defaultDestinationRules returns the default destination rules, of host "*", applying to the proxies
+// of the namespace: the one of the namespace, then the one of the root namespace.
+func (ps *PushContext) defaultDestinationRules(namespace string) []*Config {
+	if len(ps.defaultDestRules) == 0 {
+		return nil
+	}
+	var out []*Config
+	if d, f := ps.defaultDestRules[namespace]; f && namespace != ps.Env.Mesh.RootNamespace {
+		out = append(out, d)
+	}
+	if d, f := ps.defaultDestRules[ps.Env.Mesh.RootNamespace]; f {
+		out = append(out, d)
+	}
+	return out
+}
+
+// mergeDestinationRules merges the destination rules, by descending precedence, into the first one:
+// the top level settings of the traffic policy it leaves unset, and the subsets it does not define,
+// are inherited from the following rules.
+func mergeDestinationRules(configs []*Config) *Config {
+	if len(configs) == 0 {
+		return nil
+	}
+	if len(configs) == 1 {
+		return configs[0]
+	}
+	rule := configs[0].Spec.(*networking.DestinationRule)
+	merged := &networking.DestinationRule{
+		Host:          rule.Host,
+		TrafficPolicy: rule.TrafficPolicy,
+		Subsets:       append([]*networking.Subset(nil), rule.Subsets...),
+		ExportTo:      rule.ExportTo,
+	}
+	subsets := make(map[string]bool, len(rule.Subsets))
+	for _, subset := range rule.Subsets {
+		subsets[subset.Name] = true
+	}
+	for _, cfg := range configs[1:] {
+		inherited := cfg.Spec.(*networking.DestinationRule)
+		merged.TrafficPolicy = mergeTrafficPolicy(merged.TrafficPolicy, inherited.TrafficPolicy)
+		for _, subset := range inherited.Subsets {
+			if !subsets[subset.Name] {
+				subsets[subset.Name] = true
+				merged.Subsets = append(merged.Subsets, subset)
+			}
+		}
+	}
+
+	out := *configs[0]
+	out.Spec = merged
+	return &out
+}
+
+// mergeTrafficPolicy returns the traffic policy with its unset top level settings inherited from
+// the other traffic policy. The port level settings are not inherited, as they would override the
+// top level settings of the traffic policy for their ports.
+func mergeTrafficPolicy(policy, inherited *networking.TrafficPolicy) *networking.TrafficPolicy {
+	if inherited == nil {
+		return policy
+	}
+	if policy == nil {
+		return inherited
+	}
+	out := &networking.TrafficPolicy{
+		LoadBalancer:      policy.LoadBalancer,
+		ConnectionPool:    policy.ConnectionPool,
+		OutlierDetection:  policy.OutlierDetection,
+		Tls:               policy.Tls,
+		PortLevelSettings: policy.PortLevelSettings,
+	}
+	if out.LoadBalancer == nil {
+		out.LoadBalancer = inherited.LoadBalancer
+	}
+	if out.ConnectionPool == nil {
+		out.ConnectionPool = inherited.ConnectionPool
+	}
+	if out.OutlierDetection == nil {
+		out.OutlierDetection = inherited.OutlierDetection
+	}
+	if out.Tls == nil {
+		out.Tls = inherited.Tls
+	}
+	return out
+}
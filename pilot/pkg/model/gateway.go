@@ -15,6 +15,7 @@
 package model
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -23,6 +24,7 @@ import (
 
 	"istio.io/istio/pkg/config/gateway"
 	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
 	"istio.io/pkg/monitoring"
 )
 
@@ -46,8 +48,153 @@ type MergedGateway struct {
 	// Inverse of ServersByRouteName. Returning this as part of merge result allows to keep route name generation logic
 	// encapsulated within the model and, as a side effect, to avoid generating route names twice.
 	RouteNamesByServer map[*networking.Server]string
+
+	// maps from server to the path prefixes exempted from its HTTPS redirect
+	HTTPSRedirectExemptPaths map[*networking.Server][]string
+
+	// maps from server to the access log settings overriding the mesh ones
+	AccessLogForServer map[*networking.Server]*GatewayAccessLog
+
+	// maps from server to the header operations applied to all the routes bound to it
+	HeadersForServer map[*networking.Server]*networking.Headers
+
+	// maps from server to the server and via headers settings overriding the mesh ones
+	ServerHeaderForServer map[*networking.Server]*GatewayServerHeader
+
+	// maps from server to the validation of the Host header of the requests it receives
+	HostValidationForServer map[*networking.Server]*GatewayHostValidation
+
+	// maps from server to the HTTP/2 settings of the connections it accepts
+	HTTP2OptionsForServer map[*networking.Server]*HTTP2Options
+
+	// maps from server to the compression policy of the responses it sends
+	CompressionForServer map[*networking.Server]*CompressionPolicy
+
+	// maps from server to the cache of the responses it sends
+	ResponseCacheForServer map[*networking.Server]*GatewayResponseCache
+
+	// maps from server to the maximum size of the bodies of the requests it receives
+	MaxRequestBodyBytesForServer map[*networking.Server]uint32
+
+	// set of the servers capitalizing the names of the HTTP/1 headers they send
+	ProperCaseHeadersForServer map[*networking.Server]bool
 }
 
+// GatewayResponseCache caches the responses sent by the servers of a gateway, as allowed by their
+// Cache-Control headers, and sets how the cache keys are built from the requests. By default the keys
+// hold the scheme, the host, the path and all the query parameters of the requests.
+type GatewayResponseCache struct {
+	// ExcludeScheme leaves the scheme out of the cache keys.
+	ExcludeScheme bool `json:"excludeScheme,omitempty"`
+
+	// ExcludeHost leaves the host out of the cache keys.
+	ExcludeHost bool `json:"excludeHost,omitempty"`
+
+	// QueryParametersIncluded restricts the query parameters of the cache keys to these ones.
+	QueryParametersIncluded []string `json:"queryParametersIncluded,omitempty"`
+
+	// QueryParametersExcluded leaves these query parameters out of the cache keys.
+	QueryParametersExcluded []string `json:"queryParametersExcluded,omitempty"`
+
+	// MaxBodyBytes is the maximum size of the cached responses, in bytes. There is no limit if unset.
+	MaxBodyBytes uint32 `json:"maxBodyBytes,omitempty"`
+}
+
+// GatewayHostValidation hardens the routing of the requests received by the servers of a gateway
+// against Host header attacks.
+type GatewayHostValidation struct {
+	// RejectUnknownHosts rejects the requests whose Host matches none of the virtual hosts with a 421
+	// (Misdirected Request) rather than a 404, unless a VirtualService bound to the port serves all hosts.
+	RejectUnknownHosts bool
+
+	// IgnorePort matches the Host of the requests with the virtual hosts whatever its port, e.g. when
+	// the gateway is exposed on another port than the one it listens on.
+	IgnorePort bool
+}
+
+// GatewayServerHeader overrides the mesh settings of the server and via headers for the servers of
+// a gateway. Empty fields fall back to the mesh settings.
+type GatewayServerHeader struct {
+	// Transformation is how the server header of the responses is handled: OVERWRITE,
+	// APPEND_IF_ABSENT or PASS_THROUGH.
+	Transformation string
+
+	// Via is the value of the via header added to the requests and responses.
+	Via string
+}
+
+// GatewayAccessLog overrides the access log settings of the mesh for the servers of a gateway.
+// Empty fields fall back to the mesh settings.
+type GatewayAccessLog struct {
+	// File is the path of the access log. If neither the gateway nor the mesh set it, the access log
+	// is written to /dev/stdout.
+	File string
+
+	// Format is the access log format, in the syntax of the mesh accessLogFormat.
+	Format string
+
+	// Encoding is the access log encoding, TEXT or JSON.
+	Encoding string
+
+	// MinStatusCode restricts the access log to the responses with at least this status code.
+	MinStatusCode uint32
+}
+
+const (
+	// HTTPSRedirectExemptPathsAnnotation is the Gateway annotation listing, comma separated, the path
+	// prefixes exempted from the HTTPS redirect of its servers, e.g. "/.well-known/acme-challenge/".
+	HTTPSRedirectExemptPathsAnnotation = "networking.istio.io/httpsRedirectExemptPaths"
+
+	// AccessLogFileAnnotation is the Gateway annotation overriding the access log file of its servers.
+	AccessLogFileAnnotation = "networking.istio.io/accessLogFile"
+
+	// AccessLogFormatAnnotation is the Gateway annotation overriding the access log format of its servers.
+	AccessLogFormatAnnotation = "networking.istio.io/accessLogFormat"
+
+	// AccessLogEncodingAnnotation is the Gateway annotation overriding the access log encoding of its
+	// servers, TEXT or JSON.
+	AccessLogEncodingAnnotation = "networking.istio.io/accessLogEncoding"
+
+	// AccessLogMinStatusCodeAnnotation is the Gateway annotation restricting the access log of its servers
+	// to the responses with at least the given status code, e.g. "400".
+	AccessLogMinStatusCodeAnnotation = "networking.istio.io/accessLogMinStatusCode"
+
+	// HeadersAnnotation is the Gateway annotation holding, in JSON, the header operations applied to all
+	// the routes bound to its servers, in the syntax of the headers of HTTP routes, e.g.
+	// {"response": {"set": {"Strict-Transport-Security": "max-age=31536000"}}}.
+	HeadersAnnotation = "networking.istio.io/headers"
+
+	// ServerHeaderAnnotation is the Gateway annotation setting how its servers handle the server header of
+	// the responses: OVERWRITE it with istio-envoy, APPEND_IF_ABSENT or PASS_THROUGH the one of the application.
+	ServerHeaderAnnotation = "networking.istio.io/serverHeader"
+
+	// ViaAnnotation is the Gateway annotation setting the via header its servers add to the requests
+	// and responses, e.g. "1.1 edge-gateway".
+	ViaAnnotation = "networking.istio.io/via"
+
+	// RejectUnknownHostsAnnotation is the Gateway annotation which, set to "true", makes its servers reject
+	// the requests whose Host matches none of the hosts of the bound VirtualServices.
+	RejectUnknownHostsAnnotation = "networking.istio.io/rejectUnknownHosts"
+
+	// IgnoreHostPortAnnotation is the Gateway annotation which, set to "true", makes its servers ignore the
+	// port of the Host of the requests when matching it with the hosts of the bound VirtualServices.
+	IgnoreHostPortAnnotation = "networking.istio.io/ignoreHostPort"
+
+	// ResponseCacheAnnotation is the Gateway annotation holding, in JSON, the cache of the responses of
+	// its servers, e.g. {"excludeScheme": true, "queryParametersIncluded": ["v"]}, or "{}" for the
+	// default cache keys. The responses are cached as allowed by their Cache-Control headers, which
+	// the cacheTtl annotation of the bound VirtualServices can set. It requires Envoy 1.14 or later, and
+	// is ignored for proxies older than Istio 1.6.
+	ResponseCacheAnnotation = "networking.istio.io/responseCache"
+
+	// MaxRequestBodyBytesAnnotation is the Gateway annotation setting the maximum size, in bytes, of the
+	// bodies of the requests received by its servers, e.g. "10485760". The servers buffer the bodies and
+	// reject the larger ones with a 413 before they reach the backends. On a VirtualService, it sets the
+	// size for its routes on these servers as name=size pairs, e.g. "upload=104857600,*=1048576", where
+	// "*" applies to the routes not listed and a size of 0 disables the buffering of a route.
+	MaxRequestBodyBytesAnnotation = "networking.istio.io/maxRequestBodyBytes"
+)
+
 var (
 	typeTag = monitoring.MustCreateLabel("type")
 	nameTag = monitoring.MustCreateLabel("name")
@@ -69,8 +216,11 @@ func recordRejectedConfig(gatewayName string) {
 
 // MergeGateways combines multiple gateways targeting the same workload into a single logical Gateway.
 // Note that today any Servers in the combined gateways listening on the same port must have the same protocol.
-// If servers with different protocols attempt to listen on the same port, one of the protocols will be chosen at random.
+// The gateways are merged from the oldest to the newest: if servers with different protocols attempt to listen on the
+// same port, the protocol of the oldest gateway is chosen, and the listener options of plain text HTTP servers sharing
+// a port always come from the server of the oldest gateway.
 func MergeGateways(gateways ...Config) *MergedGateway {
+	gateways = sortConfigByCreationTime(append([]Config(nil), gateways...))
 	names := make(map[string]bool, len(gateways))
 	gatewayPorts := make(map[uint32]bool)
 	servers := make(map[uint32][]*networking.Server)
@@ -79,6 +229,16 @@ func MergeGateways(gateways ...Config) *MergedGateway {
 	serversByRouteName := make(map[string][]*networking.Server)
 	routeNamesByServer := make(map[*networking.Server]string)
 	gatewayNameForServer := make(map[*networking.Server]string)
+	httpsRedirectExemptPaths := make(map[*networking.Server][]string)
+	accessLogForServer := make(map[*networking.Server]*GatewayAccessLog)
+	headersForServer := make(map[*networking.Server]*networking.Headers)
+	serverHeaderForServer := make(map[*networking.Server]*GatewayServerHeader)
+	hostValidationForServer := make(map[*networking.Server]*GatewayHostValidation)
+	http2OptionsForServer := make(map[*networking.Server]*HTTP2Options)
+	compressionForServer := make(map[*networking.Server]*CompressionPolicy)
+	responseCacheForServer := make(map[*networking.Server]*GatewayResponseCache)
+	maxRequestBodyBytesForServer := make(map[*networking.Server]uint32)
+	properCaseHeadersForServer := make(map[*networking.Server]bool)
 	tlsHostsByPort := map[uint32]map[string]struct{}{} // port -> host -> exists
 
 	log.Debugf("MergeGateways: merging %d gateways", len(gateways))
@@ -86,11 +246,52 @@ func MergeGateways(gateways ...Config) *MergedGateway {
 		gatewayName := fmt.Sprintf("%s/%s", gatewayConfig.Namespace, gatewayConfig.Name)
 		names[gatewayName] = true
 
+		exemptPaths := parseHTTPSRedirectExemptPaths(gatewayConfig.Annotations[HTTPSRedirectExemptPathsAnnotation])
+		accessLog := parseGatewayAccessLog(gatewayConfig.Annotations)
+		headers := parseGatewayHeaders(gatewayConfig.Annotations[HeadersAnnotation])
+		serverHeader := parseGatewayServerHeader(gatewayConfig.Annotations)
+		hostValidation := parseGatewayHostValidation(gatewayConfig.Annotations)
+		http2Options := ParseHTTP2Options(gatewayConfig.Annotations[HTTP2OptionsAnnotation])
+		compression := ParseCompressionPolicy(gatewayConfig.Annotations[CompressionAnnotation])
+		responseCache := parseGatewayResponseCache(gatewayConfig.Annotations[ResponseCacheAnnotation])
+		maxRequestBodyBytes := parseGatewayMaxRequestBodyBytes(gatewayConfig.Annotations[MaxRequestBodyBytesAnnotation])
+		properCaseHeaders := gatewayConfig.Annotations[HTTP1ProperCaseHeadersAnnotation] == "true"
+
 		gatewayCfg := gatewayConfig.Spec.(*networking.Gateway)
 		log.Debugf("MergeGateways: merging gateway %q into %v:\n%v", gatewayName, names, gatewayCfg)
 		for _, s := range gatewayCfg.Servers {
 			sanitizeServerHostNamespace(s, gatewayConfig.Namespace)
 			gatewayNameForServer[s] = gatewayName
+			if s.Tls != nil && s.Tls.HttpsRedirect && len(exemptPaths) > 0 {
+				httpsRedirectExemptPaths[s] = exemptPaths
+			}
+			if accessLog != nil {
+				accessLogForServer[s] = accessLog
+			}
+			if headers != nil {
+				headersForServer[s] = headers
+			}
+			if serverHeader != nil {
+				serverHeaderForServer[s] = serverHeader
+			}
+			if hostValidation != nil {
+				hostValidationForServer[s] = hostValidation
+			}
+			if http2Options != nil {
+				http2OptionsForServer[s] = http2Options
+			}
+			if compression != nil {
+				compressionForServer[s] = compression
+			}
+			if responseCache != nil {
+				responseCacheForServer[s] = responseCache
+			}
+			if maxRequestBodyBytes > 0 {
+				maxRequestBodyBytesForServer[s] = maxRequestBodyBytes
+			}
+			if properCaseHeaders {
+				properCaseHeadersForServer[s] = true
+			}
 			log.Debugf("MergeGateways: gateway %q processing server %v", gatewayName, s.Hosts)
 			p := protocol.Parse(s.Port.Protocol)
 
@@ -195,11 +396,128 @@ func MergeGateways(gateways ...Config) *MergedGateway {
 	}
 
 	return &MergedGateway{
-		Servers:              servers,
-		GatewayNameForServer: gatewayNameForServer,
-		ServersByRouteName:   serversByRouteName,
-		RouteNamesByServer:   routeNamesByServer,
+		Servers:                      servers,
+		GatewayNameForServer:         gatewayNameForServer,
+		ServersByRouteName:           serversByRouteName,
+		RouteNamesByServer:           routeNamesByServer,
+		HTTPSRedirectExemptPaths:     httpsRedirectExemptPaths,
+		AccessLogForServer:           accessLogForServer,
+		HeadersForServer:             headersForServer,
+		ServerHeaderForServer:        serverHeaderForServer,
+		HostValidationForServer:      hostValidationForServer,
+		HTTP2OptionsForServer:        http2OptionsForServer,
+		CompressionForServer:         compressionForServer,
+		ResponseCacheForServer:       responseCacheForServer,
+		MaxRequestBodyBytesForServer: maxRequestBodyBytesForServer,
+		ProperCaseHeadersForServer:   properCaseHeadersForServer,
+	}
+}
+
+// parseGatewayAccessLog returns the access log settings set by the annotations of a gateway, or nil
+// if the gateway uses the mesh settings.
+func parseGatewayAccessLog(annotations map[string]string) *GatewayAccessLog {
+	accessLog := &GatewayAccessLog{
+		File:     annotations[AccessLogFileAnnotation],
+		Format:   annotations[AccessLogFormatAnnotation],
+		Encoding: strings.ToUpper(annotations[AccessLogEncodingAnnotation]),
+	}
+	if value := annotations[AccessLogMinStatusCodeAnnotation]; value != "" {
+		code, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			log.Warnf("ignoring invalid %s annotation %q: %v", AccessLogMinStatusCodeAnnotation, value, err)
+		} else {
+			accessLog.MinStatusCode = uint32(code)
+		}
+	}
+	if *accessLog == (GatewayAccessLog{}) {
+		return nil
+	}
+	return accessLog
+}
+
+// parseGatewayServerHeader returns the server and via headers settings set by the annotations of a
+// gateway, or nil if the gateway uses the mesh settings.
+func parseGatewayServerHeader(annotations map[string]string) *GatewayServerHeader {
+	serverHeader := &GatewayServerHeader{
+		Transformation: strings.ToUpper(annotations[ServerHeaderAnnotation]),
+		Via:            annotations[ViaAnnotation],
+	}
+	if *serverHeader == (GatewayServerHeader{}) {
+		return nil
+	}
+	return serverHeader
+}
+
+// parseGatewayHostValidation returns the Host header validation set by the annotations of a gateway,
+// or nil if the gateway has none.
+func parseGatewayHostValidation(annotations map[string]string) *GatewayHostValidation {
+	validation := &GatewayHostValidation{
+		RejectUnknownHosts: annotations[RejectUnknownHostsAnnotation] == "true",
+		IgnorePort:         annotations[IgnoreHostPortAnnotation] == "true",
+	}
+	if *validation == (GatewayHostValidation{}) {
+		return nil
+	}
+	return validation
+}
+
+// parseGatewayHeaders parses the value of the HeadersAnnotation, returning nil if it is not set or invalid.
+func parseGatewayHeaders(value string) *networking.Headers {
+	if value == "" {
+		return nil
+	}
+	headers := &networking.Headers{}
+	if err := gogoprotomarshal.ApplyJSON(value, headers); err != nil {
+		log.Warnf("ignoring invalid %s annotation %q: %v", HeadersAnnotation, value, err)
+		return nil
+	}
+	return headers
+}
+
+// parseGatewayResponseCache parses the value of the ResponseCacheAnnotation, returning nil if it is not
+// set or invalid.
+func parseGatewayResponseCache(value string) *GatewayResponseCache {
+	if value == "" {
+		return nil
+	}
+	cache := &GatewayResponseCache{}
+	if err := json.Unmarshal([]byte(value), cache); err != nil {
+		log.Warnf("ignoring invalid %s annotation %q: %v", ResponseCacheAnnotation, value, err)
+		return nil
+	}
+	if len(cache.QueryParametersIncluded) > 0 && len(cache.QueryParametersExcluded) > 0 {
+		log.Warnf("ignoring invalid %s annotation %q: queryParametersIncluded and queryParametersExcluded "+
+			"are exclusive", ResponseCacheAnnotation, value)
+		return nil
+	}
+	return cache
+}
+
+// parseGatewayMaxRequestBodyBytes parses the value of the MaxRequestBodyBytesAnnotation, returning 0 if
+// it is not set or invalid.
+func parseGatewayMaxRequestBodyBytes(value string) uint32 {
+	if value == "" {
+		return 0
+	}
+	size, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation %q: %v", MaxRequestBodyBytesAnnotation, value, err)
+		return 0
+	}
+	return uint32(size)
+}
+
+// parseHTTPSRedirectExemptPaths parses the value of the HTTPSRedirectExemptPathsAnnotation, ignoring
+// the paths which are not absolute.
+func parseHTTPSRedirectExemptPaths(value string) []string {
+	var paths []string
+	for _, p := range strings.Split(value, ",") {
+		p = strings.TrimSpace(p)
+		if strings.HasPrefix(p, "/") {
+			paths = append(paths, p)
+		}
 	}
+	return paths
 }
 
 // checkDuplicates returns all of the hosts provided that are already known
@@ -16,7 +16,11 @@ package model
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
 
 	networking "istio.io/api/networking/v1alpha3"
 )
@@ -161,3 +165,234 @@ func TestParseGatewayRDSRouteName(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeGatewaysOldestFirst(t *testing.T) {
+	now := time.Now()
+	oldest := makeConfig("foo2", "not-default", "foo.bar.com", "http", "http", 80, "ingressgateway")
+	oldest.CreationTimestamp = now.Add(-time.Hour)
+	oldest.Annotations = map[string]string{AccessLogEncodingAnnotation: "json"}
+	newest := makeConfig("foo1", "not-default", "bar.foo.com", "http", "http", 80, "ingressgateway")
+	newest.CreationTimestamp = now
+	sameAge := makeConfig("foo3", "not-default", "foo.foo.com", "http", "http", 80, "ingressgateway")
+	sameAge.CreationTimestamp = now
+
+	for _, gateways := range [][]Config{{oldest, newest, sameAge}, {sameAge, newest, oldest}} {
+		mgw := MergeGateways(gateways...)
+		var got []string
+		for _, s := range mgw.ServersByRouteName["http.80"] {
+			got = append(got, mgw.GatewayNameForServer[s])
+		}
+		if fmt.Sprint(got) != "[not-default/foo2 not-default/foo1 not-default/foo3]" {
+			t.Errorf("got servers of gateways %v, want the oldest first", got)
+		}
+	}
+}
+
+func TestMergeGatewaysHTTPSRedirectExemptPaths(t *testing.T) {
+	redirected := makeConfig("foo1", "not-default", "foo.bar.com", "http", "http", 80, "ingressgateway")
+	redirected.Annotations = map[string]string{
+		HTTPSRedirectExemptPathsAnnotation: "/.well-known/acme-challenge/, healthz,/healthz",
+	}
+	redirected.Spec.(*networking.Gateway).Servers[0].Tls = &networking.Server_TLSOptions{HttpsRedirect: true}
+	notRedirected := makeConfig("foo2", "not-default", "bar.foo.com", "http", "http", 80, "ingressgateway")
+	notRedirected.Annotations = redirected.Annotations
+
+	mgw := MergeGateways(redirected, notRedirected)
+	got := mgw.HTTPSRedirectExemptPaths[redirected.Spec.(*networking.Gateway).Servers[0]]
+	if fmt.Sprint(got) != "[/.well-known/acme-challenge/ /healthz]" {
+		t.Errorf("got exempt paths %v", got)
+	}
+	if got := mgw.HTTPSRedirectExemptPaths[notRedirected.Spec.(*networking.Gateway).Servers[0]]; got != nil {
+		t.Errorf("expected no exempt paths for a server without HTTPS redirect, got %v", got)
+	}
+}
+
+func TestMergeGatewaysAccessLog(t *testing.T) {
+	overridden := makeConfig("foo1", "not-default", "foo.bar.com", "http", "http", 80, "ingressgateway")
+	overridden.Annotations = map[string]string{
+		AccessLogEncodingAnnotation:      "json",
+		AccessLogMinStatusCodeAnnotation: "500",
+	}
+	inherited := makeConfig("foo2", "not-default", "bar.foo.com", "http", "http", 80, "ingressgateway")
+
+	mgw := MergeGateways(overridden, inherited)
+	got := mgw.AccessLogForServer[overridden.Spec.(*networking.Gateway).Servers[0]]
+	if got == nil || *got != (GatewayAccessLog{Encoding: "JSON", MinStatusCode: 500}) {
+		t.Errorf("got access log %+v", got)
+	}
+	if got := mgw.AccessLogForServer[inherited.Spec.(*networking.Gateway).Servers[0]]; got != nil {
+		t.Errorf("expected the mesh access log for a gateway without annotations, got %+v", got)
+	}
+}
+
+func TestMergeGatewaysHeaders(t *testing.T) {
+	withHeaders := makeConfig("foo1", "not-default", "foo.bar.com", "http", "http", 80, "ingressgateway")
+	withHeaders.Annotations = map[string]string{
+		HeadersAnnotation: `{"request": {"remove": ["x-internal"]}, "response": {"set": {"Strict-Transport-Security": "max-age=31536000"}}}`,
+	}
+	invalid := makeConfig("foo2", "not-default", "bar.foo.com", "http", "http", 80, "ingressgateway")
+	invalid.Annotations = map[string]string{HeadersAnnotation: `{"request": `}
+
+	mgw := MergeGateways(withHeaders, invalid)
+	got := mgw.HeadersForServer[withHeaders.Spec.(*networking.Gateway).Servers[0]]
+	want := &networking.Headers{
+		Request:  &networking.Headers_HeaderOperations{Remove: []string{"x-internal"}},
+		Response: &networking.Headers_HeaderOperations{Set: map[string]string{"Strict-Transport-Security": "max-age=31536000"}},
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("got headers %v, want %v", got, want)
+	}
+	if got := mgw.HeadersForServer[invalid.Spec.(*networking.Gateway).Servers[0]]; got != nil {
+		t.Errorf("expected invalid headers to be ignored, got %v", got)
+	}
+}
+
+func TestMergeGatewaysServerHeader(t *testing.T) {
+	overridden := makeConfig("foo1", "not-default", "foo.bar.com", "http", "http", 80, "ingressgateway")
+	overridden.Annotations = map[string]string{
+		ServerHeaderAnnotation: "pass_through",
+		ViaAnnotation:          "1.1 edge-gateway",
+	}
+	inherited := makeConfig("foo2", "not-default", "bar.foo.com", "http", "http", 80, "ingressgateway")
+
+	mgw := MergeGateways(overridden, inherited)
+	got := mgw.ServerHeaderForServer[overridden.Spec.(*networking.Gateway).Servers[0]]
+	if got == nil || *got != (GatewayServerHeader{Transformation: "PASS_THROUGH", Via: "1.1 edge-gateway"}) {
+		t.Errorf("got server header %+v", got)
+	}
+	if got := mgw.ServerHeaderForServer[inherited.Spec.(*networking.Gateway).Servers[0]]; got != nil {
+		t.Errorf("expected the mesh server header for a gateway without annotations, got %+v", got)
+	}
+}
+
+func TestMergeGatewaysHostValidation(t *testing.T) {
+	strict := makeConfig("foo1", "not-default", "foo.bar.com", "http", "http", 80, "ingressgateway")
+	strict.Annotations = map[string]string{
+		RejectUnknownHostsAnnotation: "true",
+		IgnoreHostPortAnnotation:     "true",
+	}
+	lax := makeConfig("foo2", "not-default", "bar.foo.com", "http", "http", 80, "ingressgateway")
+	lax.Annotations = map[string]string{RejectUnknownHostsAnnotation: "yes"}
+
+	mgw := MergeGateways(strict, lax)
+	got := mgw.HostValidationForServer[strict.Spec.(*networking.Gateway).Servers[0]]
+	if got == nil || *got != (GatewayHostValidation{RejectUnknownHosts: true, IgnorePort: true}) {
+		t.Errorf("got host validation %+v", got)
+	}
+	if got := mgw.HostValidationForServer[lax.Spec.(*networking.Gateway).Servers[0]]; got != nil {
+		t.Errorf("expected no host validation for a gateway without valid annotations, got %+v", got)
+	}
+}
+
+func TestMergeGatewaysHTTP2Options(t *testing.T) {
+	tuned := makeConfig("foo1", "not-default", "foo.bar.com", "grpc", "grpc", 80, "ingressgateway")
+	tuned.Annotations = map[string]string{
+		HTTP2OptionsAnnotation: `{"maxConcurrentStreams": 1000, "initialStreamWindowSize": 1048576, "allowConnect": true}`,
+	}
+	invalid := makeConfig("foo2", "not-default", "bar.foo.com", "grpc", "grpc", 81, "ingressgateway")
+	invalid.Annotations = map[string]string{HTTP2OptionsAnnotation: `{"initialConnectionWindowSize": 1024}`}
+
+	mgw := MergeGateways(tuned, invalid)
+	got := mgw.HTTP2OptionsForServer[tuned.Spec.(*networking.Gateway).Servers[0]]
+	want := HTTP2Options{MaxConcurrentStreams: 1000, InitialStreamWindowSize: 1048576, AllowConnect: true}
+	if got == nil || *got != want {
+		t.Errorf("got HTTP/2 options %+v, want %+v", got, want)
+	}
+	if got := mgw.HTTP2OptionsForServer[invalid.Spec.(*networking.Gateway).Servers[0]]; got != nil {
+		t.Errorf("expected no HTTP/2 options for a gateway with an out of range window size, got %+v", got)
+	}
+}
+
+func TestMergeGatewaysCompression(t *testing.T) {
+	compressed := makeConfig("foo1", "not-default", "foo.bar.com", "http", "http", 80, "ingressgateway")
+	compressed.Annotations = map[string]string{
+		CompressionAnnotation: `{"contentTypes": ["application/json"], "minLength": 1024, "level": "best"}`,
+	}
+	brotli := makeConfig("foo2", "not-default", "bar.foo.com", "http", "http", 81, "ingressgateway")
+	brotli.Annotations = map[string]string{CompressionAnnotation: `{"algorithm": "brotli"}`}
+
+	mgw := MergeGateways(compressed, brotli)
+	got := mgw.CompressionForServer[compressed.Spec.(*networking.Gateway).Servers[0]]
+	want := &CompressionPolicy{ContentTypes: []string{"application/json"}, MinLength: 1024, Level: "BEST"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got compression %+v, want %+v", got, want)
+	}
+	if got := mgw.CompressionForServer[brotli.Spec.(*networking.Gateway).Servers[0]]; got != nil {
+		t.Errorf("expected no compression for a gateway with an unsupported algorithm, got %+v", got)
+	}
+}
+
+func TestParseCompressionPolicy(t *testing.T) {
+	cases := []struct {
+		value string
+		valid bool
+	}{
+		{value: `{}`, valid: true},
+		{value: `{"algorithm": "GZIP", "level": "speed"}`, valid: true},
+		{value: `{"algorithm": "deflate"}`},
+		{value: `{"level": "fastest"}`},
+		{value: `{"minLength": 10}`},
+		{value: `{"minLength": "large"}`},
+	}
+	for _, c := range cases {
+		if got := ParseCompressionPolicy(c.value); (got != nil) != c.valid {
+			t.Errorf("%s: got %+v, want valid %v", c.value, got, c.valid)
+		}
+	}
+	if got := ParseCompressionPolicy(""); got != nil {
+		t.Errorf("expected no compression without annotation, got %+v", got)
+	}
+}
+
+func TestMergeGatewaysResponseCache(t *testing.T) {
+	cached := makeConfig("foo1", "not-default", "foo.bar.com", "http", "http", 80, "ingressgateway")
+	cached.Annotations = map[string]string{
+		ResponseCacheAnnotation: `{"excludeScheme": true, "queryParametersIncluded": ["v"]}`,
+	}
+	invalid := makeConfig("foo2", "not-default", "bar.foo.com", "http", "http", 81, "ingressgateway")
+	invalid.Annotations = map[string]string{
+		ResponseCacheAnnotation: `{"queryParametersIncluded": ["v"], "queryParametersExcluded": ["utm"]}`,
+	}
+	uncached := makeConfig("foo3", "not-default", "foo.foo.com", "http", "http", 82, "ingressgateway")
+
+	mgw := MergeGateways(cached, invalid, uncached)
+	got := mgw.ResponseCacheForServer[cached.Spec.(*networking.Gateway).Servers[0]]
+	want := &GatewayResponseCache{ExcludeScheme: true, QueryParametersIncluded: []string{"v"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got response cache %+v, want %+v", got, want)
+	}
+	for _, c := range []Config{invalid, uncached} {
+		if got := mgw.ResponseCacheForServer[c.Spec.(*networking.Gateway).Servers[0]]; got != nil {
+			t.Errorf("%s: expected no response cache, got %+v", c.Name, got)
+		}
+	}
+}
+
+func TestMergeGatewaysProperCaseHeaders(t *testing.T) {
+	capitalized := makeConfig("foo1", "not-default", "foo.bar.com", "http", "http", 80, "ingressgateway")
+	capitalized.Annotations = map[string]string{HTTP1ProperCaseHeadersAnnotation: "true"}
+	lowercase := makeConfig("foo2", "not-default", "bar.foo.com", "http", "http", 81, "ingressgateway")
+
+	mgw := MergeGateways(capitalized, lowercase)
+	if !mgw.ProperCaseHeadersForServer[capitalized.Spec.(*networking.Gateway).Servers[0]] {
+		t.Error("expected the annotated gateway server to capitalize the header names")
+	}
+	if mgw.ProperCaseHeadersForServer[lowercase.Spec.(*networking.Gateway).Servers[0]] {
+		t.Error("expected the other gateway server to keep the header names lowercase")
+	}
+}
+
+func TestMergeGatewaysMaxRequestBodyBytes(t *testing.T) {
+	limited := makeConfig("foo1", "not-default", "foo.bar.com", "http", "http", 80, "ingressgateway")
+	limited.Annotations = map[string]string{MaxRequestBodyBytesAnnotation: "10485760"}
+	invalid := makeConfig("foo2", "not-default", "bar.foo.com", "http", "http", 81, "ingressgateway")
+	invalid.Annotations = map[string]string{MaxRequestBodyBytesAnnotation: "10Mi"}
+
+	mgw := MergeGateways(limited, invalid)
+	if got := mgw.MaxRequestBodyBytesForServer[limited.Spec.(*networking.Gateway).Servers[0]]; got != 10485760 {
+		t.Errorf("got max request body bytes %d, want 10485760", got)
+	}
+	if got, f := mgw.MaxRequestBodyBytesForServer[invalid.Spec.(*networking.Gateway).Servers[0]]; f {
+		t.Errorf("expected no max request body bytes for an invalid annotation, got %d", got)
+	}
+}
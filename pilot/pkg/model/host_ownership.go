@@ -0,0 +1,90 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"istio.io/istio/pkg/config/host"
+)
+
+// ParseConfigWriters parses the value of the ConfigWritersAnnotation of a service, returning nil
+// if it is not set.
+func ParseConfigWriters(value string) map[string]bool {
+	if value == "" {
+		return nil
+	}
+	out := map[string]bool{}
+	for _, ns := range strings.Split(value, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			out[ns] = true
+		}
+	}
+	return out
+}
+
+// canWriteHost returns whether the configs of the namespace may define the routes and policies of
+// the host: every service of the host, or of the hosts matched by a wildcard host, must be in the
+// namespace or list it as a config writer. The hosts without services have no owner.
+func (ps *PushContext) canWriteHost(namespace string, h host.Name) bool {
+	if len(h) > 0 && h[0] == '*' {
+		for hostname, services := range ps.ServiceByHostnameAndNamespace {
+			if hostname.SubsetOf(h) && !canWriteServices(namespace, services) {
+				return false
+			}
+		}
+		return true
+	}
+	return canWriteServices(namespace, ps.ServiceByHostnameAndNamespace[h])
+}
+
+// canWriteServices returns whether the namespace owns one of the services of a hostname, indexed by
+// namespace, or is listed as a config writer by one of them.
+func canWriteServices(namespace string, services map[string]*Service) bool {
+	if len(services) == 0 {
+		return true
+	}
+	if _, f := services[namespace]; f {
+		return true
+	}
+	for _, svc := range services {
+		if svc.Attributes.ConfigWriters[namespace] || svc.Attributes.ConfigWriters["*"] {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectUnownedConfigs returns the configs whose hosts may be written by their namespace, recording
+// the others in the push status.
+func (ps *PushContext) rejectUnownedConfigs(configs []Config, hosts func(Config) []host.Name) []Config {
+	out := make([]Config, 0, len(configs))
+	for _, cfg := range configs {
+		owned := true
+		for _, h := range hosts(cfg) {
+			if !ps.canWriteHost(cfg.Namespace, h) {
+				ps.Add(RejectedUnownedHosts, cfg.Namespace+"/"+cfg.Name, nil,
+					fmt.Sprintf("%s %s/%s rejected: host %s is owned by another namespace", cfg.Type, cfg.Namespace, cfg.Name, h))
+				owned = false
+				break
+			}
+		}
+		if owned {
+			out = append(out, cfg)
+		}
+	}
+	return out
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/schemas"
+)
+
+func TestParseConfigWriters(t *testing.T) {
+	if got := ParseConfigWriters(""); got != nil {
+		t.Errorf("ParseConfigWriters(\"\") = %v, want nil", got)
+	}
+	want := map[string]bool{"frontend": true, "gateways": true}
+	if got := ParseConfigWriters("frontend, gateways,"); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseConfigWriters() = %v, want %v", got, want)
+	}
+}
+
+func TestRejectUnownedConfigs(t *testing.T) {
+	service := func(hostname host.Name, namespace string, writers ...string) *Service {
+		svc := &Service{Hostname: hostname, Attributes: ServiceAttributes{Namespace: namespace}}
+		if len(writers) > 0 {
+			svc.Attributes.ConfigWriters = map[string]bool{}
+			for _, w := range writers {
+				svc.Attributes.ConfigWriters[w] = true
+			}
+		}
+		return svc
+	}
+	ps := NewPushContext()
+	for _, svc := range []*Service{
+		service("reviews.bookinfo.svc.cluster.local", "bookinfo"),
+		service("ratings.bookinfo.svc.cluster.local", "bookinfo", "frontend"),
+		service("api.example.com", "external", "*"),
+	} {
+		ps.ServiceByHostnameAndNamespace[svc.Hostname] = map[string]*Service{svc.Attributes.Namespace: svc}
+	}
+
+	virtualService := func(name, namespace string, hosts ...string) Config {
+		return Config{
+			ConfigMeta: ConfigMeta{Type: schemas.VirtualService.Type, Name: name, Namespace: namespace},
+			Spec:       &networking.VirtualService{Hosts: hosts},
+		}
+	}
+	configs := []Config{
+		virtualService("owner", "bookinfo", "reviews.bookinfo.svc.cluster.local"),
+		virtualService("hijack", "frontend", "reviews.bookinfo.svc.cluster.local"),
+		virtualService("writer", "frontend", "ratings.bookinfo.svc.cluster.local", "api.example.com"),
+		virtualService("wildcard", "frontend", "*.bookinfo.svc.cluster.local"),
+		virtualService("unowned", "frontend", "www.example.com"),
+	}
+
+	out := ps.rejectUnownedConfigs(configs, func(cfg Config) []host.Name {
+		var hosts []host.Name
+		for _, h := range cfg.Spec.(*networking.VirtualService).Hosts {
+			hosts = append(hosts, host.Name(h))
+		}
+		return hosts
+	})
+	var got []string
+	for _, cfg := range out {
+		got = append(got, cfg.Name)
+	}
+	if want := []string{"owner", "writer", "unowned"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got configs %v, want %v", got, want)
+	}
+	if rejected := ps.ProxyStatus[RejectedUnownedHosts.Name()]; len(rejected) != 2 {
+		t.Errorf("got rejected configs %v, want 2", rejected)
+	}
+}
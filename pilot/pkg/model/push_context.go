@@ -20,6 +20,8 @@ import (
 	"sync"
 	"time"
 
+	ot "github.com/opentracing/opentracing-go"
+
 	networking "istio.io/api/networking/v1alpha3"
 
 	"istio.io/istio/pilot/pkg/features"
@@ -53,7 +55,7 @@ type PushContext struct {
 	defaultVirtualServiceExportTo  map[visibility.Instance]bool
 	defaultDestinationRuleExportTo map[visibility.Instance]bool
 
-	// privateServices are reachable within the same namespace.
+	// privateServices are reachable within the namespaces they are exported to, by "." or by name.
 	privateServicesByNamespace map[string][]*Service
 	// publicServices are services reachable within the mesh.
 	publicServices []*Service
@@ -70,6 +72,12 @@ type PushContext struct {
 	namespaceLocalDestRules    map[string]*processedDestRules
 	namespaceExportedDestRules map[string]*processedDestRules
 	allExportedDestRules       *processedDestRules
+	// namespaceImportedDestRules holds, per namespace, the dest rules of other namespaces exported to
+	// it by name
+	namespaceImportedDestRules map[string]*processedDestRules
+	// defaultDestRules holds, per namespace, the dest rule of host "*" whose traffic policy is the
+	// baseline of the dest rules of the proxies of the namespace, or of all proxies for the root namespace
+	defaultDestRules map[string]*Config
 
 	// sidecars for each namespace
 	sidecarsByNamespace map[string][]*SidecarScope
@@ -169,6 +177,10 @@ type PushRequest struct {
 	// Start represents the time a push was started. This represents the time of adding to the PushQueue.
 	// Note that this does not include time spent debouncing.
 	Start time.Time
+
+	// Span traces the request from its first config event until it is queued for the proxies,
+	// which trace their own pushes as following from it. It is nil if the request is not traced.
+	Span ot.Span
 }
 
 // Merge two update requests together
@@ -184,12 +196,18 @@ func (first *PushRequest) Merge(other *PushRequest) *PushRequest {
 		// Keep the first (older) start time
 		Start: first.Start,
 
+		// Keep the span of the first request, which covers the other one
+		Span: first.Span,
+
 		// If either is full we need a full push
 		Full: first.Full || other.Full,
 
 		// The other push context is presumed to be later and more up to date
 		Push: other.Push,
 	}
+	if merged.Span == nil {
+		merged.Span = other.Span
+	}
 
 	// Only merge EdsUpdates when incremental eds push needed.
 	if !merged.Full {
@@ -349,6 +367,13 @@ var (
 		"Duplicate subsets across destination rules for same host",
 	)
 
+	// RejectedUnownedHosts tracks the virtual services and destination rules rejected because their
+	// hosts are owned by other namespaces, when host ownership is enforced.
+	RejectedUnownedHosts = monitoring.NewGauge(
+		"pilot_config_unowned_host",
+		"Virtual services and destination rules rejected for hosts owned by other namespaces.",
+	)
+
 	// totalVirtualServices tracks the total number of virtual service
 	totalVirtualServices = monitoring.NewGauge(
 		"pilot_virt_services",
@@ -375,6 +400,7 @@ var (
 		ProxyStatusClusterNoInstances,
 		DuplicatedDomains,
 		DuplicatedSubsets,
+		RejectedUnownedHosts,
 	}
 )
 
@@ -451,8 +477,15 @@ func (ps *PushContext) Services(proxy *Proxy) []*Service {
 
 	// First add private services
 	if proxy == nil {
+		// Services exported to several namespaces are listed in each of them.
+		added := make(map[*Service]bool)
 		for _, privateServices := range ps.privateServicesByNamespace {
-			out = append(out, privateServices...)
+			for _, s := range privateServices {
+				if !added[s] {
+					added[s] = true
+					out = append(out, s)
+				}
+			}
 		}
 	} else {
 		out = append(out, ps.privateServicesByNamespace[proxy.ConfigNamespace]...)
@@ -474,9 +507,17 @@ func (ps *PushContext) VirtualServices(proxy *Proxy, gateways map[string]bool) [
 	// filter out virtual services not reachable
 	// First private virtual service
 	if proxy == nil {
+		// Virtual services exported to several namespaces are listed in each of them.
+		added := make(map[string]bool)
 		for _, virtualSvcs := range ps.privateVirtualServicesByNamespace {
-			configs = append(configs, virtualSvcs...)
+			for _, cfg := range virtualSvcs {
+				if key := cfg.Namespace + "/" + cfg.Name; !added[key] {
+					added[key] = true
+					configs = append(configs, cfg)
+				}
+			}
 		}
+		sortConfigByCreationTime(configs)
 	} else {
 		configs = append(configs, ps.privateVirtualServicesByNamespace[proxy.ConfigNamespace]...)
 	}
@@ -493,7 +534,7 @@ func (ps *PushContext) VirtualServices(proxy *Proxy, gateways map[string]bool) [
 		} else {
 			for _, g := range rule.Gateways {
 				// note: Gateway names do _not_ use wildcard matching, so we do not use Name.Matches here
-				if gateways[resolveGatewayName(g, cfg.ConfigMeta)] {
+				if gateways[ResolveGatewayName(g, cfg.ConfigMeta)] {
 					out = append(out, cfg)
 					break
 				} else if g == constants.IstioMeshGateway && gateways[g] {
@@ -504,6 +545,8 @@ func (ps *PushContext) VirtualServices(proxy *Proxy, gateways map[string]bool) [
 			}
 		}
 	}
+	// Virtual services of the same hosts are merged in this order, so the first takes precedence.
+	sortVirtualServicesByPriority(out)
 
 	return out
 }
@@ -568,13 +611,18 @@ func (ps *PushContext) GetAllSidecarScopes() map[string][]*SidecarScope {
 }
 
 // DestinationRule returns a destination rule for a service name in a given domain.
+// The first destination rule of the service applying to the proxy is looked up, by descending precedence,
+// in the proxy's namespace, the rules exported to it, the service's namespace and the root namespace.
+// With PILOT_ENABLE_DESTINATION_RULE_INHERITANCE, all these rules are merged. The result is then
+// merged with the default rules of host "*".
 func (ps *PushContext) DestinationRule(proxy *Proxy, service *Service) *Config {
 	// FIXME: this code should be removed once the EDS issue is fixed
 	if proxy == nil {
+		var configs []*Config
 		if hostname, ok := MostSpecificHostMatch(service.Hostname, ps.allExportedDestRules.hosts); ok {
-			return ps.allExportedDestRules.destRule[hostname].config
+			configs = append(configs, ps.allExportedDestRules.destRule[hostname].config)
 		}
-		return nil
+		return mergeDestinationRules(append(configs, ps.defaultDestinationRules("")...))
 	}
 
 	// If proxy has a sidecar scope that is user supplied, then get the destination rules from the sidecar scope
@@ -585,6 +633,31 @@ func (ps *PushContext) DestinationRule(proxy *Proxy, service *Service) *Config {
 		return proxy.SidecarScope.DestinationRule(service.Hostname)
 	}
 
+	configs := ps.destinationRules(proxy, service)
+	return mergeDestinationRules(append(configs, ps.defaultDestinationRules(proxy.ConfigNamespace)...))
+}
+
+// destinationRules returns the destination rules of the service applying to the proxy, by descending
+// precedence, without the default rules. Unless destination rule inheritance is enabled, only the
+// first rule is returned.
+func (ps *PushContext) destinationRules(proxy *Proxy, service *Service) []*Config {
+	var out []*Config
+	seen := map[string]bool{}
+	// add adds the rule of the service, if any, and returns true once the lookup is done.
+	add := func(rules *processedDestRules) bool {
+		if rules == nil {
+			return false
+		}
+		if hostname, ok := MostSpecificHostMatch(service.Hostname, rules.hosts); ok {
+			cfg := rules.destRule[hostname].config
+			if key := cfg.Namespace + "/" + cfg.Name; !seen[key] {
+				seen[key] = true
+				out = append(out, cfg)
+			}
+		}
+		return len(out) > 0 && !features.EnableDestinationRuleInheritance
+	}
+
 	// If the proxy config namespace is same as the root config namespace
 	// look for dest rules in the service's namespace first. This hack is needed
 	// because sometimes, istio-system tends to become the root config namespace.
@@ -595,14 +668,16 @@ func (ps *PushContext) DestinationRule(proxy *Proxy, service *Service) *Config {
 	// rules anyway, later in the code
 	if proxy.ConfigNamespace != ps.Env.Mesh.RootNamespace {
 		// search through the DestinationRules in proxy's namespace first
-		if ps.namespaceLocalDestRules[proxy.ConfigNamespace] != nil {
-			if hostname, ok := MostSpecificHostMatch(service.Hostname,
-				ps.namespaceLocalDestRules[proxy.ConfigNamespace].hosts); ok {
-				return ps.namespaceLocalDestRules[proxy.ConfigNamespace].destRule[hostname].config
-			}
+		if add(ps.namespaceLocalDestRules[proxy.ConfigNamespace]) {
+			return out
 		}
 	}
 
+	// then through the DestinationRules of other namespaces exported to the proxy's namespace by name
+	if add(ps.namespaceImportedDestRules[proxy.ConfigNamespace]) {
+		return out
+	}
+
 	svcNs := service.Attributes.Namespace
 
 	// This can happen when finding the subset labels for a proxy in root namespace.
@@ -617,26 +692,16 @@ func (ps *PushContext) DestinationRule(proxy *Proxy, service *Service) *Config {
 		}
 	}
 
-	// if no private/public rule matched in the calling proxy's namespace,
-	// check the target service's namespace for public rules
-	if svcNs != "" && ps.namespaceExportedDestRules[svcNs] != nil {
-		if hostname, ok := MostSpecificHostMatch(service.Hostname,
-			ps.namespaceExportedDestRules[svcNs].hosts); ok {
-			return ps.namespaceExportedDestRules[svcNs].destRule[hostname].config
-		}
+	// then the public rules of the target service's namespace
+	if svcNs != "" && add(ps.namespaceExportedDestRules[svcNs]) {
+		return out
 	}
 
-	// if no public/private rule in calling proxy's namespace matched, and no public rule in the
-	// target service's namespace matched, search for any public destination rule in the config root namespace
+	// and last the public destination rules of the config root namespace
 	// NOTE: This does mean that we are effectively ignoring private dest rules in the config root namespace
-	if ps.namespaceExportedDestRules[ps.Env.Mesh.RootNamespace] != nil {
-		if hostname, ok := MostSpecificHostMatch(service.Hostname,
-			ps.namespaceExportedDestRules[ps.Env.Mesh.RootNamespace].hosts); ok {
-			return ps.namespaceExportedDestRules[ps.Env.Mesh.RootNamespace].destRule[hostname].config
-		}
-	}
+	add(ps.namespaceExportedDestRules[ps.Env.Mesh.RootNamespace])
 
-	return nil
+	return out
 }
 
 // SubsetToLabels returns the labels associated with a subset of a given service.
@@ -712,6 +777,13 @@ func (ps *PushContext) InitContext(env *Environment) error {
 	return nil
 }
 
+// InitDone returns true once InitContext has completed successfully.
+func (ps *PushContext) InitDone() bool {
+	ps.Mutex.Lock()
+	defer ps.Mutex.Unlock()
+	return ps.initDone
+}
+
 // Caches list of services in the registry, and creates a map
 // of hostname to service
 func (ps *PushContext) initServiceRegistry(env *Environment) error {
@@ -722,18 +794,15 @@ func (ps *PushContext) initServiceRegistry(env *Environment) error {
 	// Sort the services in order of creation.
 	allServices := sortServicesByCreationTime(services)
 	for _, s := range allServices {
-		ns := s.Attributes.Namespace
-		if len(s.Attributes.ExportTo) == 0 {
-			if ps.defaultServiceExportTo[visibility.Private] {
-				ps.privateServicesByNamespace[ns] = append(ps.privateServicesByNamespace[ns], s)
-			} else if ps.defaultServiceExportTo[visibility.Public] {
-				ps.publicServices = append(ps.publicServices, s)
-			}
+		exportTo := s.Attributes.ExportTo
+		if len(exportTo) == 0 {
+			exportTo = ps.defaultServiceExportTo
+		}
+		if public, namespaces := exportedNamespaces(exportTo, s.Attributes.Namespace); public {
+			ps.publicServices = append(ps.publicServices, s)
 		} else {
-			if s.Attributes.ExportTo[visibility.Private] {
+			for _, ns := range namespaces {
 				ps.privateServicesByNamespace[ns] = append(ps.privateServicesByNamespace[ns], s)
-			} else {
-				ps.publicServices = append(ps.publicServices, s)
 			}
 		}
 		if _, f := ps.ServiceByHostnameAndNamespace[s.Hostname]; !f {
@@ -747,6 +816,37 @@ func (ps *PushContext) initServiceRegistry(env *Environment) error {
 	return nil
 }
 
+// exportToMap converts the exportTo field of a config to a set.
+func exportToMap(exportTo []string) map[visibility.Instance]bool {
+	out := make(map[visibility.Instance]bool, len(exportTo))
+	for _, e := range exportTo {
+		out[visibility.Instance(e)] = true
+	}
+	return out
+}
+
+// exportedNamespaces returns whether a config of the namespace is exported to all the namespaces and, if
+// it is not, the sorted namespaces it is exported to. "." exports to the namespace of the config, "~" to none.
+func exportedNamespaces(exportTo map[visibility.Instance]bool, configNamespace string) (bool, []string) {
+	if exportTo[visibility.Public] {
+		return true, nil
+	}
+	namespaces := make([]string, 0, len(exportTo))
+	for e := range exportTo {
+		switch e {
+		case visibility.None:
+		case visibility.Private:
+			if !exportTo[visibility.Instance(configNamespace)] {
+				namespaces = append(namespaces, configNamespace)
+			}
+		default:
+			namespaces = append(namespaces, string(e))
+		}
+	}
+	sort.Strings(namespaces)
+	return false, namespaces
+}
+
 // sortServicesByCreationTime sorts the list of services in ascending order by their creation time (if available).
 func sortServicesByCreationTime(services []*Service) []*Service {
 	sort.SliceStable(services, func(i, j int) bool {
@@ -803,7 +903,7 @@ func (ps *PushContext) initVirtualServices(env *Environment) error {
 		// resolve gateways to bind to
 		for i, g := range rule.Gateways {
 			if g != constants.IstioMeshGateway {
-				rule.Gateways[i] = resolveGatewayName(g, r.ConfigMeta)
+				rule.Gateways[i] = ResolveGatewayName(g, r.ConfigMeta)
 			}
 		}
 		// resolve host in http route.destination, route.mirror
@@ -811,7 +911,7 @@ func (ps *PushContext) initVirtualServices(env *Environment) error {
 			for _, m := range d.Match {
 				for i, g := range m.Gateways {
 					if g != constants.IstioMeshGateway {
-						m.Gateways[i] = resolveGatewayName(g, r.ConfigMeta)
+						m.Gateways[i] = ResolveGatewayName(g, r.ConfigMeta)
 					}
 				}
 			}
@@ -827,7 +927,7 @@ func (ps *PushContext) initVirtualServices(env *Environment) error {
 			for _, m := range d.Match {
 				for i, g := range m.Gateways {
 					if g != constants.IstioMeshGateway {
-						m.Gateways[i] = resolveGatewayName(g, r.ConfigMeta)
+						m.Gateways[i] = ResolveGatewayName(g, r.ConfigMeta)
 					}
 				}
 			}
@@ -840,7 +940,7 @@ func (ps *PushContext) initVirtualServices(env *Environment) error {
 			for _, m := range tls.Match {
 				for i, g := range m.Gateways {
 					if g != constants.IstioMeshGateway {
-						m.Gateways[i] = resolveGatewayName(g, r.ConfigMeta)
+						m.Gateways[i] = ResolveGatewayName(g, r.ConfigMeta)
 					}
 				}
 			}
@@ -850,28 +950,29 @@ func (ps *PushContext) initVirtualServices(env *Environment) error {
 		}
 	}
 
+	if features.EnableHostOwnership {
+		vservices = ps.rejectUnownedConfigs(vservices, func(cfg Config) []host.Name {
+			rule := cfg.Spec.(*networking.VirtualService)
+			hosts := make([]host.Name, 0, len(rule.Hosts))
+			for _, h := range rule.Hosts {
+				hosts = append(hosts, host.Name(h))
+			}
+			return hosts
+		})
+	}
+
 	for _, virtualService := range vservices {
-		ns := virtualService.Namespace
 		rule := virtualService.Spec.(*networking.VirtualService)
-		if len(rule.ExportTo) == 0 {
-			// No exportTo in virtualService. Use the global default
-			// TODO: We currently only honor ., * and ~
-			if ps.defaultVirtualServiceExportTo[visibility.Private] {
-				// add to local namespace only
-				ps.privateVirtualServicesByNamespace[ns] = append(ps.privateVirtualServicesByNamespace[ns], virtualService)
-			} else if ps.defaultVirtualServiceExportTo[visibility.Public] {
-				ps.publicVirtualServices = append(ps.publicVirtualServices, virtualService)
-			}
+		// No exportTo in virtualService. Use the global default
+		exportTo := ps.defaultVirtualServiceExportTo
+		if len(rule.ExportTo) > 0 {
+			exportTo = exportToMap(rule.ExportTo)
+		}
+		if public, namespaces := exportedNamespaces(exportTo, virtualService.Namespace); public {
+			ps.publicVirtualServices = append(ps.publicVirtualServices, virtualService)
 		} else {
-			// TODO: we currently only process the first element in the array
-			// and currently only consider . or * which maps to public/private
-			if visibility.Instance(rule.ExportTo[0]) == visibility.Private {
-				// add to local namespace only
+			for _, ns := range namespaces {
 				ps.privateVirtualServicesByNamespace[ns] = append(ps.privateVirtualServicesByNamespace[ns], virtualService)
-			} else {
-				// ~ is not valid in the exportTo fields in virtualServices, services, destination rules
-				// and we currently only allow . or *. So treat this as public export
-				ps.publicVirtualServices = append(ps.publicVirtualServices, virtualService)
 			}
 		}
 	}
@@ -947,13 +1048,6 @@ func (ps *PushContext) initSidecarScopes(env *Environment) error {
 	sidecarConfigs = append(sidecarConfigs, sidecarConfigWithSelector...)
 	sidecarConfigs = append(sidecarConfigs, sidecarConfigWithoutSelector...)
 
-	ps.sidecarsByNamespace = make(map[string][]*SidecarScope, sidecarNum)
-	for _, sidecarConfig := range sidecarConfigs {
-		sidecarConfig := sidecarConfig
-		ps.sidecarsByNamespace[sidecarConfig.Namespace] = append(ps.sidecarsByNamespace[sidecarConfig.Namespace],
-			ConvertToSidecarScope(ps, &sidecarConfig, sidecarConfig.Namespace))
-	}
-
 	// Hold reference root namespace's sidecar config
 	// Root namespace can have only one sidecar config object
 	// Currently we expect that it has no workloadSelectors
@@ -967,6 +1061,17 @@ func (ps *PushContext) initSidecarScopes(env *Environment) error {
 			}
 		}
 	}
+	inheritRootEgress := rootNSConfig != nil && rootNSConfig.Annotations[InheritedEgressAnnotation] == "true"
+
+	ps.sidecarsByNamespace = make(map[string][]*SidecarScope, sidecarNum)
+	for _, sidecarConfig := range sidecarConfigs {
+		sidecarConfig := sidecarConfig
+		if inheritRootEgress && (sidecarConfig.Namespace != rootNSConfig.Namespace || sidecarConfig.Name != rootNSConfig.Name) {
+			sidecarConfig = inheritRootSidecarEgress(rootNSConfig, sidecarConfig)
+		}
+		ps.sidecarsByNamespace[sidecarConfig.Namespace] = append(ps.sidecarsByNamespace[sidecarConfig.Namespace],
+			ConvertToSidecarScope(ps, &sidecarConfig, sidecarConfig.Namespace))
+	}
 
 	// build sidecar scopes for namespaces that dont have a non-workloadSelector sidecar CRD object.
 	// Derive the sidecar scope from the root namespace's sidecar object if present. Else fallback
@@ -988,6 +1093,11 @@ func (ps *PushContext) initDestinationRules(env *Environment) error {
 	if err != nil {
 		return err
 	}
+	if features.EnableHostOwnership {
+		configs = ps.rejectUnownedConfigs(configs, func(cfg Config) []host.Name {
+			return []host.Name{ResolveShortnameToFQDN(cfg.Spec.(*networking.DestinationRule).Host, cfg.ConfigMeta)}
+		})
+	}
 	ps.SetDestinationRules(configs)
 	return nil
 }
@@ -1002,47 +1112,66 @@ func (ps *PushContext) SetDestinationRules(configs []Config) {
 	sortConfigByCreationTime(configs)
 	namespaceLocalDestRules := make(map[string]*processedDestRules)
 	namespaceExportedDestRules := make(map[string]*processedDestRules)
+	namespaceImportedDestRules := make(map[string]*processedDestRules)
 	allExportedDestRules := &processedDestRules{
 		hosts:    make([]host.Name, 0),
 		destRule: map[host.Name]*combinedDestinationRule{},
 	}
 
+	defaultDestRules := make(map[string]*Config)
+
 	for i := range configs {
 		rule := configs[i].Spec.(*networking.DestinationRule)
 		rule.Host = string(ResolveShortnameToFQDN(rule.Host, configs[i].ConfigMeta))
+		if rule.Host == "*" {
+			// The oldest default dest rule of the namespace takes precedence.
+			if _, exist := defaultDestRules[configs[i].Namespace]; !exist {
+				defaultDestRules[configs[i].Namespace] = &configs[i]
+			}
+			continue
+		}
+		// No exportTo in destinationRule. Use the global default
+		exportTo := ps.defaultDestinationRuleExportTo
+		if len(rule.ExportTo) > 0 {
+			exportTo = exportToMap(rule.ExportTo)
+		}
+		isPubliclyExported, namespaces := exportedNamespaces(exportTo, configs[i].Namespace)
+
 		// Store in an index for the config's namespace
 		// a proxy from this namespace will first look here for the destination rule for a given service
 		// This pool consists of both public/private destination rules.
-		// TODO: when exportTo is fully supported, only add the rule here if exportTo is '.'
-		// The global exportTo doesn't matter here (its either . or * - both of which are applicable here)
-		if _, exist := namespaceLocalDestRules[configs[i].Namespace]; !exist {
-			namespaceLocalDestRules[configs[i].Namespace] = &processedDestRules{
-				hosts:    make([]host.Name, 0),
-				destRule: map[host.Name]*combinedDestinationRule{},
+		// The global exportTo doesn't matter here, only an explicit exportTo can hide the rule from its namespace.
+		isLocal := len(rule.ExportTo) == 0 || isPubliclyExported
+		for _, ns := range namespaces {
+			if ns == configs[i].Namespace {
+				isLocal = true
+				continue
 			}
-		}
-		// Merge this destination rule with any public/private dest rules for same host in the same namespace
-		// If there are no duplicates, the dest rule will be added to the list
-		namespaceLocalDestRules[configs[i].Namespace].hosts = ps.combineSingleDestinationRule(
-			namespaceLocalDestRules[configs[i].Namespace].hosts,
-			namespaceLocalDestRules[configs[i].Namespace].destRule,
-			configs[i])
-
-		isPubliclyExported := false
-		if len(rule.ExportTo) == 0 {
-			// No exportTo in destinationRule. Use the global default
-			// TODO: We currently only honor ., * and ~
-			if ps.defaultDestinationRuleExportTo[visibility.Public] {
-				isPubliclyExported = true
+			// Store in an index for the namespaces the rule is exported to by name
+			if _, exist := namespaceImportedDestRules[ns]; !exist {
+				namespaceImportedDestRules[ns] = &processedDestRules{
+					hosts:    make([]host.Name, 0),
+					destRule: map[host.Name]*combinedDestinationRule{},
+				}
 			}
-		} else {
-			// TODO: we currently only process the first element in the array
-			// and currently only consider . or * which maps to public/private
-			if visibility.Instance(rule.ExportTo[0]) != visibility.Private {
-				// ~ is not valid in the exportTo fields in virtualServices, services, destination rules
-				// and we currently only allow . or *. So treat this as public export
-				isPubliclyExported = true
+			namespaceImportedDestRules[ns].hosts = ps.combineSingleDestinationRule(
+				namespaceImportedDestRules[ns].hosts,
+				namespaceImportedDestRules[ns].destRule,
+				configs[i])
+		}
+		if isLocal {
+			if _, exist := namespaceLocalDestRules[configs[i].Namespace]; !exist {
+				namespaceLocalDestRules[configs[i].Namespace] = &processedDestRules{
+					hosts:    make([]host.Name, 0),
+					destRule: map[host.Name]*combinedDestinationRule{},
+				}
 			}
+			// Merge this destination rule with any public/private dest rules for same host in the same namespace
+			// If there are no duplicates, the dest rule will be added to the list
+			namespaceLocalDestRules[configs[i].Namespace].hosts = ps.combineSingleDestinationRule(
+				namespaceLocalDestRules[configs[i].Namespace].hosts,
+				namespaceLocalDestRules[configs[i].Namespace].destRule,
+				configs[i])
 		}
 
 		if isPubliclyExported {
@@ -1074,11 +1203,16 @@ func (ps *PushContext) SetDestinationRules(configs []Config) {
 	for ns := range namespaceExportedDestRules {
 		sort.Sort(host.Names(namespaceExportedDestRules[ns].hosts))
 	}
+	for ns := range namespaceImportedDestRules {
+		sort.Sort(host.Names(namespaceImportedDestRules[ns].hosts))
+	}
 	sort.Sort(host.Names(allExportedDestRules.hosts))
 
 	ps.namespaceLocalDestRules = namespaceLocalDestRules
 	ps.namespaceExportedDestRules = namespaceExportedDestRules
+	ps.namespaceImportedDestRules = namespaceImportedDestRules
 	ps.allExportedDestRules = allExportedDestRules
+	ps.defaultDestRules = defaultDestRules
 }
 
 func (ps *PushContext) initAuthorizationPolicies(env *Environment) error {
@@ -21,10 +21,13 @@ import (
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/schema"
 	"istio.io/istio/pkg/config/schemas"
+	"istio.io/istio/pkg/config/visibility"
 )
 
 func TestMergeUpdateRequest(t *testing.T) {
@@ -262,6 +265,91 @@ func TestSidecarScope(t *testing.T) {
 	}
 }
 
+func TestSidecarScopeInheritedEgress(t *testing.T) {
+	ps := NewPushContext()
+	env := &Environment{Mesh: &meshconfig.MeshConfig{RootNamespace: "istio-system"}}
+	ps.Env = env
+	ps.ServiceByHostnameAndNamespace[host.Name("svc2.nosidecar.cluster.local")] = map[string]*Service{"nosidecar": nil}
+
+	configStore := newFakeStore()
+	root := &networking.Sidecar{
+		Egress: []*networking.IstioEgressListener{{Hosts: []string{"istio-system/*", "./*"}}},
+	}
+	local := &networking.Sidecar{
+		Egress: []*networking.IstioEgressListener{
+			{Port: &networking.Port{Number: 9080, Protocol: "HTTP", Name: "http"}, Hosts: []string{"bar/*"}},
+			{Hosts: []string{"./*", "foo/*"}},
+		},
+	}
+	portOnly := &networking.Sidecar{
+		WorkloadSelector: &networking.WorkloadSelector{Labels: map[string]string{"app": "foo"}},
+		Egress: []*networking.IstioEgressListener{
+			{Port: &networking.Port{Number: 9080, Protocol: "HTTP", Name: "http"}, Hosts: []string{"bar/*"}},
+		},
+	}
+	for _, c := range []Config{
+		{
+			ConfigMeta: ConfigMeta{Type: schemas.Sidecar.Type, Group: schemas.Sidecar.Group, Version: schemas.Sidecar.Version,
+				Name: "global", Namespace: "istio-system", Annotations: map[string]string{InheritedEgressAnnotation: "true"}},
+			Spec: root,
+		},
+		{
+			ConfigMeta: ConfigMeta{Type: schemas.Sidecar.Type, Group: schemas.Sidecar.Group, Version: schemas.Sidecar.Version,
+				Name: "local", Namespace: "default"},
+			Spec: local,
+		},
+		{
+			ConfigMeta: ConfigMeta{Type: schemas.Sidecar.Type, Group: schemas.Sidecar.Group, Version: schemas.Sidecar.Version,
+				Name: "port-only", Namespace: "default"},
+			Spec: portOnly,
+		},
+	} {
+		_, _ = configStore.Create(c)
+	}
+	env.IstioConfigStore = &istioConfigStore{ConfigStore: configStore}
+	if err := ps.initSidecarScopes(env); err != nil {
+		t.Fatalf("init sidecar scope failed: %v", err)
+	}
+
+	egressHosts := func(scope *SidecarScope) [][]string {
+		var out [][]string
+		for _, e := range scope.Config.Spec.(*networking.Sidecar).Egress {
+			out = append(out, e.Hosts)
+		}
+		return out
+	}
+	cases := []struct {
+		proxy      *Proxy
+		collection labels.Collection
+		want       [][]string
+	}{
+		{
+			proxy:      &Proxy{ConfigNamespace: "default"},
+			collection: labels.Collection{map[string]string{"app": "bar"}},
+			want:       [][]string{{"bar/*"}, {"./*", "foo/*", "istio-system/*"}},
+		},
+		{
+			proxy:      &Proxy{ConfigNamespace: "default"},
+			collection: labels.Collection{map[string]string{"app": "foo"}},
+			want:       [][]string{{"bar/*"}, {"istio-system/*", "./*"}},
+		},
+		{
+			proxy:      &Proxy{ConfigNamespace: "nosidecar"},
+			collection: labels.Collection{map[string]string{"app": "bar"}},
+			want:       [][]string{{"istio-system/*", "./*"}},
+		},
+	}
+	for _, c := range cases {
+		scope := ps.getSidecarScope(c.proxy, c.collection)
+		if got := egressHosts(scope); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("sidecar %s of %v has egress hosts %v, want %v", scopeToSidecar(scope), c.collection, got, c.want)
+		}
+	}
+	if len(local.Egress[1].Hosts) != 2 {
+		t.Errorf("the egress hosts of the stored sidecar were modified: %v", local.Egress[1].Hosts)
+	}
+}
+
 func scopeToSidecar(scope *SidecarScope) string {
 	if scope == nil || scope.Config == nil {
 		return ""
@@ -316,3 +404,236 @@ func (s *fakeStore) Create(config Config) (revision string, err error) {
 func (*fakeStore) Update(config Config) (newRevision string, err error) { return "", nil }
 
 func (*fakeStore) Delete(typ, name, namespace string) error { return nil }
+
+func TestExportedNamespaces(t *testing.T) {
+	cases := []struct {
+		exportTo   []string
+		wantPublic bool
+		want       []string
+	}{
+		{[]string{"*"}, true, nil},
+		{[]string{"."}, false, []string{"default"}},
+		{[]string{"~"}, false, []string{}},
+		{[]string{"foo", ".", "bar"}, false, []string{"bar", "default", "foo"}},
+		{[]string{".", "default"}, false, []string{"default"}},
+	}
+	for _, c := range cases {
+		public, namespaces := exportedNamespaces(exportToMap(c.exportTo), "default")
+		if public != c.wantPublic || !reflect.DeepEqual(namespaces, c.want) {
+			t.Errorf("exportedNamespaces(%v) = %v, %v, want %v, %v", c.exportTo, public, namespaces, c.wantPublic, c.want)
+		}
+	}
+}
+
+func TestDestinationRuleExportTo(t *testing.T) {
+	ps := NewPushContext()
+	ps.Env = &Environment{Mesh: &meshconfig.MeshConfig{RootNamespace: "istio-system"}}
+	ps.defaultDestinationRuleExportTo = map[visibility.Instance]bool{visibility.Public: true}
+	destRule := func(name, hostname string, exportTo ...string) Config {
+		return Config{
+			ConfigMeta: ConfigMeta{Type: schemas.DestinationRule.Type, Name: name, Namespace: "test"},
+			Spec:       &networking.DestinationRule{Host: hostname, ExportTo: exportTo},
+		}
+	}
+	ps.SetDestinationRules([]Config{
+		destRule("to-foo", "a.test.svc.cluster.local", "foo"),
+		destRule("to-local-and-foo", "b.test.svc.cluster.local", ".", "foo"),
+		destRule("to-none", "c.test.svc.cluster.local", "~"),
+	})
+
+	cases := []struct {
+		namespace string
+		hostname  host.Name
+		want      string
+	}{
+		{"foo", "a.test.svc.cluster.local", "to-foo"},
+		{"bar", "a.test.svc.cluster.local", ""},
+		{"test", "a.test.svc.cluster.local", ""},
+		{"foo", "b.test.svc.cluster.local", "to-local-and-foo"},
+		{"test", "b.test.svc.cluster.local", "to-local-and-foo"},
+		{"bar", "b.test.svc.cluster.local", ""},
+		{"test", "c.test.svc.cluster.local", ""},
+		{"foo", "c.test.svc.cluster.local", ""},
+	}
+	for _, c := range cases {
+		proxy := &Proxy{ConfigNamespace: c.namespace}
+		svc := &Service{Hostname: c.hostname, Attributes: ServiceAttributes{Namespace: "test"}}
+		got := ""
+		if cfg := ps.DestinationRule(proxy, svc); cfg != nil {
+			got = cfg.Name
+		}
+		if got != c.want {
+			t.Errorf("DestinationRule(%s, %s) = %q, want %q", c.namespace, c.hostname, got, c.want)
+		}
+	}
+}
+
+func TestDefaultDestinationRules(t *testing.T) {
+	ps := NewPushContext()
+	ps.Env = &Environment{Mesh: &meshconfig.MeshConfig{RootNamespace: "istio-system"}}
+	ps.defaultDestinationRuleExportTo = map[visibility.Instance]bool{visibility.Public: true}
+	destRule := func(name, namespace, hostname string, policy *networking.TrafficPolicy) Config {
+		return Config{
+			ConfigMeta: ConfigMeta{Type: schemas.DestinationRule.Type, Name: name, Namespace: namespace},
+			Spec:       &networking.DestinationRule{Host: hostname, TrafficPolicy: policy},
+		}
+	}
+	roundRobin := &networking.LoadBalancerSettings{
+		LbPolicy: &networking.LoadBalancerSettings_Simple{Simple: networking.LoadBalancerSettings_ROUND_ROBIN},
+	}
+	leastConn := &networking.LoadBalancerSettings{
+		LbPolicy: &networking.LoadBalancerSettings_Simple{Simple: networking.LoadBalancerSettings_LEAST_CONN},
+	}
+	meshPool := &networking.ConnectionPoolSettings{Tcp: &networking.ConnectionPoolSettings_TCPSettings{MaxConnections: 100}}
+	fooPool := &networking.ConnectionPoolSettings{Tcp: &networking.ConnectionPoolSettings_TCPSettings{MaxConnections: 10}}
+	outlier := &networking.OutlierDetection{ConsecutiveErrors: 5}
+	ps.SetDestinationRules([]Config{
+		destRule("mesh-default", "istio-system", "*",
+			&networking.TrafficPolicy{LoadBalancer: roundRobin, ConnectionPool: meshPool, OutlierDetection: outlier}),
+		destRule("foo-default", "foo", "*", &networking.TrafficPolicy{ConnectionPool: fooPool}),
+		destRule("a", "test", "a.test.svc.cluster.local", &networking.TrafficPolicy{LoadBalancer: leastConn}),
+	})
+
+	cases := []struct {
+		namespace string
+		hostname  host.Name
+		want      string
+		policy    *networking.TrafficPolicy
+	}{
+		{"bar", "a.test.svc.cluster.local", "a",
+			&networking.TrafficPolicy{LoadBalancer: leastConn, ConnectionPool: meshPool, OutlierDetection: outlier}},
+		{"foo", "a.test.svc.cluster.local", "a",
+			&networking.TrafficPolicy{LoadBalancer: leastConn, ConnectionPool: fooPool, OutlierDetection: outlier}},
+		{"bar", "b.test.svc.cluster.local", "mesh-default",
+			&networking.TrafficPolicy{LoadBalancer: roundRobin, ConnectionPool: meshPool, OutlierDetection: outlier}},
+		{"foo", "b.test.svc.cluster.local", "foo-default",
+			&networking.TrafficPolicy{LoadBalancer: roundRobin, ConnectionPool: fooPool, OutlierDetection: outlier}},
+	}
+	for _, c := range cases {
+		proxy := &Proxy{ConfigNamespace: c.namespace}
+		svc := &Service{Hostname: c.hostname, Attributes: ServiceAttributes{Namespace: "test"}}
+		cfg := ps.DestinationRule(proxy, svc)
+		if cfg == nil {
+			t.Fatalf("DestinationRule(%s, %s) = nil, want %q", c.namespace, c.hostname, c.want)
+		}
+		if cfg.Name != c.want {
+			t.Errorf("DestinationRule(%s, %s) = %q, want %q", c.namespace, c.hostname, cfg.Name, c.want)
+		}
+		if policy := cfg.Spec.(*networking.DestinationRule).TrafficPolicy; !reflect.DeepEqual(policy, c.policy) {
+			t.Errorf("DestinationRule(%s, %s) traffic policy = %v, want %v", c.namespace, c.hostname, policy, c.policy)
+		}
+	}
+
+	// The specific destination rule is left unchanged.
+	if policy := ps.allExportedDestRules.destRule["a.test.svc.cluster.local"].config.Spec.(*networking.DestinationRule).TrafficPolicy; policy.ConnectionPool != nil {
+		t.Errorf("destination rule a was modified: %v", policy)
+	}
+}
+
+func TestDestinationRuleInheritance(t *testing.T) {
+	ps := NewPushContext()
+	ps.Env = &Environment{Mesh: &meshconfig.MeshConfig{RootNamespace: "istio-system"}}
+	ps.defaultDestinationRuleExportTo = map[visibility.Instance]bool{visibility.Public: true}
+	now := time.Now()
+	destRule := func(name, namespace string, created time.Time, policy *networking.TrafficPolicy, subsets ...string) Config {
+		rule := &networking.DestinationRule{Host: "a.test.svc.cluster.local", TrafficPolicy: policy}
+		for _, subset := range subsets {
+			rule.Subsets = append(rule.Subsets, &networking.Subset{Name: subset, Labels: map[string]string{"version": subset}})
+		}
+		return Config{
+			ConfigMeta: ConfigMeta{Type: schemas.DestinationRule.Type, Name: name, Namespace: namespace, CreationTimestamp: created},
+			Spec:       rule,
+		}
+	}
+	leastConn := &networking.LoadBalancerSettings{
+		LbPolicy: &networking.LoadBalancerSettings_Simple{Simple: networking.LoadBalancerSettings_LEAST_CONN},
+	}
+	random := &networking.LoadBalancerSettings{
+		LbPolicy: &networking.LoadBalancerSettings_Simple{Simple: networking.LoadBalancerSettings_RANDOM},
+	}
+	pool := &networking.ConnectionPoolSettings{Tcp: &networking.ConnectionPoolSettings_TCPSettings{MaxConnections: 10}}
+	outlier := &networking.OutlierDetection{ConsecutiveErrors: 5}
+	tls := &networking.TLSSettings{Mode: networking.TLSSettings_ISTIO_MUTUAL}
+	ps.SetDestinationRules([]Config{
+		destRule("mesh", "istio-system", now, &networking.TrafficPolicy{OutlierDetection: outlier, LoadBalancer: random}, "v1"),
+		destRule("service", "test", now, &networking.TrafficPolicy{LoadBalancer: leastConn}, "v1", "v2"),
+		destRule("service-tls", "test", now.Add(time.Minute), &networking.TrafficPolicy{LoadBalancer: random, Tls: tls}),
+		destRule("client", "foo", now, &networking.TrafficPolicy{ConnectionPool: pool}, "v3"),
+	})
+
+	defer func(enabled bool) { features.EnableDestinationRuleInheritance = enabled }(features.EnableDestinationRuleInheritance)
+	svc := &Service{Hostname: "a.test.svc.cluster.local", Attributes: ServiceAttributes{Namespace: "test"}}
+	cases := []struct {
+		namespace string
+		inherit   bool
+		want      string
+		policy    *networking.TrafficPolicy
+		subsets   []string
+	}{
+		// Without inheritance, only the rules of the same namespace are merged.
+		{"bar", false, "service",
+			&networking.TrafficPolicy{LoadBalancer: leastConn, Tls: tls}, []string{"v1", "v2"}},
+		{"foo", false, "client",
+			&networking.TrafficPolicy{ConnectionPool: pool}, []string{"v3"}},
+		{"bar", true, "service",
+			&networking.TrafficPolicy{LoadBalancer: leastConn, Tls: tls, OutlierDetection: outlier}, []string{"v1", "v2"}},
+		{"foo", true, "client",
+			&networking.TrafficPolicy{LoadBalancer: leastConn, Tls: tls, OutlierDetection: outlier, ConnectionPool: pool},
+			[]string{"v3", "v1", "v2"}},
+	}
+	for _, c := range cases {
+		features.EnableDestinationRuleInheritance = c.inherit
+		cfg := ps.DestinationRule(&Proxy{ConfigNamespace: c.namespace}, svc)
+		if cfg == nil {
+			t.Fatalf("DestinationRule(%s, inherit %v) = nil, want %q", c.namespace, c.inherit, c.want)
+		}
+		if cfg.Name != c.want {
+			t.Errorf("DestinationRule(%s) = %q, want %q", c.namespace, cfg.Name, c.want)
+		}
+		rule := cfg.Spec.(*networking.DestinationRule)
+		if !reflect.DeepEqual(rule.TrafficPolicy, c.policy) {
+			t.Errorf("DestinationRule(%s) traffic policy = %v, want %v", c.namespace, rule.TrafficPolicy, c.policy)
+		}
+		var subsets []string
+		for _, subset := range rule.Subsets {
+			subsets = append(subsets, subset.Name)
+		}
+		if !reflect.DeepEqual(subsets, c.subsets) {
+			t.Errorf("DestinationRule(%s) subsets = %v, want %v", c.namespace, subsets, c.subsets)
+		}
+	}
+}
+
+func TestVirtualServicesPriority(t *testing.T) {
+	virtualService := func(name, namespace, priority string, created time.Time) Config {
+		cfg := Config{
+			ConfigMeta: ConfigMeta{
+				Name:              name,
+				Namespace:         namespace,
+				CreationTimestamp: created,
+			},
+			Spec: &networking.VirtualService{Hosts: []string{"foo.com"}},
+		}
+		if priority != "" {
+			cfg.Annotations = map[string]string{constants.RoutePriorityAnnotation: priority}
+		}
+		return cfg
+	}
+	now := time.Now()
+	ps := NewPushContext()
+	ps.privateVirtualServicesByNamespace = map[string][]Config{
+		"a": {virtualService("old", "a", "", now.Add(-time.Hour)), virtualService("new", "a", "", now)},
+		"b": {virtualService("high", "b", "10", now), virtualService("invalid", "b", "high", now.Add(-2*time.Hour))},
+	}
+	ps.publicVirtualServices = []Config{virtualService("low", "c", "-1", now.Add(-3*time.Hour))}
+
+	gateways := map[string]bool{constants.IstioMeshGateway: true}
+	var got []string
+	for _, cfg := range ps.VirtualServices(nil, gateways) {
+		got = append(got, cfg.Name)
+	}
+	want := []string{"high", "invalid", "old", "new", "low"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got virtual services %v, want %v", got, want)
+	}
+}
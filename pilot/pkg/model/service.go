@@ -119,6 +119,18 @@ const (
 const (
 	// MTLSReadyLabelName name for the mtlsReady label given to service instances to toggle mTLS autopilot
 	MTLSReadyLabelName = "security.istio.io/mtlsReady"
+
+	// PodNodeNameLabel is the endpoint label holding the node a pod runs on, added when the
+	// PILOT_ENDPOINT_POD_FIELDS feature is enabled.
+	PodNodeNameLabel = "pod.istio.io/nodeName"
+
+	// PodRuntimeClassLabel is the endpoint label holding the runtime class of a pod, added when the
+	// PILOT_ENDPOINT_POD_FIELDS feature is enabled.
+	PodRuntimeClassLabel = "pod.istio.io/runtimeClassName"
+
+	// RevisionLabel is the label selecting the control plane revision a namespace or workload uses.
+	// Namespaces without the label use the default, unrevisioned, control plane.
+	RevisionLabel = "istio.io/rev"
 )
 
 // Port represents a network port where a service is listening for
@@ -362,6 +374,9 @@ type ServiceAttributes struct {
 	// ExportTo defines the visibility of Service in
 	// a namespace when the namespace is imported.
 	ExportTo map[visibility.Instance]bool
+	// ConfigWriters are the namespaces, besides the namespace of the service, allowed to define
+	// the virtual services and destination rules of its hostname when host ownership is enforced.
+	ConfigWriters map[string]bool
 
 	// For Kubernetes platform
 
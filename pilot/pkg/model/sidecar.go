@@ -18,6 +18,7 @@ import (
 	"strings"
 
 	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	"github.com/gogo/protobuf/proto"
 
 	networking "istio.io/api/networking/v1alpha3"
 
@@ -29,6 +30,17 @@ const (
 	wildcardNamespace = "*"
 	currentNamespace  = "."
 	wildcardService   = host.Name("*")
+
+	// DynamicForwardProxyDomainsAnnotation lists, comma separated, the domain suffixes of the external
+	// hosts which the sidecars selected by a Sidecar resource reach through a dynamic forward proxy,
+	// resolving the hosts at request time instead of requiring a ServiceEntry for each of them.
+	DynamicForwardProxyDomainsAnnotation = "networking.istio.io/dynamicForwardProxyDomains"
+
+	// InheritedEgressAnnotation is the annotation of the Sidecar of the root namespace which, set to "true",
+	// adds its egress hosts to all the Sidecars of the mesh, instead of only applying to the namespaces
+	// without a Sidecar. The root Sidecar then holds the hosts all the workloads need, e.g. "istio-system/*",
+	// and the namespace and workload Sidecars only list their own hosts.
+	InheritedEgressAnnotation = "networking.istio.io/inheritedEgress"
 )
 
 // SidecarScope is a wrapper over the Sidecar resource with some
@@ -96,6 +108,11 @@ type SidecarScope struct {
 
 	// Set of all namespaces this sidecar depends on. This is determined from the egress config
 	namespaceDependencies map[string]struct{}
+
+	// Compression policy and adaptive concurrency limit of the inbound listeners, parsed from the
+	// annotations of the Sidecar config. Nil when not set.
+	compression         *CompressionPolicy
+	adaptiveConcurrency *AdaptiveConcurrency
 }
 
 // IstioEgressListenerWrapper is a wrapper for
@@ -251,10 +268,51 @@ func ConvertToSidecarScope(ps *PushContext, sidecarConfig *Config, configNamespa
 	if len(r.Ingress) > 0 {
 		out.HasCustomIngressListeners = true
 	}
+	out.compression = ParseCompressionPolicy(sidecarConfig.Annotations[CompressionAnnotation])
+	out.adaptiveConcurrency = ParseAdaptiveConcurrency(sidecarConfig.Annotations[AdaptiveConcurrencyAnnotation])
 
 	return out
 }
 
+// inheritRootSidecarEgress returns the sidecar config with the egress hosts of the catch-all egress
+// listener of the root sidecar, the listener without port, added to its own catch-all egress listener.
+// The listener is added if the sidecar has none.
+func inheritRootSidecarEgress(root *Config, sidecarConfig Config) Config {
+	var rootHosts []string
+	for _, e := range root.Spec.(*networking.Sidecar).Egress {
+		if e.Port == nil {
+			rootHosts = append(rootHosts, e.Hosts...)
+		}
+	}
+	if len(rootHosts) == 0 {
+		return sidecarConfig
+	}
+
+	sidecar := proto.Clone(sidecarConfig.Spec.(*networking.Sidecar)).(*networking.Sidecar)
+	var catchAll *networking.IstioEgressListener
+	for _, e := range sidecar.Egress {
+		if e.Port == nil {
+			catchAll = e
+		}
+	}
+	if catchAll == nil {
+		catchAll = &networking.IstioEgressListener{}
+		sidecar.Egress = append(sidecar.Egress, catchAll)
+	}
+	hosts := make(map[string]bool, len(catchAll.Hosts))
+	for _, h := range catchAll.Hosts {
+		hosts[h] = true
+	}
+	for _, h := range rootHosts {
+		if !hosts[h] {
+			hosts[h] = true
+			catchAll.Hosts = append(catchAll.Hosts, h)
+		}
+	}
+	sidecarConfig.Spec = sidecar
+	return sidecarConfig
+}
+
 func convertIstioListenerToWrapper(ps *PushContext, configNamespace string,
 	istioListener *networking.IstioEgressListener) *IstioEgressListenerWrapper {
 
@@ -394,6 +452,47 @@ func (sc *SidecarScope) DependsOnNamespace(namespace string) bool {
 	return false
 }
 
+// DynamicForwardProxyDomains returns the domains, as "*.<suffix>" wildcards, of the external hosts
+// to reach through the dynamic forward proxy. It returns nil when the dynamic forward proxy is not
+// enabled for the sidecar.
+func (sc *SidecarScope) DynamicForwardProxyDomains() []string {
+	if sc == nil || sc.Config == nil {
+		return nil
+	}
+	value := sc.Config.Annotations[DynamicForwardProxyDomainsAnnotation]
+	if value == "" {
+		return nil
+	}
+
+	var domains []string
+	for _, suffix := range strings.Split(value, ",") {
+		suffix = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(suffix), "*"), ".")
+		if suffix == "" {
+			continue
+		}
+		domains = append(domains, "*."+suffix)
+	}
+	return domains
+}
+
+// Compression returns the compression policy of the responses sent by the inbound listeners of the
+// sidecar, or nil if they are not compressed.
+func (sc *SidecarScope) Compression() *CompressionPolicy {
+	if sc == nil {
+		return nil
+	}
+	return sc.compression
+}
+
+// AdaptiveConcurrency returns the adaptive concurrency limit of the inbound requests of the sidecar,
+// or nil if the concurrency is not limited.
+func (sc *SidecarScope) AdaptiveConcurrency() *AdaptiveConcurrency {
+	if sc == nil {
+		return nil
+	}
+	return sc.adaptiveConcurrency
+}
+
 // Given a list of virtual services visible to this namespace,
 // selectVirtualServices returns the list of virtual services that are
 // applicable to this egress listener, based on the hosts field specified
@@ -567,3 +567,104 @@ outboundTrafficPolicy:
 		})
 	}
 }
+
+func TestSidecarDynamicForwardProxyDomains(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        []string
+	}{
+		{
+			name: "no annotation",
+			want: nil,
+		},
+		{
+			name:        "empty annotation",
+			annotations: map[string]string{DynamicForwardProxyDomainsAnnotation: ""},
+			want:        nil,
+		},
+		{
+			name: "suffixes",
+			annotations: map[string]string{
+				DynamicForwardProxyDomainsAnnotation: "example.com, .googleapis.com,*.amazonaws.com,,",
+			},
+			want: []string{"*.example.com", "*.googleapis.com", "*.amazonaws.com"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sc := &SidecarScope{Config: &Config{ConfigMeta: ConfigMeta{Annotations: test.annotations}}}
+			if got := sc.DynamicForwardProxyDomains(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got domains %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	var sc *SidecarScope
+	if got := sc.DynamicForwardProxyDomains(); got != nil {
+		t.Errorf("got domains %v for a nil sidecar scope", got)
+	}
+}
+
+func TestSidecarAdaptiveConcurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        *AdaptiveConcurrency
+	}{
+		{
+			name: "no annotation",
+			want: nil,
+		},
+		{
+			name:        "defaults",
+			annotations: map[string]string{AdaptiveConcurrencyAnnotation: "{}"},
+			want: &AdaptiveConcurrency{
+				LatencyPercentile:         50,
+				ConcurrencyUpdateInterval: "100ms",
+				MinRTTInterval:            "1m0s",
+			},
+		},
+		{
+			name: "settings",
+			annotations: map[string]string{
+				AdaptiveConcurrencyAnnotation: `{"latencyPercentile": 90, "maxConcurrencyLimit": 500, "minRttInterval": "30s"}`,
+			},
+			want: &AdaptiveConcurrency{
+				LatencyPercentile:         90,
+				MaxConcurrencyLimit:       500,
+				ConcurrencyUpdateInterval: "100ms",
+				MinRTTInterval:            "30s",
+			},
+		},
+		{
+			name:        "invalid percentile",
+			annotations: map[string]string{AdaptiveConcurrencyAnnotation: `{"latencyPercentile": 150}`},
+			want:        nil,
+		},
+		{
+			name:        "invalid interval",
+			annotations: map[string]string{AdaptiveConcurrencyAnnotation: `{"concurrencyUpdateInterval": "-1s"}`},
+			want:        nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ps := NewPushContext()
+			meshConfig := mesh.DefaultMeshConfig()
+			ps.Env = &Environment{
+				Mesh: &meshConfig,
+			}
+			cfg := &Config{
+				ConfigMeta: ConfigMeta{Name: "sidecar", Namespace: "default", Annotations: test.annotations},
+				Spec:       &networking.Sidecar{},
+			}
+			sc := ConvertToSidecarScope(ps, cfg, "default")
+			if got := sc.AdaptiveConcurrency(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got adaptive concurrency %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
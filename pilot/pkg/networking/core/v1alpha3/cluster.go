@@ -24,6 +24,8 @@ import (
 	v2Cluster "github.com/envoyproxy/go-control-plane/envoy/api/v2/cluster"
 	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
+	dfpcluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/dynamic_forward_proxy/v2alpha"
+	dfpcommon "github.com/envoyproxy/go-control-plane/envoy/config/common/dynamic_forward_proxy/v2alpha"
 	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type"
 	"github.com/gogo/protobuf/types"
 	"github.com/golang/protobuf/ptypes/wrappers"
@@ -45,6 +47,7 @@ import (
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/security"
 )
 
 const (
@@ -60,6 +63,12 @@ const (
 	servicePortStatPattern     = "%SERVICE_PORT%"
 	servicePortNameStatPattern = "%SERVICE_PORT_NAME%"
 	subsetNameStatPattern      = "%SUBSET_NAME%"
+
+	// dynamicForwardProxyClusterType is the extension of the cluster resolving hosts at request time
+	dynamicForwardProxyClusterType = "envoy.clusters.dynamic_forward_proxy"
+	// dynamicForwardProxyDNSCache is the name of the DNS cache shared by the dynamic forward proxy
+	// cluster and HTTP filter
+	dynamicForwardProxyDNSCache = "dynamic_forward_proxy_cache"
 )
 
 var (
@@ -72,6 +81,33 @@ var (
 		// in before Pilot is able to deliver an updated endpoint list to Envoy, leading to client-facing 503s.
 		MaxRetries: &wrappers.UInt32Value{Value: 1024},
 	}
+
+	// The high priority thresholds apply to the requests routed with the high priority, e.g. by the
+	// requestPriorities annotation of VirtualServices. The connection pool settings of the destination
+	// rules do not limit them, but they keep the default max_retries, rather than the Envoy default of 3.
+	// They are shared by all clusters and must not be modified.
+	defaultInboundHighPriorityCircuitBreakerThresholds = v2Cluster.CircuitBreakers_Thresholds{
+		Priority: core.RoutingPriority_HIGH,
+	}
+	defaultOutboundHighPriorityCircuitBreakerThresholds = v2Cluster.CircuitBreakers_Thresholds{
+		Priority:   core.RoutingPriority_HIGH,
+		MaxRetries: &wrappers.UInt32Value{Value: 1024},
+	}
+
+	// defaultInboundCircuitBreakers and defaultOutboundCircuitBreakers are shared by all clusters that
+	// do not override any threshold. They must not be modified.
+	defaultInboundCircuitBreakers = &v2Cluster.CircuitBreakers{
+		Thresholds: []*v2Cluster.CircuitBreakers_Thresholds{
+			&defaultInboundCircuitBreakerThresholds,
+			&defaultInboundHighPriorityCircuitBreakerThresholds,
+		},
+	}
+	defaultOutboundCircuitBreakers = &v2Cluster.CircuitBreakers{
+		Thresholds: []*v2Cluster.CircuitBreakers_Thresholds{
+			&defaultOutboundCircuitBreakerThresholds,
+			&defaultOutboundHighPriorityCircuitBreakerThresholds,
+		},
+	}
 )
 
 // getDefaultCircuitBreakerThresholds returns a copy of the default circuit breaker thresholds for the given traffic direction.
@@ -84,6 +120,23 @@ func getDefaultCircuitBreakerThresholds(direction model.TrafficDirection) *v2Clu
 	return &thresholds
 }
 
+// getHighPriorityCircuitBreakerThresholds returns the shared, immutable high priority circuit breaker
+// thresholds for the given traffic direction.
+func getHighPriorityCircuitBreakerThresholds(direction model.TrafficDirection) *v2Cluster.CircuitBreakers_Thresholds {
+	if direction == model.TrafficDirectionInbound {
+		return &defaultInboundHighPriorityCircuitBreakerThresholds
+	}
+	return &defaultOutboundHighPriorityCircuitBreakerThresholds
+}
+
+// getDefaultCircuitBreakers returns the shared, immutable default circuit breakers for the given traffic direction.
+func getDefaultCircuitBreakers(direction model.TrafficDirection) *v2Cluster.CircuitBreakers {
+	if direction == model.TrafficDirectionInbound {
+		return defaultInboundCircuitBreakers
+	}
+	return defaultOutboundCircuitBreakers
+}
+
 // TODO: Need to do inheritance of DestRules based on domain suffix match
 
 // BuildClusters returns the list of clusters for the given proxy. This is the CDS output
@@ -98,7 +151,11 @@ func (configgen *ConfigGeneratorImpl) BuildClusters(env *model.Environment, prox
 
 	if env.Mesh.LocalityLbSetting != nil {
 		// apply load balancer setting fot cluster endpoints
-		applyLocalityLBSetting(proxy.Locality, outboundClusters, env.Mesh.LocalityLbSetting)
+		applyLocalityLBSetting(proxy.Locality, proxy.ClusterID, proxy.Metadata[model.NodeMetadataNetwork], outboundClusters,
+			env.Mesh.LocalityLbSetting)
+	}
+	if proxy.IsProxylessGRPC() {
+		return buildGRPCClusters(outboundClusters)
 	}
 	// Add a blackhole and passthrough cluster for catching traffic to unresolved routes
 	// DO NOT CALL PLUGINS for these two clusters.
@@ -109,6 +166,9 @@ func (configgen *ConfigGeneratorImpl) BuildClusters(env *model.Environment, prox
 		outboundClusters = envoyfilter.ApplyClusterPatches(networking.EnvoyFilter_SIDECAR_OUTBOUND, proxy, push, outboundClusters)
 		// Let ServiceDiscovery decide which IP and Port are used for management if
 		// there are multiple IPs
+		if len(proxy.SidecarScope.DynamicForwardProxyDomains()) > 0 {
+			outboundClusters = append(outboundClusters, buildDynamicForwardProxyCluster(env, proxy))
+		}
 		managementPorts := make([]*model.Port, 0)
 		for _, ip := range proxy.IPAddresses {
 			managementPorts = append(managementPorts, env.ManagementPorts(ip)...)
@@ -129,6 +189,9 @@ func (configgen *ConfigGeneratorImpl) BuildClusters(env *model.Environment, prox
 	}
 
 	clusters = normalizeClusters(push, proxy, clusters)
+	if security.FIPSMode {
+		applyFIPSToClusters(clusters)
+	}
 
 	return clusters
 }
@@ -175,18 +238,25 @@ func (configgen *ConfigGeneratorImpl) buildOutboundClusters(env *model.Environme
 			discoveryType := convertResolution(service.Resolution)
 			clusterName := model.BuildSubsetKey(model.TrafficDirectionOutbound, "", service.Hostname, port.Port)
 			serviceAccounts := push.ServiceAccounts[service.Hostname][port.Port]
-			defaultCluster := buildDefaultCluster(env, clusterName, discoveryType, lbEndpoints, model.TrafficDirectionOutbound, proxy, port)
+			retainDNS := destRule != nil && destRule.Annotations[model.RetainEndpointsOnDNSFailureAnnotation] == "true"
+			properCaseHeaders := destRule != nil && destRule.Annotations[model.HTTP1ProperCaseHeadersAnnotation] == "true"
+			defaultCluster := buildDefaultCluster(env, clusterName, dnsDiscoveryType(discoveryType, retainDNS, lbEndpoints),
+				lbEndpoints, model.TrafficDirectionOutbound, proxy, port)
 			// If stat name is configured, build the alternate stats name.
 			if len(env.Mesh.OutboundClusterStatName) != 0 {
 				defaultCluster.AltStatName = altStatName(env.Mesh.OutboundClusterStatName, string(service.Hostname), "", proxy.DNSDomain, port)
 			}
 
 			setUpstreamProtocol(defaultCluster, port)
+			if properCaseHeaders {
+				applyProperCaseHeaders(defaultCluster, port)
+			}
 			clusters = append(clusters, defaultCluster)
 
 			if destRule != nil {
 				destinationRule := destRule.Spec.(*networking.DestinationRule)
 				defaultSni := model.BuildDNSSrvSubsetKey(model.TrafficDirectionOutbound, "", service.Hostname, port.Port)
+				http2Options := model.ParseHTTP2Options(destRule.Annotations[model.HTTP2OptionsAnnotation])
 				opts := buildClusterOpts{
 					env:             env,
 					cluster:         defaultCluster,
@@ -197,11 +267,13 @@ func (configgen *ConfigGeneratorImpl) buildOutboundClusters(env *model.Environme
 					clusterMode:     DefaultClusterMode,
 					direction:       model.TrafficDirectionOutbound,
 					proxy:           proxy,
+					credentialName:  destRule.Annotations[model.CredentialNameAnnotation],
+					http2Options:    http2Options,
 				}
 
 				applyTrafficPolicy(opts, proxy)
 				defaultCluster.Metadata = util.BuildConfigInfoMetadata(destRule.ConfigMeta)
-				for _, subset := range destinationRule.Subsets {
+				for i, subset := range destinationRule.Subsets {
 					subsetClusterName := model.BuildSubsetKey(model.TrafficDirectionOutbound, subset.Name, service.Hostname, port.Port)
 					defaultSni := model.BuildDNSSrvSubsetKey(model.TrafficDirectionOutbound, subset.Name, service.Hostname, port.Port)
 
@@ -210,11 +282,15 @@ func (configgen *ConfigGeneratorImpl) buildOutboundClusters(env *model.Environme
 					if discoveryType != apiv2.Cluster_EDS && len(subset.Labels) != 0 {
 						lbEndpoints = buildLocalityLbEndpoints(env, networkView, service, port.Port, []labels.Instance{subset.Labels})
 					}
-					subsetCluster := buildDefaultCluster(env, subsetClusterName, discoveryType, lbEndpoints, model.TrafficDirectionOutbound, proxy, nil)
+					subsetCluster := buildDefaultCluster(env, subsetClusterName, dnsDiscoveryType(discoveryType, retainDNS, lbEndpoints),
+						lbEndpoints, model.TrafficDirectionOutbound, proxy, nil)
 					if len(env.Mesh.OutboundClusterStatName) != 0 {
 						subsetCluster.AltStatName = altStatName(env.Mesh.OutboundClusterStatName, string(service.Hostname), subset.Name, proxy.DNSDomain, port)
 					}
 					setUpstreamProtocol(subsetCluster, port)
+					if properCaseHeaders {
+						applyProperCaseHeaders(subsetCluster, port)
+					}
 
 					opts := buildClusterOpts{
 						env:             env,
@@ -226,6 +302,8 @@ func (configgen *ConfigGeneratorImpl) buildOutboundClusters(env *model.Environme
 						clusterMode:     DefaultClusterMode,
 						direction:       model.TrafficDirectionOutbound,
 						proxy:           proxy,
+						credentialName:  destRule.Annotations[model.CredentialNameAnnotation],
+						http2Options:    http2Options,
 					}
 					applyTrafficPolicy(opts, proxy)
 
@@ -239,12 +317,14 @@ func (configgen *ConfigGeneratorImpl) buildOutboundClusters(env *model.Environme
 						clusterMode:     DefaultClusterMode,
 						direction:       model.TrafficDirectionOutbound,
 						proxy:           proxy,
+						credentialName:  destRule.Annotations[model.CredentialNameAnnotation],
+						http2Options:    http2Options,
 					}
 					applyTrafficPolicy(opts, proxy)
 
 					updateEds(subsetCluster)
 
-					subsetCluster.Metadata = util.BuildConfigInfoMetadata(destRule.ConfigMeta)
+					subsetCluster.Metadata = util.BuildConfigInfoMetadataForRule(destRule.ConfigMeta, "subsets", i)
 					// call plugins
 					for _, p := range configgen.Plugins {
 						p.OnOutboundCluster(inputParams, subsetCluster)
@@ -300,7 +380,7 @@ func (configgen *ConfigGeneratorImpl) buildOutboundSniDnatClusters(env *model.En
 				}
 				applyTrafficPolicy(opts, proxy)
 				defaultCluster.Metadata = util.BuildConfigInfoMetadata(destRule.ConfigMeta)
-				for _, subset := range destinationRule.Subsets {
+				for i, subset := range destinationRule.Subsets {
 					subsetClusterName := model.BuildDNSSrvSubsetKey(model.TrafficDirectionOutbound, subset.Name, service.Hostname, port.Port)
 					// clusters with discovery type STATIC, STRICT_DNS rely on cluster.hosts field
 					// ServiceEntry's need to filter hosts based on subset.labels in order to perform weighted routing
@@ -334,7 +414,7 @@ func (configgen *ConfigGeneratorImpl) buildOutboundSniDnatClusters(env *model.En
 
 					updateEds(subsetCluster)
 
-					subsetCluster.Metadata = util.BuildConfigInfoMetadata(destRule.ConfigMeta)
+					subsetCluster.Metadata = util.BuildConfigInfoMetadataForRule(destRule.ConfigMeta, "subsets", i)
 					clusters = append(clusters, subsetCluster)
 				}
 			}
@@ -486,6 +566,10 @@ func (configgen *ConfigGeneratorImpl) buildInboundClusters(env *model.Environmen
 	noneMode := proxy.GetInterceptionMode() == model.InterceptionNone
 
 	_, actualLocalHost := getActualWildcardAndLocalHost(proxy)
+	// Connections from the address of the downstream client cannot be opened to the loopback address.
+	if proxy.PreservesSourceAddress() && len(proxy.IPAddresses) > 0 {
+		actualLocalHost = proxy.IPAddresses[0]
+	}
 
 	if !sidecarScope.HasCustomIngressListeners {
 		// No user supplied sidecar scope or the user supplied one has no ingress listeners
@@ -648,6 +732,10 @@ func (configgen *ConfigGeneratorImpl) buildInboundClusterForPortOrUDS(pluginPara
 				model.TrafficDirectionInbound)
 			localCluster.Metadata = util.BuildConfigInfoMetadata(cfg.ConfigMeta)
 		}
+		// The local application is the legacy destination expecting capitalized header names.
+		if cfg.Annotations[model.HTTP1ProperCaseHeadersAnnotation] == "true" {
+			applyProperCaseHeaders(localCluster, instance.Endpoint.ServicePort)
+		}
 	}
 	return localCluster
 }
@@ -665,6 +753,24 @@ func convertResolution(resolution model.Resolution) apiv2.Cluster_DiscoveryType
 	}
 }
 
+// dnsDiscoveryType returns the discovery type of a cluster with the given endpoints. STRICT_DNS clusters
+// retaining their endpoints on DNS failure use LOGICAL_DNS, which keeps the last resolved address when
+// a resolution fails. LOGICAL_DNS requires a single endpoint, so the others keep using STRICT_DNS.
+func dnsDiscoveryType(discoveryType apiv2.Cluster_DiscoveryType, retainOnFailure bool,
+	localityLbEndpoints []*endpoint.LocalityLbEndpoints) apiv2.Cluster_DiscoveryType {
+	if discoveryType != apiv2.Cluster_STRICT_DNS || !retainOnFailure {
+		return discoveryType
+	}
+	endpoints := 0
+	for _, locLbEps := range localityLbEndpoints {
+		endpoints += len(locLbEps.LbEndpoints)
+	}
+	if endpoints != 1 {
+		return discoveryType
+	}
+	return apiv2.Cluster_LOGICAL_DNS
+}
+
 // conditionallyConvertToIstioMtls fills key cert fields for all TLSSettings when the mode is `ISTIO_MUTUAL`.
 func conditionallyConvertToIstioMtls(
 	tls *networking.TLSSettings,
@@ -761,6 +867,10 @@ type buildClusterOpts struct {
 	clusterMode     ClusterMode
 	direction       model.TrafficDirection
 	proxy           *model.Proxy
+	// credentialName is the secret holding the client certificate of MUTUAL TLS settings, if any
+	credentialName string
+	// http2Options are the HTTP/2 settings of the destination rule, if any
+	http2Options *model.HTTP2Options
 }
 
 func applyTrafficPolicy(opts buildClusterOpts, proxy *model.Proxy) {
@@ -769,9 +879,10 @@ func applyTrafficPolicy(opts buildClusterOpts, proxy *model.Proxy) {
 	applyConnectionPool(opts.env, opts.cluster, connectionPool, opts.direction)
 	applyOutlierDetection(opts.cluster, outlierDetection)
 	applyLoadBalancer(opts.cluster, loadBalancer, opts.port, proxy)
+	applyHTTP2Options(opts.cluster.Http2ProtocolOptions, opts.http2Options)
 	if opts.clusterMode != SniDnatClusterMode {
 		tls = conditionallyConvertToIstioMtls(tls, opts.serviceAccounts, opts.sni, opts.proxy)
-		applyUpstreamTLSSettings(opts.env, opts.cluster, tls, opts.proxy.Metadata)
+		applyUpstreamTLSSettings(opts.env, opts.cluster, tls, opts.proxy.Metadata, opts.credentialName)
 	}
 }
 
@@ -781,17 +892,25 @@ func applyConnectionPool(env *model.Environment, cluster *apiv2.Cluster, setting
 		return
 	}
 
-	threshold := getDefaultCircuitBreakerThresholds(direction)
+	// Most clusters keep the default thresholds, so they share them and only the clusters
+	// overriding a threshold get their own copy.
+	var threshold *v2Cluster.CircuitBreakers_Thresholds
+	mutableThreshold := func() *v2Cluster.CircuitBreakers_Thresholds {
+		if threshold == nil {
+			threshold = getDefaultCircuitBreakerThresholds(direction)
+		}
+		return threshold
+	}
 	var idleTimeout *types.Duration
 
 	if settings.Http != nil {
 		if settings.Http.Http2MaxRequests > 0 {
 			// Envoy only applies MaxRequests in HTTP/2 clusters
-			threshold.MaxRequests = &wrappers.UInt32Value{Value: uint32(settings.Http.Http2MaxRequests)}
+			mutableThreshold().MaxRequests = &wrappers.UInt32Value{Value: uint32(settings.Http.Http2MaxRequests)}
 		}
 		if settings.Http.Http1MaxPendingRequests > 0 {
 			// Envoy only applies MaxPendingRequests in HTTP/1.1 clusters
-			threshold.MaxPendingRequests = &wrappers.UInt32Value{Value: uint32(settings.Http.Http1MaxPendingRequests)}
+			mutableThreshold().MaxPendingRequests = &wrappers.UInt32Value{Value: uint32(settings.Http.Http1MaxPendingRequests)}
 		}
 
 		if settings.Http.MaxRequestsPerConnection > 0 {
@@ -800,7 +919,7 @@ func applyConnectionPool(env *model.Environment, cluster *apiv2.Cluster, setting
 
 		// FIXME: zero is a valid value if explicitly set, otherwise we want to use the default
 		if settings.Http.MaxRetries > 0 {
-			threshold.MaxRetries = &wrappers.UInt32Value{Value: uint32(settings.Http.MaxRetries)}
+			mutableThreshold().MaxRetries = &wrappers.UInt32Value{Value: uint32(settings.Http.MaxRetries)}
 		}
 
 		idleTimeout = settings.Http.IdleTimeout
@@ -812,14 +931,18 @@ func applyConnectionPool(env *model.Environment, cluster *apiv2.Cluster, setting
 		}
 
 		if settings.Tcp.MaxConnections > 0 {
-			threshold.MaxConnections = &wrappers.UInt32Value{Value: uint32(settings.Tcp.MaxConnections)}
+			mutableThreshold().MaxConnections = &wrappers.UInt32Value{Value: uint32(settings.Tcp.MaxConnections)}
 		}
 
 		applyTCPKeepalive(env, cluster, settings)
 	}
 
-	cluster.CircuitBreakers = &v2Cluster.CircuitBreakers{
-		Thresholds: []*v2Cluster.CircuitBreakers_Thresholds{threshold},
+	if threshold == nil {
+		cluster.CircuitBreakers = getDefaultCircuitBreakers(direction)
+	} else {
+		cluster.CircuitBreakers = &v2Cluster.CircuitBreakers{
+			Thresholds: []*v2Cluster.CircuitBreakers_Thresholds{threshold, getHighPriorityCircuitBreakerThresholds(direction)},
+		}
 	}
 
 	if idleTimeout != nil {
@@ -980,6 +1103,8 @@ func applyLoadBalancer(cluster *apiv2.Cluster, lb *networking.LoadBalancerSettin
 
 func applyLocalityLBSetting(
 	locality *core.Locality,
+	clusterID string,
+	network string,
 	clusters []*apiv2.Cluster,
 	localityLB *meshconfig.LocalityLoadBalancerSetting,
 ) {
@@ -990,16 +1115,23 @@ func applyLocalityLBSetting(
 		// Failover should only be applied with outlier detection, or traffic will never failover.
 		enabledFailover := cluster.OutlierDetection != nil
 		if cluster.LoadAssignment != nil {
-			loadbalancer.ApplyLocalityLBSetting(locality, cluster.LoadAssignment, localityLB, enabledFailover)
+			loadbalancer.ApplyLocalityLBSetting(locality, clusterID, network, cluster.LoadAssignment, localityLB, enabledFailover)
 		}
 	}
 }
 
-func applyUpstreamTLSSettings(env *model.Environment, cluster *apiv2.Cluster, tls *networking.TLSSettings, metadata map[string]string) {
+func applyUpstreamTLSSettings(env *model.Environment, cluster *apiv2.Cluster, tls *networking.TLSSettings,
+	metadata map[string]string, credentialName string) {
 	if tls == nil {
 		return
 	}
 
+	// Gateways with an SDS agent fetch the client certificate of MUTUAL TLS settings from a secret.
+	if tls.Mode == networking.TLSSettings_MUTUAL && credentialName != "" && isGatewaySdsEnabled(metadata) {
+		applyUpstreamTLSSettingsFromCredential(cluster, tls, credentialName)
+		return
+	}
+
 	certValidationContext := &auth.CertificateValidationContext{}
 	var trustedCa *core.DataSource
 	if len(tls.CaCertificates) != 0 {
@@ -1096,6 +1228,35 @@ func applyUpstreamTLSSettings(env *model.Environment, cluster *apiv2.Cluster, tl
 	}
 }
 
+// applyUpstreamTLSSettingsFromCredential configures the gateway SDS agent to provide the client
+// certificate and the CA certificate of MUTUAL TLS settings from the credentialName secret.
+func applyUpstreamTLSSettingsFromCredential(cluster *apiv2.Cluster, tls *networking.TLSSettings, credentialName string) {
+	cluster.TlsContext = &auth.UpstreamTlsContext{
+		CommonTlsContext: &auth.CommonTlsContext{
+			TlsCertificateSdsSecretConfigs: []*auth.SdsSecretConfig{
+				authn_model.ConstructSdsSecretConfigForGatewayListener(credentialName, authn_model.IngressGatewaySdsUdsPath),
+			},
+			ValidationContextType: &auth.CommonTlsContext_CombinedValidationContext{
+				CombinedValidationContext: &auth.CommonTlsContext_CombinedCertificateValidationContext{
+					DefaultValidationContext: &auth.CertificateValidationContext{VerifySubjectAltName: tls.SubjectAltNames},
+					ValidationContextSdsSecretConfig: authn_model.ConstructSdsSecretConfigForGatewayListener(
+						credentialName+authn_model.IngressGatewaySdsCaSuffix, authn_model.IngressGatewaySdsUdsPath),
+				},
+			},
+		},
+		Sni: tls.Sni,
+	}
+	if cluster.Http2ProtocolOptions != nil {
+		cluster.TlsContext.CommonTlsContext.AlpnProtocols = util.ALPNH2Only
+	}
+}
+
+// isGatewaySdsEnabled returns true for the gateways running an SDS agent, which send the USER_SDS metadata.
+func isGatewaySdsEnabled(metadata map[string]string) bool {
+	enabled, _ := strconv.ParseBool(metadata["USER_SDS"])
+	return enabled
+}
+
 func setUpstreamProtocol(cluster *apiv2.Cluster, port *model.Port) {
 	if port.Protocol.IsHTTP2() {
 		cluster.Http2ProtocolOptions = &core.Http2ProtocolOptions{
@@ -1107,6 +1268,34 @@ func setUpstreamProtocol(cluster *apiv2.Cluster, port *model.Port) {
 	}
 }
 
+// applyProperCaseHeaders makes the HTTP/1 clusters capitalize the header names they send, as set by the
+// HTTP1ProperCaseHeadersAnnotation. HTTP/2 header names are always lowercase.
+func applyProperCaseHeaders(cluster *apiv2.Cluster, port *model.Port) {
+	if port.Protocol.IsHTTP() && !port.Protocol.IsHTTP2() {
+		cluster.HttpProtocolOptions = &core.Http1ProtocolOptions{HeaderKeyFormat: properCaseHeaderKeyFormat()}
+	}
+}
+
+// applyHTTP2Options overrides the HTTP/2 protocol options with the settings of the HTTP2OptionsAnnotation.
+// Nothing is done for the clusters and listeners not using HTTP/2.
+func applyHTTP2Options(protocolOptions *core.Http2ProtocolOptions, opts *model.HTTP2Options) {
+	if protocolOptions == nil || opts == nil {
+		return
+	}
+	if opts.MaxConcurrentStreams > 0 {
+		protocolOptions.MaxConcurrentStreams = &wrappers.UInt32Value{Value: opts.MaxConcurrentStreams}
+	}
+	if opts.InitialStreamWindowSize > 0 {
+		protocolOptions.InitialStreamWindowSize = &wrappers.UInt32Value{Value: opts.InitialStreamWindowSize}
+	}
+	if opts.InitialConnectionWindowSize > 0 {
+		protocolOptions.InitialConnectionWindowSize = &wrappers.UInt32Value{Value: opts.InitialConnectionWindowSize}
+	}
+	if opts.AllowConnect {
+		protocolOptions.AllowConnect = true
+	}
+}
+
 // generates a cluster that sends traffic to dummy localport 0
 // This cluster is used to catch all traffic to unresolved destinations in virtual service
 func buildBlackHoleCluster(env *model.Environment) *apiv2.Cluster {
@@ -1139,6 +1328,35 @@ func buildDefaultPassthroughCluster(env *model.Environment, proxy *model.Proxy)
 	return cluster
 }
 
+// generates a cluster that sends traffic to the host requested, resolved at request time.
+// This cluster is used for the external hosts allowed through the dynamic forward proxy
+func buildDynamicForwardProxyCluster(env *model.Environment, proxy *model.Proxy) *apiv2.Cluster {
+	cluster := &apiv2.Cluster{
+		Name: util.DynamicForwardProxyCluster,
+		ClusterDiscoveryType: &apiv2.Cluster_ClusterType{
+			ClusterType: &apiv2.Cluster_CustomClusterType{
+				Name: dynamicForwardProxyClusterType,
+				TypedConfig: util.MessageToAny(&dfpcluster.ClusterConfig{
+					DnsCacheConfig: buildDynamicForwardProxyDNSCacheConfig(env),
+				}),
+			},
+		},
+		ConnectTimeout: gogo.DurationToProtoDuration(env.Mesh.ConnectTimeout),
+		LbPolicy:       lbPolicyClusterProvided(proxy),
+	}
+	return cluster
+}
+
+// buildDynamicForwardProxyDNSCacheConfig returns the DNS cache configuration, which must be identical
+// in the dynamic forward proxy cluster and HTTP filter.
+func buildDynamicForwardProxyDNSCacheConfig(env *model.Environment) *dfpcommon.DnsCacheConfig {
+	return &dfpcommon.DnsCacheConfig{
+		Name:            dynamicForwardProxyDNSCache,
+		DnsLookupFamily: apiv2.Cluster_V4_ONLY,
+		DnsRefreshRate:  gogo.DurationToProtoDuration(env.Mesh.DnsRefreshRate),
+	}
+}
+
 func buildDefaultCluster(env *model.Environment, name string, discoveryType apiv2.Cluster_DiscoveryType,
 	localityLbEndpoints []*endpoint.LocalityLbEndpoints, direction model.TrafficDirection, proxy *model.Proxy, port *model.Port) *apiv2.Cluster {
 	cluster := &apiv2.Cluster{
@@ -1146,7 +1364,7 @@ func buildDefaultCluster(env *model.Environment, name string, discoveryType apiv
 		ClusterDiscoveryType: &apiv2.Cluster_Type{Type: discoveryType},
 	}
 
-	if discoveryType == apiv2.Cluster_STRICT_DNS {
+	if discoveryType == apiv2.Cluster_STRICT_DNS || discoveryType == apiv2.Cluster_LOGICAL_DNS {
 		cluster.DnsLookupFamily = apiv2.Cluster_V4_ONLY
 		dnsRate := gogo.DurationToProtoDuration(env.Mesh.DnsRefreshRate)
 		cluster.DnsRefreshRate = dnsRate
@@ -1155,7 +1373,7 @@ func buildDefaultCluster(env *model.Environment, name string, discoveryType apiv
 		}
 	}
 
-	if discoveryType == apiv2.Cluster_STATIC || discoveryType == apiv2.Cluster_STRICT_DNS {
+	if discoveryType == apiv2.Cluster_STATIC || discoveryType == apiv2.Cluster_STRICT_DNS || discoveryType == apiv2.Cluster_LOGICAL_DNS {
 		cluster.LoadAssignment = &apiv2.ClusterLoadAssignment{
 			ClusterName: name,
 			Endpoints:   localityLbEndpoints,
@@ -28,6 +28,8 @@ import (
 
 	apiv2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
+	dfpcluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/dynamic_forward_proxy/v2alpha"
 	"github.com/gogo/protobuf/proto"
 	"github.com/gogo/protobuf/types"
 	. "github.com/onsi/gomega"
@@ -108,8 +110,9 @@ func TestHTTPCircuitBreakerThresholds(t *testing.T) {
 				g.Expect(err).NotTo(HaveOccurred())
 				g.Expect(len(clusters)).To(Equal(6))
 				cluster := clusters[directionInfo.clusterIndex]
-				g.Expect(len(cluster.CircuitBreakers.Thresholds)).To(Equal(1))
+				g.Expect(len(cluster.CircuitBreakers.Thresholds)).To(Equal(2))
 				thresholds := cluster.CircuitBreakers.Thresholds[0]
+				g.Expect(cluster.CircuitBreakers.Thresholds[1]).To(Equal(getHighPriorityCircuitBreakerThresholds(directionInfo.direction)))
 
 				if s == nil {
 					// Assume the correct defaults for this direction.
@@ -757,6 +760,33 @@ func TestConditionallyConvertToIstioMtls(t *testing.T) {
 	}
 }
 
+func TestApplyUpstreamTLSSettingsFromCredential(t *testing.T) {
+	g := NewGomegaWithT(t)
+	env := &model.Environment{Mesh: &meshconfig.MeshConfig{}}
+	tls := &networking.TLSSettings{
+		Mode:            networking.TLSSettings_MUTUAL,
+		SubjectAltNames: []string{"partner.example.com"},
+		Sni:             "partner.example.com",
+	}
+
+	// Without an SDS agent, the certificate file paths are required.
+	cluster := &apiv2.Cluster{Name: "outbound|443||partner.example.com"}
+	applyUpstreamTLSSettings(env, cluster, tls, map[string]string{}, "partner-credential")
+	g.Expect(cluster.TlsContext).To(BeNil())
+
+	cluster = &apiv2.Cluster{Name: "outbound|443||partner.example.com"}
+	applyUpstreamTLSSettings(env, cluster, tls, map[string]string{"USER_SDS": "true"}, "partner-credential")
+	g.Expect(cluster.TlsContext).NotTo(BeNil())
+	g.Expect(cluster.TlsContext.Sni).To(Equal("partner.example.com"))
+	common := cluster.TlsContext.CommonTlsContext
+	g.Expect(common.TlsCertificateSdsSecretConfigs).To(HaveLen(1))
+	g.Expect(common.TlsCertificateSdsSecretConfigs[0].Name).To(Equal("partner-credential"))
+	validation := common.GetCombinedValidationContext()
+	g.Expect(validation).NotTo(BeNil())
+	g.Expect(validation.ValidationContextSdsSecretConfig.Name).To(Equal("partner-credential-cacert"))
+	g.Expect(validation.DefaultValidationContext.VerifySubjectAltName).To(Equal([]string{"partner.example.com"}))
+}
+
 func TestDisablePanicThresholdAsDefault(t *testing.T) {
 	g := NewGomegaWithT(t)
 
@@ -1261,3 +1291,127 @@ func TestPassthroughClustersBuildUponProxyIpVersions(t *testing.T) {
 		g.Expect(validation(clusters)).To(Equal(inAndOut.features))
 	}
 }
+
+func TestBuildDynamicForwardProxyCluster(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	env := newTestEnvironment(&fakes.ServiceDiscovery{}, testMesh, &fakes.IstioConfigStore{})
+	proxy := &model.Proxy{Metadata: map[string]string{}, IstioVersion: model.MaxIstioVersion}
+
+	cluster := buildDynamicForwardProxyCluster(env, proxy)
+	g.Expect(cluster.Name).To(Equal(util.DynamicForwardProxyCluster))
+	g.Expect(cluster.LbPolicy).To(Equal(apiv2.Cluster_CLUSTER_PROVIDED))
+	clusterType, ok := cluster.ClusterDiscoveryType.(*apiv2.Cluster_ClusterType)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(clusterType.ClusterType.Name).To(Equal(dynamicForwardProxyClusterType))
+
+	config := &dfpcluster.ClusterConfig{}
+	g.Expect(ptypes.UnmarshalAny(clusterType.ClusterType.TypedConfig, config)).To(Succeed())
+	g.Expect(config.DnsCacheConfig.Name).To(Equal(dynamicForwardProxyDNSCache))
+	g.Expect(config.DnsCacheConfig.DnsLookupFamily).To(Equal(apiv2.Cluster_V4_ONLY))
+}
+
+func TestDefaultCircuitBreakersShared(t *testing.T) {
+	g := NewGomegaWithT(t)
+	clusters, err := buildTestClusters("*.example.org", 0, model.SidecarProxy, nil, testMesh,
+		&networking.DestinationRule{
+			Host: "*.example.org",
+			Subsets: []*networking.Subset{
+				{
+					Name: "override",
+					TrafficPolicy: &networking.TrafficPolicy{
+						ConnectionPool: &networking.ConnectionPoolSettings{
+							Tcp: &networking.ConnectionPoolSettings_TCPSettings{MaxConnections: 10},
+						},
+					},
+				},
+			},
+		})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	defaultCluster := clusters[0]
+	g.Expect(defaultCluster.CircuitBreakers).To(BeIdenticalTo(getDefaultCircuitBreakers(model.TrafficDirectionOutbound)))
+
+	var subsetCluster *apiv2.Cluster
+	for _, c := range clusters {
+		if strings.Contains(c.Name, "|override|") {
+			subsetCluster = c
+		}
+	}
+	g.Expect(subsetCluster).NotTo(BeNil())
+	g.Expect(subsetCluster.CircuitBreakers).NotTo(BeIdenticalTo(getDefaultCircuitBreakers(model.TrafficDirectionOutbound)))
+	g.Expect(subsetCluster.CircuitBreakers.Thresholds[0].MaxConnections.Value).To(Equal(uint32(10)))
+
+	// The override must not leak into the shared defaults, nor limit the high priority requests.
+	g.Expect(defaultOutboundCircuitBreakerThresholds.MaxConnections).To(BeNil())
+	high := subsetCluster.CircuitBreakers.Thresholds[1]
+	g.Expect(high.Priority).To(Equal(core.RoutingPriority_HIGH))
+	g.Expect(high.MaxConnections).To(BeNil())
+	g.Expect(high.MaxRetries.GetValue()).To(Equal(uint32(1024)))
+}
+
+func TestApplyProperCaseHeaders(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cluster := &apiv2.Cluster{}
+	applyProperCaseHeaders(cluster, &model.Port{Port: 8080, Protocol: protocol.HTTP})
+	g.Expect(cluster.HttpProtocolOptions.GetHeaderKeyFormat().GetProperCaseWords()).NotTo(BeNil())
+
+	// HTTP/2 header names are always lowercase.
+	cluster = &apiv2.Cluster{}
+	applyProperCaseHeaders(cluster, &model.Port{Port: 8080, Protocol: protocol.GRPC})
+	g.Expect(cluster.HttpProtocolOptions).To(BeNil())
+
+	cluster = &apiv2.Cluster{}
+	applyProperCaseHeaders(cluster, &model.Port{Port: 8080, Protocol: protocol.TCP})
+	g.Expect(cluster.HttpProtocolOptions).To(BeNil())
+}
+
+func TestApplyHTTP2Options(t *testing.T) {
+	g := NewGomegaWithT(t)
+	opts := &model.HTTP2Options{MaxConcurrentStreams: 100, InitialConnectionWindowSize: 1048576, AllowConnect: true}
+
+	cluster := &apiv2.Cluster{}
+	setUpstreamProtocol(cluster, &model.Port{Port: 8080, Protocol: protocol.GRPC})
+	applyHTTP2Options(cluster.Http2ProtocolOptions, opts)
+	g.Expect(cluster.Http2ProtocolOptions.MaxConcurrentStreams.GetValue()).To(Equal(uint32(100)))
+	g.Expect(cluster.Http2ProtocolOptions.InitialConnectionWindowSize.GetValue()).To(Equal(uint32(1048576)))
+	g.Expect(cluster.Http2ProtocolOptions.InitialStreamWindowSize).To(BeNil())
+	g.Expect(cluster.Http2ProtocolOptions.AllowConnect).To(BeTrue())
+
+	// HTTP/1 clusters are left alone.
+	cluster = &apiv2.Cluster{}
+	setUpstreamProtocol(cluster, &model.Port{Port: 8080, Protocol: protocol.HTTP})
+	applyHTTP2Options(cluster.Http2ProtocolOptions, opts)
+	g.Expect(cluster.Http2ProtocolOptions).To(BeNil())
+}
+
+func TestDNSDiscoveryType(t *testing.T) {
+	g := NewGomegaWithT(t)
+	one := []*endpoint.LocalityLbEndpoints{{LbEndpoints: []*endpoint.LbEndpoint{{}}}}
+	two := []*endpoint.LocalityLbEndpoints{{LbEndpoints: []*endpoint.LbEndpoint{{}}}, {LbEndpoints: []*endpoint.LbEndpoint{{}}}}
+
+	g.Expect(dnsDiscoveryType(apiv2.Cluster_STRICT_DNS, true, one)).To(Equal(apiv2.Cluster_LOGICAL_DNS))
+	g.Expect(dnsDiscoveryType(apiv2.Cluster_STRICT_DNS, false, one)).To(Equal(apiv2.Cluster_STRICT_DNS))
+	// LOGICAL_DNS clusters must have exactly one endpoint.
+	g.Expect(dnsDiscoveryType(apiv2.Cluster_STRICT_DNS, true, two)).To(Equal(apiv2.Cluster_STRICT_DNS))
+	g.Expect(dnsDiscoveryType(apiv2.Cluster_STRICT_DNS, true, nil)).To(Equal(apiv2.Cluster_STRICT_DNS))
+	g.Expect(dnsDiscoveryType(apiv2.Cluster_EDS, true, one)).To(Equal(apiv2.Cluster_EDS))
+}
+
+func BenchmarkBuildClusters(b *testing.B) {
+	destRule := &networking.DestinationRule{
+		Host: "*.example.org",
+		TrafficPolicy: &networking.TrafficPolicy{
+			ConnectionPool: &networking.ConnectionPoolSettings{
+				Tcp: &networking.ConnectionPoolSettings_TCPSettings{ConnectTimeout: &types.Duration{Seconds: 1}},
+			},
+		},
+		Subsets: []*networking.Subset{{Name: "v1"}, {Name: "v2"}, {Name: "v3"}},
+	}
+	for n := 0; n < b.N; n++ {
+		if _, err := buildTestClusters("*.example.org", 0, model.SidecarProxy, nil, testMesh, destRule); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -47,7 +47,11 @@ func ApplyClusterPatches(patchContext networking.EnvoyFilter_PatchContext, proxy
 						clusters[i] = nil
 						clustersRemoved = true
 					} else {
-						proto.Merge(clusters[i], cp.Value)
+						// Generated clusters share immutable sub-messages with each other,
+						// so merge into a private copy.
+						merged := proto.Clone(clusters[i]).(*xdsapi.Cluster)
+						proto.Merge(merged, cp.Value)
+						clusters[i] = merged
 					}
 				}
 			}
@@ -0,0 +1,60 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+
+	"istio.io/istio/pkg/config/security"
+)
+
+// applyFIPSToClusters restricts the upstream TLS contexts of the clusters to FIPS approved settings.
+func applyFIPSToClusters(clusters []*xdsapi.Cluster) {
+	for _, c := range clusters {
+		if c.TlsContext != nil {
+			applyFIPSTLSParams(c.TlsContext.CommonTlsContext)
+		}
+	}
+}
+
+// applyFIPSToListeners restricts the downstream TLS contexts of the listeners to FIPS approved settings.
+func applyFIPSToListeners(listeners []*xdsapi.Listener) {
+	for _, l := range listeners {
+		for _, fc := range l.FilterChains {
+			if fc.TlsContext != nil {
+				applyFIPSTLSParams(fc.TlsContext.CommonTlsContext)
+			}
+		}
+	}
+}
+
+// applyFIPSTLSParams restricts a TLS context to TLS 1.2 and to the FIPS approved cipher suites and
+// curves. Cipher suites chosen by the user are kept, validation only accepts approved ones.
+func applyFIPSTLSParams(ctx *auth.CommonTlsContext) {
+	if ctx == nil {
+		return
+	}
+	if ctx.TlsParams == nil {
+		ctx.TlsParams = &auth.TlsParameters{}
+	}
+	params := ctx.TlsParams
+	params.TlsMinimumProtocolVersion = auth.TlsParameters_TLSv1_2
+	params.TlsMaximumProtocolVersion = auth.TlsParameters_TLSv1_2
+	if len(params.CipherSuites) == 0 || security.ValidateFIPSCipherSuites(params.CipherSuites) != nil {
+		params.CipherSuites = security.FIPSCipherSuites
+	}
+	params.EcdhCurves = security.FIPSEcdhCurves
+}
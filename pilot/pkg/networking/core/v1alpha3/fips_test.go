@@ -0,0 +1,77 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"reflect"
+	"testing"
+
+	auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+
+	"istio.io/istio/pkg/config/security"
+)
+
+func TestApplyFIPSTLSParams(t *testing.T) {
+	cases := []struct {
+		name   string
+		params *auth.TlsParameters
+		want   *auth.TlsParameters
+	}{
+		{
+			name:   "defaults",
+			params: nil,
+			want: &auth.TlsParameters{
+				TlsMinimumProtocolVersion: auth.TlsParameters_TLSv1_2,
+				TlsMaximumProtocolVersion: auth.TlsParameters_TLSv1_2,
+				CipherSuites:              security.FIPSCipherSuites,
+				EcdhCurves:                security.FIPSEcdhCurves,
+			},
+		},
+		{
+			name: "approved user cipher suites are kept",
+			params: &auth.TlsParameters{
+				TlsMinimumProtocolVersion: auth.TlsParameters_TLSv1_0,
+				CipherSuites:              []string{"ECDHE-RSA-AES256-GCM-SHA384"},
+			},
+			want: &auth.TlsParameters{
+				TlsMinimumProtocolVersion: auth.TlsParameters_TLSv1_2,
+				TlsMaximumProtocolVersion: auth.TlsParameters_TLSv1_2,
+				CipherSuites:              []string{"ECDHE-RSA-AES256-GCM-SHA384"},
+				EcdhCurves:                security.FIPSEcdhCurves,
+			},
+		},
+		{
+			name: "other cipher suites are replaced",
+			params: &auth.TlsParameters{
+				CipherSuites: []string{"AES128-SHA"},
+			},
+			want: &auth.TlsParameters{
+				TlsMinimumProtocolVersion: auth.TlsParameters_TLSv1_2,
+				TlsMaximumProtocolVersion: auth.TlsParameters_TLSv1_2,
+				CipherSuites:              security.FIPSCipherSuites,
+				EcdhCurves:                security.FIPSEcdhCurves,
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := &auth.CommonTlsContext{TlsParams: c.params}
+			applyFIPSTLSParams(ctx)
+			if !reflect.DeepEqual(ctx.TlsParams, c.want) {
+				t.Errorf("got %v, want %v", ctx.TlsParams, c.want)
+			}
+		})
+	}
+}
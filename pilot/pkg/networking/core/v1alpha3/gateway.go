@@ -16,9 +16,11 @@ package v1alpha3
 
 import (
 	"fmt"
+	"net/http"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
@@ -26,6 +28,8 @@ import (
 	listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
 	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
 	http_conn "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/hashicorp/go-multierror"
 
 	networking "istio.io/api/networking/v1alpha3"
@@ -238,8 +242,16 @@ func (configgen *ConfigGeneratorImpl) buildGatewayHTTPRouteConfig(env *model.Env
 	}
 
 	vHostDedupMap := make(map[host.Name]*route.VirtualHost)
+	redirectExemptPaths := make(map[host.Name][]string)
+	// The unknown hosts are rejected only if every gateway sharing the route opts in, so that the
+	// annotation of a gateway does not change how the port serves the hosts of the others.
+	rejectUnknownHosts := true
 	for _, server := range servers {
 		gatewayName := merged.GatewayNameForServer[server]
+		hostValidation := merged.HostValidationForServer[server]
+		if hostValidation == nil || !hostValidation.RejectUnknownHosts {
+			rejectUnknownHosts = false
+		}
 		virtualServices := push.VirtualServices(node, map[string]bool{gatewayName: true})
 		for _, virtualService := range virtualServices {
 			virtualServiceHosts := host.NewNames(virtualService.Spec.(*networking.VirtualService).Hosts)
@@ -268,8 +280,19 @@ func (configgen *ConfigGeneratorImpl) buildGatewayHTTPRouteConfig(env *model.Env
 						Domains: []string{string(hostname), fmt.Sprintf("%s:%d", hostname, port)},
 						Routes:  routes,
 					}
+					// Envoy cannot strip the port of the Host before routing, match any port instead.
+					if hostValidation != nil && hostValidation.IgnorePort && !strings.HasPrefix(string(hostname), "*") {
+						newVHost.Domains = append(newVHost.Domains, string(hostname)+":*")
+					}
+					if headers := merged.HeadersForServer[server]; headers != nil {
+						istio_route.ApplyVirtualHostHeaders(newVHost, headers)
+					}
 					if server.Tls != nil && server.Tls.HttpsRedirect {
-						newVHost.RequireTls = route.VirtualHost_ALL
+						if exemptPaths := merged.HTTPSRedirectExemptPaths[server]; len(exemptPaths) > 0 {
+							redirectExemptPaths[hostname] = exemptPaths
+						} else {
+							newVHost.RequireTls = route.VirtualHost_ALL
+						}
 					}
 					vHostDedupMap[hostname] = newVHost
 				}
@@ -277,6 +300,14 @@ func (configgen *ConfigGeneratorImpl) buildGatewayHTTPRouteConfig(env *model.Env
 		}
 	}
 
+	for hostname, exemptPaths := range redirectExemptPaths {
+		vHost := vHostDedupMap[hostname]
+		vHost.Routes = buildHTTPSRedirectRoutes(vHost.Routes, exemptPaths)
+	}
+	for _, vHost := range vHostDedupMap {
+		istio_route.PrependRejectAmbiguousPathRoute(node, vHost)
+	}
+
 	var virtualHosts []*route.VirtualHost
 	if len(vHostDedupMap) == 0 {
 		log.Warnf("constructed http route config for port %d with no vhosts; Setting up a default 404 vhost", port)
@@ -305,6 +336,9 @@ func (configgen *ConfigGeneratorImpl) buildGatewayHTTPRouteConfig(env *model.Env
 		for _, v := range vHostDedupMap {
 			virtualHosts = append(virtualHosts, v)
 		}
+		if _, servesAllHosts := vHostDedupMap[host.Name("*")]; rejectUnknownHosts && !servesAllHosts {
+			virtualHosts = append(virtualHosts, buildRejectUnknownHostsVirtualHost(node, port))
+		}
 	}
 
 	util.SortVirtualHosts(virtualHosts)
@@ -332,6 +366,70 @@ func (configgen *ConfigGeneratorImpl) buildGatewayHTTPRouteConfig(env *model.Env
 	return routeCfg
 }
 
+// buildHTTPSRedirectRoutes redirects the requests to HTTPS, except for the requests under the exempted
+// path prefixes, which are routed as usual. Routes matching a regex cannot be restricted to a prefix,
+// so they only apply to redirected requests.
+func buildHTTPSRedirectRoutes(routes []*route.Route, exemptPaths []string) []*route.Route {
+	out := make([]*route.Route, 0, len(routes)*len(exemptPaths)+1)
+	for _, exemptPath := range exemptPaths {
+		for _, r := range routes {
+			match := *r.Match
+			switch m := r.Match.GetPathSpecifier().(type) {
+			case *route.RouteMatch_Prefix:
+				if strings.HasPrefix(exemptPath, m.Prefix) {
+					match.PathSpecifier = &route.RouteMatch_Prefix{Prefix: exemptPath}
+				} else if !strings.HasPrefix(m.Prefix, exemptPath) {
+					continue
+				}
+			case *route.RouteMatch_Path:
+				if !strings.HasPrefix(m.Path, exemptPath) {
+					continue
+				}
+			default:
+				continue
+			}
+			exempted := *r
+			exempted.Match = &match
+			out = append(out, &exempted)
+		}
+	}
+	return append(out, &route.Route{
+		Match: &route.RouteMatch{
+			PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"},
+		},
+		Action: &route.Route_Redirect{
+			Redirect: &route.RedirectAction{
+				SchemeRewriteSpecifier: &route.RedirectAction_HttpsRedirect{HttpsRedirect: true},
+			},
+		},
+	})
+}
+
+// buildRejectUnknownHostsVirtualHost builds the virtual host answering the requests whose Host matches
+// none of the other virtual hosts with a 421 (Misdirected Request).
+func buildRejectUnknownHostsVirtualHost(node *model.Proxy, port int) *route.VirtualHost {
+	vHost := &route.VirtualHost{
+		Name:    fmt.Sprintf("reject:%d", port),
+		Domains: []string{"*"},
+		Routes: []*route.Route{
+			{
+				Match: &route.RouteMatch{
+					PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"},
+				},
+				Action: &route.Route_DirectResponse{
+					DirectResponse: &route.DirectResponseAction{
+						Status: http.StatusMisdirectedRequest,
+					},
+				},
+			},
+		},
+	}
+	if util.IsIstioVersionGE13(node) {
+		vHost.Routes[0].Name = istio_route.DefaultRouteName
+	}
+	return vHost
+}
+
 // builds a HTTP connection manager for servers of type HTTP or HTTPS (mode: simple/mutual)
 func (configgen *ConfigGeneratorImpl) createGatewayHTTPFilterChainOpts(
 	node *model.Proxy, server *networking.Server, routeName string, sdsPath string) *filterChainOpts {
@@ -344,6 +442,37 @@ func (configgen *ConfigGeneratorImpl) createGatewayHTTPFilterChainOpts(
 		httpProtoOpts.AcceptHttp_10 = true
 	}
 
+	// Limit the streams a single downstream connection can open, so that a client cannot exhaust
+	// a gateway shared by several tenants.
+	var http2ProtoOpts *core.Http2ProtocolOptions
+	if limit, err := strconv.ParseUint(node.Metadata[model.NodeMetadataMaxConcurrentStreams], 10, 32); err == nil && limit > 0 {
+		http2ProtoOpts = &core.Http2ProtocolOptions{MaxConcurrentStreams: &wrappers.UInt32Value{Value: uint32(limit)}}
+	}
+
+	var accessLog *model.GatewayAccessLog
+	var serverHeader *model.GatewayServerHeader
+	var compression *model.CompressionPolicy
+	var responseCache *model.GatewayResponseCache
+	var maxRequestBodyBytes uint32
+	if node.MergedGateway != nil {
+		accessLog = node.MergedGateway.AccessLogForServer[server]
+		serverHeader = node.MergedGateway.ServerHeaderForServer[server]
+		compression = node.MergedGateway.CompressionForServer[server]
+		responseCache = node.MergedGateway.ResponseCacheForServer[server]
+		maxRequestBodyBytes = node.MergedGateway.MaxRequestBodyBytesForServer[server]
+		if node.MergedGateway.ProperCaseHeadersForServer[server] {
+			httpProtoOpts.HeaderKeyFormat = properCaseHeaderKeyFormat()
+		}
+		if http2Options := node.MergedGateway.HTTP2OptionsForServer[server]; http2Options != nil {
+			if http2ProtoOpts == nil {
+				http2ProtoOpts = &core.Http2ProtocolOptions{}
+			}
+			applyHTTP2Options(http2ProtoOpts, http2Options)
+		}
+	}
+
+	forwardClientCertDetails, xffNumTrustedHops := gatewayTopology(node)
+
 	// Are we processing plaintext servers or HTTPS servers?
 	// If plain text, we have to combine all servers into a single listener
 	if serverProto.IsHTTP() {
@@ -354,20 +483,28 @@ func (configgen *ConfigGeneratorImpl) createGatewayHTTPFilterChainOpts(
 			sniHosts:   nil,
 			tlsContext: nil,
 			httpOpts: &httpListenerOpts{
-				rds:              routeName,
-				useRemoteAddress: true,
-				direction:        http_conn.HttpConnectionManager_Tracing_EGRESS, // viewed as from gateway to internal
+				rds:                 routeName,
+				useRemoteAddress:    true,
+				direction:           http_conn.HttpConnectionManager_Tracing_EGRESS, // viewed as from gateway to internal
+				accessLog:           accessLog,
+				serverHeader:        serverHeader,
+				compression:         compression,
+				responseCache:       responseCache,
+				maxRequestBodyBytes: maxRequestBodyBytes,
+				addGRPCWebFilter:    serverProto == protocol.GRPCWeb,
 				connectionManager: &http_conn.HttpConnectionManager{
 					// Forward client cert if connection is mTLS
-					ForwardClientCertDetails: http_conn.HttpConnectionManager_SANITIZE_SET,
+					ForwardClientCertDetails: forwardClientCertDetails,
 					SetCurrentClientCertDetails: &http_conn.HttpConnectionManager_SetCurrentClientCertDetails{
 						Subject: proto.BoolTrue,
 						Cert:    true,
 						Uri:     true,
 						Dns:     true,
 					},
-					ServerName:          EnvoyServerName,
-					HttpProtocolOptions: httpProtoOpts,
+					ServerName:           EnvoyServerName,
+					HttpProtocolOptions:  httpProtoOpts,
+					Http2ProtocolOptions: http2ProtoOpts,
+					XffNumTrustedHops:    xffNumTrustedHops,
 				},
 			},
 		}
@@ -389,25 +526,56 @@ func (configgen *ConfigGeneratorImpl) createGatewayHTTPFilterChainOpts(
 		sniHosts:   getSNIHostsForServer(server),
 		tlsContext: buildGatewayListenerTLSContext(server, enableIngressSdsAgent, sdsPath, node.Metadata),
 		httpOpts: &httpListenerOpts{
-			rds:              routeName,
-			useRemoteAddress: true,
-			direction:        http_conn.HttpConnectionManager_Tracing_EGRESS, // viewed as from gateway to internal
+			rds:                 routeName,
+			useRemoteAddress:    true,
+			direction:           http_conn.HttpConnectionManager_Tracing_EGRESS, // viewed as from gateway to internal
+			accessLog:           accessLog,
+			serverHeader:        serverHeader,
+			compression:         compression,
+			responseCache:       responseCache,
+			maxRequestBodyBytes: maxRequestBodyBytes,
+			addGRPCWebFilter:    serverProto == protocol.GRPCWeb,
 			connectionManager: &http_conn.HttpConnectionManager{
 				// Forward client cert if connection is mTLS
-				ForwardClientCertDetails: http_conn.HttpConnectionManager_SANITIZE_SET,
+				ForwardClientCertDetails: forwardClientCertDetails,
 				SetCurrentClientCertDetails: &http_conn.HttpConnectionManager_SetCurrentClientCertDetails{
 					Subject: proto.BoolTrue,
 					Cert:    true,
 					Uri:     true,
 					Dns:     true,
 				},
-				ServerName:          EnvoyServerName,
-				HttpProtocolOptions: httpProtoOpts,
+				ServerName:           EnvoyServerName,
+				HttpProtocolOptions:  httpProtoOpts,
+				Http2ProtocolOptions: http2ProtoOpts,
+				XffNumTrustedHops:    xffNumTrustedHops,
 			},
 		},
 	}
 }
 
+// gatewayTopology returns how the gateway handles the x-forwarded-client-cert header, and the number of
+// trusted proxies in front of it, from the metadata of the gateway.
+func gatewayTopology(node *model.Proxy) (http_conn.HttpConnectionManager_ForwardClientCertDetails, uint32) {
+	forwardClientCertDetails := http_conn.HttpConnectionManager_SANITIZE_SET
+	if value, f := node.Metadata[model.NodeMetadataForwardClientCertDetails]; f {
+		if details, ok := http_conn.HttpConnectionManager_ForwardClientCertDetails_value[value]; ok {
+			forwardClientCertDetails = http_conn.HttpConnectionManager_ForwardClientCertDetails(details)
+		} else {
+			log.Warnf("Invalid %s %q of gateway %s", model.NodeMetadataForwardClientCertDetails, value, node.ID)
+		}
+	}
+
+	var xffNumTrustedHops uint32
+	if value, f := node.Metadata[model.NodeMetadataNumTrustedProxies]; f {
+		if hops, err := strconv.ParseUint(value, 10, 32); err == nil {
+			xffNumTrustedHops = uint32(hops)
+		} else {
+			log.Warnf("Invalid %s %q of gateway %s", model.NodeMetadataNumTrustedProxies, value, node.ID)
+		}
+	}
+	return forwardClientCertDetails, xffNumTrustedHops
+}
+
 // enableIngressSds: signifies whether this is an SDS enabled ingress controller, with an embedded node agent running
 // alongside the gateway pod (https://istio.io/docs/tasks/traffic-management/ingress/secure-ingress-sds/)
 // sdsPath: is the path to the mesh-wide workload sds uds path, and it is assumed that if this path is unset, that sds is
@@ -563,9 +731,43 @@ func buildGatewayListenerTLSContext(
 		}
 	}
 
+	applyTLSSessionResumption(tls, metadata)
+
 	return tls
 }
 
+// applyTLSSessionResumption sets the session ticket keys and the session timeout of a gateway from
+// its NodeMetadataTLSSessionTicketKeys and NodeMetadataTLSSessionTimeout metadata. Without keys, each
+// Envoy generates its own, so sessions can only be resumed on the replica that established them.
+func applyTLSSessionResumption(tls *auth.DownstreamTlsContext, metadata map[string]string) {
+	if keys := metadata[model.NodeMetadataTLSSessionTicketKeys]; keys != "" {
+		ticketKeys := &auth.TlsSessionTicketKeys{}
+		for _, key := range strings.Split(keys, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				ticketKeys.Keys = append(ticketKeys.Keys, &core.DataSource{
+					Specifier: &core.DataSource_Filename{
+						Filename: key,
+					},
+				})
+			}
+		}
+		if len(ticketKeys.Keys) > 0 {
+			tls.SessionTicketKeysType = &auth.DownstreamTlsContext_SessionTicketKeys{
+				SessionTicketKeys: ticketKeys,
+			}
+		}
+	}
+
+	if timeout := metadata[model.NodeMetadataTLSSessionTimeout]; timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			log.Warnf("ignoring invalid TLS session timeout %q of gateway", timeout)
+			return
+		}
+		tls.SessionTimeout = ptypes.DurationProto(d)
+	}
+}
+
 func convertTLSProtocol(in networking.Server_TLSOptions_TLSProtocol) auth.TlsParameters_TlsProtocol {
 	out := auth.TlsParameters_TlsProtocol(in) // There should be a one-to-one enum mapping
 	if out < auth.TlsParameters_TLS_AUTO || out > auth.TlsParameters_TLSv1_3 {
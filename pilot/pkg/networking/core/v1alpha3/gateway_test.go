@@ -17,10 +17,16 @@ package v1alpha3
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
 	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
 	http_conn "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	"github.com/envoyproxy/go-control-plane/envoy/type/matcher"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/golang/protobuf/ptypes/wrappers"
 
 	networking "istio.io/api/networking/v1alpha3"
 
@@ -579,6 +585,63 @@ func TestBuildGatewayListenerTlsContext(t *testing.T) {
 	}
 }
 
+func TestBuildGatewayListenerTlsContextSessionResumption(t *testing.T) {
+	server := &networking.Server{
+		Hosts: []string{"httpbin.example.com"},
+		Tls: &networking.Server_TLSOptions{
+			Mode:              networking.Server_TLSOptions_SIMPLE,
+			ServerCertificate: "server-cert.crt",
+			PrivateKey:        "private-key.key",
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		metadata map[string]string
+		keys     []string
+		timeout  *duration.Duration
+	}{
+		{
+			name:     "no session resumption settings",
+			metadata: map[string]string{},
+		},
+		{
+			name: "shared ticket keys and timeout",
+			metadata: map[string]string{
+				pilot_model.NodeMetadataTLSSessionTicketKeys: "/etc/tickets/current, /etc/tickets/previous",
+				pilot_model.NodeMetadataTLSSessionTimeout:    "2h",
+			},
+			keys:    []string{"/etc/tickets/current", "/etc/tickets/previous"},
+			timeout: ptypes.DurationProto(2 * time.Hour),
+		},
+		{
+			name: "invalid timeout",
+			metadata: map[string]string{
+				pilot_model.NodeMetadataTLSSessionTimeout: "forever",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ret := buildGatewayListenerTLSContext(server, false, "", tc.metadata)
+
+			var keys []string
+			if ticketKeys, ok := ret.SessionTicketKeysType.(*auth.DownstreamTlsContext_SessionTicketKeys); ok {
+				for _, key := range ticketKeys.SessionTicketKeys.Keys {
+					keys = append(keys, key.GetFilename())
+				}
+			}
+			if !reflect.DeepEqual(keys, tc.keys) {
+				t.Errorf("expected session ticket keys %v, got %v", tc.keys, keys)
+			}
+			if !reflect.DeepEqual(ret.SessionTimeout, tc.timeout) {
+				t.Errorf("expected session timeout %v, got %v", tc.timeout, ret.SessionTimeout)
+			}
+		})
+	}
+}
+
 func TestCreateGatewayHTTPFilterChainOpts(t *testing.T) {
 	testCases := []struct {
 		name      string
@@ -621,6 +684,106 @@ func TestCreateGatewayHTTPFilterChainOpts(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "gateway topology",
+			node: &pilot_model.Proxy{
+				Metadata: map[string]string{
+					pilot_model.NodeMetadataNumTrustedProxies:        "2",
+					pilot_model.NodeMetadataForwardClientCertDetails: "APPEND_FORWARD",
+				},
+			},
+			server: &networking.Server{
+				Port: &networking.Port{},
+			},
+			routeName: "some-route",
+			result: &filterChainOpts{
+				sniHosts:   nil,
+				tlsContext: nil,
+				httpOpts: &httpListenerOpts{
+					rds:              "some-route",
+					useRemoteAddress: true,
+					direction:        http_conn.HttpConnectionManager_Tracing_EGRESS,
+					connectionManager: &http_conn.HttpConnectionManager{
+						ForwardClientCertDetails: http_conn.HttpConnectionManager_APPEND_FORWARD,
+						SetCurrentClientCertDetails: &http_conn.HttpConnectionManager_SetCurrentClientCertDetails{
+							Subject: proto.BoolTrue,
+							Cert:    true,
+							Uri:     true,
+							Dns:     true,
+						},
+						ServerName:          EnvoyServerName,
+						HttpProtocolOptions: &core.Http1ProtocolOptions{},
+						XffNumTrustedHops:   2,
+					},
+				},
+			},
+		},
+		{
+			name: "gRPC-Web server",
+			node: &pilot_model.Proxy{
+				Metadata: map[string]string{},
+			},
+			server: &networking.Server{
+				Port: &networking.Port{Protocol: "GRPC-WEB"},
+			},
+			routeName: "some-route",
+			result: &filterChainOpts{
+				sniHosts:   nil,
+				tlsContext: nil,
+				httpOpts: &httpListenerOpts{
+					rds:              "some-route",
+					useRemoteAddress: true,
+					direction:        http_conn.HttpConnectionManager_Tracing_EGRESS,
+					addGRPCWebFilter: true,
+					connectionManager: &http_conn.HttpConnectionManager{
+						ForwardClientCertDetails: http_conn.HttpConnectionManager_SANITIZE_SET,
+						SetCurrentClientCertDetails: &http_conn.HttpConnectionManager_SetCurrentClientCertDetails{
+							Subject: proto.BoolTrue,
+							Cert:    true,
+							Uri:     true,
+							Dns:     true,
+						},
+						ServerName:          EnvoyServerName,
+						HttpProtocolOptions: &core.Http1ProtocolOptions{},
+					},
+				},
+			},
+		},
+		{
+			name: "max concurrent streams",
+			node: &pilot_model.Proxy{
+				Metadata: map[string]string{
+					pilot_model.NodeMetadataMaxConcurrentStreams: "100",
+				},
+			},
+			server: &networking.Server{
+				Port: &networking.Port{},
+			},
+			routeName: "some-route",
+			result: &filterChainOpts{
+				sniHosts:   nil,
+				tlsContext: nil,
+				httpOpts: &httpListenerOpts{
+					rds:              "some-route",
+					useRemoteAddress: true,
+					direction:        http_conn.HttpConnectionManager_Tracing_EGRESS,
+					connectionManager: &http_conn.HttpConnectionManager{
+						ForwardClientCertDetails: http_conn.HttpConnectionManager_SANITIZE_SET,
+						SetCurrentClientCertDetails: &http_conn.HttpConnectionManager_SetCurrentClientCertDetails{
+							Subject: proto.BoolTrue,
+							Cert:    true,
+							Uri:     true,
+							Dns:     true,
+						},
+						ServerName:          EnvoyServerName,
+						HttpProtocolOptions: &core.Http1ProtocolOptions{},
+						Http2ProtocolOptions: &core.Http2ProtocolOptions{
+							MaxConcurrentStreams: &wrappers.UInt32Value{Value: 100},
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "Duplicate hosts in TLS filterChain",
 			node: &pilot_model.Proxy{},
@@ -897,6 +1060,45 @@ func TestGatewayHTTPRouteConfig(t *testing.T) {
 			},
 		},
 	}
+	strictGateway := httpGateway
+	strictGateway.Annotations = map[string]string{pilot_model.RejectUnknownHostsAnnotation: "true"}
+	strictWildcardGateway := strictGateway
+	strictWildcardGateway.Spec = &networking.Gateway{
+		Selector: map[string]string{"istio": "ingressgateway"},
+		Servers: []*networking.Server{
+			{
+				Hosts: []string{"*"},
+				Port:  &networking.Port{Name: "http", Number: 80, Protocol: "HTTP"},
+			},
+		},
+	}
+	laxGateway := pilot_model.Config{
+		ConfigMeta: pilot_model.ConfigMeta{
+			Name:      "lax-gateway",
+			Namespace: "default",
+		},
+		Spec: &networking.Gateway{
+			Selector: map[string]string{"istio": "ingressgateway"},
+			Servers: []*networking.Server{
+				{
+					Hosts: []string{"example.com"},
+					Port:  &networking.Port{Name: "http", Number: 80, Protocol: "HTTP"},
+				},
+			},
+		},
+	}
+	virtualServiceAllHosts := pilot_model.Config{
+		ConfigMeta: pilot_model.ConfigMeta{
+			Type:      schemas.VirtualService.Type,
+			Name:      "virtual-service-all-hosts",
+			Namespace: "default",
+		},
+		Spec: &networking.VirtualService{
+			Hosts:    []string{"*"},
+			Gateways: []string{"gateway"},
+			Http:     virtualServiceSpec.Http,
+		},
+	}
 	cases := []struct {
 		name                 string
 		virtualServices      []pilot_model.Config
@@ -932,6 +1134,27 @@ func TestGatewayHTTPRouteConfig(t *testing.T) {
 			"http.80",
 			[]string{"example.org:80"},
 		},
+		{
+			"reject unknown hosts",
+			[]pilot_model.Config{virtualService},
+			[]pilot_model.Config{strictGateway},
+			"http.80",
+			[]string{"example.org:80", "reject:80"},
+		},
+		{
+			"keep unknown hosts when another gateway on the port does not reject them",
+			[]pilot_model.Config{virtualService},
+			[]pilot_model.Config{strictGateway, laxGateway},
+			"http.80",
+			[]string{"example.org:80"},
+		},
+		{
+			"reject unknown hosts with a virtual service serving all hosts",
+			[]pilot_model.Config{virtualService, virtualServiceAllHosts},
+			[]pilot_model.Config{strictWildcardGateway},
+			"http.80",
+			[]string{"*:80", "example.org:80"},
+		},
 	}
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
@@ -955,6 +1178,39 @@ func TestGatewayHTTPRouteConfig(t *testing.T) {
 
 }
 
+func TestBuildHTTPSRedirectRoutes(t *testing.T) {
+	action := &route.Route_Route{Route: &route.RouteAction{}}
+	routes := []*route.Route{
+		{Match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_Path{Path: "/healthz"}}, Action: action},
+		{Match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_SafeRegex{SafeRegex: &matcher.RegexMatcher{Regex: "/.*"}}}, Action: action},
+		{Match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/api"}}, Action: action},
+		{Match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"}}, Action: action},
+	}
+
+	got := buildHTTPSRedirectRoutes(routes, []string{"/.well-known/acme-challenge/", "/healthz"})
+	want := []string{
+		"/.well-known/acme-challenge/", // catch-all route restricted to the exempted prefix
+		"/healthz",                     // exact path under the exempted prefix
+		"/healthz",                     // catch-all route restricted to the exempted prefix
+		"/",                            // redirect
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d routes, want %d: %v", len(got), len(want), got)
+	}
+	for i, r := range got {
+		if path := r.Match.GetPrefix() + r.Match.GetPath(); path != want[i] {
+			t.Errorf("route %d: got path %q, want %q", i, path, want[i])
+		}
+	}
+	redirect := got[len(got)-1].GetRedirect()
+	if redirect == nil || !redirect.GetHttpsRedirect() {
+		t.Errorf("expected the last route to redirect to HTTPS, got %v", got[len(got)-1])
+	}
+	if routes[3].Match.GetPrefix() != "/" {
+		t.Errorf("the original routes must not be modified")
+	}
+}
+
 func buildEnv(t *testing.T, gateways []pilot_model.Config, virtualServices []pilot_model.Config) pilot_model.Environment {
 	serviceDiscovery := new(fakes.ServiceDiscovery)
 
@@ -0,0 +1,82 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"fmt"
+	"strconv"
+
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	http_conn "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	apilistener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v2"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+)
+
+// buildGRPCListeners builds the listeners requested by proxyless gRPC clients. gRPC looks up the
+// target of a channel, e.g. xds:///foo.ns.svc.cluster.local:8080, as a listener named after the
+// host and port. The listener only carries an HTTP connection manager pointing at the route
+// configuration of the port, which is the same one sidecars get. Its virtual hosts match the
+// authority of the channel and lead to EDS clusters, the only kind gRPC accepts.
+func buildGRPCListeners(node *model.Proxy, push *model.PushContext) []*xdsapi.Listener {
+	listeners := make([]*xdsapi.Listener, 0)
+	for _, svc := range push.Services(node) {
+		for _, port := range svc.Ports {
+			if !port.Protocol.IsHTTP() {
+				continue
+			}
+			listeners = append(listeners, buildGRPCListener(fmt.Sprintf("%s:%d", svc.Hostname, port.Port), port.Port))
+		}
+	}
+	return listeners
+}
+
+func buildGRPCListener(name string, port int) *xdsapi.Listener {
+	connectionManager := &http_conn.HttpConnectionManager{
+		RouteSpecifier: &http_conn.HttpConnectionManager_Rds{
+			Rds: &http_conn.Rds{
+				ConfigSource: &core.ConfigSource{
+					ConfigSourceSpecifier: &core.ConfigSource_Ads{
+						Ads: &core.AggregatedConfigSource{},
+					},
+					InitialFetchTimeout: features.InitialFetchTimeout,
+				},
+				RouteConfigName: strconv.Itoa(port),
+			},
+		},
+	}
+
+	return &xdsapi.Listener{
+		Name: name,
+		// gRPC only accepts the typed config of the API listener.
+		ApiListener: &apilistener.ApiListener{
+			ApiListener: util.MessageToAny(connectionManager),
+		},
+	}
+}
+
+// buildGRPCClusters keeps the EDS clusters, the only kind proxyless gRPC clients accept.
+func buildGRPCClusters(clusters []*xdsapi.Cluster) []*xdsapi.Cluster {
+	out := make([]*xdsapi.Cluster, 0, len(clusters))
+	for _, c := range clusters {
+		if t, ok := c.ClusterDiscoveryType.(*xdsapi.Cluster_Type); ok && t.Type == xdsapi.Cluster_EDS {
+			out = append(out, c)
+		}
+	}
+	return out
+}
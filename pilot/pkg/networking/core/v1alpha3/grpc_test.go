@@ -0,0 +1,58 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	http_conn "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	"github.com/golang/protobuf/ptypes"
+)
+
+func TestBuildGRPCListener(t *testing.T) {
+	l := buildGRPCListener("foo.default.svc.cluster.local:8080", 8080)
+	if l.Name != "foo.default.svc.cluster.local:8080" {
+		t.Errorf("listener name is %s", l.Name)
+	}
+	if l.Address != nil || len(l.FilterChains) != 0 {
+		t.Errorf("expected an API listener without address and filter chains, got %v", l)
+	}
+
+	hcm := &http_conn.HttpConnectionManager{}
+	if err := ptypes.UnmarshalAny(l.ApiListener.ApiListener, hcm); err != nil {
+		t.Fatal(err)
+	}
+	if got := hcm.GetRds().GetRouteConfigName(); got != "8080" {
+		t.Errorf("route config name is %s, want 8080", got)
+	}
+}
+
+func TestBuildGRPCClusters(t *testing.T) {
+	eds := &xdsapi.Cluster{
+		Name:                 "outbound|8080||foo.default.svc.cluster.local",
+		ClusterDiscoveryType: &xdsapi.Cluster_Type{Type: xdsapi.Cluster_EDS},
+	}
+	clusters := []*xdsapi.Cluster{
+		eds,
+		{Name: "outbound|80||bar.com", ClusterDiscoveryType: &xdsapi.Cluster_Type{Type: xdsapi.Cluster_STRICT_DNS}},
+		{Name: "PassthroughCluster", ClusterDiscoveryType: &xdsapi.Cluster_Type{Type: xdsapi.Cluster_ORIGINAL_DST}},
+	}
+
+	got := buildGRPCClusters(clusters)
+	if len(got) != 1 || got[0] != eds {
+		t.Errorf("expected only the EDS cluster, got %v", got)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"strings"
 
 	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
 
 	networking "istio.io/api/networking/v1alpha3"
@@ -37,7 +38,20 @@ import (
 	"istio.io/istio/pkg/proto"
 )
 
-const wildcardDomainPrefix = "*."
+const (
+	wildcardDomainPrefix = "*."
+
+	// blackHoleResponseBody is the body of the responses to the requests blocked by the outbound traffic policy.
+	blackHoleResponseBody = "Request blocked by the outbound traffic policy of the mesh: " +
+		"the host is not in the service registry and the policy is REGISTRY_ONLY\n"
+
+	// blackHoleResponseFlagHeader marks the responses to the requests blocked by the outbound traffic policy,
+	// the direct responses of Envoy carry no response flag. Access logs can show it with
+	// %RESP(X-ISTIO-RESPONSE-FLAG)%.
+	blackHoleResponseFlagHeader = "x-istio-response-flag"
+	// blackHoleResponseFlag is the value of blackHoleResponseFlagHeader for blocked requests.
+	blackHoleResponseFlag = "BH"
+)
 
 // BuildHTTPRoutes produces a list of routes for the proxy
 func (configgen *ConfigGeneratorImpl) BuildHTTPRoutes(env *model.Environment, node *model.Proxy, push *model.PushContext,
@@ -90,6 +104,7 @@ func (configgen *ConfigGeneratorImpl) buildSidecarInboundHTTPRouteConfig(env *mo
 		Domains: []string{"*"},
 		Routes:  []*route.Route{defaultRoute},
 	}
+	istio_route.PrependRejectAmbiguousPathRoute(node, inboundVHost)
 
 	r := &xdsapi.RouteConfiguration{
 		Name:             clusterName,
@@ -180,6 +195,15 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundHTTPRouteConfig(env *m
 
 	util.SortVirtualHosts(virtualHosts)
 
+	if domains := node.SidecarScope.DynamicForwardProxyDomains(); len(domains) > 0 && !useSniffing {
+		// Envoy picks the virtual host of the exact or the longest wildcard domain whatever their order,
+		// so the more specific hosts of the services and virtual services keep their routes. Envoy rejects
+		// domains repeated across virtual hosts, hence those already routed are left out.
+		if vhost := buildDynamicForwardProxyVirtualHost(domains, listenerPort, virtualHosts); vhost != nil {
+			virtualHosts = append(virtualHosts, vhost)
+		}
+	}
+
 	if features.EnableFallthroughRoute.Get() && !useSniffing {
 		// This needs to be the last virtual host, as routes are evaluated in order.
 		if isAllowAnyOutbound(node) {
@@ -200,22 +224,7 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundHTTPRouteConfig(env *m
 				},
 			})
 		} else {
-			virtualHosts = append(virtualHosts, &route.VirtualHost{
-				Name:    util.BlackHoleRouteName,
-				Domains: []string{"*"},
-				Routes: []*route.Route{
-					{
-						Match: &route.RouteMatch{
-							PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"},
-						},
-						Action: &route.Route_DirectResponse{
-							DirectResponse: &route.DirectResponseAction{
-								Status: 502,
-							},
-						},
-					},
-				},
-			})
+			virtualHosts = append(virtualHosts, buildBlackHoleVirtualHost())
 		}
 	}
 
@@ -246,6 +255,46 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundHTTPRouteConfig(env *m
 	return out
 }
 
+// buildDynamicForwardProxyVirtualHost builds the virtual host forwarding the requests for the
+// allowed external domains to the dynamic forward proxy cluster. The domains of the existing virtual
+// hosts are skipped, and nil is returned if no domain is left.
+func buildDynamicForwardProxyVirtualHost(domains []string, listenerPort int, existing []*route.VirtualHost) *route.VirtualHost {
+	used := make(map[string]bool)
+	for _, vhost := range existing {
+		for _, domain := range vhost.Domains {
+			used[domain] = true
+		}
+	}
+	vhostDomains := make([]string, 0, 2*len(domains))
+	for _, domain := range domains {
+		if !used[domain] {
+			vhostDomains = append(vhostDomains, domain)
+		}
+		if listenerPort > 0 && !used[domainName(domain, listenerPort)] {
+			vhostDomains = append(vhostDomains, domainName(domain, listenerPort))
+		}
+	}
+	if len(vhostDomains) == 0 {
+		return nil
+	}
+	return &route.VirtualHost{
+		Name:    util.DynamicForwardProxyRouteName,
+		Domains: vhostDomains,
+		Routes: []*route.Route{
+			{
+				Match: &route.RouteMatch{
+					PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"},
+				},
+				Action: &route.Route_Route{
+					Route: &route.RouteAction{
+						ClusterSpecifier: &route.RouteAction_Cluster{Cluster: util.DynamicForwardProxyCluster},
+					},
+				},
+			},
+		},
+	}
+}
+
 func (configgen *ConfigGeneratorImpl) buildSidecarOutboundVirtualHosts(_ *model.Environment, node *model.Proxy, push *model.PushContext,
 	routeName string, listenerPort int) []*route.VirtualHost {
 
@@ -510,3 +559,33 @@ func getUniqueAndSharedDNSDomain(fqdnHostname, proxyDomain string) (string, stri
 	sharedSuffixes := strings.Join(reverseArray(sharedSuffixesInReverse), ".")
 	return uniqHostame, sharedSuffixes
 }
+
+// buildBlackHoleVirtualHost builds the catch all virtual host of the outbound routes when the outbound
+// traffic policy is REGISTRY_ONLY. It replies a 502 explaining why the request was blocked.
+func buildBlackHoleVirtualHost() *route.VirtualHost {
+	return &route.VirtualHost{
+		Name:    util.BlackHoleRouteName,
+		Domains: []string{"*"},
+		Routes: []*route.Route{
+			{
+				Match: &route.RouteMatch{
+					PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"},
+				},
+				Action: &route.Route_DirectResponse{
+					DirectResponse: &route.DirectResponseAction{
+						Status: 502,
+						Body: &core.DataSource{
+							Specifier: &core.DataSource_InlineString{InlineString: blackHoleResponseBody},
+						},
+					},
+				},
+				ResponseHeadersToAdd: []*core.HeaderValueOption{
+					{
+						Header: &core.HeaderValue{Key: blackHoleResponseFlagHeader, Value: blackHoleResponseFlag},
+						Append: proto.BoolFalse,
+					},
+				},
+			},
+		},
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/plugin"
+	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/serviceregistry"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/protocol"
@@ -211,6 +212,20 @@ func TestSidecarOutboundHTTPRouteConfig(t *testing.T) {
 			},
 		},
 	}
+	sidecarConfigWithDynamicForwardProxy := &model.Config{
+		ConfigMeta: model.ConfigMeta{
+			Name:        "foo",
+			Namespace:   "not-default",
+			Annotations: map[string]string{model.DynamicForwardProxyDomainsAnnotation: "test-headless.com,example.org"},
+		},
+		Spec: &networking.Sidecar{
+			Egress: []*networking.IstioEgressListener{
+				{
+					Hosts: []string{"*/*"},
+				},
+			},
+		},
+	}
 	sidecarConfigWithAllowAny := &model.Config{
 		ConfigMeta: model.ConfigMeta{
 			Name:      "foo",
@@ -645,6 +660,18 @@ func TestSidecarOutboundHTTPRouteConfig(t *testing.T) {
 				"test-headless.com:8888": {"test-headless.com:8888": true, "*.test-headless.com:8888": true},
 			},
 		},
+		{
+			name:                  "sidecar config with dynamic forward proxy under the suffix of a service",
+			routeName:             "8888",
+			sidecarConfig:         sidecarConfigWithDynamicForwardProxy,
+			virtualServiceConfigs: nil,
+			expectedHosts: map[string]map[string]bool{
+				"test-headless.com:8888": {
+					"test-headless.com": true, "test-headless.com:8888": true, "*.test-headless.com": true, "*.test-headless.com:8888": true,
+				},
+				util.DynamicForwardProxyRouteName: {"*.example.org": true, "*.example.org:8888": true},
+			},
+		},
 		{
 			name:                  "sidecar config port - import headless service",
 			routeName:             "18888",
@@ -664,6 +691,69 @@ func TestSidecarOutboundHTTPRouteConfig(t *testing.T) {
 	}
 }
 
+func TestBuildDynamicForwardProxyVirtualHost(t *testing.T) {
+	cases := []struct {
+		name         string
+		listenerPort int
+		existing     []*route.VirtualHost
+		want         []string
+	}{
+		{
+			name:         "port",
+			listenerPort: 80,
+			want:         []string{"*.example.com", "*.example.com:80", "*.example.org", "*.example.org:80"},
+		},
+		{
+			name: "http proxy",
+			want: []string{"*.example.com", "*.example.org"},
+		},
+		{
+			name:         "domains of existing virtual hosts",
+			listenerPort: 80,
+			existing:     []*route.VirtualHost{{Name: "wildcard", Domains: []string{"*.example.com", "*.example.com:80"}}},
+			want:         []string{"*.example.org", "*.example.org:80"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			vhost := buildDynamicForwardProxyVirtualHost([]string{"*.example.com", "*.example.org"}, tt.listenerPort, tt.existing)
+			if vhost.Name != util.DynamicForwardProxyRouteName {
+				t.Errorf("got virtual host %s, want %s", vhost.Name, util.DynamicForwardProxyRouteName)
+			}
+			if !reflect.DeepEqual(vhost.Domains, tt.want) {
+				t.Errorf("got domains %v, want %v", vhost.Domains, tt.want)
+			}
+			if len(vhost.Routes) != 1 ||
+				vhost.Routes[0].GetRoute().GetCluster() != util.DynamicForwardProxyCluster {
+				t.Errorf("expected a single route to %s, got %v", util.DynamicForwardProxyCluster, vhost.Routes)
+			}
+		})
+	}
+
+	existing := []*route.VirtualHost{{Name: "wildcard", Domains: []string{"*.example.com"}}}
+	if vhost := buildDynamicForwardProxyVirtualHost([]string{"*.example.com"}, 0, existing); vhost != nil {
+		t.Errorf("got virtual host %v, want none when all the domains are routed", vhost)
+	}
+}
+
+func TestBuildBlackHoleVirtualHost(t *testing.T) {
+	vhost := buildBlackHoleVirtualHost()
+	if vhost.Name != util.BlackHoleRouteName || !reflect.DeepEqual(vhost.Domains, []string{"*"}) {
+		t.Fatalf("unexpected virtual host %s with domains %v", vhost.Name, vhost.Domains)
+	}
+	if len(vhost.Routes) != 1 {
+		t.Fatalf("expected a single route, got %v", vhost.Routes)
+	}
+	response := vhost.Routes[0].GetDirectResponse()
+	if response.GetStatus() != 502 || response.GetBody().GetInlineString() != blackHoleResponseBody {
+		t.Errorf("expected a 502 direct response with a body, got %v", response)
+	}
+	headers := vhost.Routes[0].ResponseHeadersToAdd
+	if len(headers) != 1 || headers[0].Header.Key != blackHoleResponseFlagHeader || headers[0].Header.Value != blackHoleResponseFlag {
+		t.Errorf("expected the %s response header, got %v", blackHoleResponseFlagHeader, headers)
+	}
+}
+
 func testSidecarRDSVHosts(t *testing.T, services []*model.Service,
 	sidecarConfig *model.Config, virtualServices []*model.Config, routeName string,
 	expectedHosts map[string]map[string]bool, fallthroughRoute bool, registryOnly bool) {
@@ -20,6 +20,7 @@ import (
 	"net"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,12 +30,17 @@ import (
 	listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
 	accesslogconfig "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v2"
 	accesslog "github.com/envoyproxy/go-control-plane/envoy/config/filter/accesslog/v2"
+	buffer "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/buffer/v2"
+	dfpfilter "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/dynamic_forward_proxy/v2alpha"
+	gzip "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/gzip/v2"
 	http_conn "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
 	tcp_proxy "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/tcp_proxy/v2"
 	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	golangproto "github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/golang/protobuf/ptypes/wrappers"
 
 	"istio.io/istio/pkg/util/gogo"
 
@@ -53,6 +59,7 @@ import (
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/security"
 	"istio.io/istio/pkg/proto"
 	"istio.io/pkg/monitoring"
 )
@@ -83,6 +90,25 @@ const (
 	// HTTP inspector listener filter
 	envoyListenerHTTPInspector = "envoy.listener.http_inspector"
 
+	// Original source listener filter
+	envoyListenerOriginalSrc = "envoy.listener.original_src"
+
+	// inboundTProxyMark is the mark of the connections that are routed back to the sidecar in TPROXY
+	// mode, the default ISTIO_INBOUND_TPROXY_MARK of istio-iptables.
+	inboundTProxyMark = 1337
+
+	// dynamicForwardProxyFilter is the HTTP filter resolving the hosts of the dynamic forward proxy
+	dynamicForwardProxyFilter = "envoy.filters.http.dynamic_forward_proxy"
+
+	// adaptiveConcurrencyFilter is the HTTP filter limiting the concurrency of the inbound requests
+	adaptiveConcurrencyFilter = "envoy.filters.http.adaptive_concurrency"
+
+	// responseCacheFilter is the HTTP filter caching the responses of the gateways
+	responseCacheFilter = "envoy.filters.http.cache"
+
+	// simpleHTTPCacheType is the type of the in-memory storage of the response cache
+	simpleHTTPCacheType = "type.googleapis.com/envoy.source.extensions.filters.http.cache.SimpleHttpCacheConfig"
+
 	// RDSHttpProxy is the special name for HTTP PROXY route
 	RDSHttpProxy = "http_proxy"
 
@@ -127,6 +153,9 @@ const (
 
 	httpEnvoyAccessLogName = "http_envoy_accesslog"
 
+	// maxRequestHeadersKb is the largest request headers size limit Envoy accepts, in KiB.
+	maxRequestHeadersKb = 96
+
 	// EnvoyAccessLogCluster is the cluster name that has details for server implementing Envoy ALS.
 	// This cluster is created in bootstrap.
 	EnvoyAccessLogCluster = "envoy_accesslog_service"
@@ -202,15 +231,50 @@ var (
 )
 
 func buildAccessLog(node *model.Proxy, fl *accesslogconfig.FileAccessLog, env *model.Environment) {
-	switch env.Mesh.AccessLogEncoding {
+	buildAccessLogFormat(node, fl, env.Mesh.AccessLogEncoding, env.Mesh.AccessLogFormat)
+}
+
+// buildMeshFileAccessLog builds the file access log configured mesh wide. Its config only depends
+// on the mesh settings and the version of the proxy, so it is marshaled once and shared by all
+// the listeners. Returns nil if the config cannot be marshaled.
+func buildMeshFileAccessLog(node *model.Proxy, env *model.Environment) *accesslog.AccessLog {
+	build := func() golangproto.Message {
+		fl := &accesslogconfig.FileAccessLog{
+			Path: env.Mesh.AccessLogFile,
+		}
+		buildAccessLog(node, fl, env)
+		return fl
+	}
+
+	acc := &accesslog.AccessLog{
+		Name: wellknown.FileAccessLog,
+	}
+	if util.IsXDSMarshalingToAnyEnabled(node) {
+		key := fmt.Sprintf("accesslog/%s/%v/%s/%t", env.Mesh.AccessLogFile, env.Mesh.AccessLogEncoding,
+			env.Mesh.AccessLogFormat, util.IsIstioVersionGE13(node))
+		config, err := util.CachedMessageToAny(key, build)
+		if err != nil {
+			log.Errorf("failed to marshal access log config: %v", err)
+			return nil
+		}
+		acc.ConfigType = &accesslog.AccessLog_TypedConfig{TypedConfig: config}
+	} else {
+		acc.ConfigType = &accesslog.AccessLog_Config{Config: util.MessageToStruct(build())}
+	}
+	return acc
+}
+
+func buildAccessLogFormat(node *model.Proxy, fl *accesslogconfig.FileAccessLog,
+	encoding meshconfig.MeshConfig_AccessLogEncoding, format string) {
+	switch encoding {
 	case meshconfig.MeshConfig_TEXT:
 		formatString := EnvoyTextLogFormat12
 		if util.IsIstioVersionGE13(node) {
 			formatString = EnvoyTextLogFormat13
 		}
 
-		if env.Mesh.AccessLogFormat != "" {
-			formatString = env.Mesh.AccessLogFormat
+		if format != "" {
+			formatString = format
 		}
 		fl.AccessLogFormat = &accesslogconfig.FileAccessLog_Format{
 			Format: formatString,
@@ -220,9 +284,9 @@ func buildAccessLog(node *model.Proxy, fl *accesslogconfig.FileAccessLog, env *m
 		// TODO potential optimization to avoid recomputing the user provided format for every listener
 		// mesh AccessLogFormat field could change so need a way to have a cached value that can be cleared
 		// on changes
-		if env.Mesh.AccessLogFormat != "" {
+		if format != "" {
 			jsonFields := map[string]string{}
-			err := json.Unmarshal([]byte(env.Mesh.AccessLogFormat), &jsonFields)
+			err := json.Unmarshal([]byte(format), &jsonFields)
 			if err == nil {
 				jsonLog = &structpb.Struct{
 					Fields: make(map[string]*structpb.Value, len(jsonFields)),
@@ -245,8 +309,190 @@ func buildAccessLog(node *model.Proxy, fl *accesslogconfig.FileAccessLog, env *m
 			JsonFormat: jsonLog,
 		}
 	default:
-		log.Warnf("unsupported access log format %v", env.Mesh.AccessLogEncoding)
+		log.Warnf("unsupported access log format %v", encoding)
+	}
+}
+
+// buildDynamicForwardProxyFilter builds the HTTP filter resolving the hosts of the requests routed
+// to the dynamic forward proxy cluster.
+func buildDynamicForwardProxyFilter(node *model.Proxy, env *model.Environment) *http_conn.HttpFilter {
+	config := &dfpfilter.FilterConfig{
+		DnsCacheConfig: buildDynamicForwardProxyDNSCacheConfig(env),
+	}
+	out := &http_conn.HttpFilter{
+		Name: dynamicForwardProxyFilter,
+	}
+	if util.IsXDSMarshalingToAnyEnabled(node) {
+		out.ConfigType = &http_conn.HttpFilter_TypedConfig{TypedConfig: util.MessageToAny(config)}
+	} else {
+		out.ConfigType = &http_conn.HttpFilter_Config{Config: util.MessageToStruct(config)}
+	}
+	return out
+}
+
+// buildAdaptiveConcurrencyFilter builds the filter limiting the concurrency of the requests with the
+// gradient controller. The filter is not in the xDS API of the proxy, so its config is not typed.
+func buildAdaptiveConcurrencyFilter(ac *model.AdaptiveConcurrency) *http_conn.HttpFilter {
+	number := func(v float64) *structpb.Value {
+		return &structpb.Value{Kind: &structpb.Value_NumberValue{NumberValue: v}}
+	}
+	object := func(fields map[string]*structpb.Value) *structpb.Value {
+		return &structpb.Value{Kind: &structpb.Value_StructValue{StructValue: &structpb.Struct{Fields: fields}}}
+	}
+	duration := func(d time.Duration) *structpb.Value {
+		return &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: fmt.Sprintf("%gs", d.Seconds())}}
+	}
+
+	concurrencyLimitParams := map[string]*structpb.Value{
+		"concurrency_update_interval": duration(ac.ConcurrencyUpdateIntervalDuration()),
+	}
+	if ac.MaxConcurrencyLimit > 0 {
+		concurrencyLimitParams["max_concurrency_limit"] = number(float64(ac.MaxConcurrencyLimit))
 	}
+	minRTTCalcParams := map[string]*structpb.Value{
+		"interval": duration(ac.MinRTTIntervalDuration()),
+	}
+	if ac.MinRTTRequestCount > 0 {
+		minRTTCalcParams["request_count"] = number(float64(ac.MinRTTRequestCount))
+	}
+
+	return &http_conn.HttpFilter{
+		Name: adaptiveConcurrencyFilter,
+		ConfigType: &http_conn.HttpFilter_Config{Config: &structpb.Struct{Fields: map[string]*structpb.Value{
+			"gradient_controller_config": object(map[string]*structpb.Value{
+				"sample_aggregate_percentile": object(map[string]*structpb.Value{"value": number(ac.LatencyPercentile)}),
+				"concurrency_limit_params":    object(concurrencyLimitParams),
+				"min_rtt_calc_params":         object(minRTTCalcParams),
+			}),
+		}}},
+	}
+}
+
+// buildBufferFilter builds the filter buffering the bodies of the requests, rejecting the ones larger
+// than the maximum size with a 413.
+func buildBufferFilter(node *model.Proxy, maxRequestBytes uint32) *http_conn.HttpFilter {
+	config := &buffer.Buffer{
+		MaxRequestBytes: &wrappers.UInt32Value{Value: maxRequestBytes},
+	}
+	out := &http_conn.HttpFilter{
+		Name: wellknown.Buffer,
+	}
+	if util.IsXDSMarshalingToAnyEnabled(node) {
+		out.ConfigType = &http_conn.HttpFilter_TypedConfig{TypedConfig: util.MessageToAny(config)}
+	} else {
+		out.ConfigType = &http_conn.HttpFilter_Config{Config: util.MessageToStruct(config)}
+	}
+	return out
+}
+
+// buildCompressionFilter builds the gzip filter compressing the responses as set by the policy.
+func buildCompressionFilter(node *model.Proxy, policy *model.CompressionPolicy) *http_conn.HttpFilter {
+	config := &gzip.Gzip{
+		ContentType: policy.ContentTypes,
+	}
+	if policy.MinLength > 0 {
+		config.ContentLength = &wrappers.UInt32Value{Value: policy.MinLength}
+	}
+	if level, ok := gzip.Gzip_CompressionLevel_Enum_value[policy.Level]; ok {
+		config.CompressionLevel = gzip.Gzip_CompressionLevel_Enum(level)
+	}
+	out := &http_conn.HttpFilter{
+		Name: wellknown.Gzip,
+	}
+	if util.IsXDSMarshalingToAnyEnabled(node) {
+		out.ConfigType = &http_conn.HttpFilter_TypedConfig{TypedConfig: util.MessageToAny(config)}
+	} else {
+		out.ConfigType = &http_conn.HttpFilter_Config{Config: util.MessageToStruct(config)}
+	}
+	return out
+}
+
+// buildResponseCacheFilter builds the filter caching the responses in memory, with the cache keys set by
+// the gateway. The filter is not in the xDS API of the proxy, so its config is not typed.
+func buildResponseCacheFilter(cache *model.GatewayResponseCache) *http_conn.HttpFilter {
+	queryParameters := func(names []string) *structpb.Value {
+		values := make([]*structpb.Value, 0, len(names))
+		for _, name := range names {
+			values = append(values, &structpb.Value{Kind: &structpb.Value_StructValue{StructValue: &structpb.Struct{
+				Fields: map[string]*structpb.Value{"name": {Kind: &structpb.Value_StringValue{StringValue: name}}},
+			}}})
+		}
+		return &structpb.Value{Kind: &structpb.Value_ListValue{ListValue: &structpb.ListValue{Values: values}}}
+	}
+
+	keyCreatorParams := map[string]*structpb.Value{
+		"exclude_scheme": {Kind: &structpb.Value_BoolValue{BoolValue: cache.ExcludeScheme}},
+		"exclude_host":   {Kind: &structpb.Value_BoolValue{BoolValue: cache.ExcludeHost}},
+	}
+	if len(cache.QueryParametersIncluded) > 0 {
+		keyCreatorParams["query_parameters_included"] = queryParameters(cache.QueryParametersIncluded)
+	}
+	if len(cache.QueryParametersExcluded) > 0 {
+		keyCreatorParams["query_parameters_excluded"] = queryParameters(cache.QueryParametersExcluded)
+	}
+
+	fields := map[string]*structpb.Value{
+		"typed_config": {Kind: &structpb.Value_StructValue{StructValue: &structpb.Struct{
+			Fields: map[string]*structpb.Value{"@type": {Kind: &structpb.Value_StringValue{StringValue: simpleHTTPCacheType}}},
+		}}},
+		"key_creator_params": {Kind: &structpb.Value_StructValue{StructValue: &structpb.Struct{Fields: keyCreatorParams}}},
+	}
+	if cache.MaxBodyBytes > 0 {
+		fields["max_body_bytes"] = &structpb.Value{Kind: &structpb.Value_NumberValue{NumberValue: float64(cache.MaxBodyBytes)}}
+	}
+	return &http_conn.HttpFilter{
+		Name:       responseCacheFilter,
+		ConfigType: &http_conn.HttpFilter_Config{Config: &structpb.Struct{Fields: fields}},
+	}
+}
+
+// buildGatewayAccessLog builds the file access log of a gateway server overriding the mesh settings.
+func buildGatewayAccessLog(node *model.Proxy, env *model.Environment, override *model.GatewayAccessLog) *accesslog.AccessLog {
+	fl := &accesslogconfig.FileAccessLog{
+		Path: override.File,
+	}
+	if fl.Path == "" {
+		fl.Path = env.Mesh.AccessLogFile
+	}
+	if fl.Path == "" {
+		fl.Path = "/dev/stdout"
+	}
+
+	encoding := env.Mesh.AccessLogEncoding
+	if e, ok := meshconfig.MeshConfig_AccessLogEncoding_value[override.Encoding]; ok {
+		encoding = meshconfig.MeshConfig_AccessLogEncoding(e)
+	}
+	format := env.Mesh.AccessLogFormat
+	if override.Format != "" {
+		format = override.Format
+	}
+	buildAccessLogFormat(node, fl, encoding, format)
+
+	acc := &accesslog.AccessLog{
+		Name: wellknown.FileAccessLog,
+	}
+	if override.MinStatusCode > 0 {
+		acc.Filter = &accesslog.AccessLogFilter{
+			FilterSpecifier: &accesslog.AccessLogFilter_StatusCodeFilter{
+				StatusCodeFilter: &accesslog.StatusCodeFilter{
+					Comparison: &accesslog.ComparisonFilter{
+						Op: accesslog.ComparisonFilter_GE,
+						Value: &core.RuntimeUInt32{
+							DefaultValue: override.MinStatusCode,
+							RuntimeKey:   "access_log.gateway_min_status_code",
+						},
+					},
+				},
+			},
+		}
+	}
+
+	if util.IsXDSMarshalingToAnyEnabled(node) {
+		acc.ConfigType = &accesslog.AccessLog_TypedConfig{TypedConfig: util.MessageToAny(fl)}
+	} else {
+		acc.ConfigType = &accesslog.AccessLog_Config{Config: util.MessageToStruct(fl)}
+	}
+	return acc
 }
 
 var (
@@ -266,6 +512,10 @@ func init() {
 // BuildListeners produces a list of listeners and referenced clusters for all proxies
 func (configgen *ConfigGeneratorImpl) BuildListeners(env *model.Environment, node *model.Proxy,
 	push *model.PushContext) []*xdsapi.Listener {
+	if node.IsProxylessGRPC() {
+		return buildGRPCListeners(node, push)
+	}
+
 	builder := NewListenerBuilder(node)
 
 	switch node.Type {
@@ -276,7 +526,11 @@ func (configgen *ConfigGeneratorImpl) BuildListeners(env *model.Environment, nod
 	}
 
 	builder.patchListeners(push)
-	return builder.getListeners()
+	listeners := builder.getListeners()
+	if security.FIPSMode {
+		applyFIPSToListeners(listeners)
+	}
+	return listeners
 }
 
 // buildSidecarListeners produces a list of listeners for sidecar proxies
@@ -484,9 +738,11 @@ func (configgen *ConfigGeneratorImpl) buildSidecarInboundHTTPListenerOptsForPort
 	httpOpts := &httpListenerOpts{
 		routeConfig: configgen.buildSidecarInboundHTTPRouteConfig(pluginParams.Env, pluginParams.Node,
 			pluginParams.Push, pluginParams.ServiceInstance, clusterName),
-		rds:              "", // no RDS for inbound traffic
-		useRemoteAddress: false,
-		direction:        http_conn.HttpConnectionManager_Tracing_INGRESS,
+		rds:                 "", // no RDS for inbound traffic
+		useRemoteAddress:    false,
+		direction:           http_conn.HttpConnectionManager_Tracing_INGRESS,
+		compression:         pluginParams.Node.SidecarScope.Compression(),
+		adaptiveConcurrency: pluginParams.Node.SidecarScope.AdaptiveConcurrency(),
 		connectionManager: &http_conn.HttpConnectionManager{
 			// Append and forward client cert to backend.
 			ForwardClientCertDetails: http_conn.HttpConnectionManager_APPEND_FORWARD,
@@ -915,14 +1171,22 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundListeners(env *model.E
 		}
 	}
 
-	tcpListeners = append(tcpListeners, httpListeners...)
+	listeners := util.NewListenerSet(tcpListeners...)
+	for _, l := range httpListeners {
+		listeners.Add(l)
+	}
 	httpProxy := configgen.buildHTTPProxy(env, node, push, node.ServiceInstances)
 	if httpProxy != nil {
 		httpProxy.TrafficDirection = core.TrafficDirection_OUTBOUND
-		tcpListeners = append(tcpListeners, httpProxy)
+		// Envoy rejects all the listeners when two of them share an address, e.g. when an egress
+		// listener of the Sidecar binds the port of the HTTP proxy, so the proxy is left out.
+		if existing, added := listeners.Add(httpProxy); !added {
+			log.Warnf("buildSidecarOutboundListeners: omitting the HTTP proxy listener %s, which conflicts with listener %s",
+				httpProxy.Name, existing.Name)
+		}
 	}
 
-	return tcpListeners
+	return listeners.Listeners()
 }
 
 func (configgen *ConfigGeneratorImpl) buildHTTPProxy(env *model.Environment, node *model.Proxy,
@@ -1069,6 +1333,8 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundHTTPListenerOptsForPor
 		useRemoteAddress: features.UseRemoteAddress.Get(),
 		direction:        http_conn.HttpConnectionManager_Tracing_EGRESS,
 		rds:              rdsName,
+		// The routes forward the allowed external hosts to the dynamic forward proxy.
+		addDynamicForwardProxyFilter: len(pluginParams.Node.SidecarScope.DynamicForwardProxyDomains()) > 0,
 	}
 
 	if features.HTTP10 || pluginParams.Node.Metadata[model.NodeMetadataHTTP10] == "1" {
@@ -1535,7 +1801,7 @@ func buildSidecarInboundMgmtListeners(node *model.Proxy, env *model.Environment,
 	for _, mPort := range managementPorts {
 		switch mPort.Protocol {
 		case protocol.HTTP, protocol.HTTP2, protocol.GRPC, protocol.GRPCWeb, protocol.TCP,
-			protocol.HTTPS, protocol.TLS, protocol.Mongo, protocol.Redis, protocol.MySQL:
+			protocol.HTTPS, protocol.TLS, protocol.Mongo, protocol.Redis, protocol.MySQL, protocol.Thrift, protocol.Kafka:
 
 			instance := &model.ServiceInstance{
 				Endpoint: model.NetworkEndpoint{
@@ -1599,7 +1865,22 @@ type httpListenerOpts struct {
 	// addGRPCWebFilter specifies whether the envoy.grpc_web HTTP filter
 	// should be added.
 	addGRPCWebFilter bool
-	useRemoteAddress bool
+	// addDynamicForwardProxyFilter specifies whether the dynamic forward
+	// proxy HTTP filter should be added.
+	addDynamicForwardProxyFilter bool
+	useRemoteAddress             bool
+	// If set, overrides the access log settings of the mesh
+	accessLog *model.GatewayAccessLog
+	// If set, overrides the server and via headers settings of the mesh
+	serverHeader *model.GatewayServerHeader
+	// If set, the responses are compressed with this policy
+	compression *model.CompressionPolicy
+	// If set, the responses are cached with these cache keys
+	responseCache *model.GatewayResponseCache
+	// If set, the request bodies are buffered and rejected above this size
+	maxRequestBodyBytes uint32
+	// If set, the concurrency of the requests is limited
+	adaptiveConcurrency *model.AdaptiveConcurrency
 }
 
 // filterChainOpts describes a filter chain: a set of filters with the same TLS context
@@ -1631,16 +1912,104 @@ type buildListenerOpts struct {
 	needHTTPInspector bool
 }
 
+// setHTTP1ProtocolOptions applies the HTTP/1 options of the node metadata to the connection manager,
+// for legacy clients sending HTTP/1.0 requests without a Host header or absolute URLs.
+func setHTTP1ProtocolOptions(node *model.Proxy, connectionManager *http_conn.HttpConnectionManager) {
+	defaultHost := node.Metadata[model.NodeMetadataHTTP10DefaultHost]
+	allowAbsoluteURL, err := strconv.ParseBool(node.Metadata[model.NodeMetadataAllowAbsoluteURL])
+	if defaultHost == "" && err != nil {
+		return
+	}
+
+	if connectionManager.HttpProtocolOptions == nil {
+		connectionManager.HttpProtocolOptions = &core.Http1ProtocolOptions{}
+	}
+	if defaultHost != "" {
+		connectionManager.HttpProtocolOptions.AcceptHttp_10 = true
+		connectionManager.HttpProtocolOptions.DefaultHostForHttp_10 = defaultHost
+	}
+	if err == nil {
+		if allowAbsoluteURL {
+			connectionManager.HttpProtocolOptions.AllowAbsoluteUrl = proto.BoolTrue
+		} else {
+			connectionManager.HttpProtocolOptions.AllowAbsoluteUrl = proto.BoolFalse
+		}
+	}
+}
+
+// setServerHeaders applies the server and via headers settings of a gateway, or else of the mesh, to
+// the connection manager.
+func setServerHeaders(connectionManager *http_conn.HttpConnectionManager, serverHeader *model.GatewayServerHeader) {
+	transformation, via := features.ServerHeaderTransformation, features.ViaHeader
+	if serverHeader != nil {
+		if serverHeader.Transformation != "" {
+			transformation = serverHeader.Transformation
+		}
+		if serverHeader.Via != "" {
+			via = serverHeader.Via
+		}
+	}
+
+	if value, ok := http_conn.HttpConnectionManager_ServerHeaderTransformation_value[strings.ToUpper(transformation)]; ok {
+		connectionManager.ServerHeaderTransformation = http_conn.HttpConnectionManager_ServerHeaderTransformation(value)
+	} else {
+		log.Warnf("ignoring invalid server header transformation %q", transformation)
+	}
+	connectionManager.Via = via
+}
+
+// properCaseHeaderKeyFormat returns the HTTP/1 header format capitalizing the words of the header
+// names, e.g. "content-type" becomes "Content-Type". Envoy lowercases them otherwise.
+func properCaseHeaderKeyFormat() *core.Http1ProtocolOptions_HeaderKeyFormat {
+	return &core.Http1ProtocolOptions_HeaderKeyFormat{
+		HeaderFormat: &core.Http1ProtocolOptions_HeaderKeyFormat_ProperCaseWords_{
+			ProperCaseWords: &core.Http1ProtocolOptions_HeaderKeyFormat_ProperCaseWords{},
+		},
+	}
+}
+
 func buildHTTPConnectionManager(node *model.Proxy, env *model.Environment, httpOpts *httpListenerOpts,
 	httpFilters []*http_conn.HttpFilter) *http_conn.HttpConnectionManager {
 
-	filters := make([]*http_conn.HttpFilter, len(httpFilters))
-	copy(filters, httpFilters)
+	filters := make([]*http_conn.HttpFilter, 0, len(httpFilters)+1)
+	// The concurrency limit comes first, so that the rejected requests cost the least. Proxies older
+	// than Envoy 1.12 do not have the filter and would reject the listener.
+	if httpOpts.adaptiveConcurrency != nil {
+		if util.IsIstioVersionGE14(node) {
+			filters = append(filters, buildAdaptiveConcurrencyFilter(httpOpts.adaptiveConcurrency))
+		} else {
+			log.Debugf("skipping the adaptive concurrency limit of proxy %s, which has no adaptive concurrency filter", node.ID)
+		}
+	}
+	filters = append(filters, httpFilters...)
 
 	if httpOpts.addGRPCWebFilter {
 		filters = append(filters, &http_conn.HttpFilter{Name: wellknown.GRPCWeb})
 	}
 
+	if httpOpts.addDynamicForwardProxyFilter {
+		filters = append(filters, buildDynamicForwardProxyFilter(node, env))
+	}
+
+	if httpOpts.maxRequestBodyBytes > 0 {
+		filters = append(filters, buildBufferFilter(node, httpOpts.maxRequestBodyBytes))
+	}
+
+	if httpOpts.compression != nil {
+		filters = append(filters, buildCompressionFilter(node, httpOpts.compression))
+	}
+
+	// The cache follows the compression filter, so that it stores the uncompressed responses, which
+	// are compressed as accepted by each request. Older proxies do not have the filter and would reject
+	// the listener.
+	if httpOpts.responseCache != nil {
+		if util.IsIstioVersionGE16(node) {
+			filters = append(filters, buildResponseCacheFilter(httpOpts.responseCache))
+		} else {
+			log.Debugf("skipping the response cache of proxy %s, which has no cache filter", node.ID)
+		}
+	}
+
 	filters = append(filters,
 		&http_conn.HttpFilter{Name: wellknown.CORS},
 		&http_conn.HttpFilter{Name: wellknown.Fault},
@@ -1671,6 +2040,14 @@ func buildHTTPConnectionManager(node *model.Proxy, env *model.Environment, httpO
 	if idleTimeout > 0 && err == nil {
 		connectionManager.IdleTimeout = ptypes.DurationProto(idleTimeout)
 	}
+	setHTTP1ProtocolOptions(node, connectionManager)
+	setServerHeaders(connectionManager, httpOpts.serverHeader)
+
+	// Envoy rejects the requests with larger headers with a 431, e.g. ones carrying a large JWT.
+	if limit, err := strconv.ParseUint(node.Metadata[model.NodeMetadataMaxRequestHeadersKb], 10, 32); err == nil &&
+		limit > 0 && limit <= maxRequestHeadersKb {
+		connectionManager.MaxRequestHeadersKb = &wrappers.UInt32Value{Value: uint32(limit)}
+	}
 
 	notimeout := ptypes.DurationProto(0 * time.Second)
 	connectionManager.StreamIdleTimeout = notimeout
@@ -1692,24 +2069,12 @@ func buildHTTPConnectionManager(node *model.Proxy, env *model.Environment, httpO
 		connectionManager.RouteSpecifier = &http_conn.HttpConnectionManager_RouteConfig{RouteConfig: httpOpts.routeConfig}
 	}
 
-	if env.Mesh.AccessLogFile != "" {
-		fl := &accesslogconfig.FileAccessLog{
-			Path: env.Mesh.AccessLogFile,
+	if httpOpts.accessLog != nil {
+		connectionManager.AccessLog = append(connectionManager.AccessLog, buildGatewayAccessLog(node, env, httpOpts.accessLog))
+	} else if env.Mesh.AccessLogFile != "" {
+		if acc := buildMeshFileAccessLog(node, env); acc != nil {
+			connectionManager.AccessLog = append(connectionManager.AccessLog, acc)
 		}
-
-		acc := &accesslog.AccessLog{
-			Name: wellknown.FileAccessLog,
-		}
-
-		buildAccessLog(node, fl, env)
-
-		if util.IsXDSMarshalingToAnyEnabled(node) {
-			acc.ConfigType = &accesslog.AccessLog_TypedConfig{TypedConfig: util.MessageToAny(fl)}
-		} else {
-			acc.ConfigType = &accesslog.AccessLog_Config{Config: util.MessageToStruct(fl)}
-		}
-
-		connectionManager.AccessLog = append(connectionManager.AccessLog, acc)
 	}
 
 	if env.Mesh.EnableEnvoyAccessLogService {
@@ -1895,6 +2260,7 @@ func appendListenerFallthroughRoute(l *xdsapi.Listener, opts *buildListenerOpts,
 			StatPrefix:       util.PassthroughCluster,
 			ClusterSpecifier: &tcp_proxy.TcpProxy_Cluster{Cluster: util.PassthroughCluster},
 		}
+		setOutboundTrafficPolicyAccessLog(node, tcpProxy, util.PassthroughCluster)
 		if util.IsXDSMarshalingToAnyEnabled(node) {
 			tcpFilter.ConfigType = &listener.Filter_TypedConfig{TypedConfig: util.MessageToAny(tcpProxy)}
 		} else {
@@ -18,6 +18,7 @@ import (
 	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+	original_src "github.com/envoyproxy/go-control-plane/envoy/config/filter/listener/original_src/v2alpha1"
 	http_conn "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
 	tcp_proxy "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/tcp_proxy/v2"
 	xdsutil "github.com/envoyproxy/go-control-plane/pkg/wellknown"
@@ -213,35 +214,22 @@ func (builder *ListenerBuilder) buildManagementListeners(_ *ConfigGeneratorImpl,
 		management := buildSidecarInboundMgmtListeners(node, env, managementPorts, ip)
 		mgmtListeners = append(mgmtListeners, management...)
 	}
-	addresses := make(map[string]*xdsapi.Listener)
-	for _, listener := range builder.inboundListeners {
-		if listener != nil {
-			addresses[listener.Address.String()] = listener
-		}
-	}
+	listeners := util.NewListenerSet(builder.inboundListeners...)
 	for _, listener := range builder.outboundListeners {
-		if listener != nil {
-			addresses[listener.Address.String()] = listener
-		}
+		listeners.Add(listener)
 	}
 
 	// If management listener port and service port are same, bad things happen
 	// when running in kubernetes, as the probes stop responding. So, append
 	// non overlapping listeners only.
-	for i := range mgmtListeners {
-		m := mgmtListeners[i]
-		addressString := m.Address.String()
-		existingListener, ok := addresses[addressString]
-		if ok {
+	for _, m := range mgmtListeners {
+		// dedup management listeners as well
+		if existingListener, added := listeners.Add(m); !added {
 			log.Debugf("Omitting listener for management address %s due to collision with service listener (%s)",
 				m.Name, existingListener.Name)
 			continue
-		} else {
-			// dedup management listeners as well
-			addresses[addressString] = m
-			builder.inboundListeners = append(builder.inboundListeners, m)
 		}
-
+		builder.inboundListeners = append(builder.inboundListeners, m)
 	}
 	return builder
 }
@@ -322,6 +310,10 @@ func (builder *ListenerBuilder) buildVirtualInboundListener(
 		UseOriginalDst: proto.BoolTrue,
 		FilterChains:   filterChains,
 	}
+	if node.PreservesSourceAddress() {
+		builder.virtualInboundListener.ListenerFilters = append(builder.virtualInboundListener.ListenerFilters,
+			newOriginalSrcListenerFilter(node))
+	}
 	if builder.useInboundFilterChain {
 		builder.aggregateVirtualInboundListener()
 	}
@@ -535,6 +527,20 @@ func newHTTPPassThroughFilterChain(configgen *ConfigGeneratorImpl, env *model.En
 	return filterChains
 }
 
+// newOriginalSrcListenerFilter returns the listener filter opening the upstream connections from the
+// address of the downstream client. The connections are marked so the replies of the application are
+// routed back to the sidecar.
+func newOriginalSrcListenerFilter(node *model.Proxy) *listener.ListenerFilter {
+	originalSrc := &original_src.OriginalSrc{Mark: inboundTProxyMark}
+	filter := &listener.ListenerFilter{Name: envoyListenerOriginalSrc}
+	if util.IsXDSMarshalingToAnyEnabled(node) {
+		filter.ConfigType = &listener.ListenerFilter_TypedConfig{TypedConfig: util.MessageToAny(originalSrc)}
+	} else {
+		filter.ConfigType = &listener.ListenerFilter_Config{Config: util.MessageToStruct(originalSrc)}
+	}
+	return filter
+}
+
 func newTCPProxyOutboundListenerFilter(env *model.Environment, node *model.Proxy) *listener.Filter {
 	tcpProxy := &tcp_proxy.TcpProxy{
 		StatPrefix:       util.BlackHoleCluster,
@@ -548,6 +554,7 @@ func newTCPProxyOutboundListenerFilter(env *model.Environment, node *model.Proxy
 		}
 		setAccessLog(env, node, tcpProxy)
 	}
+	setOutboundTrafficPolicyAccessLog(node, tcpProxy, tcpProxy.StatPrefix)
 
 	filter := listener.Filter{
 		Name: xdsutil.TCPProxy,
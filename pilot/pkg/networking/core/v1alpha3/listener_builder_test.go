@@ -292,3 +292,41 @@ func TestVirtualInboundHasPassthroughClusters(t *testing.T) {
 			xdsutil.OriginalDestination, envoyListenerHTTPInspector, l.ListenerFilters[0].Name, l.ListenerFilters[1].Name)
 	}
 }
+
+func TestVirtualInboundListenerOriginalSrc(t *testing.T) {
+	ldsEnv := getDefaultLdsEnv()
+	env := buildListenerEnv(nil)
+	if err := env.PushContext.InitContext(&env); err != nil {
+		t.Fatalf("init push context error: %s", err.Error())
+	}
+
+	for _, tc := range []struct {
+		name     string
+		mode     string
+		preserve string
+		want     bool
+	}{
+		{"tproxy", "TPROXY", "true", true},
+		{"tproxy without opt-in", "TPROXY", "", false},
+		{"redirect", "REDIRECT", "true", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := getDefaultProxy()
+			proxy.Metadata[model.NodeMetadataInterceptionMode] = tc.mode
+			proxy.Metadata[model.NodeMetadataPreserveSourceAddress] = tc.preserve
+			setNilSidecarOnProxy(&proxy, env.PushContext)
+
+			l := NewListenerBuilder(&proxy).
+				buildVirtualInboundListener(ldsEnv.configgen, &env, &proxy, env.PushContext).virtualInboundListener
+			got := false
+			for _, filter := range l.ListenerFilters {
+				if filter.Name == envoyListenerOriginalSrc {
+					got = true
+				}
+			}
+			if got != tc.want {
+				t.Errorf("expected original source listener filter %v, found %v in %v", tc.want, got, l.ListenerFilters)
+			}
+		})
+	}
+}
@@ -23,13 +23,20 @@ import (
 	"time"
 
 	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+	accesslogconfig "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v2"
+	accesslog "github.com/envoyproxy/go-control-plane/envoy/config/filter/accesslog/v2"
+	buffer "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/buffer/v2"
+	gzip "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/gzip/v2"
+	http_conn "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
 	tcp_proxy "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/tcp_proxy/v2"
 	"github.com/envoyproxy/go-control-plane/pkg/conversion"
 	xdsutil "github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/gogo/protobuf/types"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/wrappers"
 
 	networking "istio.io/api/networking/v1alpha3"
 
@@ -310,6 +317,89 @@ func TestOutboundListenerConflict_TCPWithCurrentTCP(t *testing.T) {
 	}
 }
 
+func TestOutboundListenerEgressSNIRouting(t *testing.T) {
+	defer func(enabled bool) { features.EnableEgressSNIRouting = enabled }(features.EnableEgressSNIRouting)
+
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("enabled=%v", enabled), func(t *testing.T) {
+			features.EnableEgressSNIRouting = enabled
+			services := []*model.Service{
+				buildServiceWithPort("test1.com", 443, protocol.TCP, tnow),
+				buildServiceWithPort("test2.com", 443, protocol.TCP, tnow.Add(1*time.Second)),
+			}
+			for _, svc := range services {
+				svc.MeshExternal = true
+			}
+
+			listeners := buildOutboundListeners(&fakePlugin{}, &proxy, nil, nil, services...)
+			if len(listeners) != 1 {
+				t.Fatalf("expected %d listeners, found %d", 1, len(listeners))
+			}
+
+			sniChains := map[string]string{}
+			catchAll := 0
+			for _, fc := range listeners[0].FilterChains {
+				if fc.FilterChainMatch == nil || len(fc.FilterChainMatch.ServerNames) == 0 {
+					if fc.FilterChainMatch == nil || len(fc.FilterChainMatch.PrefixRanges) == 0 {
+						catchAll++
+					}
+					continue
+				}
+				cfg, _ := conversion.MessageToStruct(fc.Filters[0].GetTypedConfig())
+				for _, sni := range fc.FilterChainMatch.ServerNames {
+					sniChains[sni] = cfg.Fields["stat_prefix"].GetStringValue()
+				}
+			}
+
+			want := map[string]string{}
+			if enabled {
+				want = map[string]string{
+					"test1.com": "outbound|443||test1.com",
+					"test2.com": "outbound|443||test2.com",
+				}
+			}
+			if !reflect.DeepEqual(sniChains, want) {
+				t.Errorf("expected SNI filter chains %v, found %v", want, sniChains)
+			}
+			if catchAll != 1 {
+				t.Errorf("expected a single port only filter chain, found %d", catchAll)
+			}
+		})
+	}
+}
+
+func TestOutboundListenerConflictWithHTTPProxy(t *testing.T) {
+	// The HTTP proxy listens on 127.0.0.1:15002 in the NONE interception mode.
+	proxy := proxy13
+	proxy.Metadata = map[string]string{
+		model.NodeMetadataConfigNamespace:  "not-default",
+		model.NodeMetadataInterceptionMode: string(model.InterceptionNone),
+		"ISTIO_VERSION":                    "1.3",
+	}
+	sidecarConfig := &model.Config{
+		ConfigMeta: model.ConfigMeta{Name: "foo", Namespace: "not-default"},
+		Spec: &networking.Sidecar{
+			Egress: []*networking.IstioEgressListener{{
+				Port:  &networking.Port{Number: uint32(features.DefaultPortHTTPProxy), Protocol: "HTTP", Name: "http"},
+				Bind:  "127.0.0.1",
+				Hosts: []string{"*/*"},
+			}},
+		},
+	}
+	listeners := buildOutboundListeners(&fakePlugin{}, &proxy, sidecarConfig, nil,
+		buildService("test.com", wildcardIP, protocol.HTTP, tnow))
+
+	bound := 0
+	for _, l := range listeners {
+		if l.Address.GetSocketAddress().GetPortValue() == uint32(features.DefaultPortHTTPProxy) {
+			bound++
+		}
+	}
+	if bound != 1 {
+		t.Fatalf("expected a single listener on port %d, found %d", features.DefaultPortHTTPProxy, bound)
+	}
+}
+
 func TestOutboundListenerTCPWithVS(t *testing.T) {
 	_ = os.Setenv("PILOT_ENABLE_FALLTHROUGH_ROUTE", "false")
 
@@ -1156,6 +1246,313 @@ func TestOutboundListenerAccessLogs(t *testing.T) {
 	}
 }
 
+func TestSetHTTP1ProtocolOptions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		metadata map[string]string
+		options  *core.Http1ProtocolOptions
+		want     *core.Http1ProtocolOptions
+	}{
+		{
+			name: "no metadata",
+		},
+		{
+			name:     "HTTP/1.0 default host",
+			metadata: map[string]string{model.NodeMetadataHTTP10DefaultHost: "legacy.example.com"},
+			want:     &core.Http1ProtocolOptions{AcceptHttp_10: true, DefaultHostForHttp_10: "legacy.example.com"},
+		},
+		{
+			name:     "allow absolute URL",
+			metadata: map[string]string{model.NodeMetadataAllowAbsoluteURL: "true"},
+			options:  &core.Http1ProtocolOptions{AcceptHttp_10: true},
+			want:     &core.Http1ProtocolOptions{AcceptHttp_10: true, AllowAbsoluteUrl: &wrappers.BoolValue{Value: true}},
+		},
+		{
+			name:     "disallow absolute URL",
+			metadata: map[string]string{model.NodeMetadataAllowAbsoluteURL: "false"},
+			options:  &core.Http1ProtocolOptions{AllowAbsoluteUrl: &wrappers.BoolValue{Value: true}},
+			want:     &core.Http1ProtocolOptions{AllowAbsoluteUrl: &wrappers.BoolValue{Value: false}},
+		},
+		{
+			name:     "invalid absolute URL setting",
+			metadata: map[string]string{model.NodeMetadataAllowAbsoluteURL: "sure"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			connectionManager := &http_conn.HttpConnectionManager{HttpProtocolOptions: tc.options}
+			setHTTP1ProtocolOptions(&model.Proxy{Metadata: tc.metadata}, connectionManager)
+			if !proto.Equal(connectionManager.HttpProtocolOptions, tc.want) {
+				t.Errorf("got HTTP/1 options %v, want %v", connectionManager.HttpProtocolOptions, tc.want)
+			}
+		})
+	}
+}
+
+func TestHTTPConnectionManagerMaxRequestHeaders(t *testing.T) {
+	m := mesh.DefaultMeshConfig()
+	env := &model.Environment{Mesh: &m}
+
+	testCases := []struct {
+		name  string
+		value string
+		want  *wrappers.UInt32Value
+	}{
+		{name: "not set"},
+		{name: "valid", value: "96", want: &wrappers.UInt32Value{Value: 96}},
+		{name: "too large", value: "97"},
+		{name: "zero", value: "0"},
+		{name: "invalid", value: "large"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			node := &model.Proxy{Metadata: map[string]string{model.NodeMetadataMaxRequestHeadersKb: tc.value}}
+			got := buildHTTPConnectionManager(node, env, &httpListenerOpts{}, nil)
+			if !proto.Equal(got.MaxRequestHeadersKb, tc.want) {
+				t.Errorf("got max request headers %v, want %v", got.MaxRequestHeadersKb, tc.want)
+			}
+		})
+	}
+}
+
+func TestHTTPConnectionManagerCompression(t *testing.T) {
+	m := mesh.DefaultMeshConfig()
+	env := &model.Environment{Mesh: &m}
+	node := &model.Proxy{Metadata: map[string]string{}}
+
+	got := buildHTTPConnectionManager(node, env, &httpListenerOpts{}, nil)
+	for _, f := range got.HttpFilters {
+		if f.Name == xdsutil.Gzip {
+			t.Fatalf("expected no gzip filter without a compression policy")
+		}
+	}
+
+	policy := &model.CompressionPolicy{ContentTypes: []string{"application/json"}, MinLength: 1024, Level: "BEST"}
+	got = buildHTTPConnectionManager(node, env, &httpListenerOpts{compression: policy}, nil)
+	var filter *http_conn.HttpFilter
+	for _, f := range got.HttpFilters {
+		if f.Name == xdsutil.Gzip {
+			filter = f
+		}
+	}
+	if filter == nil {
+		t.Fatalf("expected a gzip filter, got %v", got.HttpFilters)
+	}
+	if last := got.HttpFilters[len(got.HttpFilters)-1]; last.Name != xdsutil.Router {
+		t.Errorf("expected the router filter last, got %s", last.Name)
+	}
+	config := &gzip.Gzip{}
+	if err := ptypes.UnmarshalAny(filter.GetTypedConfig(), config); err != nil {
+		t.Fatal(err)
+	}
+	want := &gzip.Gzip{
+		ContentType:      []string{"application/json"},
+		ContentLength:    &wrappers.UInt32Value{Value: 1024},
+		CompressionLevel: gzip.Gzip_CompressionLevel_BEST,
+	}
+	if !proto.Equal(config, want) {
+		t.Errorf("got gzip config %v, want %v", config, want)
+	}
+}
+
+func TestHTTPConnectionManagerMaxRequestBodyBytes(t *testing.T) {
+	m := mesh.DefaultMeshConfig()
+	env := &model.Environment{Mesh: &m}
+	node := &model.Proxy{Metadata: map[string]string{}}
+
+	bufferFilter := func(cm *http_conn.HttpConnectionManager) *http_conn.HttpFilter {
+		for _, f := range cm.HttpFilters {
+			if f.Name == xdsutil.Buffer {
+				return f
+			}
+		}
+		return nil
+	}
+	if f := bufferFilter(buildHTTPConnectionManager(node, env, &httpListenerOpts{}, nil)); f != nil {
+		t.Fatalf("expected no buffer filter without a maximum request body size, got %v", f)
+	}
+
+	f := bufferFilter(buildHTTPConnectionManager(node, env, &httpListenerOpts{maxRequestBodyBytes: 1024}, nil))
+	if f == nil {
+		t.Fatal("expected a buffer filter")
+	}
+	config := &buffer.Buffer{}
+	if err := ptypes.UnmarshalAny(f.GetTypedConfig(), config); err != nil {
+		t.Fatal(err)
+	}
+	if got := config.MaxRequestBytes.GetValue(); got != 1024 {
+		t.Errorf("got max request bytes %d, want 1024", got)
+	}
+}
+
+func TestHTTPConnectionManagerAdaptiveConcurrency(t *testing.T) {
+	m := mesh.DefaultMeshConfig()
+	env := &model.Environment{Mesh: &m}
+	node := &model.Proxy{Metadata: map[string]string{}, IstioVersion: &model.IstioVersion{Major: 1, Minor: 4}}
+
+	ac := model.ParseAdaptiveConcurrency(`{"maxConcurrencyLimit": 500, "minRttRequestCount": 20}`)
+	pluginFilter := &http_conn.HttpFilter{Name: "plugin"}
+
+	// Older proxies do not have the filter.
+	old := &model.Proxy{Metadata: map[string]string{}, IstioVersion: &model.IstioVersion{Major: 1, Minor: 3}}
+	got := buildHTTPConnectionManager(old, env, &httpListenerOpts{adaptiveConcurrency: ac}, []*http_conn.HttpFilter{pluginFilter})
+	if got.HttpFilters[0].Name != "plugin" {
+		t.Fatalf("expected no adaptive concurrency filter for a 1.3 proxy, got %v", got.HttpFilters)
+	}
+
+	got = buildHTTPConnectionManager(node, env, &httpListenerOpts{adaptiveConcurrency: ac}, []*http_conn.HttpFilter{pluginFilter})
+	if len(got.HttpFilters) < 2 || got.HttpFilters[0].Name != adaptiveConcurrencyFilter || got.HttpFilters[1].Name != "plugin" {
+		t.Fatalf("expected the adaptive concurrency filter first, got %v", got.HttpFilters)
+	}
+
+	gradient := got.HttpFilters[0].GetConfig().Fields["gradient_controller_config"].GetStructValue().Fields
+	if got := gradient["sample_aggregate_percentile"].GetStructValue().Fields["value"].GetNumberValue(); got != 50 {
+		t.Errorf("got percentile %v, want 50", got)
+	}
+	limit := gradient["concurrency_limit_params"].GetStructValue().Fields
+	if got := limit["max_concurrency_limit"].GetNumberValue(); got != 500 {
+		t.Errorf("got max concurrency limit %v, want 500", got)
+	}
+	if got := limit["concurrency_update_interval"].GetStringValue(); got != "0.1s" {
+		t.Errorf("got concurrency update interval %q, want 0.1s", got)
+	}
+	minRTT := gradient["min_rtt_calc_params"].GetStructValue().Fields
+	if got := minRTT["interval"].GetStringValue(); got != "60s" {
+		t.Errorf("got min RTT interval %q, want 60s", got)
+	}
+	if got := minRTT["request_count"].GetNumberValue(); got != 20 {
+		t.Errorf("got min RTT request count %v, want 20", got)
+	}
+}
+
+func TestBuildResponseCacheFilter(t *testing.T) {
+	filter := buildResponseCacheFilter(&model.GatewayResponseCache{
+		ExcludeHost:             true,
+		QueryParametersExcluded: []string{"utm_source"},
+		MaxBodyBytes:            1 << 20,
+	})
+	if filter.Name != responseCacheFilter {
+		t.Errorf("got filter %s, want %s", filter.Name, responseCacheFilter)
+	}
+	fields := filter.GetConfig().Fields
+	if got := fields["typed_config"].GetStructValue().Fields["@type"].GetStringValue(); got != simpleHTTPCacheType {
+		t.Errorf("got cache storage %q, want %q", got, simpleHTTPCacheType)
+	}
+	params := fields["key_creator_params"].GetStructValue().Fields
+	if params["exclude_scheme"].GetBoolValue() || !params["exclude_host"].GetBoolValue() {
+		t.Errorf("got key creator params %v", params)
+	}
+	if _, f := params["query_parameters_included"]; f {
+		t.Errorf("expected no included query parameters, got %v", params)
+	}
+	excluded := params["query_parameters_excluded"].GetListValue().GetValues()
+	if len(excluded) != 1 || excluded[0].GetStructValue().Fields["name"].GetStringValue() != "utm_source" {
+		t.Errorf("got excluded query parameters %v", excluded)
+	}
+	if got := fields["max_body_bytes"].GetNumberValue(); got != 1<<20 {
+		t.Errorf("got max body bytes %v, want %v", got, 1<<20)
+	}
+}
+
+func TestHTTPConnectionManagerResponseCache(t *testing.T) {
+	m := mesh.DefaultMeshConfig()
+	env := &model.Environment{Mesh: &m}
+	opts := &httpListenerOpts{responseCache: &model.GatewayResponseCache{}}
+	hasCache := func(node *model.Proxy) bool {
+		for _, f := range buildHTTPConnectionManager(node, env, opts, nil).HttpFilters {
+			if f.Name == responseCacheFilter {
+				return true
+			}
+		}
+		return false
+	}
+
+	if hasCache(&model.Proxy{Metadata: map[string]string{}, IstioVersion: &model.IstioVersion{Major: 1, Minor: 4}}) {
+		t.Errorf("expected no cache filter for a proxy without it")
+	}
+	if !hasCache(&model.Proxy{Metadata: map[string]string{}, IstioVersion: &model.IstioVersion{Major: 1, Minor: 6}}) {
+		t.Errorf("expected a cache filter for a proxy with it")
+	}
+}
+
+func TestSetServerHeaders(t *testing.T) {
+	defer func(transformation, via string) {
+		features.ServerHeaderTransformation, features.ViaHeader = transformation, via
+	}(features.ServerHeaderTransformation, features.ViaHeader)
+	features.ServerHeaderTransformation, features.ViaHeader = "APPEND_IF_ABSENT", "1.1 mesh"
+
+	testCases := []struct {
+		name               string
+		serverHeader       *model.GatewayServerHeader
+		wantTransformation http_conn.HttpConnectionManager_ServerHeaderTransformation
+		wantVia            string
+	}{
+		{
+			name:               "mesh settings",
+			wantTransformation: http_conn.HttpConnectionManager_APPEND_IF_ABSENT,
+			wantVia:            "1.1 mesh",
+		},
+		{
+			name:               "gateway override",
+			serverHeader:       &model.GatewayServerHeader{Transformation: "PASS_THROUGH", Via: "1.1 edge"},
+			wantTransformation: http_conn.HttpConnectionManager_PASS_THROUGH,
+			wantVia:            "1.1 edge",
+		},
+		{
+			name:               "invalid gateway transformation",
+			serverHeader:       &model.GatewayServerHeader{Transformation: "DROP"},
+			wantTransformation: http_conn.HttpConnectionManager_OVERWRITE,
+			wantVia:            "1.1 mesh",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			connectionManager := &http_conn.HttpConnectionManager{}
+			setServerHeaders(connectionManager, tc.serverHeader)
+			if connectionManager.ServerHeaderTransformation != tc.wantTransformation {
+				t.Errorf("got server header transformation %v, want %v", connectionManager.ServerHeaderTransformation, tc.wantTransformation)
+			}
+			if connectionManager.Via != tc.wantVia {
+				t.Errorf("got via %q, want %q", connectionManager.Via, tc.wantVia)
+			}
+		})
+	}
+}
+
+func TestBuildGatewayAccessLog(t *testing.T) {
+	env := buildListenerEnv(nil)
+	acc := buildGatewayAccessLog(&proxy13Gateway, &env, &model.GatewayAccessLog{
+		File:          "/dev/stderr",
+		Encoding:      "JSON",
+		MinStatusCode: 400,
+	})
+
+	fl := &accesslogconfig.FileAccessLog{}
+	switch c := acc.ConfigType.(type) {
+	case *accesslog.AccessLog_TypedConfig:
+		if err := ptypes.UnmarshalAny(c.TypedConfig, fl); err != nil {
+			t.Fatal(err)
+		}
+	case *accesslog.AccessLog_Config:
+		if err := conversion.StructToMessage(c.Config, fl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fl.Path != "/dev/stderr" {
+		t.Errorf("got access log path %q, want /dev/stderr", fl.Path)
+	}
+	if fl.GetJsonFormat() == nil {
+		t.Errorf("expected the JSON encoding of the gateway to override the TEXT encoding of the mesh, got %v", fl)
+	}
+	comparison := acc.GetFilter().GetStatusCodeFilter().GetComparison()
+	if comparison.GetOp() != accesslog.ComparisonFilter_GE || comparison.GetValue().GetDefaultValue() != 400 {
+		t.Errorf("got access log filter %v, want status >= 400", acc.GetFilter())
+	}
+}
+
 func verifyOutboundTCPListenerHostname(t *testing.T, l *xdsapi.Listener, hostname host.Name) {
 	t.Helper()
 	if len(l.FilterChains) != 1 {
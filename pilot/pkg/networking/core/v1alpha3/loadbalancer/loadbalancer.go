@@ -21,14 +21,21 @@ import (
 
 	apiv2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
 	"github.com/golang/protobuf/ptypes/wrappers"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/networking/util"
 )
 
+// ApplyLocalityLBSetting sets the weights or the priorities of the endpoints of the load assignment,
+// relative to the locality and the cluster of the proxy. When failing over, endpoints in other
+// clusters than clusterID get a lower priority than the endpoints of the same locality in the
+// cluster of the proxy, so that traffic only leaves the cluster once its endpoints are unhealthy.
 func ApplyLocalityLBSetting(
 	locality *core.Locality,
+	clusterID string,
+	network string,
 	loadAssignment *apiv2.ClusterLoadAssignment,
 	localityLB *meshconfig.LocalityLoadBalancerSetting,
 	enableFailover bool,
@@ -42,7 +49,7 @@ func ApplyLocalityLBSetting(
 		applyLocalityWeight(locality, loadAssignment, localityLB.GetDistribute())
 	} else if enableFailover {
 		// Failover needs outlier detection, otherwise Envoy will never drop down to a lower priority.
-		applyLocalityFailover(locality, loadAssignment, localityLB.GetFailover())
+		applyLocalityFailover(locality, clusterID, network, loadAssignment, localityLB.GetFailover())
 	}
 }
 
@@ -106,11 +113,15 @@ func applyLocalityWeight(
 // set locality loadbalancing priority
 func applyLocalityFailover(
 	locality *core.Locality,
+	clusterID string,
+	network string,
 	loadAssignment *apiv2.ClusterLoadAssignment,
 	failover []*meshconfig.LocalityLoadBalancerSetting_Failover) {
 	// key is priority, value is the index of the LocalityLbEndpoints in ClusterLoadAssignment
 	priorityMap := map[int][]int{}
 
+	remote := splitRemoteClusterEndpoints(clusterID, network, loadAssignment)
+
 	// 1. calculate the LocalityLbEndpoints.Priority compared with proxy locality
 	for i, localityEndpoint := range loadAssignment.Endpoints {
 		// if region/zone/subZone all match, the priority is 0.
@@ -130,6 +141,11 @@ func applyLocalityFailover(
 				}
 			}
 		}
+		// within a locality priority, endpoints of remote clusters come after the local ones.
+		priority *= 2
+		if remote[i] {
+			priority++
+		}
 		loadAssignment.Endpoints[i].Priority = uint32(priority)
 		priorityMap[priority] = append(priorityMap[priority], i)
 	}
@@ -154,3 +170,56 @@ func applyLocalityFailover(
 	}
 
 }
+
+// splitRemoteClusterEndpoints moves the endpoints of other clusters than clusterID, or of other networks
+// than network, to LocalityLbEndpoints of their own, and returns the indexes of these LocalityLbEndpoints.
+// Endpoints without a cluster are considered local, unless they are in another network, as are the
+// gateways standing for the endpoints of the other networks.
+func splitRemoteClusterEndpoints(clusterID, network string, loadAssignment *apiv2.ClusterLoadAssignment) map[int]bool {
+	remote := map[int]bool{}
+	if clusterID == "" {
+		return remote
+	}
+
+	for i, localityEndpoint := range loadAssignment.Endpoints {
+		var local, remoteEps []*endpoint.LbEndpoint
+		for _, ep := range localityEndpoint.LbEndpoints {
+			c := util.IstioMetadata(ep.Metadata, "cluster")
+			if c != "" && c != clusterID || util.IstioMetadata(ep.Metadata, "network") != network {
+				remoteEps = append(remoteEps, ep)
+			} else {
+				local = append(local, ep)
+			}
+		}
+		if len(remoteEps) == 0 {
+			continue
+		}
+		if len(local) == 0 {
+			remote[i] = true
+			continue
+		}
+
+		split := *localityEndpoint
+		split.LbEndpoints = remoteEps
+		localityEndpoint.LbEndpoints = local
+		if localityEndpoint.LoadBalancingWeight != nil {
+			localityEndpoint.LoadBalancingWeight = &wrappers.UInt32Value{Value: lbEndpointsWeight(local)}
+			split.LoadBalancingWeight = &wrappers.UInt32Value{Value: lbEndpointsWeight(remoteEps)}
+		}
+		remote[len(loadAssignment.Endpoints)] = true
+		loadAssignment.Endpoints = append(loadAssignment.Endpoints, &split)
+	}
+	return remote
+}
+
+func lbEndpointsWeight(eps []*endpoint.LbEndpoint) uint32 {
+	var weight uint32
+	for _, ep := range eps {
+		if ep.LoadBalancingWeight != nil {
+			weight += ep.LoadBalancingWeight.Value
+		} else {
+			weight++
+		}
+	}
+	return weight
+}
@@ -22,6 +22,8 @@ import (
 	envoycore "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
 	"github.com/gogo/protobuf/types"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/golang/protobuf/ptypes/wrappers"
 	. "github.com/onsi/gomega"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
@@ -29,6 +31,7 @@ import (
 
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/core/v1alpha3/fakes"
+	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schemas"
 )
@@ -65,7 +68,7 @@ func TestApplyLocalitySetting(t *testing.T) {
 			t.Run(tt.name, func(t *testing.T) {
 				env := buildEnvForClustersWithDistribute(tt.distribute)
 				cluster := buildFakeCluster()
-				ApplyLocalityLBSetting(locality, cluster.LoadAssignment, env.Mesh.LocalityLbSetting, true)
+				ApplyLocalityLBSetting(locality, "", "", cluster.LoadAssignment, env.Mesh.LocalityLbSetting, true)
 				weights := make([]int, 0)
 				for _, localityEndpoint := range cluster.LoadAssignment.Endpoints {
 					weights = append(weights, int(localityEndpoint.LoadBalancingWeight.GetValue()))
@@ -81,7 +84,7 @@ func TestApplyLocalitySetting(t *testing.T) {
 		g := NewGomegaWithT(t)
 		env := buildEnvForClustersWithFailover()
 		cluster := buildFakeCluster()
-		ApplyLocalityLBSetting(locality, cluster.LoadAssignment, env.Mesh.LocalityLbSetting, true)
+		ApplyLocalityLBSetting(locality, "", "", cluster.LoadAssignment, env.Mesh.LocalityLbSetting, true)
 		for _, localityEndpoint := range cluster.LoadAssignment.Endpoints {
 			if localityEndpoint.Locality.Region == locality.Region {
 				if localityEndpoint.Locality.Zone == locality.Zone {
@@ -107,7 +110,7 @@ func TestApplyLocalitySetting(t *testing.T) {
 		g := NewGomegaWithT(t)
 		env := buildEnvForClustersWithFailover()
 		cluster := buildSmallCluster()
-		ApplyLocalityLBSetting(locality, cluster.LoadAssignment, env.Mesh.LocalityLbSetting, true)
+		ApplyLocalityLBSetting(locality, "", "", cluster.LoadAssignment, env.Mesh.LocalityLbSetting, true)
 		for _, localityEndpoint := range cluster.LoadAssignment.Endpoints {
 			if localityEndpoint.Locality.Region == locality.Region {
 				if localityEndpoint.Locality.Zone == locality.Zone {
@@ -133,7 +136,7 @@ func TestApplyLocalitySetting(t *testing.T) {
 		g := NewGomegaWithT(t)
 		env := buildEnvForClustersWithFailover()
 		cluster := buildSmallClusterWithNilLocalities()
-		ApplyLocalityLBSetting(locality, cluster.LoadAssignment, env.Mesh.LocalityLbSetting, true)
+		ApplyLocalityLBSetting(locality, "", "", cluster.LoadAssignment, env.Mesh.LocalityLbSetting, true)
 		for _, localityEndpoint := range cluster.LoadAssignment.Endpoints {
 			if localityEndpoint.Locality == nil {
 				g.Expect(localityEndpoint.Priority).To(Equal(uint32(2)))
@@ -157,6 +160,105 @@ func TestApplyLocalitySetting(t *testing.T) {
 	})
 }
 
+func TestApplyLocalityFailoverAcrossClusters(t *testing.T) {
+	g := NewGomegaWithT(t)
+	locality := &envoycore.Locality{Region: "region1", Zone: "zone1", SubZone: "subzone1"}
+	env := buildEnvForClustersWithFailover()
+	loadAssignment := &apiv2.ClusterLoadAssignment{
+		ClusterName: "outbound|8080||test.example.org",
+		Endpoints: []*endpoint.LocalityLbEndpoints{
+			{
+				Locality:            locality,
+				LoadBalancingWeight: &wrappers.UInt32Value{Value: 3},
+				LbEndpoints: []*endpoint.LbEndpoint{
+					buildClusterEndpoint("cluster1", 1),
+					buildClusterEndpoint("cluster2", 2),
+				},
+			},
+			{
+				Locality:    &envoycore.Locality{Region: "region1", Zone: "zone2"},
+				LbEndpoints: []*endpoint.LbEndpoint{buildClusterEndpoint("cluster1", 1)},
+			},
+			{
+				Locality:    &envoycore.Locality{Region: "region1", Zone: "zone2"},
+				LbEndpoints: []*endpoint.LbEndpoint{buildClusterEndpoint("cluster2", 1)},
+			},
+		},
+	}
+
+	ApplyLocalityLBSetting(locality, "cluster1", "", loadAssignment, env.Mesh.LocalityLbSetting, true)
+
+	g.Expect(loadAssignment.Endpoints).To(HaveLen(4))
+	type result struct {
+		zone     string
+		cluster  string
+		weight   uint32
+		priority uint32
+	}
+	got := make([]result, 0, len(loadAssignment.Endpoints))
+	for _, localityEndpoint := range loadAssignment.Endpoints {
+		g.Expect(localityEndpoint.LbEndpoints).To(HaveLen(1))
+		got = append(got, result{
+			zone:     localityEndpoint.Locality.Zone,
+			cluster:  util.IstioMetadata(localityEndpoint.LbEndpoints[0].Metadata, "cluster"),
+			weight:   localityEndpoint.LoadBalancingWeight.GetValue(),
+			priority: localityEndpoint.Priority,
+		})
+	}
+	g.Expect(got).To(ConsistOf(
+		result{zone: "zone1", cluster: "cluster1", weight: 1, priority: 0},
+		result{zone: "zone1", cluster: "cluster2", weight: 2, priority: 1},
+		result{zone: "zone2", cluster: "cluster1", priority: 2},
+		result{zone: "zone2", cluster: "cluster2", priority: 3},
+	))
+}
+
+func TestApplyLocalityFailoverAcrossNetworks(t *testing.T) {
+	g := NewGomegaWithT(t)
+	locality := &envoycore.Locality{Region: "region1", Zone: "zone1", SubZone: "subzone1"}
+	env := buildEnvForClustersWithFailover()
+	// The gateway of network2, as added by the network filter, has no cluster.
+	gateway := buildClusterEndpoint("", 2)
+	gateway.Metadata.FilterMetadata[util.IstioMetadataKey].Fields["network"] =
+		&structpb.Value{Kind: &structpb.Value_StringValue{StringValue: "network2"}}
+	local := buildClusterEndpoint("cluster1", 1)
+	local.Metadata.FilterMetadata[util.IstioMetadataKey].Fields["network"] =
+		&structpb.Value{Kind: &structpb.Value_StringValue{StringValue: "network1"}}
+	loadAssignment := &apiv2.ClusterLoadAssignment{
+		ClusterName: "outbound|8080||test.example.org",
+		Endpoints: []*endpoint.LocalityLbEndpoints{
+			{
+				Locality:            locality,
+				LoadBalancingWeight: &wrappers.UInt32Value{Value: 3},
+				LbEndpoints:         []*endpoint.LbEndpoint{local, gateway},
+			},
+		},
+	}
+
+	ApplyLocalityLBSetting(locality, "cluster1", "network1", loadAssignment, env.Mesh.LocalityLbSetting, true)
+
+	g.Expect(loadAssignment.Endpoints).To(HaveLen(2))
+	g.Expect(loadAssignment.Endpoints[0].LbEndpoints).To(Equal([]*endpoint.LbEndpoint{local}))
+	g.Expect(loadAssignment.Endpoints[0].Priority).To(Equal(uint32(0)))
+	g.Expect(loadAssignment.Endpoints[1].LbEndpoints).To(Equal([]*endpoint.LbEndpoint{gateway}))
+	g.Expect(loadAssignment.Endpoints[1].Priority).To(Equal(uint32(1)))
+}
+
+func buildClusterEndpoint(cluster string, weight uint32) *endpoint.LbEndpoint {
+	return &endpoint.LbEndpoint{
+		LoadBalancingWeight: &wrappers.UInt32Value{Value: weight},
+		Metadata: &envoycore.Metadata{
+			FilterMetadata: map[string]*structpb.Struct{
+				util.IstioMetadataKey: {
+					Fields: map[string]*structpb.Value{
+						"cluster": {Kind: &structpb.Value_StringValue{StringValue: cluster}},
+					},
+				},
+			},
+		},
+	}
+}
+
 func buildEnvForClustersWithDistribute(distribute []*meshconfig.LocalityLoadBalancerSetting_Distribute) *model.Environment {
 	serviceDiscovery := &fakes.ServiceDiscovery{}
 
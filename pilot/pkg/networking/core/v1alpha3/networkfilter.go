@@ -25,9 +25,10 @@ import (
 	mysql_proxy "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/mysql_proxy/v1alpha1"
 	redis_proxy "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/redis_proxy/v2"
 	tcp_proxy "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/tcp_proxy/v2"
-	"github.com/envoyproxy/go-control-plane/pkg/conversion"
+	thrift_proxy "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/thrift_proxy/v2alpha1"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/golang/protobuf/ptypes"
+	structpb "github.com/golang/protobuf/ptypes/struct"
 
 	networking "istio.io/api/networking/v1alpha3"
 
@@ -39,9 +40,35 @@ import (
 	"istio.io/istio/pkg/config/protocol"
 )
 
+// thriftProxyFilter is the name of the Envoy Thrift proxy network filter.
+const thriftProxyFilter = "envoy.filters.network.thrift_proxy"
+
+// kafkaBrokerFilter is the name of the Envoy Kafka broker network filter.
+const kafkaBrokerFilter = "envoy.filters.network.kafka_broker"
+
 // redisOpTimeout is the default operation timeout for the Redis proxy filter.
 var redisOpTimeout = 5 * time.Second
 
+// outboundTrafficPolicyLogFormat is the format of the access log of the connections handled by the
+// outbound traffic policy. The original destination and the SNI identify the unregistered dependencies.
+const outboundTrafficPolicyLogFormat = "[%%START_TIME%%] %s %%DOWNSTREAM_REMOTE_ADDRESS%% %%DOWNSTREAM_LOCAL_ADDRESS%% " +
+	"\"%%REQUESTED_SERVER_NAME%%\" %%BYTES_SENT%% %%BYTES_RECEIVED%% %%DURATION%% %%RESPONSE_FLAGS%%\n"
+
+// tcpAuthzLogFormat is the format of the access log of the authorization decisions on inbound TCP
+// connections. The network RBAC filter only records the decision of its shadow rules, which mirror
+// the enforced ones when this log is enabled: a denied connection has a "denied" result and, as no
+// policy allowed it, no policy.
+var tcpAuthzLogFormat = &structpb.Struct{
+	Fields: map[string]*structpb.Value{
+		"start_time":       {Kind: &structpb.Value_StringValue{StringValue: "%START_TIME%"}},
+		"result":           {Kind: &structpb.Value_StringValue{StringValue: "%DYNAMIC_METADATA(envoy.filters.network.rbac:shadow_engine_result)%"}},
+		"policy":           {Kind: &structpb.Value_StringValue{StringValue: "%DYNAMIC_METADATA(envoy.filters.network.rbac:shadow_effective_policy_id)%"}},
+		"source_principal": {Kind: &structpb.Value_StringValue{StringValue: "%DOWNSTREAM_PEER_URI_SAN%"}},
+		"source_address":   {Kind: &structpb.Value_StringValue{StringValue: "%DOWNSTREAM_REMOTE_ADDRESS%"}},
+		"destination":      {Kind: &structpb.Value_StringValue{StringValue: "%DOWNSTREAM_LOCAL_ADDRESS%"}},
+	},
+}
+
 // buildInboundNetworkFilters generates a TCP proxy network filter on the inbound path
 func buildInboundNetworkFilters(env *model.Environment, node *model.Proxy, instance *model.ServiceInstance) []*listener.Filter {
 	clusterName := model.BuildSubsetKey(model.TrafficDirectionInbound, instance.Endpoint.ServicePort.Name,
@@ -50,6 +77,7 @@ func buildInboundNetworkFilters(env *model.Environment, node *model.Proxy, insta
 		StatPrefix:       clusterName,
 		ClusterSpecifier: &tcp_proxy.TcpProxy_Cluster{Cluster: clusterName},
 	}
+	setTCPAuthzAccessLog(node, tcpProxy, clusterName)
 	tcpFilter := setAccessLogAndBuildTCPFilter(env, node, tcpProxy)
 	return buildNetworkFiltersStack(node, instance.Endpoint.ServicePort, tcpFilter, clusterName, clusterName)
 }
@@ -57,26 +85,68 @@ func buildInboundNetworkFilters(env *model.Environment, node *model.Proxy, insta
 // setAccessLog sets the AccessLog configuration in the given TcpProxy instance.
 func setAccessLog(env *model.Environment, node *model.Proxy, config *tcp_proxy.TcpProxy) *tcp_proxy.TcpProxy {
 	if env.Mesh.AccessLogFile != "" {
-		fl := &accesslogconfig.FileAccessLog{
-			Path: env.Mesh.AccessLogFile,
+		if acc := buildMeshFileAccessLog(node, env); acc != nil {
+			config.AccessLog = append(config.AccessLog, acc)
 		}
+	}
 
-		acc := &accesslog.AccessLog{
-			Name: wellknown.FileAccessLog,
-		}
-		buildAccessLog(node, fl, env)
+	// envoy als is not enabled for tcp
+	return config
+}
 
-		if util.IsXDSMarshalingToAnyEnabled(node) {
-			acc.ConfigType = &accesslog.AccessLog_TypedConfig{TypedConfig: util.MessageToAny(fl)}
-		} else {
-			c, _ := conversion.MessageToStruct(fl)
-			acc.ConfigType = &accesslog.AccessLog_Config{Config: c}
-		}
+// setOutboundTrafficPolicyAccessLog adds the dedicated access log of the connections forwarded to
+// the PassthroughCluster or the BlackHoleCluster to the given TcpProxy instance, if enabled.
+func setOutboundTrafficPolicyAccessLog(node *model.Proxy, config *tcp_proxy.TcpProxy, clusterName string) *tcp_proxy.TcpProxy {
+	if features.OutboundTrafficPolicyAccessLogFile == "" {
+		return config
+	}
+
+	fl := &accesslogconfig.FileAccessLog{
+		Path: features.OutboundTrafficPolicyAccessLogFile,
+		AccessLogFormat: &accesslogconfig.FileAccessLog_Format{
+			Format: fmt.Sprintf(outboundTrafficPolicyLogFormat, clusterName),
+		},
+	}
+	acc := &accesslog.AccessLog{
+		Name: wellknown.FileAccessLog,
+	}
+	if util.IsXDSMarshalingToAnyEnabled(node) {
+		acc.ConfigType = &accesslog.AccessLog_TypedConfig{TypedConfig: util.MessageToAny(fl)}
+	} else {
+		acc.ConfigType = &accesslog.AccessLog_Config{Config: util.MessageToStruct(fl)}
+	}
+	config.AccessLog = append(config.AccessLog, acc)
+	return config
+}
 
-		config.AccessLog = append(config.AccessLog, acc)
+// setTCPAuthzAccessLog adds the dedicated access log of the authorization decisions to the given
+// inbound TcpProxy instance, if enabled. The TCP proxy logs the connections the RBAC filter in front
+// of it closed too.
+func setTCPAuthzAccessLog(node *model.Proxy, config *tcp_proxy.TcpProxy, clusterName string) *tcp_proxy.TcpProxy {
+	if features.TCPAuthzAccessLogFile == "" {
+		return config
 	}
 
-	// envoy als is not enabled for tcp
+	format := &structpb.Struct{Fields: make(map[string]*structpb.Value, len(tcpAuthzLogFormat.Fields)+1)}
+	for k, v := range tcpAuthzLogFormat.Fields {
+		format.Fields[k] = v
+	}
+	format.Fields["cluster"] = &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: clusterName}}
+	fl := &accesslogconfig.FileAccessLog{
+		Path: features.TCPAuthzAccessLogFile,
+		AccessLogFormat: &accesslogconfig.FileAccessLog_JsonFormat{
+			JsonFormat: format,
+		},
+	}
+	acc := &accesslog.AccessLog{
+		Name: wellknown.FileAccessLog,
+	}
+	if util.IsXDSMarshalingToAnyEnabled(node) {
+		acc.ConfigType = &accesslog.AccessLog_TypedConfig{TypedConfig: util.MessageToAny(fl)}
+	} else {
+		acc.ConfigType = &accesslog.AccessLog_Config{Config: util.MessageToStruct(fl)}
+	}
+	config.AccessLog = append(config.AccessLog, acc)
 	return config
 }
 
@@ -160,7 +230,10 @@ func buildNetworkFiltersStack(node *model.Proxy, port *model.Port, tcpFilter *li
 	filterstack := make([]*listener.Filter, 0)
 	switch port.Protocol {
 	case protocol.Mongo:
-		filterstack = append(filterstack, buildMongoFilter(statPrefix, util.IsXDSMarshalingToAnyEnabled(node)), tcpFilter)
+		if features.EnableMongoFilter.Get() {
+			filterstack = append(filterstack, buildMongoFilter(statPrefix, util.IsXDSMarshalingToAnyEnabled(node)))
+		}
+		filterstack = append(filterstack, tcpFilter)
 	case protocol.Redis:
 		if features.EnableRedisFilter.Get() {
 			// redis filter has route config, it is a terminating filter, no need append tcp filter.
@@ -173,6 +246,18 @@ func buildNetworkFiltersStack(node *model.Proxy, port *model.Port, tcpFilter *li
 			filterstack = append(filterstack, buildMySQLFilter(statPrefix, util.IsXDSMarshalingToAnyEnabled(node)))
 		}
 		filterstack = append(filterstack, tcpFilter)
+	case protocol.Thrift:
+		if features.EnableThriftFilter.Get() {
+			// thrift filter routes the requests, it is a terminating filter, no need append tcp filter.
+			filterstack = append(filterstack, buildThriftFilter(statPrefix, clusterName, util.IsXDSMarshalingToAnyEnabled(node)))
+		} else {
+			filterstack = append(filterstack, tcpFilter)
+		}
+	case protocol.Kafka:
+		if features.EnableKafkaFilter.Get() && util.IsIstioVersionGE16(node) {
+			filterstack = append(filterstack, buildKafkaBrokerFilter(statPrefix))
+		}
+		filterstack = append(filterstack, tcpFilter)
 	default:
 		filterstack = append(filterstack, tcpFilter)
 	}
@@ -279,3 +364,48 @@ func buildMySQLFilter(statPrefix string, isXDSMarshalingToAnyEnabled bool) *list
 
 	return out
 }
+
+// buildThriftFilter builds an Envoy ThriftProxy filter. The transport and protocol of the
+// requests are detected, and all the methods are routed to the cluster.
+func buildThriftFilter(statPrefix, clusterName string, isXDSMarshalingToAnyEnabled bool) *listener.Filter {
+	thriftProxy := &thrift_proxy.ThriftProxy{
+		StatPrefix: statPrefix, // thrift stats are prefixed with thrift.<statPrefix> by Envoy
+		RouteConfig: &thrift_proxy.RouteConfiguration{
+			Name: statPrefix,
+			Routes: []*thrift_proxy.Route{{
+				// An empty method name matches all the methods.
+				Match: &thrift_proxy.RouteMatch{
+					MatchSpecifier: &thrift_proxy.RouteMatch_MethodName{MethodName: ""},
+				},
+				Route: &thrift_proxy.RouteAction{
+					ClusterSpecifier: &thrift_proxy.RouteAction_Cluster{Cluster: clusterName},
+				},
+			}},
+		},
+	}
+
+	out := &listener.Filter{
+		Name: thriftProxyFilter,
+	}
+	if isXDSMarshalingToAnyEnabled {
+		out.ConfigType = &listener.Filter_TypedConfig{TypedConfig: util.MessageToAny(thriftProxy)}
+	} else {
+		out.ConfigType = &listener.Filter_Config{Config: util.MessageToStruct(thriftProxy)}
+	}
+
+	return out
+}
+
+// buildKafkaBrokerFilter builds an Envoy KafkaBroker filter, recording the stats of the Kafka requests
+// and responses. The filter is not in the xDS API of the proxy, so its config is not typed.
+func buildKafkaBrokerFilter(statPrefix string) *listener.Filter {
+	return &listener.Filter{
+		Name: kafkaBrokerFilter,
+		ConfigType: &listener.Filter_Config{Config: &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				// kafka stats are prefixed with kafka.<statPrefix> by Envoy
+				"stat_prefix": {Kind: &structpb.Value_StringValue{StringValue: statPrefix}},
+			},
+		}},
+	}
+}
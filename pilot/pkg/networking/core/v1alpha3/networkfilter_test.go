@@ -15,12 +15,22 @@
 package v1alpha3
 
 import (
+	"os"
 	"testing"
 
 	listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+	accesslogconfig "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v2"
+	accesslog "github.com/envoyproxy/go-control-plane/envoy/config/filter/accesslog/v2"
 	redis_proxy "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/redis_proxy/v2"
+	tcp_proxy "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/tcp_proxy/v2"
+	thrift_proxy "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/thrift_proxy/v2alpha1"
 	xdsutil "github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/golang/protobuf/ptypes"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pkg/config/protocol"
 )
 
 func TestBuildRedisFilter(t *testing.T) {
@@ -54,3 +64,157 @@ func TestBuildRedisFilter(t *testing.T) {
 		t.Errorf("redis filter type is %T not listener.Filter_Config ", redisFilter.ConfigType)
 	}
 }
+
+func TestSetOutboundTrafficPolicyAccessLog(t *testing.T) {
+	defer func(path string) { features.OutboundTrafficPolicyAccessLogFile = path }(features.OutboundTrafficPolicyAccessLogFile)
+	node := &model.Proxy{Metadata: map[string]string{}, IstioVersion: model.MaxIstioVersion}
+
+	features.OutboundTrafficPolicyAccessLogFile = ""
+	tcpProxy := setOutboundTrafficPolicyAccessLog(node, &tcp_proxy.TcpProxy{}, util.PassthroughCluster)
+	if len(tcpProxy.AccessLog) != 0 {
+		t.Fatalf("unexpected access log %v", tcpProxy.AccessLog)
+	}
+
+	features.OutboundTrafficPolicyAccessLogFile = "/dev/stdout"
+	tcpProxy = setOutboundTrafficPolicyAccessLog(node, &tcp_proxy.TcpProxy{}, util.BlackHoleCluster)
+	if len(tcpProxy.AccessLog) != 1 {
+		t.Fatalf("expected a single access log, got %v", tcpProxy.AccessLog)
+	}
+	config, ok := tcpProxy.AccessLog[0].ConfigType.(*accesslog.AccessLog_TypedConfig)
+	if !ok {
+		t.Fatalf("access log config type is %T not accesslog.AccessLog_TypedConfig", tcpProxy.AccessLog[0].ConfigType)
+	}
+	fl := &accesslogconfig.FileAccessLog{}
+	if err := ptypes.UnmarshalAny(config.TypedConfig, fl); err != nil {
+		t.Fatal(err)
+	}
+	if fl.Path != "/dev/stdout" {
+		t.Errorf("got access log path %s, want /dev/stdout", fl.Path)
+	}
+	want := "[%START_TIME%] BlackHoleCluster %DOWNSTREAM_REMOTE_ADDRESS% %DOWNSTREAM_LOCAL_ADDRESS% " +
+		"\"%REQUESTED_SERVER_NAME%\" %BYTES_SENT% %BYTES_RECEIVED% %DURATION% %RESPONSE_FLAGS%\n"
+	if got := fl.GetFormat(); got != want {
+		t.Errorf("got access log format %q, want %q", got, want)
+	}
+}
+
+func TestSetTCPAuthzAccessLog(t *testing.T) {
+	defer func(path string) { features.TCPAuthzAccessLogFile = path }(features.TCPAuthzAccessLogFile)
+	node := &model.Proxy{Metadata: map[string]string{}, IstioVersion: model.MaxIstioVersion}
+	clusterName := "inbound|9000|tcp|foo.default.svc.cluster.local"
+
+	features.TCPAuthzAccessLogFile = ""
+	tcpProxy := setTCPAuthzAccessLog(node, &tcp_proxy.TcpProxy{}, clusterName)
+	if len(tcpProxy.AccessLog) != 0 {
+		t.Fatalf("unexpected access log %v", tcpProxy.AccessLog)
+	}
+
+	features.TCPAuthzAccessLogFile = "/dev/stdout"
+	tcpProxy = setTCPAuthzAccessLog(node, &tcp_proxy.TcpProxy{}, clusterName)
+	if len(tcpProxy.AccessLog) != 1 {
+		t.Fatalf("expected a single access log, got %v", tcpProxy.AccessLog)
+	}
+	config, ok := tcpProxy.AccessLog[0].ConfigType.(*accesslog.AccessLog_TypedConfig)
+	if !ok {
+		t.Fatalf("access log config type is %T not accesslog.AccessLog_TypedConfig", tcpProxy.AccessLog[0].ConfigType)
+	}
+	fl := &accesslogconfig.FileAccessLog{}
+	if err := ptypes.UnmarshalAny(config.TypedConfig, fl); err != nil {
+		t.Fatal(err)
+	}
+	if fl.Path != "/dev/stdout" {
+		t.Errorf("got access log path %s, want /dev/stdout", fl.Path)
+	}
+	fields := fl.GetJsonFormat().GetFields()
+	want := map[string]string{
+		"cluster":          clusterName,
+		"result":           "%DYNAMIC_METADATA(envoy.filters.network.rbac:shadow_engine_result)%",
+		"policy":           "%DYNAMIC_METADATA(envoy.filters.network.rbac:shadow_effective_policy_id)%",
+		"source_principal": "%DOWNSTREAM_PEER_URI_SAN%",
+		"destination":      "%DOWNSTREAM_LOCAL_ADDRESS%",
+	}
+	for k, v := range want {
+		if got := fields[k].GetStringValue(); got != v {
+			t.Errorf("got access log field %s %q, want %q", k, got, v)
+		}
+	}
+	if _, ok := tcpAuthzLogFormat.Fields["cluster"]; ok {
+		t.Errorf("the shared access log format must not be modified")
+	}
+}
+
+func TestBuildNetworkFiltersStackMongo(t *testing.T) {
+	defer os.Unsetenv("PILOT_ENABLE_MONGO_FILTER")
+	node := &model.Proxy{Metadata: map[string]string{}, IstioVersion: model.MaxIstioVersion}
+	port := &model.Port{Name: "mongo", Port: 27017, Protocol: protocol.Mongo}
+	tcpFilter := &listener.Filter{Name: xdsutil.TCPProxy}
+
+	filters := buildNetworkFiltersStack(node, port, tcpFilter, "mongo", "mongo-cluster")
+	if len(filters) != 2 || filters[0].Name != xdsutil.MongoProxy || filters[1] != tcpFilter {
+		t.Errorf("expected the mongo and tcp proxy filters, got %v", filters)
+	}
+
+	_ = os.Setenv("PILOT_ENABLE_MONGO_FILTER", "false")
+	filters = buildNetworkFiltersStack(node, port, tcpFilter, "mongo", "mongo-cluster")
+	if len(filters) != 1 || filters[0] != tcpFilter {
+		t.Errorf("expected only the tcp proxy filter with the mongo filter disabled, got %v", filters)
+	}
+}
+
+func TestBuildThriftFilter(t *testing.T) {
+	thriftFilter := buildThriftFilter("thrift", "thrift-cluster", true)
+	if thriftFilter.Name != thriftProxyFilter {
+		t.Errorf("thrift filter name is %s not %s", thriftFilter.Name, thriftProxyFilter)
+	}
+	config, ok := thriftFilter.ConfigType.(*listener.Filter_TypedConfig)
+	if !ok {
+		t.Fatalf("thrift filter type is %T not listener.Filter_TypedConfig", thriftFilter.ConfigType)
+	}
+	thriftProxy := thrift_proxy.ThriftProxy{}
+	if err := ptypes.UnmarshalAny(config.TypedConfig, &thriftProxy); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if thriftProxy.StatPrefix != "thrift" {
+		t.Errorf("thrift proxy statPrefix is %s", thriftProxy.StatPrefix)
+	}
+	routes := thriftProxy.RouteConfig.GetRoutes()
+	if len(routes) != 1 || routes[0].Match.GetMethodName() != "" || routes[0].Route.GetCluster() != "thrift-cluster" {
+		t.Errorf("expected a single route of all the methods to thrift-cluster, got %v", routes)
+	}
+
+	defer os.Unsetenv("PILOT_ENABLE_THRIFT_FILTER")
+	node := &model.Proxy{Metadata: map[string]string{}, IstioVersion: model.MaxIstioVersion}
+	port := &model.Port{Name: "thrift", Port: 9090, Protocol: protocol.Thrift}
+	tcpFilter := &listener.Filter{Name: xdsutil.TCPProxy}
+	if filters := buildNetworkFiltersStack(node, port, tcpFilter, "thrift", "thrift-cluster"); len(filters) != 1 || filters[0] != tcpFilter {
+		t.Errorf("expected only the tcp proxy filter with the thrift filter disabled, got %v", filters)
+	}
+	_ = os.Setenv("PILOT_ENABLE_THRIFT_FILTER", "true")
+	if filters := buildNetworkFiltersStack(node, port, tcpFilter, "thrift", "thrift-cluster"); len(filters) != 1 || filters[0].Name != thriftProxyFilter {
+		t.Errorf("expected only the thrift proxy filter, got %v", filters)
+	}
+}
+
+func TestBuildKafkaBrokerFilter(t *testing.T) {
+	defer os.Unsetenv("PILOT_ENABLE_KAFKA_FILTER")
+	node := &model.Proxy{Metadata: map[string]string{}, IstioVersion: model.MaxIstioVersion}
+	port := &model.Port{Name: "kafka", Port: 9092, Protocol: protocol.Kafka}
+	tcpFilter := &listener.Filter{Name: xdsutil.TCPProxy}
+
+	if filters := buildNetworkFiltersStack(node, port, tcpFilter, "kafka", "kafka-cluster"); len(filters) != 1 || filters[0] != tcpFilter {
+		t.Errorf("expected only the tcp proxy filter with the kafka filter disabled, got %v", filters)
+	}
+	_ = os.Setenv("PILOT_ENABLE_KAFKA_FILTER", "true")
+	filters := buildNetworkFiltersStack(node, port, tcpFilter, "kafka", "kafka-cluster")
+	if len(filters) != 2 || filters[0].Name != kafkaBrokerFilter || filters[1] != tcpFilter {
+		t.Fatalf("expected the kafka broker and tcp proxy filters, got %v", filters)
+	}
+	if got := filters[0].GetConfig().GetFields()["stat_prefix"].GetStringValue(); got != "kafka" {
+		t.Errorf("kafka broker stat_prefix is %q", got)
+	}
+
+	node.IstioVersion = &model.IstioVersion{Major: 1, Minor: 5}
+	if filters := buildNetworkFiltersStack(node, port, tcpFilter, "kafka", "kafka-cluster"); len(filters) != 1 || filters[0] != tcpFilter {
+		t.Errorf("expected only the tcp proxy filter for a proxy older than 1.6, got %v", filters)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package route
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	golangproto "github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/pkg/log"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3/route/retry"
+)
+
+// RequestPrioritiesAnnotation holds, in JSON, the policies the HTTP routes of a VirtualService apply to
+// the requests by the value of a priority header, x-request-priority by default, e.g.
+// {"values": {"batch": {"priority": "default", "timeout": "30s", "retryAttempts": 0},
+// "*": {"priority": "high"}}}, where "*" applies to the requests without a listed value.
+//
+// The policies set the routing priority of the requests, their timeout and their number of retries.
+// The connection pool settings of the DestinationRules only limit the requests of the default priority,
+// so that the default priority requests, e.g. batch ones, overflow and are shed before the high priority
+// ones, e.g. interactive ones, when the destination is overloaded. The high priority requests get the
+// Envoy default limits of 1024 connections, pending and active requests, and up to 1024 parallel retries.
+const RequestPrioritiesAnnotation = "networking.istio.io/requestPriorities"
+
+// defaultRequestPriorityHeader is the header holding the priority of the requests.
+const defaultRequestPriorityHeader = "x-request-priority"
+
+// requestPriorities are the policies set by the RequestPrioritiesAnnotation.
+type requestPriorities struct {
+	// Header is the name of the header holding the priority of the requests.
+	Header string `json:"header,omitempty"`
+
+	// Values maps the values of the header to their policies.
+	Values map[string]*requestPriorityPolicy `json:"values"`
+}
+
+// requestPriorityPolicy is the policy of the requests of a priority. The unset fields keep the settings
+// of the route.
+type requestPriorityPolicy struct {
+	// Priority is the routing priority of the requests, high or default.
+	Priority string `json:"priority,omitempty"`
+
+	// Timeout is the timeout of the requests.
+	Timeout string `json:"timeout,omitempty"`
+
+	// RetryAttempts is the number of retries of the requests, 0 disabling the retries.
+	RetryAttempts *uint32 `json:"retryAttempts,omitempty"`
+}
+
+// parseRequestPriorities parses the RequestPrioritiesAnnotation of a VirtualService, returning nil if it
+// is not set or invalid.
+func parseRequestPriorities(virtualService model.Config) *requestPriorities {
+	value := virtualService.Annotations[RequestPrioritiesAnnotation]
+	if value == "" {
+		return nil
+	}
+	priorities := &requestPriorities{}
+	err := json.Unmarshal([]byte(value), priorities)
+	if err == nil {
+		err = priorities.validate()
+	}
+	if err != nil {
+		log.Warnf("ignored invalid %s annotation of virtual service %s/%s: %v", RequestPrioritiesAnnotation,
+			virtualService.Namespace, virtualService.Name, err)
+		return nil
+	}
+	return priorities
+}
+
+func (p *requestPriorities) validate() error {
+	if p.Header == "" {
+		p.Header = defaultRequestPriorityHeader
+	}
+	p.Header = strings.ToLower(p.Header)
+	for value, policy := range p.Values {
+		if policy == nil {
+			return fmt.Errorf("the policy of %q is null", value)
+		}
+		policy.Priority = strings.ToUpper(policy.Priority)
+		if _, ok := core.RoutingPriority_value[policy.Priority]; !ok && policy.Priority != "" {
+			return fmt.Errorf("invalid priority %q of %q, must be high or default", policy.Priority, value)
+		}
+		if policy.Timeout != "" {
+			if d, err := time.ParseDuration(policy.Timeout); err != nil || d < 0 {
+				return fmt.Errorf("invalid timeout %q of %q", policy.Timeout, value)
+			}
+		}
+	}
+	return nil
+}
+
+// applyRequestPriorities returns the route preceded by a copy of the route for each listed value of
+// the priority header, matching the requests with the value and applying its policy. The policy of
+// "*" applies to the route itself.
+func applyRequestPriorities(r *route.Route, priorities *requestPriorities) []*route.Route {
+	if priorities == nil || r.GetRoute() == nil {
+		return []*route.Route{r}
+	}
+	values := make([]string, 0, len(priorities.Values))
+	for value := range priorities.Values {
+		if value != "*" {
+			values = append(values, value)
+		}
+	}
+	sort.Strings(values)
+
+	out := make([]*route.Route, 0, len(values)+1)
+	for _, value := range values {
+		prioritized := golangproto.Clone(r).(*route.Route)
+		prioritized.Match.Headers = append(prioritized.Match.Headers, &route.HeaderMatcher{
+			Name:                 priorities.Header,
+			HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: value},
+		})
+		applyRequestPriorityPolicy(prioritized.GetRoute(), priorities.Values[value])
+		out = append(out, prioritized)
+	}
+	if policy, f := priorities.Values["*"]; f {
+		applyRequestPriorityPolicy(r.GetRoute(), policy)
+	}
+	return append(out, r)
+}
+
+func applyRequestPriorityPolicy(action *route.RouteAction, policy *requestPriorityPolicy) {
+	if policy.Priority != "" {
+		action.Priority = core.RoutingPriority(core.RoutingPriority_value[policy.Priority])
+	}
+	if policy.Timeout != "" {
+		d, _ := time.ParseDuration(policy.Timeout)
+		action.Timeout = ptypes.DurationProto(d)
+		action.MaxGrpcTimeout = ptypes.DurationProto(d)
+	}
+	if policy.RetryAttempts != nil {
+		switch {
+		case *policy.RetryAttempts == 0:
+			action.RetryPolicy = nil
+		case action.RetryPolicy == nil:
+			action.RetryPolicy = retry.ConvertPolicy(&networking.HTTPRetry{Attempts: int32(*policy.RetryAttempts)})
+		default:
+			action.RetryPolicy.NumRetries = &wrappers.UInt32Value{Value: *policy.RetryAttempts}
+		}
+	}
+}
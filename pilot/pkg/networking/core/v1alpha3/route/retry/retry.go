@@ -24,14 +24,19 @@ import (
 
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pkg/config/defaults"
 )
 
+// defaultRetryOn and defaultRetriableStatusCodes are the Envoy retry conditions of the default
+// retry policy, which is also materialized into the routes by the defaulting webhook.
+var defaultRetryOn, defaultRetriableStatusCodes = parseRetryOn(defaults.DefaultRetryOn)
+
 // DefaultPolicy gets a copy of the default retry policy.
 func DefaultPolicy() *route.RetryPolicy {
 	policy := route.RetryPolicy{
-		NumRetries:           &wrappers.UInt32Value{Value: 2},
-		RetryOn:              "connect-failure,refused-stream,unavailable,cancelled,resource-exhausted,retriable-status-codes",
-		RetriableStatusCodes: []uint32{http.StatusServiceUnavailable},
+		NumRetries:           &wrappers.UInt32Value{Value: defaults.DefaultRetryAttempts},
+		RetryOn:              defaultRetryOn,
+		RetriableStatusCodes: append([]uint32(nil), defaultRetriableStatusCodes...),
 		RetryHostPredicate: []*route.RetryPolicy_RetryHostPredicate{
 			{
 				// to configure retries to prefer hosts that haven’t been attempted already,
@@ -56,7 +61,8 @@ func DefaultPolicy() *route.RetryPolicy {
 // - NumRetries: set from in.Attempts
 //
 // - RetryOn, RetriableStatusCodes: set from in.RetryOn (if specified). RetriableStatusCodes
-// is appended when encountering parts that are valid HTTP status codes.
+// is appended when encountering parts that are valid HTTP status codes, in which case RetryOn
+// includes retriable-status-codes so that Envoy retries on them.
 //
 // - PerTryTimeout: set from in.PerTryTimeout (if specified)
 func ConvertPolicy(in *networking.HTTPRetry) *route.RetryPolicy {
@@ -107,5 +113,38 @@ func parseRetryOn(retryOn string) (string, []uint32) {
 		}
 	}
 
+	if len(codes) > 0 {
+		tojoin = AppendRetryOn(tojoin, "retriable-status-codes")
+	}
+
 	return strings.Join(tojoin, ","), codes
 }
+
+// AddRetriableHeaders makes the policy retry the requests whose responses have any of the headers,
+// e.g. one set by the upstream when it is overloaded.
+func AddRetriableHeaders(policy *route.RetryPolicy, headers []string) {
+	if len(headers) == 0 {
+		return
+	}
+	for _, name := range headers {
+		policy.RetriableHeaders = append(policy.RetriableHeaders, &route.HeaderMatcher{
+			Name:                 strings.ToLower(name),
+			HeaderMatchSpecifier: &route.HeaderMatcher_PresentMatch{PresentMatch: true},
+		})
+	}
+	var conditions []string
+	if policy.RetryOn != "" {
+		conditions = strings.Split(policy.RetryOn, ",")
+	}
+	policy.RetryOn = strings.Join(AppendRetryOn(conditions, "retriable-headers"), ",")
+}
+
+// AppendRetryOn appends the retry condition to the conditions unless they already include it.
+func AppendRetryOn(conditions []string, condition string) []string {
+	for _, c := range conditions {
+		if c == condition {
+			return conditions
+		}
+	}
+	return append(conditions, condition)
+}
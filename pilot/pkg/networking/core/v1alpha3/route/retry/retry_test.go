@@ -18,12 +18,14 @@ import (
 	"testing"
 	"time"
 
+	envoyroute "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
 	gogoTypes "github.com/gogo/protobuf/types"
 	"github.com/golang/protobuf/ptypes"
 	. "github.com/onsi/gomega"
 
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/networking/core/v1alpha3/route/retry"
+	"istio.io/istio/pkg/config/defaults"
 )
 
 func TestNilRetryShouldReturnDefault(t *testing.T) {
@@ -37,6 +39,18 @@ func TestNilRetryShouldReturnDefault(t *testing.T) {
 	g.Expect(*policy).To(Equal(*retry.DefaultPolicy()))
 }
 
+func TestMaterializedDefaultShouldReturnDefault(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// The retry policy set by the defaulting webhook must keep the routes unchanged.
+	policy := retry.ConvertPolicy(&networking.HTTPRetry{
+		Attempts: defaults.DefaultRetryAttempts,
+		RetryOn:  defaults.DefaultRetryOn,
+	})
+	g.Expect(policy).To(Not(BeNil()))
+	g.Expect(*policy).To(Equal(*retry.DefaultPolicy()))
+}
+
 func TestZeroAttemptsShouldReturnNilPolicy(t *testing.T) {
 	g := NewGomegaWithT(t)
 
@@ -124,10 +138,44 @@ func TestRetryOnContainingStatusCodes(t *testing.T) {
 
 	policy := retry.ConvertPolicy(route.Retries)
 	g.Expect(policy).To(Not(BeNil()))
-	g.Expect(policy.RetryOn).To(Equal("some,fake,5xx,conditions"))
+	g.Expect(policy.RetryOn).To(Equal("some,fake,5xx,conditions,retriable-status-codes"))
 	g.Expect(policy.RetriableStatusCodes).To(Equal([]uint32{404, 503}))
 }
 
+func TestRetryOnResetAndStatusCodes(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// Create a route retrying on resets and connect failures and on explicit status codes.
+	route := networking.HTTPRoute{
+		Retries: &networking.HTTPRetry{
+			Attempts: 3,
+			RetryOn:  "reset,retriable-status-codes,connect-failure,502,503",
+		},
+	}
+
+	policy := retry.ConvertPolicy(route.Retries)
+	g.Expect(policy).To(Not(BeNil()))
+	g.Expect(policy.RetryOn).To(Equal("reset,retriable-status-codes,connect-failure"))
+	g.Expect(policy.RetriableStatusCodes).To(Equal([]uint32{502, 503}))
+}
+
+func TestAddRetriableHeaders(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	policy := retry.ConvertPolicy(&networking.HTTPRetry{Attempts: 2, RetryOn: "reset"})
+	retry.AddRetriableHeaders(policy, []string{"X-Upstream-Busy", "x-retry"})
+	g.Expect(policy.RetryOn).To(Equal("reset,retriable-headers"))
+	g.Expect(policy.RetriableHeaders).To(Equal([]*envoyroute.HeaderMatcher{
+		{Name: "x-upstream-busy", HeaderMatchSpecifier: &envoyroute.HeaderMatcher_PresentMatch{PresentMatch: true}},
+		{Name: "x-retry", HeaderMatchSpecifier: &envoyroute.HeaderMatcher_PresentMatch{PresentMatch: true}},
+	}))
+
+	// The condition is not repeated.
+	retry.AddRetriableHeaders(policy, []string{"x-other"})
+	g.Expect(policy.RetryOn).To(Equal("reset,retriable-headers"))
+	g.Expect(len(policy.RetriableHeaders)).To(Equal(3))
+}
+
 func TestRetryOnWithInvalidStatusCodesShouldAddToRetryOn(t *testing.T) {
 	g := NewGomegaWithT(t)
 
@@ -16,6 +16,7 @@ package route
 
 import (
 	"fmt"
+	"net/http"
 	"sort"
 	"strconv"
 	"strings"
@@ -24,9 +25,12 @@ import (
 	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
 	xdsfault "github.com/envoyproxy/go-control-plane/envoy/config/filter/fault/v2"
+	buffer "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/buffer/v2"
 	xdshttpfault "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/fault/v2"
 	xdstype "github.com/envoyproxy/go-control-plane/envoy/type"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
 	xdsutil "github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	golangproto "github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/any"
 	"github.com/golang/protobuf/ptypes/duration"
@@ -39,6 +43,7 @@ import (
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/pkg/log"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/core/v1alpha3/route/retry"
 	"istio.io/istio/pilot/pkg/networking/util"
@@ -57,6 +62,52 @@ const (
 // DefaultRouteName is the name assigned to a route generated by default in absence of a virtual service.
 const DefaultRouteName = "default"
 
+// GRPCWebAnnotation marks the VirtualServices whose routes serve gRPC-Web clients. The CORS policies
+// of their routes allow the request headers and expose the response headers of the gRPC-Web protocol,
+// so that browsers can call the services without listing them in every policy.
+const GRPCWebAnnotation = "networking.istio.io/grpcWeb"
+
+// RewriteHostToDestinationAnnotation lists, comma separated, the names of the HTTP routes of a
+// VirtualService rewriting the Host/authority header to the hostname of their destination, or "*" for
+// all its routes. This lets vanity domains front services, such as external SaaS ServiceEntries, which
+// only accept their own hostname. An explicit authority rewrite of a route takes precedence.
+const RewriteHostToDestinationAnnotation = "networking.istio.io/rewriteHostToDestination"
+
+// DisableWebsocketAnnotation lists, comma separated, the names of the HTTP routes of a VirtualService
+// rejecting WebSocket upgrades, or "*" for all its routes. The HTTP listeners allow them otherwise.
+const DisableWebsocketAnnotation = "networking.istio.io/disableWebsocket"
+
+// IdleTimeoutAnnotation sets, comma separated, the idle timeout of HTTP routes of a VirtualService as
+// name=duration pairs, e.g. "chat=1h,*=5m", where "*" applies to the routes not listed. The stream of
+// a request, e.g. an upgraded WebSocket connection, is closed when idle for longer. There is no idle
+// timeout otherwise.
+const IdleTimeoutAnnotation = "networking.istio.io/idleTimeout"
+
+// CacheTTLAnnotation sets, comma separated, how long the gateways cache the responses of HTTP routes of
+// a VirtualService as name=duration pairs, e.g. "assets=1h,*=5m", where "*" applies to the routes not
+// listed. The gateway routes set the Cache-Control header of their responses to public with this
+// max-age, which the response cache of the gateways enabled by their responseCache annotation honors.
+const CacheTTLAnnotation = "networking.istio.io/cacheTtl"
+
+// RetriableHeadersAnnotation sets, comma separated, the response headers on which HTTP routes of a
+// VirtualService retry the requests as name=headers pairs, the headers being space separated, e.g.
+// "api=x-upstream-busy x-retry,*=x-retry", where "*" applies to the routes not listed. It adds the
+// retriable-headers condition to the retry policy of the routes, which have to retry.
+const RetriableHeadersAnnotation = "networking.istio.io/retriableHeaders"
+
+// CORSCredentialedOriginsAnnotation lists, comma separated, the origins allowed to send credentials
+// to the HTTP routes of a VirtualService, in the syntax of the allowed origins of their CORS policies,
+// e.g. "https://app.example.com,regex:https://.*\\.example\\.com". The CORS policies allow the
+// credentials of these origins whatever their allowCredentials, which applies to the other origins.
+const CORSCredentialedOriginsAnnotation = "networking.istio.io/corsCredentialedOrigins"
+
+var (
+	// grpcWebAllowHeaders are the request headers sent by gRPC-Web clients.
+	grpcWebAllowHeaders = []string{"content-type", "x-grpc-web", "x-user-agent", "grpc-timeout"}
+	// grpcWebExposeHeaders are the response headers read by gRPC-Web clients.
+	grpcWebExposeHeaders = []string{"grpc-status", "grpc-message"}
+)
+
 // VirtualHostWrapper is a context-dependent virtual host entry with guarded routes.
 // Note: Currently we are not fully utilizing this structure. We could invoke this logic
 // once for all sidecars in the cluster to compute all RDS for inside the mesh and arrange
@@ -264,18 +315,24 @@ func BuildHTTPRoutesForVirtualService(
 		return nil, fmt.Errorf("in not a virtual service: %#v", virtualService)
 	}
 
+	priorities := parseRequestPriorities(virtualService)
+	credentialedOrigins := parseCORSCredentialedOrigins(virtualService)
 	out := make([]*route.Route, 0, len(vs.Http))
 allroutes:
-	for _, http := range vs.Http {
+	for i, http := range vs.Http {
 		if len(http.Match) == 0 {
-			if r := translateRoute(push, node, http, nil, listenPort, virtualService, serviceRegistry, gatewayNames); r != nil {
-				out = append(out, r)
+			if r := translateRoute(push, node, http, i, nil, listenPort, virtualService, serviceRegistry, gatewayNames); r != nil {
+				for _, r := range applyCORSCredentialedOrigins(r, credentialedOrigins) {
+					out = append(out, applyRequestPriorities(r, priorities)...)
+				}
 			}
 			break allroutes // we have a rule with catch all match prefix: /. Other rules are of no use
 		} else {
 			for _, match := range http.Match {
-				if r := translateRoute(push, node, http, match, listenPort, virtualService, serviceRegistry, gatewayNames); r != nil {
-					out = append(out, r)
+				if r := translateRoute(push, node, http, i, match, listenPort, virtualService, serviceRegistry, gatewayNames); r != nil {
+					for _, r := range applyCORSCredentialedOrigins(r, credentialedOrigins) {
+						out = append(out, applyRequestPriorities(r, priorities)...)
+					}
 					rType, _ := getEnvoyRouteTypeAndVal(r)
 					if rType == envoyCatchAll {
 						// We have a catch all route. No point building other routes, with match conditions
@@ -313,8 +370,8 @@ func sourceMatchHTTP(match *networking.HTTPMatchRequest, proxyLabels labels.Coll
 	return false
 }
 
-// translateRoute translates HTTP routes
-func translateRoute(push *model.PushContext, node *model.Proxy, in *networking.HTTPRoute,
+// translateRoute translates HTTP routes. index is the position of the route in the virtual service.
+func translateRoute(push *model.PushContext, node *model.Proxy, in *networking.HTTPRoute, index int,
 	match *networking.HTTPMatchRequest, port int,
 	virtualService model.Config,
 	serviceRegistry map[host.Name]*model.Service,
@@ -335,7 +392,7 @@ func translateRoute(push *model.PushContext, node *model.Proxy, in *networking.H
 
 	out := &route.Route{
 		Match:    translateRouteMatch(match),
-		Metadata: util.BuildConfigInfoMetadata(virtualService.ConfigMeta),
+		Metadata: util.BuildConfigInfoMetadataForRule(virtualService.ConfigMeta, "http", index),
 	}
 
 	if util.IsIstioVersionGE13(node) {
@@ -383,6 +440,12 @@ func translateRoute(push *model.PushContext, node *model.Proxy, in *networking.H
 			Cors:        translateCORSPolicy(in.CorsPolicy, node),
 			RetryPolicy: retry.ConvertPolicy(in.Retries),
 		}
+		if action.Cors != nil && virtualService.Annotations[GRPCWebAnnotation] == "true" {
+			addGRPCWebCORSHeaders(action.Cors)
+		}
+		if action.RetryPolicy != nil {
+			retry.AddRetriableHeaders(action.RetryPolicy, routeRetriableHeaders(virtualService, in.Name))
+		}
 
 		if in.Timeout != nil {
 			d := gogo.DurationToProtoDuration(in.Timeout)
@@ -495,6 +558,45 @@ func translateRoute(push *model.PushContext, node *model.Proxy, in *networking.H
 			}
 		}
 
+		if selectsRoute(virtualService.Annotations[DisableWebsocketAnnotation], in.Name) {
+			action.UpgradeConfigs = []*route.RouteAction_UpgradeConfig{{
+				UpgradeType: "websocket",
+				Enabled:     proto.BoolFalse,
+			}}
+		}
+		if idleTimeout := routeIdleTimeout(virtualService, in.Name); idleTimeout > 0 {
+			action.IdleTimeout = ptypes.DurationProto(idleTimeout)
+		}
+		if node.Type == model.Router {
+			if ttl := routeCacheTTL(virtualService, in.Name); ttl > 0 {
+				out.ResponseHeadersToAdd = append(out.ResponseHeadersToAdd, &core.HeaderValueOption{
+					Header: &core.HeaderValue{
+						Key:   "Cache-Control",
+						Value: fmt.Sprintf("public, max-age=%d", int64(ttl/time.Second)),
+					},
+					Append: proto.BoolFalse,
+				})
+			}
+			if size, ok := routeMaxRequestBodyBytes(virtualService, in.Name); ok {
+				perRoute := translateMaxRequestBodyBytes(size)
+				if util.IsXDSMarshalingToAnyEnabled(node) {
+					out.TypedPerFilterConfig[xdsutil.Buffer] = util.MessageToAny(perRoute)
+				} else {
+					out.PerFilterConfig[xdsutil.Buffer] = util.MessageToStruct(perRoute)
+				}
+			}
+		}
+
+		if in.Rewrite.GetAuthority() == "" && rewriteHostToDestination(virtualService, in.Name) {
+			if hostname := destinationHostname(in.Route, virtualService.ConfigMeta); hostname != "" {
+				action.HostRewriteSpecifier = &route.RouteAction_HostRewrite{HostRewrite: hostname}
+			} else {
+				// The destinations have different hosts: rely on the hostnames of the endpoints of the
+				// DNS clusters.
+				action.HostRewriteSpecifier = &route.RouteAction_AutoHostRewrite{AutoHostRewrite: proto.BoolTrue}
+			}
+		}
+
 		// rewrite to a single cluster if there is only weighted cluster
 		if len(weighted) == 1 {
 			action.ClusterSpecifier = &route.RouteAction_Cluster{Cluster: weighted[0].Name}
@@ -525,6 +627,31 @@ func translateRoute(push *model.PushContext, node *model.Proxy, in *networking.H
 	return out
 }
 
+// translateMaxRequestBodyBytes translates the maximum request body size of a route to the config of
+// the buffer filter of the route, which disables the filter for a size of 0.
+func translateMaxRequestBodyBytes(size uint32) *buffer.BufferPerRoute {
+	if size == 0 {
+		return &buffer.BufferPerRoute{Override: &buffer.BufferPerRoute_Disabled{Disabled: true}}
+	}
+	return &buffer.BufferPerRoute{Override: &buffer.BufferPerRoute_Buffer{
+		Buffer: &buffer.Buffer{MaxRequestBytes: &wrappers.UInt32Value{Value: size}},
+	}}
+}
+
+// ApplyVirtualHostHeaders sets the header operations of a virtual host, which Envoy applies to all its
+// routes after their own header operations.
+func ApplyVirtualHostHeaders(vhost *route.VirtualHost, headers *networking.Headers) {
+	requestHeadersToAdd := translateAppendHeaders(headers.GetRequest().GetSet(), false)
+	requestHeadersToAdd = append(requestHeadersToAdd, translateAppendHeaders(headers.GetRequest().GetAdd(), true)...)
+	vhost.RequestHeadersToAdd = requestHeadersToAdd
+	vhost.RequestHeadersToRemove = headers.GetRequest().GetRemove()
+
+	responseHeadersToAdd := translateAppendHeaders(headers.GetResponse().GetSet(), false)
+	responseHeadersToAdd = append(responseHeadersToAdd, translateAppendHeaders(headers.GetResponse().GetAdd(), true)...)
+	vhost.ResponseHeadersToAdd = responseHeadersToAdd
+	vhost.ResponseHeadersToRemove = headers.GetResponse().GetRemove()
+}
+
 // SortHeaderValueOption type and the functions below (Len, Less and Swap) are for sort.Stable for type HeaderValueOption
 type SortHeaderValueOption []*core.HeaderValueOption
 
@@ -588,7 +715,13 @@ func translateRouteMatch(in *networking.HTTPMatchRequest) *route.RouteMatch {
 		case *networking.StringMatch_Prefix:
 			out.PathSpecifier = &route.RouteMatch_Prefix{Prefix: m.Prefix}
 		case *networking.StringMatch_Regex:
-			out.PathSpecifier = &route.RouteMatch_Regex{Regex: m.Regex}
+			if util.IsRE2(m.Regex) {
+				out.PathSpecifier = &route.RouteMatch_SafeRegex{SafeRegex: util.RegexMatcher(m.Regex)}
+			} else {
+				// Migration tracked in https://github.com/istio/istio/issues/17127
+				//nolint: staticcheck
+				out.PathSpecifier = &route.RouteMatch_Regex{Regex: m.Regex}
+			}
 		}
 	}
 
@@ -625,10 +758,13 @@ func translateQueryParamMatch(name string, in *networking.StringMatch) route.Que
 
 	switch m := in.MatchType.(type) {
 	case *networking.StringMatch_Exact:
-		out.Value = m.Exact
+		out.QueryParameterMatchSpecifier = &route.QueryParameterMatcher_StringMatch{
+			StringMatch: &matcher.StringMatcher{MatchPattern: &matcher.StringMatcher_Exact{Exact: m.Exact}},
+		}
 	case *networking.StringMatch_Regex:
-		out.Value = m.Regex
-		out.Regex = proto.BoolTrue
+		out.QueryParameterMatchSpecifier = &route.QueryParameterMatcher_StringMatch{
+			StringMatch: util.StringMatcherRegex(m.Regex),
+		}
 	}
 
 	return out
@@ -644,11 +780,15 @@ func translateHeaderMatch(name string, in *networking.StringMatch) route.HeaderM
 	case *networking.StringMatch_Exact:
 		out.HeaderMatchSpecifier = &route.HeaderMatcher_ExactMatch{ExactMatch: m.Exact}
 	case *networking.StringMatch_Prefix:
-		// Envoy regex grammar is ECMA-262 (http://en.cppreference.com/w/cpp/regex/ecmascript)
-		// Golang has a slightly different regex grammar
 		out.HeaderMatchSpecifier = &route.HeaderMatcher_PrefixMatch{PrefixMatch: m.Prefix}
 	case *networking.StringMatch_Regex:
-		out.HeaderMatchSpecifier = &route.HeaderMatcher_RegexMatch{RegexMatch: m.Regex}
+		if util.IsRE2(m.Regex) {
+			out.HeaderMatchSpecifier = &route.HeaderMatcher_SafeRegexMatch{SafeRegexMatch: util.RegexMatcher(m.Regex)}
+		} else {
+			// Migration tracked in https://github.com/istio/istio/issues/17127
+			//nolint: staticcheck
+			out.HeaderMatchSpecifier = &route.HeaderMatcher_RegexMatch{RegexMatch: m.Regex}
+		}
 	}
 
 	return out
@@ -661,8 +801,16 @@ func translateCORSPolicy(in *networking.CorsPolicy, _ *model.Proxy) *route.CorsP
 	}
 
 	// CORS filter is enabled by default
-	out := route.CorsPolicy{
-		AllowOrigin: in.AllowOrigin,
+	out := route.CorsPolicy{}
+	if hasCORSOriginMatchers(in.AllowOrigin) {
+		for _, origin := range in.AllowOrigin {
+			if m := translateCORSOrigin(origin); m != nil {
+				out.AllowOriginStringMatch = append(out.AllowOriginStringMatch, m)
+			}
+		}
+	} else {
+		// Keep the exact origins understood by older proxies when no pattern is used.
+		out.AllowOrigin = in.AllowOrigin
 	}
 
 	out.EnabledSpecifier = &route.CorsPolicy_FilterEnabled{
@@ -684,6 +832,229 @@ func translateCORSPolicy(in *networking.CorsPolicy, _ *model.Proxy) *route.CorsP
 	return &out
 }
 
+// rewriteHostToDestination returns true if the RewriteHostToDestinationAnnotation of a VirtualService
+// selects the HTTP route with the given name.
+func rewriteHostToDestination(virtualService model.Config, routeName string) bool {
+	return selectsRoute(virtualService.Annotations[RewriteHostToDestinationAnnotation], routeName)
+}
+
+// selectsRoute returns true if the comma separated list of route names holds the given one or "*".
+func selectsRoute(value, routeName string) bool {
+	if value == "" {
+		return false
+	}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "*" || (name != "" && name == routeName) {
+			return true
+		}
+	}
+	return false
+}
+
+// routeIdleTimeout returns the idle timeout the IdleTimeoutAnnotation of a VirtualService sets for the
+// HTTP route with the given name, or 0 if none.
+func routeIdleTimeout(virtualService model.Config, routeName string) time.Duration {
+	return routeDuration(virtualService, IdleTimeoutAnnotation, routeName)
+}
+
+// routeCacheTTL returns the cache TTL the CacheTTLAnnotation of a VirtualService sets for the HTTP
+// route with the given name, or 0 if none.
+func routeCacheTTL(virtualService model.Config, routeName string) time.Duration {
+	return routeDuration(virtualService, CacheTTLAnnotation, routeName)
+}
+
+// routeRetriableHeaders returns the response headers the RetriableHeadersAnnotation of a VirtualService
+// sets for the HTTP route with the given name.
+func routeRetriableHeaders(virtualService model.Config, routeName string) []string {
+	value, _ := routeAnnotationValue(virtualService, RetriableHeadersAnnotation, routeName, func(value string) bool {
+		return len(strings.Fields(value)) > 0
+	})
+	return strings.Fields(value)
+}
+
+// routeDuration returns the duration the annotation of a VirtualService, holding name=duration pairs,
+// sets for the HTTP route with the given name, or 0 if none.
+func routeDuration(virtualService model.Config, annotation, routeName string) time.Duration {
+	value, ok := routeAnnotationValue(virtualService, annotation, routeName, func(value string) bool {
+		d, err := time.ParseDuration(value)
+		return err == nil && d > 0
+	})
+	if !ok {
+		return 0
+	}
+	d, _ := time.ParseDuration(value)
+	return d
+}
+
+// routeMaxRequestBodyBytes returns the maximum request body size the MaxRequestBodyBytesAnnotation of a
+// VirtualService sets for the HTTP route with the given name, and whether it sets one.
+func routeMaxRequestBodyBytes(virtualService model.Config, routeName string) (uint32, bool) {
+	value, ok := routeAnnotationValue(virtualService, model.MaxRequestBodyBytesAnnotation, routeName, func(value string) bool {
+		_, err := strconv.ParseUint(value, 10, 32)
+		return err == nil
+	})
+	if !ok {
+		return 0, false
+	}
+	size, _ := strconv.ParseUint(value, 10, 32)
+	return uint32(size), true
+}
+
+// routeAnnotationValue returns the value the annotation of a VirtualService, holding name=value pairs
+// where "*" applies to the routes not listed, sets for the HTTP route with the given name. The invalid
+// values are ignored.
+func routeAnnotationValue(virtualService model.Config, annotation, routeName string, valid func(string) bool) (string, bool) {
+	value := virtualService.Annotations[annotation]
+	if value == "" {
+		return "", false
+	}
+	var routeValue, defaultValue string
+	var routeFound, defaultFound bool
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || !valid(strings.TrimSpace(parts[1])) {
+			log.Warnf("ignored invalid %s entry %q of virtual service %s/%s", annotation, entry, virtualService.Namespace, virtualService.Name)
+			continue
+		}
+		name := strings.TrimSpace(parts[0])
+		switch {
+		case name == "*":
+			defaultValue, defaultFound = strings.TrimSpace(parts[1]), true
+		case name != "" && name == routeName:
+			routeValue, routeFound = strings.TrimSpace(parts[1]), true
+		}
+	}
+	if routeFound {
+		return routeValue, true
+	}
+	return defaultValue, defaultFound
+}
+
+// destinationHostname returns the FQDN shared by all the destinations of a route, or an empty
+// string if they have different or wildcard hosts. Short hosts are resolved in the namespace of
+// the virtual service, as the upstream would not know the name otherwise.
+func destinationHostname(destinations []*networking.HTTPRouteDestination, meta model.ConfigMeta) string {
+	hostname := ""
+	for _, dst := range destinations {
+		if dst.GetDestination().GetHost() == "" {
+			return ""
+		}
+		h := string(model.ResolveShortnameToFQDN(dst.GetDestination().GetHost(), meta))
+		if strings.HasPrefix(h, "*") || (hostname != "" && h != hostname) {
+			return ""
+		}
+		hostname = h
+	}
+	return hostname
+}
+
+// hasCORSOriginMatchers returns true if some of the allowed origins are patterns.
+func hasCORSOriginMatchers(origins []string) bool {
+	for _, origin := range origins {
+		if strings.HasPrefix(origin, constants.CORSOriginRegexPrefix) || strings.HasPrefix(origin, constants.CORSOriginPrefixPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// translateCORSOrigin translates an allowed origin to a string matcher of the origin header. The
+// regexes are matched with RE2, the engine used by validation, and the origins whose regex is not
+// valid RE2 are ignored rather than matched with another engine.
+func translateCORSOrigin(origin string) *matcher.StringMatcher {
+	switch {
+	case strings.HasPrefix(origin, constants.CORSOriginRegexPrefix):
+		regex := strings.TrimPrefix(origin, constants.CORSOriginRegexPrefix)
+		if !util.IsRE2(regex) {
+			return nil
+		}
+		return &matcher.StringMatcher{MatchPattern: &matcher.StringMatcher_SafeRegex{SafeRegex: util.RegexMatcher(regex)}}
+	case strings.HasPrefix(origin, constants.CORSOriginPrefixPrefix):
+		return &matcher.StringMatcher{
+			MatchPattern: &matcher.StringMatcher_Prefix{Prefix: strings.TrimPrefix(origin, constants.CORSOriginPrefixPrefix)},
+		}
+	case origin == "*":
+		return &matcher.StringMatcher{MatchPattern: &matcher.StringMatcher_SafeRegex{SafeRegex: util.RegexMatcher(".*")}}
+	default:
+		return &matcher.StringMatcher{MatchPattern: &matcher.StringMatcher_Exact{Exact: origin}}
+	}
+}
+
+// parseCORSCredentialedOrigins returns the origins listed by the CORSCredentialedOriginsAnnotation of a
+// VirtualService.
+func parseCORSCredentialedOrigins(virtualService model.Config) []string {
+	var origins []string
+	for _, origin := range strings.Split(virtualService.Annotations[CORSCredentialedOriginsAnnotation], ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// applyCORSCredentialedOrigins returns the route preceded by a copy of the route for each credentialed
+// origin, matching the requests from the origin and allowing their credentials. Envoy has a single
+// allowCredentials per CORS policy, so the routes are split by the origin header instead.
+func applyCORSCredentialedOrigins(r *route.Route, origins []string) []*route.Route {
+	cors := r.GetRoute().GetCors()
+	if len(origins) == 0 || cors == nil || cors.GetAllowCredentials().GetValue() {
+		return []*route.Route{r}
+	}
+	out := make([]*route.Route, 0, len(origins)+1)
+	for _, origin := range origins {
+		// Browsers reject credentials allowed to any origin.
+		m := translateCORSOrigin(origin)
+		if m == nil || origin == "*" {
+			log.Warnf("ignored credentialed origin %q of route %s", origin, r.Name)
+			continue
+		}
+		credentialed := golangproto.Clone(r).(*route.Route)
+		credentialed.Match.Headers = append(credentialed.Match.Headers, originHeaderMatcher(m))
+		credentialed.GetRoute().Cors.AllowCredentials = proto.BoolTrue
+		out = append(out, credentialed)
+	}
+	return append(out, r)
+}
+
+// originHeaderMatcher returns the matcher of the origin header equivalent to a string matcher.
+func originHeaderMatcher(m *matcher.StringMatcher) *route.HeaderMatcher {
+	out := &route.HeaderMatcher{Name: "origin"}
+	switch pattern := m.MatchPattern.(type) {
+	case *matcher.StringMatcher_Prefix:
+		out.HeaderMatchSpecifier = &route.HeaderMatcher_PrefixMatch{PrefixMatch: pattern.Prefix}
+	case *matcher.StringMatcher_SafeRegex:
+		out.HeaderMatchSpecifier = &route.HeaderMatcher_SafeRegexMatch{SafeRegexMatch: pattern.SafeRegex}
+	default:
+		out.HeaderMatchSpecifier = &route.HeaderMatcher_ExactMatch{ExactMatch: m.GetExact()}
+	}
+	return out
+}
+
+// addGRPCWebCORSHeaders adds the headers of the gRPC-Web protocol missing from a CORS policy.
+func addGRPCWebCORSHeaders(cors *route.CorsPolicy) {
+	cors.AllowHeaders = appendCORSHeaders(cors.AllowHeaders, grpcWebAllowHeaders)
+	cors.ExposeHeaders = appendCORSHeaders(cors.ExposeHeaders, grpcWebExposeHeaders)
+}
+
+// appendCORSHeaders appends the headers not already in a comma separated list of headers.
+func appendCORSHeaders(list string, headers []string) string {
+	present := make(map[string]bool)
+	var out []string
+	if list != "" {
+		out = strings.Split(list, ",")
+		for _, h := range out {
+			present[strings.ToLower(strings.TrimSpace(h))] = true
+		}
+	}
+	for _, h := range headers {
+		if !present[h] {
+			out = append(out, h)
+		}
+	}
+	return strings.Join(out, ",")
+}
+
 // getRouteOperation returns readable route description for trace.
 func getRouteOperation(in *route.Route, vsName string, port int) string {
 	path := "/*"
@@ -695,6 +1066,8 @@ func getRouteOperation(in *route.Route, vsName string, port int) string {
 			path = fmt.Sprintf("%s*", m.GetPrefix())
 		case *route.RouteMatch_Path:
 			path = m.GetPath()
+		case *route.RouteMatch_SafeRegex:
+			path = m.GetSafeRegex().GetRegex()
 		case *route.RouteMatch_Regex:
 			// Migration tracked in https://github.com/istio/istio/issues/17127
 			//nolint: staticcheck
@@ -737,6 +1110,32 @@ func BuildDefaultHTTPInboundRoute(node *model.Proxy, clusterName string, operati
 	return val
 }
 
+// ambiguousPathRegex matches the paths that Envoy does not normalize and which an authorization
+// policy or a route could read differently from the application: repeated slashes and percent-encoded
+// slashes, backslashes and dots.
+const ambiguousPathRegex = `.*(//|%2[fF]|%5[cC]|%2[eE]).*`
+
+// AmbiguousPathRouteName is the name of the route rejecting the ambiguous paths.
+const AmbiguousPathRouteName = "reject-ambiguous-path"
+
+// PrependRejectAmbiguousPathRoute prepends to the routes of the virtual host a route rejecting the
+// requests with an ambiguous path with a 400, if enabled.
+func PrependRejectAmbiguousPathRoute(node *model.Proxy, vHost *route.VirtualHost) {
+	if !features.RejectAmbiguousPaths {
+		return
+	}
+	reject := &route.Route{
+		Match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_SafeRegex{SafeRegex: util.RegexMatcher(ambiguousPathRegex)}},
+		Action: &route.Route_DirectResponse{
+			DirectResponse: &route.DirectResponseAction{Status: http.StatusBadRequest},
+		},
+	}
+	if util.IsIstioVersionGE13(node) {
+		reject.Name = AmbiguousPathRouteName
+	}
+	vHost.Routes = append([]*route.Route{reject}, vHost.Routes...)
+}
+
 // BuildDefaultHTTPOutboundRoute builds a default outbound route, including a retry policy.
 func BuildDefaultHTTPOutboundRoute(node *model.Proxy, clusterName string, operation string) *route.Route {
 	// Start with the same configuration as for inbound.
@@ -956,6 +1355,9 @@ func getEnvoyRouteTypeAndVal(r *route.Route) (envoyRouteType, string) {
 	case *route.RouteMatch_Prefix:
 		iVal = iR.Prefix
 		iType = envoyPrefix
+	case *route.RouteMatch_SafeRegex:
+		iVal = iR.SafeRegex.GetRegex()
+		iType = envoyRegex
 	case *route.RouteMatch_Regex:
 		iVal = iR.Regex
 		iType = envoyRegex
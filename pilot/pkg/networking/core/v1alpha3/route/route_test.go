@@ -16,17 +16,25 @@ package route_test
 
 import (
 	"reflect"
+	"regexp"
 	"testing"
 	"time"
 
+	envoycore "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	envoyroute "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	buffer "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/buffer/v2"
+	"github.com/envoyproxy/go-control-plane/envoy/type/matcher"
+	xdsutil "github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/gogo/protobuf/types"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/onsi/gomega"
 
 	networking "istio.io/api/networking/v1alpha3"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/core/v1alpha3/route"
+	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/protocol"
@@ -305,6 +313,503 @@ func TestBuildHTTPRoutes(t *testing.T) {
 		g.Expect(ok).NotTo(gomega.BeFalse())
 		g.Expect(redirectAction.Redirect.ResponseCode).To(gomega.Equal(envoyroute.RedirectAction_PERMANENT_REDIRECT))
 	})
+	t.Run("for virtual service rewriting the host to the destination", func(t *testing.T) {
+		g := gomega.NewGomegaWithT(t)
+
+		vs := &networking.VirtualService{
+			Hosts:    []string{},
+			Gateways: []string{"some-gateway"},
+			Http: []*networking.HTTPRoute{
+				{
+					Name:  "saas",
+					Match: []*networking.HTTPMatchRequest{{Uri: &networking.StringMatch{MatchType: &networking.StringMatch_Prefix{Prefix: "/saas"}}}},
+					Route: []*networking.HTTPRouteDestination{
+						{Destination: &networking.Destination{Host: "api.saas.com"}, Weight: 100},
+					},
+				},
+				{
+					Name:    "explicit",
+					Match:   []*networking.HTTPMatchRequest{{Uri: &networking.StringMatch{MatchType: &networking.StringMatch_Prefix{Prefix: "/explicit"}}}},
+					Rewrite: &networking.HTTPRewrite{Authority: "explicit.saas.com"},
+					Route: []*networking.HTTPRouteDestination{
+						{Destination: &networking.Destination{Host: "api.saas.com"}, Weight: 100},
+					},
+				},
+				{
+					Name: "split",
+					Route: []*networking.HTTPRouteDestination{
+						{Destination: &networking.Destination{Host: "api.saas.com"}, Weight: 50},
+						{Destination: &networking.Destination{Host: "api.other.com"}, Weight: 50},
+					},
+				},
+			},
+		}
+		config := model.Config{
+			ConfigMeta: model.ConfigMeta{
+				Type:    schemas.VirtualService.Type,
+				Version: schemas.VirtualService.Version,
+				Name:    "acme",
+			},
+			Spec: vs,
+		}
+
+		routes, err := route.BuildHTTPRoutesForVirtualService(node, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(routes[0].GetRoute().HostRewriteSpecifier).To(gomega.BeNil())
+
+		config.Annotations = map[string]string{route.RewriteHostToDestinationAnnotation: "*"}
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(len(routes)).To(gomega.Equal(3))
+		g.Expect(routes[0].GetRoute().GetHostRewrite()).To(gomega.Equal("api.saas.com"))
+		g.Expect(routes[1].GetRoute().GetHostRewrite()).To(gomega.Equal("explicit.saas.com"))
+		g.Expect(routes[2].GetRoute().GetAutoHostRewrite().GetValue()).To(gomega.BeTrue())
+
+		config.Annotations = map[string]string{route.RewriteHostToDestinationAnnotation: "split"}
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(routes[0].GetRoute().HostRewriteSpecifier).To(gomega.BeNil())
+		g.Expect(routes[2].GetRoute().GetAutoHostRewrite().GetValue()).To(gomega.BeTrue())
+
+		config.Namespace = "default"
+		config.Domain = "cluster.local"
+		config.Annotations = map[string]string{route.RewriteHostToDestinationAnnotation: "saas"}
+		vs.Http[0].Route[0].Destination.Host = "api"
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(routes[0].GetRoute().GetHostRewrite()).To(gomega.Equal("api.default.svc.cluster.local"))
+	})
+	t.Run("for virtual service with websocket and idle timeout annotations", func(t *testing.T) {
+		g := gomega.NewGomegaWithT(t)
+
+		destination := []*networking.HTTPRouteDestination{
+			{Destination: &networking.Destination{Host: "*.example.org"}, Weight: 100},
+		}
+		config := model.Config{
+			ConfigMeta: model.ConfigMeta{
+				Type:    schemas.VirtualService.Type,
+				Version: schemas.VirtualService.Version,
+				Name:    "acme",
+				Annotations: map[string]string{
+					route.DisableWebsocketAnnotation: "api",
+					route.IdleTimeoutAnnotation:      "chat=1h, *=5m, api=never",
+				},
+			},
+			Spec: &networking.VirtualService{
+				Hosts:    []string{},
+				Gateways: []string{"some-gateway"},
+				Http: []*networking.HTTPRoute{
+					{
+						Name:  "chat",
+						Match: []*networking.HTTPMatchRequest{{Uri: &networking.StringMatch{MatchType: &networking.StringMatch_Prefix{Prefix: "/chat"}}}},
+						Route: destination,
+					},
+					{
+						Name:  "api",
+						Route: destination,
+					},
+				},
+			},
+		}
+
+		routes, err := route.BuildHTTPRoutesForVirtualService(node, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(len(routes)).To(gomega.Equal(2))
+
+		g.Expect(routes[0].GetRoute().UpgradeConfigs).To(gomega.BeNil())
+		g.Expect(routes[0].GetRoute().IdleTimeout).To(gomega.Equal(ptypes.DurationProto(time.Hour)))
+
+		g.Expect(len(routes[1].GetRoute().UpgradeConfigs)).To(gomega.Equal(1))
+		g.Expect(routes[1].GetRoute().UpgradeConfigs[0].UpgradeType).To(gomega.Equal("websocket"))
+		g.Expect(routes[1].GetRoute().UpgradeConfigs[0].Enabled.GetValue()).To(gomega.BeFalse())
+		// The invalid timeout of the route is ignored in favor of the default one.
+		g.Expect(routes[1].GetRoute().IdleTimeout).To(gomega.Equal(ptypes.DurationProto(5 * time.Minute)))
+	})
+	t.Run("for virtual service with cache TTL annotation", func(t *testing.T) {
+		g := gomega.NewGomegaWithT(t)
+
+		destination := []*networking.HTTPRouteDestination{
+			{Destination: &networking.Destination{Host: "*.example.org"}, Weight: 100},
+		}
+		config := model.Config{
+			ConfigMeta: model.ConfigMeta{
+				Type:        schemas.VirtualService.Type,
+				Version:     schemas.VirtualService.Version,
+				Name:        "acme",
+				Annotations: map[string]string{route.CacheTTLAnnotation: "assets=1h"},
+			},
+			Spec: &networking.VirtualService{
+				Hosts:    []string{},
+				Gateways: []string{"some-gateway"},
+				Http: []*networking.HTTPRoute{
+					{
+						Name:  "assets",
+						Match: []*networking.HTTPMatchRequest{{Uri: &networking.StringMatch{MatchType: &networking.StringMatch_Prefix{Prefix: "/assets"}}}},
+						Route: destination,
+					},
+					{
+						Name:  "api",
+						Route: destination,
+					},
+				},
+			},
+		}
+		cacheControl := func(r *envoyroute.Route) string {
+			for _, h := range r.ResponseHeadersToAdd {
+				if h.Header.Key == "Cache-Control" {
+					return h.Header.Value
+				}
+			}
+			return ""
+		}
+
+		gateway := *node
+		gateway.Type = model.Router
+		routes, err := route.BuildHTTPRoutesForVirtualService(&gateway, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(len(routes)).To(gomega.Equal(2))
+		g.Expect(cacheControl(routes[0])).To(gomega.Equal("public, max-age=3600"))
+		g.Expect(cacheControl(routes[1])).To(gomega.BeEmpty())
+
+		// Only the gateways cache the responses.
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(cacheControl(routes[0])).To(gomega.BeEmpty())
+	})
+	t.Run("for virtual service with max request body bytes annotation", func(t *testing.T) {
+		g := gomega.NewGomegaWithT(t)
+
+		destination := []*networking.HTTPRouteDestination{
+			{Destination: &networking.Destination{Host: "*.example.org"}, Weight: 100},
+		}
+		config := model.Config{
+			ConfigMeta: model.ConfigMeta{
+				Type:        schemas.VirtualService.Type,
+				Version:     schemas.VirtualService.Version,
+				Name:        "acme",
+				Annotations: map[string]string{model.MaxRequestBodyBytesAnnotation: "upload=104857600, *=0"},
+			},
+			Spec: &networking.VirtualService{
+				Hosts:    []string{},
+				Gateways: []string{"some-gateway"},
+				Http: []*networking.HTTPRoute{
+					{
+						Name:  "upload",
+						Match: []*networking.HTTPMatchRequest{{Uri: &networking.StringMatch{MatchType: &networking.StringMatch_Prefix{Prefix: "/upload"}}}},
+						Route: destination,
+					},
+					{
+						Name:  "stream",
+						Route: destination,
+					},
+				},
+			},
+		}
+		perRoute := func(r *envoyroute.Route) *buffer.BufferPerRoute {
+			config, f := r.TypedPerFilterConfig[xdsutil.Buffer]
+			if !f {
+				return nil
+			}
+			out := &buffer.BufferPerRoute{}
+			g.Expect(ptypes.UnmarshalAny(config, out)).To(gomega.Succeed())
+			return out
+		}
+
+		gateway := *node
+		gateway.Type = model.Router
+		routes, err := route.BuildHTTPRoutesForVirtualService(&gateway, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(len(routes)).To(gomega.Equal(2))
+		g.Expect(perRoute(routes[0]).GetBuffer().GetMaxRequestBytes().GetValue()).To(gomega.Equal(uint32(104857600)))
+		g.Expect(perRoute(routes[1]).GetDisabled()).To(gomega.BeTrue())
+
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(perRoute(routes[0])).To(gomega.BeNil())
+	})
+	t.Run("for virtual service with request priorities annotation", func(t *testing.T) {
+		g := gomega.NewGomegaWithT(t)
+
+		config := model.Config{
+			ConfigMeta: model.ConfigMeta{
+				Type:    schemas.VirtualService.Type,
+				Version: schemas.VirtualService.Version,
+				Name:    "acme",
+				Annotations: map[string]string{route.RequestPrioritiesAnnotation: `{"values": {
+					"interactive": {"priority": "high", "timeout": "2s", "retryAttempts": 3},
+					"batch": {"timeout": "30s", "retryAttempts": 0},
+					"*": {"priority": "high"}}}`},
+			},
+			Spec: &networking.VirtualService{
+				Hosts:    []string{},
+				Gateways: []string{"some-gateway"},
+				Http: []*networking.HTTPRoute{
+					{
+						Route: []*networking.HTTPRouteDestination{
+							{Destination: &networking.Destination{Host: "*.example.org"}, Weight: 100},
+						},
+						Timeout: types.DurationProto(10 * time.Second),
+						Retries: &networking.HTTPRetry{Attempts: 2},
+					},
+				},
+			},
+		}
+
+		routes, err := route.BuildHTTPRoutesForVirtualService(node, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(len(routes)).To(gomega.Equal(3))
+
+		batch := routes[0]
+		g.Expect(batch.Match.Headers).To(gomega.Equal([]*envoyroute.HeaderMatcher{{
+			Name:                 "x-request-priority",
+			HeaderMatchSpecifier: &envoyroute.HeaderMatcher_ExactMatch{ExactMatch: "batch"},
+		}}))
+		g.Expect(batch.GetRoute().Priority).To(gomega.Equal(envoycore.RoutingPriority_DEFAULT))
+		g.Expect(batch.GetRoute().Timeout.Seconds).To(gomega.Equal(int64(30)))
+		g.Expect(batch.GetRoute().RetryPolicy).To(gomega.BeNil())
+
+		interactive := routes[1]
+		g.Expect(interactive.Match.Headers[0].GetExactMatch()).To(gomega.Equal("interactive"))
+		g.Expect(interactive.GetRoute().Priority).To(gomega.Equal(envoycore.RoutingPriority_HIGH))
+		g.Expect(interactive.GetRoute().Timeout.Seconds).To(gomega.Equal(int64(2)))
+		g.Expect(interactive.GetRoute().RetryPolicy.NumRetries.GetValue()).To(gomega.Equal(uint32(3)))
+
+		base := routes[2]
+		g.Expect(base.Match.Headers).To(gomega.BeEmpty())
+		g.Expect(base.GetRoute().Priority).To(gomega.Equal(envoycore.RoutingPriority_HIGH))
+		g.Expect(base.GetRoute().Timeout.Seconds).To(gomega.Equal(int64(10)))
+		g.Expect(base.GetRoute().RetryPolicy.NumRetries.GetValue()).To(gomega.Equal(uint32(2)))
+	})
+	t.Run("for virtual service with retriable headers annotation", func(t *testing.T) {
+		g := gomega.NewGomegaWithT(t)
+
+		destination := []*networking.HTTPRouteDestination{
+			{Destination: &networking.Destination{Host: "*.example.org"}, Weight: 100},
+		}
+		config := model.Config{
+			ConfigMeta: model.ConfigMeta{
+				Type:        schemas.VirtualService.Type,
+				Version:     schemas.VirtualService.Version,
+				Name:        "acme",
+				Annotations: map[string]string{route.RetriableHeadersAnnotation: "api=x-upstream-busy x-retry, *=x-retry"},
+			},
+			Spec: &networking.VirtualService{
+				Hosts:    []string{},
+				Gateways: []string{"some-gateway"},
+				Http: []*networking.HTTPRoute{
+					{
+						Name:    "api",
+						Match:   []*networking.HTTPMatchRequest{{Uri: &networking.StringMatch{MatchType: &networking.StringMatch_Prefix{Prefix: "/api"}}}},
+						Route:   destination,
+						Retries: &networking.HTTPRetry{Attempts: 3, RetryOn: "reset,connect-failure,503"},
+					},
+					{
+						Name:    "static",
+						Route:   destination,
+						Retries: &networking.HTTPRetry{Attempts: 0},
+					},
+				},
+			},
+		}
+
+		routes, err := route.BuildHTTPRoutesForVirtualService(node, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(len(routes)).To(gomega.Equal(2))
+
+		policy := routes[0].GetRoute().RetryPolicy
+		g.Expect(policy.RetryOn).To(gomega.Equal("reset,connect-failure,retriable-status-codes,retriable-headers"))
+		g.Expect(policy.RetriableStatusCodes).To(gomega.Equal([]uint32{503}))
+		g.Expect(len(policy.RetriableHeaders)).To(gomega.Equal(2))
+		g.Expect(policy.RetriableHeaders[0].Name).To(gomega.Equal("x-upstream-busy"))
+		g.Expect(policy.RetriableHeaders[1].Name).To(gomega.Equal("x-retry"))
+
+		// The routes which do not retry are left alone.
+		g.Expect(routes[1].GetRoute().RetryPolicy).To(gomega.BeNil())
+	})
+	t.Run("for virtual service with CORS origin patterns", func(t *testing.T) {
+		g := gomega.NewGomegaWithT(t)
+
+		cors := &networking.CorsPolicy{AllowOrigin: []string{"https://example.org"}}
+		config := model.Config{
+			ConfigMeta: model.ConfigMeta{
+				Type:    schemas.VirtualService.Type,
+				Version: schemas.VirtualService.Version,
+				Name:    "acme",
+			},
+			Spec: &networking.VirtualService{
+				Hosts:    []string{},
+				Gateways: []string{"some-gateway"},
+				Http: []*networking.HTTPRoute{
+					{
+						Route: []*networking.HTTPRouteDestination{
+							{Destination: &networking.Destination{Host: "*.example.org"}, Weight: 100},
+						},
+						CorsPolicy: cors,
+					},
+				},
+			},
+		}
+
+		// Exact origins are kept as is for older proxies.
+		routes, err := route.BuildHTTPRoutesForVirtualService(node, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(routes[0].GetRoute().Cors.AllowOrigin).To(gomega.Equal([]string{"https://example.org"}))
+		g.Expect(routes[0].GetRoute().Cors.AllowOriginStringMatch).To(gomega.BeNil())
+
+		cors.AllowOrigin = []string{"https://example.org", "regex:https://.*[.]example[.]com", "prefix:https://app-", "*"}
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(routes[0].GetRoute().Cors.AllowOrigin).To(gomega.BeNil())
+		g.Expect(routes[0].GetRoute().Cors.AllowOriginStringMatch).To(gomega.Equal([]*matcher.StringMatcher{
+			{MatchPattern: &matcher.StringMatcher_Exact{Exact: "https://example.org"}},
+			{MatchPattern: &matcher.StringMatcher_SafeRegex{SafeRegex: util.RegexMatcher("https://.*[.]example[.]com")}},
+			{MatchPattern: &matcher.StringMatcher_Prefix{Prefix: "https://app-"}},
+			{MatchPattern: &matcher.StringMatcher_SafeRegex{SafeRegex: util.RegexMatcher(".*")}},
+		}))
+
+		// Regexes which are not RE2 are ignored rather than matched with another engine.
+		cors.AllowOrigin = []string{"regex:https://(?!internal).*", "prefix:https://app-"}
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(routes[0].GetRoute().Cors.AllowOriginStringMatch).To(gomega.Equal([]*matcher.StringMatcher{
+			{MatchPattern: &matcher.StringMatcher_Prefix{Prefix: "https://app-"}},
+		}))
+	})
+	t.Run("for virtual service with CORS credentialed origins", func(t *testing.T) {
+		g := gomega.NewGomegaWithT(t)
+
+		config := model.Config{
+			ConfigMeta: model.ConfigMeta{
+				Type:    schemas.VirtualService.Type,
+				Version: schemas.VirtualService.Version,
+				Name:    "acme",
+				Annotations: map[string]string{
+					route.CORSCredentialedOriginsAnnotation: "https://app.example.org, prefix:https://admin-, *",
+				},
+			},
+			Spec: &networking.VirtualService{
+				Hosts:    []string{},
+				Gateways: []string{"some-gateway"},
+				Http: []*networking.HTTPRoute{
+					{
+						Route: []*networking.HTTPRouteDestination{
+							{Destination: &networking.Destination{Host: "*.example.org"}, Weight: 100},
+						},
+						CorsPolicy: &networking.CorsPolicy{AllowOrigin: []string{"*"}},
+					},
+				},
+			},
+		}
+
+		routes, err := route.BuildHTTPRoutesForVirtualService(node, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(len(routes)).To(gomega.Equal(3))
+		g.Expect(routes[0].Match.Headers).To(gomega.Equal([]*envoyroute.HeaderMatcher{{
+			Name:                 "origin",
+			HeaderMatchSpecifier: &envoyroute.HeaderMatcher_ExactMatch{ExactMatch: "https://app.example.org"},
+		}}))
+		g.Expect(routes[0].GetRoute().Cors.AllowCredentials.GetValue()).To(gomega.BeTrue())
+		g.Expect(routes[1].Match.Headers).To(gomega.Equal([]*envoyroute.HeaderMatcher{{
+			Name:                 "origin",
+			HeaderMatchSpecifier: &envoyroute.HeaderMatcher_PrefixMatch{PrefixMatch: "https://admin-"},
+		}}))
+		g.Expect(routes[1].GetRoute().Cors.AllowCredentials.GetValue()).To(gomega.BeTrue())
+		// The wildcard is not credentialed, and the other origins keep the policy.
+		g.Expect(routes[2].Match.Headers).To(gomega.BeNil())
+		g.Expect(routes[2].GetRoute().Cors.AllowCredentials.GetValue()).To(gomega.BeFalse())
+	})
+	t.Run("for virtual service with regex matches", func(t *testing.T) {
+		g := gomega.NewGomegaWithT(t)
+
+		regex := &networking.StringMatch{MatchType: &networking.StringMatch_Regex{Regex: "v[0-9]+"}}
+		vs := &networking.VirtualService{
+			Hosts:    []string{},
+			Gateways: []string{"some-gateway"},
+			Http: []*networking.HTTPRoute{
+				{
+					Match: []*networking.HTTPMatchRequest{{
+						Uri:         &networking.StringMatch{MatchType: &networking.StringMatch_Regex{Regex: "/api/.*"}},
+						Headers:     map[string]*networking.StringMatch{"x-version": regex},
+						QueryParams: map[string]*networking.StringMatch{"version": regex},
+					}},
+					Route: []*networking.HTTPRouteDestination{
+						{Destination: &networking.Destination{Host: "*.example.org"}, Weight: 100},
+					},
+				},
+			},
+		}
+		config := model.Config{
+			ConfigMeta: model.ConfigMeta{
+				Type:    schemas.VirtualService.Type,
+				Version: schemas.VirtualService.Version,
+				Name:    "acme",
+			},
+			Spec: vs,
+		}
+
+		routes, err := route.BuildHTTPRoutesForVirtualService(node, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(len(routes)).To(gomega.Equal(1))
+		match := routes[0].Match
+		g.Expect(match.GetSafeRegex()).To(gomega.Equal(util.RegexMatcher("/api/.*")))
+		g.Expect(match.Headers[0].GetSafeRegexMatch()).To(gomega.Equal(util.RegexMatcher("v[0-9]+")))
+		g.Expect(match.QueryParameters[0].GetStringMatch().GetSafeRegex()).To(gomega.Equal(util.RegexMatcher("v[0-9]+")))
+		g.Expect(match.GetSafeRegex().GetGoogleRe2()).NotTo(gomega.BeNil())
+
+		// Regexes that are not valid RE2 keep the deprecated ECMAScript matchers.
+		legacy := &networking.StringMatch{MatchType: &networking.StringMatch_Regex{Regex: "(v[0-9]+)-\\1"}}
+		vs.Http[0].Match[0].Uri = &networking.StringMatch{MatchType: &networking.StringMatch_Regex{Regex: "/api/(?!internal).*"}}
+		vs.Http[0].Match[0].Headers["x-version"] = legacy
+		vs.Http[0].Match[0].QueryParams["version"] = legacy
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		match = routes[0].Match
+		g.Expect(match.GetRegex()).To(gomega.Equal("/api/(?!internal).*"))
+		g.Expect(match.Headers[0].GetRegexMatch()).To(gomega.Equal("(v[0-9]+)-\\1"))
+		g.Expect(match.QueryParameters[0].GetStringMatch().GetRegex()).To(gomega.Equal("(v[0-9]+)-\\1"))
+	})
+
+	t.Run("for virtual service with gRPC-Web", func(t *testing.T) {
+		g := gomega.NewGomegaWithT(t)
+
+		vs := &networking.VirtualService{
+			Hosts:    []string{},
+			Gateways: []string{"some-gateway"},
+			Http: []*networking.HTTPRoute{
+				{
+					Route: []*networking.HTTPRouteDestination{
+						{Destination: &networking.Destination{Host: "*.example.org"}, Weight: 100},
+					},
+					CorsPolicy: &networking.CorsPolicy{
+						AllowOrigin:   []string{"https://example.org"},
+						AllowHeaders:  []string{"Content-Type", "authorization"},
+						ExposeHeaders: []string{"x-custom"},
+					},
+				},
+			},
+		}
+		config := model.Config{
+			ConfigMeta: model.ConfigMeta{
+				Type:    schemas.VirtualService.Type,
+				Version: schemas.VirtualService.Version,
+				Name:    "acme",
+			},
+			Spec: vs,
+		}
+
+		routes, err := route.BuildHTTPRoutesForVirtualService(node, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		cors := routes[0].GetRoute().Cors
+		g.Expect(cors.AllowHeaders).To(gomega.Equal("Content-Type,authorization"))
+		g.Expect(cors.ExposeHeaders).To(gomega.Equal("x-custom"))
+
+		config.Annotations = map[string]string{route.GRPCWebAnnotation: "true"}
+		routes, err = route.BuildHTTPRoutesForVirtualService(node, nil, config, serviceRegistry, 8080, gatewayNames)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		cors = routes[0].GetRoute().Cors
+		g.Expect(cors.AllowHeaders).To(gomega.Equal("Content-Type,authorization,x-grpc-web,x-user-agent,grpc-timeout"))
+		g.Expect(cors.ExposeHeaders).To(gomega.Equal("x-custom,grpc-status,grpc-message"))
+	})
 	t.Run("for no virtualservice but has destinationrule with consistentHash loadbalancer", func(t *testing.T) {
 		g := gomega.NewGomegaWithT(t)
 		meshConfig := mesh.DefaultMeshConfig()
@@ -570,15 +1075,15 @@ func TestCombineVHostRoutes(t *testing.T) {
 	first := []*envoyroute.Route{
 		{Match: &envoyroute.RouteMatch{PathSpecifier: &envoyroute.RouteMatch_Path{Path: "/path1"}}},
 		{Match: &envoyroute.RouteMatch{PathSpecifier: &envoyroute.RouteMatch_Prefix{Prefix: "/prefix1"}}},
-		{Match: &envoyroute.RouteMatch{PathSpecifier: &envoyroute.RouteMatch_Regex{Regex: ".*?regex1"}}},
+		{Match: &envoyroute.RouteMatch{PathSpecifier: &envoyroute.RouteMatch_SafeRegex{SafeRegex: &matcher.RegexMatcher{Regex: ".*?regex1"}}}},
 		{Match: &envoyroute.RouteMatch{PathSpecifier: &envoyroute.RouteMatch_Prefix{Prefix: "/"}}},
 	}
 	second := []*envoyroute.Route{
 		{Match: &envoyroute.RouteMatch{PathSpecifier: &envoyroute.RouteMatch_Path{Path: "/path12"}}},
 		{Match: &envoyroute.RouteMatch{PathSpecifier: &envoyroute.RouteMatch_Prefix{Prefix: "/prefix12"}}},
-		{Match: &envoyroute.RouteMatch{PathSpecifier: &envoyroute.RouteMatch_Regex{Regex: ".*?regex12"}}},
+		{Match: &envoyroute.RouteMatch{PathSpecifier: &envoyroute.RouteMatch_SafeRegex{SafeRegex: &matcher.RegexMatcher{Regex: ".*?regex12"}}}},
 		{Match: &envoyroute.RouteMatch{
-			PathSpecifier: &envoyroute.RouteMatch_Regex{Regex: "*"},
+			PathSpecifier: &envoyroute.RouteMatch_SafeRegex{SafeRegex: &matcher.RegexMatcher{Regex: "*"}},
 			Headers: []*envoyroute.HeaderMatcher{
 				{
 					Name:                 "foo",
@@ -592,12 +1097,12 @@ func TestCombineVHostRoutes(t *testing.T) {
 	want := []*envoyroute.Route{
 		{Match: &envoyroute.RouteMatch{PathSpecifier: &envoyroute.RouteMatch_Path{Path: "/path1"}}},
 		{Match: &envoyroute.RouteMatch{PathSpecifier: &envoyroute.RouteMatch_Prefix{Prefix: "/prefix1"}}},
-		{Match: &envoyroute.RouteMatch{PathSpecifier: &envoyroute.RouteMatch_Regex{Regex: ".*?regex1"}}},
+		{Match: &envoyroute.RouteMatch{PathSpecifier: &envoyroute.RouteMatch_SafeRegex{SafeRegex: &matcher.RegexMatcher{Regex: ".*?regex1"}}}},
 		{Match: &envoyroute.RouteMatch{PathSpecifier: &envoyroute.RouteMatch_Path{Path: "/path12"}}},
 		{Match: &envoyroute.RouteMatch{PathSpecifier: &envoyroute.RouteMatch_Prefix{Prefix: "/prefix12"}}},
-		{Match: &envoyroute.RouteMatch{PathSpecifier: &envoyroute.RouteMatch_Regex{Regex: ".*?regex12"}}},
+		{Match: &envoyroute.RouteMatch{PathSpecifier: &envoyroute.RouteMatch_SafeRegex{SafeRegex: &matcher.RegexMatcher{Regex: ".*?regex12"}}}},
 		{Match: &envoyroute.RouteMatch{
-			PathSpecifier: &envoyroute.RouteMatch_Regex{Regex: "*"},
+			PathSpecifier: &envoyroute.RouteMatch_SafeRegex{SafeRegex: &matcher.RegexMatcher{Regex: "*"}},
 			Headers: []*envoyroute.HeaderMatcher{
 				{
 					Name:                 "foo",
@@ -622,3 +1127,56 @@ func TestCombineVHostRoutes(t *testing.T) {
 		}
 	}
 }
+
+func TestApplyVirtualHostHeaders(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	vhost := &envoyroute.VirtualHost{Name: "foo.example.com:80"}
+	route.ApplyVirtualHostHeaders(vhost, &networking.Headers{
+		Request: &networking.Headers_HeaderOperations{
+			Add:    map[string]string{"x-gateway": "ingress"},
+			Remove: []string{"x-internal"},
+		},
+		Response: &networking.Headers_HeaderOperations{
+			Set:    map[string]string{"Strict-Transport-Security": "max-age=31536000"},
+			Remove: []string{"server"},
+		},
+	})
+
+	g.Expect(len(vhost.RequestHeadersToAdd)).To(gomega.Equal(1))
+	g.Expect(vhost.RequestHeadersToAdd[0].Header.Key).To(gomega.Equal("x-gateway"))
+	g.Expect(vhost.RequestHeadersToAdd[0].Append.GetValue()).To(gomega.BeTrue())
+	g.Expect(vhost.RequestHeadersToRemove).To(gomega.Equal([]string{"x-internal"}))
+	g.Expect(len(vhost.ResponseHeadersToAdd)).To(gomega.Equal(1))
+	g.Expect(vhost.ResponseHeadersToAdd[0].Header.Key).To(gomega.Equal("Strict-Transport-Security"))
+	g.Expect(vhost.ResponseHeadersToAdd[0].Append.GetValue()).To(gomega.BeFalse())
+	g.Expect(vhost.ResponseHeadersToRemove).To(gomega.Equal([]string{"server"}))
+}
+
+func TestPrependRejectAmbiguousPathRoute(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	defer func(enabled bool) { features.RejectAmbiguousPaths = enabled }(features.RejectAmbiguousPaths)
+	node := &model.Proxy{IstioVersion: &model.IstioVersion{Major: 1, Minor: 4}}
+
+	features.RejectAmbiguousPaths = false
+	vhost := &envoyroute.VirtualHost{Routes: []*envoyroute.Route{{Name: "default"}}}
+	route.PrependRejectAmbiguousPathRoute(node, vhost)
+	g.Expect(len(vhost.Routes)).To(gomega.Equal(1))
+
+	features.RejectAmbiguousPaths = true
+	route.PrependRejectAmbiguousPathRoute(node, vhost)
+	g.Expect(len(vhost.Routes)).To(gomega.Equal(2))
+	reject := vhost.Routes[0]
+	g.Expect(reject.Name).To(gomega.Equal(route.AmbiguousPathRouteName))
+	g.Expect(reject.GetDirectResponse().Status).To(gomega.Equal(uint32(400)))
+	g.Expect(vhost.Routes[1].Name).To(gomega.Equal("default"))
+
+	// Envoy matches the whole path with the regex.
+	re := regexp.MustCompile("^(?:" + reject.Match.GetSafeRegex().GetRegex() + ")$")
+	for _, path := range []string{"/admin//users", "/admin%2Fusers", "/admin%2fusers", "/public/%2e%2e/admin", "/public%5Cadmin"} {
+		g.Expect(re.MatchString(path)).To(gomega.BeTrue(), path)
+	}
+	for _, path := range []string{"/", "/admin/users", "/search%20term", "/a/b.c"} {
+		g.Expect(re.MatchString(path)).To(gomega.BeFalse(), path)
+	}
+}
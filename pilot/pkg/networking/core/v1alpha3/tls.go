@@ -21,6 +21,7 @@ import (
 
 	"istio.io/api/networking/v1alpha3"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pkg/config/host"
@@ -29,6 +30,9 @@ import (
 	"istio.io/pkg/log"
 )
 
+// httpsPort is the port of the external services whose TCP traffic is also routed by SNI.
+const httpsPort = 443
+
 // Match by source labels, the listener port where traffic comes in, the gateway on which the rule is being
 // bound, etc. All these can be checked statically, since we are generating the configuration for a proxy
 // with predefined labels, on a specific port.
@@ -124,7 +128,7 @@ func buildSidecarOutboundTLSFilterChainOpts(env *model.Environment, node *model.
 	out := make([]*filterChainOpts, 0)
 	for _, cfg := range configs {
 		virtualService := cfg.Spec.(*v1alpha3.VirtualService)
-		for _, tls := range virtualService.Tls {
+		for i, tls := range virtualService.Tls {
 			for _, match := range tls.Match {
 				if matchTLS(match, node.WorkloadLabels, gateways, listenPort.Port) {
 					// Use the service's CIDRs.
@@ -141,7 +145,7 @@ func buildSidecarOutboundTLSFilterChainOpts(env *model.Environment, node *model.
 					matchHash := hashRuntimeTLSMatchPredicates(match)
 					if !matchHasBeenHandled[matchHash] {
 						out = append(out, &filterChainOpts{
-							metadata:         util.BuildConfigInfoMetadata(cfg.ConfigMeta),
+							metadata:         util.BuildConfigInfoMetadataForRule(cfg.ConfigMeta, "tls", i),
 							sniHosts:         match.SniHosts,
 							destinationCIDRs: destinationCIDRs,
 							networkFilters:   buildOutboundNetworkFilters(env, node, tls.Route, push, listenPort, cfg.ConfigMeta),
@@ -211,12 +215,12 @@ func buildSidecarOutboundTCPFilterChainOpts(env *model.Environment, node *model.
 TcpLoop:
 	for _, cfg := range configs {
 		virtualService := cfg.Spec.(*v1alpha3.VirtualService)
-		for _, tcp := range virtualService.Tcp {
+		for i, tcp := range virtualService.Tcp {
 			destinationCIDRs := []string{destinationCIDR}
 			if len(tcp.Match) == 0 {
 				// implicit match
 				out = append(out, &filterChainOpts{
-					metadata:         util.BuildConfigInfoMetadata(cfg.ConfigMeta),
+					metadata:         util.BuildConfigInfoMetadataForRule(cfg.ConfigMeta, "tcp", i),
 					destinationCIDRs: destinationCIDRs,
 					networkFilters:   buildOutboundNetworkFilters(env, node, tcp.Route, push, listenPort, cfg.ConfigMeta),
 				})
@@ -240,7 +244,7 @@ TcpLoop:
 					// (this is similar to virtual hosts in http) and create filter chain match accordingly.
 					if len(match.DestinationSubnets) == 0 || listenPort.Port == 0 {
 						out = append(out, &filterChainOpts{
-							metadata:         util.BuildConfigInfoMetadata(cfg.ConfigMeta),
+							metadata:         util.BuildConfigInfoMetadataForRule(cfg.ConfigMeta, "tcp", i),
 							destinationCIDRs: destinationCIDRs,
 							networkFilters:   buildOutboundNetworkFilters(env, node, tcp.Route, push, listenPort, cfg.ConfigMeta),
 						})
@@ -254,6 +258,7 @@ TcpLoop:
 
 			if len(virtualServiceDestinationSubnets) > 0 {
 				out = append(out, &filterChainOpts{
+					metadata:         util.BuildConfigInfoMetadataForRule(cfg.ConfigMeta, "tcp", i),
 					destinationCIDRs: virtualServiceDestinationSubnets,
 					networkFilters:   buildOutboundNetworkFilters(env, node, tcp.Route, push, listenPort, cfg.ConfigMeta),
 				})
@@ -283,6 +288,15 @@ TcpLoop:
 		}
 
 		clusterName := model.BuildSubsetKey(model.TrafficDirectionOutbound, "", service.Hostname, port)
+		if useEgressSNIRouting(node, service, destinationCIDR, listenPort) {
+			// Applications originating TLS themselves still send the SNI of the service. Match it first,
+			// as the port only match below is kept for a single one of the services sharing the port.
+			out = append(out, &filterChainOpts{
+				sniHosts:         []string{string(service.Hostname)},
+				destinationCIDRs: []string{destinationCIDR},
+				networkFilters:   buildOutboundNetworkFiltersWithSingleDestination(env, node, clusterName, listenPort),
+			})
+		}
 		out = append(out, &filterChainOpts{
 			destinationCIDRs: []string{destinationCIDR},
 			networkFilters:   buildOutboundNetworkFiltersWithSingleDestination(env, node, clusterName, listenPort),
@@ -292,6 +306,19 @@ TcpLoop:
 	return out
 }
 
+// useEgressSNIRouting returns true if TCP traffic to an external service should also be matched by its
+// SNI: the service listens on the HTTPS port of a wildcard listener, where it cannot be told apart from
+// the other services on the port by its address.
+func useEgressSNIRouting(node *model.Proxy, service *model.Service, destinationCIDR string, listenPort *model.Port) bool {
+	if !features.EnableEgressSNIRouting || service == nil || !service.MeshExternal ||
+		listenPort.Port != httpsPort || destinationCIDR != "" {
+		return false
+	}
+	actualWildcard, _ := getActualWildcardAndLocalHost(node)
+	svcListenAddress := service.GetServiceAddressForProxy(node)
+	return svcListenAddress == "" || svcListenAddress == actualWildcard
+}
+
 // This function can be called for namespaces with the auto generated sidecar, i.e. once per service and per port.
 // OR, it could be called in the context of an egress listener with specific TCP port on a sidecar config.
 // In the latter case, there is no service associated with this listen port. So we have to account for this
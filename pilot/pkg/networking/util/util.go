@@ -18,15 +18,19 @@ import (
 	"fmt"
 	"math"
 	"net"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
 	listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
 	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	"github.com/envoyproxy/go-control-plane/envoy/type/matcher"
 	"github.com/envoyproxy/go-control-plane/pkg/conversion"
 	xdsutil "github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/gogo/protobuf/types"
@@ -38,6 +42,7 @@ import (
 	"github.com/golang/protobuf/ptypes/wrappers"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/pkg/cache"
 	"istio.io/pkg/log"
 
 	"istio.io/istio/pilot/pkg/features"
@@ -55,6 +60,11 @@ const (
 	// PassthroughRouteName is the name of the route that forwards traffic to the
 	// PassthroughCluster
 	PassthroughRouteName = "allow_any"
+	// DynamicForwardProxyCluster forwards traffic to external hosts resolved at request time.
+	DynamicForwardProxyCluster = "DynamicForwardProxyCluster"
+	// DynamicForwardProxyRouteName is the name of the route that forwards traffic to the
+	// DynamicForwardProxyCluster
+	DynamicForwardProxyRouteName = "dynamic_forward_proxy"
 
 	// Inbound pass through cluster need to the bind the loopback ip address for the security and loop avoidance.
 	InboundPassthroughClusterIpv4 = "InboundPassthroughClusterIpv4"
@@ -210,15 +220,67 @@ func LocalityLbWeightNormalize(endpoints []*endpoint.LocalityLbEndpoints) []*end
 	return out
 }
 
-// GetByAddress returns a listener by its address
-// TODO(mostrowski): consider passing map around to save iteration.
-func GetByAddress(listeners []*xdsapi.Listener, addr core.Address) *xdsapi.Listener {
+// ListenerSet indexes listeners by their address, so that looking up the listener bound to an
+// address does not require scanning all listeners. Listeners are kept in insertion order.
+type ListenerSet struct {
+	listeners []*xdsapi.Listener
+	byAddress map[string]*xdsapi.Listener
+}
+
+// NewListenerSet returns a set holding the given listeners. For listeners sharing an address,
+// the first one wins.
+func NewListenerSet(listeners ...*xdsapi.Listener) *ListenerSet {
+	set := &ListenerSet{
+		listeners: make([]*xdsapi.Listener, 0, len(listeners)),
+		byAddress: make(map[string]*xdsapi.Listener, len(listeners)),
+	}
 	for _, l := range listeners {
-		if l != nil && proto.Equal(l.Address, &addr) {
-			return l
-		}
+		set.Add(l)
+	}
+	return set
+}
+
+// Add adds the listener to the set unless a listener with the same address is already present.
+// It returns the listener bound to the address after the call, and whether it was added.
+func (s *ListenerSet) Add(l *xdsapi.Listener) (*xdsapi.Listener, bool) {
+	if l == nil {
+		return nil, false
+	}
+	key := addressKey(l.Address)
+	if existing, ok := s.byAddress[key]; ok {
+		return existing, false
+	}
+	s.byAddress[key] = l
+	s.listeners = append(s.listeners, l)
+	return l, true
+}
+
+// GetByAddress returns the listener bound to the address, or nil if there is none.
+func (s *ListenerSet) GetByAddress(addr *core.Address) *xdsapi.Listener {
+	return s.byAddress[addressKey(addr)]
+}
+
+// Listeners returns the listeners of the set in insertion order.
+func (s *ListenerSet) Listeners() []*xdsapi.Listener {
+	return s.listeners
+}
+
+// Len returns the number of listeners in the set.
+func (s *ListenerSet) Len() int {
+	return len(s.listeners)
+}
+
+// addressKey returns a string uniquely identifying an address. Socket addresses and pipes, the
+// only kinds built by pilot, are formatted directly; anything else falls back to the proto text.
+func addressKey(addr *core.Address) string {
+	switch a := addr.GetAddress().(type) {
+	case *core.Address_SocketAddress:
+		sa := a.SocketAddress
+		return fmt.Sprintf("%s/%s:%d/%s", sa.Protocol, sa.Address, sa.GetPortValue(), sa.GetNamedPort())
+	case *core.Address_Pipe:
+		return "pipe/" + a.Pipe.Path
 	}
-	return nil
+	return addr.String()
 }
 
 // MessageToAnyWithError converts from proto message to proto Any
@@ -235,7 +297,9 @@ func MessageToAnyWithError(msg proto.Message) (*any.Any, error) {
 	}, nil
 }
 
-// MessageToAny converts from proto message to proto Any
+// MessageToAny converts from proto message to proto Any. It logs marshaling errors and returns nil,
+// so it is only left in the filter and route builders that cannot return errors yet; the xDS
+// responses use MessageToAnyWithError.
 func MessageToAny(msg proto.Message) *any.Any {
 	out, err := MessageToAnyWithError(msg)
 	if err != nil {
@@ -245,6 +309,41 @@ func MessageToAny(msg proto.Message) *any.Any {
 	return out
 }
 
+const (
+	anyCacheSize       = 1000
+	anyCacheExpiration = 30 * time.Minute
+	anyCacheEviction   = time.Minute
+
+	re2CacheSize = 1000
+)
+
+var (
+	anyCache     cache.ExpiringCache
+	anyCacheOnce sync.Once
+
+	re2Cache     cache.ExpiringCache
+	re2CacheOnce sync.Once
+)
+
+// CachedMessageToAny returns the Any form of the message built by build, reusing the result of
+// an earlier call with the same key. The key must identify the content of the message, so that
+// hot messages shared by many listeners or proxies, like filter configs, are built and marshaled
+// once. The returned Any is shared and must not be modified. Marshaling errors are not cached.
+func CachedMessageToAny(key string, build func() proto.Message) (*any.Any, error) {
+	anyCacheOnce.Do(func() {
+		anyCache = cache.NewLRU(anyCacheExpiration, anyCacheEviction, anyCacheSize)
+	})
+	if cached, ok := anyCache.Get(key); ok {
+		return cached.(*any.Any), nil
+	}
+	out, err := MessageToAnyWithError(build())
+	if err != nil {
+		return nil, err
+	}
+	anyCache.Set(key, out)
+	return out, nil
+}
+
 // MessageToStruct converts from proto message to proto Struct
 func MessageToStruct(msg proto.Message) *pstruct.Struct {
 	s, err := conversion.MessageToStruct(msg)
@@ -290,6 +389,20 @@ func IsIstioVersionGE13(node *model.Proxy) bool {
 		node.IstioVersion.Compare(&model.IstioVersion{Major: 1, Minor: 3, Patch: -1}) >= 0
 }
 
+// IsIstioVersionGE14 checks whether the given Istio version is greater than or equals 1.4, whose
+// proxy is built on Envoy 1.12.
+func IsIstioVersionGE14(node *model.Proxy) bool {
+	return node.IstioVersion != nil &&
+		node.IstioVersion.Compare(&model.IstioVersion{Major: 1, Minor: 4, Patch: -1}) >= 0
+}
+
+// IsIstioVersionGE16 checks whether the given Istio version is greater than or equals 1.6, whose
+// proxy is built on Envoy 1.14.
+func IsIstioVersionGE16(node *model.Proxy) bool {
+	return node.IstioVersion != nil &&
+		node.IstioVersion.Compare(&model.IstioVersion{Major: 1, Minor: 6, Patch: -1}) >= 0
+}
+
 // IsXDSMarshalingToAnyEnabled controls whether "marshaling to Any" feature is enabled.
 func IsXDSMarshalingToAnyEnabled(node *model.Proxy) bool {
 	return !features.DisableXDSMarshalingToAny
@@ -453,23 +566,56 @@ func cloneLocalityLbEndpoints(endpoints []*endpoint.LocalityLbEndpoints) []*endp
 
 // BuildConfigInfoMetadata builds core.Metadata struct containing the
 // name.namespace of the config, the type, etc. Used by Mixer client
-// to generate attributes for policy and telemetry.
+// to generate attributes for policy and telemetry. When
+// features.EnableConfigRevisionMetadata is set, the resource version and UID
+// of the config, when known, let a config dump be traced back to the exact
+// revision of the resource.
 func BuildConfigInfoMetadata(config model.ConfigMeta) *core.Metadata {
+	fields := map[string]*pstruct.Value{
+		"config": stringValue(fmt.Sprintf("/apis/%s/%s/namespaces/%s/%s/%s", config.Group, config.Version, config.Namespace, config.Type, config.Name)),
+	}
+	if features.EnableConfigRevisionMetadata {
+		if config.ResourceVersion != "" {
+			fields["resourceVersion"] = stringValue(config.ResourceVersion)
+		}
+		if config.UID != "" {
+			fields["uid"] = stringValue(config.UID)
+		}
+	}
 	return &core.Metadata{
 		FilterMetadata: map[string]*pstruct.Struct{
 			IstioMetadataKey: {
-				Fields: map[string]*pstruct.Value{
-					"config": {
-						Kind: &pstruct.Value_StringValue{
-							StringValue: fmt.Sprintf("/apis/%s/%s/namespaces/%s/%s/%s", config.Group, config.Version, config.Namespace, config.Type, config.Name),
-						},
-					},
-				},
+				Fields: fields,
 			},
 		},
 	}
 }
 
+// BuildConfigInfoMetadataForRule builds the metadata of BuildConfigInfoMetadata, also recording
+// which rule of the config produced the object, e.g. "http[2]" for the third HTTP route of a
+// virtual service.
+func BuildConfigInfoMetadataForRule(config model.ConfigMeta, rule string, index int) *core.Metadata {
+	metadata := BuildConfigInfoMetadata(config)
+	metadata.FilterMetadata[IstioMetadataKey].Fields["rule"] = stringValue(fmt.Sprintf("%s[%d]", rule, index))
+	return metadata
+}
+
+func stringValue(s string) *pstruct.Value {
+	return &pstruct.Value{Kind: &pstruct.Value_StringValue{StringValue: s}}
+}
+
+// IstioMetadata returns the string value of the given key in the Istio filter metadata, or an empty
+// string if there is none.
+func IstioMetadata(metadata *core.Metadata, key string) string {
+	if metadata == nil || metadata.FilterMetadata[IstioMetadataKey] == nil {
+		return ""
+	}
+	if v := metadata.FilterMetadata[IstioMetadataKey].Fields[key]; v != nil {
+		return v.GetStringValue()
+	}
+	return ""
+}
+
 // IsHTTPFilterChain returns true if the filter chain contains a HTTP connection manager filter
 func IsHTTPFilterChain(filterChain *listener.FilterChain) bool {
 	for _, f := range filterChain.Filters {
@@ -538,3 +684,47 @@ func MergeAnyWithAny(dst *any.Any, src *any.Any) (*any.Any, error) {
 
 	return retVal, nil
 }
+
+// RegexMatcher returns a matcher of the regex with the RE2 engine, whose program size is bounded by
+// the PILOT_REGEX_MAX_PROGRAM_SIZE setting.
+func RegexMatcher(regex string) *matcher.RegexMatcher {
+	re2 := &matcher.RegexMatcher_GoogleRE2{}
+	if features.RegexMaxProgramSize > 0 {
+		re2.MaxProgramSize = &wrappers.UInt32Value{Value: uint32(features.RegexMaxProgramSize)}
+	}
+	return &matcher.RegexMatcher{
+		EngineType: &matcher.RegexMatcher_GoogleRe2{GoogleRe2: re2},
+		Regex:      regex,
+	}
+}
+
+// IsRE2 returns true if the regex is valid RE2 and can be matched with the safe_regex matchers.
+// Regexes accepted before validation required RE2 may use ECMAScript only constructs, such as
+// lookaheads or backreferences, which only the deprecated regex matchers support. The regexes are
+// the same on every push, so the results are cached, and the warning is logged when a regex is
+// first found not to be RE2.
+func IsRE2(regex string) bool {
+	re2CacheOnce.Do(func() {
+		re2Cache = cache.NewLRU(anyCacheExpiration, anyCacheEviction, re2CacheSize)
+	})
+	if cached, ok := re2Cache.Get(regex); ok {
+		return cached.(bool)
+	}
+	_, err := regexp.Compile(regex)
+	if err != nil {
+		log.Warnf("regex %q is not valid RE2, using the deprecated regex matcher: %v", regex, err)
+	}
+	re2Cache.Set(regex, err == nil)
+	return err == nil
+}
+
+// StringMatcherRegex returns a string matcher of the regex. Regexes that are not valid RE2 keep the
+// deprecated ECMAScript matcher, which Envoy still accepts, rather than getting the config rejected.
+func StringMatcherRegex(regex string) *matcher.StringMatcher {
+	if !IsRE2(regex) {
+		// Migration tracked in https://github.com/istio/istio/issues/17127
+		//nolint: staticcheck
+		return &matcher.StringMatcher{MatchPattern: &matcher.StringMatcher_Regex{Regex: regex}}
+	}
+	return &matcher.StringMatcher{MatchPattern: &matcher.StringMatcher_SafeRegex{SafeRegex: RegexMatcher(regex)}}
+}
@@ -36,6 +36,7 @@ import (
 	"gopkg.in/d4l3k/messagediff.v1"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 )
 
@@ -384,12 +385,14 @@ func TestIsLocalityEmpty(t *testing.T) {
 
 func TestBuildConfigInfoMetadata(t *testing.T) {
 	cases := []struct {
-		name string
-		in   model.ConfigMeta
-		want *core.Metadata
+		name     string
+		revision bool
+		in       model.ConfigMeta
+		want     *core.Metadata
 	}{
 		{
 			"destination-rule",
+			false,
 			model.ConfigMeta{
 				Group:     "networking.istio.io",
 				Version:   "v1alpha3",
@@ -412,10 +415,70 @@ func TestBuildConfigInfoMetadata(t *testing.T) {
 				},
 			},
 		},
+		{
+			"revision disabled",
+			false,
+			model.ConfigMeta{
+				Group:           "networking.istio.io",
+				Version:         "v1alpha3",
+				Name:            "svcA",
+				Namespace:       "default",
+				Type:            "destination-rule",
+				ResourceVersion: "42",
+				UID:             "6a3c7d1e-0b6f-4c43-9f8e-2b1d1c2f6e7a",
+			},
+			&core.Metadata{
+				FilterMetadata: map[string]*structpb.Struct{
+					IstioMetadataKey: {
+						Fields: map[string]*structpb.Value{
+							"config": {
+								Kind: &structpb.Value_StringValue{
+									StringValue: "/apis/networking.istio.io/v1alpha3/namespaces/default/destination-rule/svcA",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			"revision",
+			true,
+			model.ConfigMeta{
+				Group:           "networking.istio.io",
+				Version:         "v1alpha3",
+				Name:            "svcA",
+				Namespace:       "default",
+				Type:            "destination-rule",
+				ResourceVersion: "42",
+				UID:             "6a3c7d1e-0b6f-4c43-9f8e-2b1d1c2f6e7a",
+			},
+			&core.Metadata{
+				FilterMetadata: map[string]*structpb.Struct{
+					IstioMetadataKey: {
+						Fields: map[string]*structpb.Value{
+							"config": {
+								Kind: &structpb.Value_StringValue{
+									StringValue: "/apis/networking.istio.io/v1alpha3/namespaces/default/destination-rule/svcA",
+								},
+							},
+							"resourceVersion": {
+								Kind: &structpb.Value_StringValue{StringValue: "42"},
+							},
+							"uid": {
+								Kind: &structpb.Value_StringValue{StringValue: "6a3c7d1e-0b6f-4c43-9f8e-2b1d1c2f6e7a"},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
+	defer func(v bool) { features.EnableConfigRevisionMetadata = v }(features.EnableConfigRevisionMetadata)
 	for _, v := range cases {
 		t.Run(v.name, func(tt *testing.T) {
+			features.EnableConfigRevisionMetadata = v.revision
 			got := BuildConfigInfoMetadata(v.in)
 			if diff, equal := messagediff.PrettyDiff(got, v.want); !equal {
 				tt.Errorf("BuildConfigInfoMetadata(%v) produced incorrect result:\ngot: %v\nwant: %v\nDiff: %s", v.in, got, v.want, diff)
@@ -424,6 +487,23 @@ func TestBuildConfigInfoMetadata(t *testing.T) {
 	}
 }
 
+func TestBuildConfigInfoMetadataForRule(t *testing.T) {
+	config := model.ConfigMeta{
+		Group:     "networking.istio.io",
+		Version:   "v1alpha3",
+		Name:      "vs",
+		Namespace: "default",
+		Type:      "virtual-service",
+	}
+	got := BuildConfigInfoMetadataForRule(config, "http", 2)
+	if rule := IstioMetadata(got, "rule"); rule != "http[2]" {
+		t.Errorf("got rule %q, want %q", rule, "http[2]")
+	}
+	if path := IstioMetadata(got, "config"); path != "/apis/networking.istio.io/v1alpha3/namespaces/default/virtual-service/vs" {
+		t.Errorf("got config %q", path)
+	}
+}
+
 func TestCloneCluster(t *testing.T) {
 	cluster := buildFakeCluster()
 	clone := CloneCluster(cluster)
@@ -504,18 +584,16 @@ func TestIsHTTPFilterChain(t *testing.T) {
 }
 
 var (
-	listener80 = &v2.Listener{Address: BuildAddress("0.0.0.0", 80)}
-	listener81 = &v2.Listener{Address: BuildAddress("0.0.0.0", 81)}
-	listenerip = &v2.Listener{Address: BuildAddress("1.1.1.1", 80)}
+	listener80   = &v2.Listener{Address: BuildAddress("0.0.0.0", 80)}
+	listener81   = &v2.Listener{Address: BuildAddress("0.0.0.0", 81)}
+	listenerip   = &v2.Listener{Address: BuildAddress("1.1.1.1", 80)}
+	listenerPipe = &v2.Listener{Address: BuildAddress("unix:///var/run/test.sock", 0)}
 )
 
 func BenchmarkGetByAddress(b *testing.B) {
+	listeners := NewListenerSet(listener80, listener81, listenerip)
 	for n := 0; n < b.N; n++ {
-		GetByAddress([]*v2.Listener{
-			listener80,
-			listener81,
-			listenerip,
-		}, *listenerip.Address)
+		listeners.GetByAddress(listenerip.Address)
 	}
 }
 
@@ -550,11 +628,29 @@ func TestGetByAddress(t *testing.T) {
 			BuildAddress("0.0.0.0", 80),
 			listener80,
 		},
+		{
+			"nil listener",
+			[]*v2.Listener{
+				nil,
+				listener80,
+			},
+			BuildAddress("0.0.0.0", 80),
+			listener80,
+		},
+		{
+			"pipe",
+			[]*v2.Listener{
+				listener80,
+				listenerPipe,
+			},
+			BuildAddress("unix:///var/run/test.sock", 0),
+			listenerPipe,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := GetByAddress(tt.listeners, *tt.address)
+			got := NewListenerSet(tt.listeners...).GetByAddress(tt.address)
 			if got != tt.expected {
 				t.Errorf("Got %v, expected %v", got, tt.expected)
 			}
@@ -562,6 +658,50 @@ func TestGetByAddress(t *testing.T) {
 	}
 }
 
+func TestListenerSetAdd(t *testing.T) {
+	duplicate := &v2.Listener{Name: "duplicate", Address: BuildAddress("0.0.0.0", 80)}
+	listeners := NewListenerSet(listener80, listener81)
+
+	if existing, added := listeners.Add(duplicate); added || existing != listener80 {
+		t.Errorf("Add(duplicate) = %v, %v, expected %v, false", existing, added, listener80)
+	}
+	if existing, added := listeners.Add(listenerip); !added || existing != listenerip {
+		t.Errorf("Add(listenerip) = %v, %v, expected %v, true", existing, added, listenerip)
+	}
+	expected := []*v2.Listener{listener80, listener81, listenerip}
+	if !reflect.DeepEqual(listeners.Listeners(), expected) {
+		t.Errorf("Listeners() = %v, expected %v", listeners.Listeners(), expected)
+	}
+}
+
+func TestCachedMessageToAny(t *testing.T) {
+	builds := 0
+	build := func() proto.Message {
+		builds++
+		return &http_conn.HttpConnectionManager{StatPrefix: "cached"}
+	}
+
+	first, err := CachedMessageToAny("test/cached", build)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := CachedMessageToAny("test/cached", build)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Errorf("expected the cached Any to be reused")
+	}
+	if builds != 1 {
+		t.Errorf("expected the message to be built once, got %d", builds)
+	}
+
+	expected, _ := MessageToAnyWithError(&http_conn.HttpConnectionManager{StatPrefix: "cached"})
+	if !proto.Equal(first, expected) {
+		t.Errorf("got %v, expected %v", first, expected)
+	}
+}
+
 func TestMergeAnyWithStruct(t *testing.T) {
 	inHCM := &http_conn.HttpConnectionManager{
 		CodecType:  http_conn.HttpConnectionManager_HTTP1,
@@ -620,3 +760,42 @@ func TestMergeAnyWithStruct(t *testing.T) {
 		t.Errorf("Merged HCM does not match the expected output")
 	}
 }
+
+func TestRegexMatcher(t *testing.T) {
+	defer func(size int) { features.RegexMaxProgramSize = size }(features.RegexMaxProgramSize)
+	features.RegexMaxProgramSize = 0
+	m := RegexMatcher("/api/.*")
+	if m.Regex != "/api/.*" {
+		t.Errorf("got regex %q, want /api/.*", m.Regex)
+	}
+	if m.GetGoogleRe2() == nil || m.GetGoogleRe2().MaxProgramSize != nil {
+		t.Errorf("expected the RE2 engine with the default program size, got %v", m.EngineType)
+	}
+
+	features.RegexMaxProgramSize = 200
+	if got := RegexMatcher("/api/.*").GetGoogleRe2().GetMaxProgramSize().GetValue(); got != 200 {
+		t.Errorf("got max program size %d, want 200", got)
+	}
+}
+
+func TestIsRE2(t *testing.T) {
+	for _, regex := range []string{"/api/.*", "/api/(?!internal).*"} {
+		want := regex == "/api/.*"
+		// The second call is answered from the cache.
+		for i := 0; i < 2; i++ {
+			if got := IsRE2(regex); got != want {
+				t.Errorf("IsRE2(%q) = %v, want %v", regex, got, want)
+			}
+		}
+	}
+}
+
+func TestStringMatcherRegex(t *testing.T) {
+	if got := StringMatcherRegex("/api/.*").GetSafeRegex(); got == nil || got.Regex != "/api/.*" {
+		t.Errorf("got %v, want the RE2 matcher of /api/.*", got)
+	}
+	// Lookaheads are ECMAScript only and keep the deprecated matcher.
+	if got := StringMatcherRegex("/api/(?!internal).*").GetRegex(); got != "/api/(?!internal).*" {
+		t.Errorf("got regex %q, want the deprecated matcher of /api/(?!internal).*", got)
+	}
+}
@@ -94,6 +94,10 @@ type DiscoveryServiceOptions struct {
 	// a port number is automatically chosen.
 	GrpcAddr string
 
+	// The path of a Unix domain socket on which GRPC is also served, to the agents running on the same
+	// node. "" means disabled.
+	GrpcUDSPath string
+
 	// The listening address for secure GRPC. If the port in the address is empty or "0" (as in "127.0.0.1:" or "[::1]:0")
 	// a port number is automatically chosen.
 	// "" means disabling secure GRPC, used in test.
@@ -27,6 +27,7 @@ import (
 	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	ads "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v2"
 	"github.com/golang/protobuf/ptypes/any"
+	ot "github.com/opentracing/opentracing-go"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/peer"
@@ -194,6 +195,9 @@ type XdsEvent struct {
 
 	// function to call once a push is finished. This must be called or future changes may be blocked.
 	done func()
+
+	// spanContext is the context of the traced push request, if any.
+	spanContext ot.SpanContext
 }
 
 // UpdateEvent represents a update request for the proxy.
@@ -599,30 +603,42 @@ func (s *DiscoveryServer) pushConnection(con *XdsConnection, pushEv *XdsEvent) e
 
 	adsLog.Infof("Pushing %v", con.ConID)
 
+	span := startFollowingSpan("push_proxy", pushEv.spanContext)
+	span.SetTag("proxy", con.ConID)
+	defer span.Finish()
+
 	// check version, suppress if changed.
 	currentVersion := versionInfo()
 
 	if con.CDSWatch {
-		err := s.pushCds(con, pushEv.push, currentVersion)
+		err := tracePush(span, "cds", func() error {
+			return s.pushCds(con, pushEv.push, currentVersion)
+		})
 		if err != nil {
 			return err
 		}
 	}
 
 	if len(con.Clusters) > 0 {
-		err := s.pushEds(pushEv.push, con, currentVersion, nil)
+		err := tracePush(span, "eds", func() error {
+			return s.pushEds(pushEv.push, con, currentVersion, nil)
+		})
 		if err != nil {
 			return err
 		}
 	}
 	if con.LDSWatch {
-		err := s.pushLds(con, pushEv.push, currentVersion)
+		err := tracePush(span, "lds", func() error {
+			return s.pushLds(con, pushEv.push, currentVersion)
+		})
 		if err != nil {
 			return err
 		}
 	}
 	if len(con.Routes) > 0 {
-		err := s.pushRoute(con, pushEv.push, currentVersion)
+		err := tracePush(span, "rds", func() error {
+			return s.pushRoute(con, pushEv.push, currentVersion)
+		})
 		if err != nil {
 			return err
 		}
@@ -672,12 +688,15 @@ func (s *DiscoveryServer) AdsPushAll(version string, req *model.PushRequest) {
 	// UpdateCluster updates the cluster with a mutex, this code is safe ( but computing
 	// the update may be duplicated if multiple goroutines compute at the same time).
 	// In general this code is called from the 'event' callback that is throttled.
+	span := startChildSpan("update_eds_clusters", req.Span)
+	span.SetTag("clusters", len(cMap))
 	for clusterName, edsCluster := range cMap {
 		if err := s.updateCluster(req.Push, clusterName, edsCluster); err != nil {
 			adsLog.Errorf("updateCluster failed with clusterName %s", clusterName)
 			totalXDSInternalErrors.Increment()
 		}
 	}
+	span.Finish()
 	adsLog.Infof("Cluster init time %v %s", time.Since(t0), version)
 	req.EdsUpdates = nil
 	s.startPush(req)
@@ -704,6 +723,10 @@ func (s *DiscoveryServer) startPush(req *model.PushRequest) {
 	for _, p := range pending {
 		s.pushQueue.Enqueue(p, req)
 	}
+	if req.Span != nil {
+		req.Span.SetTag("proxies", len(pending))
+		req.Span.Finish()
+	}
 }
 
 func ProxyNeedsPush(proxy *model.Proxy, targetNamespaces map[string]struct{}, configs map[string]struct{}) bool {
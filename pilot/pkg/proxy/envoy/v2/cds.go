@@ -39,7 +39,12 @@ func (conn *XdsConnection) clusters(response []*xdsapi.Cluster) *xdsapi.Discover
 	}
 
 	for _, c := range response {
-		cc := util.MessageToAny(c)
+		cc, err := util.MessageToAnyWithError(c)
+		if err != nil {
+			adsLog.Errorf("CDS: failed to marshal cluster %s: %v", c.Name, err)
+			totalXDSInternalErrors.Increment()
+			continue
+		}
 		out.Resources = append(out.Resources, cc)
 	}
 
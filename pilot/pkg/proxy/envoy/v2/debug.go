@@ -64,6 +64,7 @@ func (s *DiscoveryServer) InitDebug(mux *http.ServeMux, sctl *aggregate.Controll
 	mux.HandleFunc("/debug/authenticationz", s.authenticationz)
 	mux.HandleFunc("/debug/config_dump", s.ConfigDump)
 	mux.HandleFunc("/debug/push_status", s.PushStatusHandler)
+	mux.HandleFunc("/debug/dryrunz", s.dryRunz)
 }
 
 // SyncStatus is the synchronization status between Pilot and a given Envoy
@@ -479,6 +480,12 @@ func (s *DiscoveryServer) ready(w http.ResponseWriter, req *http.Request) {
 			return
 		}
 	}
+	// Proxies connecting before the first push context is built would get
+	// config computed from a partial view of the mesh.
+	if pc := s.globalPushContext(); pc == nil || !pc.InitDone() {
+		w.WriteHeader(503)
+		return
+	}
 	w.WriteHeader(200)
 }
 
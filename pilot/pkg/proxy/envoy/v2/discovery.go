@@ -21,6 +21,7 @@ import (
 
 	ads "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v2"
 	"github.com/google/uuid"
+	ot "github.com/opentracing/opentracing-go"
 	"go.uber.org/atomic"
 	"google.golang.org/grpc"
 
@@ -114,6 +115,13 @@ type DiscoveryServer struct {
 
 	// pushQueue is the buffer that used after debounce and before the real xds push.
 	pushQueue *PushQueue
+
+	// warmupMutex protects warmupPushes.
+	warmupMutex sync.Mutex
+
+	// warmupPushes holds the services, keyed by hostname and namespace, with an EDS push scheduled
+	// to step up the weight of their warming endpoints.
+	warmupPushes map[string]bool
 }
 
 // EndpointShards holds the set of endpoint shards of a service. Registries update
@@ -134,6 +142,11 @@ type EndpointShards struct {
 	// Due to the larger time, it is still possible that connection errors will occur while
 	// CDS is updated.
 	ServiceAccounts map[string]bool
+
+	// FirstSeen records when endpoint addresses were added to the service, for the warmup of
+	// endpoints added by rolling deploys. Endpoints of shards seen for the first time, e.g. after
+	// a Pilot restart, are not recorded as they are not new.
+	FirstSeen map[string]time.Time
 }
 
 // NewDiscoveryServer creates DiscoveryServer that sources data from Pilot's internal mesh data structures
@@ -252,18 +265,24 @@ func (s *DiscoveryServer) Push(req *model.PushRequest) {
 	// PushContext is reset after a config change. Previous status is
 	// saved.
 	t0 := time.Now()
+	span := startChildSpan("init_push_context", req.Span)
 	push := model.NewPushContext()
 	err := push.InitContext(s.Env)
 	if err != nil {
 		adsLog.Errorf("XDS: Failed to update services: %v", err)
 		// We can't push if we can't read the data - stick with previous version.
 		pushContextErrors.Increment()
+		finishSpan(span, err)
+		finishSpan(req.Span, err)
 		return
 	}
 
 	if err := s.updateServiceShards(push); err != nil {
+		finishSpan(span, err)
+		finishSpan(req.Span, err)
 		return
 	}
+	finishSpan(span, nil)
 
 	s.updateMutex.Lock()
 	s.Env.PushContext = push
@@ -336,6 +355,9 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, pushFn func(re
 	pushCounter := 0
 	debouncedEvents := 0
 
+	// Spans of the pending request, started by its first event.
+	var pushSpan, debounceSpan ot.Span
+
 	// Keeps track of the push requests. If updates are debounce they will be merged.
 	var req *model.PushRequest
 
@@ -358,6 +380,10 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, pushFn func(re
 					pushCounter, debouncedEvents,
 					quietTime, eventDelay, req.Full)
 
+				debounceSpan.SetTag("events", debouncedEvents)
+				debounceSpan.Finish()
+				req.Span = pushSpan
+
 				free = false
 				go push(req)
 				req = nil
@@ -384,6 +410,8 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, pushFn func(re
 			if debouncedEvents == 0 {
 				timeChan = time.After(DebounceAfter)
 				startDebounce = lastConfigUpdateTime
+				pushSpan = ot.StartSpan("push")
+				debounceSpan = startChildSpan("debounce", pushSpan)
 			}
 			debouncedEvents++
 
@@ -434,6 +462,7 @@ func doSendPushes(stopCh <-chan struct{}, semaphore chan struct{}, queue *PushQu
 					start:              info.Start,
 					targetNamespaces:   info.TargetNamespaces,
 					configTypesUpdated: info.ConfigTypesUpdated,
+					spanContext:        spanContext(info.Span),
 				}:
 					return
 				case <-client.stream.Context().Done(): // grpc stream was closed
@@ -0,0 +1,335 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	"github.com/golang/protobuf/proto"
+
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/schemas"
+)
+
+// DryRunReport is the impact of applying the proposed configs on the proxies connected to this
+// Pilot instance.
+type DryRunReport struct {
+	// Configs are the proposed configs, as type/namespace/name.
+	Configs []string `json:"configs"`
+	// Warnings are the push errors, e.g. conflicting listeners, added by the proposed configs.
+	Warnings []string `json:"warnings,omitempty"`
+	// Proxies are the proxies whose configuration changes, and would be pushed.
+	Proxies []ProxyImpact `json:"proxies"`
+	// Skipped is the number of connected proxies beyond features.DryRunMaxProxies, whose impact
+	// was not computed.
+	Skipped int `json:"skipped,omitempty"`
+}
+
+const (
+	// dryRunMaxBodyBytes and dryRunMaxConfigs bound the proposed configs of a dry run.
+	dryRunMaxBodyBytes = 1 << 20
+	dryRunMaxConfigs   = 100
+)
+
+// dryRunSlot allows a single dry run at a time.
+var dryRunSlot = make(chan struct{}, 1)
+
+// ProxyImpact is the change of the configuration of a proxy.
+type ProxyImpact struct {
+	ProxyID   string          `json:"proxy"`
+	Clusters  ResourceChanges `json:"clusters"`
+	Listeners ResourceChanges `json:"listeners"`
+	Routes    ResourceChanges `json:"routes"`
+}
+
+// ResourceChanges lists the names of the changed xDS resources of a type.
+type ResourceChanges struct {
+	Added    []string `json:"added,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+	Modified []string `json:"modified,omitempty"`
+}
+
+func (c ResourceChanges) empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Modified) == 0
+}
+
+// overlayConfigStore is a read only view of the config store with the proposed configs applied.
+type overlayConfigStore struct {
+	model.ConfigStore
+	configs []model.Config
+}
+
+func (o *overlayConfigStore) Get(typ, name, namespace string) *model.Config {
+	for i := range o.configs {
+		if c := &o.configs[i]; c.Type == typ && c.Name == name && c.Namespace == namespace {
+			return c
+		}
+	}
+	return o.ConfigStore.Get(typ, name, namespace)
+}
+
+func (o *overlayConfigStore) List(typ, namespace string) ([]model.Config, error) {
+	configs, err := o.ConfigStore.List(typ, namespace)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]model.Config, 0, len(configs))
+	for _, c := range configs {
+		if !o.proposed(c) {
+			out = append(out, c)
+		}
+	}
+	for _, c := range o.configs {
+		if c.Type == typ && (namespace == "" || c.Namespace == namespace) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// proposed returns whether the config is replaced by a proposed config.
+func (o *overlayConfigStore) proposed(c model.Config) bool {
+	for _, p := range o.configs {
+		if p.Type == c.Type && p.Name == c.Name && p.Namespace == c.Namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// dryRunz reports the impact of applying the configs posted in YAML, without applying them.
+// The configs are validated, and the configuration of each connected proxy is generated with
+// and without them.
+func (s *DiscoveryServer) dryRunz(w http.ResponseWriter, req *http.Request) {
+	if !features.EnableDryRun {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("The dry run is disabled, set PILOT_ENABLE_DRY_RUN to enable it"))
+		return
+	}
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = w.Write([]byte("You must post the proposed configs"))
+		return
+	}
+	select {
+	case dryRunSlot <- struct{}{}:
+		defer func() { <-dryRunSlot }()
+	default:
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte("Another dry run is in progress"))
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, dryRunMaxBodyBytes))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, "unable to read the proposed configs: %v", err)
+		return
+	}
+	configs, _, err := crd.ParseInputs(string(body))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, "invalid configs: %v", err)
+		return
+	}
+	if len(configs) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("no Istio configs found"))
+		return
+	}
+	if err := validateDryRunConfigs(configs); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, "%v", err)
+		return
+	}
+
+	report, err := s.dryRun(configs)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to compute the impact of the configs: %v", err)
+		return
+	}
+	out, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to marshal the dry run report: %v", err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
+
+// validateDryRunConfigs rejects the configs whose impact the dry run cannot compute. The services of
+// the ServiceEntries come from the external service registry, which is built from the config store
+// rather than read through it, so that the overlay of the proposed configs would not change them.
+func validateDryRunConfigs(configs []model.Config) error {
+	if len(configs) > dryRunMaxConfigs {
+		return fmt.Errorf("the dry run supports at most %d configs, got %d", dryRunMaxConfigs, len(configs))
+	}
+	for _, c := range configs {
+		if c.Type == schemas.ServiceEntry.Type {
+			return fmt.Errorf("the dry run does not support %s configs: %s/%s", c.Type, c.Namespace, c.Name)
+		}
+	}
+	return nil
+}
+
+// dryRun computes the impact of the configs on the proxies connected to this Pilot instance.
+func (s *DiscoveryServer) dryRun(configs []model.Config) (*DryRunReport, error) {
+	if err := validateDryRunConfigs(configs); err != nil {
+		return nil, err
+	}
+	report := &DryRunReport{Proxies: []ProxyImpact{}}
+	for i := range configs {
+		if configs[i].Namespace == "" {
+			configs[i].Namespace = "default"
+		}
+		report.Configs = append(report.Configs, configs[i].Type+"/"+configs[i].Namespace+"/"+configs[i].Name)
+	}
+
+	// The current configuration is generated from a private push context, so that the push errors
+	// are compared on the same proxies and the global push context is left untouched.
+	current := model.NewPushContext()
+	if err := current.InitContext(s.Env); err != nil {
+		return nil, err
+	}
+	env := *s.Env
+	env.IstioConfigStore = model.MakeIstioStore(&overlayConfigStore{ConfigStore: s.Env.IstioConfigStore, configs: configs})
+	proposed := model.NewPushContext()
+	if err := proposed.InitContext(&env); err != nil {
+		return nil, err
+	}
+
+	type conn struct {
+		node   model.Proxy
+		routes []string
+	}
+	var conns []conn
+	adsClientsMutex.RLock()
+	for _, con := range adsClients {
+		con.mu.RLock()
+		if con.modelNode != nil {
+			conns = append(conns, conn{node: *con.modelNode, routes: append([]string(nil), con.Routes...)})
+		}
+		con.mu.RUnlock()
+	}
+	adsClientsMutex.RUnlock()
+
+	// The proxies are sorted, so that the same proxies are skipped by successive dry runs.
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].node.ID < conns[j].node.ID
+	})
+	if len(conns) > features.DryRunMaxProxies {
+		report.Skipped = len(conns) - features.DryRunMaxProxies
+		conns = conns[:features.DryRunMaxProxies]
+	}
+
+	for _, c := range conns {
+		before, after := c.node, c.node
+		before.SetSidecarScope(current)
+		after.SetSidecarScope(proposed)
+		before.SetGatewaysForProxy(current)
+		after.SetGatewaysForProxy(proposed)
+		impact := ProxyImpact{
+			ProxyID: c.node.ID,
+			Clusters: diffResources(
+				clusterResources(s.ConfigGenerator.BuildClusters(s.Env, &before, current)),
+				clusterResources(s.ConfigGenerator.BuildClusters(&env, &after, proposed))),
+			Listeners: diffResources(
+				listenerResources(s.ConfigGenerator.BuildListeners(s.Env, &before, current)),
+				listenerResources(s.ConfigGenerator.BuildListeners(&env, &after, proposed))),
+			Routes: diffResources(
+				routeResources(s.ConfigGenerator.BuildHTTPRoutes(s.Env, &before, current, c.routes)),
+				routeResources(s.ConfigGenerator.BuildHTTPRoutes(&env, &after, proposed, c.routes))),
+		}
+		if !impact.Clusters.empty() || !impact.Listeners.empty() || !impact.Routes.empty() {
+			report.Proxies = append(report.Proxies, impact)
+		}
+	}
+	sort.Slice(report.Proxies, func(i, j int) bool {
+		return report.Proxies[i].ProxyID < report.Proxies[j].ProxyID
+	})
+
+	report.Warnings = pushStatusWarnings(current, proposed)
+	return report, nil
+}
+
+// pushStatusWarnings returns the push errors of the proposed push context which are not in the
+// current one.
+func pushStatusWarnings(current, proposed *model.PushContext) []string {
+	var warnings []string
+	for metric, status := range proposed.ProxyStatus {
+		for key, s := range status {
+			if _, f := current.ProxyStatus[metric][key]; f {
+				continue
+			}
+			warnings = append(warnings, fmt.Sprintf("%s: %s %s", metric, key, s.Message))
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+func clusterResources(clusters []*xdsapi.Cluster) map[string]proto.Message {
+	out := make(map[string]proto.Message, len(clusters))
+	for _, c := range clusters {
+		out[c.Name] = c
+	}
+	return out
+}
+
+func listenerResources(listeners []*xdsapi.Listener) map[string]proto.Message {
+	out := make(map[string]proto.Message, len(listeners))
+	for _, l := range listeners {
+		out[l.Name] = l
+	}
+	return out
+}
+
+func routeResources(routes []*xdsapi.RouteConfiguration) map[string]proto.Message {
+	out := make(map[string]proto.Message, len(routes))
+	for _, r := range routes {
+		out[r.Name] = r
+	}
+	return out
+}
+
+// diffResources compares xDS resources, keyed by name.
+func diffResources(before, after map[string]proto.Message) ResourceChanges {
+	var changes ResourceChanges
+	for name, b := range before {
+		a, f := after[name]
+		if !f {
+			changes.Removed = append(changes.Removed, name)
+		} else if !proto.Equal(a, b) {
+			changes.Modified = append(changes.Modified, name)
+		}
+	}
+	for name := range after {
+		if _, f := before[name]; !f {
+			changes.Added = append(changes.Added, name)
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Modified)
+	return changes
+}
@@ -0,0 +1,226 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	"github.com/gogo/protobuf/types"
+
+	networking "istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/core"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/schemas"
+)
+
+func TestOverlayConfigStore(t *testing.T) {
+	virtualService := func(name string, hosts ...string) model.Config {
+		return model.Config{
+			ConfigMeta: model.ConfigMeta{
+				Type:      schemas.VirtualService.Type,
+				Group:     schemas.VirtualService.Group,
+				Version:   schemas.VirtualService.Version,
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: &networking.VirtualService{
+				Hosts: hosts,
+				Tcp: []*networking.TCPRoute{{
+					Route: []*networking.RouteDestination{{Destination: &networking.Destination{Host: hosts[0]}}},
+				}},
+			},
+		}
+	}
+	store := memory.Make(schemas.Istio)
+	for _, c := range []model.Config{virtualService("a", "a.com"), virtualService("b", "b.com")} {
+		if _, err := store.Create(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	overlay := &overlayConfigStore{
+		ConfigStore: store,
+		configs:     []model.Config{virtualService("b", "new.b.com"), virtualService("c", "c.com")},
+	}
+	configs, err := overlay.List(schemas.VirtualService.Type, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string][]string{}
+	for _, c := range configs {
+		got[c.Name] = c.Spec.(*networking.VirtualService).Hosts
+	}
+	want := map[string][]string{"a": {"a.com"}, "b": {"new.b.com"}, "c": {"c.com"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got virtual services %v, want %v", got, want)
+	}
+	if c := overlay.Get(schemas.VirtualService.Type, "b", "default"); c.Spec.(*networking.VirtualService).Hosts[0] != "new.b.com" {
+		t.Errorf("expected the proposed virtual service, got %v", c)
+	}
+	if c := overlay.Get(schemas.VirtualService.Type, "a", "default"); c == nil {
+		t.Error("expected the current virtual service")
+	}
+	if configs, _ := overlay.List(schemas.DestinationRule.Type, ""); len(configs) != 0 {
+		t.Errorf("expected no destination rules, got %v", configs)
+	}
+}
+
+func TestDiffResources(t *testing.T) {
+	before := clusterResources([]*xdsapi.Cluster{
+		{Name: "kept"},
+		{Name: "modified", AltStatName: "before"},
+		{Name: "removed"},
+	})
+	after := clusterResources([]*xdsapi.Cluster{
+		{Name: "kept"},
+		{Name: "modified", AltStatName: "after"},
+		{Name: "added"},
+	})
+	got := diffResources(before, after)
+	want := ResourceChanges{Added: []string{"added"}, Removed: []string{"removed"}, Modified: []string{"modified"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got changes %+v, want %+v", got, want)
+	}
+	if changes := diffResources(before, before); !changes.empty() {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	const reviews = "reviews.default.svc.cluster.local"
+	sd := NewMemServiceDiscovery(map[host.Name]*model.Service{}, 0)
+	sd.AddService(reviews, &model.Service{
+		Hostname:   reviews,
+		Address:    "10.1.0.1",
+		Ports:      model.PortList{{Name: "http", Port: 9080, Protocol: protocol.HTTP}},
+		Attributes: model.ServiceAttributes{Name: "reviews", Namespace: "default"},
+	})
+	m := mesh.DefaultMeshConfig()
+	s := &DiscoveryServer{
+		Env: &model.Environment{
+			ServiceDiscovery: sd,
+			IstioConfigStore: model.MakeIstioStore(memory.Make(schemas.Istio)),
+			Mesh:             &m,
+			PushContext:      model.NewPushContext(),
+		},
+		ConfigGenerator: core.NewConfigGenerator(nil),
+	}
+
+	adsClientsMutex.Lock()
+	adsClients["dryrun-test"] = &XdsConnection{
+		ConID: "dryrun-test",
+		modelNode: &model.Proxy{
+			Type:            model.SidecarProxy,
+			ID:              "productpage.default",
+			IPAddresses:     []string{"10.2.0.1"},
+			DNSDomain:       "default.svc.cluster.local",
+			ConfigNamespace: "default",
+			Metadata:        map[string]string{},
+			IstioVersion:    &model.IstioVersion{Major: 1, Minor: 4},
+		},
+		Routes: []string{"9080"},
+	}
+	adsClientsMutex.Unlock()
+	defer func() {
+		adsClientsMutex.Lock()
+		delete(adsClients, "dryrun-test")
+		adsClientsMutex.Unlock()
+	}()
+
+	virtualService := model.Config{
+		ConfigMeta: model.ConfigMeta{
+			Type:    schemas.VirtualService.Type,
+			Group:   schemas.VirtualService.Group,
+			Version: schemas.VirtualService.Version,
+			Name:    "reviews",
+		},
+		Spec: &networking.VirtualService{
+			Hosts: []string{reviews},
+			Http: []*networking.HTTPRoute{{
+				Route:   []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: reviews}}},
+				Timeout: types.DurationProto(5 * time.Second),
+			}},
+		},
+	}
+	report, err := s.dryRun([]model.Config{virtualService})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"virtual-service/default/reviews"}; !reflect.DeepEqual(report.Configs, want) {
+		t.Errorf("got configs %v, want %v", report.Configs, want)
+	}
+	if len(report.Proxies) != 1 {
+		t.Fatalf("expected the impact on the connected proxy, got %+v", report.Proxies)
+	}
+	impact := report.Proxies[0]
+	if impact.ProxyID != "productpage.default" {
+		t.Errorf("got proxy %q, want productpage.default", impact.ProxyID)
+	}
+	if want := (ResourceChanges{Modified: []string{"9080"}}); !reflect.DeepEqual(impact.Routes, want) {
+		t.Errorf("got route changes %+v, want %+v", impact.Routes, want)
+	}
+
+	serviceEntry := model.Config{
+		ConfigMeta: model.ConfigMeta{
+			Type:      schemas.ServiceEntry.Type,
+			Group:     schemas.ServiceEntry.Group,
+			Version:   schemas.ServiceEntry.Version,
+			Name:      "external",
+			Namespace: "default",
+		},
+		Spec: &networking.ServiceEntry{
+			Hosts:      []string{"example.com"},
+			Ports:      []*networking.Port{{Number: 443, Name: "tls", Protocol: "TLS"}},
+			Resolution: networking.ServiceEntry_DNS,
+		},
+	}
+	if _, err := s.dryRun([]model.Config{virtualService, serviceEntry}); err == nil {
+		t.Error("expected the service entry to be rejected")
+	}
+
+	defer func(v int) { features.DryRunMaxProxies = v }(features.DryRunMaxProxies)
+	features.DryRunMaxProxies = 0
+	report, err = s.dryRun([]model.Config{virtualService})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Proxies) != 0 || report.Skipped != 1 {
+		t.Errorf("expected the connected proxy to be skipped, got %+v", report)
+	}
+}
+
+func TestDryRunzDisabled(t *testing.T) {
+	defer func(v bool) { features.EnableDryRun = v }(features.EnableDryRun)
+	features.EnableDryRun = false
+
+	s := &DiscoveryServer{}
+	w := httptest.NewRecorder()
+	s.dryRunz(w, httptest.NewRequest(http.MethodPost, "/debug/dryrunz", strings.NewReader("")))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -15,7 +15,9 @@
 package v2
 
 import (
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -28,6 +30,7 @@ import (
 
 	networkingapi "istio.io/api/networking/v1alpha3"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	networking "istio.io/istio/pilot/pkg/networking/core/v1alpha3"
 	"istio.io/istio/pilot/pkg/networking/core/v1alpha3/loadbalancer"
@@ -96,7 +99,8 @@ func loadAssignment(c *EdsCluster) *xdsapi.ClusterLoadAssignment {
 }
 
 // buildEnvoyLbEndpoint packs the endpoint based on istio info.
-func buildEnvoyLbEndpoint(uid string, family model.AddressFamily, address string, port uint32, network string, weight uint32) *endpoint.LbEndpoint {
+func buildEnvoyLbEndpoint(uid string, family model.AddressFamily, address string, port uint32, network, cluster string,
+	weight uint32, epLabels labels.Instance) *endpoint.LbEndpoint {
 	var addr core.Address
 	switch family {
 	case model.AddressFamilyTCP:
@@ -131,12 +135,12 @@ func buildEnvoyLbEndpoint(uid string, family model.AddressFamily, address string
 
 	// Istio telemetry depends on the metadata value being set for endpoints in the mesh.
 	// Do not remove: mixerfilter depends on this logic.
-	ep.Metadata = endpointMetadata(uid, network)
+	ep.Metadata = endpointMetadata(uid, network, cluster, epLabels)
 
 	return ep
 }
 
-func networkEndpointToEnvoyEndpoint(e *model.NetworkEndpoint) (*endpoint.LbEndpoint, error) {
+func networkEndpointToEnvoyEndpoint(e *model.NetworkEndpoint, epLabels labels.Instance) (*endpoint.LbEndpoint, error) {
 	err := model.ValidateNetworkEndpointAddress(e)
 	if err != nil {
 		return nil, err
@@ -161,14 +165,20 @@ func networkEndpointToEnvoyEndpoint(e *model.NetworkEndpoint) (*endpoint.LbEndpo
 
 	// Istio telemetry depends on the metadata value being set for endpoints in the mesh.
 	// Do not remove: mixerfilter depends on this logic.
-	ep.Metadata = endpointMetadata(e.UID, e.Network)
+	ep.Metadata = endpointMetadata(e.UID, e.Network, "", epLabels)
 
 	return ep, nil
 }
 
-// Create an Istio filter metadata object with the UID and Network fields (if exist).
-func endpointMetadata(uid string, network string) *core.Metadata {
-	if uid == "" && network == "" {
+// envoyLbMetadataKey is the filter metadata key of the endpoint settings of the Envoy load balancers.
+const envoyLbMetadataKey = "envoy.lb"
+
+// Create an Istio filter metadata object with the UID, Network and Cluster fields (if exist), and
+// the workload labels listed by PILOT_ENDPOINT_METADATA_LABELS. With stable endpoint hashing, the
+// UID is also the hash key of the endpoint.
+func endpointMetadata(uid, network, cluster string, epLabels labels.Instance) *core.Metadata {
+	metadataLabels := endpointMetadataLabels(epLabels)
+	if uid == "" && network == "" && cluster == "" && metadataLabels == nil {
 		return nil
 	}
 
@@ -188,9 +198,45 @@ func endpointMetadata(uid string, network string) *core.Metadata {
 		metadata.FilterMetadata[util.IstioMetadataKey].Fields["network"] = &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: network}}
 	}
 
+	if cluster != "" {
+		metadata.FilterMetadata[util.IstioMetadataKey].Fields["cluster"] = &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: cluster}}
+	}
+
+	if metadataLabels != nil {
+		metadata.FilterMetadata[util.IstioMetadataKey].Fields["labels"] = &structpb.Value{Kind: &structpb.Value_StructValue{StructValue: metadataLabels}}
+	}
+
+	if uid != "" && features.EnableStableEndpointHashing {
+		metadata.FilterMetadata[envoyLbMetadataKey] = &structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"hash_key": {Kind: &structpb.Value_StringValue{StringValue: uid}},
+			},
+		}
+	}
+
 	return metadata
 }
 
+// endpointMetadataLabels returns the workload labels of the endpoint listed by PILOT_ENDPOINT_METADATA_LABELS,
+// or nil if it has none of them.
+func endpointMetadataLabels(epLabels labels.Instance) *structpb.Struct {
+	if len(epLabels) == 0 || features.EndpointMetadataLabels == "" {
+		return nil
+	}
+	var out *structpb.Struct
+	for _, key := range strings.Split(features.EndpointMetadataLabels, ",") {
+		value, f := epLabels[strings.TrimSpace(key)]
+		if !f {
+			continue
+		}
+		if out == nil {
+			out = &structpb.Struct{Fields: map[string]*structpb.Value{}}
+		}
+		out.Fields[strings.TrimSpace(key)] = &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: value}}
+	}
+	return out
+}
+
 // Determine Service associated with a hostname when there is no Sidecar scope. Which namespace the service comes from
 // is undefined, as we do not have enough information to make a smart decision
 func legacyServiceForHostname(hostname host.Name, serviceByHostname map[host.Name]map[string]*model.Service) *model.Service {
@@ -457,6 +503,8 @@ func (s *DiscoveryServer) edsUpdate(clusterID, serviceName string, namespace str
 	if len(istioEndpoints) == 0 {
 		if s.EndpointShardsByService[serviceName][namespace] != nil {
 			s.EndpointShardsByService[serviceName][namespace].mutex.Lock()
+			s.EndpointShardsByService[serviceName][namespace].updateFirstSeen(serviceName,
+				s.EndpointShardsByService[serviceName][namespace].Shards[clusterID], nil, time.Now())
 			delete(s.EndpointShardsByService[serviceName][namespace].Shards, clusterID)
 			svcShards := len(s.EndpointShardsByService[serviceName][namespace].Shards)
 			s.EndpointShardsByService[serviceName][namespace].mutex.Unlock()
@@ -480,6 +528,7 @@ func (s *DiscoveryServer) edsUpdate(clusterID, serviceName string, namespace str
 		ep = &EndpointShards{
 			Shards:          map[string][]*model.IstioEndpoint{},
 			ServiceAccounts: map[string]bool{},
+			FirstSeen:       map[string]time.Time{},
 		}
 		s.EndpointShardsByService[serviceName][namespace] = ep
 		if !internal {
@@ -509,6 +558,9 @@ func (s *DiscoveryServer) edsUpdate(clusterID, serviceName string, namespace str
 		}
 	}
 	ep.mutex.Lock()
+	if old, f := ep.Shards[clusterID]; f {
+		ep.updateFirstSeen(serviceName, old, istioEndpoints, time.Now())
+	}
 	ep.Shards[clusterID] = istioEndpoints
 	ep.mutex.Unlock()
 
@@ -535,7 +587,7 @@ func (s *DiscoveryServer) edsUpdate(clusterID, serviceName string, namespace str
 func localityLbEndpointsFromInstances(instances []*model.ServiceInstance) []*endpoint.LocalityLbEndpoints {
 	localityEpMap := make(map[string]*endpoint.LocalityLbEndpoints)
 	for _, instance := range instances {
-		lbEp, err := networkEndpointToEnvoyEndpoint(&instance.Endpoint)
+		lbEp, err := networkEndpointToEnvoyEndpoint(&instance.Endpoint, instance.Labels)
 		if err != nil {
 			adsLog.Errorf("EDS: Unexpected pilot model endpoint v1 to v2 conversion: %v", err)
 			totalXDSInternalErrors.Increment()
@@ -631,6 +683,17 @@ func (s *DiscoveryServer) loadAssignmentsForClusterIsolated(proxy *model.Proxy,
 	}
 
 	locEps := buildLocalityLbEndpointsFromShards(se, svcPort, subsetLabels, clusterName, push)
+	destRule := push.DestinationRule(proxy, svc)
+	if size := endpointSubsetSize(destRule); size > 0 {
+		locEps = subsetEndpoints(locEps, size, proxy.ID)
+	}
+	if warmup := warmupDuration(destRule); warmup > 0 {
+		var warming bool
+		locEps, warming = applyEndpointWarmup(se, locEps, warmup, clusterName, time.Now())
+		if warming {
+			s.scheduleWarmupPush(string(hostname), svc.Attributes.Namespace, warmup/warmupSteps)
+		}
+	}
 
 	return &xdsapi.ClusterLoadAssignment{
 		ClusterName: clusterName,
@@ -687,7 +750,8 @@ func (s *DiscoveryServer) pushEds(push *model.PushContext, con *XdsConnection, v
 			// Failover should only be enabled when there is an outlier detection, otherwise Envoy
 			// will never detect the hosts are unhealthy and redirect traffic.
 			enableFailover := hasOutlierDetection(push, con.modelNode, clusterName)
-			loadbalancer.ApplyLocalityLBSetting(con.modelNode.Locality, l, s.Env.Mesh.LocalityLbSetting, enableFailover)
+			loadbalancer.ApplyLocalityLBSetting(con.modelNode.Locality, con.modelNode.ClusterID,
+				con.modelNode.Metadata[model.NodeMetadataNetwork], l, s.Env.Mesh.LocalityLbSetting, enableFailover)
 		}
 
 		endpoints += len(l.Endpoints)
@@ -824,13 +888,38 @@ func endpointDiscoveryResponse(loadAssignments []*xdsapi.ClusterLoadAssignment,
 		Nonce:       nonce(),
 	}
 	for _, loadAssignment := range loadAssignments {
-		resource := util.MessageToAny(loadAssignment)
+		resource, err := util.MessageToAnyWithError(loadAssignment)
+		if err != nil {
+			adsLog.Errorf("EDS: failed to marshal endpoints of cluster %s: %v", loadAssignment.ClusterName, err)
+			totalXDSInternalErrors.Increment()
+			continue
+		}
 		out.Resources = append(out.Resources, resource)
 	}
 
 	return out
 }
 
+// shardEndpoint is an endpoint of a shard, with the ID of the cluster of the shard.
+type shardEndpoint struct {
+	clusterID string
+	ep        *model.IstioEndpoint
+}
+
+// less orders the endpoints by workload UID, e.g. pod name, then by address and port, then by cluster.
+func (e shardEndpoint) less(other shardEndpoint) bool {
+	if e.ep.UID != other.ep.UID {
+		return e.ep.UID < other.ep.UID
+	}
+	if e.ep.Address != other.ep.Address {
+		return e.ep.Address < other.ep.Address
+	}
+	if e.ep.EndpointPort != other.ep.EndpointPort {
+		return e.ep.EndpointPort < other.ep.EndpointPort
+	}
+	return e.clusterID < other.clusterID
+}
+
 // build LocalityLbEndpoints for a cluster from existing EndpointShards.
 func buildLocalityLbEndpointsFromShards(
 	shards *EndpointShards,
@@ -842,9 +931,11 @@ func buildLocalityLbEndpointsFromShards(
 
 	shards.mutex.Lock()
 	// The shards are updated independently, now need to filter and merge
-	// for this cluster
-	for _, endpoints := range shards.Shards {
-		for _, ep := range endpoints {
+	// for this cluster. The endpoints are sorted, so that repeated pushes
+	// send them in the same order.
+	endpoints := make([]shardEndpoint, 0)
+	for clusterID, clusterEndpoints := range shards.Shards {
+		for _, ep := range clusterEndpoints {
 			if svcPort.Name != ep.ServicePortName {
 				continue
 			}
@@ -852,25 +943,36 @@ func buildLocalityLbEndpointsFromShards(
 			if !epLabels.HasSubsetOf(ep.Labels) {
 				continue
 			}
-
-			locLbEps, found := localityEpMap[ep.Locality]
-			if !found {
-				locLbEps = &endpoint.LocalityLbEndpoints{
-					Locality: util.ConvertLocality(ep.Locality),
-				}
-				localityEpMap[ep.Locality] = locLbEps
-			}
-			if ep.EnvoyEndpoint == nil {
-				ep.EnvoyEndpoint = buildEnvoyLbEndpoint(ep.UID, ep.Family, ep.Address, ep.EndpointPort, ep.Network, ep.LbWeight)
+			endpoints = append(endpoints, shardEndpoint{clusterID: clusterID, ep: ep})
+		}
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].less(endpoints[j])
+	})
+	for _, e := range endpoints {
+		ep := e.ep
+		locLbEps, found := localityEpMap[ep.Locality]
+		if !found {
+			locLbEps = &endpoint.LocalityLbEndpoints{
+				Locality: util.ConvertLocality(ep.Locality),
 			}
-			locLbEps.LbEndpoints = append(locLbEps.LbEndpoints, ep.EnvoyEndpoint)
-
+			localityEpMap[ep.Locality] = locLbEps
+		}
+		if ep.EnvoyEndpoint == nil {
+			ep.EnvoyEndpoint = buildEnvoyLbEndpoint(ep.UID, ep.Family, ep.Address, ep.EndpointPort, ep.Network, e.clusterID, ep.LbWeight, ep.Labels)
 		}
+		locLbEps.LbEndpoints = append(locLbEps.LbEndpoints, ep.EnvoyEndpoint)
 	}
 	shards.mutex.Unlock()
 
+	localities := make([]string, 0, len(localityEpMap))
+	for locality := range localityEpMap {
+		localities = append(localities, locality)
+	}
+	sort.Strings(localities)
 	locEps := make([]*endpoint.LocalityLbEndpoints, 0, len(localityEpMap))
-	for _, locLbEps := range localityEpMap {
+	for _, locality := range localities {
+		locLbEps := localityEpMap[locality]
 		var weight uint32
 		for _, ep := range locLbEps.LbEndpoints {
 			weight += ep.LoadBalancingWeight.GetValue()
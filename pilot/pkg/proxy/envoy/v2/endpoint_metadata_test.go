@@ -0,0 +1,71 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"reflect"
+	"testing"
+
+	structpb "github.com/golang/protobuf/ptypes/struct"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pkg/config/labels"
+)
+
+func TestEndpointMetadataLabels(t *testing.T) {
+	defer func(keys string) { features.EndpointMetadataLabels = keys }(features.EndpointMetadataLabels)
+	features.EndpointMetadataLabels = "app, version,tier"
+
+	epLabels := labels.Instance{"app": "reviews", "version": "v2", "pod-template-hash": "abc"}
+	ep := buildEnvoyLbEndpoint("kubernetes://reviews-v2", model.AddressFamilyTCP, "10.0.0.1", 80, "", "", 1, epLabels)
+	got := ep.Metadata.FilterMetadata[util.IstioMetadataKey].Fields["labels"].GetStructValue()
+	want := &structpb.Struct{Fields: map[string]*structpb.Value{
+		"app":     {Kind: &structpb.Value_StringValue{StringValue: "reviews"}},
+		"version": {Kind: &structpb.Value_StringValue{StringValue: "v2"}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got labels metadata %v, want %v", got, want)
+	}
+
+	// Endpoints without any listed label get no labels metadata.
+	ep = buildEnvoyLbEndpoint("", model.AddressFamilyTCP, "10.0.0.2", 80, "", "", 1, labels.Instance{"foo": "bar"})
+	if ep.Metadata != nil {
+		t.Errorf("got metadata %v, want none", ep.Metadata)
+	}
+
+	features.EndpointMetadataLabels = ""
+	ep = buildEnvoyLbEndpoint("", model.AddressFamilyTCP, "10.0.0.1", 80, "", "", 1, epLabels)
+	if ep.Metadata != nil {
+		t.Errorf("got metadata %v with no listed labels, want none", ep.Metadata)
+	}
+}
+
+func TestStableEndpointHashing(t *testing.T) {
+	defer func(enabled bool) { features.EnableStableEndpointHashing = enabled }(features.EnableStableEndpointHashing)
+
+	features.EnableStableEndpointHashing = false
+	ep := buildEnvoyLbEndpoint("kubernetes://reviews-v2", model.AddressFamilyTCP, "10.0.0.1", 80, "", "", 1, nil)
+	if _, f := ep.Metadata.FilterMetadata[envoyLbMetadataKey]; f {
+		t.Errorf("got %s metadata with stable hashing disabled", envoyLbMetadataKey)
+	}
+
+	features.EnableStableEndpointHashing = true
+	ep = buildEnvoyLbEndpoint("kubernetes://reviews-v2", model.AddressFamilyTCP, "10.0.0.1", 80, "", "", 1, nil)
+	if got := ep.Metadata.FilterMetadata[envoyLbMetadataKey].GetFields()["hash_key"].GetStringValue(); got != "kubernetes://reviews-v2" {
+		t.Errorf("got hash key %q, want the UID", got)
+	}
+}
@@ -116,6 +116,9 @@ func EndpointsByNetworkFilter(endpoints []*endpoint.LocalityLbEndpoints, conn *X
 									Address: epAddr,
 								},
 							},
+							// The gateway stands for endpoints of the network, and of its registry if any,
+							// so that locality failover ranks it after the local endpoints.
+							Metadata: endpointMetadata("", network, registryName, nil),
 							LoadBalancingWeight: &wrappers.UInt32Value{
 								Value: w,
 							},
@@ -151,13 +154,7 @@ func EndpointsByNetworkFilter(endpoints []*endpoint.LocalityLbEndpoints, conn *X
 // Checks whether there is an istio metadata string value for the provided key
 // within the endpoint metadata. If exists, it will return the value.
 func istioMetadata(ep *endpoint.LbEndpoint, key string) string {
-	if ep.Metadata != nil &&
-		ep.Metadata.FilterMetadata["istio"] != nil &&
-		ep.Metadata.FilterMetadata["istio"].Fields != nil &&
-		ep.Metadata.FilterMetadata["istio"].Fields[key] != nil {
-		return ep.Metadata.FilterMetadata["istio"].Fields[key].GetStringValue()
-	}
-	return ""
+	return util.IstioMetadata(ep.Metadata, key)
 }
 
 func createLocalityLbEndpoints(base *endpoint.LocalityLbEndpoints, lbEndpoints []*endpoint.LbEndpoint) *endpoint.LocalityLbEndpoints {
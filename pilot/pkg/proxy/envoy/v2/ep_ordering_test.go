@@ -0,0 +1,68 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func TestEndpointOrdering(t *testing.T) {
+	endpoint := func(uid, address, locality string) *model.IstioEndpoint {
+		return &model.IstioEndpoint{
+			UID:             uid,
+			Address:         address,
+			EndpointPort:    8080,
+			ServicePortName: "http",
+			Locality:        locality,
+		}
+	}
+	shards := &EndpointShards{Shards: map[string][]*model.IstioEndpoint{
+		"cluster-b": {
+			endpoint("kubernetes://reviews-c.default", "10.0.0.3", "us-east/a"),
+			endpoint("kubernetes://reviews-a.default", "10.0.0.9", "us-west/a"),
+		},
+		"cluster-a": {
+			endpoint("kubernetes://reviews-b.default", "10.0.0.1", "us-east/a"),
+			endpoint("kubernetes://reviews-a.default", "10.0.0.2", "us-east/a"),
+		},
+	}}
+
+	want := [][]string{
+		{"10.0.0.2", "10.0.0.1", "10.0.0.3"},
+		{"10.0.0.9"},
+	}
+	// The order does not depend on the iteration order of the shards.
+	for i := 0; i < 10; i++ {
+		locEps := buildLocalityLbEndpointsFromShards(shards, &model.Port{Name: "http"}, nil,
+			"outbound|8080||reviews.default.svc.cluster.local", model.NewPushContext())
+		var got [][]string
+		for _, locEp := range locEps {
+			var addresses []string
+			for _, ep := range locEp.LbEndpoints {
+				addresses = append(addresses, ep.GetEndpoint().Address.GetSocketAddress().Address)
+			}
+			got = append(got, addresses)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got endpoints %v, want %v", got, want)
+		}
+		if locEps[0].Locality.Region != "us-east" || locEps[1].Locality.Region != "us-west" {
+			t.Fatalf("got localities %v, %v, want us-east then us-west", locEps[0].Locality, locEps[1].Locality)
+		}
+	}
+}
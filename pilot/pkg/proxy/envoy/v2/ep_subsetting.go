@@ -0,0 +1,116 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+)
+
+// endpointSubsetSize returns the endpoint subset size set on the destination rule, or 0 if the
+// clients get all the endpoints.
+func endpointSubsetSize(cfg *model.Config) int {
+	if cfg == nil {
+		return 0
+	}
+	value, f := cfg.Annotations[model.EndpointSubsetSizeAnnotation]
+	if !f {
+		return 0
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil || size <= 0 {
+		adsLog.Warnf("Invalid %s annotation %q on destination rule %s/%s", model.EndpointSubsetSizeAnnotation,
+			value, cfg.Namespace, cfg.Name)
+		return 0
+	}
+	return size
+}
+
+// subsetEndpoints keeps a subset of size endpoints for the client, spread across the localities in
+// proportion to their endpoints. Each locality keeps at least one endpoint, so the subset may be
+// larger when there are more localities than size. The endpoints are picked by rendezvous hashing of
+// the client and the endpoint addresses: a client always gets the same subset, and most of it is
+// kept when endpoints are added or removed.
+func subsetEndpoints(locEps []*endpoint.LocalityLbEndpoints, size int, client string) []*endpoint.LocalityLbEndpoints {
+	total := 0
+	for _, locLbEps := range locEps {
+		total += len(locLbEps.LbEndpoints)
+	}
+	if size <= 0 || total <= size {
+		return locEps
+	}
+
+	// The localities are built from a map, sort them so the rounding is the same on every push.
+	sort.SliceStable(locEps, func(i, j int) bool {
+		return util.LocalityToString(locEps[i].Locality) < util.LocalityToString(locEps[j].Locality)
+	})
+	quotas := make([]int, len(locEps))
+	assigned := 0
+	for i, locLbEps := range locEps {
+		quotas[i] = size * len(locLbEps.LbEndpoints) / total
+		if quotas[i] == 0 && len(locLbEps.LbEndpoints) > 0 {
+			quotas[i] = 1
+		}
+		assigned += quotas[i]
+	}
+	// Hand out the endpoints left over by the rounding down.
+	for assigned < size {
+		for i, locLbEps := range locEps {
+			if assigned < size && quotas[i] < len(locLbEps.LbEndpoints) {
+				quotas[i]++
+				assigned++
+			}
+		}
+	}
+
+	for i, locLbEps := range locEps {
+		scores := make(map[*endpoint.LbEndpoint]uint64, len(locLbEps.LbEndpoints))
+		for _, lbEp := range locLbEps.LbEndpoints {
+			scores[lbEp] = rendezvousScore(client, lbEp)
+		}
+		lbEps := append([]*endpoint.LbEndpoint(nil), locLbEps.LbEndpoints...)
+		sort.Slice(lbEps, func(i, j int) bool {
+			return scores[lbEps[i]] > scores[lbEps[j]]
+		})
+		lbEps = lbEps[:quotas[i]]
+
+		var weight uint32
+		for _, lbEp := range lbEps {
+			weight += lbEp.GetLoadBalancingWeight().GetValue()
+		}
+		locLbEps.LbEndpoints = lbEps
+		locLbEps.LoadBalancingWeight = &wrappers.UInt32Value{Value: weight}
+	}
+	return LoadBalancingWeightNormalize(locEps)
+}
+
+// rendezvousScore returns the score of the endpoint for the client. Each client keeps the endpoints
+// with the highest scores.
+func rendezvousScore(client string, lbEp *endpoint.LbEndpoint) uint64 {
+	address := lbEp.GetEndpoint().GetAddress().GetSocketAddress()
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(client))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(address.GetAddress()))
+	_, _ = h.Write([]byte(strconv.FormatUint(uint64(address.GetPortValue()), 10)))
+	return h.Sum64()
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+)
+
+func TestSubsetEndpoints(t *testing.T) {
+	// buildLocEps builds the endpoints of zones a and b, skipping the given address.
+	buildLocEps := func(skip string) []*endpoint.LocalityLbEndpoints {
+		zones := map[string]int{"region/a": 6, "region/b": 2}
+		var locEps []*endpoint.LocalityLbEndpoints
+		for locality, n := range zones {
+			locLbEps := &endpoint.LocalityLbEndpoints{Locality: util.ConvertLocality(locality)}
+			for i := 0; i < n; i++ {
+				address := fmt.Sprintf("10.0.%s.%d", locality[len(locality)-1:], i)
+				if address == skip {
+					continue
+				}
+				locLbEps.LbEndpoints = append(locLbEps.LbEndpoints,
+					buildEnvoyLbEndpoint("", model.AddressFamilyTCP, address, 80, "", "", 1, nil))
+			}
+			locEps = append(locEps, locLbEps)
+		}
+		return locEps
+	}
+	addresses := func(locEps []*endpoint.LocalityLbEndpoints) map[string][]string {
+		out := map[string][]string{}
+		for _, locLbEps := range locEps {
+			zone := locLbEps.Locality.Zone
+			for _, lbEp := range locLbEps.LbEndpoints {
+				out[zone] = append(out[zone], lbEp.GetEndpoint().GetAddress().GetSocketAddress().GetAddress())
+			}
+			sort.Strings(out[zone])
+		}
+		return out
+	}
+
+	got := addresses(subsetEndpoints(buildLocEps(""), 4, "sidecar~10.1.0.1~client.ns~ns.svc.cluster.local"))
+	if len(got["a"]) != 3 || len(got["b"]) != 1 {
+		t.Fatalf("expected 3 endpoints of zone a and 1 of zone b, got %v", got)
+	}
+	again := addresses(subsetEndpoints(buildLocEps(""), 4, "sidecar~10.1.0.1~client.ns~ns.svc.cluster.local"))
+	if fmt.Sprint(again) != fmt.Sprint(got) {
+		t.Errorf("expected a stable subset, got %v then %v", got, again)
+	}
+
+	// Removing an endpoint out of the subset keeps the subset.
+	selected := map[string]bool{}
+	for _, address := range got["a"] {
+		selected[address] = true
+	}
+	var removed string
+	for i := 0; i < 6 && removed == ""; i++ {
+		if address := fmt.Sprintf("10.0.a.%d", i); !selected[address] {
+			removed = address
+		}
+	}
+	afterRemoval := addresses(subsetEndpoints(buildLocEps(removed), 4, "sidecar~10.1.0.1~client.ns~ns.svc.cluster.local"))
+	if fmt.Sprint(afterRemoval) != fmt.Sprint(got) {
+		t.Errorf("expected the subset to be kept when removing %s, got %v then %v", removed, got, afterRemoval)
+	}
+
+	all := subsetEndpoints(buildLocEps(""), 8, "client")
+	if got := addresses(all); len(got["a"]) != 6 || len(got["b"]) != 2 {
+		t.Errorf("expected all the endpoints when there are fewer than the subset size, got %v", got)
+	}
+}
+
+func TestEndpointSubsetSize(t *testing.T) {
+	cases := []struct {
+		annotations map[string]string
+		want        int
+	}{
+		{nil, 0},
+		{map[string]string{model.EndpointSubsetSizeAnnotation: "50"}, 50},
+		{map[string]string{model.EndpointSubsetSizeAnnotation: "0"}, 0},
+		{map[string]string{model.EndpointSubsetSizeAnnotation: "many"}, 0},
+	}
+	for _, c := range cases {
+		cfg := &model.Config{ConfigMeta: model.ConfigMeta{Annotations: c.annotations}}
+		if got := endpointSubsetSize(cfg); got != c.want {
+			t.Errorf("endpointSubsetSize(%v) = %d, want %d", c.annotations, got, c.want)
+		}
+	}
+}
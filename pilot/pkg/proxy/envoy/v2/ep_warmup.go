@@ -0,0 +1,161 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"time"
+
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// warmupSteps is the number of steps in which the weight of warming endpoints ramps up. Warming
+// endpoints start at 1/warmupSteps of their weight, and are pushed again every
+// warmupDuration/warmupSteps until they are warm.
+const warmupSteps = 10
+
+// updateFirstSeen records the endpoints added to and removed from a shard of the service by a
+// registry update, and when the added endpoints were first seen. The shards mutex must be held.
+func (e *EndpointShards) updateFirstSeen(serviceName string, old, cur []*model.IstioEndpoint, now time.Time) {
+	oldAddresses := make(map[string]struct{}, len(old))
+	for _, ep := range old {
+		oldAddresses[ep.Address] = struct{}{}
+	}
+	curAddresses := make(map[string]struct{}, len(cur))
+	for _, ep := range cur {
+		curAddresses[ep.Address] = struct{}{}
+	}
+
+	added, removed := 0, 0
+	for address := range curAddresses {
+		if _, f := oldAddresses[address]; !f {
+			added++
+			if e.FirstSeen == nil {
+				e.FirstSeen = map[string]time.Time{}
+			}
+			e.FirstSeen[address] = now
+		}
+	}
+	for address := range oldAddresses {
+		if _, f := curAddresses[address]; !f {
+			removed++
+			delete(e.FirstSeen, address)
+		}
+	}
+	if added == 0 && removed == 0 {
+		return
+	}
+
+	adsLog.Debugf("Endpoints of %s changed: %d added, %d removed", serviceName, added, removed)
+	edsEndpointChanges.With(serviceTag.Value(serviceName), typeTag.Value("added")).Record(float64(added))
+	edsEndpointChanges.With(serviceTag.Value(serviceName), typeTag.Value("removed")).Record(float64(removed))
+}
+
+// warmupDuration returns the warmup duration set on the destination rule, or 0 if there is none.
+func warmupDuration(cfg *model.Config) time.Duration {
+	if cfg == nil {
+		return 0
+	}
+	value, f := cfg.Annotations[model.WarmupDurationAnnotation]
+	if !f {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		adsLog.Warnf("Invalid %s annotation %q on destination rule %s/%s", model.WarmupDurationAnnotation,
+			value, cfg.Namespace, cfg.Name)
+		return 0
+	}
+	return d
+}
+
+// applyEndpointWarmup ramps up the weight of the endpoints added to the service less than warmup
+// ago, in proportion to the time since they were added. It returns the endpoints with their
+// weights scaled, and whether any endpoint is still warming.
+func applyEndpointWarmup(shards *EndpointShards, locEps []*endpoint.LocalityLbEndpoints, warmup time.Duration,
+	clusterName string, now time.Time) ([]*endpoint.LocalityLbEndpoints, bool) {
+	// Computed in steps, so the weights only change when the warmup push is due.
+	shards.mutex.RLock()
+	steps := make(map[*endpoint.LbEndpoint]uint32)
+	for _, locLbEps := range locEps {
+		for _, lbEp := range locLbEps.LbEndpoints {
+			address := lbEp.GetEndpoint().GetAddress().GetSocketAddress().GetAddress()
+			seen, f := shards.FirstSeen[address]
+			if !f || now.Sub(seen) >= warmup {
+				continue
+			}
+			steps[lbEp] = 1 + uint32(now.Sub(seen)*warmupSteps/warmup)
+		}
+	}
+	shards.mutex.RUnlock()
+
+	edsWarmingEndpoints.With(clusterTag.Value(clusterName)).Record(float64(len(steps)))
+	if len(steps) == 0 {
+		return locEps, false
+	}
+
+	// The endpoints are shared with the other clusters of the service, so scale copies of them.
+	for _, locLbEps := range locEps {
+		var localityWeight uint32
+		lbEps := make([]*endpoint.LbEndpoint, 0, len(locLbEps.LbEndpoints))
+		for _, lbEp := range locLbEps.LbEndpoints {
+			weight := lbEp.GetLoadBalancingWeight().GetValue()
+			if weight == 0 {
+				weight = 1
+			}
+			step, f := steps[lbEp]
+			if !f {
+				step = warmupSteps
+			}
+			lbEp = proto.Clone(lbEp).(*endpoint.LbEndpoint)
+			lbEp.LoadBalancingWeight = &wrappers.UInt32Value{Value: weight * step}
+			localityWeight += lbEp.LoadBalancingWeight.Value
+			lbEps = append(lbEps, lbEp)
+		}
+		locLbEps.LbEndpoints = lbEps
+		locLbEps.LoadBalancingWeight = &wrappers.UInt32Value{Value: localityWeight}
+	}
+	return LoadBalancingWeightNormalize(locEps), true
+}
+
+// scheduleWarmupPush schedules an EDS push of the service, to step up the weight of its warming
+// endpoints. Pushes already scheduled for the service are not duplicated.
+func (s *DiscoveryServer) scheduleWarmupPush(hostname, namespace string, after time.Duration) {
+	key := hostname + "/" + namespace
+	s.warmupMutex.Lock()
+	defer s.warmupMutex.Unlock()
+	if s.warmupPushes[key] {
+		return
+	}
+	if s.warmupPushes == nil {
+		s.warmupPushes = map[string]bool{}
+	}
+	s.warmupPushes[key] = true
+
+	time.AfterFunc(after, func() {
+		s.warmupMutex.Lock()
+		delete(s.warmupPushes, key)
+		s.warmupMutex.Unlock()
+
+		s.ConfigUpdate(&model.PushRequest{
+			Full:             false,
+			TargetNamespaces: map[string]struct{}{namespace: {}},
+			EdsUpdates:       map[string]struct{}{hostname: {}},
+		})
+	})
+}
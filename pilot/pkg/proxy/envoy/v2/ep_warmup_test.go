@@ -0,0 +1,92 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"testing"
+	"time"
+
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func TestEndpointWarmup(t *testing.T) {
+	now := time.Now()
+	shards := &EndpointShards{}
+	old := []*model.IstioEndpoint{{Address: "10.0.0.1"}, {Address: "10.0.0.2"}}
+	cur := []*model.IstioEndpoint{{Address: "10.0.0.2"}, {Address: "10.0.0.3"}}
+	shards.updateFirstSeen("svc.default.svc.cluster.local", old, cur, now.Add(-30*time.Second))
+	if len(shards.FirstSeen) != 1 || !shards.FirstSeen["10.0.0.3"].Equal(now.Add(-30*time.Second)) {
+		t.Fatalf("expected only the added endpoint to be recorded, got %v", shards.FirstSeen)
+	}
+
+	warm := buildEnvoyLbEndpoint("", model.AddressFamilyTCP, "10.0.0.2", 80, "", "", 1, nil)
+	warming := buildEnvoyLbEndpoint("", model.AddressFamilyTCP, "10.0.0.3", 80, "", "", 1, nil)
+	locEps := func() []*endpoint.LocalityLbEndpoints {
+		return []*endpoint.LocalityLbEndpoints{{LbEndpoints: []*endpoint.LbEndpoint{warm, warming}}}
+	}
+
+	out, isWarming := applyEndpointWarmup(shards, locEps(), time.Minute, "outbound|80||svc", now)
+	if !isWarming {
+		t.Fatal("expected an endpoint to be warming")
+	}
+	if got := out[0].LbEndpoints[0].LoadBalancingWeight.GetValue(); got != warmupSteps {
+		t.Errorf("warm endpoint weight = %d, want %d", got, warmupSteps)
+	}
+	if got := out[0].LbEndpoints[1].LoadBalancingWeight.GetValue(); got != warmupSteps/2+1 {
+		t.Errorf("warming endpoint weight = %d, want %d", got, warmupSteps/2+1)
+	}
+	if out[0].LoadBalancingWeight.GetValue() != warmupSteps+warmupSteps/2+1 {
+		t.Errorf("unexpected locality weight %d", out[0].LoadBalancingWeight.GetValue())
+	}
+	if warming.LoadBalancingWeight.GetValue() != 1 {
+		t.Errorf("shared endpoint was modified: %v", warming)
+	}
+
+	out, isWarming = applyEndpointWarmup(shards, locEps(), 20*time.Second, "outbound|80||svc", now)
+	if isWarming {
+		t.Error("expected no endpoint to be warming once the warmup duration has passed")
+	}
+	if got := out[0].LbEndpoints[1]; got != warming {
+		t.Errorf("expected the endpoints to be unchanged, got %v", got)
+	}
+
+	shards.updateFirstSeen("svc.default.svc.cluster.local", cur, nil, now)
+	if len(shards.FirstSeen) != 0 {
+		t.Errorf("expected removed endpoints to be forgotten, got %v", shards.FirstSeen)
+	}
+}
+
+func TestWarmupDuration(t *testing.T) {
+	cases := []struct {
+		annotations map[string]string
+		want        time.Duration
+	}{
+		{nil, 0},
+		{map[string]string{model.WarmupDurationAnnotation: "2m"}, 2 * time.Minute},
+		{map[string]string{model.WarmupDurationAnnotation: "soon"}, 0},
+		{map[string]string{model.WarmupDurationAnnotation: "-1s"}, 0},
+	}
+	for _, c := range cases {
+		cfg := &model.Config{ConfigMeta: model.ConfigMeta{Annotations: c.annotations}}
+		if got := warmupDuration(cfg); got != c.want {
+			t.Errorf("warmupDuration(%v) = %v, want %v", c.annotations, got, c.want)
+		}
+	}
+	if got := warmupDuration(nil); got != 0 {
+		t.Errorf("warmupDuration(nil) = %v, want 0", got)
+	}
+}
@@ -76,7 +76,12 @@ func ldsDiscoveryResponse(ls []*xdsapi.Listener, version string) *xdsapi.Discove
 			totalXDSInternalErrors.Increment()
 			continue
 		}
-		lr := util.MessageToAny(ll)
+		lr, err := util.MessageToAnyWithError(ll)
+		if err != nil {
+			adsLog.Errorf("LDS: failed to marshal listener %s: %v", ll.Name, err)
+			totalXDSInternalErrors.Increment()
+			continue
+		}
 		resp.Resources = append(resp.Resources, lr)
 	}
 
@@ -25,6 +25,7 @@ var (
 	clusterTag = monitoring.MustCreateLabel("cluster")
 	nodeTag    = monitoring.MustCreateLabel("node")
 	typeTag    = monitoring.MustCreateLabel("type")
+	serviceTag = monitoring.MustCreateLabel("service")
 
 	cdsReject = monitoring.NewGauge(
 		"pilot_xds_cds_reject",
@@ -44,6 +45,18 @@ var (
 		monitoring.WithLabels(clusterTag),
 	)
 
+	edsWarmingEndpoints = monitoring.NewGauge(
+		"pilot_eds_warming_endpoints",
+		"Number of endpoints of a cluster whose load balancing weight is still ramping up.",
+		monitoring.WithLabels(clusterTag),
+	)
+
+	edsEndpointChanges = monitoring.NewSum(
+		"pilot_eds_endpoint_changes",
+		"Number of endpoints added to or removed from a service by registry updates.",
+		monitoring.WithLabels(serviceTag, typeTag),
+	)
+
 	ldsReject = monitoring.NewGauge(
 		"pilot_xds_lds_reject",
 		"Pilot rejected LDS.",
@@ -170,6 +183,8 @@ func init() {
 		ldsReject,
 		rdsReject,
 		edsInstances,
+		edsWarmingEndpoints,
+		edsEndpointChanges,
 		rdsExpiredNonce,
 		totalXDSRejects,
 		monServices,
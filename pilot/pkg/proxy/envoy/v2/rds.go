@@ -77,7 +77,12 @@ func routeDiscoveryResponse(rs []*xdsapi.RouteConfiguration, version string) *xd
 		Nonce:       nonce(),
 	}
 	for _, rc := range rs {
-		rr := util.MessageToAny(rc)
+		rr, err := util.MessageToAnyWithError(rc)
+		if err != nil {
+			adsLog.Errorf("RDS: failed to marshal route %s: %v", rc.Name, err)
+			totalXDSInternalErrors.Increment()
+			continue
+		}
 		resp.Resources = append(resp.Resources, rr)
 	}
 
@@ -0,0 +1,74 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	ot "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+// The push pipeline is traced with the global tracer, a no-op unless pilot-discovery is started
+// with tracing flags. A push is traced as:
+//
+//   push                        first config event until the proxies are queued
+//     debounce                  first config event until the debounced push starts
+//     init_push_context         building the push context
+//     update_eds_clusters       recomputing the shared cluster load assignments
+//   push_proxy                  one per proxy, following from push
+//     cds, eds, lds, rds        generating and sending each type
+
+// startChildSpan starts a span as a child of parent, or a new trace if parent is nil.
+func startChildSpan(operation string, parent ot.Span) ot.Span {
+	if parent == nil {
+		return ot.StartSpan(operation)
+	}
+	return ot.StartSpan(operation, ot.ChildOf(parent.Context()))
+}
+
+// startFollowingSpan starts a span following from parent, or a new trace if parent is nil.
+func startFollowingSpan(operation string, parent ot.SpanContext) ot.Span {
+	if parent == nil {
+		return ot.StartSpan(operation)
+	}
+	return ot.StartSpan(operation, ot.FollowsFrom(parent))
+}
+
+// spanContext returns the context of span, or nil if span is nil.
+func spanContext(span ot.Span) ot.SpanContext {
+	if span == nil {
+		return nil
+	}
+	return span.Context()
+}
+
+// finishSpan finishes span, if not nil, marking it as failed if err is not nil.
+func finishSpan(span ot.Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogKV("error", err.Error())
+	}
+	span.Finish()
+}
+
+// tracePush runs push in a span named after the pushed type, as a child of parent.
+func tracePush(parent ot.Span, typ string, push func() error) error {
+	span := startChildSpan(typ, parent)
+	err := push()
+	finishSpan(span, err)
+	return err
+}
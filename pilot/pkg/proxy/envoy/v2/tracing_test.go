@@ -0,0 +1,90 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	ot "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func TestDebounceTracing(t *testing.T) {
+	tracer := mocktracer.New()
+	ot.SetGlobalTracer(tracer)
+	defer ot.SetGlobalTracer(ot.NoopTracer{})
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	updateCh := make(chan *model.PushRequest)
+	pushed := make(chan *model.PushRequest, 1)
+	go debounce(updateCh, stopCh, func(req *model.PushRequest) {
+		pushed <- req
+	})
+
+	updateCh <- &model.PushRequest{Full: true}
+	updateCh <- &model.PushRequest{Full: true}
+
+	var req *model.PushRequest
+	select {
+	case req = <-pushed:
+	case <-time.After(DebounceMax * 2):
+		t.Fatal("timed out waiting for the push")
+	}
+	if req.Span == nil {
+		t.Fatal("expected the push request to be traced")
+	}
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 1 || spans[0].OperationName != "debounce" {
+		t.Fatalf("expected only the debounce span to be finished, got %v", spans)
+	}
+	if got := spans[0].Tag("events"); got != 2 {
+		t.Errorf("expected 2 debounced events, got %v", got)
+	}
+	if spans[0].ParentID != req.Span.(*mocktracer.MockSpan).SpanContext.SpanID {
+		t.Errorf("expected the debounce span to be a child of the push span")
+	}
+}
+
+func TestTracePush(t *testing.T) {
+	tracer := mocktracer.New()
+	ot.SetGlobalTracer(tracer)
+	defer ot.SetGlobalTracer(ot.NoopTracer{})
+
+	parent := startFollowingSpan("push_proxy", nil)
+	if err := tracePush(parent, "cds", func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracePush(parent, "lds", func() error { return errors.New("failed") }); err == nil {
+		t.Fatal("expected the push error to be returned")
+	}
+	parent.Finish()
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %v", spans)
+	}
+	if spans[0].OperationName != "cds" || spans[0].Tag("error") != nil {
+		t.Errorf("expected a successful cds span, got %v", spans[0])
+	}
+	if spans[1].OperationName != "lds" || spans[1].Tag("error") != true {
+		t.Errorf("expected a failed lds span, got %v", spans[1])
+	}
+}
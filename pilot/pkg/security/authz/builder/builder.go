@@ -19,6 +19,7 @@ import (
 	http_filter "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
 	tcp_config "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/rbac/v2"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
 	authz_model "istio.io/istio/pilot/pkg/security/authz/model"
@@ -117,6 +118,11 @@ func (b *Builder) BuildTCPFilter() *tcp_filter.Filter {
 		ShadowRules: config.ShadowRules,
 		StatPrefix:  authz_model.RBACTCPFilterStatPrefix,
 	}
+	if features.TCPAuthzAccessLogFile != "" && rbacConfig.ShadowRules == nil {
+		// Envoy only records the decisions of the shadow rules in the connection metadata. Evaluate the
+		// enforced rules in shadow mode too, so that the TCP authorization access log can report them.
+		rbacConfig.ShadowRules = rbacConfig.Rules
+	}
 
 	tcpConfig := tcp_filter.Filter{
 		Name: authz_model.RBACTCPFilterName,
@@ -21,8 +21,10 @@ import (
 	http_config "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/rbac/v2"
 	tcp_config "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/rbac/v2"
 	"github.com/envoyproxy/go-control-plane/pkg/conversion"
+	"github.com/golang/protobuf/proto"
 
 	istio_rbac "istio.io/api/rbac/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	authz_model "istio.io/istio/pilot/pkg/security/authz/model"
 	"istio.io/istio/pilot/pkg/security/authz/policy"
@@ -251,3 +253,20 @@ func TestBuilder_BuildTCPFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestBuilder_BuildTCPFilterAuthzAccessLog(t *testing.T) {
+	defer func(path string) { features.TCPAuthzAccessLogFile = path }(features.TCPAuthzAccessLogFile)
+	features.TCPAuthzAccessLogFile = "/dev/stdout"
+
+	service := newService("foo.a.svc.cluster.local", nil, t)
+	p := policy.NewAuthzPolicies([]*model.Config{policy.SimpleClusterRbacConfig()}, t)
+	got := NewBuilder(service, nil, "a", p, false).BuildTCPFilter()
+
+	rbacConfig := &tcp_config.RBAC{}
+	if err := conversion.StructToMessage(got.GetConfig(), rbacConfig); err != nil {
+		t.Fatalf("failed to convert struct to message: %s", err)
+	}
+	if rbacConfig.GetShadowRules() == nil || !proto.Equal(rbacConfig.GetShadowRules(), rbacConfig.GetRules()) {
+		t.Errorf("got shadow rules %v but want the enforced rules %v", rbacConfig.GetShadowRules(), rbacConfig.GetRules())
+	}
+}
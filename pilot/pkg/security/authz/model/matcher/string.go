@@ -18,6 +18,8 @@ import (
 	"strings"
 
 	envoy_matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
+
+	"istio.io/istio/pilot/pkg/networking/util"
 )
 
 func StringMatcher(v string) *envoy_matcher.StringMatcher {
@@ -25,7 +27,7 @@ func StringMatcher(v string) *envoy_matcher.StringMatcher {
 }
 
 func StringMatcherRegex(regex string) *envoy_matcher.StringMatcher {
-	return &envoy_matcher.StringMatcher{MatchPattern: &envoy_matcher.StringMatcher_Regex{Regex: regex}}
+	return util.StringMatcherRegex(regex)
 }
 
 func StringMatcherWithPrefix(v, prefix string) *envoy_matcher.StringMatcher {
@@ -233,6 +233,9 @@ func principalForKeyValue(key, value string, forTCPFilter bool) *envoy_rbac.Prin
 		if value == allAuthenticatedUsers {
 			value = "*"
 		}
+		// The principals are SPIFFE IDs without the scheme, e.g. cluster.local/ns/foo/sa/bar, accept the
+		// full ID too, e.g. spiffe://example.org/vm/web issued by an external SPIRE server.
+		value = strings.TrimPrefix(value, spiffe.URIPrefix)
 
 		if forTCPFilter {
 			m := matcher.StringMatcherWithPrefix(value, spiffe.URIPrefix)
@@ -265,14 +265,18 @@ func TestPrincipal_Generate(t *testing.T) {
                   - key: source.principal
                   value:
                     stringMatch:
-                      regex: .*/ns/ns-1/.*
+                      safeRegex:
+                        googleRe2: {}
+                        regex: .*/ns/ns-1/.*
               - metadata:
                   filter: istio_authn
                   path:
                   - key: source.principal
                   value:
                     stringMatch:
-                      regex: .*/ns/ns-2/.*`,
+                      safeRegex:
+                        googleRe2: {}
+                        regex: .*/ns/ns-2/.*`,
 		},
 		{
 			name: "principal with ips",
@@ -350,14 +354,18 @@ func TestPrincipal_Generate(t *testing.T) {
                   - key: source.principal
                   value:
                     stringMatch:
-                      regex: .*/ns/ns-1/.*
+                      safeRegex:
+                        googleRe2: {}
+                        regex: .*/ns/ns-1/.*
               - metadata:
                   filter: istio_authn
                   path:
                   - key: source.principal
                   value:
                     stringMatch:
-                      regex: .*/ns/ns-2/.*`,
+                      safeRegex:
+                        googleRe2: {}
+                        regex: .*/ns/ns-2/.*`,
 		},
 		{
 			name: "principal with property attrSrcNamespace for TCP filter",
@@ -376,10 +384,14 @@ func TestPrincipal_Generate(t *testing.T) {
               ids:
               - authenticated:
                   principalName:
-                    regex: .*/ns/ns-1/.*
+                    safeRegex:
+                      googleRe2: {}
+                      regex: .*/ns/ns-1/.*
               - authenticated:
                   principalName:
-                    regex: .*/ns/ns-2/.*`,
+                    safeRegex:
+                      googleRe2: {}
+                      regex: .*/ns/ns-2/.*`,
 		},
 		{
 			name: "principal with property attrSrcPrincipal",
@@ -409,7 +421,9 @@ func TestPrincipal_Generate(t *testing.T) {
                   - key: source.principal
                   value:
                     stringMatch:
-                      regex: .*
+                      safeRegex:
+                        googleRe2: {}
+                        regex: .*
               - any: true`,
 		},
 		{
@@ -433,9 +447,30 @@ func TestPrincipal_Generate(t *testing.T) {
               - any: true
               - authenticated:
                   principalName:
-                    regex: .*
+                    safeRegex:
+                      googleRe2: {}
+                      regex: .*
               - any: true`,
 		},
+		{
+			name: "principal with property attrSrcPrincipal in SPIFFE ID format",
+			principal: &Principal{
+				Properties: []KeyValues{
+					{
+						attrSrcPrincipal: []string{"spiffe://example.org/vm/web"},
+					},
+				},
+			},
+			forTCPFilter: true,
+			wantYAML: `
+        andIds:
+          ids:
+          - orIds:
+              ids:
+              - authenticated:
+                  principalName:
+                    exact: spiffe://example.org/vm/web`,
+		},
 		{
 			name: "principal with property attrRequestPrincipal",
 			principal: &Principal{
@@ -718,7 +753,9 @@ func TestPrincipal_Generate(t *testing.T) {
               - key: source.principal
               value:
                 stringMatch:
-                  regex: .*/ns/ns/.*`,
+                  safeRegex:
+                    googleRe2: {}
+                    regex: .*/ns/ns/.*`,
 		},
 		{
 			name: "principal with multiple properties",
@@ -753,14 +790,18 @@ func TestPrincipal_Generate(t *testing.T) {
                   - key: source.principal
                   value:
                     stringMatch:
-                      regex: .*/ns/ns-1/.*
+                      safeRegex:
+                        googleRe2: {}
+                        regex: .*/ns/ns-1/.*
               - metadata:
                   filter: istio_authn
                   path:
                   - key: source.principal
                   value:
                     stringMatch:
-                      regex: .*/ns/ns-2/.*
+                      safeRegex:
+                        googleRe2: {}
+                        regex: .*/ns/ns-2/.*
           - orIds:
               ids:
               - metadata:
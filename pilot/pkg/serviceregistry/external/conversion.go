@@ -65,6 +65,8 @@ func convertServices(cfg model.Config) []*model.Service {
 		}
 	}
 
+	configWriters := model.ParseConfigWriters(cfg.Annotations[constants.ConfigWritersAnnotation])
+
 	for _, hostname := range serviceEntry.Hosts {
 		if len(serviceEntry.Addresses) > 0 {
 			for _, address := range serviceEntry.Addresses {
@@ -87,6 +89,7 @@ func convertServices(cfg model.Config) []*model.Service {
 							Name:            hostname,
 							Namespace:       cfg.Namespace,
 							ExportTo:        exportTo,
+							ConfigWriters:   configWriters,
 						},
 					})
 				} else if net.ParseIP(address) != nil {
@@ -102,6 +105,7 @@ func convertServices(cfg model.Config) []*model.Service {
 							Name:            hostname,
 							Namespace:       cfg.Namespace,
 							ExportTo:        exportTo,
+							ConfigWriters:   configWriters,
 						},
 					})
 				}
@@ -119,6 +123,7 @@ func convertServices(cfg model.Config) []*model.Service {
 					Name:            hostname,
 					Namespace:       cfg.Namespace,
 					ExportTo:        exportTo,
+					ConfigWriters:   configWriters,
 				},
 			})
 		}
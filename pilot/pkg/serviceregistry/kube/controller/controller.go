@@ -43,7 +43,6 @@ import (
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/serviceregistry/kube"
 	"istio.io/istio/pkg/config/host"
-	configKube "istio.io/istio/pkg/config/kube"
 	"istio.io/istio/pkg/config/labels"
 )
 
@@ -318,7 +317,8 @@ func (c *Controller) GetService(hostname host.Name) (*model.Service, error) {
 	return c.servicesMap[hostname], nil
 }
 
-// GetPodLocality retrieves the locality for a pod.
+// GetPodLocality retrieves the locality for a pod. The istio-locality label of the pod takes
+// precedence over the locality of its node, also when the node has no locality labels.
 func (c *Controller) GetPodLocality(pod *v1.Pod) string {
 	// NodeName is set by the scheduler after the pod is created
 	// https://github.com/kubernetes/community/blob/master/contributors/devel/api-conventions.md#late-initialization
@@ -328,13 +328,36 @@ func (c *Controller) GetPodLocality(pod *v1.Pod) string {
 		return ""
 	}
 
-	region := node.(*v1.Node).Labels[NodeRegionLabel]
-	zone := node.(*v1.Node).Labels[NodeZoneLabel]
-	if region == "" && zone == "" {
+	return model.GetLocalityOrDefault(pod.Labels[model.LocalityLabel], nodeLocality(node.(*v1.Node)))
+}
+
+// nodeLocality returns the locality of a node, read from the label keys configured with
+// PILOT_LOCALITY_{REGION,ZONE,SUBZONE}_LABELS and then from the well-known region and zone labels.
+func nodeLocality(node *v1.Node) string {
+	region := firstLabelValue(node.Labels, features.LocalityRegionLabels, NodeRegionLabel)
+	zone := firstLabelValue(node.Labels, features.LocalityZoneLabels, NodeZoneLabel)
+	subzone := firstLabelValue(node.Labels, features.LocalitySubzoneLabels, "")
+	if region == "" && zone == "" && subzone == "" {
+		return ""
+	}
+	if subzone == "" {
+		return fmt.Sprintf("%v/%v", region, zone)
+	}
+	return fmt.Sprintf("%v/%v/%v", region, zone, subzone)
+}
+
+// firstLabelValue returns the value of the first of the comma separated keys, followed by
+// the fallback key, set in nodeLabels.
+func firstLabelValue(nodeLabels map[string]string, keys string, fallback string) string {
+	for _, key := range splitKeys(keys) {
+		if value := nodeLabels[key]; value != "" {
+			return value
+		}
+	}
+	if fallback == "" {
 		return ""
 	}
-	locality := fmt.Sprintf("%v/%v", region, zone)
-	return model.GetLocalityOrDefault(pod.Labels[model.LocalityLabel], locality)
+	return nodeLabels[fallback]
 }
 
 // ManagementPorts implements a service catalog operation
@@ -453,7 +476,7 @@ func (c *Controller) InstancesByPort(svc *model.Service, reqSvcPort int,
 			var podLabels labels.Instance
 			pod := c.pods.getPodByIP(ea.IP)
 			if pod != nil {
-				podLabels = configKube.ConvertLabels(pod.ObjectMeta)
+				podLabels = c.endpointLabels(pod)
 			}
 			// check that one of the input labels is a subset of the labels
 			if !labelsList.HasSubsetOf(podLabels) {
@@ -927,7 +950,7 @@ func (c *Controller) updateEDS(ep *v1.Endpoints, event model.Event) {
 					if mixerEnabled {
 						uid = fmt.Sprintf("kubernetes://%s.%s", pod.Name, pod.Namespace)
 					}
-					labels = map[string]string(configKube.ConvertLabels(pod.ObjectMeta))
+					labels = map[string]string(c.endpointLabels(pod))
 				}
 
 				// EDS and ServiceEntry use name for service port - ADS will need to
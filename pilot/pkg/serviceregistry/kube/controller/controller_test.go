@@ -34,6 +34,7 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/pkg/log"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry"
 	"istio.io/istio/pilot/pkg/serviceregistry/kube"
@@ -316,6 +317,62 @@ func makeService(n, ns string, cl kubernetes.Interface, t *testing.T) {
 	log.Infof("Created service %s", n)
 }
 
+func TestNodeLocality(t *testing.T) {
+	defer func(region, zone, subzone string) {
+		features.LocalityRegionLabels = region
+		features.LocalityZoneLabels = zone
+		features.LocalitySubzoneLabels = subzone
+	}(features.LocalityRegionLabels, features.LocalityZoneLabels, features.LocalitySubzoneLabels)
+	features.LocalityRegionLabels = "example.com/datacenter"
+	features.LocalityZoneLabels = "example.com/room, example.com/hall"
+	features.LocalitySubzoneLabels = "example.com/rack"
+
+	testCases := []struct {
+		name   string
+		labels map[string]string
+		want   string
+	}{
+		{
+			name:   "no labels",
+			labels: map[string]string{},
+			want:   "",
+		},
+		{
+			name:   "well-known labels",
+			labels: map[string]string{NodeRegionLabel: "region1", NodeZoneLabel: "zone1"},
+			want:   "region1/zone1",
+		},
+		{
+			name: "custom labels take precedence",
+			labels: map[string]string{
+				NodeRegionLabel:          "region1",
+				NodeZoneLabel:            "zone1",
+				"example.com/datacenter": "dc1",
+				"example.com/hall":       "hall1",
+			},
+			want: "dc1/hall1",
+		},
+		{
+			name: "custom labels in order",
+			labels: map[string]string{
+				"example.com/datacenter": "dc1",
+				"example.com/room":       "room1",
+				"example.com/hall":       "hall1",
+				"example.com/rack":       "rack1",
+			},
+			want: "dc1/room1/rack1",
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := nodeLocality(generateNode("node1", c.labels)); got != c.want {
+				t.Errorf("nodeLocality() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
 func TestController_GetPodLocality(t *testing.T) {
 	t.Parallel()
 	pod1 := generatePod("128.0.1.1", "pod1", "nsA", "", "node1", map[string]string{"app": "prod-app"}, map[string]string{})
@@ -389,6 +446,16 @@ func TestController_GetPodLocality(t *testing.T) {
 				podOverride: "regionOverride/zoneOverride/subzoneOverride",
 			},
 		},
+		{
+			name: "should return the pod locality if node has no locality labels",
+			pods: []*coreV1.Pod{podOverride},
+			nodes: []*coreV1.Node{
+				generateNode("node1", map[string]string{}),
+			},
+			wantAZ: map[*coreV1.Pod]string{
+				podOverride: "regionOverride/zoneOverride/subzoneOverride",
+			},
+		},
 	}
 
 	for _, c := range testCases {
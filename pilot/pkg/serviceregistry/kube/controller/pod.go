@@ -16,11 +16,13 @@ package controller
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/cache"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/kube"
 	configKube "istio.io/istio/pkg/config/kube"
@@ -140,5 +142,47 @@ func (pc *PodCache) labelsByIP(addr string) (labels.Instance, bool) {
 	if pod == nil {
 		return nil, false
 	}
-	return configKube.ConvertLabels(pod.ObjectMeta), true
+	return pc.c.endpointLabels(pod), true
+}
+
+// endpointLabels returns the labels of the endpoints of a pod, which DestinationRule subsets select
+// on: the pod labels, extended with the pod fields, pod annotations and node labels configured with
+// PILOT_ENDPOINT_POD_FIELDS, PILOT_ENDPOINT_POD_ANNOTATIONS and PILOT_ENDPOINT_NODE_LABELS. Pod
+// labels are never overridden.
+func (c *Controller) endpointLabels(pod *v1.Pod) labels.Instance {
+	out := configKube.ConvertLabels(pod.ObjectMeta)
+	add := func(key, value string) {
+		if _, f := out[key]; !f && value != "" {
+			out[key] = value
+		}
+	}
+
+	if features.EndpointPodFields {
+		add(model.PodNodeNameLabel, pod.Spec.NodeName)
+		if pod.Spec.RuntimeClassName != nil {
+			add(model.PodRuntimeClassLabel, *pod.Spec.RuntimeClassName)
+		}
+	}
+	for _, key := range splitKeys(features.EndpointPodAnnotations) {
+		add(key, pod.Annotations[key])
+	}
+	if nodeLabels := splitKeys(features.EndpointNodeLabels); len(nodeLabels) > 0 && c != nil && pod.Spec.NodeName != "" {
+		if node, exists, err := c.nodes.informer.GetStore().GetByKey(pod.Spec.NodeName); exists && err == nil {
+			for _, key := range nodeLabels {
+				add(key, node.(*v1.Node).Labels[key])
+			}
+		}
+	}
+	return out
+}
+
+// splitKeys splits a comma separated list of keys, skipping empty ones.
+func splitKeys(keys string) []string {
+	var out []string
+	for _, key := range strings.Split(keys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			out = append(out, key)
+		}
+	}
+	return out
 }
@@ -25,6 +25,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/kube"
 	"istio.io/istio/pkg/config/labels"
@@ -169,6 +170,46 @@ func testPodCache(t *testing.T, c *Controller, fx *FakeXdsUpdater) {
 	}
 }
 
+func TestEndpointLabels(t *testing.T) {
+	defer func(podFields bool, podAnnotations, nodeLabels string) {
+		features.EndpointPodFields = podFields
+		features.EndpointPodAnnotations = podAnnotations
+		features.EndpointNodeLabels = nodeLabels
+	}(features.EndpointPodFields, features.EndpointPodAnnotations, features.EndpointNodeLabels)
+
+	c, _ := newFakeController(t)
+	defer c.Stop()
+	if err := c.nodes.informer.GetStore().Add(generateNode("node1", map[string]string{
+		"accelerator": "nvidia-tesla-v100",
+		"app":         "node",
+	})); err != nil {
+		t.Fatal(err)
+	}
+	runtimeClass := "gvisor"
+	pod := generatePod("128.0.0.1", "pod1", "nsa", "", "node1",
+		map[string]string{"app": "test-app"},
+		map[string]string{"example.com/tier": "gold", "example.com/ignored": "true"})
+	pod.Spec.RuntimeClassName = &runtimeClass
+
+	if got, want := c.endpointLabels(pod), (labels.Instance{"app": "test-app"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected only the pod labels by default, got %v", got)
+	}
+
+	features.EndpointPodFields = true
+	features.EndpointPodAnnotations = "example.com/tier, example.com/missing"
+	features.EndpointNodeLabels = "accelerator,app"
+	want := labels.Instance{
+		"app":                      "test-app",
+		model.PodNodeNameLabel:     "node1",
+		model.PodRuntimeClassLabel: "gvisor",
+		"example.com/tier":         "gold",
+		"accelerator":              "nvidia-tesla-v100",
+	}
+	if got := c.endpointLabels(pod); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
 // Checks that events from the watcher create the proper internal structures
 func TestPodCacheEvents(t *testing.T) {
 	t.Parallel()
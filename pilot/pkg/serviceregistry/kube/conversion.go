@@ -43,6 +43,11 @@ const (
 	// responsible for it
 	IngressClassAnnotation = "kubernetes.io/ingress.class"
 
+	// DisableProtocolDetectionAnnotation is the annotation on services listing, by number or name, the ports
+	// whose protocol is not detected, e.g. ports of server first protocols like MySQL. "*" lists all the ports.
+	// The ports use their declared protocol, or TCP if the port name declares none.
+	DisableProtocolDetectionAnnotation = "networking.istio.io/disableProtocolDetection"
+
 	managementPortPrefix = "mgmt-"
 )
 
@@ -54,6 +59,22 @@ func convertPort(port coreV1.ServicePort) *model.Port {
 	}
 }
 
+// disableProtocolDetection makes the listed ports without declared protocol TCP ports.
+func disableProtocolDetection(ports []*model.Port, listed []string) {
+	for _, port := range ports {
+		if !port.Protocol.IsUnsupported() {
+			continue
+		}
+		for _, l := range listed {
+			l = strings.TrimSpace(l)
+			if l == "*" || l == port.Name || l == strconv.Itoa(port.Port) {
+				port.Protocol = protocol.TCP
+				break
+			}
+		}
+	}
+}
+
 func ConvertService(svc coreV1.Service, domainSuffix string, clusterID string) *model.Service {
 	addr, external := constants.UnspecifiedIP, ""
 	if svc.Spec.ClusterIP != "" && svc.Spec.ClusterIP != coreV1.ClusterIPNone {
@@ -77,6 +98,9 @@ func ConvertService(svc coreV1.Service, domainSuffix string, clusterID string) *
 	for _, port := range svc.Spec.Ports {
 		ports = append(ports, convertPort(port))
 	}
+	if value := svc.Annotations[DisableProtocolDetectionAnnotation]; value != "" {
+		disableProtocolDetection(ports, strings.Split(value, ","))
+	}
 
 	var exportTo map[visibility.Instance]bool
 	serviceaccounts := make([]string, 0)
@@ -112,6 +136,7 @@ func ConvertService(svc coreV1.Service, domainSuffix string, clusterID string) *
 			Namespace:       svc.Namespace,
 			UID:             fmt.Sprintf("istio://%s/services/%s", svc.Namespace, svc.Name),
 			ExportTo:        exportTo,
+			ConfigWriters:   model.ParseConfigWriters(svc.Annotations[constants.ConfigWritersAnnotation]),
 		},
 	}
 
@@ -249,6 +249,48 @@ func TestServiceConversionWithEmptyServiceAccountsAnnotation(t *testing.T) {
 	}
 }
 
+func TestServiceConversionDisableProtocolDetection(t *testing.T) {
+	svc := coreV1.Service{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "mysql",
+			Namespace: "default",
+			Annotations: map[string]string{
+				DisableProtocolDetectionAnnotation: "3306, admin",
+			},
+		},
+		Spec: coreV1.ServiceSpec{
+			ClusterIP: "10.0.0.1",
+			Ports: []coreV1.ServicePort{
+				{Name: "db", Port: 3306, Protocol: coreV1.ProtocolTCP},
+				{Name: "admin", Port: 8080, Protocol: coreV1.ProtocolTCP},
+				{Name: "http-api", Port: 9080, Protocol: coreV1.ProtocolTCP},
+				{Name: "other", Port: 9090, Protocol: coreV1.ProtocolTCP},
+			},
+		},
+	}
+
+	service := ConvertService(svc, domainSuffix, clusterID)
+	want := map[string]protocol.Instance{
+		"db":       protocol.TCP,
+		"admin":    protocol.TCP,
+		"http-api": protocol.HTTP,
+		"other":    protocol.Unsupported,
+	}
+	for _, port := range service.Ports {
+		if port.Protocol != want[port.Name] {
+			t.Errorf("port %s has protocol %v, want %v", port.Name, port.Protocol, want[port.Name])
+		}
+	}
+
+	svc.Annotations[DisableProtocolDetectionAnnotation] = "*"
+	service = ConvertService(svc, domainSuffix, clusterID)
+	for _, port := range service.Ports {
+		if port.Protocol.IsUnsupported() {
+			t.Errorf("port %s has no protocol with protocol detection disabled on all ports", port.Name)
+		}
+	}
+}
+
 func TestExternalServiceConversion(t *testing.T) {
 	serviceName := "service1"
 	namespace := "default"
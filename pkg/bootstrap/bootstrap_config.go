@@ -15,6 +15,7 @@
 package bootstrap
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -24,10 +25,13 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
 
+	jsonpatch "github.com/evanphx/json-patch"
+
 	apiv2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	"github.com/gogo/protobuf/types"
@@ -69,12 +73,21 @@ const (
 
 	envoyAccessLogServiceName = "envoy_accesslog_service"
 	envoyMetricsServiceName   = "envoy_metrics_service"
+
+	// ExchangeAttributesAnnotation is the pod annotation listing, comma separated, the extra node
+	// metadata keys exchanged with the peers besides the built in ones, e.g. "team,cost-center". The
+	// pod labels and annotations and the ISTIO_META_ variables are all node metadata keys. Peers
+	// receive them in the exchanged metadata, but neither the stats filter nor the RBAC filter of
+	// the proxy reads extra keys yet.
+	ExchangeAttributesAnnotation = "sidecar.istio.io/exchangeAttributes"
 )
 
 var (
-	// required stats are used by readiness checks.
-	requiredEnvoyStatsMatcherInclusionPrefixes = "cluster_manager,listener_manager,http_mixer_filter,tcp_mixer_filter,server,cluster.xds-grpc"
-	requiredEnvoyStatsMatcherInclusionSuffix   = "ssl_context_update_by_sds"
+	// required stats are used by readiness checks and to report the traffic handled by the outbound
+	// traffic policy.
+	requiredEnvoyStatsMatcherInclusionPrefixes = "cluster_manager,listener_manager,http_mixer_filter,tcp_mixer_filter,server,cluster.xds-grpc," +
+		"cluster.PassthroughCluster,cluster.BlackHoleCluster,tcp.PassthroughCluster,tcp.BlackHoleCluster"
+	requiredEnvoyStatsMatcherInclusionSuffix = "ssl_context_update_by_sds"
 
 	metadataExchangeKeys = strings.Join(
 		[]string{
@@ -297,7 +310,30 @@ func extractAttributesMetadata(envVars []string, plat platform.Environment, meta
 	if plat != nil && len(plat.Metadata()) > 0 {
 		meta[model.NodeMetadataPlatformMetadata] = plat.Metadata()
 	}
-	meta[model.NodeMetadataExchangeKeys] = metadataExchangeKeys
+	meta[model.NodeMetadataExchangeKeys] = exchangeKeys(meta)
+}
+
+// exchangeKeys returns the node metadata keys exchanged with the peers, the built in ones followed
+// by the extra ones of the ExchangeAttributesAnnotation.
+func exchangeKeys(meta map[string]interface{}) string {
+	extra, _ := meta[ExchangeAttributesAnnotation].(string)
+	if extra == "" {
+		return metadataExchangeKeys
+	}
+	keys := strings.Split(metadataExchangeKeys, ",")
+	seen := map[string]bool{}
+	for _, key := range keys {
+		seen[key] = true
+	}
+	for _, key := range strings.Split(extra, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return strings.Join(keys, ",")
 }
 
 // getNodeMetaData function uses an environment variable contract
@@ -326,6 +362,95 @@ func getNodeMetaData(envs []string, plat platform.Environment) map[string]interf
 
 var overrideVar = env.RegisterStringVar("ISTIO_BOOTSTRAP", "", "")
 
+// BootstrapPatchEnv holds a JSON merge patch (RFC 7386) applied to the generated bootstrap. It allows
+// tweaking e.g. admin, stats sinks or tracing settings without maintaining a custom template.
+const BootstrapPatchEnv = "ISTIO_BOOTSTRAP_PATCH"
+
+// The settings of the connection to pilot. Long-lived xDS connections going through some cloud load
+// balancers are silently dropped when idle, keepalive probes detect this sooner.
+const (
+	// XdsKeepaliveTimeEnv is how long the connection may stay idle before keepalive probes are sent, e.g. "60s".
+	XdsKeepaliveTimeEnv = "ISTIO_XDS_KEEPALIVE_TIME"
+	// XdsKeepaliveIntervalEnv is the time between keepalive probes, e.g. "10s".
+	XdsKeepaliveIntervalEnv = "ISTIO_XDS_KEEPALIVE_INTERVAL"
+	// XdsInitialStreamWindowSizeEnv is the initial HTTP/2 flow control window of the xDS streams, in bytes.
+	XdsInitialStreamWindowSizeEnv = "ISTIO_XDS_INITIAL_STREAM_WINDOW_SIZE"
+	// XdsInitialConnectionWindowSizeEnv is the initial HTTP/2 flow control window of the connection, in bytes.
+	XdsInitialConnectionWindowSizeEnv = "ISTIO_XDS_INITIAL_CONNECTION_WINDOW_SIZE"
+
+	defaultXdsKeepaliveTime = 300 * time.Second
+)
+
+// lookupEnv returns the value of the named variable in the given environment, if any.
+func lookupEnv(envs []string, name string) string {
+	prefix := name + "="
+	for _, e := range envs {
+		if strings.HasPrefix(e, prefix) {
+			return strings.TrimSpace(e[len(prefix):])
+		}
+	}
+	return ""
+}
+
+// bootstrapPatch returns the bootstrap patch found in the given environment, if any.
+func bootstrapPatch(envs []string) string {
+	return lookupEnv(envs, BootstrapPatchEnv)
+}
+
+// setXdsConnectionOptions sets the keepalive and HTTP/2 window options of the xds-grpc cluster from
+// the given environment.
+func setXdsConnectionOptions(opts map[string]interface{}, envs []string) error {
+	keepaliveTime := defaultXdsKeepaliveTime
+	if v := lookupEnv(envs, XdsKeepaliveTimeEnv); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Second {
+			return fmt.Errorf("invalid %s %q: must be a duration of at least 1s", XdsKeepaliveTimeEnv, v)
+		}
+		keepaliveTime = d
+	}
+	opts["xds_keepalive_time"] = int64(keepaliveTime / time.Second)
+
+	if v := lookupEnv(envs, XdsKeepaliveIntervalEnv); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Second {
+			return fmt.Errorf("invalid %s %q: must be a duration of at least 1s", XdsKeepaliveIntervalEnv, v)
+		}
+		opts["xds_keepalive_interval"] = int64(d / time.Second)
+	}
+
+	http2Options := map[string]interface{}{}
+	for name, field := range map[string]string{
+		XdsInitialStreamWindowSizeEnv:     "initial_stream_window_size",
+		XdsInitialConnectionWindowSizeEnv: "initial_connection_window_size",
+	} {
+		v := lookupEnv(envs, name)
+		if v == "" {
+			continue
+		}
+		// Envoy accepts windows between 64KiB and 2GiB - 1.
+		size, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || size < 65535 || size > 2147483647 {
+			return fmt.Errorf("invalid %s %q: must be between 65535 and 2147483647", name, v)
+		}
+		http2Options[field] = size
+	}
+	opts["xds_http2_protocol_options"] = convertToJSON(http2Options)
+	return nil
+}
+
+// applyBootstrapPatch applies a JSON merge patch to the generated bootstrap.
+func applyBootstrapPatch(bootstrap []byte, patch string) ([]byte, error) {
+	out, err := jsonpatch.MergePatch(bootstrap, []byte(patch))
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply %s: %v", BootstrapPatchEnv, err)
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, out, "", "  "); err != nil {
+		return nil, err
+	}
+	return indented.Bytes(), nil
+}
+
 // WriteBootstrap generates an envoy config based on config and epoch, and returns the filename.
 // TODO: in v2 some of the LDS ports (port, http_port) should be configured in the bootstrap.
 func WriteBootstrap(config *meshconfig.ProxyConfig, node string, epoch int, pilotSAN []string,
@@ -445,6 +570,10 @@ func writeBootstrapForPlatform(config *meshconfig.ProxyConfig, node string, epoc
 
 	opts["dns_refresh_rate"] = dnsRefreshRate
 
+	if err := setXdsConnectionOptions(opts, localEnv); err != nil {
+		return "", err
+	}
+
 	// Setting default to ipv4 local host, wildcard and dns policy
 	opts["localhost"] = "127.0.0.1"
 	opts["wildcard"] = "0.0.0.0"
@@ -542,15 +671,24 @@ func writeBootstrapForPlatform(config *meshconfig.ProxyConfig, node string, epoc
 		storeKeepalive(config.EnvoyAccessLogService.TcpKeepalive, "envoy_accesslog_service_tcp_keepalive", opts)
 	}
 
-	fout, err := os.Create(fname)
-	if err != nil {
+	var out bytes.Buffer
+	if err := t.Execute(&out, opts); err != nil {
 		return "", err
 	}
-	defer fout.Close()
+	content := out.Bytes()
+	if patch := bootstrapPatch(localEnv); patch != "" {
+		if !json.Valid(content) {
+			// Custom bootstrap templates may render other formats, which the patch does not apply to.
+			log.Warnf("Ignoring %s: the bootstrap generated from %s is not JSON", BootstrapPatchEnv, cfg)
+		} else if content, err = applyBootstrapPatch(content, patch); err != nil {
+			return "", err
+		}
+	}
 
-	// Execute needs some sort of io.Writer
-	err = t.Execute(fout, opts)
-	return fname, err
+	if err := ioutil.WriteFile(fname, content, 0644); err != nil {
+		return "", err
+	}
+	return fname, nil
 }
 
 func setOptsWithDefaults(src *types.Int64Value, name string, opts map[string]interface{}, defaultVal int64) {
@@ -694,6 +694,22 @@ func TestNodeMetadata(t *testing.T) {
 	}
 }
 
+func TestNodeMetadataExchangeAttributes(t *testing.T) {
+	labels := map[string]string{"app": "reviews", "team": "bookinfo", "cost-center": "1234"}
+	anno := map[string]string{ExchangeAttributesAnnotation: "team, cost-center,NAMESPACE,"}
+
+	_, envs := createEnv(t, labels, anno)
+	nm := getNodeMetaData(envs, nil)
+
+	want := metadataExchangeKeys + ",team,cost-center"
+	if got := nm[model.NodeMetadataExchangeKeys]; got != want {
+		t.Fatalf("got exchange keys %v, want %v", got, want)
+	}
+	if nm["team"] != "bookinfo" || nm["cost-center"] != "1234" {
+		t.Fatalf("exchanged attributes missing from node metadata: %v", nm)
+	}
+}
+
 func TestNodeMetadataEncodeEnvWithIstioMetaPrefix(t *testing.T) {
 	originalKey := "foo"
 	notIstioMetaKey := "NOT_AN_" + IstioMetaPrefix + originalKey
@@ -736,3 +752,126 @@ func (f *fakePlatform) Metadata() map[string]string {
 func (f *fakePlatform) Locality() *core.Locality {
 	return &core.Locality{}
 }
+
+func TestApplyBootstrapPatch(t *testing.T) {
+	bootstrap := `{"admin": {"access_log_path": "/dev/null", "address": {"socket_address": {"port_value": 15000}}},` +
+		`"stats_config": {"use_all_default_tags": false}, "tracing": {"http": {"name": "envoy.zipkin"}}}`
+	patch := `{"admin": {"access_log_path": "/dev/stdout"}, "stats_config": null, "stats_flush_interval": "10s"}`
+	envs := []string{"FOO=bar", BootstrapPatchEnv + "=" + patch}
+
+	got := bootstrapPatch(envs)
+	if got != patch {
+		t.Fatalf("bootstrapPatch() got %q, want %q", got, patch)
+	}
+	out, err := applyBootstrapPatch([]byte(bootstrap), got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual, want map[string]interface{}
+	if err := json.Unmarshal(out, &actual); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"admin": {"access_log_path": "/dev/stdout", "address": {"socket_address": {"port_value": 15000}}},`+
+		`"stats_flush_interval": "10s", "tracing": {"http": {"name": "envoy.zipkin"}}}`), &want); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, want) {
+		t.Errorf("applyBootstrapPatch() got %v, want %v", actual, want)
+	}
+
+	if _, err := applyBootstrapPatch([]byte(bootstrap), "{invalid"); err == nil {
+		t.Errorf("applyBootstrapPatch() expected error for an invalid patch")
+	}
+}
+
+func TestBootstrapPatchIgnoresNonJSONTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bootstrap-patch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg, err := loadProxyConfig("default", dir, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := "admin:\n  access_log_path: /dev/null\n"
+	cfg.CustomConfigFile = path.Join(dir, "custom.yaml")
+	if err := ioutil.WriteFile(cfg.CustomConfigFile, []byte(tmpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fn, err := writeBootstrapForPlatform(cfg, "sidecar~1.2.3.4~foo~bar", 0, nil, nil,
+		[]string{BootstrapPatchEnv + `={"stats_flush_interval": "10s"}`}, []string{"10.3.3.3"}, "60s", &fakePlatform{})
+	if err != nil {
+		t.Fatalf("writeBootstrapForPlatform() unexpected error: %v", err)
+	}
+	got, err := ioutil.ReadFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != tmpl {
+		t.Errorf("got bootstrap %q, want the template unpatched %q", got, tmpl)
+	}
+}
+
+func TestSetXdsConnectionOptions(t *testing.T) {
+	cases := []struct {
+		name    string
+		envs    []string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: map[string]interface{}{
+				"xds_keepalive_time":         int64(300),
+				"xds_http2_protocol_options": `{}`,
+			},
+		},
+		{
+			name: "keepalive and windows",
+			envs: []string{
+				XdsKeepaliveTimeEnv + "=1m",
+				XdsKeepaliveIntervalEnv + "=10s",
+				XdsInitialStreamWindowSizeEnv + "=65536",
+				XdsInitialConnectionWindowSizeEnv + "=1048576",
+			},
+			want: map[string]interface{}{
+				"xds_keepalive_time":         int64(60),
+				"xds_keepalive_interval":     int64(10),
+				"xds_http2_protocol_options": `{"initial_connection_window_size":1048576,"initial_stream_window_size":65536}`,
+			},
+		},
+		{
+			name:    "invalid keepalive time",
+			envs:    []string{XdsKeepaliveTimeEnv + "=100ms"},
+			wantErr: true,
+		},
+		{
+			name:    "window too small",
+			envs:    []string{XdsInitialStreamWindowSizeEnv + "=1024"},
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			opts := map[string]interface{}{}
+			err := setXdsConnectionOptions(opts, c.envs)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("setXdsConnectionOptions() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(opts, c.want) {
+				t.Errorf("setXdsConnectionOptions() got %v, want %v", opts, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"encoding/json"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// GRPCBootstrapEnv is the environment variable gRPC reads the path of its xDS bootstrap from.
+const GRPCBootstrapEnv = "GRPC_XDS_BOOTSTRAP"
+
+// grpcBootstrap is the xDS bootstrap file of proxyless gRPC clients.
+type grpcBootstrap struct {
+	XdsServers []grpcXdsServer `json:"xds_servers"`
+	Node       grpcNode        `json:"node"`
+}
+
+type grpcXdsServer struct {
+	ServerURI    string            `json:"server_uri"`
+	ChannelCreds []grpcChannelCred `json:"channel_creds"`
+}
+
+type grpcChannelCred struct {
+	Type string `json:"type"`
+}
+
+type grpcNode struct {
+	ID       string            `json:"id"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// GenerateGRPCBootstrap returns the xDS bootstrap of a proxyless gRPC client connecting to pilot at
+// discoveryAddress. The node is identified like a sidecar, with the metadata selecting the gRPC
+// config generator in pilot.
+func GenerateGRPCBootstrap(discoveryAddress, nodeID string, meta map[string]string) ([]byte, error) {
+	metadata := map[string]string{}
+	for k, v := range meta {
+		metadata[k] = v
+	}
+	metadata[model.NodeMetadataGenerator] = model.GeneratorGRPC
+
+	return json.MarshalIndent(grpcBootstrap{
+		XdsServers: []grpcXdsServer{{
+			ServerURI: discoveryAddress,
+			// The plain text port of pilot is used, as gRPC does not support mTLS to the control plane yet.
+			ChannelCreds: []grpcChannelCred{{Type: "insecure"}},
+		}},
+		Node: grpcNode{
+			ID:       nodeID,
+			Metadata: metadata,
+		},
+	}, "", "  ")
+}
@@ -0,0 +1,48 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func TestGenerateGRPCBootstrap(t *testing.T) {
+	out, err := GenerateGRPCBootstrap("istio-pilot.istio-system:15010", "sidecar~10.1.1.1~foo.default~default.svc.cluster.local",
+		map[string]string{model.NodeMetadataNamespace: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got, want map[string]interface{}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{
+  "xds_servers": [{"server_uri": "istio-pilot.istio-system:15010", "channel_creds": [{"type": "insecure"}]}],
+  "node": {
+    "id": "sidecar~10.1.1.1~foo.default~default.svc.cluster.local",
+    "metadata": {"GENERATOR": "grpc", "NAMESPACE": "default"}
+  }
+}`), &want); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GenerateGRPCBootstrap() got %v, want %v", got, want)
+	}
+}
@@ -89,4 +89,22 @@ const (
 
 	// IstioMeshGateway is the built in gateway for all sidecars
 	IstioMeshGateway = "mesh"
+
+	// CORSOriginRegexPrefix marks the CORS allowed origins which are regular expressions, e.g.
+	// "regex:https://.*[.]example[.]com".
+	CORSOriginRegexPrefix = "regex:"
+
+	// CORSOriginPrefixPrefix marks the CORS allowed origins which match the origins starting with
+	// the given value, e.g. "prefix:https://app-".
+	CORSOriginPrefixPrefix = "prefix:"
+
+	// RoutePriorityAnnotation is the annotation on virtual services setting their precedence over the
+	// other virtual services of the same hosts, the highest first. Virtual services without it have
+	// the priority 0.
+	RoutePriorityAnnotation = "networking.istio.io/routePriority"
+
+	// ConfigWritersAnnotation is the annotation on services and service entries listing, comma
+	// separated, the namespaces besides their own allowed to define the virtual services and
+	// destination rules of their hosts when host ownership is enforced, or "*" for all namespaces.
+	ConfigWritersAnnotation = "networking.istio.io/configWriters"
 )
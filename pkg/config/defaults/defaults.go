@@ -0,0 +1,123 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package defaults materializes the implicit defaults of the Istio configuration, i.e. the values
+// Pilot assumes for fields left unset, so that stored resources reflect the effective configuration.
+package defaults
+
+import (
+	"github.com/gogo/protobuf/proto"
+
+	networking "istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/pkg/config/protocol"
+)
+
+const (
+	// DefaultRetryAttempts is the number of retries of HTTP routes without a retry policy. Pilot
+	// builds its default retry policy from it and DefaultRetryOn.
+	DefaultRetryAttempts = 2
+
+	// DefaultRetryOn are the retry conditions of HTTP routes without a retry policy, in the format
+	// of the retryOn field of the HTTP retries.
+	DefaultRetryOn = "connect-failure,refused-stream,unavailable,cancelled,resource-exhausted,retriable-status-codes,503"
+
+	// singleDestinationWeight is the weight of the destination of a route with a single destination.
+	singleDestinationWeight = 100
+)
+
+// DefaultFunc sets the implicit defaults of a configuration proto in place and returns whether
+// the configuration changed.
+type DefaultFunc func(config proto.Message) bool
+
+// DefaultVirtualService sets the retry policy of HTTP routes and the weight of single destinations.
+func DefaultVirtualService(config proto.Message) bool {
+	vs, ok := config.(*networking.VirtualService)
+	if !ok {
+		return false
+	}
+
+	changed := false
+	for _, route := range vs.Http {
+		if route.Redirect != nil || len(route.Route) == 0 {
+			continue
+		}
+		if route.Retries == nil {
+			route.Retries = &networking.HTTPRetry{
+				Attempts: DefaultRetryAttempts,
+				RetryOn:  DefaultRetryOn,
+			}
+			changed = true
+		}
+		if len(route.Route) == 1 && route.Route[0].Weight == 0 {
+			route.Route[0].Weight = singleDestinationWeight
+			changed = true
+		}
+	}
+	for _, route := range vs.Tcp {
+		if len(route.Route) == 1 && route.Route[0].Weight == 0 {
+			route.Route[0].Weight = singleDestinationWeight
+			changed = true
+		}
+	}
+	for _, route := range vs.Tls {
+		if len(route.Route) == 1 && route.Route[0].Weight == 0 {
+			route.Route[0].Weight = singleDestinationWeight
+			changed = true
+		}
+	}
+	return changed
+}
+
+// DefaultGateway canonicalizes the protocols of the server ports.
+func DefaultGateway(config proto.Message) bool {
+	gw, ok := config.(*networking.Gateway)
+	if !ok {
+		return false
+	}
+
+	changed := false
+	for _, server := range gw.Servers {
+		changed = defaultPort(server.Port) || changed
+	}
+	return changed
+}
+
+// DefaultServiceEntry canonicalizes the protocols of the ports.
+func DefaultServiceEntry(config proto.Message) bool {
+	se, ok := config.(*networking.ServiceEntry)
+	if !ok {
+		return false
+	}
+
+	changed := false
+	for _, port := range se.Ports {
+		changed = defaultPort(port) || changed
+	}
+	return changed
+}
+
+// defaultPort replaces the protocol of the port, which Pilot parses ignoring case, with its
+// canonical name.
+func defaultPort(port *networking.Port) bool {
+	if port == nil {
+		return false
+	}
+	p := protocol.Parse(port.Protocol)
+	if p == protocol.Unsupported || string(p) == port.Protocol {
+		return false
+	}
+	port.Protocol = string(p)
+	return true
+}
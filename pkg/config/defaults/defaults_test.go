@@ -0,0 +1,139 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package defaults
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+
+	networking "istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/pkg/config/validation"
+)
+
+func destination(host string, weight int32) *networking.HTTPRouteDestination {
+	return &networking.HTTPRouteDestination{Destination: &networking.Destination{Host: host}, Weight: weight}
+}
+
+func TestDefaultVirtualService(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      *networking.VirtualService
+		want    *networking.VirtualService
+		changed bool
+	}{
+		{
+			name: "route without retries",
+			in: &networking.VirtualService{
+				Hosts: []string{"reviews"},
+				Http:  []*networking.HTTPRoute{{Route: []*networking.HTTPRouteDestination{destination("reviews", 0)}}},
+			},
+			want: &networking.VirtualService{
+				Hosts: []string{"reviews"},
+				Http: []*networking.HTTPRoute{{
+					Route:   []*networking.HTTPRouteDestination{destination("reviews", 100)},
+					Retries: &networking.HTTPRetry{Attempts: DefaultRetryAttempts, RetryOn: DefaultRetryOn},
+				}},
+			},
+			changed: true,
+		},
+		{
+			name: "explicitly disabled retries and weighted destinations",
+			in: &networking.VirtualService{
+				Hosts: []string{"reviews"},
+				Http: []*networking.HTTPRoute{{
+					Route:   []*networking.HTTPRouteDestination{destination("reviews", 80), destination("ratings", 20)},
+					Retries: &networking.HTTPRetry{},
+				}},
+			},
+		},
+		{
+			name: "redirect",
+			in: &networking.VirtualService{
+				Hosts: []string{"reviews"},
+				Http:  []*networking.HTTPRoute{{Redirect: &networking.HTTPRedirect{Uri: "/v2"}}},
+			},
+		},
+		{
+			name: "tcp route",
+			in: &networking.VirtualService{
+				Hosts: []string{"reviews"},
+				Tcp: []*networking.TCPRoute{{Route: []*networking.RouteDestination{{
+					Destination: &networking.Destination{Host: "reviews"},
+				}}}},
+			},
+			want: &networking.VirtualService{
+				Hosts: []string{"reviews"},
+				Tcp: []*networking.TCPRoute{{Route: []*networking.RouteDestination{{
+					Destination: &networking.Destination{Host: "reviews"},
+					Weight:      100,
+				}}}},
+			},
+			changed: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			want := tc.want
+			if want == nil {
+				want = proto.Clone(tc.in).(*networking.VirtualService)
+			}
+			if changed := DefaultVirtualService(tc.in); changed != tc.changed {
+				t.Errorf("got changed %v, want %v", changed, tc.changed)
+			}
+			if !proto.Equal(tc.in, want) {
+				t.Errorf("got %v, want %v", tc.in, want)
+			}
+			// Defaults are idempotent and valid.
+			if DefaultVirtualService(tc.in) {
+				t.Error("defaults applied twice")
+			}
+			if err := validation.ValidateVirtualService("name", "default", tc.in); err != nil {
+				t.Errorf("defaulted virtual service is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestDefaultPortProtocols(t *testing.T) {
+	gw := &networking.Gateway{
+		Servers: []*networking.Server{
+			{Port: &networking.Port{Number: 80, Name: "http", Protocol: "http"}},
+			{Port: &networking.Port{Number: 443, Name: "https", Protocol: "HTTPS"}},
+			{Port: &networking.Port{Number: 8080, Name: "unknown", Protocol: "foo"}},
+		},
+	}
+	if !DefaultGateway(gw) {
+		t.Error("expected the gateway to change")
+	}
+	for i, want := range []string{"HTTP", "HTTPS", "foo"} {
+		if got := gw.Servers[i].Port.Protocol; got != want {
+			t.Errorf("server %d: got protocol %q, want %q", i, got, want)
+		}
+	}
+	if DefaultGateway(gw) {
+		t.Error("defaults applied twice")
+	}
+
+	se := &networking.ServiceEntry{Ports: []*networking.Port{{Number: 27017, Name: "mongo", Protocol: "MONGO"}}}
+	if !DefaultServiceEntry(se) || se.Ports[0].Protocol != "Mongo" {
+		t.Errorf("got protocol %q, want Mongo", se.Ports[0].Protocol)
+	}
+
+	if DefaultGateway(se) || DefaultServiceEntry(gw) || DefaultVirtualService(gw) {
+		t.Error("unexpected change of a config of another type")
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// DelegationsConfigMapName is the name of the ConfigMap of the Istio namespace in which the
+	// platform admins delegate domains to namespaces.
+	DelegationsConfigMapName = "istio-host-delegations"
+
+	// DelegationsConfigMapKey is the key of the delegations in the ConfigMap, holding
+	// domain=namespace pairs separated by commas or new lines, e.g. *.payments.example.com=payments.
+	DelegationsConfigMapKey = "delegations"
+)
+
+var (
+	delegatedMutex sync.RWMutex
+	// delegated are the domains delegated to namespaces by the platform admins, and
+	// delegatingNamespace the namespace of the ConfigMap delegating them.
+	delegated           Delegations
+	delegatingNamespace string
+)
+
+// Delegations maps delegated domains, possibly wildcarded, to the namespaces they are delegated to.
+type Delegations map[Name]map[string]bool
+
+// SetDelegated replaces the delegations in force with the ones read from the ConfigMap of the
+// namespace.
+func SetDelegated(namespace string, d Delegations) {
+	delegatedMutex.Lock()
+	defer delegatedMutex.Unlock()
+	delegated = d
+	delegatingNamespace = namespace
+}
+
+// CheckDelegatedClaim checks the claim of the host by a config of the namespace against the
+// delegations in force. The configs of the namespace delegating the domains, such as the stock
+// ingress Gateway of the Istio namespace with the * host, are not checked.
+func CheckDelegatedClaim(namespace string, h Name) error {
+	delegatedMutex.RLock()
+	defer delegatedMutex.RUnlock()
+	if namespace == delegatingNamespace {
+		return nil
+	}
+	return delegated.CheckClaim(namespace, h)
+}
+
+// ParseDelegations parses domain=namespace pairs separated by commas or new lines.
+func ParseDelegations(value string) (Delegations, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	out := Delegations{}
+	for _, pair := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.Split(pair, "=")
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			return nil, fmt.Errorf("invalid delegation %q, must be domain=namespace", pair)
+		}
+		domain := Name(strings.ToLower(strings.TrimSpace(kv[0])))
+		if _, f := out[domain]; !f {
+			out[domain] = map[string]bool{}
+		}
+		out[domain][strings.TrimSpace(kv[1])] = true
+	}
+	return out, nil
+}
+
+// CheckClaim returns an error if a config of the namespace claims a host of a domain delegated to
+// other namespaces, including with a wildcard host covering the domain. A host under several
+// delegated domains must be delegated to the namespace by each of them. Configs without namespace
+// are not checked.
+func (d Delegations) CheckClaim(namespace string, h Name) error {
+	if namespace == "" {
+		return nil
+	}
+	h = Name(strings.ToLower(string(h)))
+	domains := make([]string, 0, len(d))
+	for domain := range d {
+		domains = append(domains, string(domain))
+	}
+	sort.Strings(domains)
+	for _, domain := range domains {
+		if !h.SubsetOf(Name(domain)) && !Name(domain).SubsetOf(h) {
+			continue
+		}
+		if !d[Name(domain)][namespace] {
+			return fmt.Errorf("host %s is in domain %s, which is not delegated to namespace %s", h, domain, namespace)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host_test
+
+import (
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pkg/config/host"
+)
+
+func TestParseDelegations(t *testing.T) {
+	got, err := host.ParseDelegations("*.payments.example.com=payments, *.Payments.example.com=payments-canary\nshop.example.com=shop\n")
+	if err != nil {
+		t.Fatalf("ParseDelegations() failed: %v", err)
+	}
+	want := host.Delegations{
+		"*.payments.example.com": {"payments": true, "payments-canary": true},
+		"shop.example.com":       {"shop": true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDelegations() = %v, want %v", got, want)
+	}
+
+	for _, invalid := range []string{"payments.example.com", "=payments", "payments.example.com=", "a=b=c"} {
+		if _, err := host.ParseDelegations(invalid); err == nil {
+			t.Errorf("ParseDelegations(%q) succeeded, want error", invalid)
+		}
+	}
+}
+
+func TestDelegationsCheckClaim(t *testing.T) {
+	delegations := host.Delegations{
+		"*.payments.example.com": {"payments": true},
+		"shop.example.com":       {"shop": true},
+	}
+	tests := []struct {
+		namespace string
+		host      host.Name
+		valid     bool
+	}{
+		{"payments", "api.payments.example.com", true},
+		{"payments", "API.Payments.example.com", true},
+		{"payments", "*.eu.payments.example.com", true},
+		{"shop", "api.payments.example.com", false},
+		{"shop", "*.example.com", false},
+		{"shop", "*", false},
+		{"shop", "shop.example.com", true},
+		{"payments", "shop.example.com", false},
+		{"shop", "www.example.com", true},
+		{"", "api.payments.example.com", true},
+	}
+	for _, tt := range tests {
+		err := delegations.CheckClaim(tt.namespace, tt.host)
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("CheckClaim(%q, %q) = %v, want valid %v", tt.namespace, tt.host, err, tt.valid)
+		}
+	}
+}
+
+func TestCheckDelegatedClaim(t *testing.T) {
+	defer host.SetDelegated("", nil)
+	if err := host.CheckDelegatedClaim("shop", "*"); err != nil {
+		t.Errorf("CheckDelegatedClaim() without delegations = %v, want nil", err)
+	}
+
+	host.SetDelegated("istio-system", host.Delegations{"*.payments.example.com": {"payments": true}})
+	if err := host.CheckDelegatedClaim("shop", "*"); err == nil {
+		t.Error("CheckDelegatedClaim() in another namespace succeeded, want error")
+	}
+	if err := host.CheckDelegatedClaim("istio-system", "*"); err != nil {
+		t.Errorf("CheckDelegatedClaim() in the delegating namespace = %v, want nil", err)
+	}
+}
@@ -15,6 +15,7 @@
 package mesh
 
 import (
+	"reflect"
 	"time"
 
 	"github.com/gogo/protobuf/types"
@@ -137,3 +138,54 @@ func LoadMeshNetworksConfig(yaml string) (*meshconfig.MeshNetworks, error) {
 	// }
 	return &out, nil
 }
+
+// Changes classifies the differences between two mesh configurations by what
+// is needed to apply them.
+type Changes struct {
+	// Push is true when fields read while generating the proxy config have changed.
+	// Pushing the regenerated config to the proxies applies them.
+	Push bool
+
+	// ProxyRestart lists the changed fields that are only read when the proxies are
+	// injected or bootstrapped, so running workloads keep the old values until restarted.
+	ProxyRestart []string
+
+	// PilotRestart lists the changed fields that are only read when pilot starts.
+	PilotRestart []string
+}
+
+// DiffMeshConfig compares two mesh configurations and returns how to apply the changes.
+func DiffMeshConfig(old, cur *meshconfig.MeshConfig) Changes {
+	o, c := *old, *cur
+	changes := Changes{}
+
+	if !reflect.DeepEqual(o.DefaultConfig, c.DefaultConfig) {
+		changes.ProxyRestart = append(changes.ProxyRestart, "defaultConfig")
+	}
+	o.DefaultConfig, c.DefaultConfig = nil, nil
+
+	if !reflect.DeepEqual(o.ConfigSources, c.ConfigSources) {
+		changes.PilotRestart = append(changes.PilotRestart, "configSources")
+	}
+	o.ConfigSources, c.ConfigSources = nil, nil
+
+	if o.IngressControllerMode != c.IngressControllerMode {
+		changes.PilotRestart = append(changes.PilotRestart, "ingressControllerMode")
+	}
+	o.IngressControllerMode, c.IngressControllerMode = 0, 0
+
+	if o.IngressClass != c.IngressClass {
+		changes.PilotRestart = append(changes.PilotRestart, "ingressClass")
+	}
+	o.IngressClass, c.IngressClass = "", ""
+
+	if o.IngressService != c.IngressService {
+		changes.PilotRestart = append(changes.PilotRestart, "ingressService")
+	}
+	o.IngressService, c.IngressService = "", ""
+
+	// Everything else, such as the access log, tracing and outbound traffic policy settings,
+	// only affects the generated config.
+	changes.Push = !reflect.DeepEqual(o, c)
+	return changes
+}
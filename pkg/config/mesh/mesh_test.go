@@ -121,3 +121,67 @@ networks:
 		t.Fatalf("Wrong values:\n got %#v \nwant %#v", got, &want)
 	}
 }
+
+func TestDiffMeshConfig(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(m *meshconfig.MeshConfig)
+		want   mesh.Changes
+	}{
+		{
+			name:   "unchanged",
+			modify: func(m *meshconfig.MeshConfig) {},
+			want:   mesh.Changes{},
+		},
+		{
+			name: "access log file",
+			modify: func(m *meshconfig.MeshConfig) {
+				m.AccessLogFile = "/dev/stdout"
+			},
+			want: mesh.Changes{Push: true},
+		},
+		{
+			name: "outbound traffic policy",
+			modify: func(m *meshconfig.MeshConfig) {
+				m.OutboundTrafficPolicy = &meshconfig.MeshConfig_OutboundTrafficPolicy{
+					Mode: meshconfig.MeshConfig_OutboundTrafficPolicy_REGISTRY_ONLY,
+				}
+			},
+			want: mesh.Changes{Push: true},
+		},
+		{
+			name: "tracing",
+			modify: func(m *meshconfig.MeshConfig) {
+				m.EnableTracing = !m.EnableTracing
+			},
+			want: mesh.Changes{Push: true},
+		},
+		{
+			name: "proxy config",
+			modify: func(m *meshconfig.MeshConfig) {
+				m.DefaultConfig.ConfigPath = "/test/config/patch"
+			},
+			want: mesh.Changes{ProxyRestart: []string{"defaultConfig"}},
+		},
+		{
+			name: "ingress class and access log encoding",
+			modify: func(m *meshconfig.MeshConfig) {
+				m.IngressClass = "nginx"
+				m.AccessLogEncoding = meshconfig.MeshConfig_JSON
+			},
+			want: mesh.Changes{Push: true, PilotRestart: []string{"ingressClass"}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			old := mesh.DefaultMeshConfig()
+			cur := mesh.DefaultMeshConfig()
+			tc.modify(&cur)
+
+			if got := mesh.DiffMeshConfig(&old, &cur); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("DiffMeshConfig() => got %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
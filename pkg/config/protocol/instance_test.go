@@ -55,6 +55,10 @@ func TestParse(t *testing.T) {
 		{"mysql", protocol.MySQL},
 		{"MYSQL", protocol.MySQL},
 		{"MySQL", protocol.MySQL},
+		{"thrift", protocol.Thrift},
+		{"THRIFT", protocol.Thrift},
+		{"kafka", protocol.Kafka},
+		{"Kafka", protocol.Kafka},
 		{"", protocol.Unsupported},
 		{"SMTP", protocol.Unsupported},
 	}
@@ -22,6 +22,7 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"gopkg.in/yaml.v2"
 
+	"istio.io/istio/pkg/config/defaults"
 	"istio.io/istio/pkg/config/validation"
 	"istio.io/istio/pkg/util/gogoprotomarshal"
 )
@@ -55,6 +56,10 @@ type Instance struct {
 	// instance of the expected message type
 	Validate validation.ValidateFunc
 
+	// Default sets the implicit defaults of the configuration, assuming the object is an instance
+	// of the expected message type. Nil when the type has no implicit defaults.
+	Default defaults.DefaultFunc
+
 	// MCP collection for this configuration resource schema
 	Collection string
 }
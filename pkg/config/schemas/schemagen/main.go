@@ -39,6 +39,7 @@ package {{ .Package }}
 
 import (
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/defaults"
 	"istio.io/istio/pkg/config/schema"
 	"istio.io/istio/pkg/config/validation"
 )
@@ -53,6 +54,9 @@ var (
           Version: "{{ .Version }}",
           MessageName: "{{ .MessageName }}",
           Validate: validation.{{ .Validate }},
+          {{- if .Default }}
+          Default: defaults.{{ .Default }},
+          {{- end }}
           Collection: "{{ .Collection }}",
           ClusterScoped: {{ .ClusterScoped }},
           VariableName: "{{ .VariableName }}",
@@ -170,6 +174,7 @@ type SchemaInfo struct {
 	Version       string `json:"version"`
 	MessageName   string `json:"messageName"`
 	Validate      string `json:"validate"`
+	Default       string `json:"default"`
 	Collection    string `json:"collection"`
 	ClusterScoped bool   `json:"clusterScoped"`
 	VariableName  string `json:"variableName"`
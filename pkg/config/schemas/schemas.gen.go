@@ -3,6 +3,7 @@
 package schemas
 
 import (
+	"istio.io/istio/pkg/config/defaults"
 	"istio.io/istio/pkg/config/schema"
 	"istio.io/istio/pkg/config/validation"
 )
@@ -17,6 +18,7 @@ var (
 		Version:       "v1alpha3",
 		MessageName:   "istio.networking.v1alpha3.VirtualService",
 		Validate:      validation.ValidateVirtualService,
+		Default:       defaults.DefaultVirtualService,
 		Collection:    "istio/networking/v1alpha3/virtualservices",
 		ClusterScoped: false,
 		VariableName:  "VirtualService",
@@ -30,6 +32,7 @@ var (
 		Version:       "v1alpha3",
 		MessageName:   "istio.networking.v1alpha3.Gateway",
 		Validate:      validation.ValidateGateway,
+		Default:       defaults.DefaultGateway,
 		Collection:    "istio/networking/v1alpha3/gateways",
 		ClusterScoped: false,
 		VariableName:  "Gateway",
@@ -43,6 +46,7 @@ var (
 		Version:       "v1alpha3",
 		MessageName:   "istio.networking.v1alpha3.ServiceEntry",
 		Validate:      validation.ValidateServiceEntry,
+		Default:       defaults.DefaultServiceEntry,
 		Collection:    "istio/networking/v1alpha3/serviceentries",
 		ClusterScoped: false,
 		VariableName:  "ServiceEntry",
@@ -0,0 +1,94 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"istio.io/pkg/env"
+)
+
+var (
+	// FIPSMode restricts the TLS settings of the control plane servers and of the generated proxy
+	// config to FIPS 140-2 approved ones, and makes validation reject user TLS settings that are
+	// not. It must be set on every control plane component, and the proxies must run a FIPS
+	// compliant Envoy build.
+	FIPSMode = env.RegisterBoolVar(
+		"FIPS_MODE",
+		false,
+		"If enabled, TLS is restricted to FIPS 140-2 approved protocol versions, cipher suites and curves.",
+	).Get()
+
+	// FIPSCipherSuites are the cipher suites approved in FIPS mode, as named by Envoy.
+	FIPSCipherSuites = []string{
+		"ECDHE-ECDSA-AES128-GCM-SHA256",
+		"ECDHE-RSA-AES128-GCM-SHA256",
+		"ECDHE-ECDSA-AES256-GCM-SHA384",
+		"ECDHE-RSA-AES256-GCM-SHA384",
+	}
+
+	// FIPSEcdhCurves are the ECDH curves approved in FIPS mode, as named by Envoy.
+	FIPSEcdhCurves = []string{"P-256"}
+
+	fipsGoCipherSuites = []uint16{
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	}
+)
+
+// ValidateFIPSCipherSuites returns an error naming the cipher suites that are not approved in
+// FIPS mode.
+func ValidateFIPSCipherSuites(cipherSuites []string) error {
+	var invalid []string
+	for _, cs := range cipherSuites {
+		if !IsFIPSCipherSuite(cs) {
+			invalid = append(invalid, cs)
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("cipher suites %s are not allowed in FIPS mode, use a subset of %s",
+			strings.Join(invalid, ", "), strings.Join(FIPSCipherSuites, ", "))
+	}
+	return nil
+}
+
+// IsFIPSCipherSuite returns true if the cipher suite, as named by Envoy, is approved in FIPS mode.
+func IsFIPSCipherSuite(cipherSuite string) bool {
+	for _, cs := range FIPSCipherSuites {
+		if cs == cipherSuite {
+			return true
+		}
+	}
+	return false
+}
+
+// RestrictTLSConfig restricts the TLS config of a control plane server to FIPS approved settings
+// when FIPSMode is enabled, and returns it.
+func RestrictTLSConfig(cfg *tls.Config) *tls.Config {
+	if !FIPSMode {
+		return cfg
+	}
+	// The cipher suites of TLS 1.3 cannot be configured, pin TLS 1.2 to keep them under control.
+	cfg.MinVersion = tls.VersionTLS12
+	cfg.MaxVersion = tls.VersionTLS12
+	cfg.CipherSuites = fipsGoCipherSuites
+	cfg.CurvePreferences = []tls.CurveID{tls.CurveP256}
+	cfg.PreferServerCipherSuites = true
+	return cfg
+}
@@ -0,0 +1,59 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security_test
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"istio.io/istio/pkg/config/security"
+)
+
+func TestValidateFIPSCipherSuites(t *testing.T) {
+	cases := []struct {
+		name         string
+		cipherSuites []string
+		valid        bool
+	}{
+		{"none", nil, true},
+		{"approved", []string{"ECDHE-RSA-AES256-GCM-SHA384", "ECDHE-ECDSA-AES128-GCM-SHA256"}, true},
+		{"not approved", []string{"ECDHE-RSA-AES256-GCM-SHA384", "ECDHE-RSA-CHACHA20-POLY1305"}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := security.ValidateFIPSCipherSuites(c.cipherSuites); (err == nil) != c.valid {
+				t.Errorf("ValidateFIPSCipherSuites(%v) = %v, want valid: %v", c.cipherSuites, err, c.valid)
+			}
+		})
+	}
+}
+
+func TestRestrictTLSConfig(t *testing.T) {
+	defer func(fips bool) { security.FIPSMode = fips }(security.FIPSMode)
+
+	security.FIPSMode = false
+	if cfg := security.RestrictTLSConfig(&tls.Config{}); cfg.MinVersion != 0 || cfg.CipherSuites != nil {
+		t.Errorf("expected the config to be left untouched without FIPS mode, got %+v", cfg)
+	}
+
+	security.FIPSMode = true
+	cfg := security.RestrictTLSConfig(&tls.Config{})
+	if cfg.MinVersion != tls.VersionTLS12 || cfg.MaxVersion != tls.VersionTLS12 {
+		t.Errorf("expected TLS 1.2 only, got min %x max %x", cfg.MinVersion, cfg.MaxVersion)
+	}
+	if len(cfg.CipherSuites) == 0 || len(cfg.CurvePreferences) == 0 {
+		t.Errorf("expected cipher suites and curves to be restricted, got %+v", cfg)
+	}
+}
@@ -72,6 +72,8 @@ var supportedRetryOnPolicies = map[string]bool{
 	"retriable-4xx":          true,
 	"refused-stream":         true,
 	"retriable-status-codes": true,
+	"retriable-headers":      true,
+	"reset":                  true,
 
 	// 'x-envoy-retry-grpc-on' supported policies:
 	// https://www.envoyproxy.io/docs/envoy/latest/configuration/http_filters/router_filter#x-envoy-retry-grpc-on
@@ -287,7 +289,7 @@ func ValidateUnixAddress(addr string) error {
 }
 
 // ValidateGateway checks gateway specifications
-func ValidateGateway(name, _ string, msg proto.Message) (errs error) {
+func ValidateGateway(name, namespace string, msg proto.Message) (errs error) {
 	// Gateway name must conform to the DNS label format (no dots)
 	if !labels.IsDNS1123Label(name) {
 		errs = appendErrors(errs, fmt.Errorf("invalid gateway name: %q", name))
@@ -303,6 +305,10 @@ func ValidateGateway(name, _ string, msg proto.Message) (errs error) {
 	} else {
 		for _, server := range value.Servers {
 			errs = appendErrors(errs, validateServer(server))
+			for _, hostname := range server.Hosts {
+				parts := strings.Split(hostname, "/")
+				errs = appendErrors(errs, host.CheckDelegatedClaim(namespace, host.Name(parts[len(parts)-1])))
+			}
 		}
 	}
 
@@ -378,6 +384,15 @@ func validateTLSOptions(tls *networking.Server_TLSOptions) (errs error) {
 		return
 	}
 
+	if security.FIPSMode {
+		errs = appendErrors(errs, security.ValidateFIPSCipherSuites(tls.CipherSuites))
+		if tls.MinProtocolVersion == networking.Server_TLSOptions_TLSV1_0 ||
+			tls.MinProtocolVersion == networking.Server_TLSOptions_TLSV1_1 {
+			errs = appendErrors(errs, fmt.Errorf("minimum TLS version %v is not allowed in FIPS mode, use TLSV1_2",
+				tls.MinProtocolVersion))
+		}
+	}
+
 	if tls.Mode == networking.Server_TLSOptions_ISTIO_MUTUAL {
 		// ISTIO_MUTUAL TLS mode uses either SDS or default certificate mount paths
 		// therefore, we should fail validation if other TLS fields are set
@@ -440,12 +455,18 @@ func ValidateDestinationRule(_, _ string, msg proto.Message) (errs error) {
 }
 
 func validateExportTo(exportTo []string) (errs error) {
-	if len(exportTo) > 0 {
-		if len(exportTo) > 1 {
-			errs = appendErrors(errs, fmt.Errorf("exportTo should have only one entry (. or *) in the current release"))
-		} else {
-			errs = appendErrors(errs, visibility.Instance(exportTo[0]).Validate())
+	exported := make(map[visibility.Instance]bool, len(exportTo))
+	for _, e := range exportTo {
+		v := visibility.Instance(e)
+		if exported[v] {
+			errs = appendErrors(errs, fmt.Errorf("duplicate exportTo entry %q", e))
+			continue
 		}
+		exported[v] = true
+		if (v == visibility.Public || v == visibility.None) && len(exportTo) > 1 {
+			errs = appendErrors(errs, fmt.Errorf("exportTo %q must be the only entry", e))
+		}
+		errs = appendErrors(errs, v.Validate())
 	}
 
 	return
@@ -1748,7 +1769,7 @@ func validateAuthNPolicyTarget(target *authn.TargetSelector) (errs error) {
 }
 
 // ValidateVirtualService checks that a v1alpha3 route rule is well-formed.
-func ValidateVirtualService(_, _ string, msg proto.Message) (errs error) {
+func ValidateVirtualService(_, namespace string, msg proto.Message) (errs error) {
 	virtualService, ok := msg.(*networking.VirtualService)
 	if !ok {
 		return errors.New("cannot cast to virtual service")
@@ -1783,6 +1804,7 @@ func ValidateVirtualService(_, _ string, msg proto.Message) (errs error) {
 			errs = appendErrors(errs, fmt.Errorf("wildcard host * is not allowed for virtual services bound to the mesh gateway"))
 			allHostsValid = false
 		}
+		errs = appendErrors(errs, host.CheckDelegatedClaim(namespace, host.Name(virtualHost)))
 	}
 
 	// Check for duplicate hosts
@@ -1973,7 +1995,18 @@ func validateHTTPRoute(http *networking.HTTPRoute) (errs error) {
 					errs = appendErrors(errs, fmt.Errorf("header match %v cannot be null", name))
 				}
 				errs = appendErrors(errs, ValidateHTTPHeaderName(name))
+				errs = appendErrors(errs, validateStringMatchRegexp(header, "headers"))
+			}
+			for name, param := range match.QueryParams {
+				if param == nil {
+					errs = appendErrors(errs, fmt.Errorf("query param match %v cannot be null", name))
+				}
+				errs = appendErrors(errs, validateStringMatchRegexp(param, "queryParams"))
 			}
+			errs = appendErrors(errs, validateStringMatchRegexp(match.Uri, "uri"))
+			errs = appendErrors(errs, validateStringMatchRegexp(match.Scheme, "scheme"))
+			errs = appendErrors(errs, validateStringMatchRegexp(match.Method, "method"))
+			errs = appendErrors(errs, validateStringMatchRegexp(match.Authority, "authority"))
 
 			if match.Port != 0 {
 				errs = appendErrors(errs, ValidatePort(int(match.Port)))
@@ -2094,12 +2127,42 @@ func validateRouteDestinations(weights []*networking.RouteDestination) (errs err
 	return
 }
 
+// validateStringMatchRegexp checks that the regex of the string match is a valid RE2 regex, the
+// syntax of the Envoy safe_regex matchers.
+func validateStringMatchRegexp(sm *networking.StringMatch, where string) error {
+	re := sm.GetRegex()
+	if re == "" {
+		return nil
+	}
+	if _, err := regexp.Compile(re); err != nil {
+		return fmt.Errorf("%q: invalid RE2 regex %q: %v", where, re, err)
+	}
+	return nil
+}
+
 func validateCORSPolicy(policy *networking.CorsPolicy) (errs error) {
 	if policy == nil {
 		return
 	}
 
 	for _, hostname := range policy.AllowOrigin {
+		if hostname == "*" && policy.AllowCredentials.GetValue() {
+			// Envoy would echo the origin of any site and allow its credentials, which browsers
+			// forbid for the wildcard.
+			errs = appendErrors(errs, fmt.Errorf("CORS Allow Origin '*' cannot allow credentials"))
+		}
+		if strings.HasPrefix(hostname, constants.CORSOriginRegexPrefix) {
+			if _, err := regexp.Compile(strings.TrimPrefix(hostname, constants.CORSOriginRegexPrefix)); err != nil {
+				errs = appendErrors(errs, fmt.Errorf("invalid RE2 regex in CORS Allow Origin %q: %v", hostname, err))
+			}
+			continue
+		}
+		if strings.HasPrefix(hostname, constants.CORSOriginPrefixPrefix) {
+			if strings.TrimPrefix(hostname, constants.CORSOriginPrefixPrefix) == "" {
+				errs = appendErrors(errs, fmt.Errorf("empty prefix in CORS Allow Origin"))
+			}
+			continue
+		}
 		if hostname != "*" {
 			hostname = strings.TrimPrefix(hostname, "https://")
 			hostname = strings.TrimPrefix(hostname, "http://")
@@ -34,6 +34,8 @@ import (
 	api "istio.io/api/type/v1beta1"
 
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/security"
 )
 
 const (
@@ -1490,6 +1492,46 @@ func TestValidateTlsOptions(t *testing.T) {
 	}
 }
 
+func TestValidateTlsOptionsFIPS(t *testing.T) {
+	defer func(fips bool) { security.FIPSMode = fips }(security.FIPSMode)
+	security.FIPSMode = true
+
+	tests := []struct {
+		name string
+		in   *networking.Server_TLSOptions
+		out  string
+	}{
+		{"approved",
+			&networking.Server_TLSOptions{
+				Mode:               networking.Server_TLSOptions_ISTIO_MUTUAL,
+				MinProtocolVersion: networking.Server_TLSOptions_TLSV1_2,
+				CipherSuites:       []string{"ECDHE-RSA-AES256-GCM-SHA384"}},
+			""},
+		{"cipher suite not approved",
+			&networking.Server_TLSOptions{
+				Mode:         networking.Server_TLSOptions_ISTIO_MUTUAL,
+				CipherSuites: []string{"ECDHE-RSA-CHACHA20-POLY1305"}},
+			"not allowed in FIPS mode"},
+		{"TLS 1.0",
+			&networking.Server_TLSOptions{
+				Mode:               networking.Server_TLSOptions_ISTIO_MUTUAL,
+				MinProtocolVersion: networking.Server_TLSOptions_TLSV1_0},
+			"not allowed in FIPS mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTLSOptions(tt.in)
+			if err == nil && tt.out != "" {
+				t.Fatalf("validateTLSOptions(%v) = nil, wanted %q", tt.in, tt.out)
+			} else if err != nil && tt.out == "" {
+				t.Fatalf("validateTLSOptions(%v) = %v, wanted nil", tt.in, err)
+			} else if err != nil && !strings.Contains(err.Error(), tt.out) {
+				t.Fatalf("validateTLSOptions(%v) = %v, wanted %q", tt.in, err, tt.out)
+			}
+		})
+	}
+}
+
 func TestValidateHTTPHeaderName(t *testing.T) {
 	testCases := []struct {
 		name  string
@@ -1545,6 +1587,21 @@ func TestValidateCORSPolicy(t *testing.T) {
 			ExposeHeaders: []string{"header3"},
 			MaxAge:        &types.Duration{Seconds: 2},
 		}, valid: true},
+		{name: "good regex origin", in: &networking.CorsPolicy{
+			AllowOrigin: []string{"regex:https://.*[.]example[.]com", "https://example.org"},
+		}, valid: true},
+		{name: "bad regex origin", in: &networking.CorsPolicy{
+			AllowOrigin: []string{"regex:https://(.*"},
+		}, valid: false},
+		{name: "non RE2 regex origin", in: &networking.CorsPolicy{
+			AllowOrigin: []string{"regex:https://(?!internal).*[.]example[.]com"},
+		}, valid: false},
+		{name: "good prefix origin", in: &networking.CorsPolicy{
+			AllowOrigin: []string{"prefix:https://app-"},
+		}, valid: true},
+		{name: "empty prefix origin", in: &networking.CorsPolicy{
+			AllowOrigin: []string{"prefix:"},
+		}, valid: false},
 		{name: "good origin with star", in: &networking.CorsPolicy{
 			AllowOrigin:   []string{"*"},
 			AllowMethods:  []string{"GET", "POST"},
@@ -1552,6 +1609,14 @@ func TestValidateCORSPolicy(t *testing.T) {
 			ExposeHeaders: []string{"header3"},
 			MaxAge:        &types.Duration{Seconds: 2},
 		}, valid: true},
+		{name: "origin with star allowing credentials", in: &networking.CorsPolicy{
+			AllowOrigin:      []string{"*"},
+			AllowCredentials: &types.BoolValue{Value: true},
+		}, valid: false},
+		{name: "good origin allowing credentials", in: &networking.CorsPolicy{
+			AllowOrigin:      []string{"https://example.com", "regex:https://.*[.]example[.]com"},
+			AllowCredentials: &types.BoolValue{Value: true},
+		}, valid: true},
 		{name: "good origin with http", in: &networking.CorsPolicy{
 			AllowOrigin:   []string{"http://example.com"},
 			AllowMethods:  []string{"GET", "POST"},
@@ -1796,6 +1861,10 @@ func TestValidateHTTPRetry(t *testing.T) {
 			PerTryTimeout: &types.Duration{Seconds: 2},
 			RetryOn:       "503,connect-failure",
 		}, valid: true},
+		{name: "valid reset and retriable headers retryOn", in: &networking.HTTPRetry{
+			Attempts: 10,
+			RetryOn:  "reset,retriable-headers,retriable-status-codes,502",
+		}, valid: true},
 		{name: "invalid attempts", in: &networking.HTTPRetry{
 			Attempts:      -1,
 			PerTryTimeout: &types.Duration{Seconds: 2},
@@ -1912,6 +1981,32 @@ func TestValidatePortName(t *testing.T) {
 	}
 }
 
+func TestValidateExportTo(t *testing.T) {
+	testCases := []struct {
+		name     string
+		exportTo []string
+		valid    bool
+	}{
+		{name: "empty", exportTo: nil, valid: true},
+		{name: "private", exportTo: []string{"."}, valid: true},
+		{name: "public", exportTo: []string{"*"}, valid: true},
+		{name: "none", exportTo: []string{"~"}, valid: true},
+		{name: "namespaces", exportTo: []string{".", "foo", "bar-baz"}, valid: true},
+		{name: "duplicate", exportTo: []string{"foo", "foo"}, valid: false},
+		{name: "public and namespace", exportTo: []string{"*", "foo"}, valid: false},
+		{name: "none and private", exportTo: []string{".", "~"}, valid: false},
+		{name: "invalid namespace", exportTo: []string{"Foo_Bar"}, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateExportTo(tc.exportTo); (err == nil) != tc.valid {
+				t.Fatalf("got valid=%v but wanted valid=%v: %v", err == nil, tc.valid, err)
+			}
+		})
+	}
+}
+
 func TestValidateHTTPRedirect(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -2240,6 +2335,48 @@ func TestValidateHTTPRoute(t *testing.T) {
 			}},
 			Match: []*networking.HTTPMatchRequest{nil},
 		}, valid: true},
+		{name: "regex matches", route: &networking.HTTPRoute{
+			Route: []*networking.HTTPRouteDestination{{
+				Destination: &networking.Destination{Host: "foo.bar"},
+			}},
+			Match: []*networking.HTTPMatchRequest{{
+				Uri: &networking.StringMatch{MatchType: &networking.StringMatch_Regex{Regex: "/api/v[0-9]+/.*"}},
+				Headers: map[string]*networking.StringMatch{
+					"header": {MatchType: &networking.StringMatch_Regex{Regex: "(foo|bar)"}},
+				},
+				QueryParams: map[string]*networking.StringMatch{
+					"param": {MatchType: &networking.StringMatch_Regex{Regex: "[a-z]+"}},
+				},
+			}},
+		}, valid: true},
+		{name: "uri regex with lookahead", route: &networking.HTTPRoute{
+			Route: []*networking.HTTPRouteDestination{{
+				Destination: &networking.Destination{Host: "foo.bar"},
+			}},
+			Match: []*networking.HTTPMatchRequest{{
+				Uri: &networking.StringMatch{MatchType: &networking.StringMatch_Regex{Regex: "/api/(?!admin).*"}},
+			}},
+		}, valid: false},
+		{name: "header regex with backreference", route: &networking.HTTPRoute{
+			Route: []*networking.HTTPRouteDestination{{
+				Destination: &networking.Destination{Host: "foo.bar"},
+			}},
+			Match: []*networking.HTTPMatchRequest{{
+				Headers: map[string]*networking.StringMatch{
+					"header": {MatchType: &networking.StringMatch_Regex{Regex: "(a)\\1"}},
+				},
+			}},
+		}, valid: false},
+		{name: "null query param match", route: &networking.HTTPRoute{
+			Route: []*networking.HTTPRouteDestination{{
+				Destination: &networking.Destination{Host: "foo.bar"},
+			}},
+			Match: []*networking.HTTPMatchRequest{{
+				QueryParams: map[string]*networking.StringMatch{
+					"param": nil,
+				},
+			}},
+		}, valid: false},
 	}
 
 	for _, tc := range testCases {
@@ -2478,6 +2615,50 @@ func TestValidateVirtualService(t *testing.T) {
 	}
 }
 
+func TestValidateHostDelegations(t *testing.T) {
+	defer host.SetDelegated("", nil)
+	host.SetDelegated("istio-system", host.Delegations{"*.payments.example.com": {"payments": true}})
+
+	gateway := &networking.Gateway{
+		Servers: []*networking.Server{{
+			Hosts: []string{"./api.payments.example.com"},
+			Port:  &networking.Port{Name: "http", Number: 80, Protocol: "HTTP"},
+		}},
+	}
+	virtualService := &networking.VirtualService{
+		Hosts: []string{"api.payments.example.com"},
+		Http: []*networking.HTTPRoute{{
+			Route: []*networking.HTTPRouteDestination{{
+				Destination: &networking.Destination{Host: "api.payments.svc.cluster.local"},
+			}},
+		}},
+	}
+
+	if err := ValidateGateway("gateway", "payments", gateway); err != nil {
+		t.Errorf("ValidateGateway() in the delegated namespace failed: %v", err)
+	}
+	if err := ValidateGateway("gateway", "shop", gateway); err == nil {
+		t.Errorf("ValidateGateway() in another namespace succeeded, want error")
+	}
+	if err := ValidateVirtualService("vs", "payments", virtualService); err != nil {
+		t.Errorf("ValidateVirtualService() in the delegated namespace failed: %v", err)
+	}
+	if err := ValidateVirtualService("vs", "shop", virtualService); err == nil {
+		t.Errorf("ValidateVirtualService() in another namespace succeeded, want error")
+	}
+
+	// The stock ingress Gateway of the namespace delegating the domains is not checked.
+	ingress := &networking.Gateway{
+		Servers: []*networking.Server{{
+			Hosts: []string{"*"},
+			Port:  &networking.Port{Name: "http", Number: 80, Protocol: "HTTP"},
+		}},
+	}
+	if err := ValidateGateway("ingressgateway", "istio-system", ingress); err != nil {
+		t.Errorf("ValidateGateway() in the delegating namespace failed: %v", err)
+	}
+}
+
 func TestValidateDestinationRule(t *testing.T) {
 	cases := []struct {
 		name  string
@@ -14,9 +14,13 @@
 
 package visibility
 
-import "fmt"
+import (
+	"fmt"
+	"regexp"
+)
 
-// Instance defines whether a given config or service is exported to local namespace, all namespaces or none
+// Instance defines whether a given config or service is exported to local namespace, all namespaces,
+// a given namespace or none
 type Instance string
 
 const (
@@ -24,14 +28,22 @@ const (
 	Private Instance = "."
 	// Public implies config is visible to all
 	Public Instance = "*"
+	// None implies config is visible to none
+	None Instance = "~"
 )
 
+// namespaceRegexp matches the namespace names, which are DNS-1123 labels.
+var namespaceRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
 // Validate a visibility value.
 func (v Instance) Validate() (errs error) {
 	switch v {
-	case Private, Public:
+	case Private, Public, None:
 		return nil
 	default:
-		return fmt.Errorf("only . or * is allowed in the exportTo in the current release")
+		if len(v) > 63 || !namespaceRegexp.MatchString(string(v)) {
+			return fmt.Errorf("exportTo %q must be ., *, ~ or a namespace name", v)
+		}
+		return nil
 	}
 }
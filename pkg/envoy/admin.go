@@ -15,10 +15,12 @@
 package envoy
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
 	envoyAdmin "github.com/envoyproxy/go-control-plane/envoy/admin/v2alpha"
@@ -61,6 +63,39 @@ func GetConfigDump(adminPort uint32) (*envoyAdmin.ConfigDump, error) {
 	return msg, nil
 }
 
+// GetActiveConnections returns the number of active downstream connections on the listeners of
+// Envoy, excluding the admin listener.
+func GetActiveConnections(adminPort uint32) (int, error) {
+	buffer, err := doEnvoyGet("stats?filter=downstream_cx_active", adminPort)
+	if err != nil {
+		return 0, err
+	}
+	return parseActiveConnections(buffer.String())
+}
+
+// parseActiveConnections sums the listener downstream_cx_active gauges of the text stats output.
+func parseActiveConnections(stats string) (int, error) {
+	active := 0
+	scanner := bufio.NewScanner(strings.NewReader(stats))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		name := strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(name, "listener.") || !strings.HasSuffix(name, ".downstream_cx_active") ||
+			strings.HasPrefix(name, "listener.admin.") {
+			continue
+		}
+		v, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return 0, fmt.Errorf("invalid value for stat %s: %v", name, err)
+		}
+		active += v
+	}
+	return active, scanner.Err()
+}
+
 func doEnvoyGet(path string, adminPort uint32) (*bytes.Buffer, error) {
 	requestURL := fmt.Sprintf("http://127.0.0.1:%d/%s", adminPort, path)
 	buffer, err := doHTTPGet(requestURL)
@@ -0,0 +1,34 @@
+//  Copyright 2018 Istio Authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package envoy
+
+import (
+	"testing"
+)
+
+func TestParseActiveConnections(t *testing.T) {
+	stats := `listener.0.0.0.0_15001.downstream_cx_active: 2
+listener.10.0.0.1_8080.downstream_cx_active: 3
+listener.admin.downstream_cx_active: 1
+http.inbound_0.0.0.0_8080.downstream_cx_active: 7
+`
+	active, err := parseActiveConnections(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active != 5 {
+		t.Errorf("got %d active connections, want 5", active)
+	}
+}
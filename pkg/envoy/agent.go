@@ -130,6 +130,15 @@ type Proxy interface {
 // DrainConfig is used to signal to the Proxy that it should start draining connections
 type DrainConfig struct{}
 
+// ConnectionCounter is optionally implemented by a Proxy to report its active downstream
+// connections. The agent then ends the termination drain period as soon as all connections are closed.
+type ConnectionCounter interface {
+	ActiveConnections() (int, error)
+}
+
+// drainPollInterval is the interval at which active connections are checked while draining.
+var drainPollInterval = time.Second
+
 type agent struct {
 	// proxy commands
 	proxy Proxy
@@ -266,11 +275,43 @@ func (a *agent) terminate() {
 	a.desiredConfig = DrainConfig{}
 	a.reconcile()
 	log.Infof("Graceful termination period is %v, starting...", a.terminationDrainDuration)
-	time.Sleep(a.terminationDrainDuration)
+	a.waitForDrain()
 	log.Infof("Graceful termination period complete, terminating remaining proxies.")
 	a.abortAll()
 }
 
+// waitForDrain waits for the termination drain duration, returning early once the proxy
+// reports that no connection is left.
+func (a *agent) waitForDrain() {
+	counter, ok := a.proxy.(ConnectionCounter)
+	if !ok {
+		time.Sleep(a.terminationDrainDuration)
+		return
+	}
+	deadline := time.Now().Add(a.terminationDrainDuration)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return
+		}
+		if remaining > drainPollInterval {
+			remaining = drainPollInterval
+		}
+		time.Sleep(remaining)
+
+		active, err := counter.ActiveConnections()
+		if err != nil {
+			log.Debugf("Failed to get active connections: %v", err)
+			continue
+		}
+		if active == 0 {
+			log.Infof("All connections drained")
+			return
+		}
+		log.Debugf("Waiting for %d active connections to drain", active)
+	}
+}
+
 func (a *agent) reconcile() {
 	// cancel any scheduled restart
 	a.retry.restart = nil
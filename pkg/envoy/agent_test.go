@@ -427,3 +427,35 @@ func TestLockup(t *testing.T) {
 		t.Error("liveness check failed")
 	}
 }
+
+type drainingProxy struct {
+	TestProxy
+	active []int
+}
+
+func (p *drainingProxy) ActiveConnections() (int, error) {
+	if len(p.active) == 0 {
+		return 0, errors.New("no stats")
+	}
+	n := p.active[0]
+	p.active = p.active[1:]
+	return n, nil
+}
+
+// TestDrainEndsWhenConnectionsClose verifies that the termination drain period ends as soon as the
+// proxy reports that its connections are closed.
+func TestDrainEndsWhenConnectionsClose(t *testing.T) {
+	defer func(interval time.Duration) { drainPollInterval = interval }(drainPollInterval)
+	drainPollInterval = time.Millisecond
+
+	proxy := &drainingProxy{active: []int{3, 1, 0, 5}}
+	a := NewAgent(proxy, testRetry, time.Minute).(*agent)
+	start := time.Now()
+	a.waitForDrain()
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("drain took %v, expected to end once connections closed", elapsed)
+	}
+	if len(proxy.active) != 1 {
+		t.Errorf("expected the drain to stop polling once connections closed, %d polls left", len(proxy.active))
+	}
+}
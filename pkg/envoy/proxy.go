@@ -107,6 +107,11 @@ func (e *envoy) args(fname string, epoch int, bootstrapConfig string) []string {
 	return startupArgs
 }
 
+// ActiveConnections implements ConnectionCounter.
+func (e *envoy) ActiveConnections() (int, error) {
+	return GetActiveConnections(uint32(e.config.ProxyAdminPort))
+}
+
 var istioBootstrapOverrideVar = env.RegisterStringVar("ISTIO_BOOTSTRAP_OVERRIDE", "", "")
 
 func (e *envoy) Run(config interface{}, epoch int, abort <-chan error) error {
@@ -0,0 +1,66 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"istio.io/istio/pkg/config/host"
+	"istio.io/pkg/log"
+)
+
+// WatchHostDelegations keeps the host delegations in force in sync with the istio-host-delegations
+// ConfigMap of the namespace, until stop is closed. Invalid delegations are ignored, keeping the
+// previous ones.
+func WatchHostDelegations(client kubernetes.Interface, namespace string, stop <-chan struct{}) {
+	selector := fields.OneTermEqualSelector("metadata.name", host.DelegationsConfigMapName).String()
+	update := func(obj interface{}) {
+		cm, ok := obj.(*v1.ConfigMap)
+		if !ok || cm.Name != host.DelegationsConfigMapName {
+			return
+		}
+		d, err := host.ParseDelegations(cm.Data[host.DelegationsConfigMapKey])
+		if err != nil {
+			log.Errorf("ignoring invalid host delegations of ConfigMap %s/%s: %v", cm.Namespace, cm.Name, err)
+			return
+		}
+		host.SetDelegated(namespace, d)
+	}
+	_, informer := cache.NewInformer(
+		&cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				opts.FieldSelector = selector
+				return client.CoreV1().ConfigMaps(namespace).List(opts)
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				opts.FieldSelector = selector
+				return client.CoreV1().ConfigMaps(namespace).Watch(opts)
+			},
+		},
+		&v1.ConfigMap{}, 0,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc:    update,
+			UpdateFunc: func(_, obj interface{}) { update(obj) },
+			DeleteFunc: func(interface{}) { host.SetDelegated(namespace, nil) },
+		},
+	)
+	go informer.Run(stop)
+}
@@ -0,0 +1,53 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/pkg/config/host"
+)
+
+func TestWatchHostDelegations(t *testing.T) {
+	defer host.SetDelegated("", nil)
+	client := fake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: host.DelegationsConfigMapName, Namespace: "istio-system"},
+		Data:       map[string]string{host.DelegationsConfigMapKey: "*.payments.example.com=payments"},
+	})
+	stop := make(chan struct{})
+	defer close(stop)
+	WatchHostDelegations(client, "istio-system", stop)
+
+	claimed := func() bool { return host.CheckDelegatedClaim("shop", "api.payments.example.com") != nil }
+	for deadline := time.Now().Add(5 * time.Second); !claimed(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the delegations of the ConfigMap were not applied")
+		}
+	}
+
+	if err := client.CoreV1().ConfigMaps("istio-system").Delete(host.DelegationsConfigMapName, nil); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); claimed(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the delegations were kept after the ConfigMap was deleted")
+		}
+	}
+}
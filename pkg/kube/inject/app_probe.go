@@ -105,8 +105,18 @@ func extractStatusPort(sidecar *corev1.Container) int {
 }
 
 // convertAppProber returns a overwritten `HTTPGetAction` for pilot agent to take over.
+// Both HTTP and TCP socket probers are converted; the latter become HTTP probers served by the agent.
 func convertAppProber(probe *corev1.Probe, newURL string, statusPort int) *corev1.HTTPGetAction {
-	if probe == nil || probe.HTTPGet == nil {
+	if probe == nil {
+		return nil
+	}
+	if probe.TCPSocket != nil && probe.HTTPGet == nil {
+		return &corev1.HTTPGetAction{
+			Path: newURL,
+			Port: intstr.FromInt(statusPort),
+		}
+	}
+	if probe.HTTPGet == nil {
 		return nil
 	}
 	c := probe.HTTPGet.DeepCopy()
@@ -126,19 +136,37 @@ func convertAppProber(probe *corev1.Probe, newURL string, statusPort int) *corev
 // Also update the probers so that all usages of named port will be resolved to integer.
 func DumpAppProbers(podspec *corev1.PodSpec) string {
 	out := status.KubeAppProbers{}
-	updateNamedPort := func(p *corev1.Probe, portMap map[string]int32) *corev1.HTTPGetAction {
-		if p == nil || p.HTTPGet == nil {
+	resolveNamedPort := func(port *intstr.IntOrString, portMap map[string]int32) bool {
+		if port.Type == intstr.String {
+			p, exists := portMap[port.StrVal]
+			if !exists {
+				return false
+			}
+			*port = intstr.FromInt(int(p))
+		}
+		return true
+	}
+	updateNamedPort := func(p *corev1.Probe, portMap map[string]int32) *status.Prober {
+		if p == nil {
 			return nil
 		}
-		h := p.HTTPGet
-		if h.Port.Type == intstr.String {
-			port, exists := portMap[h.Port.StrVal]
-			if !exists {
+		prober := &status.Prober{TimeoutSeconds: p.TimeoutSeconds}
+		switch {
+		case p.HTTPGet != nil:
+			if !resolveNamedPort(&p.HTTPGet.Port, portMap) {
+				return nil
+			}
+			prober.HTTPGetAction = *p.HTTPGet
+		case p.TCPSocket != nil:
+			if !resolveNamedPort(&p.TCPSocket.Port, portMap) {
 				return nil
 			}
-			h.Port = intstr.FromInt(int(port))
+			prober.Port = p.TCPSocket.Port
+			prober.TCPSocket = true
+		default:
+			return nil
 		}
-		return h
+		return prober
 	}
 	for _, c := range podspec.Containers {
 		if c.Name == ProxyContainerName {
@@ -166,6 +194,15 @@ func DumpAppProbers(podspec *corev1.PodSpec) string {
 	return string(b)
 }
 
+// rewriteSidecarReadinessProbe points the readiness probe of the sidecar to the aggregated health
+// of the agent, which also covers the readiness of the application taken over by the agent, the
+// xDS connection and the validity of the certificates. Custom probes of the template are kept.
+func rewriteSidecarReadinessProbe(sidecar *corev1.Container) {
+	if p := sidecar.ReadinessProbe; p != nil && p.HTTPGet != nil && p.HTTPGet.Path == status.ReadyPath {
+		p.HTTPGet.Path = status.HealthPath
+	}
+}
+
 // rewriteAppHTTPProbes modifies the app probers in place for kube-inject.
 func rewriteAppHTTPProbe(annotations map[string]string, podSpec *corev1.PodSpec, spec *SidecarInjectionSpec) {
 	if !ShouldRewriteAppHTTPProbers(annotations, spec) {
@@ -185,6 +222,7 @@ func rewriteAppHTTPProbe(annotations map[string]string, podSpec *corev1.PodSpec,
 		// We don't have to escape json encoding here when using golang libraries.
 		sidecar.Env = append(sidecar.Env, corev1.EnvVar{Name: status.KubeAppProberEnvName, Value: prober})
 	}
+	rewriteSidecarReadinessProbe(sidecar)
 	// Now modify the container probers.
 	for _, c := range podSpec.Containers {
 		// Skip sidecar container.
@@ -193,14 +231,39 @@ func rewriteAppHTTPProbe(annotations map[string]string, podSpec *corev1.PodSpec,
 		}
 		readyz, livez := status.FormatProberURL(c.Name)
 		if hg := convertAppProber(c.ReadinessProbe, readyz, statusPort); hg != nil {
-			*c.ReadinessProbe.HTTPGet = *hg
+			c.ReadinessProbe.TCPSocket = nil
+			c.ReadinessProbe.HTTPGet = hg
 		}
 		if hg := convertAppProber(c.LivenessProbe, livez, statusPort); hg != nil {
-			*c.LivenessProbe.HTTPGet = *hg
+			c.LivenessProbe.TCPSocket = nil
+			c.LivenessProbe.HTTPGet = hg
 		}
 	}
 }
 
+// probeRewritePatch generates the patch replacing the prober at path with the given HTTP prober.
+// TCP socket probers are removed, as a prober can only have a single handler.
+func probeRewritePatch(probe *corev1.Probe, after *corev1.HTTPGetAction, path string) []rfc6902PatchOperation {
+	if probe.HTTPGet != nil {
+		return []rfc6902PatchOperation{{
+			Op:    "replace",
+			Path:  path + "/httpGet",
+			Value: *after,
+		}}
+	}
+	return []rfc6902PatchOperation{
+		{
+			Op:   "remove",
+			Path: path + "/tcpSocket",
+		},
+		{
+			Op:    "add",
+			Path:  path + "/httpGet",
+			Value: *after,
+		},
+	}
+}
+
 // createProbeRewritePatch generates the patch for webhook.
 func createProbeRewritePatch(annotations map[string]string, podSpec *corev1.PodSpec, spec *SidecarInjectionSpec) []rfc6902PatchOperation {
 	if !ShouldRewriteAppHTTPProbers(annotations, spec) {
@@ -216,6 +279,11 @@ func createProbeRewritePatch(annotations map[string]string, podSpec *corev1.PodS
 	if statusPort == -1 {
 		return nil
 	}
+	// Injected containers placed in front of the application shift the application container indices.
+	offset := 0
+	if spec.HoldApplicationUntilProxyStarts {
+		offset = len(spec.Containers)
+	}
 	for i, c := range podSpec.Containers {
 		// Skip sidecar container.
 		if c.Name == ProxyContainerName {
@@ -227,18 +295,10 @@ func createProbeRewritePatch(annotations map[string]string, podSpec *corev1.PodS
 		}
 		readyz, livez := status.FormatProberURL(c.Name)
 		if after := convertAppProber(c.ReadinessProbe, readyz, statusPort); after != nil {
-			patch = append(patch, rfc6902PatchOperation{
-				Op:    "replace",
-				Path:  fmt.Sprintf("/spec/containers/%v/readinessProbe/httpGet", i),
-				Value: *after,
-			})
+			patch = append(patch, probeRewritePatch(c.ReadinessProbe, after, fmt.Sprintf("/spec/containers/%v/readinessProbe", i+offset))...)
 		}
 		if after := convertAppProber(c.LivenessProbe, livez, statusPort); after != nil {
-			patch = append(patch, rfc6902PatchOperation{
-				Op:    "replace",
-				Path:  fmt.Sprintf("/spec/containers/%v/livenessProbe/httpGet", i),
-				Value: *after,
-			})
+			patch = append(patch, probeRewritePatch(c.LivenessProbe, after, fmt.Sprintf("/spec/containers/%v/livenessProbe", i+offset))...)
 		}
 	}
 	return patch
@@ -19,6 +19,7 @@ import (
 	"istio.io/api/annotation"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func TestFindSidecar(t *testing.T) {
@@ -109,3 +110,48 @@ func TestShouldRewriteAppHTTPProbers(t *testing.T) {
 		}
 	}
 }
+
+func TestDumpAppProbersTCP(t *testing.T) {
+	podSpec := &corev1.PodSpec{
+		Containers: []corev1.Container{
+			{
+				Name:  "db",
+				Ports: []corev1.ContainerPort{{Name: "mysql", ContainerPort: 3306}},
+				ReadinessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString("mysql")},
+					},
+					TimeoutSeconds: 3,
+				},
+			},
+		},
+	}
+	want := `{"/app-health/db/readyz":{"port":3306,"tcpSocket":true,"timeoutSeconds":3}}`
+	if got := DumpAppProbers(podSpec); got != want {
+		t.Errorf("DumpAppProbers() got %v, want %v", got, want)
+	}
+
+	after := convertAppProber(podSpec.Containers[0].ReadinessProbe, "/app-health/db/readyz", 15020)
+	if after == nil || after.Path != "/app-health/db/readyz" || after.Port.IntValue() != 15020 {
+		t.Errorf("convertAppProber() got %v, want HTTP prober on the status port", after)
+	}
+}
+
+func TestRewriteSidecarReadinessProbe(t *testing.T) {
+	probe := func(path string) *corev1.Probe {
+		return &corev1.Probe{Handler: corev1.Handler{HTTPGet: &corev1.HTTPGetAction{Path: path, Port: intstr.FromInt(15020)}}}
+	}
+	sidecar := &corev1.Container{Name: ProxyContainerName, ReadinessProbe: probe("/healthz/ready")}
+	rewriteSidecarReadinessProbe(sidecar)
+	if got := sidecar.ReadinessProbe.HTTPGet.Path; got != "/healthz/status" {
+		t.Errorf("got readiness path %q, want /healthz/status", got)
+	}
+
+	// Custom probes of the template are kept.
+	sidecar.ReadinessProbe = probe("/custom")
+	rewriteSidecarReadinessProbe(sidecar)
+	if got := sidecar.ReadinessProbe.HTTPGet.Path; got != "/custom" {
+		t.Errorf("got readiness path %q, want /custom", got)
+	}
+	rewriteSidecarReadinessProbe(&corev1.Container{Name: ProxyContainerName})
+}
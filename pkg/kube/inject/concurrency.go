@@ -29,6 +29,10 @@ import (
 const (
 	// concurrencyCmdFlagName
 	concurrencyCmdFlagName = "concurrency"
+
+	// minConcurrency is the lowest number of worker threads inferred from the cpu resources, so that
+	// one busy connection does not stall all the others on small pods.
+	minConcurrency = 2
 )
 
 var (
@@ -71,8 +75,9 @@ func extractConcurrency(sidecar *corev1.Container) int {
 	return 0
 }
 
-// applyConcurrency changes sidecar containers' concurrency to equals the cpu cores of the container
-// if not set. It is inferred from the container's resource limit or request.
+// applyConcurrency changes sidecar containers' concurrency to equals the cpu cores of the container,
+// rounded up and at least minConcurrency, if not set. It is inferred from the container's resource
+// limit or request.
 func applyConcurrency(containers []corev1.Container) {
 	for i, c := range containers {
 		if c.Name == ProxyContainerName {
@@ -96,6 +101,9 @@ func updateConcurrency(container *corev1.Container, cpumillis int64) bool {
 	cpu := float64(cpumillis) / 1000
 	concurrency := int(math.Ceil(cpu))
 	if concurrency > 0 {
+		if concurrency < minConcurrency {
+			concurrency = minConcurrency
+		}
 		container.Args = append(container.Args, []string{fmt.Sprintf("--%s", concurrencyCmdFlagName), strconv.Itoa(concurrency)}...)
 		return true
 	}
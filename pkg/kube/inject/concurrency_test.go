@@ -94,7 +94,7 @@ func TestApplyConcurrency(t *testing.T) {
 			want: []corev1.Container{
 				{
 					Name: "istio-proxy",
-					Args: []string{"--foo", "--concurrency", "1"},
+					Args: []string{"--foo", "--concurrency", "2"},
 					Resources: corev1.ResourceRequirements{
 						Requests: corev1.ResourceList{
 							corev1.ResourceMemory: resource.MustParse("1G"),
@@ -108,6 +108,31 @@ func TestApplyConcurrency(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "apply concurrency rounded up from resource limit",
+			original: []corev1.Container{
+				{
+					Name: "istio-proxy",
+					Args: []string{"--foo"},
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU: resource.MustParse("2500m"),
+						},
+					},
+				},
+			},
+			want: []corev1.Container{
+				{
+					Name: "istio-proxy",
+					Args: []string{"--foo", "--concurrency", "3"},
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU: resource.MustParse("2500m"),
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "no concurrency without cpu resource request/limit",
 			original: []corev1.Container{
@@ -0,0 +1,52 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// TerminationDrainDurationAnnotation sets how long the proxy keeps draining connections once the pod is
+	// deleted, e.g. "30s". Overrides global.proxy.terminationDrainDuration.
+	TerminationDrainDurationAnnotation = "sidecar.istio.io/terminationDrainDuration"
+
+	// ExitOnApplicationExitAnnotation makes the proxy terminate once all application containers have
+	// exited, so that Jobs complete. The pod then shares a single process namespace.
+	ExitOnApplicationExitAnnotation = "sidecar.istio.io/exitOnApplicationExit"
+)
+
+// validateDuration validates that the given annotation value is a positive duration.
+func validateDuration(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if d < 0 {
+		return fmt.Errorf("duration must not be negative: %v", value)
+	}
+	return nil
+}
+
+// durationSeconds converts a duration such as "1m30s" to a number of seconds, as expected by the
+// agent environment. Invalid durations yield an empty string.
+func durationSeconds(value interface{}) string {
+	d, err := time.ParseDuration(fmt.Sprint(value))
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprint(int64(d / time.Second))
+}
@@ -0,0 +1,44 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import "testing"
+
+func TestDurationSeconds(t *testing.T) {
+	cases := map[string]string{
+		"30s":    "30",
+		"1m30s":  "90",
+		"1500ms": "1",
+		"bad":    "",
+	}
+	for in, want := range cases {
+		if got := durationSeconds(in); got != want {
+			t.Errorf("durationSeconds(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestValidateDuration(t *testing.T) {
+	for _, valid := range []string{"0s", "5s", "1m"} {
+		if err := validateDuration(valid); err != nil {
+			t.Errorf("validateDuration(%q) unexpected error: %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{"", "5", "-1s"} {
+		if err := validateDuration(invalid); err == nil {
+			t.Errorf("validateDuration(%q) expected error", invalid)
+		}
+	}
+}
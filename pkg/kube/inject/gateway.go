@@ -0,0 +1,59 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// GatewayInjectionAnnotation marks a pod as a gateway. The injector then provides a complete gateway
+// proxy instead of a sidecar: the proxy runs in router mode and no traffic redirect is set up.
+//
+// Since Kubernetes requires at least one container, the pod declares a placeholder container named
+// istio-proxy, typically with just the gateway ports. The placeholder is replaced by the injected
+// proxy, which inherits its ports and environment.
+const GatewayInjectionAnnotation = "sidecar.istio.io/gateway"
+
+// mergeGatewayProxy copies the ports and environment of the istio-proxy placeholder found in existing
+// into the injected proxy container, and returns the existing containers without the placeholder.
+func mergeGatewayProxy(injected, existing []corev1.Container) []corev1.Container {
+	proxy := FindSidecar(injected)
+	if proxy == nil {
+		return existing
+	}
+	remaining := make([]corev1.Container, 0, len(existing))
+	for _, c := range existing {
+		if c.Name != ProxyContainerName {
+			remaining = append(remaining, c)
+			continue
+		}
+		for _, p := range c.Ports {
+			if !hasContainerPort(proxy.Ports, p) {
+				proxy.Ports = append(proxy.Ports, p)
+			}
+		}
+		proxy.Env = append(proxy.Env, c.Env...)
+	}
+	return remaining
+}
+
+func hasContainerPort(ports []corev1.ContainerPort, port corev1.ContainerPort) bool {
+	for _, p := range ports {
+		if p.ContainerPort == port.ContainerPort && p.Protocol == port.Protocol {
+			return true
+		}
+	}
+	return false
+}
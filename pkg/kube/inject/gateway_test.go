@@ -0,0 +1,64 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestMergeGatewayProxy(t *testing.T) {
+	injected := []corev1.Container{{
+		Name:  ProxyContainerName,
+		Ports: []corev1.ContainerPort{{Name: "http-envoy-prom", ContainerPort: 15090, Protocol: corev1.ProtocolTCP}},
+		Env:   []corev1.EnvVar{{Name: "POD_NAME"}},
+	}}
+	existing := []corev1.Container{
+		{
+			Name:  ProxyContainerName,
+			Image: "auto",
+			Ports: []corev1.ContainerPort{
+				{Name: "http2", ContainerPort: 80, Protocol: corev1.ProtocolTCP},
+				{Name: "https", ContainerPort: 443, Protocol: corev1.ProtocolTCP},
+				{Name: "duplicate", ContainerPort: 15090, Protocol: corev1.ProtocolTCP},
+			},
+			Env: []corev1.EnvVar{{Name: "ISTIO_META_ROUTER_MODE", Value: "sni-dnat"}},
+		},
+		{Name: "other"},
+	}
+
+	remaining := mergeGatewayProxy(injected, existing)
+
+	if want := []corev1.Container{{Name: "other"}}; !reflect.DeepEqual(remaining, want) {
+		t.Errorf("got remaining containers %v, want %v", remaining, want)
+	}
+	wantPorts := []corev1.ContainerPort{
+		{Name: "http-envoy-prom", ContainerPort: 15090, Protocol: corev1.ProtocolTCP},
+		{Name: "http2", ContainerPort: 80, Protocol: corev1.ProtocolTCP},
+		{Name: "https", ContainerPort: 443, Protocol: corev1.ProtocolTCP},
+	}
+	if !reflect.DeepEqual(injected[0].Ports, wantPorts) {
+		t.Errorf("got ports %v, want %v", injected[0].Ports, wantPorts)
+	}
+	wantEnv := []corev1.EnvVar{{Name: "POD_NAME"}, {Name: "ISTIO_META_ROUTER_MODE", Value: "sni-dnat"}}
+	if !reflect.DeepEqual(injected[0].Env, wantEnv) {
+		t.Errorf("got env %v, want %v", injected[0].Env, wantEnv)
+	}
+	if injected[0].Image != "" {
+		t.Errorf("placeholder image leaked into the injected proxy: %q", injected[0].Image)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"istio.io/istio/pkg/bootstrap"
+)
+
+// ProxylessGRPCAnnotation marks a pod whose gRPC applications use the xDS resolver and balancer of
+// gRPC instead of a sidecar. The injector then replaces the sidecar with an init container writing
+// the xDS bootstrap of gRPC, which the application containers find through GRPC_XDS_BOOTSTRAP. No
+// traffic redirect is set up.
+const ProxylessGRPCAnnotation = "sidecar.istio.io/proxylessGRPC"
+
+const (
+	grpcBootstrapContainerName = "grpc-bootstrap"
+	grpcBootstrapVolumeName    = "istio-grpc-bootstrap"
+	grpcBootstrapDir           = "/etc/istio/grpc"
+)
+
+// grpcBootstrapPath is the path of the xDS bootstrap in the containers of proxyless gRPC pods.
+var grpcBootstrapPath = path.Join(grpcBootstrapDir, "bootstrap.json")
+
+// applyProxylessGRPC replaces the injected sidecar with the init container writing the gRPC xDS
+// bootstrap. The init container runs the agent of the proxy image with the discovery address and the
+// domain of the sidecar, so that the node is identified like a sidecar of the pod.
+func applyProxylessGRPC(sic *SidecarInjectionSpec) {
+	proxy := FindSidecar(sic.Containers)
+	if proxy == nil {
+		return
+	}
+	args := []string{"grpc-bootstrap", "--out", grpcBootstrapPath}
+	for i := 0; i < len(proxy.Args)-1; i++ {
+		if proxy.Args[i] == "--discoveryAddress" || proxy.Args[i] == "--domain" {
+			args = append(args, proxy.Args[i], proxy.Args[i+1])
+		}
+	}
+	sic.InitContainers = []corev1.Container{{
+		Name:            grpcBootstrapContainerName,
+		Image:           proxy.Image,
+		ImagePullPolicy: proxy.ImagePullPolicy,
+		Args:            args,
+		Env:             proxy.Env,
+		Resources:       proxy.Resources,
+		VolumeMounts:    []corev1.VolumeMount{{Name: grpcBootstrapVolumeName, MountPath: grpcBootstrapDir}},
+	}}
+	sic.Containers = nil
+	sic.Volumes = []corev1.Volume{{
+		Name:         grpcBootstrapVolumeName,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory}},
+	}}
+	sic.DNSConfig = nil
+	sic.RewriteAppHTTPProbe = false
+	sic.HoldApplicationUntilProxyStarts = false
+	sic.ShareProcessNamespace = false
+	sic.ProxylessGRPC = true
+}
+
+// addGRPCBootstrap mounts the gRPC xDS bootstrap in the application containers and points their
+// GRPC_XDS_BOOTSTRAP variable at it.
+func addGRPCBootstrap(containers []corev1.Container) {
+	for i := range containers {
+		c := &containers[i]
+		if !hasGRPCBootstrapEnv(c) {
+			c.Env = append(c.Env, grpcBootstrapEnv())
+		}
+		if !hasGRPCBootstrapMount(c) {
+			c.VolumeMounts = append(c.VolumeMounts, grpcBootstrapMount())
+		}
+	}
+}
+
+// createGRPCBootstrapPatch returns the patch of the application containers equivalent to
+// addGRPCBootstrap.
+func createGRPCBootstrapPatch(containers []corev1.Container) []rfc6902PatchOperation {
+	var patch []rfc6902PatchOperation
+	for i := range containers {
+		c := &containers[i]
+		if !hasGRPCBootstrapEnv(c) {
+			patch = append(patch, appendPatch(len(c.Env) == 0, fmt.Sprintf("/spec/containers/%d/env", i), grpcBootstrapEnv()))
+		}
+		if !hasGRPCBootstrapMount(c) {
+			patch = append(patch, appendPatch(len(c.VolumeMounts) == 0, fmt.Sprintf("/spec/containers/%d/volumeMounts", i), grpcBootstrapMount()))
+		}
+	}
+	return patch
+}
+
+// appendPatch returns the operation appending value to the list at path, creating the list if empty.
+func appendPatch(empty bool, path string, value interface{}) rfc6902PatchOperation {
+	if empty {
+		return rfc6902PatchOperation{Op: "add", Path: path, Value: []interface{}{value}}
+	}
+	return rfc6902PatchOperation{Op: "add", Path: path + "/-", Value: value}
+}
+
+func grpcBootstrapEnv() corev1.EnvVar {
+	return corev1.EnvVar{Name: bootstrap.GRPCBootstrapEnv, Value: grpcBootstrapPath}
+}
+
+func grpcBootstrapMount() corev1.VolumeMount {
+	return corev1.VolumeMount{Name: grpcBootstrapVolumeName, MountPath: grpcBootstrapDir, ReadOnly: true}
+}
+
+func hasGRPCBootstrapEnv(c *corev1.Container) bool {
+	for _, e := range c.Env {
+		if e.Name == bootstrap.GRPCBootstrapEnv {
+			return true
+		}
+	}
+	return false
+}
+
+func hasGRPCBootstrapMount(c *corev1.Container) bool {
+	for _, m := range c.VolumeMounts {
+		if m.Name == grpcBootstrapVolumeName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestApplyProxylessGRPC(t *testing.T) {
+	sic := &SidecarInjectionSpec{
+		RewriteAppHTTPProbe: true,
+		InitContainers:      []corev1.Container{{Name: "istio-init"}},
+		Containers: []corev1.Container{{
+			Name:  ProxyContainerName,
+			Image: "docker.io/istio/proxyv2:1.4.0",
+			Args: []string{"proxy", "sidecar", "--domain", "$(POD_NAMESPACE).svc.example.com",
+				"--discoveryAddress", "istio-pilot.istio-system:15010", "--concurrency", "2"},
+			Env: []corev1.EnvVar{{Name: "POD_NAME"}, {Name: "POD_NAMESPACE"}, {Name: "INSTANCE_IP"}},
+		}},
+		Volumes:   []corev1.Volume{{Name: "istio-envoy"}},
+		DNSConfig: &corev1.PodDNSConfig{},
+	}
+
+	applyProxylessGRPC(sic)
+
+	if !sic.ProxylessGRPC || len(sic.Containers) != 0 || sic.DNSConfig != nil || sic.RewriteAppHTTPProbe {
+		t.Fatalf("expected the sidecar to be removed, got %+v", sic)
+	}
+	if len(sic.InitContainers) != 1 {
+		t.Fatalf("expected only the bootstrap init container, got %v", sic.InitContainers)
+	}
+	init := sic.InitContainers[0]
+	wantArgs := []string{"grpc-bootstrap", "--out", "/etc/istio/grpc/bootstrap.json",
+		"--domain", "$(POD_NAMESPACE).svc.example.com", "--discoveryAddress", "istio-pilot.istio-system:15010"}
+	if !reflect.DeepEqual(init.Args, wantArgs) {
+		t.Errorf("got args %v, want %v", init.Args, wantArgs)
+	}
+	if init.Image != "docker.io/istio/proxyv2:1.4.0" || len(init.Env) != 3 {
+		t.Errorf("expected the image and environment of the sidecar, got %+v", init)
+	}
+	if len(sic.Volumes) != 1 || sic.Volumes[0].Name != grpcBootstrapVolumeName {
+		t.Errorf("expected only the bootstrap volume, got %v", sic.Volumes)
+	}
+}
+
+func TestGRPCBootstrap(t *testing.T) {
+	containers := []corev1.Container{
+		{Name: "app"},
+		{
+			Name:         "other",
+			Env:          []corev1.EnvVar{{Name: "FOO"}},
+			VolumeMounts: []corev1.VolumeMount{{Name: "data"}},
+		},
+	}
+
+	patch, err := json.Marshal(createGRPCBootstrapPatch(containers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[` +
+		`{"op":"add","path":"/spec/containers/0/env","value":[{"name":"GRPC_XDS_BOOTSTRAP","value":"/etc/istio/grpc/bootstrap.json"}]},` +
+		`{"op":"add","path":"/spec/containers/0/volumeMounts","value":[{"name":"istio-grpc-bootstrap","readOnly":true,"mountPath":"/etc/istio/grpc"}]},` +
+		`{"op":"add","path":"/spec/containers/1/env/-","value":{"name":"GRPC_XDS_BOOTSTRAP","value":"/etc/istio/grpc/bootstrap.json"}},` +
+		`{"op":"add","path":"/spec/containers/1/volumeMounts/-","value":{"name":"istio-grpc-bootstrap","readOnly":true,"mountPath":"/etc/istio/grpc"}}` +
+		`]`
+	if string(patch) != want {
+		t.Errorf("got patch %s, want %s", patch, want)
+	}
+
+	addGRPCBootstrap(containers)
+	if len(createGRPCBootstrapPatch(containers)) != 0 {
+		t.Errorf("expected the bootstrap to be added once, got %+v", containers)
+	}
+	if len(containers[1].Env) != 2 || len(containers[1].VolumeMounts) != 2 {
+		t.Errorf("expected the bootstrap to be appended, got %+v", containers[1])
+	}
+}
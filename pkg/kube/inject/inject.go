@@ -66,6 +66,14 @@ var (
 		annotation.SidecarProxyImage.Name:                         alwaysValidFunc,
 		annotation.SidecarProxyCPU.Name:                           alwaysValidFunc,
 		annotation.SidecarProxyMemory.Name:                        alwaysValidFunc,
+		HoldApplicationUntilProxyStartsAnnotation:                 validateBool,
+		GatewayInjectionAnnotation:                                validateBool,
+		ProxylessGRPCAnnotation:                                   validateBool,
+		TerminationDrainDurationAnnotation:                        validateDuration,
+		ExitOnApplicationExitAnnotation:                           validateBool,
+		ProxyConfigAnnotation:                                     validateProxyConfigOverlay,
+		ProxyCPULimitAnnotation:                                   validateResourceQuantity,
+		ProxyMemoryLimitAnnotation:                                validateResourceQuantity,
 		annotation.SidecarInterceptionMode.Name:                   validateInterceptionMode,
 		annotation.SidecarBootstrapOverride.Name:                  alwaysValidFunc,
 		annotation.SidecarStatsInclusionPrefixes.Name:             alwaysValidFunc,
@@ -137,6 +145,10 @@ const (
 const (
 	// ProxyContainerName is used by e2e integration tests for fetching logs
 	ProxyContainerName = "istio-proxy"
+
+	// HoldApplicationUntilProxyStartsAnnotation controls whether the application containers are held
+	// until the injected proxy is ready. Overrides global.proxy.holdApplicationUntilProxyStarts.
+	HoldApplicationUntilProxyStartsAnnotation = "sidecar.istio.io/holdApplicationUntilProxyStarts"
 )
 
 // SidecarInjectionSpec collects all container types and volumes for
@@ -144,13 +156,26 @@ const (
 type SidecarInjectionSpec struct {
 	// RewriteHTTPProbe indicates whether Kubernetes HTTP prober in the PodSpec
 	// will be rewritten to be redirected by pilot agent.
-	PodRedirectAnnot    map[string]string             `yaml:"podRedirectAnnot"`
-	RewriteAppHTTPProbe bool                          `yaml:"rewriteAppHTTPProbe"`
-	InitContainers      []corev1.Container            `yaml:"initContainers"`
-	Containers          []corev1.Container            `yaml:"containers"`
-	Volumes             []corev1.Volume               `yaml:"volumes"`
-	DNSConfig           *corev1.PodDNSConfig          `yaml:"dnsConfig"`
-	ImagePullSecrets    []corev1.LocalObjectReference `yaml:"imagePullSecrets"`
+	PodRedirectAnnot    map[string]string `yaml:"podRedirectAnnot"`
+	RewriteAppHTTPProbe bool              `yaml:"rewriteAppHTTPProbe"`
+	// HoldApplicationUntilProxyStarts indicates whether the injected containers are placed in
+	// front of the application containers, so that the kubelet only starts the application once
+	// the proxy's postStart hook has returned.
+	HoldApplicationUntilProxyStarts bool `yaml:"holdApplicationUntilProxyStarts"`
+	// Gateway indicates that the injected proxy is a standalone gateway replacing the
+	// istio-proxy placeholder container of the pod.
+	Gateway bool `yaml:"gateway"`
+	// ProxylessGRPC indicates that the sidecar was replaced with the init container writing the xDS
+	// bootstrap of the gRPC applications of the pod, which have to mount it.
+	ProxylessGRPC bool `yaml:"-"`
+	// ShareProcessNamespace indicates whether the pod containers share a single process namespace,
+	// which lets the agent detect that the application containers have exited.
+	ShareProcessNamespace bool                          `yaml:"shareProcessNamespace"`
+	InitContainers        []corev1.Container            `yaml:"initContainers"`
+	Containers            []corev1.Container            `yaml:"containers"`
+	Volumes               []corev1.Volume               `yaml:"volumes"`
+	DNSConfig             *corev1.PodDNSConfig          `yaml:"dnsConfig"`
+	ImagePullSecrets      []corev1.LocalObjectReference `yaml:"imagePullSecrets"`
 }
 
 // SidecarTemplateData is the data object to which the templated
@@ -163,6 +188,9 @@ type SidecarTemplateData struct {
 	ProxyConfig    *meshconfig.ProxyConfig
 	MeshConfig     *meshconfig.MeshConfig
 	Values         map[string]interface{}
+	// BootstrapPatch is the JSON merge patch of the Envoy bootstrap of the workload, merging the
+	// bootstrapPatch of its ProxyConfigAnnotation onto global.proxy.bootstrapPatch.
+	BootstrapPatch string
 }
 
 // InitImageName returns the fully qualified image name for the istio
@@ -399,6 +427,12 @@ func validateStatusPort(port string) error {
 	return nil
 }
 
+// validateBool validates that the given annotation value is a boolean.
+func validateBool(value string) error {
+	_, err := strconv.ParseBool(value)
+	return err
+}
+
 // validateUInt32 validates that the given annotation value is a positive integer.
 func validateUInt32(value string) error {
 	_, err := strconv.ParseUint(value, 10, 32)
@@ -540,6 +574,14 @@ func flippedContains(needle, haystack string) bool {
 func InjectionData(sidecarTemplate, valuesConfig, version string, typeMetadata *metav1.TypeMeta, deploymentMetadata *metav1.ObjectMeta, spec *corev1.PodSpec,
 	metadata *metav1.ObjectMeta, proxyConfig *meshconfig.ProxyConfig, meshConfig *meshconfig.MeshConfig) (
 	*SidecarInjectionSpec, string, error) {
+	return injectionData(sidecarTemplate, valuesConfig, version, typeMetadata, deploymentMetadata, spec, metadata, proxyConfig, meshConfig, nil)
+}
+
+// injectionData renders sidecarTemplate with valuesConfig, applying the resource overrides found in
+// the labels of the pod's namespace.
+func injectionData(sidecarTemplate, valuesConfig, version string, typeMetadata *metav1.TypeMeta, deploymentMetadata *metav1.ObjectMeta, spec *corev1.PodSpec,
+	metadata *metav1.ObjectMeta, proxyConfig *meshconfig.ProxyConfig, meshConfig *meshconfig.MeshConfig, namespaceLabels map[string]string) (
+	*SidecarInjectionSpec, string, error) {
 
 	// If DNSPolicy is not ClusterFirst, the Envoy sidecar may not able to connect to Istio Pilot.
 	if spec.DNSPolicy != "" && spec.DNSPolicy != corev1.DNSClusterFirst {
@@ -552,11 +594,21 @@ func InjectionData(sidecarTemplate, valuesConfig, version string, typeMetadata *
 		return nil, "", err
 	}
 
+	proxyConfig, workloadPatch, err := workloadProxyConfig(proxyConfig, metadata.GetAnnotations())
+	if err != nil {
+		log.Errorf("Injection failed due to invalid proxy config overrides: %v", err)
+		return nil, "", multierror.Prefix(err, "invalid "+ProxyConfigAnnotation+" annotation:")
+	}
+
 	values := map[string]interface{}{}
 	if err := yaml.Unmarshal([]byte(valuesConfig), &values); err != nil {
 		log.Infof("Failed to parse values config: %v [%v]\n", err, valuesConfig)
 		return nil, "", multierror.Prefix(err, "could not parse configuration values:")
 	}
+	bootstrapPatch, err := mergeBootstrapPatches(values, workloadPatch)
+	if err != nil {
+		return nil, "", err
+	}
 
 	data := SidecarTemplateData{
 		TypeMeta:       typeMetadata,
@@ -566,6 +618,7 @@ func InjectionData(sidecarTemplate, valuesConfig, version string, typeMetadata *
 		ProxyConfig:    proxyConfig,
 		MeshConfig:     meshConfig,
 		Values:         values,
+		BootstrapPatch: bootstrapPatch,
 	}
 
 	funcMap := template.FuncMap{
@@ -586,6 +639,8 @@ func InjectionData(sidecarTemplate, valuesConfig, version string, typeMetadata *
 		"directory":           directory,
 		"contains":            flippedContains,
 		"toLower":             strings.ToLower,
+		"durationSeconds":     durationSeconds,
+		"quote":               strconv.Quote,
 	}
 
 	// Need to use FuncMap and SidecarTemplateData context
@@ -611,9 +666,18 @@ func InjectionData(sidecarTemplate, valuesConfig, version string, typeMetadata *
 		return nil, "", multierror.Prefix(err, "failed parsing generated injected YAML (check Istio sidecar injector configuration):")
 	}
 
+	// override sidecar resources before --concurrency is inferred from them
+	if err := applyResourceOverrides(sic.Containers, metadata.Annotations, namespaceLabels); err != nil {
+		return nil, "", err
+	}
+
 	// set sidecar --concurrency
 	applyConcurrency(sic.Containers)
 
+	if metadata.Annotations[ProxylessGRPCAnnotation] == "true" {
+		applyProxylessGRPC(&sic)
+	}
+
 	status := &SidecarInjectionStatus{Version: version}
 	for _, c := range sic.InitContainers {
 		status.InitContainers = append(status.InitContainers, c.Name)
@@ -802,7 +866,7 @@ func IntoObject(sidecarTemplate string, valuesConfig string, meshconfig *meshcon
 		return out, nil
 	}
 
-	//skip injection for injected pods
+	//skip injection for injected pods, except gateways which only hold the istio-proxy placeholder
 	if len(podSpec.Containers) > 1 {
 		for _, c := range podSpec.Containers {
 			if c.Name == ProxyContainerName {
@@ -829,10 +893,24 @@ func IntoObject(sidecarTemplate string, valuesConfig string, meshconfig *meshcon
 
 	podSpec.InitContainers = append(podSpec.InitContainers, spec.InitContainers...)
 
-	podSpec.Containers = append(podSpec.Containers, spec.Containers...)
+	if spec.Gateway {
+		podSpec.Containers = mergeGatewayProxy(spec.Containers, podSpec.Containers)
+	}
+	if spec.HoldApplicationUntilProxyStarts {
+		podSpec.Containers = append(spec.Containers, podSpec.Containers...)
+	} else {
+		podSpec.Containers = append(podSpec.Containers, spec.Containers...)
+	}
 	podSpec.Volumes = append(podSpec.Volumes, spec.Volumes...)
+	if spec.ProxylessGRPC {
+		addGRPCBootstrap(podSpec.Containers)
+	}
 
 	podSpec.DNSConfig = spec.DNSConfig
+	if spec.ShareProcessNamespace {
+		share := true
+		podSpec.ShareProcessNamespace = &share
+	}
 
 	// Modify application containers' HTTP probe after appending injected containers.
 	// Because we need to extract istio-proxy's statusPort.
@@ -0,0 +1,118 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/ghodss/yaml"
+	"github.com/gogo/protobuf/proto"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+
+	"istio.io/istio/pkg/config/validation"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
+)
+
+// ProxyConfigAnnotation overlays the mesh-wide proxy config (meshConfig.defaultConfig) for the
+// annotated workload. The value is a ProxyConfig in YAML, e.g. "concurrency: 4", and only the
+// fields it sets are changed.
+const ProxyConfigAnnotation = "proxy.istio.io/config"
+
+// bootstrapPatchField is the field of the ProxyConfigAnnotation holding a JSON merge patch (RFC 7386)
+// applied by the agent to the generated Envoy bootstrap, e.g. "bootstrapPatch: {stats_flush_interval: 10s}".
+// ProxyConfig has no such field, so the injector takes it out of the overlay and passes it to the agent,
+// merged onto global.proxy.bootstrapPatch.
+const bootstrapPatchField = "bootstrapPatch"
+
+// validateProxyConfigOverlay validates that the given annotation value is a valid ProxyConfig.
+func validateProxyConfigOverlay(value string) error {
+	_, _, err := applyProxyConfigOverlay(&meshconfig.ProxyConfig{}, value)
+	return err
+}
+
+// applyProxyConfigOverlay returns a copy of proxyConfig with the fields set in overlay replaced, and the
+// bootstrap patch of the overlay in JSON, if any.
+func applyProxyConfigOverlay(proxyConfig *meshconfig.ProxyConfig, overlay string) (*meshconfig.ProxyConfig, string, error) {
+	overlay, patch, err := splitBootstrapPatch(overlay)
+	if err != nil {
+		return nil, "", err
+	}
+	out := proto.Clone(proxyConfig).(*meshconfig.ProxyConfig)
+	if err := gogoprotomarshal.ApplyYAML(overlay, out); err != nil {
+		return nil, "", err
+	}
+	return out, patch, nil
+}
+
+// splitBootstrapPatch returns the overlay without its bootstrap patch, and the patch in JSON.
+func splitBootstrapPatch(overlay string) (string, string, error) {
+	fields := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(overlay), &fields); err != nil {
+		return "", "", err
+	}
+	patch, ok := fields[bootstrapPatchField]
+	if !ok {
+		return overlay, "", nil
+	}
+	if _, ok := patch.(map[string]interface{}); !ok {
+		return "", "", fmt.Errorf("%s must be an object", bootstrapPatchField)
+	}
+	delete(fields, bootstrapPatchField)
+	rest, err := yaml.Marshal(fields)
+	if err != nil {
+		return "", "", err
+	}
+	out, err := json.Marshal(patch)
+	if err != nil {
+		return "", "", err
+	}
+	return string(rest), string(out), nil
+}
+
+// workloadProxyConfig returns the proxy config of a workload: the mesh default overlaid with the
+// workload's ProxyConfigAnnotation, if any, and the bootstrap patch of the overlay.
+func workloadProxyConfig(proxyConfig *meshconfig.ProxyConfig, annotations map[string]string) (*meshconfig.ProxyConfig, string, error) {
+	overlay, ok := annotations[ProxyConfigAnnotation]
+	if !ok || proxyConfig == nil {
+		return proxyConfig, "", nil
+	}
+	out, patch, err := applyProxyConfigOverlay(proxyConfig, overlay)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := validation.ValidateProxyConfig(out); err != nil {
+		return nil, "", err
+	}
+	return out, patch, nil
+}
+
+// mergeBootstrapPatches returns the mesh-wide bootstrap patch, global.proxy.bootstrapPatch, with the
+// workload patch merged onto it.
+func mergeBootstrapPatches(values map[string]interface{}, workloadPatch string) (string, error) {
+	global, _ := values["global"].(map[string]interface{})
+	proxy, _ := global["proxy"].(map[string]interface{})
+	meshPatch, _ := proxy["bootstrapPatch"].(string)
+	if meshPatch == "" || workloadPatch == "" {
+		return meshPatch + workloadPatch, nil
+	}
+	out, err := jsonpatch.MergeMergePatches([]byte(meshPatch), []byte(workloadPatch))
+	if err != nil {
+		return "", fmt.Errorf("invalid global.proxy.bootstrapPatch: %v", err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"testing"
+
+	"istio.io/istio/pkg/config/mesh"
+)
+
+func TestWorkloadProxyConfig(t *testing.T) {
+	defaults := mesh.DefaultProxyConfig()
+
+	got, patch, err := workloadProxyConfig(&defaults, map[string]string{
+		ProxyConfigAnnotation: "concurrency: 4\ndrainDuration: 10s\n",
+	})
+	if err != nil {
+		t.Fatalf("workloadProxyConfig() unexpected error: %v", err)
+	}
+	if got.Concurrency != 4 || got.DrainDuration.Seconds != 10 {
+		t.Errorf("workloadProxyConfig() did not apply the overlay: %v", got)
+	}
+	if got.DiscoveryAddress != defaults.DiscoveryAddress {
+		t.Errorf("workloadProxyConfig() changed fields missing from the overlay: %v", got)
+	}
+	if defaults.Concurrency == 4 {
+		t.Errorf("workloadProxyConfig() modified the mesh default proxy config")
+	}
+	if patch != "" {
+		t.Errorf("workloadProxyConfig() returned a bootstrap patch for an overlay without any: %s", patch)
+	}
+
+	if got, _, _ := workloadProxyConfig(&defaults, nil); got != &defaults {
+		t.Errorf("workloadProxyConfig() without the annotation should return the mesh default")
+	}
+
+	for _, invalid := range []string{"concurrency: four", "drainDuration: -1s", "[", "bootstrapPatch: '{}'", "bootstrapPatch: {}\nunknown: 1"} {
+		if _, _, err := workloadProxyConfig(&defaults, map[string]string{ProxyConfigAnnotation: invalid}); err == nil {
+			t.Errorf("workloadProxyConfig(%q) expected error", invalid)
+		}
+	}
+}
+
+func TestWorkloadBootstrapPatch(t *testing.T) {
+	defaults := mesh.DefaultProxyConfig()
+
+	got, patch, err := workloadProxyConfig(&defaults, map[string]string{
+		ProxyConfigAnnotation: "concurrency: 4\nbootstrapPatch:\n  stats_flush_interval: 10s\n  admin: {access_log_path: /dev/stdout}\n",
+	})
+	if err != nil {
+		t.Fatalf("workloadProxyConfig() unexpected error: %v", err)
+	}
+	if got.Concurrency != 4 {
+		t.Errorf("workloadProxyConfig() did not apply the overlay: %v", got)
+	}
+	if want := `{"admin":{"access_log_path":"/dev/stdout"},"stats_flush_interval":"10s"}`; patch != want {
+		t.Errorf("workloadProxyConfig() got bootstrap patch %s, want %s", patch, want)
+	}
+
+	values := map[string]interface{}{"global": map[string]interface{}{"proxy": map[string]interface{}{
+		"bootstrapPatch": `{"stats_flush_interval": "5s", "admin": {"access_log_path": "/dev/null"}, "tracing": null}`,
+	}}}
+	merged, err := mergeBootstrapPatches(values, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"admin":{"access_log_path":"/dev/stdout"},"stats_flush_interval":"10s","tracing":null}`; merged != want {
+		t.Errorf("mergeBootstrapPatches() got %s, want %s", merged, want)
+	}
+	if merged, _ := mergeBootstrapPatches(map[string]interface{}{}, patch); merged != patch {
+		t.Errorf("mergeBootstrapPatches() without a mesh patch got %s, want %s", merged, patch)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"istio.io/api/annotation"
+)
+
+const (
+	// ProxyCPULimitAnnotation overrides the CPU limit of the injected proxy container.
+	ProxyCPULimitAnnotation = "sidecar.istio.io/proxyCPULimit"
+	// ProxyMemoryLimitAnnotation overrides the memory limit of the injected proxy container.
+	ProxyMemoryLimitAnnotation = "sidecar.istio.io/proxyMemoryLimit"
+)
+
+// resourceOverride maps an annotation (or namespace label) key to the proxy resource it controls.
+type resourceOverride struct {
+	key      string
+	resource corev1.ResourceName
+	limit    bool
+}
+
+// resourceOverrides lists the keys that can override the proxy container resources. The same keys are
+// honored as pod annotations and as namespace labels, with the pod annotation taking precedence.
+var resourceOverrides = []resourceOverride{
+	{key: annotation.SidecarProxyCPU.Name, resource: corev1.ResourceCPU},
+	{key: annotation.SidecarProxyMemory.Name, resource: corev1.ResourceMemory},
+	{key: ProxyCPULimitAnnotation, resource: corev1.ResourceCPU, limit: true},
+	{key: ProxyMemoryLimitAnnotation, resource: corev1.ResourceMemory, limit: true},
+}
+
+// validateResourceQuantity validates that the given annotation value is a valid resource quantity.
+func validateResourceQuantity(value string) error {
+	if _, err := resource.ParseQuantity(value); err != nil {
+		return fmt.Errorf("invalid resource quantity: %v", err)
+	}
+	return nil
+}
+
+// applyResourceOverrides updates the resources of the proxy container based on the pod annotations,
+// falling back to the namespace labels. Requests are lowered to the limit when an override would
+// otherwise leave the request above the limit, which Kubernetes rejects.
+func applyResourceOverrides(containers []corev1.Container, annotations, namespaceLabels map[string]string) error {
+	sidecar := FindSidecar(containers)
+	if sidecar == nil {
+		return nil
+	}
+
+	for _, o := range resourceOverrides {
+		value, ok := annotations[o.key]
+		if !ok {
+			if value, ok = namespaceLabels[o.key]; !ok {
+				continue
+			}
+		}
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return fmt.Errorf("invalid value '%s' for '%s': %v", value, o.key, err)
+		}
+		if o.limit {
+			if sidecar.Resources.Limits == nil {
+				sidecar.Resources.Limits = corev1.ResourceList{}
+			}
+			sidecar.Resources.Limits[o.resource] = q
+		} else {
+			if sidecar.Resources.Requests == nil {
+				sidecar.Resources.Requests = corev1.ResourceList{}
+			}
+			sidecar.Resources.Requests[o.resource] = q
+		}
+	}
+
+	for name, limit := range sidecar.Resources.Limits {
+		if request, ok := sidecar.Resources.Requests[name]; ok && request.Cmp(limit) > 0 {
+			sidecar.Resources.Requests[name] = limit
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestApplyResourceOverrides(t *testing.T) {
+	defaults := func() []corev1.Container {
+		return []corev1.Container{
+			{Name: "app"},
+			{
+				Name: ProxyContainerName,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("100m"),
+						corev1.ResourceMemory: resource.MustParse("128Mi"),
+					},
+					Limits: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("2000m"),
+						corev1.ResourceMemory: resource.MustParse("1024Mi"),
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name            string
+		annotations     map[string]string
+		namespaceLabels map[string]string
+		want            corev1.ResourceRequirements
+		wantErr         bool
+	}{
+		{
+			name: "no overrides",
+			want: defaults()[1].Resources,
+		},
+		{
+			name: "namespace label overrides",
+			namespaceLabels: map[string]string{
+				"sidecar.istio.io/proxyMemory":      "64Mi",
+				"sidecar.istio.io/proxyMemoryLimit": "256Mi",
+			},
+			want: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("100m"),
+					corev1.ResourceMemory: resource.MustParse("64Mi"),
+				},
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("2000m"),
+					corev1.ResourceMemory: resource.MustParse("256Mi"),
+				},
+			},
+		},
+		{
+			name: "pod annotation takes precedence over namespace label",
+			annotations: map[string]string{
+				"sidecar.istio.io/proxyCPULimit": "500m",
+			},
+			namespaceLabels: map[string]string{
+				"sidecar.istio.io/proxyCPULimit": "200m",
+			},
+			want: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("100m"),
+					corev1.ResourceMemory: resource.MustParse("128Mi"),
+				},
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("500m"),
+					corev1.ResourceMemory: resource.MustParse("1024Mi"),
+				},
+			},
+		},
+		{
+			name: "request lowered to limit",
+			annotations: map[string]string{
+				"sidecar.istio.io/proxyMemoryLimit": "64Mi",
+			},
+			want: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("100m"),
+					corev1.ResourceMemory: resource.MustParse("64Mi"),
+				},
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("2000m"),
+					corev1.ResourceMemory: resource.MustParse("64Mi"),
+				},
+			},
+		},
+		{
+			name: "invalid quantity",
+			namespaceLabels: map[string]string{
+				"sidecar.istio.io/proxyCPU": "lots",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			containers := defaults()
+			err := applyResourceOverrides(containers, tt.annotations, tt.namespaceLabels)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("applyResourceOverrides() got error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := containers[1].Resources; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applyResourceOverrides() got %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(containers[0], corev1.Container{Name: "app"}) {
+				t.Errorf("applyResourceOverrides() modified the application container: %v", containers[0])
+			}
+		})
+	}
+}
@@ -34,6 +34,7 @@ import (
 	"istio.io/istio/pilot/cmd"
 	"istio.io/istio/pilot/cmd/pilot-agent/status"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/security"
 	"istio.io/pkg/log"
 
 	"k8s.io/api/admission/v1beta1"
@@ -41,6 +42,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/kubernetes"
 )
 
 var (
@@ -78,6 +80,8 @@ type Webhook struct {
 	keyFile    string
 	cert       *tls.Certificate
 	mon        *monitor
+	client     kubernetes.Interface
+	revision   string
 }
 
 func loadConfig(injectFile, meshFile, valuesFile string) (*Config, *meshconfig.MeshConfig, string, error) {
@@ -141,6 +145,15 @@ type WebhookParameters struct {
 	// HealthCheckFile specifies the path to the health check file
 	// that is periodically updated.
 	HealthCheckFile string
+
+	// Client is an optional Kubernetes client used to look up the
+	// labels of the pod's namespace, e.g. for sidecar resource
+	// overrides. Namespace labels are ignored if not set.
+	Client kubernetes.Interface
+
+	// Revision is the control plane revision served by the webhook. Injected pods are labeled
+	// with the revision, so that the control plane managing them can be identified.
+	Revision string
 }
 
 // NewWebhook creates a new instance of a mutating webhook for automatic sidecar injection.
@@ -184,9 +197,11 @@ func NewWebhook(p WebhookParameters) (*Webhook, error) {
 		certFile:               p.CertFile,
 		keyFile:                p.KeyFile,
 		cert:                   &pair,
+		client:                 p.Client,
+		revision:               p.Revision,
 	}
 	// mtls disabled because apiserver webhook cert usage is still TBD.
-	wh.server.TLSConfig = &tls.Config{GetCertificate: wh.getCert}
+	wh.server.TLSConfig = security.RestrictTLSConfig(&tls.Config{GetCertificate: wh.getCert})
 	h := http.NewServeMux()
 	h.HandleFunc("/inject", wh.serveInject)
 
@@ -328,7 +343,9 @@ func removeImagePullSecrets(imagePullSecrets []corev1.LocalObjectReference, remo
 	return patch
 }
 
-func addContainer(target, added []corev1.Container, basePath string) (patch []rfc6902PatchOperation) {
+// addContainer appends the added containers to target, or inserts them in front of the
+// existing containers if prepend is set.
+func addContainer(target, added []corev1.Container, basePath string, prepend bool) (patch []rfc6902PatchOperation) {
 	saJwtSecretMountName := ""
 	var saJwtSecretMount corev1.VolumeMount
 	// find service account secret volume mount(/var/run/secrets/kubernetes.io/serviceaccount,
@@ -343,7 +360,7 @@ func addContainer(target, added []corev1.Container, basePath string) (patch []rf
 	}
 	first := len(target) == 0
 	var value interface{}
-	for _, add := range added {
+	for i, add := range added {
 		if add.Name == "istio-proxy" && saJwtSecretMountName != "" {
 			// add service account secret volume mount(/var/run/secrets/kubernetes.io/serviceaccount,
 			// https://kubernetes.io/docs/reference/access-authn-authz/service-accounts-admin/#service-account-automation) to istio-proxy container,
@@ -355,6 +372,8 @@ func addContainer(target, added []corev1.Container, basePath string) (patch []rf
 		if first {
 			first = false
 			value = []corev1.Container{add}
+		} else if prepend {
+			path = fmt.Sprintf("%v/%v", basePath, i)
 		} else {
 			path += "/-"
 		}
@@ -485,7 +504,8 @@ func updateAnnotation(target map[string]string, added map[string]string) (patch
 	return patch
 }
 
-func createPatch(pod *corev1.Pod, prevStatus *SidecarInjectionStatus, annotations map[string]string, sic *SidecarInjectionSpec) ([]byte, error) {
+func createPatch(pod *corev1.Pod, prevStatus *SidecarInjectionStatus, revision string, annotations map[string]string,
+	sic *SidecarInjectionSpec) ([]byte, error) {
 	var patch []rfc6902PatchOperation
 
 	// Remove any containers previously injected by kube-inject using
@@ -509,11 +529,18 @@ func createPatch(pod *corev1.Pod, prevStatus *SidecarInjectionStatus, annotation
 		if prober := DumpAppProbers(&pod.Spec); prober != "" {
 			sidecar.Env = append(sidecar.Env, corev1.EnvVar{Name: status.KubeAppProberEnvName, Value: prober})
 		}
+		rewriteSidecarReadinessProbe(sidecar)
 	}
 	addAppProberCmd()
 
-	patch = append(patch, addContainer(pod.Spec.InitContainers, sic.InitContainers, "/spec/initContainers")...)
-	patch = append(patch, addContainer(pod.Spec.Containers, sic.Containers, "/spec/containers")...)
+	if sic.Gateway {
+		// The istio-proxy placeholder is removed above as a legacy container; carry its ports and
+		// environment over to the injected gateway proxy.
+		mergeGatewayProxy(sic.Containers, pod.Spec.Containers)
+	}
+
+	patch = append(patch, addContainer(pod.Spec.InitContainers, sic.InitContainers, "/spec/initContainers", false)...)
+	patch = append(patch, addContainer(pod.Spec.Containers, sic.Containers, "/spec/containers", sic.HoldApplicationUntilProxyStarts)...)
 	patch = append(patch, addVolume(pod.Spec.Volumes, sic.Volumes, "/spec/volumes")...)
 	patch = append(patch, addImagePullSecrets(pod.Spec.ImagePullSecrets, sic.ImagePullSecrets, "/spec/imagePullSecrets")...)
 
@@ -521,17 +548,32 @@ func createPatch(pod *corev1.Pod, prevStatus *SidecarInjectionStatus, annotation
 		patch = append(patch, addPodDNSConfig(sic.DNSConfig, "/spec/dnsConfig")...)
 	}
 
+	if sic.ShareProcessNamespace {
+		patch = append(patch, rfc6902PatchOperation{
+			Op:    "add",
+			Path:  "/spec/shareProcessNamespace",
+			Value: true,
+		})
+	}
+
 	if pod.Spec.SecurityContext != nil {
 		patch = append(patch, addSecurityContext(pod.Spec.SecurityContext, "/spec/securityContext")...)
 	}
 
 	patch = append(patch, updateAnnotation(pod.Annotations, annotations)...)
 
-	patch = append(patch, addLabels(pod.Labels, map[string]string{model.MTLSReadyLabelName: "true"})...)
+	labels := map[string]string{model.MTLSReadyLabelName: "true"}
+	if revision != "" {
+		labels[model.RevisionLabel] = revision
+	}
+	patch = append(patch, addLabels(pod.Labels, labels)...)
 
 	if rewrite {
 		patch = append(patch, createProbeRewritePatch(pod.Annotations, &pod.Spec, sic)...)
 	}
+	if sic.ProxylessGRPC {
+		patch = append(patch, createGRPCBootstrapPatch(pod.Spec.Containers)...)
+	}
 
 	return json.Marshal(patch)
 }
@@ -658,7 +700,7 @@ func (wh *Webhook) inject(ar *v1beta1.AdmissionReview) *v1beta1.AdmissionRespons
 		deployMeta.Name = pod.Name
 	}
 
-	spec, iStatus, err := InjectionData(wh.sidecarConfig.Template, wh.valuesConfig, wh.sidecarTemplateVersion, typeMetadata, deployMeta, &pod.Spec, &pod.ObjectMeta, wh.meshConfig.DefaultConfig, wh.meshConfig) // nolint: lll
+	spec, iStatus, err := injectionData(wh.sidecarConfig.Template, wh.valuesConfig, wh.sidecarTemplateVersion, typeMetadata, deployMeta, &pod.Spec, &pod.ObjectMeta, wh.meshConfig.DefaultConfig, wh.meshConfig, wh.namespaceLabels(pod.ObjectMeta.Namespace)) // nolint: lll
 	if err != nil {
 		handleError(fmt.Sprintf("Injection data: err=%v spec=%v\n", err, iStatus))
 		return toAdmissionResponse(err)
@@ -666,7 +708,7 @@ func (wh *Webhook) inject(ar *v1beta1.AdmissionReview) *v1beta1.AdmissionRespons
 
 	annotations := map[string]string{annotation.SidecarStatus.Name: iStatus}
 
-	patchBytes, err := createPatch(&pod, injectionStatus(&pod), annotations, spec)
+	patchBytes, err := createPatch(&pod, injectionStatus(&pod), wh.revision, annotations, spec)
 	if err != nil {
 		handleError(fmt.Sprintf("AdmissionResponse: err=%v spec=%v\n", err, spec))
 		return toAdmissionResponse(err)
@@ -686,6 +728,19 @@ func (wh *Webhook) inject(ar *v1beta1.AdmissionReview) *v1beta1.AdmissionRespons
 	return &reviewResponse
 }
 
+// namespaceLabels returns the labels of the given namespace, or nil if they can't be determined.
+func (wh *Webhook) namespaceLabels(namespace string) map[string]string {
+	if wh.client == nil || namespace == "" {
+		return nil
+	}
+	ns, err := wh.client.CoreV1().Namespaces().Get(namespace, metav1.GetOptions{})
+	if err != nil {
+		log.Warnf("Failed to get namespace %s, ignoring namespace level overrides: %v", namespace, err)
+		return nil
+	}
+	return ns.Labels
+}
+
 func (wh *Webhook) serveInject(w http.ResponseWriter, r *http.Request) {
 	totalInjections.Increment()
 	var body []byte
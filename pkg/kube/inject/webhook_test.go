@@ -36,6 +36,7 @@ import (
 
 	"istio.io/api/annotation"
 
+	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/test/util"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/mcp/testing/testcerts"
@@ -829,6 +830,96 @@ func TestHelmInject(t *testing.T) {
 	}
 }
 
+func TestAddContainerPrepend(t *testing.T) {
+	added := []corev1.Container{{Name: ProxyContainerName}, {Name: "extra"}}
+	cases := []struct {
+		name      string
+		target    []corev1.Container
+		prepend   bool
+		wantPaths []string
+	}{
+		{
+			name:      "append",
+			target:    []corev1.Container{{Name: "app"}},
+			wantPaths: []string{"/spec/containers/-", "/spec/containers/-"},
+		},
+		{
+			name:      "prepend",
+			target:    []corev1.Container{{Name: "app"}},
+			prepend:   true,
+			wantPaths: []string{"/spec/containers/0", "/spec/containers/1"},
+		},
+		{
+			name:      "prepend to empty",
+			prepend:   true,
+			wantPaths: []string{"/spec/containers", "/spec/containers/1"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			patch := addContainer(c.target, added, "/spec/containers", c.prepend)
+			if len(patch) != len(c.wantPaths) {
+				t.Fatalf("got %d patch operations, want %d", len(patch), len(c.wantPaths))
+			}
+			for i, p := range patch {
+				if p.Op != "add" || p.Path != c.wantPaths[i] {
+					t.Errorf("patch %d: got %s %s, want add %s", i, p.Op, p.Path, c.wantPaths[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCreatePatchRevisionLabel(t *testing.T) {
+	cases := []struct {
+		name     string
+		labels   map[string]string
+		revision string
+		want     string
+	}{
+		{name: "default revision", labels: map[string]string{"app": "foo"}},
+		{name: "revision", labels: map[string]string{"app": "foo"}, revision: "canary", want: "canary"},
+		{name: "revision without labels", revision: "canary", want: "canary"},
+		{
+			name:     "revision already set",
+			labels:   map[string]string{"app": "foo", model.RevisionLabel: "stable"},
+			revision: "canary",
+			want:     "stable",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", Labels: c.labels}}
+			patch, err := createPatch(pod, &SidecarInjectionStatus{}, c.revision, nil, &SidecarInjectionSpec{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw, err := json.Marshal(pod)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := jsonpatch.DecodePatch(patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			patched, err := p.Apply(raw)
+			if err != nil {
+				t.Fatalf("patch %s does not apply: %v", patch, err)
+			}
+			var got corev1.Pod
+			if err := json.Unmarshal(patched, &got); err != nil {
+				t.Fatal(err)
+			}
+			if rev := got.Labels[model.RevisionLabel]; rev != c.want {
+				t.Errorf("got revision label %q, want %q", rev, c.want)
+			}
+			if got.Labels[model.MTLSReadyLabelName] != "true" {
+				t.Errorf("missing %s label: %v", model.MTLSReadyLabelName, got.Labels)
+			}
+		})
+	}
+}
+
 func createTestWebhook(t testing.TB, sidecarTemplate string) (*Webhook, func()) {
 	m := mesh.DefaultMeshConfig()
 	dir, err := ioutil.TempDir("", "webhook_test")
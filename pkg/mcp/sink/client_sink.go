@@ -17,6 +17,7 @@ package sink
 import (
 	"context"
 	"io"
+	"sync"
 	"time"
 
 	"istio.io/istio/pkg/mcp/status"
@@ -39,15 +40,42 @@ type Client struct {
 	client mcp.ResourceSourceClient
 	*Sink
 	reporter monitoring.Reporter
+
+	stateMu sync.Mutex
+	// connected is true while a stream is established.
+	connected bool
+	// disconnectedAt is the time the last stream was closed, or the creation time of the client.
+	disconnectedAt time.Time
 }
 
 // NewClient returns a new instance of Client.
 func NewClient(client mcp.ResourceSourceClient, options *Options) *Client {
 	return &Client{
-		Sink:     New(options),
-		reporter: options.Reporter,
-		client:   client,
+		Sink:           New(options),
+		reporter:       options.Reporter,
+		client:         client,
+		disconnectedAt: time.Now(),
+	}
+}
+
+// DisconnectedFor returns how long the client has been without an MCP stream, since
+// it was created or since its last stream was closed. It returns 0 while connected.
+func (c *Client) DisconnectedFor() time.Duration {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	if c.connected {
+		return 0
+	}
+	return time.Since(c.disconnectedAt)
+}
+
+func (c *Client) setConnected(connected bool) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	if c.connected && !connected {
+		c.disconnectedAt = time.Now()
 	}
+	c.connected = connected
 }
 
 var reconnectTestProbe = func() {}
@@ -85,7 +113,9 @@ func (c *Client) Run(ctx context.Context) {
 			scope.Errorf("Failed to create a new MCP sink stream: %v", err)
 		}
 
+		c.setConnected(true)
 		err := c.ProcessStream(c.stream)
+		c.setConnected(false)
 		if err != nil && err != io.EOF {
 			c.reporter.RecordRecvError(err, status.Code(err))
 			scope.Errorf("Error receiving MCP response: %v", err)
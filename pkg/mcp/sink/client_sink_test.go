@@ -106,6 +106,9 @@ func TestClientSink(t *testing.T) {
 	if diff := cmp.Diff(got, want); diff != "" {
 		t.Fatalf("wrong change on first update: \n got %v \nwant %v \ndiff %v", got, want, diff)
 	}
+	if d := c.DisconnectedFor(); d != 0 {
+		t.Fatalf("DisconnectedFor() = %v while connected", d)
+	}
 
 	prevDelay := reestablishStreamDelay
 	reestablishStreamDelay = 100 * time.Millisecond
@@ -123,6 +126,9 @@ func TestClientSink(t *testing.T) {
 	h.setOpenError(errors.New("fake connection error"))
 	h.recvErrorChan <- errors.New("non-EOF error")
 	<-reconnectChan
+	if d := c.DisconnectedFor(); d == 0 {
+		t.Fatal("DisconnectedFor() = 0 after the stream was closed")
+	}
 
 	// allow connection to succeed
 	h.setOpenError(nil)
@@ -414,6 +414,15 @@ func (con *connection) processClientRequest(req *mcp.RequestResources) error {
 
 		if w.pending == nil {
 			scope.Infof("MCP: connection %v: inc=%v WATCH for %v", con, req.Incremental, collection)
+
+			// The initial request of a sink reconnecting with incremental updates carries the
+			// resources it already has, so that only the changes are sent, including removals.
+			if req.ResponseNonce == "" && req.Incremental && w.incremental {
+				w.ackedVersionMap = make(map[string]string, len(req.InitialResourceVersions))
+				for name, version := range req.InitialResourceVersions {
+					w.ackedVersionMap[name] = version
+				}
+			}
 		} else {
 			versionInfo = w.pending.SystemVersionInfo
 			if req.ErrorDetail != nil {
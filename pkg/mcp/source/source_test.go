@@ -858,3 +858,50 @@ func TestSourceACKAddUpdateDelete_Incremental(t *testing.T) {
 		}
 	}
 }
+
+func TestSourceIncrementalInitialResourceVersions(t *testing.T) {
+	h := newSourceTestHarness(t)
+	h.setContext(peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.IPAddr{IP: net.IPv4(192, 168, 1, 1)},
+	}))
+
+	fakeLimiter := test.NewFakePerConnLimiter()
+	close(fakeLimiter.ErrCh)
+	options := &Options{
+		Watcher:            h,
+		CollectionsOptions: CollectionOptionsFromSlice(test.SupportedCollections),
+		Reporter:           monitoring.NewInMemoryStatsContext(),
+		ConnRateLimiter:    fakeLimiter,
+	}
+	for i := range options.CollectionsOptions {
+		options.CollectionsOptions[i].Incremental = true
+	}
+	s := New(options)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		if err := s.ProcessStream(h); err != nil {
+			t.Errorf("Stream() => got %v, want no error", err)
+		}
+		wg.Done()
+	}()
+
+	defer func() {
+		h.setRecvError(io.EOF)
+		wg.Wait()
+	}()
+
+	// The sink reconnects with A0 and B0, which it received from a previous stream.
+	req := test.MakeRequest(true, test.FakeType0Collection, "", codes.OK)
+	req.InitialResourceVersions = map[string]string{
+		test.Type0A[0].Metadata.Name: test.Type0A[0].Metadata.Version,
+		test.Type0B[0].Metadata.Name: test.Type0B[0].Metadata.Version,
+	}
+	h.requestsChan <- req
+
+	// Keep A0, delete B0 and add C0: only the changes are sent.
+	h.injectWatchResponse(makeWatchResponse(test.FakeType0Collection, "1", true, test.Type0A[0], test.Type0C[0]))
+	verifySentResources(t, h,
+		test.MakeResources(true, test.FakeType0Collection, "1", "1", []string{test.Type0B[0].Metadata.Name}, test.Type0C[0]))
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spiffe
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+)
+
+// x509SVIDUse is the use of the keys of a SPIFFE bundle holding X.509 SVID roots.
+const x509SVIDUse = "x509-svid"
+
+// trustBundle is a SPIFFE trust bundle, as served by the bundle endpoint of a SPIRE server. It is
+// a JWK set in which the X.509 roots are the keys with the x509-svid use.
+type trustBundle struct {
+	Keys []struct {
+		Use string   `json:"use"`
+		X5c [][]byte `json:"x5c"`
+	} `json:"keys"`
+}
+
+// TrustBundleToPEM returns the X.509 roots of the given trust bundle as PEM. The bundle is either a
+// SPIFFE trust bundle, e.g. exported from SPIRE with `spire-server bundle show -format spiffe`, or
+// already PEM encoded, in which case it is returned as is.
+func TrustBundleToPEM(bundle []byte) ([]byte, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(bundle), []byte("{")) {
+		return bundle, nil
+	}
+
+	b := &trustBundle{}
+	if err := json.Unmarshal(bundle, b); err != nil {
+		return nil, fmt.Errorf("failed to parse SPIFFE trust bundle: %v", err)
+	}
+	var out []byte
+	for _, key := range b.Keys {
+		if key.Use != x509SVIDUse {
+			continue
+		}
+		if len(key.X5c) != 1 {
+			return nil, fmt.Errorf("SPIFFE trust bundle key must hold exactly one certificate, got %d", len(key.X5c))
+		}
+		if _, err := x509.ParseCertificate(key.X5c[0]); err != nil {
+			return nil, fmt.Errorf("failed to parse SPIFFE trust bundle certificate: %v", err)
+		}
+		out = append(out, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: key.X5c[0]})...)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("SPIFFE trust bundle has no %s key", x509SVIDUse)
+	}
+	return out, nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spiffe
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func selfSignedCert(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"SPIRE"}},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestTrustBundleToPEM(t *testing.T) {
+	der := selfSignedCert(t)
+	x5c := base64.StdEncoding.EncodeToString(der)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	testCases := []struct {
+		name    string
+		bundle  string
+		want    []byte
+		wantErr string
+	}{
+		{
+			name:   "PEM",
+			bundle: string(certPEM),
+			want:   certPEM,
+		},
+		{
+			name: "SPIFFE bundle",
+			bundle: fmt.Sprintf(`{"keys": [
+				{"use": "jwt-svid", "kty": "EC", "kid": "foo"},
+				{"use": "x509-svid", "kty": "EC", "x5c": [%q]}
+			], "spiffe_refresh_hint": 60}`, x5c),
+			want: certPEM,
+		},
+		{
+			name:    "SPIFFE bundle without X.509 roots",
+			bundle:  `{"keys": [{"use": "jwt-svid", "kty": "EC"}]}`,
+			wantErr: "SPIFFE trust bundle has no x509-svid key",
+		},
+		{
+			name:    "invalid certificate",
+			bundle:  `{"keys": [{"use": "x509-svid", "x5c": ["Zm9v"]}]}`,
+			wantErr: "failed to parse SPIFFE trust bundle certificate",
+		},
+		{
+			name:    "invalid JSON",
+			bundle:  `{"keys": `,
+			wantErr: "failed to parse SPIFFE trust bundle",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := TrustBundleToPEM([]byte(tc.bundle))
+			if tc.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
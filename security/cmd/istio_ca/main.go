@@ -183,7 +183,9 @@ func initCLI() {
 	flags.StringVar(&opts.signingKeyFile, "signing-key", "", "Path to the CA signing key file.")
 
 	// Both self-signed or non-self-signed Citadel may take a root certificate file with a list of root certificates.
-	flags.StringVar(&opts.rootCertFile, "root-cert", "", "Path to the root certificate file.")
+	flags.StringVar(&opts.rootCertFile, "root-cert", "", "Path to the root certificate file. "+
+		"With a self signed CA, these roots are trusted in addition to the CA certificate, in PEM or in the SPIFFE "+
+		"trust bundle format of e.g. SPIRE.")
 
 	// Configuration if Citadel acts as a self signed CA.
 	flags.BoolVar(&opts.selfSignedCA, "self-signed-ca", false,
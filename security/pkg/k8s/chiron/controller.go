@@ -22,6 +22,7 @@ import (
 	"sync"
 	"time"
 
+	admissionapi "k8s.io/api/admissionregistration/v1beta1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -172,6 +173,11 @@ func (wc *WebhookController) Run(stopCh chan struct{}) {
 		}
 	}
 
+	// Point the webhook configurations at the CA certificate signing the webhook certificates
+	if err := wc.patchCABundles(); err != nil {
+		log.Errorf("error when patching the CA bundles of the webhook configurations: %v", err)
+	}
+
 	// Manage the secrets of webhooks
 	go wc.scrtController.Run(stopCh)
 
@@ -317,6 +323,8 @@ func (wc *WebhookController) scrtUpdated(oldObj, newObj interface{}) {
 
 		if err = wc.refreshSecret(scrt); err != nil {
 			log.Errorf("failed to update secret %s/%s (error: %s)", namespace, name, err)
+		} else if err = wc.patchCABundles(); err != nil {
+			log.Errorf("failed to patch the CA bundles of the webhook configurations: %v", err)
 		}
 	}
 }
@@ -344,6 +352,73 @@ func (wc *WebhookController) refreshSecret(scrt *v1.Secret) error {
 	return err
 }
 
+// patchCABundles sets the CA certificate as the CA bundle of the webhooks of the managed services, in
+// all the webhook configurations.
+func (wc *WebhookController) patchCABundles() error {
+	caCert, err := wc.getCACert()
+	if err != nil {
+		return err
+	}
+
+	mutatingConfigs, err := wc.admission.MutatingWebhookConfigurations().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for i := range mutatingConfigs.Items {
+		config := &mutatingConfigs.Items[i]
+		updated := false
+		for j, w := range config.Webhooks {
+			if wc.isManagedService(w.ClientConfig.Service, wc.mutatingWebhookServiceNames) &&
+				!bytes.Equal(w.ClientConfig.CABundle, caCert) {
+				config.Webhooks[j].ClientConfig.CABundle = caCert
+				updated = true
+			}
+		}
+		if updated {
+			if _, err := wc.admission.MutatingWebhookConfigurations().Update(config); err != nil {
+				return fmt.Errorf("failed to patch mutating webhook configuration %s: %v", config.Name, err)
+			}
+			log.Infof("CA bundle of mutating webhook configuration %s has been patched", config.Name)
+		}
+	}
+
+	validatingConfigs, err := wc.admission.ValidatingWebhookConfigurations().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for i := range validatingConfigs.Items {
+		config := &validatingConfigs.Items[i]
+		updated := false
+		for j, w := range config.Webhooks {
+			if wc.isManagedService(w.ClientConfig.Service, wc.validatingWebhookServiceNames) &&
+				!bytes.Equal(w.ClientConfig.CABundle, caCert) {
+				config.Webhooks[j].ClientConfig.CABundle = caCert
+				updated = true
+			}
+		}
+		if updated {
+			if _, err := wc.admission.ValidatingWebhookConfigurations().Update(config); err != nil {
+				return fmt.Errorf("failed to patch validating webhook configuration %s: %v", config.Name, err)
+			}
+			log.Infof("CA bundle of validating webhook configuration %s has been patched", config.Name)
+		}
+	}
+	return nil
+}
+
+// Return whether the webhook service is one of the services, in the namespace of the webhook certificates
+func (wc *WebhookController) isManagedService(svc *admissionapi.ServiceReference, svcNames []string) bool {
+	if svc == nil || svc.Namespace != wc.namespace {
+		return false
+	}
+	for _, name := range svcNames {
+		if svc.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Clean up the CSR
 func (wc *WebhookController) cleanUpCertGen(csrName string) error {
 	// Delete CSR
@@ -26,6 +26,7 @@ import (
 
 	"istio.io/istio/security/pkg/pki/util"
 
+	admissionapi "k8s.io/api/admissionregistration/v1beta1"
 	cert "k8s.io/api/certificates/v1beta1"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -691,6 +692,60 @@ func TestGetCACert(t *testing.T) {
 	}
 }
 
+func TestPatchCABundles(t *testing.T) {
+	webhook := func(name, svcName, svcNamespace string) admissionapi.Webhook {
+		return admissionapi.Webhook{
+			Name: name,
+			ClientConfig: admissionapi.WebhookClientConfig{
+				Service:  &admissionapi.ServiceReference{Name: svcName, Namespace: svcNamespace},
+				CABundle: []byte("old"),
+			},
+		}
+	}
+	client := fake.NewSimpleClientset(
+		&admissionapi.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "injector"},
+			Webhooks: []admissionapi.Webhook{
+				webhook("managed", "foo", "foo.ns"),
+				webhook("other-namespace", "foo", "bar.ns"),
+				webhook("other-service", "bar", "foo.ns"),
+			},
+		},
+		&admissionapi.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "galley"},
+			Webhooks:   []admissionapi.Webhook{webhook("managed", "baz", "foo.ns")},
+		},
+	)
+	wc, err := NewWebhookController(0.6, time.Hour,
+		client.CoreV1(), client.AdmissionregistrationV1beta1(), client.CertificatesV1beta1(),
+		"./test-data/example-ca-cert.pem", "foo.ns", []string{"foo"}, []string{"baz"})
+	if err != nil {
+		t.Fatalf("failed at creating webhook controller: %v", err)
+	}
+
+	if err := wc.patchCABundles(); err != nil {
+		t.Fatalf("failed to patch the CA bundles: %v", err)
+	}
+
+	mutating, err := client.AdmissionregistrationV1beta1().MutatingWebhookConfigurations().Get("injector", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get the mutating webhook configuration: %v", err)
+	}
+	want := map[string]string{"managed": exampleCACert1, "other-namespace": "old", "other-service": "old"}
+	for _, w := range mutating.Webhooks {
+		if string(w.ClientConfig.CABundle) != want[w.Name] {
+			t.Errorf("unexpected CA bundle of mutating webhook %s: %s", w.Name, w.ClientConfig.CABundle)
+		}
+	}
+	validating, err := client.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Get("galley", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get the validating webhook configuration: %v", err)
+	}
+	if !bytes.Equal(validating.Webhooks[0].ClientConfig.CABundle, []byte(exampleCACert1)) {
+		t.Errorf("unexpected CA bundle of validating webhook: %s", validating.Webhooks[0].ClientConfig.CABundle)
+	}
+}
+
 func TestGetServiceName(t *testing.T) {
 	mutatingWebhookServiceNames := []string{"foo", "bar"}
 	validatingWebhookServiceNames := []string{"baz"}
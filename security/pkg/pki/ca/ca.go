@@ -28,6 +28,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
+	"istio.io/istio/pkg/spiffe"
 	"istio.io/istio/security/pkg/k8s/configmap"
 	"istio.io/istio/security/pkg/pki/util"
 	"istio.io/pkg/log"
@@ -118,6 +119,11 @@ func appendRootCerts(pemCert []byte, rootCertFile string) ([]byte, error) {
 		if err != nil {
 			return rootCerts, fmt.Errorf("failed to read root certificates (%v)", err)
 		}
+		// The file may hold the trust bundle of another SPIFFE implementation, e.g. SPIRE, for the
+		// workloads to accept the identities it issues.
+		if certBytes, err = spiffe.TrustBundleToPEM(certBytes); err != nil {
+			return rootCerts, fmt.Errorf("failed to read root certificates (%v)", err)
+		}
 		log.Debugf("The root certificates to be appended is: %v", rootCertFile)
 		if len(rootCerts) > 0 {
 			// Append a newline after the last cert
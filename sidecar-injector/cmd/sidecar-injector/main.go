@@ -61,6 +61,7 @@ var (
 		webhookConfigName   string
 		webhookName         string
 		monitoringPort      int
+		revision            string
 	}{
 		loggingOptions: log.DefaultOptions(),
 	}
@@ -78,6 +79,11 @@ var (
 
 			log.Infof("version %s", version.Info.String())
 
+			client, err := kube.CreateClientset(flags.kubeconfigFile, "")
+			if err != nil {
+				return multierror.Prefix(err, "failed to create kubernetes client")
+			}
+
 			parameters := inject.WebhookParameters{
 				ConfigFile:          flags.injectConfigFile,
 				ValuesFile:          flags.injectValuesFile,
@@ -88,6 +94,8 @@ var (
 				HealthCheckInterval: flags.healthCheckInterval,
 				HealthCheckFile:     flags.healthCheckFile,
 				MonitoringPort:      flags.monitoringPort,
+				Client:              client,
+				Revision:            flags.revision,
 			}
 			wh, err := inject.NewWebhook(parameters)
 			if err != nil {
@@ -228,6 +236,8 @@ func init() {
 		"Name of the mutatingwebhookconfiguration resource in Kubernetes.")
 	rootCmd.PersistentFlags().StringVar(&flags.webhookName, "webhookName", "sidecar-injector.istio.io",
 		"Name of the webhook entry in the webhook config.")
+	rootCmd.PersistentFlags().StringVar(&flags.revision, "revision", "",
+		"Control plane revision served by the webhook. Injected pods are labeled with the revision.")
 	// Attach the Istio logging options to the command.
 	flags.loggingOptions.AttachCobraFlags(rootCmd)
 
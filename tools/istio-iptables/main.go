@@ -102,7 +102,8 @@ func run(args []string, flagSet *flag.FlagSet) {
 		"Comma separated list of IP ranges in CIDR form to be excluded from redirection. "+
 			"Only applies when all  outbound traffic (i.e. \"*\") is being redirected (default to $ISTIO_SERVICE_EXCLUDE_CIDR)")
 	flagSet.StringVar(&outboundPortsExclude, "o", outboundPortsExclude,
-		"Comma separated list of outbound ports to be excluded from redirection to Envoy (optional")
+		"Comma separated list of outbound ports to be excluded from redirection to Envoy (optional). "+
+			"Only TCP traffic is redirected, so UDP traffic, e.g. DNS on port 53, never needs to be excluded")
 	flagSet.StringVar(&kubevirtInterfaces, "k", kubevirtInterfaces,
 		"Comma separated list of virtual interfaces whose inbound traffic (from VM) will be treated as outbound (optional)")
 
@@ -174,6 +175,10 @@ func run(args []string, flagSet *flag.FlagSet) {
 	// Remove the old chains, to generate new configs.
 	ext.RunQuietlyAndIgnore(dep.IPTABLES, "-t", "nat", "-D", "PREROUTING", "-p", "tcp", "-j", "ISTIO_INBOUND")
 	ext.RunQuietlyAndIgnore(dep.IPTABLES, "-t", "mangle", "-D", "PREROUTING", "-p", "tcp", "-j", "ISTIO_INBOUND")
+	ext.RunQuietlyAndIgnore(dep.IPTABLES, "-t", "mangle", "-D", "PREROUTING", "-p", "tcp", "-m", "mark", "--mark", inboundTProxyMark,
+		"-j", "CONNMARK", "--save-mark")
+	ext.RunQuietlyAndIgnore(dep.IPTABLES, "-t", "mangle", "-D", "OUTPUT", "-p", "tcp", "-m", "connmark", "--mark", inboundTProxyMark,
+		"-j", "CONNMARK", "--restore-mark")
 	ext.RunQuietlyAndIgnore(dep.IPTABLES, "-t", "nat", "-D", "OUTPUT", "-p", "tcp", "-j", "ISTIO_OUTPUT")
 	// Flush and delete the istio chains.
 	ext.RunQuietlyAndIgnore(dep.IPTABLES, "-t", "nat", "-F", "ISTIO_OUTPUT")
@@ -288,6 +293,17 @@ func run(args []string, flagSet *flag.FlagSet) {
 		ext.RunOrFail(dep.IPTABLES, "-t", table, "-N", "ISTIO_INBOUND")
 		ext.RunOrFail(dep.IPTABLES, "-t", table, "-A", "PREROUTING", "-p", "tcp", "-j", "ISTIO_INBOUND")
 
+		if inboundInterceptionMode == "TPROXY" {
+			// Envoy marks the connections it opens to the application with the original source address.
+			// Don't divert those back to Envoy, and remember the mark on the connection so that the
+			// application replies, addressed to the original source, are routed back to Envoy.
+			ext.RunOrFail(dep.IPTABLES, "-t", "mangle", "-A", "ISTIO_INBOUND", "-p", "tcp", "-m", "mark", "--mark", inboundTProxyMark, "-j", "RETURN")
+			ext.RunOrFail(dep.IPTABLES, "-t", "mangle", "-A", "PREROUTING", "-p", "tcp", "-m", "mark", "--mark", inboundTProxyMark,
+				"-j", "CONNMARK", "--save-mark")
+			ext.RunOrFail(dep.IPTABLES, "-t", "mangle", "-A", "OUTPUT", "-p", "tcp", "-m", "connmark", "--mark", inboundTProxyMark,
+				"-j", "CONNMARK", "--restore-mark")
+		}
+
 		if inboundPortsInclude == "*" {
 			// Makes sure SSH is not redirected
 			ext.RunOrFail(dep.IPTABLES, "-t", table, "-A", "ISTIO_INBOUND", "-p", "tcp", "--dport", "22", "-j", "RETURN")
@@ -318,10 +334,6 @@ func run(args []string, flagSet *flag.FlagSet) {
 					if err != nil {
 						fmt.Println("No socket match support")
 					}
-					err = ext.Run(dep.IPTABLES, "-t", "mangle", "-A", "ISTIO_INBOUND", "-p", "tcp", "--dport", port, "-m", "socket", "-j", "ISTIO_DIVERT")
-					if err != nil {
-						fmt.Println("No socket match support")
-					}
 					ext.RunOrFail(dep.IPTABLES, "-t", "mangle", "-A", "ISTIO_INBOUND", "-p", "tcp", "--dport", port, "-j", "ISTIO_TPROXY")
 				} else {
 					ext.RunOrFail(dep.IPTABLES, "-t", "nat", "-A", "ISTIO_INBOUND", "-p", "tcp", "--dport", port, "-j", "ISTIO_IN_REDIRECT")
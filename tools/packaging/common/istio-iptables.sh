@@ -49,6 +49,7 @@ function usage() {
   # shellcheck disable=SC2016
   echo '      outbound traffic (i.e. "*") is being redirected (default to $ISTIO_SERVICE_EXCLUDE_CIDR).'
   echo '  -o: Comma separated list of outbound ports to be excluded from redirection to Envoy (optional).'
+  echo '      Only TCP traffic is redirected, so UDP traffic, e.g. DNS on port 53, never needs to be excluded.'
   echo '  -k: Comma separated list of virtual interfaces whose inbound traffic (from VM)'
   echo '      will be treated as outbound (optional)'
   echo '  -t: Unit testing, only functions are loaded and no other instructions are executed.'
@@ -258,6 +259,8 @@ fi
 # Remove the old chains, to generate new configs.
 iptables -t nat -D PREROUTING -p tcp -j ISTIO_INBOUND 2>/dev/null
 iptables -t mangle -D PREROUTING -p tcp -j ISTIO_INBOUND 2>/dev/null
+iptables -t mangle -D PREROUTING -p tcp -m mark --mark "${INBOUND_TPROXY_MARK}" -j CONNMARK --save-mark 2>/dev/null
+iptables -t mangle -D OUTPUT -p tcp -m connmark --mark "${INBOUND_TPROXY_MARK}" -j CONNMARK --restore-mark 2>/dev/null
 iptables -t nat -D OUTPUT -p tcp -j ISTIO_OUTPUT 2>/dev/null
 
 # Flush and delete the istio chains.
@@ -378,6 +381,15 @@ if [ -n "${INBOUND_PORTS_INCLUDE}" ]; then
   iptables -t ${table} -N ISTIO_INBOUND
   iptables -t ${table} -A PREROUTING -p tcp -j ISTIO_INBOUND
 
+  if [ "${INBOUND_INTERCEPTION_MODE}" = "TPROXY" ]; then
+    # Envoy marks the connections it opens to the application with the original source address.
+    # Don't divert those back to Envoy, and remember the mark on the connection so that the
+    # application replies, addressed to the original source, are routed back to Envoy.
+    iptables -t mangle -A ISTIO_INBOUND -p tcp -m mark --mark "${INBOUND_TPROXY_MARK}" -j RETURN
+    iptables -t mangle -A PREROUTING -p tcp -m mark --mark "${INBOUND_TPROXY_MARK}" -j CONNMARK --save-mark
+    iptables -t mangle -A OUTPUT -p tcp -m connmark --mark "${INBOUND_TPROXY_MARK}" -j CONNMARK --restore-mark
+  fi
+
   if [ "${INBOUND_PORTS_INCLUDE}" == "*" ]; then
     # Makes sure SSH is not redirected
     iptables -t ${table} -A ISTIO_INBOUND -p tcp --dport 22 -j RETURN
@@ -401,7 +413,6 @@ if [ -n "${INBOUND_PORTS_INCLUDE}" ]; then
     # User has specified a non-empty list of ports to be redirected to Envoy.
     for port in ${INBOUND_PORTS_INCLUDE}; do
       if [ "${INBOUND_INTERCEPTION_MODE}" = "TPROXY" ]; then
-        iptables -t mangle -A ISTIO_INBOUND -p tcp --dport "${port}" -m socket -j ISTIO_DIVERT || echo "No socket match support"
         iptables -t mangle -A ISTIO_INBOUND -p tcp --dport "${port}" -m socket -j ISTIO_DIVERT || echo "No socket match support"
         iptables -t mangle -A ISTIO_INBOUND -p tcp --dport "${port}" -j ISTIO_TPROXY
       else